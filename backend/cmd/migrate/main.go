@@ -0,0 +1,120 @@
+// Command migrate applies, rolls back and inspects the database migrations of the server.
+//
+// Usage:
+//
+//	migrate up        apply every pending migration
+//	migrate down N    roll back the last N migrations
+//	migrate version   print the current schema version
+//	migrate force N   set the schema version to N without migrating, clearing a dirty state
+//
+// The database and migrations are read from DATABASE_URL and MIGRATIONS_DIR, as by the server.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+
+	"github.com/lightshare/backend/internal/config"
+	"github.com/lightshare/backend/pkg/database"
+)
+
+const usage = `usage: migrate <command>
+
+commands:
+  up        apply every pending migration
+  down N    roll back the last N migrations
+  version   print the current schema version
+  force N   set the schema version to N without migrating, clearing a dirty state
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "up", "down", "version", "force":
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", args[0], usage)
+	}
+
+	cfg := config.Load().Database
+	db, err := database.New(database.Config{URL: cfg.URL, MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	m, err := database.NewMigrator(db.DB, cfg.MigrationsDir)
+	if err != nil {
+		return err
+	}
+	defer func() { _, _ = m.Close() }()
+
+	switch args[0] {
+	case "up":
+		if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return err
+		}
+	case "down":
+		steps, err := countArg(args)
+		if err != nil {
+			return err
+		}
+		if err := m.Steps(-steps); err != nil {
+			return err
+		}
+	case "force":
+		version, err := countArg(args)
+		if err != nil {
+			return err
+		}
+		if err := m.Force(version); err != nil {
+			return err
+		}
+	}
+
+	return printVersion(m)
+}
+
+// countArg parses the positive number following a command
+func countArg(args []string) (int, error) {
+	if len(args) != 2 {
+		return 0, fmt.Errorf("%s requires a number\n\n%s", args[0], usage)
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s requires a positive number, got %q", args[0], args[1])
+	}
+	return n, nil
+}
+
+// printVersion prints the schema version the database is at
+func printVersion(m *migrate.Migrate) error {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Println("no migrations applied")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		fmt.Printf("version %d (dirty)\n", version)
+	} else {
+		fmt.Printf("version %d\n", version)
+	}
+	return nil
+}
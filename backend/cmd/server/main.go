@@ -6,38 +6,82 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/middleware/skip"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 
 	"github.com/lightshare/backend/internal/config"
 	"github.com/lightshare/backend/internal/handlers"
 	"github.com/lightshare/backend/internal/middleware"
+	"github.com/lightshare/backend/internal/models"
 	"github.com/lightshare/backend/internal/repository"
 	"github.com/lightshare/backend/internal/services"
 	"github.com/lightshare/backend/pkg/crypto"
 	"github.com/lightshare/backend/pkg/database"
 	"github.com/lightshare/backend/pkg/email"
+	"github.com/lightshare/backend/pkg/events"
+	"github.com/lightshare/backend/pkg/jobs"
 	"github.com/lightshare/backend/pkg/jwt"
 	"github.com/lightshare/backend/pkg/logger"
+	"github.com/lightshare/backend/pkg/metrics"
+	"github.com/lightshare/backend/pkg/oauth"
+	"github.com/lightshare/backend/pkg/providers"
+	"github.com/lightshare/backend/pkg/ratelimit"
 	"github.com/lightshare/backend/pkg/redis"
+	"github.com/lightshare/backend/pkg/tracing"
 )
 
 var (
 	version = "dev"
 )
 
+// Request timeouts per kind of route; routes calling providers get longer than auth, and
+// refreshing an account's devices the longest
+const (
+	authRequestTimeout          = 5 * time.Second
+	deviceReadRequestTimeout    = 10 * time.Second
+	deviceActionRequestTimeout  = 15 * time.Second
+	deviceRefreshRequestTimeout = 30 * time.Second
+)
+
+// How long clients may cache public metadata; feature flags can change at runtime, the
+// provider catalogue only with the enabled providers
+const (
+	featuresCacheMaxAge        = time.Minute
+	providerCatalogCacheMaxAge = time.Hour
+)
+
 func main() {
 	// Initialize logger
-	logLevel := os.Getenv("LOG_LEVEL")
-	if logLevel == "" {
-		logLevel = "info"
+	if err := logger.InitWithConfig(loadLogConfig()); err != nil {
+		logger.Init("info")
+		logger.Error("Invalid log configuration", "error", err)
+		os.Exit(1)
 	}
-	logger.Init(logLevel)
 
 	// Load configuration
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		logger.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize tracing; without an OTLP endpoint spans are not recorded
+	var tracerProvider *sdktrace.TracerProvider
+	if cfg.Tracing.Endpoint != "" {
+		provider, err := tracing.Init(context.Background(), "lightshare-backend", version)
+		if err != nil {
+			logger.Warn("Failed to initialize tracing", "error", err)
+		} else {
+			tracerProvider = provider
+			logger.Info("Tracing enabled", "endpoint", cfg.Tracing.Endpoint)
+		}
+	}
 
 	// Initialize database
 	logger.Info("Connecting to database...")
@@ -59,6 +103,21 @@ func main() {
 	}()
 	logger.Info("Database connected successfully")
 
+	// Bring the schema up to date before anything uses it
+	if cfg.Database.SkipMigrations {
+		logger.Info("Skipping database migrations")
+	} else {
+		logger.Info("Running database migrations...", "dir", cfg.Database.MigrationsDir)
+		if err := database.RunMigrations(db.DB, cfg.Database.MigrationsDir); err != nil {
+			logger.Error("Failed to run database migrations", "error", err)
+			if closeErr := db.Close(); closeErr != nil {
+				logger.Error("Failed to close database connection during cleanup", "error", closeErr)
+			}
+			//nolint:gocritic // exitAfterDefer is acceptable here as we manually clean up resources
+			os.Exit(1)
+		}
+	}
+
 	// Initialize Redis
 	logger.Info("Connecting to Redis...")
 	redisClient, err := redis.New(redis.Config{
@@ -82,21 +141,41 @@ func main() {
 	}()
 	logger.Info("Redis connected successfully")
 
+	// Preload rate limiter scripts; the limiter falls back to uploading them on first use
+	if err := ratelimit.LoadScripts(context.Background(), redisClient.Client); err != nil {
+		logger.Warn("Failed to preload rate limit scripts", "error", err)
+	}
+
 	// Initialize services
 	logger.Info("Initializing services...")
 
-	// Load encryption key for provider tokens
-	encryptionKey, err := crypto.LoadEncryptionKey()
+	// Load the local encryption key, and the key provider for provider tokens
+	localKeys, err := crypto.LoadLocalKeyProvider()
 	if err != nil {
 		logger.Error("Failed to load encryption key", "error", err)
 		logger.Info("To generate a new encryption key, run: openssl rand -hex 32")
 		os.Exit(1)
 	}
+	tokenKeys, err := crypto.LoadKeyProvider()
+	if err != nil {
+		logger.Error("Failed to load encryption key provider", "error", err)
+		os.Exit(1)
+	}
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db.DB)
 	refreshTokenRepo := repository.NewRefreshTokenRepository(db.DB)
-	accountRepo := repository.NewAccountRepository(db.DB, encryptionKey)
+	accountRepo := repository.NewAccountRepository(db.DB, tokenKeys, localKeys)
+	apiKeyRepo := repository.NewAPIKeyRepository(db.DB)
+	auditRepo := repository.NewAuditRepository(db.DB)
+	oauthRepo := repository.NewOAuthProviderRepository(db.DB)
+	sceneRepo := repository.NewSceneRepository(db.DB)
+	stateSnapshotRepo := repository.NewStateSnapshotRepository(db.DB)
+	webhookRepo := repository.NewWebhookRepository(db.DB)
+	deviceStateRepo := repository.NewDeviceStateRepository(db.DB)
+	preferencesRepo := repository.NewUserPreferencesRepository(db.DB)
+	deviceLabelRepo := repository.NewDeviceLabelRepository(db.DB)
+	scheduleRepo := repository.NewScheduleRepository(db.DB)
 
 	// Initialize JWT service
 	jwtService := jwt.New(jwt.Config{
@@ -117,20 +196,116 @@ func main() {
 		MobileDeepLinkScheme: cfg.Email.MobileDeepLinkScheme,
 	})
 
+	// Start async email worker
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	emailWorker := email.NewWorker(emailService, 100)
+	emailWorker.Start(workerCtx)
+
+	// Optional MX lookup for signup email addresses
+	var domainValidator *email.DomainValidator
+	if cfg.Email.MXLookupEnabled {
+		domainValidator = email.NewDomainValidator(nil, cfg.Email.MXLookupTimeout, cfg.Email.MXCacheTTL)
+	}
+
+	// Optional Google sign-in
+	var googleVerifier services.IDTokenVerifier
+	if cfg.OAuth.GoogleClientID != "" {
+		googleVerifier = oauth.NewGoogleVerifier(cfg.OAuth.GoogleClientID)
+	}
+
 	// Initialize auth service
-	authService := services.NewAuthService(userRepo, refreshTokenRepo, jwtService, emailService)
+	authService := services.NewAuthService(userRepo, refreshTokenRepo, auditRepo, oauthRepo, jwtService, emailService, emailWorker, domainValidator, googleVerifier, redisClient.Client, localKeys)
+
+	authService.SetLockout(services.NewAccountLockoutService(redisClient.Client))
+
+	// Purge expired tokens, users whose deletion grace period has passed and stale Redis keys
+	tokenCleanup := jobs.NewTokenCleanupJob(refreshTokenRepo, userRepo, userRepo, redisClient.Client, cfg.JWT.CleanupInterval)
+	tokenCleanup.DeletedAccountRetention = services.AccountDeletionGracePeriod
+	tokenCleanup.Start(workerCtx)
+
+	// Provider feature flags are re-read for every provider client, so they apply without a restart
+	enabledProviders := func() []string { return config.LoadFeatures().EnabledProviders }
 
 	// Initialize provider service
-	providerService := services.NewProviderService(accountRepo, encryptionKey)
+	providerService := services.NewProviderService(accountRepo, tokenKeys, redisClient.Client, cfg.Providers.ValidationCacheTTL)
+	providerService.SetEnabledProviders(enabledProviders)
+	providerService.SetOAuth(providerOAuthConfig(cfg))
+
+	// Initialize Prometheus metrics
+	appMetrics := metrics.New()
+
+	// Initialize in-process event bus
+	eventBus := events.NewBus()
+
+	// Initialize user preferences service
+	preferencesService := services.NewUserPreferencesService(preferencesRepo)
+
+	// Initialize device label service
+	deviceLabelService := services.NewDeviceLabelService(deviceLabelRepo)
 
 	// Initialize device service
 	deviceService := services.NewDeviceService(
 		accountRepo,
 		redisClient.Client,
-		cfg.Devices.CacheTTL,
-		cfg.Devices.RateLimitPerMin,
+		eventBus,
+		services.DeviceServiceConfig{
+			CacheTTL:         cfg.Devices.CacheTTL,
+			FetchConcurrency: cfg.Devices.MaxConcurrentProviderCalls,
+			ProviderTimeouts: cfg.Devices.ProviderTimeouts,
+			FetchTimeout:     cfg.Devices.FetchTimeout,
+			StreamInterval:   cfg.Devices.StreamInterval,
+			RateLimits: services.RateLimits{
+				Read:  ratelimit.Limit{PerMinute: cfg.Devices.ReadRateLimitPerMin, Burst: cfg.Devices.RateLimitBurst},
+				Write: ratelimit.Limit{PerMinute: cfg.Devices.WriteRateLimitPerMin, Burst: cfg.Devices.RateLimitBurst},
+				User:  ratelimit.Limit{PerMinute: cfg.Devices.UserRateLimitPerMin},
+				Providers: map[providers.Provider]ratelimit.Limit{
+					providers.ProviderLIFX: {PerMinute: cfg.Providers.LIFXRateLimitPerMin},
+					providers.ProviderHue:  {PerMinute: cfg.Providers.HueRateLimitPerMin},
+				},
+			},
+			CircuitBreaker: services.CircuitBreakerConfig{
+				MaxRequests: uint32(cfg.Providers.CircuitMaxRequests),
+				Interval:    cfg.Providers.CircuitInterval,
+				Timeout:     cfg.Providers.CircuitTimeout,
+			},
+			Metrics:          appMetrics,
+			EnableRetry:      cfg.Providers.RetryEnabled,
+			LIFXLANMode:      cfg.Providers.LIFXLANMode,
+			EnabledProviders: enabledProviders,
+			StateHistory:     deviceStateRepo,
+			Preferences:      preferencesService,
+			Labels:           deviceLabelService,
+			Schedules:        scheduleRepo,
+		},
 	)
 
+	// Initialize scene service
+	sceneService := services.NewSceneService(sceneRepo, deviceService)
+
+	// Initialize state snapshot service
+	stateSnapshotService := services.NewStateSnapshotService(stateSnapshotRepo, deviceService)
+
+	// Initialize schedule service and start executing scheduled actions as they come due
+	scheduleService := services.NewScheduleService(scheduleRepo, deviceService)
+	scheduleRunner := services.NewScheduleRunner(scheduleRepo, deviceService)
+	scheduleRunner.Start(workerCtx)
+
+	// Periodically re-validate stored provider tokens, flagging revoked ones
+	tokenValidator := services.NewTokenValidatorJob(accountRepo, userRepo, deviceService, emailService, cfg.Providers.TokenValidationInterval)
+	tokenValidator.Start(workerCtx)
+
+	// Initialize webhook service and start delivering device events to webhooks
+	webhookService := services.NewWebhookService(webhookRepo)
+	webhookDispatcher := services.NewWebhookDispatcher(webhookRepo, eventBus, services.WebhookDispatcherConfig{
+		Workers: cfg.Webhooks.Workers,
+		Timeout: cfg.Webhooks.Timeout,
+	})
+	webhookDispatcher.Start(workerCtx)
+
+	// Initialize API key service
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo)
+
 	logger.Info("Services initialized successfully")
 
 	// Create Fiber app
@@ -143,10 +318,13 @@ func main() {
 	})
 
 	// Setup middleware
-	middleware.Setup(app)
+	middleware.Setup(app, cfg.Server, appMetrics)
 
 	// Setup routes
-	setupRoutes(app, authService, providerService, deviceService, jwtService)
+	setupRoutes(app, db, redisClient, appMetrics, cfg.Metrics.Token, cfg.Readiness, authService, providerService, deviceService, sceneService, stateSnapshotService, webhookService, apiKeyService, preferencesService, deviceLabelService, scheduleService, jwtService, tokenCleanup, refreshTokenRepo)
+	if cfg.Server.ServiceSecret != "" {
+		setupInternalRoutes(app, cfg.Server.ServiceSecret, authService, tokenCleanup, appMetrics)
+	}
 
 	// Start server in goroutine
 	go func() {
@@ -173,79 +351,282 @@ func main() {
 		logger.Error("Server shutdown error", "error", err)
 	}
 
+	// Flush queued emails and webhook events, and stop background jobs
+	tokenCleanup.Stop()
+	scheduleRunner.Stop()
+	tokenValidator.Stop()
+	emailWorker.Stop()
+	webhookDispatcher.Stop()
+
+	// Flush buffered spans
+	if tracerProvider != nil {
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			logger.Error("Tracer shutdown error", "error", err)
+		}
+	}
+
 	logger.Info("Server stopped")
 }
 
-func setupRoutes(app *fiber.App, authService *services.AuthService, providerService *services.ProviderService, deviceService *services.DeviceService, jwtService *jwt.Service) {
+// providerOAuthConfig returns the OAuth2 clients of the providers that have one configured
+func providerOAuthConfig(cfg *config.Config) services.ProviderOAuthConfig {
+	oauthConfig := services.ProviderOAuthConfig{
+		Providers:  make(map[providers.Provider]*oauth.AuthCodeConfig),
+		SuccessURL: cfg.Email.BaseURL + "/oauth/success",
+	}
+
+	clients := map[providers.Provider]struct {
+		config   config.ProviderOAuthConfig
+		endpoint oauth.Endpoint
+	}{
+		providers.ProviderLIFX: {cfg.OAuth.LIFX, oauth.LIFXEndpoint},
+		providers.ProviderHue:  {cfg.OAuth.Hue, oauth.HueEndpoint},
+	}
+	for provider, client := range clients {
+		if client.config.ClientID != "" {
+			oauthConfig.Providers[provider] = oauth.NewAuthCodeConfig(client.endpoint,
+				client.config.ClientID, client.config.ClientSecret, client.config.RedirectURI)
+		}
+	}
+	return oauthConfig
+}
+
+// loadLogConfig reads the log level and outputs from the environment. Logs always go to
+// stdout, and also to LOG_FILE, rotated at LOG_MAX_SIZE_MB, when it is set.
+func loadLogConfig() logger.LogConfig {
+	cfg := logger.LogConfig{
+		Level:   os.Getenv("LOG_LEVEL"),
+		Outputs: []logger.OutputConfig{{Type: logger.OutputStdout}},
+	}
+	if cfg.Level == "" {
+		cfg.Level = "info"
+	}
+
+	if path := os.Getenv("LOG_FILE"); path != "" {
+		maxSizeMB, _ := strconv.Atoi(os.Getenv("LOG_MAX_SIZE_MB"))
+		maxBackups, _ := strconv.Atoi(os.Getenv("LOG_MAX_BACKUPS"))
+		maxAgeDays, _ := strconv.Atoi(os.Getenv("LOG_MAX_AGE_DAYS"))
+		cfg.Outputs = append(cfg.Outputs, logger.OutputConfig{
+			Type:       logger.OutputFile,
+			Path:       path,
+			MaxSizeMB:  maxSizeMB,
+			MaxBackups: maxBackups,
+			MaxAgeDays: maxAgeDays,
+		})
+	}
+	return cfg
+}
+
+// setupInternalRoutes mounts the management endpoints called by other services, which
+// authenticate with requests signed with serviceSecret
+func setupInternalRoutes(app *fiber.App, serviceSecret string, authService *services.AuthService, tokenCleanup *jobs.TokenCleanupJob, appMetrics *metrics.Metrics) {
+	internalHandler := handlers.NewInternalHandler(authService, tokenCleanup, appMetrics)
+
+	internal := app.Group("/internal", middleware.ServiceAuthMiddleware(serviceSecret))
+	internal.Get("/users/:id", internalHandler.GetUser)
+	internal.Post("/jobs/cleanup-tokens", internalHandler.CleanupTokens)
+	internal.Get("/metrics/summary", internalHandler.MetricsSummary)
+}
+
+func setupRoutes(app *fiber.App, db *database.DB, redisClient *redis.Client, appMetrics *metrics.Metrics, metricsToken string, readiness config.ReadinessConfig, authService *services.AuthService, providerService *services.ProviderService, deviceService *services.DeviceService, sceneService *services.SceneService, stateSnapshotService *services.StateSnapshotService, webhookService *services.WebhookService, apiKeyService *services.APIKeyService, preferencesService *services.UserPreferencesService, deviceLabelService *services.DeviceLabelService, scheduleService *services.ScheduleService, jwtService *jwt.Service, tokenCleanup *jobs.TokenCleanupJob, refreshTokenRepo *repository.RefreshTokenRepository) {
 	// Health check endpoints
 	app.Get("/health", handlers.Health(version))
-	app.Get("/ready", handlers.Ready())
+	app.Get("/ready", handlers.Ready(db, redisClient, handlers.ReadinessSLO{
+		Database: readiness.DatabaseLatencySLO,
+		Redis:    readiness.RedisLatencySLO,
+	}))
 
-	// API v1 routes
-	v1 := app.Group("/api/v1")
+	// Prometheus metrics
+	app.Get("/metrics", middleware.MetricsAuth(metricsToken), adaptor.HTTPHandler(appMetrics.Handler()))
+
+	// API v1 routes; responses to mutations are never cached
+	v1 := app.Group("/api/v1", middleware.NoCacheMiddleware())
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService)
 	providerHandler := handlers.NewProviderHandler(providerService)
 	deviceHandler := handlers.NewDeviceHandler(deviceService)
+	deviceLabelHandler := handlers.NewDeviceLabelHandler(deviceService, deviceLabelService)
+	sceneHandler := handlers.NewSceneHandler(sceneService)
+	stateSnapshotHandler := handlers.NewStateSnapshotHandler(stateSnapshotService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	colorHandler := handlers.NewColorHandler()
+	preferencesHandler := handlers.NewPreferencesHandler(preferencesService)
+	scheduleHandler := handlers.NewScheduleHandler(scheduleService)
+	adminHandler := handlers.NewAdminHandler(tokenCleanup, refreshTokenRepo, authService)
+
+	// Color conversion utilities (public)
+	v1.Get("/color/convert", colorHandler.Convert)
+	v1.Get("/color/harmony", colorHandler.GetHarmony)
+
+	// Feature flags (public)
+	v1.Get("/features", middleware.StaticCacheMiddleware(featuresCacheMaxAge), handlers.Features(config.LoadFeatures))
 
 	// Auth routes
-	auth := v1.Group("/auth")
+	auth := v1.Group("/auth", middleware.Timeout(authRequestTimeout))
 	auth.Post("/signup", authHandler.Signup)
 	auth.Post("/login", authHandler.Login)
+	auth.Post("/signup/resend-verification", authHandler.ResendVerification)
 	auth.Post("/verify-email", authHandler.VerifyEmail)
 	auth.Post("/magic-link", authHandler.RequestMagicLink)
 	auth.Post("/magic-link/verify", authHandler.LoginWithMagicLink)
+	auth.Post("/oauth/google", authHandler.LoginWithGoogle)
+	auth.Post("/forgot-password", authHandler.RequestPasswordReset)
+	auth.Post("/reset-password", authHandler.ResetPassword)
+	auth.Post("/change-email/verify", authHandler.VerifyEmailChange)
 	auth.Post("/refresh", authHandler.RefreshToken)
 	auth.Post("/logout", authHandler.Logout)
+	auth.Post("/2fa/complete", authHandler.CompleteLogin2FA)
+	auth.Post("/restore-account", authHandler.RestoreAccount)
+	auth.Get("/oauth/:provider/callback", providerHandler.OAuthCallback)
 
 	// Protected auth routes
 	authMiddleware := middleware.AuthMiddleware(jwtService)
+	// Routes an admin impersonating the user may not call
+	notImpersonated := middleware.DenyImpersonation()
 	auth.Get("/me", authMiddleware, authHandler.Me)
-	auth.Post("/logout-all", authMiddleware, authHandler.LogoutAll)
+	auth.Patch("/me", authMiddleware, authHandler.UpdateProfile)
+	auth.Get("/oauth/:provider/authorize", authMiddleware, providerHandler.AuthorizeOAuth)
+	auth.Post("/oauth/:provider/result", authMiddleware, providerHandler.CollectOAuth)
+	auth.Delete("/me", authMiddleware, notImpersonated, authHandler.DeleteAccount)
+	auth.Delete("/account", authMiddleware, notImpersonated, authHandler.DeleteAccount)
+	auth.Post("/logout-all", authMiddleware, notImpersonated, authHandler.LogoutAll)
+	auth.Post("/change-email", authMiddleware, notImpersonated, authHandler.ChangeEmail)
+	auth.Post("/me/test-email", authMiddleware, authHandler.SendTestEmail)
+	auth.Get("/audit-log", authMiddleware, authHandler.AuditLog)
+	auth.Post("/unlock", authMiddleware, notImpersonated, middleware.RequireRole("admin"), authHandler.UnlockAccount)
+	auth.Get("/sessions", authMiddleware, authHandler.ListSessions)
+	auth.Delete("/sessions/:sessionId", authMiddleware, notImpersonated, authHandler.RevokeSession)
+
+	// Two-factor enrollment
+	auth.Post("/2fa/enroll", authMiddleware, notImpersonated, authHandler.Enroll2FA)
+	auth.Post("/2fa/verify-enroll", authMiddleware, notImpersonated, authHandler.Confirm2FA)
+	auth.Post("/2fa/disable", authMiddleware, notImpersonated, authHandler.Disable2FA)
+
+	// API key management (JWT only; API keys cannot mint other keys)
+	auth.Post("/api-keys", authMiddleware, notImpersonated, apiKeyHandler.CreateAPIKey)
+	auth.Get("/api-keys", authMiddleware, notImpersonated, apiKeyHandler.ListAPIKeys)
+	auth.Delete("/api-keys/:id", authMiddleware, notImpersonated, apiKeyHandler.RevokeAPIKey)
+
+	// Admin maintenance routes
+	admin := v1.Group("/admin", authMiddleware, notImpersonated, middleware.RequireRole("admin"))
+	admin.Post("/cleanup", adminHandler.Cleanup)
+	admin.Get("/token-stats", adminHandler.TokenStats)
+	admin.Post("/impersonate", adminHandler.Impersonate)
+
+	// Provider catalogue (public), registered ahead of the protected provider group.
+	// Requests carrying credentials still get the user's connections, which GET /providers
+	// listed before /providers/connections, until older clients have moved over.
+	providerCache := middleware.StaticCacheMiddleware(providerCatalogCacheMaxAge)
+	v1.Get("/providers",
+		func(c *fiber.Ctx) error {
+			c.Vary(fiber.HeaderAuthorization)
+			return c.Next()
+		},
+		skip.New(providerCache, middleware.HasAuthorization),
+		skip.New(handlers.ProviderCatalog(config.LoadFeatures), middleware.HasAuthorization),
+		authMiddleware,
+		middleware.Deprecated("/api/v1/providers/connections"),
+		providerHandler.ListProviders,
+	)
+	v1.Get("/providers/:id/capabilities", providerCache, handlers.ProviderCapabilitiesHandler(config.LoadFeatures))
 
 	// Provider routes (protected)
 	providers := v1.Group("/providers", authMiddleware)
+	providers.Get("/connections", providerHandler.ListProviders)
 	providers.Post("/connect", providerHandler.ConnectProvider)
 
+	// Device action preferences (protected)
+	v1.Get("/preferences", authMiddleware, preferencesHandler.GetPreferences)
+	v1.Put("/preferences", authMiddleware, preferencesHandler.UpdatePreferences)
+
+	// Scheduled action routes (protected)
+	schedules := v1.Group("/schedules", authMiddleware)
+	schedules.Post("", scheduleHandler.CreateSchedule)
+	schedules.Get("", scheduleHandler.ListSchedules)
+	schedules.Delete("/:id", scheduleHandler.DeleteSchedule)
+
+	// Webhook routes (protected)
+	webhooksEnabled := middleware.RequireFeature(func() bool { return config.LoadFeatures().EnableWebhooks })
+	webhooks := v1.Group("/webhooks", webhooksEnabled, authMiddleware)
+	webhooks.Post("", webhookHandler.CreateWebhook)
+	webhooks.Get("", webhookHandler.ListWebhooks)
+	webhooks.Delete("/:id", webhookHandler.DeleteWebhook)
+
 	// Account routes (protected)
-	accounts := v1.Group("/accounts", authMiddleware)
-	accounts.Get("", providerHandler.ListAccounts)
-	accounts.Delete("/:id", providerHandler.DisconnectAccount)
+	// Middleware is attached per route so device routes below can also accept API keys
+	accounts := v1.Group("/accounts")
+	accounts.Get("", authMiddleware, providerHandler.ListAccounts)
+	accounts.Delete("/:id", authMiddleware, providerHandler.DisconnectAccount)
+	accounts.Get("/:id/health", authMiddleware, providerHandler.CheckAccountHealth)
+	accounts.Put("/:id/reconnect", authMiddleware, providerHandler.ReconnectAccount)
+	accounts.Put("/:id/label", authMiddleware, providerHandler.UpdateAccountLabel)
+
+	// Device routes (protected by JWT or API key) - Phase 4
+	deviceAuth := middleware.AuthOrAPIKeyMiddleware(jwtService, apiKeyService)
+	canRead := middleware.RequireScope(models.APIKeyScopeDevicesRead)
+	canWrite := middleware.RequireScope(models.APIKeyScopeDevicesWrite)
+	readTimeout := middleware.Timeout(deviceReadRequestTimeout)
+	actionTimeout := middleware.Timeout(deviceActionRequestTimeout)
 
-	// Device routes (protected) - Phase 4
 	// List all devices across all accounts
-	v1.Get("/devices", authMiddleware, deviceHandler.ListDevices)
+	v1.Get("/devices", readTimeout, deviceAuth, canRead, deviceHandler.ListDevices)
+	v1.Get("/devices/summary", readTimeout, deviceAuth, canRead, deviceHandler.GetDeviceSummary)
+	v1.Post("/devices/group-action", actionTimeout, deviceAuth, canWrite, deviceHandler.ExecuteGroupAction)
 
 	// Account-specific device routes
-	v1.Get("/accounts/:accountId/devices", authMiddleware, deviceHandler.ListAccountDevices)
-	v1.Get("/accounts/:accountId/devices/:deviceId", authMiddleware, deviceHandler.GetDevice)
-	v1.Post("/accounts/:accountId/devices/:selector/action", authMiddleware, deviceHandler.ExecuteAction)
-	v1.Post("/accounts/:accountId/devices/refresh", authMiddleware, deviceHandler.RefreshDevices)
+	v1.Get("/accounts/:accountId/devices", readTimeout, deviceAuth, canRead, deviceHandler.ListAccountDevices)
+	// The event stream stays open for as long as the client listens, so it has no timeout
+	v1.Get("/accounts/:accountId/devices/events", deviceAuth, canRead, deviceHandler.StreamDeviceEvents)
+	v1.Get("/accounts/:accountId/devices/:deviceId", readTimeout, deviceAuth, canRead, deviceHandler.GetDevice)
+	v1.Get("/accounts/:accountId/devices/:deviceId/state", readTimeout, deviceAuth, canRead, deviceHandler.GetDeviceState)
+	v1.Get("/accounts/:accountId/devices/:deviceId/history", readTimeout, deviceAuth, canRead, deviceHandler.GetDeviceHistory)
+	v1.Patch("/accounts/:accountId/devices/:deviceId/label", readTimeout, deviceAuth, canWrite, deviceLabelHandler.SetDeviceLabel)
+	v1.Delete("/accounts/:accountId/devices/:deviceId/label", readTimeout, deviceAuth, canWrite, deviceLabelHandler.DeleteDeviceLabel)
+	v1.Post("/accounts/:accountId/devices/:selector/action", actionTimeout, deviceAuth, canWrite, deviceHandler.ExecuteAction)
+	v1.Post("/accounts/:accountId/devices/:selector/alarm", actionTimeout, deviceAuth, canWrite, deviceHandler.StartAlarm)
+	v1.Delete("/accounts/:accountId/devices/:selector/alarm", actionTimeout, deviceAuth, canWrite, deviceHandler.CancelAlarm)
+	v1.Post("/accounts/:accountId/devices/bulk-action", actionTimeout, deviceAuth, canWrite, deviceHandler.BulkExecuteAction)
+	v1.Post("/accounts/:accountId/devices/apply-harmony", actionTimeout, deviceAuth, canWrite, deviceHandler.ApplyHarmony)
+	// A continuous color sync streams for as long as the client listens, so only a single
+	// sync has a timeout
+	colorSyncTimeout := func(c *fiber.Ctx) error {
+		if c.QueryBool("continuous") {
+			return c.Next()
+		}
+		return actionTimeout(c)
+	}
+	v1.Post("/accounts/:accountId/devices/color-sync", colorSyncTimeout, deviceAuth, canWrite, deviceHandler.ColorSync)
+	v1.Delete("/accounts/:accountId/devices/color-sync", actionTimeout, deviceAuth, canWrite, deviceHandler.StopColorSync)
+	v1.Post("/color/apply-palette", actionTimeout, deviceAuth, canWrite, deviceHandler.ApplyPalette)
+	v1.Post("/accounts/:accountId/devices/refresh", middleware.Timeout(deviceRefreshRequestTimeout), deviceAuth, canRead, deviceHandler.RefreshDevices)
+	v1.Get("/accounts/:accountId/status", readTimeout, deviceAuth, canRead, deviceHandler.AccountStatus)
+
+	// Group routes
+	v1.Get("/accounts/:accountId/groups", readTimeout, deviceAuth, canRead, deviceHandler.ListGroups)
+
+	// Location routes
+	v1.Get("/accounts/:accountId/locations", readTimeout, deviceAuth, canRead, deviceHandler.ListLocations)
+	v1.Post("/accounts/:accountId/locations/:locationId/state", actionTimeout, deviceAuth, canWrite, deviceHandler.ApplyLocationState)
+
+	// State snapshot routes
+	v1.Post("/accounts/:accountId/devices/:selector/state-snapshot", actionTimeout, deviceAuth, canWrite, stateSnapshotHandler.TakeSnapshot)
+	v1.Get("/state-snapshots", readTimeout, deviceAuth, canRead, stateSnapshotHandler.ListSnapshots)
+	v1.Post("/state-snapshots/:snapshotId/restore", actionTimeout, deviceAuth, canWrite, stateSnapshotHandler.RestoreSnapshot)
+	v1.Delete("/state-snapshots/:snapshotId", actionTimeout, deviceAuth, canWrite, stateSnapshotHandler.DeleteSnapshot)
+
+	// Scene routes
+	scenesEnabled := middleware.RequireFeature(func() bool { return config.LoadFeatures().EnableScenes })
+	v1.Post("/accounts/:accountId/scenes", scenesEnabled, actionTimeout, deviceAuth, canWrite, sceneHandler.CreateScene)
+	v1.Get("/accounts/:accountId/scenes", scenesEnabled, readTimeout, deviceAuth, canRead, sceneHandler.ListScenes)
+	v1.Post("/accounts/:accountId/scenes/:sceneId/activate", scenesEnabled, actionTimeout, deviceAuth, canWrite, sceneHandler.ActivateScene)
+	v1.Delete("/accounts/:accountId/scenes/:sceneId", scenesEnabled, actionTimeout, deviceAuth, canWrite, sceneHandler.DeleteScene)
 }
 
+// errorHandler responds to errors returned by handlers with an RFC 7807 problem. Fiber
+// errors keep their status and message; service errors returned as is are mapped to
+// their problem type.
 func errorHandler(c *fiber.Ctx, err error) error {
-	// Default to 500 Internal Server Error
-	code := fiber.StatusInternalServerError
-	message := "Internal Server Error"
-
-	// Check if it's a Fiber error
-	if e, ok := err.(*fiber.Error); ok {
-		code = e.Code
-		message = e.Message
-	}
-
-	// Log the error
-	logger.Error("Request error",
-		"error", err,
-		"status", code,
-		"path", c.Path(),
-		"method", c.Method(),
-	)
-
-	// Return JSON error response
-	return c.Status(code).JSON(fiber.Map{
-		"error":  message,
-		"status": code,
-	})
+	return handlers.RespondProblem(c, handlers.ProblemFromError(err), err)
 }
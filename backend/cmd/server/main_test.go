@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/config"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/jwt"
+	"github.com/lightshare/backend/pkg/metrics"
+)
+
+func TestSetupRoutes_DeniesImpersonatedRequests(t *testing.T) {
+	jwtService := jwt.New(jwt.Config{Secret: "test-secret", AccessExpiration: time.Hour, RefreshExpiration: time.Hour})
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	setupRoutes(app, nil, nil, metrics.New(), "", config.ReadinessConfig{}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, jwtService, nil, nil)
+
+	// An admin role lets the admin routes get past RequireRole, so only the impersonation check can reject them.
+	token, _, err := jwtService.GenerateImpersonationToken(uuid.New(), "user@example.com", "admin", uuid.New(), "debugging")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	routes := []struct{ method, path string }{
+		{"POST", "/api/v1/auth/api-keys"},
+		{"GET", "/api/v1/auth/api-keys"},
+		{"DELETE", "/api/v1/auth/api-keys/" + uuid.NewString()},
+		{"POST", "/api/v1/auth/2fa/enroll"},
+		{"POST", "/api/v1/auth/2fa/verify-enroll"},
+		{"POST", "/api/v1/auth/2fa/disable"},
+		{"POST", "/api/v1/auth/change-email"},
+		{"DELETE", "/api/v1/auth/sessions/" + uuid.NewString()},
+		{"POST", "/api/v1/auth/logout-all"},
+		{"DELETE", "/api/v1/auth/me"},
+		{"DELETE", "/api/v1/auth/account"},
+		{"POST", "/api/v1/auth/unlock"},
+		{"POST", "/api/v1/admin/cleanup"},
+		{"GET", "/api/v1/admin/token-stats"},
+		{"POST", "/api/v1/admin/impersonate"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, http.NoBody)
+			req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to test request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != fiber.StatusForbidden {
+				t.Fatalf("Expected 403, got %d", resp.StatusCode)
+			}
+			if got := resp.Header.Get(fiber.HeaderContentType); got != apierror.ContentTypeProblemJSON {
+				t.Errorf("Expected a problem, got Content-Type %q", got)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if !strings.Contains(string(body), "impersonating") {
+				t.Errorf("Expected impersonation error, got %s", body)
+			}
+		})
+	}
+}
+
+func TestSetupRoutes_FeatureTogglesApplyWithoutRestart(t *testing.T) {
+	jwtService := jwt.New(jwt.Config{Secret: "test-secret", AccessExpiration: time.Hour, RefreshExpiration: time.Hour})
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	setupRoutes(app, nil, nil, metrics.New(), "", config.ReadinessConfig{}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, jwtService, nil, nil)
+
+	routes := []struct{ method, path, env string }{
+		{"GET", "/api/v1/webhooks", "FEATURE_WEBHOOKS"},
+		{"GET", "/api/v1/accounts/" + uuid.NewString() + "/scenes", "FEATURE_SCENES"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.path, func(t *testing.T) {
+			status := func() int {
+				resp, err := app.Test(httptest.NewRequest(route.method, route.path, http.NoBody))
+				if err != nil {
+					t.Fatalf("Failed to test request: %v", err)
+				}
+				_ = resp.Body.Close()
+				return resp.StatusCode
+			}
+
+			t.Setenv(route.env, "false")
+			if got := status(); got != fiber.StatusNotFound {
+				t.Errorf("Expected 404 with the feature off, got %d", got)
+			}
+			t.Setenv(route.env, "true")
+			if got := status(); got != fiber.StatusUnauthorized {
+				t.Errorf("Expected 401 with the feature on, got %d", got)
+			}
+		})
+	}
+}
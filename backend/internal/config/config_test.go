@@ -0,0 +1,177 @@
+package config
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLoad_ProviderTimeouts(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want map[string]time.Duration
+		name string
+	}{
+		{
+			name: "defaults",
+			want: map[string]time.Duration{"lifx": 10 * time.Second, "hue": 10 * time.Second, "nanoleaf": 10 * time.Second, "wiz": 10 * time.Second, "govee": 10 * time.Second, "homeassistant": 10 * time.Second},
+		},
+		{
+			name: "overrides",
+			env:  map[string]string{"PROVIDER_TIMEOUT_LIFX": "5s", "PROVIDER_TIMEOUT_HUE": "30s"},
+			want: map[string]time.Duration{"lifx": 5 * time.Second, "hue": 30 * time.Second, "nanoleaf": 10 * time.Second, "wiz": 10 * time.Second, "govee": 10 * time.Second, "homeassistant": 10 * time.Second},
+		},
+		{
+			name: "invalid value keeps default",
+			env:  map[string]string{"PROVIDER_TIMEOUT_HUE": "soon"},
+			want: map[string]time.Duration{"lifx": 10 * time.Second, "hue": 10 * time.Second, "nanoleaf": 10 * time.Second, "wiz": 10 * time.Second, "govee": 10 * time.Second, "homeassistant": 10 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			timeouts := Load().Devices.ProviderTimeouts
+			if len(timeouts) != len(tt.want) {
+				t.Fatalf("Expected %d provider timeouts, got %v", len(tt.want), timeouts)
+			}
+			for provider, want := range tt.want {
+				if timeouts[provider] != want {
+					t.Errorf("Expected %s timeout %v, got %v", provider, want, timeouts[provider])
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_MaxConcurrentProviderCalls(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		name string
+		want int
+	}{
+		{name: "default", want: 5},
+		{name: "set", env: map[string]string{"MAX_CONCURRENT_PROVIDER_CALLS": "8"}, want: 8},
+		{name: "legacy variable", env: map[string]string{"DEVICE_FETCH_CONCURRENCY": "3"}, want: 3},
+		{
+			name: "takes precedence over legacy variable",
+			env:  map[string]string{"MAX_CONCURRENT_PROVIDER_CALLS": "8", "DEVICE_FETCH_CONCURRENCY": "3"},
+			want: 8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			if got := Load().Devices.MaxConcurrentProviderCalls; got != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoad_CORSAllowedOrigins(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "unset allows any origin", want: nil},
+		{
+			name:  "list",
+			value: "https://app.lightshare.com, https://Staging.lightshare.com,",
+			want:  []string{"https://app.lightshare.com", "https://staging.lightshare.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CORS_ALLOWED_ORIGINS", tt.value)
+
+			if got := Load().Server.CORSAllowedOrigins; !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidate_ProviderTimeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout string
+		wantErr bool
+	}{
+		{name: "default", wantErr: false},
+		{name: "minimum", timeout: "1s", wantErr: false},
+		{name: "maximum", timeout: "60s", wantErr: false},
+		{name: "too short", timeout: "500ms", wantErr: true},
+		{name: "too long", timeout: "2m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.timeout != "" {
+				t.Setenv("PROVIDER_TIMEOUT_LIFX", tt.timeout)
+			}
+
+			err := Load().Validate()
+			if tt.wantErr && err == nil {
+				t.Error("Expected a validation error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadFeatures(t *testing.T) {
+	tests := []struct {
+		env           map[string]string
+		name          string
+		wantProviders []string
+		wantScenes    bool
+		wantWebhooks  bool
+	}{
+		{
+			name:          "defaults",
+			wantProviders: []string{"govee", "homeassistant", "hue", "lifx", "nanoleaf", "wiz"},
+			wantScenes:    true,
+			wantWebhooks:  true,
+		},
+		{
+			name:          "overrides",
+			env:           map[string]string{"FEATURE_ENABLED_PROVIDERS": " LIFX, hue ,", "FEATURE_SCENES": "false", "FEATURE_WEBHOOKS": "false"},
+			wantProviders: []string{"lifx", "hue"},
+		},
+		{
+			name:          "no providers",
+			env:           map[string]string{"FEATURE_ENABLED_PROVIDERS": ","},
+			wantProviders: []string{},
+			wantScenes:    true,
+			wantWebhooks:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			features := LoadFeatures()
+			if !slices.Equal(features.EnabledProviders, tt.wantProviders) {
+				t.Errorf("Expected providers %v, got %v", tt.wantProviders, features.EnabledProviders)
+			}
+			if features.EnableScenes != tt.wantScenes || features.EnableWebhooks != tt.wantWebhooks {
+				t.Errorf("Expected scenes %v and webhooks %v, got %+v", tt.wantScenes, tt.wantWebhooks, features)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/middleware"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/internal/services"
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+// TokenStatsReader counts refresh tokens by state
+type TokenStatsReader interface {
+	Stats(ctx context.Context) (*repository.TokenStats, error)
+}
+
+// UserImpersonator issues admins access tokens for other users
+type UserImpersonator interface {
+	ImpersonateUser(ctx context.Context, adminID, targetUserID uuid.UUID, reason string, userAgent, ipAddress *string) (*services.ImpersonationResponse, error)
+}
+
+// AdminHandler handles maintenance endpoints for users with the admin role
+type AdminHandler struct {
+	tokenCleaner TokenCleaner
+	tokenStats   TokenStatsReader
+	impersonator UserImpersonator
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(tokenCleaner TokenCleaner, tokenStats TokenStatsReader, impersonator UserImpersonator) *AdminHandler {
+	return &AdminHandler{
+		tokenCleaner: tokenCleaner,
+		tokenStats:   tokenStats,
+		impersonator: impersonator,
+	}
+}
+
+// Cleanup runs the token cleanup job now and returns what it removed
+// POST /api/v1/admin/cleanup
+func (h *AdminHandler) Cleanup(c *fiber.Ctx) error {
+	adminID, _ := middleware.GetUserID(c)
+	result := h.tokenCleaner.Run(c.UserContext())
+	logger.Info("Token cleanup triggered by admin",
+		"admin_id", adminID,
+		"refresh_tokens", result.RefreshTokens,
+		"deleted_accounts", result.DeletedAccounts,
+	)
+
+	return c.Status(fiber.StatusOK).JSON(newCleanupResponse(result))
+}
+
+// TokenStats returns how many refresh tokens are active, revoked and expired
+// GET /api/v1/admin/token-stats
+func (h *AdminHandler) TokenStats(c *fiber.Ctx) error {
+	stats, err := h.tokenStats.Stats(c.UserContext())
+	if err != nil {
+		return serviceError(c, err, "failed to get token stats")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(stats)
+}
+
+// ImpersonateRequest represents the impersonate request body
+type ImpersonateRequest struct {
+	UserID string `json:"user_id" validate:"required,uuid"`
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// Impersonate issues the admin a 15-minute access token for another user, without a
+// refresh token
+// POST /api/v1/admin/impersonate
+func (h *AdminHandler) Impersonate(c *fiber.Ctx) error {
+	adminID, err := middleware.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req ImpersonateRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	// The uuid tag guarantees the ID parses
+	targetUserID, _ := uuid.Parse(req.UserID)
+	userAgent := c.Get("User-Agent")
+	ipAddress := c.IP()
+
+	resp, err := h.impersonator.ImpersonateUser(c.UserContext(), adminID, targetUserID, req.Reason, &userAgent, &ipAddress)
+	if err != nil {
+		return serviceError(c, err, "failed to impersonate user")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/middleware"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/internal/services"
+	"github.com/lightshare/backend/pkg/jobs"
+	"github.com/lightshare/backend/pkg/jwt"
+)
+
+// stubTokenCleaner is a TokenCleaner returning a fixed result
+type stubTokenCleaner struct {
+	result jobs.CleanupResult
+}
+
+func (s *stubTokenCleaner) Run(context.Context) jobs.CleanupResult {
+	return s.result
+}
+
+// stubTokenStats is a TokenStatsReader returning fixed stats or an error
+type stubTokenStats struct {
+	stats *repository.TokenStats
+	err   error
+}
+
+func (s *stubTokenStats) Stats(context.Context) (*repository.TokenStats, error) {
+	return s.stats, s.err
+}
+
+func TestAdminHandler_Cleanup(t *testing.T) {
+	cleaner := &stubTokenCleaner{result: jobs.CleanupResult{RefreshTokens: 7, DeletedAccounts: 2, MagicLinks: 3}}
+	handler := NewAdminHandler(cleaner, &stubTokenStats{}, nil)
+
+	app := fiber.New()
+	app.Post("/admin/cleanup", handler.Cleanup)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/admin/cleanup", http.NoBody))
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var body map[string]int
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body["deleted_refresh_tokens"] != 7 || body["deleted_users"] != 2 {
+		t.Errorf("Expected 7 refresh tokens and 2 users deleted, got %v", body)
+	}
+	if body["magic_links"] != 3 {
+		t.Errorf("Expected the job's own counts to be kept, got %v", body)
+	}
+}
+
+func TestAdminHandler_TokenStats(t *testing.T) {
+	testCases := []struct {
+		stats      *repository.TokenStats
+		err        error
+		name       string
+		wantStatus int
+	}{
+		{
+			name:       "stats",
+			stats:      &repository.TokenStats{Total: 10, Active: 6, Revoked: 3, Expired: 1},
+			wantStatus: fiber.StatusOK,
+		},
+		{
+			name:       "database error",
+			err:        errors.New("connection reset"),
+			wantStatus: fiber.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := NewAdminHandler(&stubTokenCleaner{}, &stubTokenStats{stats: tc.stats, err: tc.err}, nil)
+
+			app := fiber.New()
+			app.Get("/admin/token-stats", handler.TokenStats)
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/admin/token-stats", http.NoBody))
+			if err != nil {
+				t.Fatalf("Failed to test request: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			if resp.StatusCode != tc.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tc.wantStatus, resp.StatusCode)
+			}
+			if tc.stats == nil {
+				return
+			}
+
+			var body repository.TokenStats
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if body != *tc.stats {
+				t.Errorf("Expected %+v, got %+v", *tc.stats, body)
+			}
+		})
+	}
+}
+
+// stubImpersonator records the impersonations it is asked for
+type stubImpersonator struct {
+	adminID, targetUserID uuid.UUID
+	reason                string
+	calls                 int
+}
+
+func (s *stubImpersonator) ImpersonateUser(_ context.Context, adminID, targetUserID uuid.UUID, reason string, _, _ *string) (*services.ImpersonationResponse, error) {
+	s.calls++
+	s.adminID, s.targetUserID, s.reason = adminID, targetUserID, reason
+	return &services.ImpersonationResponse{AccessToken: "impersonation-token", TokenType: "Bearer"}, nil
+}
+
+func TestAdminHandler_Impersonate(t *testing.T) {
+	jwtService := jwt.New(jwt.Config{Secret: "test-secret", AccessExpiration: time.Hour, RefreshExpiration: time.Hour})
+	impersonator := &stubImpersonator{}
+	handler := NewAdminHandler(&stubTokenCleaner{}, &stubTokenStats{}, impersonator)
+
+	app := fiber.New()
+	admin := app.Group("/admin", middleware.AuthMiddleware(jwtService), middleware.RequireRole("admin"))
+	admin.Post("/impersonate", handler.Impersonate)
+
+	adminID, targetUserID := uuid.New(), uuid.New()
+	impersonate := func(role, body string) *http.Response {
+		t.Helper()
+		tokens, err := jwtService.GenerateTokenPair(adminID, "someone@example.com", role, uuid.New())
+		if err != nil {
+			t.Fatalf("Failed to generate tokens: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/admin/impersonate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		return resp
+	}
+
+	body := `{"user_id":"` + targetUserID.String() + `","reason":"debugging ticket #1234"}`
+	resp := impersonate("user", body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != fiber.StatusForbidden || impersonator.calls != 0 {
+		t.Fatalf("Expected a non-admin to be forbidden, got %d after %d calls", resp.StatusCode, impersonator.calls)
+	}
+
+	resp = impersonate("admin", `{"user_id":"not-a-uuid","reason":"debugging"}`)
+	_ = resp.Body.Close()
+	if resp.StatusCode != fiber.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for an invalid user ID, got %d", resp.StatusCode)
+	}
+
+	resp = impersonate("admin", body)
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var got map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if got["access_token"] != "impersonation-token" || got["refresh_token"] != nil {
+		t.Errorf("Expected only an access token, got %v", got)
+	}
+	if impersonator.adminID != adminID || impersonator.targetUserID != targetUserID || impersonator.reason != "debugging ticket #1234" {
+		t.Errorf("Unexpected impersonation: %+v", impersonator)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/middleware"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/internal/services"
+)
+
+// APIKeyHandler handles API key management endpoints
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKey issues a new API key; the plaintext key is only shown in this response
+// POST /api/v1/auth/api-keys
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req services.CreateAPIKeyRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	resp, err := h.apiKeyService.Create(c.UserContext(), userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAPIKeyRequest) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return serviceError(c, err, "failed to create api key")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// ListAPIKeys lists the user's active API keys
+// GET /api/v1/auth/api-keys
+func (h *APIKeyHandler) ListAPIKeys(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	keys, err := h.apiKeyService.List(c.UserContext(), userID)
+	if err != nil {
+		return serviceError(c, err, "failed to list api keys")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"api_keys": keys,
+	})
+}
+
+// RevokeAPIKey revokes one of the user's API keys
+// DELETE /api/v1/auth/api-keys/:id
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	keyID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid api key id")
+	}
+
+	if err := h.apiKeyService.Revoke(c.UserContext(), userID, keyID); err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "api key not found")
+		}
+		return serviceError(c, err, "failed to revoke api key")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "api key revoked",
+	})
+}
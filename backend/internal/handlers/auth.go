@@ -3,13 +3,19 @@ package handlers
 
 import (
 	"errors"
+	"math"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 
 	"github.com/lightshare/backend/internal/middleware"
+	"github.com/lightshare/backend/internal/models"
 	"github.com/lightshare/backend/internal/repository"
 	"github.com/lightshare/backend/internal/services"
 	"github.com/lightshare/backend/pkg/logger"
+	"github.com/lightshare/backend/pkg/oauth"
 )
 
 // AuthHandler handles authentication endpoints
@@ -24,56 +30,42 @@ func NewAuthHandler(authService *services.AuthService) *AuthHandler {
 	}
 }
 
-// parseRequestBody parses the request body and sends an error response if parsing fails.
-// Returns true if an error occurred (and error response was sent), false otherwise.
-func parseRequestBody(c *fiber.Ctx, req interface{}) bool {
-	if err := c.BodyParser(req); err != nil {
-		_ = c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body",
-		})
-		return true
-	}
-	return false
-}
-
 // SignupRequest represents the signup request body
 type SignupRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=8"`
 }
 
 // Signup handles user signup
 func (h *AuthHandler) Signup(c *fiber.Ctx) error {
 	var req SignupRequest
-	if parseRequestBody(c, &req) {
+	if ValidateRequest(c, &req) {
 		return nil
 	}
 
+	// Get user agent and IP address
+	userAgent := c.Get("User-Agent")
+	ipAddress := c.IP()
+
 	// Call auth service
-	resp, err := h.authService.Signup(c.Context(), services.SignupRequest{
+	resp, err := h.authService.Signup(c.UserContext(), services.SignupRequest{
 		Email:    req.Email,
 		Password: req.Password,
-	})
+	}, &userAgent, &ipAddress)
 	if err != nil {
 		if errors.Is(err, services.ErrWeakPassword) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "password must be at least 8 characters",
-			})
+			return fiber.NewError(fiber.StatusBadRequest, "password must be at least 8 characters")
 		}
-		if err.Error() == "email already registered" {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"error": "email already registered",
-			})
+		if errors.Is(err, services.ErrEmailAlreadyRegistered) {
+			return fiber.NewError(fiber.StatusConflict, "email already registered")
 		}
-		if err.Error() == "invalid email address" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "invalid email address",
-			})
+		if errors.Is(err, services.ErrEmailPendingDeletion) {
+			return fiber.NewError(fiber.StatusConflict, "email belongs to a deleted account; restore it instead")
 		}
-		logger.Error("Failed to signup user", "error", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to create account",
-		})
+		if errors.Is(err, services.ErrInvalidEmail) {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid email address")
+		}
+		return serviceError(c, err, "failed to create account")
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(resp)
@@ -81,14 +73,14 @@ func (h *AuthHandler) Signup(c *fiber.Ctx) error {
 
 // LoginRequest represents the login request body
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
 }
 
 // Login handles user login
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	var req LoginRequest
-	if parseRequestBody(c, &req) {
+	if ValidateRequest(c, &req) {
 		return nil
 	}
 
@@ -97,30 +89,106 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	ipAddress := c.IP()
 
 	// Call auth service
-	resp, err := h.authService.Login(c.Context(), services.LoginRequest{
+	resp, err := h.authService.Login(c.UserContext(), services.LoginRequest{
 		Email:    req.Email,
 		Password: req.Password,
 	}, &userAgent, &ipAddress)
 	if err != nil {
+		var mfaErr *services.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			return c.Status(fiber.StatusAccepted).JSON(mfaErr)
+		}
+		var lockedErr *services.AccountLockedError
+		if errors.As(err, &lockedErr) {
+			return accountLocked(c, lockedErr)
+		}
+		var deletedErr *services.AccountDeletedError
+		if errors.As(err, &deletedErr) {
+			return writeProblem(c, newProblem(fiber.StatusForbidden, "account deleted").
+				With("can_restore_until", deletedErr.CanRestoreUntil))
+		}
 		if errors.Is(err, services.ErrInvalidCredentials) {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "invalid email or password",
-			})
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid email or password")
 		}
 		if errors.Is(err, services.ErrEmailNotVerified) {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "email not verified",
-			})
+			return fiber.NewError(fiber.StatusForbidden, "email not verified")
 		}
-		logger.Error("Failed to login user", "error", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to login",
-		})
+		return serviceError(c, err, "failed to login")
 	}
 
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
+// accountLocked responds to a login rejected because the account is locked, with
+// Retry-After set to when the lock expires
+func accountLocked(c *fiber.Ctx, lockedErr *services.AccountLockedError) error {
+	retryAfter := math.Ceil(time.Until(lockedErr.LockedUntil).Seconds())
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Max(retryAfter, 1))))
+	return writeProblem(c, newProblem(fiber.StatusTooManyRequests, lockedErr.Error()).
+		With("locked_until", lockedErr.LockedUntil))
+}
+
+// RestoreAccount undoes the deletion of the caller's account within its grace period and
+// signs them in
+// POST /api/v1/auth/restore-account
+func (h *AuthHandler) RestoreAccount(c *fiber.Ctx) error {
+	var req LoginRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	userAgent := c.Get("User-Agent")
+	ipAddress := c.IP()
+
+	resp, err := h.authService.RestoreAccount(c.UserContext(), services.LoginRequest{
+		Email:    req.Email,
+		Password: req.Password,
+	}, &userAgent, &ipAddress)
+	if err != nil {
+		var mfaErr *services.MFARequiredError
+		var lockedErr *services.AccountLockedError
+		switch {
+		case errors.As(err, &mfaErr):
+			return c.Status(fiber.StatusAccepted).JSON(mfaErr)
+		case errors.As(err, &lockedErr):
+			return accountLocked(c, lockedErr)
+		case errors.Is(err, services.ErrInvalidCredentials):
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid email or password")
+		case errors.Is(err, services.ErrAccountNotDeleted):
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		case errors.Is(err, services.ErrRestorePeriodExpired):
+			return fiber.NewError(fiber.StatusGone, err.Error())
+		case errors.Is(err, services.ErrEmailNotVerified):
+			return fiber.NewError(fiber.StatusForbidden, "email not verified")
+		}
+		return serviceError(c, err, "failed to restore account")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// UnlockAccountRequest represents the unlock account request body
+type UnlockAccountRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// UnlockAccount lifts the lock placed on an account after repeated failed logins
+// POST /api/v1/auth/unlock
+func (h *AuthHandler) UnlockAccount(c *fiber.Ctx) error {
+	var req UnlockAccountRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	if err := h.authService.UnlockAccount(c.UserContext(), req.Email); err != nil {
+		return serviceError(c, err, "failed to unlock account")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "account unlocked",
+	})
+}
+
 // VerifyEmailRequest represents the verify email request body
 type VerifyEmailRequest struct {
 	Token string `json:"token"`
@@ -129,7 +197,7 @@ type VerifyEmailRequest struct {
 // VerifyEmail handles email verification
 func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
 	var req VerifyEmailRequest
-	if parseRequestBody(c, &req) {
+	if ValidateRequest(c, &req) {
 		return nil
 	}
 
@@ -138,22 +206,48 @@ func (h *AuthHandler) VerifyEmail(c *fiber.Ctx) error {
 	ipAddress := c.IP()
 
 	// Call auth service
-	resp, err := h.authService.VerifyEmail(c.Context(), req.Token, &userAgent, &ipAddress)
+	resp, err := h.authService.VerifyEmail(c.UserContext(), req.Token, &userAgent, &ipAddress)
 	if err != nil {
+		var mfaErr *services.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			return c.Status(fiber.StatusAccepted).JSON(mfaErr)
+		}
 		if errors.Is(err, repository.ErrTokenExpired) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "verification token expired",
-			})
+			return fiber.NewError(fiber.StatusBadRequest, "verification token expired")
 		}
-		logger.Error("Failed to verify email", "error", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to verify email",
-		})
+		return serviceError(c, err, "failed to verify email")
 	}
 
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
+// ResendVerificationRequest represents the resend verification email request body
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResendVerification sends a new email verification link
+// POST /api/v1/auth/signup/resend-verification
+func (h *AuthHandler) ResendVerification(c *fiber.Ctx) error {
+	var req ResendVerificationRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	err := h.authService.ResendVerificationEmail(c.UserContext(), req.Email)
+	if errors.Is(err, services.ErrAlreadyVerified) {
+		return fiber.NewError(fiber.StatusConflict, "email already verified")
+	}
+	if err != nil {
+		logger.Error("Failed to resend verification email", "error", err)
+		// Don't reveal if email exists or not
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "if the email exists and is not verified, a verification email has been sent",
+	})
+}
+
 // MagicLinkRequest represents the magic link request body
 type MagicLinkRequest struct {
 	Email string `json:"email"`
@@ -162,12 +256,12 @@ type MagicLinkRequest struct {
 // RequestMagicLink handles magic link request
 func (h *AuthHandler) RequestMagicLink(c *fiber.Ctx) error {
 	var req MagicLinkRequest
-	if parseRequestBody(c, &req) {
+	if ValidateRequest(c, &req) {
 		return nil
 	}
 
 	// Call auth service
-	err := h.authService.RequestMagicLink(c.Context(), req.Email)
+	err := h.authService.RequestMagicLink(c.UserContext(), req.Email)
 	if err != nil {
 		logger.Error("Failed to send magic link", "error", err)
 		// Don't reveal if email exists or not
@@ -186,7 +280,7 @@ type LoginWithMagicLinkRequest struct {
 // LoginWithMagicLink handles login with magic link
 func (h *AuthHandler) LoginWithMagicLink(c *fiber.Ctx) error {
 	var req LoginWithMagicLinkRequest
-	if parseRequestBody(c, &req) {
+	if ValidateRequest(c, &req) {
 		return nil
 	}
 
@@ -195,22 +289,186 @@ func (h *AuthHandler) LoginWithMagicLink(c *fiber.Ctx) error {
 	ipAddress := c.IP()
 
 	// Call auth service
-	resp, err := h.authService.LoginWithMagicLink(c.Context(), req.Token, &userAgent, &ipAddress)
+	resp, err := h.authService.LoginWithMagicLink(c.UserContext(), req.Token, &userAgent, &ipAddress)
 	if err != nil {
-		if err.Error() == "magic link expired" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "magic link expired",
-			})
+		var mfaErr *services.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			return c.Status(fiber.StatusAccepted).JSON(mfaErr)
+		}
+		if errors.Is(err, services.ErrMagicLinkExpired) {
+			return fiber.NewError(fiber.StatusBadRequest, "magic link expired")
 		}
 		logger.Error("Failed to login with magic link", "error", err)
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "invalid magic link",
-		})
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid magic link")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
+
+// LoginWithGoogleRequest represents the Google sign-in request body
+type LoginWithGoogleRequest struct {
+	IDToken string `json:"id_token"`
+}
+
+// LoginWithGoogle handles sign-in and sign-up with a Google ID token
+// POST /api/v1/auth/oauth/google
+func (h *AuthHandler) LoginWithGoogle(c *fiber.Ctx) error {
+	var req LoginWithGoogleRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	// Get user agent and IP address
+	userAgent := c.Get("User-Agent")
+	ipAddress := c.IP()
+
+	// Call auth service
+	resp, err := h.authService.LoginWithGoogle(c.UserContext(), req.IDToken, &userAgent, &ipAddress)
+	if err != nil {
+		var mfaErr *services.MFARequiredError
+		switch {
+		case errors.As(err, &mfaErr):
+			return c.Status(fiber.StatusAccepted).JSON(mfaErr)
+		case errors.Is(err, oauth.ErrInvalidIDToken), errors.Is(err, services.ErrOAuthEmailNotVerified):
+			return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+		case errors.Is(err, services.ErrOAuthAccountConflict):
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		case errors.Is(err, services.ErrGoogleSignInDisabled):
+			return fiber.NewError(fiber.StatusNotImplemented, err.Error())
+		}
+		return serviceError(c, err, "failed to login")
 	}
 
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
+// ForgotPasswordRequest represents the forgot password request body
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// RequestPasswordReset handles forgot password requests
+func (h *AuthHandler) RequestPasswordReset(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	// Call auth service
+	err := h.authService.RequestPasswordReset(c.UserContext(), req.Email)
+	if err != nil {
+		logger.Error("Failed to send password reset", "error", err)
+		// Don't reveal if email exists or not
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "if the email exists, a password reset link has been sent",
+	})
+}
+
+// ResetPasswordRequest represents the reset password request body
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ResetPassword handles setting a new password with a reset token
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	// Call auth service
+	err := h.authService.ResetPassword(c.UserContext(), req.Token, req.Password)
+	if err != nil {
+		if errors.Is(err, services.ErrWeakPassword) {
+			return fiber.NewError(fiber.StatusBadRequest, "password must be at least 8 characters")
+		}
+		if errors.Is(err, services.ErrResetTokenExpired) {
+			return fiber.NewError(fiber.StatusBadRequest, "password reset link expired")
+		}
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid password reset link")
+		}
+		return serviceError(c, err, "failed to reset password")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "password has been reset",
+	})
+}
+
+// ChangeEmailRequest represents the change email request body
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email"`
+	Password string `json:"password"`
+}
+
+// ChangeEmail starts changing the current user's email; it changes once the new address is verified
+// POST /api/v1/auth/change-email
+func (h *AuthHandler) ChangeEmail(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req ChangeEmailRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	err = h.authService.RequestEmailChange(c.UserContext(), userID, req.NewEmail, req.Password)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid password")
+		}
+		if errors.Is(err, services.ErrInvalidEmail) {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid email address")
+		}
+		if errors.Is(err, services.ErrEmailAlreadyInUse) {
+			return fiber.NewError(fiber.StatusConflict, "email already in use")
+		}
+		return serviceError(c, err, "failed to change email")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "a verification link has been sent to the new email address",
+	})
+}
+
+// VerifyEmailChangeRequest represents the verify email change request body
+type VerifyEmailChangeRequest struct {
+	Token string `json:"token"`
+}
+
+// VerifyEmailChange completes an email change with the token sent to the new address
+// POST /api/v1/auth/change-email/verify
+func (h *AuthHandler) VerifyEmailChange(c *fiber.Ctx) error {
+	var req VerifyEmailChangeRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	err := h.authService.ConfirmEmailChange(c.UserContext(), req.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrEmailChangeTokenExpired) {
+			return fiber.NewError(fiber.StatusBadRequest, "email change link expired")
+		}
+		if errors.Is(err, services.ErrInvalidEmailChangeToken) {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid email change link")
+		}
+		if errors.Is(err, services.ErrEmailAlreadyInUse) {
+			return fiber.NewError(fiber.StatusConflict, "email already in use")
+		}
+		return serviceError(c, err, "failed to verify email change")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "email has been changed, please log in again",
+	})
+}
+
 // RefreshTokenRequest represents the refresh token request body
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token"`
@@ -219,7 +477,7 @@ type RefreshTokenRequest struct {
 // RefreshToken handles token refresh
 func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 	var req RefreshTokenRequest
-	if parseRequestBody(c, &req) {
+	if ValidateRequest(c, &req) {
 		return nil
 	}
 
@@ -228,17 +486,12 @@ func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 	ipAddress := c.IP()
 
 	// Call auth service
-	resp, err := h.authService.RefreshToken(c.Context(), req.RefreshToken, &userAgent, &ipAddress)
+	resp, err := h.authService.RefreshToken(c.UserContext(), req.RefreshToken, &userAgent, &ipAddress)
 	if err != nil {
-		if err.Error() == "invalid refresh token" || err.Error() == "refresh token revoked" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": err.Error(),
-			})
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrTokenFamilyCompromised) {
+			return fiber.NewError(fiber.StatusUnauthorized, err.Error())
 		}
-		logger.Error("Failed to refresh token", "error", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to refresh token",
-		})
+		return serviceError(c, err, "failed to refresh token")
 	}
 
 	return c.Status(fiber.StatusOK).JSON(resp)
@@ -252,12 +505,16 @@ type LogoutRequest struct {
 // Logout handles user logout
 func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 	var req LogoutRequest
-	if parseRequestBody(c, &req) {
+	if ValidateRequest(c, &req) {
 		return nil
 	}
 
+	// Get user agent and IP address
+	userAgent := c.Get("User-Agent")
+	ipAddress := c.IP()
+
 	// Call auth service
-	err := h.authService.Logout(c.Context(), req.RefreshToken)
+	err := h.authService.Logout(c.UserContext(), req.RefreshToken, &userAgent, &ipAddress)
 	if err != nil {
 		logger.Error("Failed to logout user", "error", err)
 		// Don't fail on logout errors
@@ -276,13 +533,14 @@ func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
 		return err
 	}
 
+	// Get user agent and IP address
+	userAgent := c.Get("User-Agent")
+	ipAddress := c.IP()
+
 	// Call auth service
-	err = h.authService.LogoutAll(c.Context(), userID)
+	err = h.authService.LogoutAll(c.UserContext(), userID, &userAgent, &ipAddress)
 	if err != nil {
-		logger.Error("Failed to logout all", "error", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to logout from all devices",
-		})
+		return serviceError(c, err, "failed to logout from all devices")
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -290,26 +548,309 @@ func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
 	})
 }
 
-// Me returns the current user's information
+// DeleteAccountRequest represents the delete account request body. Users without a
+// password confirm with ConfirmDelete instead.
+type DeleteAccountRequest struct {
+	Password      string `json:"password"`
+	ConfirmDelete bool   `json:"confirm_delete"`
+}
+
+// DeleteAccount deletes the current user; their data is purged after a grace period
+// DELETE /api/v1/auth/me
+func (h *AuthHandler) DeleteAccount(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req DeleteAccountRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	purgeAt, err := h.authService.DeleteAccount(c.UserContext(), userID, req.Password, req.ConfirmDelete)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid password")
+		}
+		if errors.Is(err, services.ErrDeletionNotConfirmed) {
+			return fiber.NewError(fiber.StatusBadRequest, "confirm_delete must be true to delete an account without a password")
+		}
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "user not found")
+		}
+		return serviceError(c, err, "failed to delete account")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message":  "account scheduled for deletion",
+		"purge_at": purgeAt,
+	})
+}
+
+// AuditLog returns the current user's authentication events, newest first
+// GET /api/v1/auth/audit-log?limit=50&before=<cursor>
+func (h *AuthHandler) AuditLog(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	opts := models.PaginationOptions{Cursor: c.Query("before")}
+	if rawLimit := c.Query("limit"); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil || limit <= 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "limit must be a positive integer")
+		}
+		opts.Limit = limit
+	}
+
+	page, err := h.authService.AuditLog(c.UserContext(), userID, opts)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid cursor")
+		}
+		return serviceError(c, err, "failed to get audit log")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
+// ListSessions returns the current user's active sessions, marking the one making the request
+// GET /api/v1/auth/sessions
+func (h *AuthHandler) ListSessions(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := h.authService.ListSessions(c.UserContext(), userID, middleware.GetSessionID(c))
+	if err != nil {
+		return serviceError(c, err, "failed to list sessions")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(sessions)
+}
+
+// RevokeSession logs out one of the current user's sessions
+// DELETE /api/v1/auth/sessions/:sessionId
+func (h *AuthHandler) RevokeSession(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	sessionID, err := uuid.Parse(c.Params("sessionId"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid session id")
+	}
+
+	userAgent := c.Get("User-Agent")
+	ipAddress := c.IP()
+
+	if err := h.authService.RevokeSession(c.UserContext(), userID, sessionID, &userAgent, &ipAddress); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return serviceError(c, err, "failed to revoke session")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Me returns the current user's profile
+// GET /api/v1/auth/me
 func (h *AuthHandler) Me(c *fiber.Ctx) error {
 	userID, err := middleware.GetUserID(c)
 	if err != nil {
 		return err
 	}
 
-	email, err := middleware.GetUserEmail(c)
+	profile, err := h.authService.GetProfile(c.UserContext(), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "user not found")
+		}
+		return serviceError(c, err, "failed to get profile")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(profile)
+}
+
+// UpdateProfile updates the current user's display name and notification preferences
+// PATCH /api/v1/auth/me
+func (h *AuthHandler) UpdateProfile(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req services.UpdateProfileRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	profile, err := h.authService.UpdateProfile(c.UserContext(), userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDisplayName) || errors.Is(err, services.ErrInvalidNotificationPreferences) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "user not found")
+		}
+		return serviceError(c, err, "failed to update profile")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(profile)
+}
+
+// SendTestEmail sends a test email to the current user's verified address
+func (h *AuthHandler) SendTestEmail(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	// Call auth service
+	err = h.authService.SendTestEmail(c.UserContext(), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			return fiber.NewError(fiber.StatusForbidden, "email not verified")
+		}
+		if errors.Is(err, services.ErrTestEmailRateLimited) {
+			return fiber.NewError(fiber.StatusTooManyRequests, "too many test emails requested, try again later")
+		}
+		return serviceError(c, err, "failed to send test email")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "test email queued",
+	})
+}
+
+// twoFactorError returns the response error for the two-factor code errors shared by the
+// 2FA endpoints, or nil when err is not one of them
+func twoFactorError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidTOTPCode):
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid two-factor code")
+	case errors.Is(err, services.ErrTwoFactorRateLimited):
+		return fiber.NewError(fiber.StatusTooManyRequests, "too many two-factor attempts, try again later")
+	default:
+		return nil
+	}
+}
+
+// Enroll2FA starts two-factor enrollment for the current user
+func (h *AuthHandler) Enroll2FA(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
 	if err != nil {
 		return err
 	}
 
-	role, err := middleware.GetUserRole(c)
+	// Call auth service
+	enrollment, err := h.authService.Enroll2FA(c.UserContext(), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrTwoFactorAlreadyEnabled) {
+			return fiber.NewError(fiber.StatusConflict, "two-factor authentication already enabled")
+		}
+		return serviceError(c, err, "failed to start two-factor enrollment")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(enrollment)
+}
+
+// TwoFactorCodeRequest represents a request carrying a TOTP code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// Confirm2FA confirms the first code from an enrollment and enables 2FA
+func (h *AuthHandler) Confirm2FA(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req TwoFactorCodeRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	// Call auth service
+	err = h.authService.Confirm2FA(c.UserContext(), userID, req.Code)
+	if err != nil {
+		if codeErr := twoFactorError(err); codeErr != nil {
+			return codeErr
+		}
+		if errors.Is(err, services.ErrNoPendingEnrollment) {
+			return fiber.NewError(fiber.StatusBadRequest, "no pending two-factor enrollment")
+		}
+		return serviceError(c, err, "failed to enable two-factor authentication")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "two-factor authentication enabled",
+	})
+}
+
+// Disable2FA turns off two-factor authentication for the current user
+func (h *AuthHandler) Disable2FA(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
 	if err != nil {
 		return err
 	}
 
+	var req TwoFactorCodeRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	// Call auth service
+	err = h.authService.Disable2FA(c.UserContext(), userID, req.Code)
+	if err != nil {
+		if codeErr := twoFactorError(err); codeErr != nil {
+			return codeErr
+		}
+		if errors.Is(err, services.ErrTwoFactorNotEnabled) {
+			return fiber.NewError(fiber.StatusBadRequest, "two-factor authentication not enabled")
+		}
+		return serviceError(c, err, "failed to disable two-factor authentication")
+	}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"id":    userID,
-		"email": email,
-		"role":  role,
+		"message": "two-factor authentication disabled",
 	})
 }
+
+// CompleteLogin2FARequest represents the second step of a 2FA login
+type CompleteLogin2FARequest struct {
+	MFAPendingToken string `json:"mfa_pending_token"`
+	Code            string `json:"code"`
+}
+
+// CompleteLogin2FA exchanges an MFA pending token and code for a token pair
+func (h *AuthHandler) CompleteLogin2FA(c *fiber.Ctx) error {
+	var req CompleteLogin2FARequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	// Get user agent and IP address
+	userAgent := c.Get("User-Agent")
+	ipAddress := c.IP()
+
+	// Call auth service
+	resp, err := h.authService.CompleteLogin2FA(c.UserContext(), req.MFAPendingToken, req.Code, &userAgent, &ipAddress)
+	if err != nil {
+		if codeErr := twoFactorError(err); codeErr != nil {
+			return codeErr
+		}
+		if errors.Is(err, services.ErrInvalidMFAToken) {
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid or expired mfa token")
+		}
+		return serviceError(c, err, "failed to login")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(resp)
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/middleware"
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/internal/services"
+	"github.com/lightshare/backend/pkg/jwt"
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+// stubUserRepository serves a single user, found by its magic link or verification token
+type stubUserRepository struct {
+	repository.UserRepositoryInterface
+	user *models.User
+}
+
+func (r *stubUserRepository) GetByMagicLinkToken(_ context.Context, token string) (*models.User, error) {
+	if r.user.MagicLinkToken == nil || *r.user.MagicLinkToken != token {
+		return nil, repository.ErrUserNotFound
+	}
+	return r.user, nil
+}
+
+func (r *stubUserRepository) ClearMagicLinkToken(context.Context, uuid.UUID) error {
+	r.user.MagicLinkToken = nil
+	return nil
+}
+
+func (r *stubUserRepository) GetByEmailVerificationToken(_ context.Context, token string) (*models.User, error) {
+	if r.user.EmailVerificationToken == nil || *r.user.EmailVerificationToken != token {
+		return nil, repository.ErrUserNotFound
+	}
+	return r.user, nil
+}
+
+func (r *stubUserRepository) VerifyEmail(context.Context, string) error {
+	r.user.EmailVerified = true
+	r.user.EmailVerificationToken = nil
+	return nil
+}
+
+// stubAuditRepository discards audit events, failing with err when set
+type stubAuditRepository struct {
+	repository.AuditRepositoryInterface
+	err error
+}
+
+func (r stubAuditRepository) Create(context.Context, models.AuditEvent) error {
+	return r.err
+}
+
+// newTestAuthApp serves the magic link and email verification routes for a user with 2FA
+// enabled, whose magic link token is "magic" and verification token "verify"
+func newTestAuthApp(auditRepo repository.AuditRepositoryInterface) *fiber.App {
+	magicLink, verification := "magic", "verify"
+	user := &models.User{
+		ID:                     uuid.New(),
+		Email:                  "user@example.com",
+		Role:                   "user",
+		EmailVerified:          true,
+		TOTPEnabled:            true,
+		MagicLinkToken:         &magicLink,
+		EmailVerificationToken: &verification,
+	}
+	jwtService := jwt.New(jwt.Config{Secret: "test-secret", AccessExpiration: time.Hour, RefreshExpiration: time.Hour})
+	authService := services.NewAuthService(&stubUserRepository{user: user}, nil, auditRepo, nil, jwtService, nil, nil, nil, nil, nil, nil)
+	handler := NewAuthHandler(authService)
+
+	app := fiber.New()
+	app.Use(requestid.New(), middleware.RequestIDContext())
+	app.Post("/auth/magic-link/verify", handler.LoginWithMagicLink)
+	app.Post("/auth/verify-email", handler.VerifyEmail)
+	return app
+}
+
+func TestAuthHandler_SingleFactorLoginsRequireSecondFactor(t *testing.T) {
+	testCases := map[string]string{
+		"/auth/magic-link/verify": `{"token":"magic"}`,
+		"/auth/verify-email":      `{"token":"verify"}`,
+	}
+
+	for path, body := range testCases {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+			resp, err := newTestAuthApp(stubAuditRepository{}).Test(req)
+			if err != nil {
+				t.Fatalf("Failed to test request: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			if resp.StatusCode != fiber.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+			}
+
+			var got map[string]interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if token, _ := got["mfa_pending_token"].(string); token == "" {
+				t.Errorf("Expected a pending token, got %v", got)
+			}
+			if _, ok := got["access_token"]; ok {
+				t.Error("Expected no session to be created")
+			}
+		})
+	}
+}
+
+func TestAuthHandler_ServiceLogsCarryRequestID(t *testing.T) {
+	var output bytes.Buffer
+	previous := logger.Get()
+	logger.SetDefault(slog.New(slog.NewJSONHandler(&output, nil)))
+	t.Cleanup(func() { logger.SetDefault(previous) })
+
+	// The service logs the failure to record the verification
+	app := newTestAuthApp(stubAuditRepository{err: errors.New("audit log unavailable")})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/verify-email", strings.NewReader(`{"token":"verify"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.Header.Set(fiber.HeaderXRequestID, "request-123")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	for _, line := range bytes.Split(bytes.TrimSpace(output.Bytes()), []byte("\n")) {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("Failed to decode log entry %s: %v", line, err)
+		}
+		if entry["msg"] == "Failed to record audit event" {
+			if entry["request_id"] != "request-123" {
+				t.Errorf("Expected request_id request-123 in %s", line)
+			}
+			return
+		}
+	}
+	t.Fatalf("Expected the service to log the audit failure, got %s", output.String())
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/colorconv"
+)
+
+// ColorHandler exposes color space conversion utilities
+type ColorHandler struct{}
+
+// NewColorHandler creates a new color handler
+func NewColorHandler() *ColorHandler {
+	return &ColorHandler{}
+}
+
+// ColorConversionResponse describes a color in every supported representation
+type ColorConversionResponse struct {
+	Hex        string   `json:"hex"`
+	RGB        [3]uint8 `json:"rgb"`
+	Hue        float64  `json:"hue"`
+	Saturation float64  `json:"saturation"`
+	Brightness float64  `json:"brightness"`
+}
+
+// Convert converts a color given as ?rgb=255,128,0 or ?hex=FF8000
+// GET /api/v1/color/convert
+func (h *ColorHandler) Convert(c *fiber.Ctx) error {
+	rgbParam, hexParam := c.Query("rgb"), c.Query("hex")
+	if (rgbParam == "") == (hexParam == "") {
+		return fiber.NewError(fiber.StatusBadRequest, "exactly one of 'rgb' or 'hex' is required")
+	}
+
+	var hue, saturation, brightness float64
+	if rgbParam != "" {
+		r, g, b, ok := parseRGB(rgbParam)
+		if !ok {
+			return fiber.NewError(fiber.StatusBadRequest, "rgb must be three comma separated values between 0 and 255")
+		}
+		hue, saturation, brightness = colorconv.RGBToHSB(r, g, b)
+	} else {
+		var err error
+		hue, saturation, brightness, err = colorconv.HexToHSB(hexParam)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "hex must be a #RRGGBB or #RGB color")
+		}
+	}
+
+	r, g, b := colorconv.HSBToRGB(hue, saturation, brightness)
+	return c.Status(fiber.StatusOK).JSON(ColorConversionResponse{
+		Hex:        colorconv.HSBToHex(hue, saturation, brightness),
+		RGB:        [3]uint8{r, g, b},
+		Hue:        hue,
+		Saturation: saturation,
+		Brightness: brightness,
+	})
+}
+
+// GetHarmony returns a palette of colors in harmony with a hue. saturation and brightness
+// apply to every color and default to 1.0 and 0.8; count defaults to the mode's own palette.
+// GET /api/v1/color/harmony?hue=30&mode=complementary|triadic|analogous|split_complementary&count=3
+func (h *ColorHandler) GetHarmony(c *fiber.Ctx) error {
+	req := models.HarmonyRequest{Mode: c.Query("mode")}
+
+	var err error
+	if req.Hue, err = strconv.ParseFloat(c.Query("hue"), 64); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "hue must be a number between 0 and 360")
+	}
+	if value := c.Query("count"); value != "" {
+		if req.Count, err = strconv.Atoi(value); err != nil || req.Count < 1 {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("count must be between 1 and %d", models.MaxHarmonyColors))
+		}
+	}
+	if req.Saturation, err = optionalFloatQuery(c, "saturation"); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "saturation must be a number between 0.0 and 1.0")
+	}
+	if req.Brightness, err = optionalFloatQuery(c, "brightness"); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "brightness must be a number between 0.0 and 1.0")
+	}
+
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	palette, err := req.Palette()
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return c.Status(fiber.StatusOK).JSON(palette)
+}
+
+// optionalFloatQuery parses the query parameter name, returning nil when it is not set
+func optionalFloatQuery(c *fiber.Ctx, name string) (*float64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// parseRGB parses "r,g,b" with each component between 0 and 255
+func parseRGB(value string) (r, g, b uint8, ok bool) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 3 {
+		return 0, 0, 0, false
+	}
+
+	var components [3]uint8
+	for i, part := range parts {
+		component, err := strconv.ParseUint(strings.TrimSpace(part), 10, 8)
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		components[i] = uint8(component)
+	}
+	return components[0], components[1], components[2], true
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/lightshare/backend/internal/models"
+)
+
+func TestColorConvert(t *testing.T) {
+	app := fiber.New()
+	app.Get("/color/convert", NewColorHandler().Convert)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantHex    string
+		wantHue    float64
+		wantStatus int
+	}{
+		{"rgb", "rgb=255,128,0", "#FF8000", 30.12, fiber.StatusOK},
+		{"rgb with spaces", "rgb=0,%200,%20255", "#0000FF", 240, fiber.StatusOK},
+		{"hex", "hex=FF8000", "#FF8000", 30.12, fiber.StatusOK},
+		{"short hex", "hex=%23fff", "#FFFFFF", 0, fiber.StatusOK},
+		{"missing", "", "", 0, fiber.StatusBadRequest},
+		{"both", "rgb=0,0,0&hex=000000", "", 0, fiber.StatusBadRequest},
+		{"rgb out of range", "rgb=256,0,0", "", 0, fiber.StatusBadRequest},
+		{"rgb too short", "rgb=255,0", "", 0, fiber.StatusBadRequest},
+		{"invalid hex", "hex=XYZXYZ", "", 0, fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/color/convert?"+tt.query, http.NoBody)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to test request: %v", err)
+			}
+			defer func() {
+				if err := resp.Body.Close(); err != nil {
+					t.Errorf("Failed to close response body: %v", err)
+				}
+			}()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			if tt.wantStatus != fiber.StatusOK {
+				return
+			}
+
+			var body ColorConversionResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if body.Hex != tt.wantHex {
+				t.Errorf("Expected hex %s, got %s", tt.wantHex, body.Hex)
+			}
+			if body.Hue < tt.wantHue-0.01 || body.Hue > tt.wantHue+0.01 {
+				t.Errorf("Expected hue %.2f, got %.2f", tt.wantHue, body.Hue)
+			}
+		})
+	}
+}
+
+func TestColorHarmony(t *testing.T) {
+	app := fiber.New()
+	app.Get("/color/harmony", NewColorHandler().GetHarmony)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantHues   []float64
+		wantHex    string
+		wantStatus int
+	}{
+		{"complementary", "hue=30&mode=complementary", []float64{30, 210}, "#CC6600", fiber.StatusOK},
+		{"triadic with count", "hue=0&mode=triadic&count=2&brightness=1", []float64{0, 120}, "#FF0000", fiber.StatusOK},
+		{"analogous", "hue=0&mode=analogous&count=3&saturation=0", []float64{330, 0, 30}, "#CCCCCC", fiber.StatusOK},
+		{"missing hue", "mode=triadic", nil, "", fiber.StatusBadRequest},
+		{"hue out of range", "hue=400&mode=triadic", nil, "", fiber.StatusBadRequest},
+		{"unknown mode", "hue=30&mode=monochrome", nil, "", fiber.StatusBadRequest},
+		{"zero count", "hue=30&mode=analogous&count=0", nil, "", fiber.StatusBadRequest},
+		{"invalid saturation", "hue=30&mode=triadic&saturation=2", nil, "", fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/color/harmony?"+tt.query, http.NoBody)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to test request: %v", err)
+			}
+			defer func() {
+				if err := resp.Body.Close(); err != nil {
+					t.Errorf("Failed to close response body: %v", err)
+				}
+			}()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			if tt.wantStatus != fiber.StatusOK {
+				return
+			}
+
+			var palette []models.HarmonyColor
+			if err := json.NewDecoder(resp.Body).Decode(&palette); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(palette) != len(tt.wantHues) {
+				t.Fatalf("Expected %d colors, got %d", len(tt.wantHues), len(palette))
+			}
+			for i, color := range palette {
+				if color.Hue < tt.wantHues[i]-0.01 || color.Hue > tt.wantHues[i]+0.01 {
+					t.Errorf("Expected hue %.2f, got %.2f", tt.wantHues[i], color.Hue)
+				}
+			}
+			if palette[0].Hex != tt.wantHex {
+				t.Errorf("Expected hex %s, got %s", tt.wantHex, palette[0].Hex)
+			}
+		})
+	}
+}
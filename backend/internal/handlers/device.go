@@ -1,18 +1,24 @@
 package handlers
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/lightshare/backend/internal/models"
 	"github.com/lightshare/backend/internal/services"
+	"github.com/lightshare/backend/pkg/providers"
 )
 
-// Error message constants
-const (
-	errAccountNotFound    = "account not found: account not found"
-	errUnauthorizedAccess = "unauthorized: user does not own this account"
-	errRateLimitExceeded  = "rate limit exceeded: max 30 requests per minute"
-)
+// sseHeartbeatInterval is how often an idle device event stream sends a ping, which also
+// detects disconnected clients
+const sseHeartbeatInterval = 15 * time.Second
 
 // DeviceHandler handles device-related HTTP requests
 type DeviceHandler struct {
@@ -26,7 +32,8 @@ func NewDeviceHandler(deviceService *services.DeviceService) *DeviceHandler {
 	}
 }
 
-// ListDevices lists all devices for the authenticated user
+// ListDevices lists all devices for the authenticated user. ?sort=health_asc&limit=5
+// lists the lights having the most issues.
 // GET /api/v1/devices
 func (h *DeviceHandler) ListDevices(c *fiber.Ctx) error {
 	userID, ok := c.Locals("user_id").(uuid.UUID)
@@ -34,14 +41,33 @@ func (h *DeviceHandler) ListDevices(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
 	}
 
-	devices, err := h.deviceService.ListDevices(c.Context(), userID.String())
+	opts, err := parsePagination(c)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to list devices")
+		return err
 	}
 
-	return c.JSON(fiber.Map{
-		"devices": devices,
-	})
+	page, err := h.deviceService.ListDevices(c.UserContext(), userID.String(), opts)
+	if err != nil {
+		return serviceError(c, err, "failed to list devices")
+	}
+
+	return c.JSON(presentDevicePage(c, page))
+}
+
+// GetDeviceSummary aggregates the state of all devices of the authenticated user
+// GET /api/v1/devices/summary
+func (h *DeviceHandler) GetDeviceSummary(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	summary, err := h.deviceService.GetDeviceSummary(c.UserContext(), userID.String())
+	if err != nil {
+		return serviceError(c, err, "failed to summarize devices")
+	}
+
+	return c.JSON(summary)
 }
 
 // ListAccountDevices lists devices for a specific account
@@ -56,21 +82,56 @@ func (h *DeviceHandler) ListAccountDevices(c *fiber.Ctx) error {
 	if accountID == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
 	}
+	defer h.setRateLimitHeaders(c, accountID)
 
-	devices, err := h.deviceService.ListAccountDevices(c.Context(), userID.String(), accountID)
+	opts, err := parsePagination(c)
 	if err != nil {
-		if err.Error() == errAccountNotFound {
-			return fiber.NewError(fiber.StatusNotFound, "account not found")
-		}
-		if err.Error() == errUnauthorizedAccess {
-			return fiber.NewError(fiber.StatusForbidden, "unauthorized")
+		return err
+	}
+
+	filter, err := parseDeviceFilter(c)
+	if err != nil {
+		return err
+	}
+
+	page, err := h.deviceService.ListAccountDevices(c.UserContext(), userID.String(), accountID, filter, opts)
+	if err != nil {
+		if errors.Is(err, services.ErrAccountTokenInvalid) {
+			return writeProblem(c, serviceProblem(err, fiber.StatusFailedDependency, "provider token is invalid").
+				With("reconnect_url", "/api/v1/accounts/"+accountID+"/reconnect"))
 		}
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to list devices")
+		return serviceError(c, err, "failed to list devices")
 	}
 
-	return c.JSON(fiber.Map{
-		"devices": devices,
-	})
+	etag := page.ETag
+	if etag == "" {
+		etag = computeETag(page.Devices)
+	}
+	c.Set(fiber.HeaderETag, `"`+etag+`"`)
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	return c.JSON(presentDevicePage(c, page))
+}
+
+// computeETag returns the ETag of a device list, a hash of its JSON
+func computeETag(devices []*models.Device) string {
+	return models.DevicesETag(devices)
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, or is "*"
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || strings.Trim(candidate, `"`) == etag {
+			return true
+		}
+	}
+	return false
 }
 
 // GetDevice returns a specific device
@@ -90,25 +151,119 @@ func (h *DeviceHandler) GetDevice(c *fiber.Ctx) error {
 	if deviceID == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "device ID is required")
 	}
+	defer h.setRateLimitHeaders(c, accountID)
 
-	device, err := h.deviceService.GetDevice(c.Context(), userID.String(), accountID, deviceID)
+	device, err := h.deviceService.GetDevice(c.UserContext(), userID.String(), accountID, deviceID)
 	if err != nil {
-		if err.Error() == errAccountNotFound {
-			return fiber.NewError(fiber.StatusNotFound, "account not found")
-		}
-		if err.Error() == errUnauthorizedAccess {
-			return fiber.NewError(fiber.StatusForbidden, "unauthorized")
-		}
-		if err.Error() == errRateLimitExceeded {
-			return fiber.NewError(fiber.StatusTooManyRequests, "rate limit exceeded")
+		return serviceError(c, err, "failed to get device")
+	}
+
+	return c.JSON(presentDevice(c, device))
+}
+
+// GetDeviceState returns a device's state from the cached device list or, with live=true,
+// fetched from the provider, which also refreshes the device's cached entry
+// GET /api/v1/accounts/:accountId/devices/:deviceId/state?live=true
+func (h *DeviceHandler) GetDeviceState(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	deviceID := c.Params("deviceId")
+
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+	if deviceID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "device ID is required")
+	}
+	defer h.setRateLimitHeaders(c, accountID)
+
+	getDevice := h.deviceService.GetCachedDevice
+	if c.QueryBool("live") {
+		getDevice = h.deviceService.GetLiveDevice
+	}
+
+	device, err := getDevice(c.UserContext(), userID.String(), accountID, deviceID)
+	if err != nil {
+		return serviceError(c, err, "failed to get device state")
+	}
+
+	return c.JSON(presentDevice(c, device))
+}
+
+// GetDeviceHistory returns a timeline of a device's state changes, oldest first. from and to
+// are RFC 3339 times or dates; a date to includes the whole day.
+// GET /api/v1/accounts/:accountId/devices/:deviceId/history?from=2024-01-01&to=2024-01-31&limit=100
+func (h *DeviceHandler) GetDeviceHistory(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	deviceID := c.Params("deviceId")
+
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+	if deviceID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "device ID is required")
+	}
+
+	from, err := parseHistoryTime(c.Query("from"), false)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "from must be an RFC 3339 time or a YYYY-MM-DD date")
+	}
+	to, err := parseHistoryTime(c.Query("to"), true)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "to must be an RFC 3339 time or a YYYY-MM-DD date")
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return fiber.NewError(fiber.StatusBadRequest, "from must be before to")
+	}
+
+	// Limits above models.MaxDeviceHistoryLimit are clamped
+	limit := models.DefaultDeviceHistoryLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return fiber.NewError(fiber.StatusBadRequest, "limit must be a positive integer")
 		}
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to get device")
 	}
 
-	return c.JSON(device)
+	history, err := h.deviceService.GetDeviceHistory(c.UserContext(), userID.String(), accountID, deviceID, from, to, limit)
+	if err != nil {
+		return serviceError(c, err, "failed to get device history")
+	}
+
+	return c.JSON(history)
 }
 
-// ExecuteAction executes a control action on device(s)
+// parseHistoryTime parses an RFC 3339 time or a YYYY-MM-DD date (UTC midnight). With
+// endOfRange, a date means the end of that day. An empty value is the zero time.
+func parseHistoryTime(value string, endOfRange bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfRange {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date, nil
+}
+
+// ExecuteAction executes a control action on device(s). With ?preflight=true the target
+// device's capabilities are checked before the provider is called.
 // POST /api/v1/accounts/:accountId/devices/:selector/action
 func (h *DeviceHandler) ExecuteAction(c *fiber.Ctx) error {
 	userID, ok := c.Locals("user_id").(uuid.UUID)
@@ -125,29 +280,59 @@ func (h *DeviceHandler) ExecuteAction(c *fiber.Ctx) error {
 	if selector == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "selector is required")
 	}
+	defer h.setRateLimitHeaders(c, accountID)
 
 	var action models.ActionRequest
-	if err := c.BodyParser(&action); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	if ValidateRequest(c, &action) {
+		return nil
 	}
+	// Parameters are validated by the service, once those left out are filled in from
+	// the user's preferences
+	action.PreFlight = c.QueryBool("preflight")
 
-	// Validate action
-	if err := action.ValidateParameters(); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	if key := c.Get("Idempotency-Key"); key != "" {
+		if _, err := uuid.Parse(key); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Idempotency-Key must be a UUID")
+		}
+		action.IdempotencyKey = key
 	}
 
-	err := h.deviceService.ExecuteAction(c.Context(), userID.String(), accountID, selector, &action)
+	err := h.deviceService.ExecuteAction(c.UserContext(), userID.String(), accountID, selector, &action)
 	if err != nil {
-		if err.Error() == errAccountNotFound {
-			return fiber.NewError(fiber.StatusNotFound, "account not found")
+		var deferredErr *services.ActionDeferredError
+		if errors.As(err, &deferredErr) {
+			return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+				"success":      true,
+				"deferred":     true,
+				"scheduled_at": deferredErr.ScheduledAt.UTC().Format(time.RFC3339),
+				"schedule_id":  deferredErr.ScheduleID,
+				"message":      "provider rate limit reached, action deferred",
+			})
 		}
-		if err.Error() == errUnauthorizedAccess {
-			return fiber.NewError(fiber.StatusForbidden, "unauthorized")
+		var replayedErr *services.ActionReplayedError
+		if errors.As(err, &replayedErr) {
+			c.Set("X-Idempotency-Cached", "true")
+			if !replayedErr.Success {
+				return c.JSON(fiber.Map{
+					"success": false,
+					"error":   replayedErr.Message,
+				})
+			}
+			return c.JSON(fiber.Map{
+				"success": true,
+				"message": "action executed successfully",
+			})
 		}
-		if err.Error() == errRateLimitExceeded {
-			return fiber.NewError(fiber.StatusTooManyRequests, "rate limit exceeded")
+		var partialErr *providers.PartialSuccessError
+		if errors.As(err, &partialErr) {
+			return c.Status(fiber.StatusMultiStatus).JSON(fiber.Map{
+				"success":   false,
+				"partial":   true,
+				"succeeded": partialErr.Succeeded,
+				"failed":    partialErr.Failed,
+			})
 		}
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to execute action")
+		return serviceError(c, err, "failed to execute action")
 	}
 
 	return c.JSON(fiber.Map{
@@ -156,6 +341,319 @@ func (h *DeviceHandler) ExecuteAction(c *fiber.Ctx) error {
 	})
 }
 
+// BulkExecuteAction applies several state actions, each to its own selector, in one request
+// POST /api/v1/accounts/:accountId/devices/bulk-action
+func (h *DeviceHandler) BulkExecuteAction(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+	defer h.setRateLimitHeaders(c, accountID)
+
+	var req models.BulkActionRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	// Validate actions
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	result, err := h.deviceService.BulkExecuteAction(c.UserContext(), userID.String(), accountID, req.Actions)
+	if err != nil {
+		return serviceError(c, err, "failed to execute bulk action")
+	}
+
+	status := fiber.StatusOK
+	switch {
+	case len(result.Failed) > 0 && len(result.Succeeded) > 0:
+		status = fiber.StatusMultiStatus
+	case len(result.Failed) > 0:
+		status = fiber.StatusBadGateway
+	}
+
+	return c.Status(status).JSON(result)
+}
+
+// ExecuteGroupAction applies an action to every device in a group, by name, across all of
+// the user's accounts
+// POST /api/v1/devices/group-action
+func (h *DeviceHandler) ExecuteGroupAction(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	var req models.GroupActionRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	result, err := h.deviceService.ExecuteGroupAction(c.UserContext(), userID.String(), req.GroupName, &req.Action)
+	if err != nil {
+		return serviceError(c, err, "failed to execute group action")
+	}
+
+	status := fiber.StatusOK
+	switch {
+	case len(result.FailedAccounts) > 0 && len(result.SucceededAccounts) > 0:
+		status = fiber.StatusMultiStatus
+	case len(result.FailedAccounts) > 0:
+		status = fiber.StatusBadGateway
+	}
+
+	return c.Status(status).JSON(result)
+}
+
+// ApplyHarmony sets the color lights of an account, in alphabetical order, to a harmony palette
+// POST /api/v1/accounts/:accountId/devices/apply-harmony
+func (h *DeviceHandler) ApplyHarmony(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+	defer h.setRateLimitHeaders(c, accountID)
+
+	var req models.ApplyHarmonyRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	result, err := h.deviceService.ApplyHarmony(c.UserContext(), userID.String(), accountID, &req)
+	if err != nil {
+		return serviceError(c, err, "failed to apply color harmony")
+	}
+
+	status := fiber.StatusOK
+	switch {
+	case len(result.Failed) > 0 && len(result.Succeeded) > 0:
+		status = fiber.StatusMultiStatus
+	case len(result.Failed) > 0:
+		status = fiber.StatusBadGateway
+	}
+
+	return c.Status(status).JSON(result)
+}
+
+// ApplyPalette spreads a palette across the lights a selector targets, in alphabetical order
+// POST /api/v1/color/apply-palette
+func (h *DeviceHandler) ApplyPalette(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	var req models.ApplyPaletteRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+	defer h.setRateLimitHeaders(c, req.AccountID)
+
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	result, err := h.deviceService.ApplyPalette(c.UserContext(), userID.String(), &req)
+	if err != nil {
+		return serviceError(c, err, "failed to apply palette")
+	}
+
+	status := fiber.StatusOK
+	switch {
+	case result.Failed > 0 && result.AppliedTo > 0:
+		status = fiber.StatusMultiStatus
+	case result.Failed > 0:
+		status = fiber.StatusBadGateway
+	}
+
+	return c.Status(status).JSON(result)
+}
+
+// StartAlarm starts a brightness ramp on device(s), turning them on dimmed and bringing
+// them up to the target brightness over ramp_minutes
+// POST /api/v1/accounts/:accountId/devices/:selector/alarm
+func (h *DeviceHandler) StartAlarm(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	selector := c.Params("selector")
+
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+	if selector == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "selector is required")
+	}
+	defer h.setRateLimitHeaders(c, accountID)
+
+	var req models.RampRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := h.deviceService.ExecuteRamp(c.UserContext(), userID.String(), accountID, selector, &req); err != nil {
+		return serviceError(c, err, "failed to start alarm")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"selector": selector,
+		"ends_at":  time.Now().Add(req.Duration()).UTC(),
+	})
+}
+
+// CancelAlarm stops the brightness ramp running on device(s), leaving them as they are
+// DELETE /api/v1/accounts/:accountId/devices/:selector/alarm
+func (h *DeviceHandler) CancelAlarm(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	selector := c.Params("selector")
+
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+	if selector == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "selector is required")
+	}
+
+	if err := h.deviceService.CancelRamp(c.UserContext(), userID.String(), accountID, selector); err != nil {
+		return serviceError(c, err, "failed to cancel alarm")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ColorSync sets the lights target_selector targets to the current color of a leader
+// device. With ?continuous=true the leader is re-read every ?interval (default 5s) and
+// each round is streamed as Server-Sent Events until the client disconnects or the sync
+// is stopped.
+// POST /api/v1/accounts/:accountId/devices/color-sync
+func (h *DeviceHandler) ColorSync(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+	defer h.setRateLimitHeaders(c, accountID)
+
+	var req models.ColorSyncRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	if !c.QueryBool("continuous") {
+		if err := h.deviceService.ColorSync(c.UserContext(), userID.String(), accountID, req); err != nil {
+			return serviceError(c, err, "failed to sync colors")
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	interval := models.DefaultColorSyncInterval
+	if raw := c.Query("interval"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "interval must be a duration, such as 5s")
+		}
+		interval = parsed
+	}
+
+	updates, stop, err := h.deviceService.StartColorSync(c.UserContext(), userID.String(), accountID, req, interval)
+	if err != nil {
+		return serviceError(c, err, "failed to start color sync")
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// A client going away stops the sync
+		defer stop()
+		writeColorSyncUpdates(w, updates, sseHeartbeatInterval)
+	})
+	return nil
+}
+
+// StopColorSync stops the continuous color sync of an account
+// DELETE /api/v1/accounts/:accountId/devices/color-sync
+func (h *DeviceHandler) StopColorSync(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+
+	if err := h.deviceService.StopColorSync(c.UserContext(), userID.String(), accountID); err != nil {
+		return serviceError(c, err, "failed to stop color sync")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// writeColorSyncUpdates writes each sync round as an SSE data message, and a ping event
+// whenever no round was written for a heartbeat interval. It returns when updates is
+// closed or the client disconnects.
+func writeColorSyncUpdates(w *bufio.Writer, updates <-chan models.ColorSyncUpdate, heartbeat time.Duration) {
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(update)
+			if err != nil {
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+			ticker.Reset(heartbeat)
+		case <-ticker.C:
+			_, _ = w.WriteString("event: ping\n\n")
+		}
+
+		// Flushing fails once the client has gone away
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
 // RefreshDevices forces a cache refresh for an account
 // POST /api/v1/accounts/:accountId/devices/refresh
 func (h *DeviceHandler) RefreshDevices(c *fiber.Ctx) error {
@@ -168,19 +666,204 @@ func (h *DeviceHandler) RefreshDevices(c *fiber.Ctx) error {
 	if accountID == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
 	}
+	defer h.setRateLimitHeaders(c, accountID)
 
-	devices, err := h.deviceService.RefreshDevices(c.Context(), userID.String(), accountID)
+	discovery, err := h.deviceService.DiscoverDevices(c.UserContext(), userID.String(), accountID)
 	if err != nil {
-		if err.Error() == "account not found: account not found" {
-			return fiber.NewError(fiber.StatusNotFound, "account not found")
-		}
-		if err.Error() == "unauthorized: user does not own this account" {
-			return fiber.NewError(fiber.StatusForbidden, "unauthorized")
-		}
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to refresh devices")
+		return serviceError(c, err, "failed to refresh devices")
 	}
 
 	return c.JSON(fiber.Map{
-		"devices": devices,
+		"devices": presentDevices(c, discovery.Devices),
+		"added":   presentDevices(c, discovery.Added),
+		"removed": presentDevices(c, discovery.Removed),
 	})
 }
+
+// StreamDeviceEvents streams an account's device state changes as Server-Sent Events
+// GET /api/v1/accounts/:accountId/devices/events
+func (h *DeviceHandler) StreamDeviceEvents(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+
+	changes, unsubscribe, err := h.deviceService.SubscribeDeviceChanges(c.UserContext(), userID.String(), accountID)
+	if err != nil {
+		return serviceError(c, err, "failed to stream device events")
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+		writeDeviceEvents(w, changes, sseHeartbeatInterval)
+	})
+	return nil
+}
+
+// writeDeviceEvents writes each change as an SSE data message, and a ping event whenever
+// no change was written for a heartbeat interval. It returns when changes is closed or
+// the client disconnects.
+func writeDeviceEvents(w *bufio.Writer, changes <-chan models.DeviceChangeEvent, heartbeat time.Duration) {
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(change)
+			if err != nil {
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+			ticker.Reset(heartbeat)
+		case <-ticker.C:
+			_, _ = w.WriteString("event: ping\n\n")
+		}
+
+		// Flushing fails once the client has gone away
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// AccountStatus reports whether an account's provider token is still valid, along with
+// its provider circuit breaker status. Invalid tokens are reported in the body with 200 OK.
+// GET /api/v1/accounts/:accountId/status
+func (h *DeviceHandler) AccountStatus(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+
+	status, err := h.deviceService.CheckAccountStatus(c.UserContext(), userID.String(), accountID)
+	if err != nil {
+		return serviceError(c, err, "failed to get account status")
+	}
+
+	return c.JSON(status)
+}
+
+// rateLimitExceeded responds 429 with Retry-After and X-RateLimit-* headers describing the exhausted limit
+func rateLimitExceeded(c *fiber.Ctx, err error) error {
+	var limitErr *services.RateLimitExceededError
+	if errors.As(err, &limitErr) {
+		retryAfter := time.Duration(limitErr.RetryAfterSeconds) * time.Second
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(limitErr.RetryAfterSeconds))
+		c.Set("X-RateLimit-Limit", strconv.Itoa(limitErr.Limit))
+		c.Set("X-RateLimit-Remaining", "0")
+		c.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(retryAfter).Unix(), 10))
+	}
+	return RespondProblem(c, serviceProblem(err, fiber.StatusTooManyRequests, "rate limit exceeded"), err)
+}
+
+// setRateLimitHeaders exposes the account's remaining read and write budgets
+func (h *DeviceHandler) setRateLimitHeaders(c *fiber.Ctx, accountID string) {
+	read, write, err := h.deviceService.RateLimitStatus(c.UserContext(), accountID)
+	if err != nil {
+		return
+	}
+
+	c.Set("X-RateLimit-Read-Limit", strconv.Itoa(read.Limit))
+	c.Set("X-RateLimit-Read-Remaining", strconv.Itoa(read.Remaining))
+	c.Set("X-RateLimit-Write-Limit", strconv.Itoa(write.Limit))
+	c.Set("X-RateLimit-Write-Remaining", strconv.Itoa(write.Remaining))
+}
+
+// includeRawPayload reports whether the client opted into provider-native payloads via ?include=raw
+func includeRawPayload(c *fiber.Ctx) bool {
+	for _, field := range strings.Split(c.Query("include"), ",") {
+		if strings.TrimSpace(field) == "raw" {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePagination reads ?limit= and ?after= for cursor-paginated listings
+// Limits above models.MaxPageLimit are clamped
+func parsePagination(c *fiber.Ctx) (models.PaginationOptions, error) {
+	opts := models.PaginationOptions{
+		Cursor: c.Query("after"),
+		Limit:  models.DefaultPageLimit,
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return opts, fiber.NewError(fiber.StatusBadRequest, "limit must be a positive integer")
+		}
+		opts.Limit = limit
+	}
+
+	opts.Sort = c.Query("sort")
+	if !models.ValidDeviceSort(opts.Sort) {
+		return opts, fiber.NewError(fiber.StatusBadRequest, "sort must be 'health_asc' or 'health_desc'")
+	}
+
+	return opts, nil
+}
+
+// parseDeviceFilter reads the label, group, location, power and capability query parameters
+func parseDeviceFilter(c *fiber.Ctx) (models.DeviceFilter, error) {
+	filter := models.DeviceFilter{
+		LabelContains: c.Query("label"),
+		GroupName:     c.Query("group"),
+		LocationName:  c.Query("location"),
+		PowerState:    c.Query("power"),
+		Capability:    c.Query("capability"),
+	}
+
+	if filter.PowerState != "" && filter.PowerState != models.PowerStateOn && filter.PowerState != models.PowerStateOff {
+		return filter, fiber.NewError(fiber.StatusBadRequest, "power must be 'on' or 'off'")
+	}
+
+	return filter, nil
+}
+
+// presentDevicePage applies presentDevices to a page of devices and replaces the errors of
+// the accounts it is missing with their client-safe message
+func presentDevicePage(c *fiber.Ctx, page *models.DevicePage) *models.DevicePage {
+	page.Devices = presentDevices(c, page.Devices)
+	for i := range page.Errors {
+		_, page.Errors[i].Error = MapServiceError(page.Errors[i].Cause)
+	}
+	return page
+}
+
+// presentDevice applies presentDevices to a single device
+func presentDevice(c *fiber.Ctx, device *models.Device) *models.Device {
+	return presentDevices(c, []*models.Device{device})[0]
+}
+
+// presentDevices strips provider-native payloads unless the client explicitly requested
+// them. Stripped devices are copies, leaving the service's devices intact.
+func presentDevices(c *fiber.Ctx, devices []*models.Device) []*models.Device {
+	if includeRawPayload(c) {
+		return devices
+	}
+
+	presented := make([]*models.Device, len(devices))
+	for i, device := range devices {
+		presented[i] = device.WithoutRawPayload()
+	}
+	return presented
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/services"
+)
+
+// DeviceLabelHandler handles users' nicknames for their devices
+type DeviceLabelHandler struct {
+	deviceService *services.DeviceService
+	labelService  *services.DeviceLabelService
+}
+
+// NewDeviceLabelHandler creates a new device label handler
+func NewDeviceLabelHandler(deviceService *services.DeviceService, labelService *services.DeviceLabelService) *DeviceLabelHandler {
+	return &DeviceLabelHandler{
+		deviceService: deviceService,
+		labelService:  labelService,
+	}
+}
+
+// SetDeviceLabel replaces the label of a device with the user's own and returns the device
+// PATCH /api/v1/accounts/:accountId/devices/:deviceId/label
+func (h *DeviceLabelHandler) SetDeviceLabel(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	deviceID := c.Params("deviceId")
+
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+	if deviceID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "device ID is required")
+	}
+
+	var req models.SetDeviceLabelRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	// Resolve the device's provider, which also checks the account belongs to the user
+	device, err := h.deviceService.GetCachedDevice(c.UserContext(), userID.String(), accountID, deviceID)
+	if err != nil {
+		return serviceError(c, err, "failed to get device")
+	}
+
+	if _, err := h.labelService.SetLabel(c.UserContext(), userID, device.Provider, deviceID, req.Label); err != nil {
+		return serviceError(c, err, "failed to set device label")
+	}
+
+	device, err = h.deviceService.GetCachedDevice(c.UserContext(), userID.String(), accountID, deviceID)
+	if err != nil {
+		return serviceError(c, err, "failed to get device")
+	}
+
+	return c.JSON(presentDevice(c, device))
+}
+
+// DeleteDeviceLabel removes the user's label of a device, restoring the provider's
+// DELETE /api/v1/accounts/:accountId/devices/:deviceId/label
+func (h *DeviceLabelHandler) DeleteDeviceLabel(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	deviceID := c.Params("deviceId")
+
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+	if deviceID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "device ID is required")
+	}
+
+	device, err := h.deviceService.GetCachedDevice(c.UserContext(), userID.String(), accountID, deviceID)
+	if err != nil {
+		return serviceError(c, err, "failed to get device")
+	}
+
+	if err := h.labelService.DeleteLabel(c.UserContext(), userID, device.Provider, deviceID); err != nil {
+		return serviceError(c, err, "failed to delete device label")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,431 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/internal/services"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+	"github.com/lightshare/backend/pkg/providers/mock"
+	"github.com/lightshare/backend/pkg/ratelimit"
+)
+
+// newDeviceFixture returns a device carrying a provider-native payload
+func newDeviceFixture() *models.Device {
+	return &models.Device{
+		ID:    "d073d5000001",
+		Label: "Kitchen",
+		Metadata: map[string]interface{}{
+			models.MetadataRawKey: json.RawMessage(`{"product":{"name":"LIFX A19"}}`),
+		},
+	}
+}
+
+func TestPresentDevices_RawOnlyWhenRequested(t *testing.T) {
+	app := fiber.New()
+	app.Get("/devices", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"devices": presentDevices(c, []*models.Device{newDeviceFixture()}),
+		})
+	})
+
+	testCases := []struct {
+		name    string
+		url     string
+		wantRaw bool
+	}{
+		{name: "default", url: "/devices", wantRaw: false},
+		{name: "other include", url: "/devices?include=group", wantRaw: false},
+		{name: "raw requested", url: "/devices?include=raw", wantRaw: true},
+		{name: "raw in list", url: "/devices?include=group,raw", wantRaw: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tc.url, http.NoBody))
+			if err != nil {
+				t.Fatalf("Failed to test request: %v", err)
+			}
+			defer func() {
+				if err := resp.Body.Close(); err != nil {
+					t.Errorf("Failed to close response body: %v", err)
+				}
+			}()
+
+			var body struct {
+				Devices []models.Device `json:"devices"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			_, hasRaw := body.Devices[0].Metadata[models.MetadataRawKey]
+			if hasRaw != tc.wantRaw {
+				t.Errorf("Expected raw present=%v, got %v", tc.wantRaw, hasRaw)
+			}
+		})
+	}
+}
+
+func TestPresentDevicePage_ClientSafeProviderErrors(t *testing.T) {
+	app := fiber.New()
+	app.Get("/devices", func(c *fiber.Ctx) error {
+		return c.JSON(presentDevicePage(c, &models.DevicePage{Errors: []models.ProviderError{
+			{AccountID: "hue-account", Cause: &apierror.ProviderError{Provider: "hue", Cause: errors.New("dial tcp 10.0.0.12:443: connection refused")}},
+			{AccountID: "lifx-account", Cause: services.ErrAccountTokenInvalid},
+		}}))
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/devices", http.NoBody))
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var body models.DevicePage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body.Errors) != 2 || body.Errors[0].Error != "provider request failed" || body.Errors[1].Error != "provider token is invalid" {
+		t.Errorf("Expected the client-safe messages of the errors, got %+v", body.Errors)
+	}
+}
+
+func TestParseDeviceFilter(t *testing.T) {
+	app := fiber.New()
+	app.Get("/devices", func(c *fiber.Ctx) error {
+		filter, err := parseDeviceFilter(c)
+		if err != nil {
+			return err
+		}
+		return c.JSON(filter)
+	})
+
+	testCases := []struct {
+		name       string
+		url        string
+		want       models.DeviceFilter
+		wantStatus int
+	}{
+		{name: "empty", url: "/devices", wantStatus: fiber.StatusOK},
+		{
+			name:       "all fields",
+			url:        "/devices?label=kitchen&group=living+room&location=Home&power=on&capability=color",
+			want:       models.DeviceFilter{LabelContains: "kitchen", GroupName: "living room", LocationName: "Home", PowerState: "on", Capability: "color"},
+			wantStatus: fiber.StatusOK,
+		},
+		{name: "invalid power", url: "/devices?power=dim", wantStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tc.url, http.NoBody))
+			if err != nil {
+				t.Fatalf("Failed to test request: %v", err)
+			}
+			defer func() {
+				if err := resp.Body.Close(); err != nil {
+					t.Errorf("Failed to close response body: %v", err)
+				}
+			}()
+
+			if resp.StatusCode != tc.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tc.wantStatus, resp.StatusCode)
+			}
+			if tc.wantStatus != fiber.StatusOK {
+				return
+			}
+
+			var filter models.DeviceFilter
+			if err := json.NewDecoder(resp.Body).Decode(&filter); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if filter != tc.want {
+				t.Errorf("Expected %+v, got %+v", tc.want, filter)
+			}
+		})
+	}
+}
+
+func TestParseHistoryTime(t *testing.T) {
+	testCases := []struct {
+		want       time.Time
+		value      string
+		endOfRange bool
+		wantErr    bool
+	}{
+		{value: ""},
+		{value: "2024-01-01", want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-01-31", endOfRange: true, want: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-01-31T08:30:00Z", endOfRange: true, want: time.Date(2024, 1, 31, 8, 30, 0, 0, time.UTC)},
+		{value: "last week", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		got, err := parseHistoryTime(tc.value, tc.endOfRange)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseHistoryTime(%q) error = %v, wantErr %v", tc.value, err, tc.wantErr)
+			continue
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("parseHistoryTime(%q, %v) = %v, want %v", tc.value, tc.endOfRange, got, tc.want)
+		}
+	}
+}
+
+func TestWriteDeviceEvents(t *testing.T) {
+	var buf bytes.Buffer
+	changes := make(chan models.DeviceChangeEvent, 1)
+	changes <- models.DeviceChangeEvent{ID: "bulb-1", ChangedFields: map[string]interface{}{"power": "on"}}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(changes)
+	}()
+	writeDeviceEvents(bufio.NewWriter(&buf), changes, 10*time.Millisecond)
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "data: {\"changed_fields\":{\"power\":\"on\"},\"id\":\"bulb-1\"}\n\n") {
+		t.Errorf("Expected change as the first message, got %q", out)
+	}
+	if !strings.Contains(out, "event: ping\n\n") {
+		t.Errorf("Expected heartbeat ping while idle, got %q", out)
+	}
+}
+
+// stubAccountRepository serves a single account; its other methods are not implemented
+type stubAccountRepository struct {
+	repository.AccountRepositoryInterface
+	account *models.Account
+}
+
+func (s *stubAccountRepository) FindByIDString(context.Context, string) (*models.Account, error) {
+	return s.account, nil
+}
+
+func TestListAccountDevices_ConditionalGet(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	userID := uuid.New()
+	account := &models.Account{ID: uuid.New(), OwnerUserID: userID, Provider: "lifx"}
+	deviceService := services.NewDeviceService(&stubAccountRepository{account: account}, cache, nil, services.DeviceServiceConfig{CacheTTL: time.Minute})
+	handler := NewDeviceHandler(deviceService)
+
+	// Serve the devices from cache, so the provider is never called
+	cacheDevices := func(devices ...*models.Device) {
+		data, _ := json.Marshal(fiber.Map{"devices": devices, "cached_at": float64(time.Now().Unix())})
+		if err := mr.Set("devices:account:"+account.ID.String(), string(data)); err != nil {
+			t.Fatalf("Failed to cache devices: %v", err)
+		}
+	}
+	cacheDevices(newDeviceFixture())
+
+	app := fiber.New()
+	app.Get("/accounts/:accountId/devices", func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		return handler.ListAccountDevices(c)
+	})
+
+	list := func(ifNoneMatch string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "/accounts/"+account.ID.String()+"/devices", http.NoBody)
+		if ifNoneMatch != "" {
+			req.Header.Set(fiber.HeaderIfNoneMatch, ifNoneMatch)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	first := list("")
+	etag := first.Header.Get(fiber.HeaderETag)
+	if first.StatusCode != fiber.StatusOK || len(etag) != 18 {
+		t.Fatalf("Expected 200 with a quoted 16-character ETag, got %d with %q", first.StatusCode, etag)
+	}
+
+	second := list(etag)
+	if second.StatusCode != fiber.StatusNotModified {
+		t.Fatalf("Expected 304 for a matching If-None-Match, got %d", second.StatusCode)
+	}
+	var body bytes.Buffer
+	if _, _ = body.ReadFrom(second.Body); body.Len() > 0 {
+		t.Errorf("Expected no body with 304, got %q", body.String())
+	}
+
+	// Once the list changes, the old ETag no longer matches
+	renamed := newDeviceFixture()
+	renamed.Label = "Pantry"
+	cacheDevices(renamed)
+
+	third := list(etag)
+	if third.StatusCode != fiber.StatusOK || third.Header.Get(fiber.HeaderETag) == etag {
+		t.Errorf("Expected 200 with a new ETag after the list changed, got %d with %q", third.StatusCode, third.Header.Get(fiber.HeaderETag))
+	}
+}
+
+// GetDecryptedToken returns a fixed token for the stub's account
+func (s *stubAccountRepository) GetDecryptedToken(context.Context, string) (string, error) {
+	return "test-token", nil
+}
+
+func TestExecuteAction_ProviderErrorStatus(t *testing.T) {
+	testCases := []struct {
+		powerErr   error
+		name       string
+		wantType   string
+		wantStatus int
+	}{
+		{
+			name:       "provider failure",
+			powerErr:   errors.New("bulb unreachable"),
+			wantStatus: fiber.StatusBadGateway,
+			wantType:   apierror.TypeProviderError,
+		},
+		{
+			name:       "provider rate limit",
+			powerErr:   &providers.RateLimitError{Provider: providers.ProviderLIFX, RetryAfter: 2 * time.Second},
+			wantStatus: fiber.StatusTooManyRequests,
+			wantType:   apierror.TypeProviderRateLimited,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+			client := mock.NewMockClient()
+			client.SetPowerFn = func(_, _ string, _ bool, _ float64) error {
+				return tc.powerErr
+			}
+
+			userID := uuid.New()
+			account := &models.Account{ID: uuid.New(), OwnerUserID: userID, Provider: "lifx"}
+			deviceService := services.NewDeviceService(&stubAccountRepository{account: account}, cache, nil, services.DeviceServiceConfig{
+				CacheTTL: time.Minute,
+				RateLimits: services.RateLimits{
+					Read:  ratelimit.Limit{PerMinute: 30},
+					Write: ratelimit.Limit{PerMinute: 30},
+				},
+				NewClient: func(providers.Provider) (providers.Client, error) {
+					return client, nil
+				},
+			})
+			handler := NewDeviceHandler(deviceService)
+
+			app := fiber.New()
+			app.Post("/accounts/:accountId/devices/:selector/action", func(c *fiber.Ctx) error {
+				c.Locals("user_id", userID)
+				return handler.ExecuteAction(c)
+			})
+
+			body := strings.NewReader(`{"action":"power","parameters":{"state":"on"}}`)
+			req := httptest.NewRequest(http.MethodPost, "/accounts/"+account.ID.String()+"/devices/all/action", body)
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to test request: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			var problem apierror.ProblemDetails
+			if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+				t.Fatalf("Failed to decode problem: %v", err)
+			}
+			if resp.StatusCode != tc.wantStatus || problem.Type != tc.wantType {
+				t.Errorf("Expected %d %s, got %d %s", tc.wantStatus, tc.wantType, resp.StatusCode, problem.Type)
+			}
+			if client.CallCount("SetPower") != 1 {
+				t.Errorf("Expected one SetPower call, got %d", client.CallCount("SetPower"))
+			}
+		})
+	}
+}
+
+func TestListAccountDevices_ConcurrentMixedRawRequests(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	// The provider answers slowly, so the concurrent cache misses share one fetch
+	client := mock.NewMockClient()
+	client.ListDevicesFn = func(string) ([]*providers.Device, error) {
+		time.Sleep(50 * time.Millisecond)
+		return []*providers.Device{{ID: "d1", Label: "Kitchen", Raw: json.RawMessage(`{"id":"d1"}`)}}, nil
+	}
+
+	userID := uuid.New()
+	account := &models.Account{ID: uuid.New(), OwnerUserID: userID, Provider: "lifx"}
+	deviceService := services.NewDeviceService(&stubAccountRepository{account: account}, cache, nil, services.DeviceServiceConfig{
+		CacheTTL:   time.Minute,
+		RateLimits: services.RateLimits{Read: ratelimit.Limit{PerMinute: 100}},
+		NewClient: func(providers.Provider) (providers.Client, error) {
+			return client, nil
+		},
+	})
+	handler := NewDeviceHandler(deviceService)
+
+	app := fiber.New()
+	app.Get("/accounts/:accountId/devices", func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		return handler.ListAccountDevices(c)
+	})
+
+	const requests = 8
+	hasRaw := make([]bool, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			url := "/accounts/" + account.ID.String() + "/devices"
+			if i%2 == 0 {
+				url += "?include=raw"
+			}
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, url, http.NoBody), -1)
+			if err != nil {
+				t.Errorf("Failed to test request: %v", err)
+				return
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			var body struct {
+				Devices []models.Device `json:"devices"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || len(body.Devices) != 1 {
+				t.Errorf("Expected one device, got %d (%v)", len(body.Devices), err)
+				return
+			}
+			_, hasRaw[i] = body.Devices[0].Metadata[models.MetadataRawKey]
+		}()
+	}
+	wg.Wait()
+
+	for i, raw := range hasRaw {
+		if raw != (i%2 == 0) {
+			t.Errorf("Request %d: expected raw present=%v, got %v", i, i%2 == 0, raw)
+		}
+	}
+	if calls := client.CallCount("ListDevices"); calls != 1 {
+		t.Errorf("Expected the requests to share one provider fetch, got %d", calls)
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/services"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// MapServiceError maps an error returned by a service to an HTTP status and a message safe
+// to show clients. Errors of no known type map to 500 Internal Server Error.
+func MapServiceError(err error) (int, string) {
+	var badRequestErr *apierror.BadRequestError
+	var rateLimitedErr *apierror.RateLimitedError
+	var providerErr *apierror.ProviderError
+	var capabilityErr *services.CapabilityError
+
+	switch {
+	case errors.As(err, &badRequestErr):
+		return fiber.StatusBadRequest, badRequestErr.Message
+	case errors.Is(err, models.ErrInvalidCursor):
+		return fiber.StatusBadRequest, "invalid cursor"
+	case errors.Is(err, services.ErrInvalidSceneRequest):
+		return fiber.StatusBadRequest, err.Error()
+	case errors.Is(err, providers.ErrProviderDisabled):
+		return fiber.StatusBadRequest, "provider is disabled"
+	case errors.Is(err, apierror.ErrNotFound):
+		return fiber.StatusNotFound, kindMessage(err, "not found")
+	case errors.Is(err, apierror.ErrForbidden):
+		return fiber.StatusForbidden, kindMessage(err, "forbidden")
+	case errors.Is(err, apierror.ErrConflict):
+		return fiber.StatusConflict, kindMessage(err, "conflict")
+	case errors.As(err, &capabilityErr):
+		return fiber.StatusUnprocessableEntity, capabilityErr.Error()
+	case errors.Is(err, providers.ErrCapabilityNotSupported):
+		return fiber.StatusUnprocessableEntity, "device does not support this action"
+	case errors.Is(err, services.ErrAccountTokenInvalid):
+		return fiber.StatusFailedDependency, "provider token is invalid"
+	case errors.Is(err, services.ErrProviderCircuitOpen):
+		return fiber.StatusServiceUnavailable, "provider temporarily unavailable"
+	case errors.As(err, &rateLimitedErr):
+		if errors.As(err, &providerErr) {
+			return fiber.StatusTooManyRequests, "provider rate limit exceeded"
+		}
+		return fiber.StatusTooManyRequests, "rate limit exceeded"
+	case errors.As(err, &providerErr):
+		return fiber.StatusBadGateway, "provider request failed"
+	default:
+		return fiber.StatusInternalServerError, "internal server error"
+	}
+}
+
+// kindMessage returns the message of the apierror.Error in err's chain, or fallback
+func kindMessage(err error, fallback string) string {
+	var kindErr *apierror.Error
+	if errors.As(err, &kindErr) {
+		return kindErr.Message
+	}
+	return fallback
+}
+
+// serviceError responds to an error returned by a service with a problem, using fallback
+// as the detail of unexpected errors. Rate limited responses carry Retry-After, and
+// X-RateLimit-* headers when one of our own limits was hit.
+func serviceError(c *fiber.Ctx, err error, fallback string) error {
+	if respondNotImplemented(c, err) {
+		return nil
+	}
+	if errors.Is(err, services.ErrRateLimitExceeded) {
+		return rateLimitExceeded(c, err)
+	}
+
+	status, message := MapServiceError(err)
+	if status == fiber.StatusInternalServerError {
+		message = fallback
+	}
+
+	var rateLimitedErr *apierror.RateLimitedError
+	if errors.As(err, &rateLimitedErr) && rateLimitedErr.RetryAfter > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(rateLimitedErr.RetryAfter.Seconds()))))
+	}
+
+	return RespondProblem(c, serviceProblem(err, status, message), err)
+}
+
+// respondNotImplemented writes a 501 problem naming the provider and operation when err is
+// a providers.NotImplementedError. Returns true if the response was written.
+func respondNotImplemented(c *fiber.Ctx, err error) bool {
+	var notImplErr *providers.NotImplementedError
+	if !errors.As(err, &notImplErr) {
+		return false
+	}
+
+	_ = writeProblem(c, serviceProblem(err, fiber.StatusNotImplemented, notImplErr.Error()))
+	return true
+}
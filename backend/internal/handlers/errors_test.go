@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/internal/services"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+func TestMapServiceError(t *testing.T) {
+	tests := []struct {
+		err         error
+		name        string
+		wantMessage string
+		wantStatus  int
+	}{
+		{
+			name:        "not found",
+			err:         fmt.Errorf("account not found: %w", repository.ErrAccountNotFound),
+			wantStatus:  fiber.StatusNotFound,
+			wantMessage: "account not found",
+		},
+		{
+			name:        "service not found",
+			err:         services.ErrLocationNotFound,
+			wantStatus:  fiber.StatusNotFound,
+			wantMessage: "location not found",
+		},
+		{
+			name:        "forbidden",
+			err:         services.ErrAccountNotOwned,
+			wantStatus:  fiber.StatusForbidden,
+			wantMessage: "account not owned by user",
+		},
+		{
+			name:        "provider disabled",
+			err:         fmt.Errorf("failed to create provider client: %w", providers.ErrProviderDisabled),
+			wantStatus:  fiber.StatusBadRequest,
+			wantMessage: "provider is disabled",
+		},
+		{
+			name:        "bad request",
+			err:         &apierror.BadRequestError{Message: "invalid account ID"},
+			wantStatus:  fiber.StatusBadRequest,
+			wantMessage: "invalid account ID",
+		},
+		{
+			name:        "invalid cursor",
+			err:         models.ErrInvalidCursor,
+			wantStatus:  fiber.StatusBadRequest,
+			wantMessage: "invalid cursor",
+		},
+		{
+			name:        "rate limited",
+			err:         &apierror.RateLimitedError{RetryAfter: time.Second},
+			wantStatus:  fiber.StatusTooManyRequests,
+			wantMessage: "rate limit exceeded",
+		},
+		{
+			name: "provider rate limited",
+			err: &apierror.RateLimitedError{
+				Cause: &apierror.ProviderError{Provider: "lifx", Cause: &providers.RateLimitError{RetryAfter: time.Second}},
+			},
+			wantStatus:  fiber.StatusTooManyRequests,
+			wantMessage: "provider rate limit exceeded",
+		},
+		{
+			name:        "provider error",
+			err:         fmt.Errorf("failed to get device: %w", &apierror.ProviderError{Provider: "hue", Cause: errors.New("bridge unreachable")}),
+			wantStatus:  fiber.StatusBadGateway,
+			wantMessage: "provider request failed",
+		},
+		{
+			name:        "circuit open",
+			err:         services.ErrProviderCircuitOpen,
+			wantStatus:  fiber.StatusServiceUnavailable,
+			wantMessage: "provider temporarily unavailable",
+		},
+		{
+			name:        "capability not supported",
+			err:         fmt.Errorf("preflight failed: %w", &services.CapabilityError{Capability: "color"}),
+			wantStatus:  fiber.StatusUnprocessableEntity,
+			wantMessage: "device does not support color",
+		},
+		{
+			name:        "unknown",
+			err:         errors.New("connection refused"),
+			wantStatus:  fiber.StatusInternalServerError,
+			wantMessage: "internal server error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, message := MapServiceError(tt.err)
+			if status != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, status)
+			}
+			if message != tt.wantMessage {
+				t.Errorf("Expected message %q, got %q", tt.wantMessage, message)
+			}
+		})
+	}
+}
+
+func TestServiceError_SetsRetryAfter(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return serviceError(c, &apierror.RateLimitedError{RetryAfter: 1500 * time.Millisecond}, "failed")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", http.NoBody))
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Errorf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", resp.StatusCode)
+	}
+	if retryAfter := resp.Header.Get(fiber.HeaderRetryAfter); retryAfter != "2" {
+		t.Errorf("Expected Retry-After 2, got %q", retryAfter)
+	}
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/lightshare/backend/internal/config"
+)
+
+// FeaturesResponse represents the feature flags response
+type FeaturesResponse struct {
+	Providers []string `json:"providers"`
+	Scenes    bool     `json:"scenes"`
+	Webhooks  bool     `json:"webhooks"`
+}
+
+// Features returns the feature flags handler. The flags are loaded on every request, so
+// they reflect the current configuration without a restart.
+func Features(load func() config.FeaturesConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		features := load()
+		return c.JSON(FeaturesResponse{
+			Providers: features.EnabledProviders,
+			Scenes:    features.EnableScenes,
+			Webhooks:  features.EnableWebhooks,
+		})
+	}
+}
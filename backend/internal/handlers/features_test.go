@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/lightshare/backend/internal/config"
+)
+
+func TestFeatures_ReflectsCurrentEnvironment(t *testing.T) {
+	app := fiber.New()
+	app.Get("/features", Features(config.LoadFeatures))
+
+	getFeatures := func() FeaturesResponse {
+		t.Helper()
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/features", http.NoBody))
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		defer func() {
+			if err := resp.Body.Close(); err != nil {
+				t.Errorf("Failed to close response body: %v", err)
+			}
+		}()
+
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		var body FeaturesResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return body
+	}
+
+	t.Setenv("FEATURE_ENABLED_PROVIDERS", "lifx")
+	t.Setenv("FEATURE_SCENES", "true")
+	t.Setenv("FEATURE_WEBHOOKS", "false")
+
+	body := getFeatures()
+	if !slices.Equal(body.Providers, []string{"lifx"}) || !body.Scenes || body.Webhooks {
+		t.Errorf("Expected lifx only with scenes and without webhooks, got %+v", body)
+	}
+
+	// The flags are re-read on every request
+	t.Setenv("FEATURE_ENABLED_PROVIDERS", "lifx,hue")
+	t.Setenv("FEATURE_WEBHOOKS", "true")
+
+	body = getFeatures()
+	if !slices.Equal(body.Providers, []string{"lifx", "hue"}) || !body.Webhooks {
+		t.Errorf("Expected lifx and hue with webhooks, got %+v", body)
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// ListGroups lists the groups of an account, with the number of devices in each
+// GET /api/v1/accounts/:accountId/groups
+func (h *DeviceHandler) ListGroups(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+
+	groups, err := h.deviceService.ListGroups(c.UserContext(), userID.String(), accountID)
+	if err != nil {
+		return serviceError(c, err, "failed to list groups")
+	}
+
+	return c.JSON(fiber.Map{
+		"groups": groups,
+	})
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"context"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"golang.org/x/sync/errgroup"
 )
 
 // HealthResponse represents the health check response
@@ -24,43 +26,122 @@ func Health(version string) fiber.Handler {
 	}
 }
 
+// readyCheckTimeout bounds each dependency check of a readiness request
+const readyCheckTimeout = 2 * time.Second
+
+// DatabaseHealthChecker is implemented by *database.DB
+type DatabaseHealthChecker interface {
+	Health() error
+}
+
+// RedisHealthChecker is implemented by *redis.Client
+type RedisHealthChecker interface {
+	Health(ctx context.Context) error
+}
+
+// ReadinessSLO holds the latency above which a dependency is reported degraded. A zero
+// latency disables the check for that dependency.
+type ReadinessSLO struct {
+	Database time.Duration
+	Redis    time.Duration
+}
+
+// DependencyCheck is the result of checking a single dependency
+type DependencyCheck struct {
+	Status    string `json:"status"` // "ok" or "error: <msg>"
+	LatencyMS int64  `json:"latency_ms"`
+	Degraded  bool   `json:"degraded,omitempty"` // Responded, but slower than its SLO
+}
+
 // ReadyResponse represents the readiness check response
 type ReadyResponse struct {
-	Checks map[string]string `json:"checks"`
-	Status string            `json:"status"`
-	Ready  bool              `json:"ready"`
+	Checks   map[string]DependencyCheck `json:"checks"`
+	Status   string                     `json:"status"` // "ready", "degraded" or "not_ready"
+	Ready    bool                       `json:"ready"`
+	Degraded bool                       `json:"degraded,omitempty"`
 }
 
-// Ready returns the readiness check handler
-// This will be extended to check database and Redis connections
-func Ready() fiber.Handler {
+// Ready returns the readiness check handler, which pings the database and Redis
+// concurrently. A dependency that fails or takes longer than 2s makes the instance not
+// ready (503); one slower than its SLO only marks it degraded, still served with a 200 so
+// load balancers keep a slow but working instance in rotation.
+func Ready(db DatabaseHealthChecker, redisClient RedisHealthChecker, slo ReadinessSLO) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		checks := map[string]string{
-			"database": "ok",
-			"redis":    "ok",
-		}
+		ctx := c.UserContext()
 
-		// TODO: Add actual health checks for database and Redis
+		var database, cache DependencyCheck
+		var g errgroup.Group
+		g.Go(func() error {
+			database = runDependencyCheck(ctx, slo.Database, func(context.Context) error { return db.Health() })
+			return nil
+		})
+		g.Go(func() error {
+			cache = runDependencyCheck(ctx, slo.Redis, redisClient.Health)
+			return nil
+		})
+		_ = g.Wait()
 
-		allHealthy := true
-		for _, status := range checks {
-			if status != "ok" {
-				allHealthy = false
-				break
-			}
+		checks := map[string]DependencyCheck{
+			"database": database,
+			"redis":    cache,
 		}
 
 		response := ReadyResponse{
 			Status: "ready",
 			Checks: checks,
-			Ready:  allHealthy,
+			Ready:  true,
+		}
+		for _, check := range checks {
+			if check.Status != "ok" {
+				response.Ready = false
+			}
+			if check.Degraded {
+				response.Degraded = true
+			}
 		}
 
-		if !allHealthy {
+		switch {
+		case !response.Ready:
 			response.Status = "not_ready"
+			response.Degraded = false
 			return c.Status(fiber.StatusServiceUnavailable).JSON(response)
+		case response.Degraded:
+			response.Status = "degraded"
 		}
 
 		return c.JSON(response)
 	}
 }
+
+// runDependencyCheck times a dependency check, giving up after readyCheckTimeout or once
+// ctx is done. A check that succeeds slower than slo is marked degraded.
+func runDependencyCheck(ctx context.Context, slo time.Duration, check func(context.Context) error) DependencyCheck {
+	ctx, cancel := context.WithTimeout(ctx, readyCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	latency := time.Since(start)
+	result := DependencyCheck{
+		Status:    "ok",
+		LatencyMS: latency.Milliseconds(),
+	}
+	if err != nil {
+		result.Status = "error: " + err.Error()
+		return result
+	}
+	result.Degraded = slo > 0 && latency > slo
+	return result
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -43,9 +46,36 @@ func TestHealth(t *testing.T) {
 	}
 }
 
+// stubDatabase is a DatabaseHealthChecker returning a fixed error after a delay
+type stubDatabase struct {
+	err   error
+	delay time.Duration
+}
+
+func (s *stubDatabase) Health() error {
+	time.Sleep(s.delay)
+	return s.err
+}
+
+// stubRedis is a RedisHealthChecker returning a fixed error after a delay, or once ctx
+// is done
+type stubRedis struct {
+	err   error
+	delay time.Duration
+}
+
+func (s *stubRedis) Health(ctx context.Context) error {
+	select {
+	case <-time.After(s.delay):
+		return s.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func TestReady(t *testing.T) {
 	app := fiber.New()
-	app.Get("/ready", Ready())
+	app.Get("/ready", Ready(&stubDatabase{}, &stubRedis{}, ReadinessSLO{}))
 
 	req := httptest.NewRequest("GET", "/ready", http.NoBody)
 	resp, err := app.Test(req)
@@ -75,4 +105,143 @@ func TestReady(t *testing.T) {
 	if !body.Ready {
 		t.Error("Expected ready to be true")
 	}
+
+	if body.Checks["database"].Status != "ok" || body.Checks["redis"].Status != "ok" {
+		t.Errorf("Expected both checks ok, got %+v", body.Checks)
+	}
+}
+
+func TestReady_DatabaseDown(t *testing.T) {
+	app := fiber.New()
+	app.Get("/ready", Ready(&stubDatabase{err: errors.New("connection refused")}, &stubRedis{}, ReadinessSLO{}))
+
+	req := httptest.NewRequest("GET", "/ready", http.NoBody)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Errorf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != fiber.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", resp.StatusCode)
+	}
+
+	var body ReadyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if body.Ready || body.Status != "not_ready" {
+		t.Errorf("Expected not_ready, got '%s' (ready=%v)", body.Status, body.Ready)
+	}
+
+	if got := body.Checks["database"].Status; got != "error: connection refused" {
+		t.Errorf("Expected database check 'error: connection refused', got '%s'", got)
+	}
+
+	if got := body.Checks["redis"].Status; got != "ok" {
+		t.Errorf("Expected redis check 'ok', got '%s'", got)
+	}
+}
+
+func TestReady_LatencySLO(t *testing.T) {
+	slo := ReadinessSLO{Database: 50 * time.Millisecond, Redis: 20 * time.Millisecond}
+
+	testCases := []struct {
+		db           *stubDatabase
+		redis        *stubRedis
+		name         string
+		wantStatus   string
+		wantCode     int
+		wantDegraded bool
+		wantReady    bool
+	}{
+		{
+			name:       "within SLOs",
+			db:         &stubDatabase{},
+			redis:      &stubRedis{},
+			wantCode:   fiber.StatusOK,
+			wantStatus: "ready",
+			wantReady:  true,
+		},
+		{
+			name:         "slow database",
+			db:           &stubDatabase{delay: 80 * time.Millisecond},
+			redis:        &stubRedis{},
+			wantCode:     fiber.StatusOK,
+			wantStatus:   "degraded",
+			wantReady:    true,
+			wantDegraded: true,
+		},
+		{
+			name:         "slow redis",
+			db:           &stubDatabase{},
+			redis:        &stubRedis{delay: 40 * time.Millisecond},
+			wantCode:     fiber.StatusOK,
+			wantStatus:   "degraded",
+			wantReady:    true,
+			wantDegraded: true,
+		},
+		{
+			name:       "redis past the check timeout",
+			db:         &stubDatabase{},
+			redis:      &stubRedis{delay: time.Minute},
+			wantCode:   fiber.StatusServiceUnavailable,
+			wantStatus: "not_ready",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/ready", Ready(tc.db, tc.redis, slo))
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/ready", http.NoBody), -1)
+			if err != nil {
+				t.Fatalf("Failed to test request: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			if resp.StatusCode != tc.wantCode {
+				t.Errorf("Expected status %d, got %d", tc.wantCode, resp.StatusCode)
+			}
+
+			var body ReadyResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if body.Status != tc.wantStatus || body.Ready != tc.wantReady || body.Degraded != tc.wantDegraded {
+				t.Errorf("Expected %s (ready=%v, degraded=%v), got %+v", tc.wantStatus, tc.wantReady, tc.wantDegraded, body)
+			}
+		})
+	}
+}
+
+func TestReady_ChecksRunConcurrently(t *testing.T) {
+	app := fiber.New()
+	app.Get("/ready", Ready(&stubDatabase{delay: 100 * time.Millisecond}, &stubRedis{delay: 100 * time.Millisecond}, ReadinessSLO{}))
+
+	start := time.Now()
+	resp, err := app.Test(httptest.NewRequest("GET", "/ready", http.NoBody), -1)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if elapsed := time.Since(start); elapsed >= 190*time.Millisecond {
+		t.Errorf("Expected both checks to run concurrently, took %s", elapsed)
+	}
+
+	var body ReadyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Checks["database"].LatencyMS < 100 || body.Checks["redis"].LatencyMS < 100 {
+		t.Errorf("Expected each latency to be recorded, got %+v", body.Checks)
+	}
 }
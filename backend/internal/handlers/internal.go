@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/internal/services"
+	"github.com/lightshare/backend/pkg/jobs"
+	"github.com/lightshare/backend/pkg/logger"
+	"github.com/lightshare/backend/pkg/metrics"
+)
+
+// TokenCleaner runs a single token cleanup
+type TokenCleaner interface {
+	Run(ctx context.Context) jobs.CleanupResult
+}
+
+// CleanupResponse reports what a token cleanup removed. It carries the job's counts along
+// with the refresh tokens and users deleted under the names cron jobs and admins expect.
+type CleanupResponse struct {
+	jobs.CleanupResult
+	DeletedRefreshTokens int `json:"deleted_refresh_tokens"`
+	DeletedUsers         int `json:"deleted_users"`
+}
+
+// newCleanupResponse wraps the result of a cleanup run in a CleanupResponse
+func newCleanupResponse(result jobs.CleanupResult) CleanupResponse {
+	return CleanupResponse{
+		CleanupResult:        result,
+		DeletedRefreshTokens: result.RefreshTokens,
+		DeletedUsers:         result.DeletedAccounts,
+	}
+}
+
+// InternalHandler handles management endpoints called by other services
+type InternalHandler struct {
+	authService  *services.AuthService
+	tokenCleaner TokenCleaner
+	metrics      *metrics.Metrics
+}
+
+// NewInternalHandler creates a new internal handler
+func NewInternalHandler(authService *services.AuthService, tokenCleaner TokenCleaner, m *metrics.Metrics) *InternalHandler {
+	return &InternalHandler{
+		authService:  authService,
+		tokenCleaner: tokenCleaner,
+		metrics:      m,
+	}
+}
+
+// GetUser returns any user's profile, for debugging
+// GET /internal/users/:id
+func (h *InternalHandler) GetUser(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid user ID")
+	}
+
+	profile, err := h.authService.GetProfile(c.UserContext(), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "user not found")
+		}
+		return serviceError(c, err, "failed to get user")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(profile)
+}
+
+// CleanupTokens runs the token cleanup job now and returns what it removed
+// POST /internal/jobs/cleanup-tokens
+func (h *InternalHandler) CleanupTokens(c *fiber.Ctx) error {
+	result := h.tokenCleaner.Run(c.UserContext())
+	logger.Info("Token cleanup triggered",
+		"refresh_tokens", result.RefreshTokens,
+		"magic_links", result.MagicLinks,
+		"deleted_accounts", result.DeletedAccounts,
+		"ratelimit_keys", result.RateLimitKeys,
+		"status_keys", result.StatusKeys,
+	)
+
+	return c.Status(fiber.StatusOK).JSON(newCleanupResponse(result))
+}
+
+// MetricsSummary returns the application counters summed over their labels
+// GET /internal/metrics/summary
+func (h *InternalHandler) MetricsSummary(c *fiber.Ctx) error {
+	summary, err := h.metrics.Summary()
+	if err != nil {
+		return serviceError(c, err, "failed to summarize metrics")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(summary)
+}
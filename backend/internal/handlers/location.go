@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/lightshare/backend/internal/models"
+)
+
+// ListLocations lists the locations of an account
+// GET /api/v1/accounts/:accountId/locations
+func (h *DeviceHandler) ListLocations(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+
+	locations, err := h.deviceService.ListLocations(c.UserContext(), userID.String(), accountID)
+	if err != nil {
+		return serviceError(c, err, "failed to list locations")
+	}
+
+	return c.JSON(fiber.Map{
+		"locations": locations,
+	})
+}
+
+// ApplyLocationState applies a combined state to every device in a location
+// POST /api/v1/accounts/:accountId/locations/:locationId/state
+func (h *DeviceHandler) ApplyLocationState(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	locationID := c.Params("locationId")
+
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+	if locationID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "location ID is required")
+	}
+	defer h.setRateLimitHeaders(c, accountID)
+
+	var state models.StateRequest
+	if ValidateRequest(c, &state) {
+		return nil
+	}
+
+	// Validate state
+	if _, err := state.Actions(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	result, err := h.deviceService.ApplyLocationState(c.UserContext(), userID.String(), accountID, locationID, &state)
+	if err != nil {
+		return serviceError(c, err, "failed to apply location state")
+	}
+
+	status := fiber.StatusOK
+	switch {
+	case result.Partial():
+		status = fiber.StatusMultiStatus
+	case result.Succeeded == 0:
+		status = fiber.StatusBadGateway
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"success": result.Failed == 0,
+		"partial": result.Partial(),
+		"results": result.Results,
+	})
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/services"
+)
+
+// PreferencesHandler handles the user's device action preferences
+type PreferencesHandler struct {
+	preferencesService *services.UserPreferencesService
+}
+
+// NewPreferencesHandler creates a new preferences handler
+func NewPreferencesHandler(preferencesService *services.UserPreferencesService) *PreferencesHandler {
+	return &PreferencesHandler{
+		preferencesService: preferencesService,
+	}
+}
+
+// GetPreferences returns the current user's preferences
+// GET /api/v1/preferences
+func (h *PreferencesHandler) GetPreferences(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	prefs, err := h.preferencesService.Get(c.UserContext(), userID)
+	if err != nil {
+		return serviceError(c, err, "failed to get preferences")
+	}
+
+	return c.JSON(prefs)
+}
+
+// UpdatePreferences replaces the current user's preferences; omitted preferences are unset
+// PUT /api/v1/preferences
+func (h *PreferencesHandler) UpdatePreferences(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	var req models.UpdatePreferencesRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	prefs, err := h.preferencesService.Update(c.UserContext(), userID, req)
+	if err != nil {
+		return serviceError(c, err, "failed to update preferences")
+	}
+
+	return c.JSON(prefs)
+}
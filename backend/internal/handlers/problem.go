@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/lightshare/backend/internal/services"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/logger"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// ProblemFromError describes any error a handler returned as a problem: problems are
+// returned as is, Fiber errors keep their status and message, and service errors are
+// mapped with MapServiceError
+func ProblemFromError(err error) *apierror.ProblemDetails {
+	var problem *apierror.ProblemDetails
+	if errors.As(err, &problem) {
+		return problem
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return newProblem(fiberErr.Code, fiberErr.Message)
+	}
+
+	status, message := MapServiceError(err)
+	return serviceProblem(err, status, message)
+}
+
+// serviceProblem describes an error returned by a service, whose status and detail come
+// from MapServiceError. Typed errors get their own problem type and carry what clients need
+// to handle them as extensions.
+func serviceProblem(err error, status int, detail string) *apierror.ProblemDetails {
+	var limitErr *services.RateLimitExceededError
+	var rateLimitedErr *apierror.RateLimitedError
+	var capabilityErr *services.CapabilityError
+	var notImplErr *providers.NotImplementedError
+	var providerErr *apierror.ProviderError
+	var unsupportedErr *providers.CapabilityNotSupportedError
+
+	switch {
+	case errors.As(err, &notImplErr):
+		return apierror.NewProblem(apierror.TypeOperationNotImplemented, fiber.StatusNotImplemented, notImplErr.Error()).
+			With("provider", notImplErr.Provider).
+			With("operation", notImplErr.Operation)
+	case errors.As(err, &capabilityErr):
+		return apierror.NewProblem(apierror.TypeCapabilityNotSupported, status, detail).
+			With("capability", capabilityErr.Capability).
+			With("device_capabilities", capabilityErr.DeviceCapabilities)
+	case errors.As(err, &unsupportedErr):
+		return apierror.NewProblem(apierror.TypeCapabilityNotSupported, status, detail).
+			With("capability", unsupportedErr.Capability).
+			With("provider", unsupportedErr.Provider)
+	case errors.As(err, &limitErr):
+		return apierror.NewProblem(apierror.TypeRateLimited, fiber.StatusTooManyRequests, "rate limit exceeded").
+			With("retry_after", limitErr.RetryAfterSeconds).
+			With("limit", limitErr.Limit).
+			With("scope", limitErr.Scope)
+	case errors.As(err, &rateLimitedErr):
+		problemType := apierror.TypeRateLimited
+		if errors.As(err, &providerErr) {
+			problemType = apierror.TypeProviderRateLimited
+		}
+		problem := apierror.NewProblem(problemType, status, detail)
+		if rateLimitedErr.RetryAfter > 0 {
+			problem.With("retry_after", int(math.Ceil(rateLimitedErr.RetryAfter.Seconds())))
+		}
+		return problem
+	case errors.Is(err, services.ErrProviderCircuitOpen):
+		return apierror.NewProblem(apierror.TypeProviderUnavailable, status, detail)
+	case errors.Is(err, services.ErrAccountReadOnly):
+		return apierror.NewProblem(apierror.TypeAccountReadOnly, status, detail)
+	case errors.As(err, &providerErr):
+		return apierror.NewProblem(apierror.TypeProviderError, status, detail).With("provider", providerErr.Provider)
+	default:
+		return apierror.NewProblem(problemTypeForStatus(status), status, detail)
+	}
+}
+
+// newProblem returns a problem for an error known only by its status, for responses that
+// carry more than a fiber.Error's message
+func newProblem(status int, detail string) *apierror.ProblemDetails {
+	return apierror.NewProblem(problemTypeForStatus(status), status, detail)
+}
+
+// problemTypeForStatus returns the problem type of errors known only by their status
+func problemTypeForStatus(status int) string {
+	switch status {
+	case fiber.StatusBadRequest:
+		return apierror.TypeBadRequest
+	case fiber.StatusForbidden:
+		return apierror.TypeForbidden
+	case fiber.StatusNotFound:
+		return apierror.TypeNotFound
+	case fiber.StatusConflict:
+		return apierror.TypeConflict
+	case fiber.StatusUnprocessableEntity:
+		return apierror.TypeValidation
+	case fiber.StatusTooManyRequests:
+		return apierror.TypeRateLimited
+	case fiber.StatusServiceUnavailable:
+		return apierror.TypeProviderUnavailable
+	default:
+		return apierror.TypeAboutBlank
+	}
+}
+
+// RespondProblem logs err and writes problem as the response. Client errors are logged at
+// info level, leaving the error level to server errors.
+func RespondProblem(c *fiber.Ctx, problem *apierror.ProblemDetails, err error) error {
+	level := slog.LevelInfo
+	if problem.Status >= fiber.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	logger.WithContext(c.UserContext()).Log(c.UserContext(), level, "Request error",
+		"error", err,
+		"status", problem.Status,
+		"type", problem.Type,
+		"path", c.Path(),
+		"method", c.Method(),
+	)
+	return writeProblem(c, problem)
+}
+
+// writeProblem writes problem as an application/problem+json response. The detail is
+// repeated as "error" for clients written against the former {"error": "..."} responses.
+func writeProblem(c *fiber.Ctx, problem *apierror.ProblemDetails) error {
+	if problem.Instance == "" {
+		problem.Instance = c.Path()
+	}
+	if _, ok := problem.Extensions["error"]; !ok {
+		problem.With("error", problem.Error())
+	}
+	return c.Status(problem.Status).JSON(problem, apierror.ContentTypeProblemJSON)
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/lightshare/backend/internal/services"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/logger"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+func TestProblemResponses(t *testing.T) {
+	tests := []struct {
+		err            error
+		wantExtensions map[string]interface{}
+		name           string
+		wantType       string
+		wantStatus     int
+	}{
+		{
+			name:           "rate limited",
+			err:            &services.RateLimitExceededError{Scope: "write", Limit: 30, RetryAfterSeconds: 12},
+			wantStatus:     fiber.StatusTooManyRequests,
+			wantType:       apierror.TypeRateLimited,
+			wantExtensions: map[string]interface{}{"retry_after": float64(12), "limit": float64(30), "scope": "write"},
+		},
+		{
+			name: "provider rate limited",
+			err: &apierror.RateLimitedError{
+				Cause:      &apierror.ProviderError{Provider: "lifx", Cause: errors.New("429")},
+				RetryAfter: 1500 * time.Millisecond,
+			},
+			wantStatus:     fiber.StatusTooManyRequests,
+			wantType:       apierror.TypeProviderRateLimited,
+			wantExtensions: map[string]interface{}{"retry_after": float64(2)},
+		},
+		{
+			name:       "circuit open",
+			err:        fmt.Errorf("failed to list devices: %w", services.ErrProviderCircuitOpen),
+			wantStatus: fiber.StatusServiceUnavailable,
+			wantType:   apierror.TypeProviderUnavailable,
+		},
+		{
+			name:       "capability not supported",
+			err:        &services.CapabilityError{Capability: "color", DeviceCapabilities: []string{"power", "brightness"}},
+			wantStatus: fiber.StatusUnprocessableEntity,
+			wantType:   apierror.TypeCapabilityNotSupported,
+			wantExtensions: map[string]interface{}{
+				"capability":          "color",
+				"device_capabilities": []interface{}{"power", "brightness"},
+			},
+		},
+		{
+			name:       "account read-only",
+			err:        services.ErrAccountReadOnly,
+			wantStatus: fiber.StatusForbidden,
+			wantType:   apierror.TypeAccountReadOnly,
+		},
+		{
+			name:           "provider error",
+			err:            &apierror.ProviderError{Provider: "hue", Cause: errors.New("bridge unreachable")},
+			wantStatus:     fiber.StatusBadGateway,
+			wantType:       apierror.TypeProviderError,
+			wantExtensions: map[string]interface{}{"provider": "hue"},
+		},
+		{
+			name:           "not implemented",
+			err:            &providers.NotImplementedError{Provider: providers.ProviderHue, Operation: "pulse effect"},
+			wantStatus:     fiber.StatusNotImplemented,
+			wantType:       apierror.TypeOperationNotImplemented,
+			wantExtensions: map[string]interface{}{"provider": "hue", "operation": "pulse effect"},
+		},
+		{
+			name:       "not found",
+			err:        services.ErrDeviceNotFound,
+			wantStatus: fiber.StatusNotFound,
+			wantType:   apierror.TypeNotFound,
+		},
+		{
+			name:       "unexpected",
+			err:        errors.New("connection refused"),
+			wantStatus: fiber.StatusInternalServerError,
+			wantType:   apierror.TypeAboutBlank,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/devices", func(c *fiber.Ctx) error {
+				return serviceError(c, tt.err, "failed to list devices")
+			})
+
+			body := testProblem(t, app, "/devices", tt.wantStatus, tt.wantType)
+			for key, want := range tt.wantExtensions {
+				got, _ := json.Marshal(body[key])
+				wantJSON, _ := json.Marshal(want)
+				if string(got) != string(wantJSON) {
+					t.Errorf("Expected %s to be %s, got %s", key, wantJSON, got)
+				}
+			}
+		})
+	}
+}
+
+func TestProblemFromError_ErrorHandler(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			return RespondProblem(c, ProblemFromError(err), err)
+		},
+	})
+	app.Get("/fiber", func(*fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	})
+	app.Get("/service", func(*fiber.Ctx) error {
+		return services.ErrAccountReadOnly
+	})
+
+	body := testProblem(t, app, "/fiber", fiber.StatusBadRequest, apierror.TypeBadRequest)
+	if body["detail"] != "account ID is required" || body["error"] != "account ID is required" {
+		t.Errorf("Expected the Fiber error's message as detail and error, got %v", body)
+	}
+	if body["instance"] != "/fiber" || body["title"] != "Bad Request" {
+		t.Errorf("Expected instance /fiber and title Bad Request, got %v", body)
+	}
+
+	testProblem(t, app, "/service", fiber.StatusForbidden, apierror.TypeAccountReadOnly)
+	testProblem(t, app, "/missing", fiber.StatusNotFound, apierror.TypeNotFound)
+}
+
+func TestRespondProblem_LogsClientErrorsBelowErrorLevel(t *testing.T) {
+	var output bytes.Buffer
+	previous := logger.Get()
+	logger.SetDefault(slog.New(slog.NewJSONHandler(&output, nil)))
+	t.Cleanup(func() { logger.SetDefault(previous) })
+
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			return RespondProblem(c, ProblemFromError(err), err)
+		},
+	})
+	app.Get("/client", func(*fiber.Ctx) error {
+		return fiber.ErrNotFound
+	})
+	app.Get("/server", func(*fiber.Ctx) error {
+		return errors.New("database unreachable")
+	})
+
+	for path, wantLevel := range map[string]string{"/client": "INFO", "/server": "ERROR"} {
+		output.Reset()
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		_ = resp.Body.Close()
+
+		var entry map[string]interface{}
+		if err := json.Unmarshal(output.Bytes(), &entry); err != nil {
+			t.Fatalf("Failed to decode log entry %q: %v", output.String(), err)
+		}
+		if entry["level"] != wantLevel {
+			t.Errorf("Expected %s to be logged at %s, got %v", path, wantLevel, entry["level"])
+		}
+	}
+}
+
+// testProblem requests path and checks the response is a problem of the given status and
+// type, returning its members
+func testProblem(t *testing.T, app *fiber.App, path string, wantStatus int, wantType string) map[string]interface{} {
+	t.Helper()
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, http.NoBody))
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != wantStatus {
+		t.Errorf("Expected status %d, got %d", wantStatus, resp.StatusCode)
+	}
+	if got := resp.Header.Get(fiber.HeaderContentType); got != apierror.ContentTypeProblemJSON {
+		t.Errorf("Expected Content-Type %q, got %q", apierror.ContentTypeProblemJSON, got)
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body["type"] != wantType {
+		t.Errorf("Expected type %q, got %v", wantType, body["type"])
+	}
+	if body["status"] != float64(wantStatus) {
+		t.Errorf("Expected status member %d, got %v", wantStatus, body["status"])
+	}
+	return body
+}
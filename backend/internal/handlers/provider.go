@@ -2,13 +2,17 @@ package handlers
 
 import (
 	"errors"
+	"slices"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
+	"github.com/lightshare/backend/internal/config"
 	"github.com/lightshare/backend/internal/repository"
 	"github.com/lightshare/backend/internal/services"
 	"github.com/lightshare/backend/pkg/logger"
+	"github.com/lightshare/backend/pkg/providers"
 )
 
 // ProviderHandler handles provider connection endpoints
@@ -25,8 +29,13 @@ func NewProviderHandler(providerService *services.ProviderService) *ProviderHand
 
 // ConnectProviderRequest represents the connect provider request body
 type ConnectProviderRequest struct {
-	Provider string `json:"provider"`
-	Token    string `json:"token"`
+	Provider string `json:"provider" validate:"required"`
+	Token    string `json:"token" validate:"required"`
+}
+
+// ReconnectAccountRequest represents the reconnect account request body
+type ReconnectAccountRequest struct {
+	Token string `json:"token"`
 }
 
 // ConnectProvider handles provider connection
@@ -34,53 +43,36 @@ func (h *ProviderHandler) ConnectProvider(c *fiber.Ctx) error {
 	// Get user ID from context (set by auth middleware)
 	userID, ok := c.Locals("user_id").(uuid.UUID)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "unauthorized",
-		})
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	var req ConnectProviderRequest
-	if parseRequestBody(c, &req) {
+	if ValidateRequest(c, &req) {
 		return nil
 	}
 
-	// Validate request
-	if req.Provider == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "provider is required",
-		})
-	}
-	if req.Token == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "token is required",
-		})
-	}
-
 	// Call provider service
-	account, err := h.providerService.ConnectProvider(c.Context(), userID, services.ConnectProviderRequest{
+	account, err := h.providerService.ConnectProvider(c.UserContext(), userID, services.ConnectProviderRequest{
 		Provider: req.Provider,
 		Token:    req.Token,
 	})
 	if err != nil {
+		if respondNotImplemented(c, err) {
+			return nil
+		}
 		if errors.Is(err, services.ErrInvalidProvider) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "invalid provider type",
-			})
+			return fiber.NewError(fiber.StatusBadRequest, "invalid provider type")
+		}
+		if errors.Is(err, providers.ErrProviderDisabled) {
+			return fiber.NewError(fiber.StatusBadRequest, "provider is disabled")
 		}
 		if errors.Is(err, services.ErrInvalidToken) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "invalid provider token",
-			})
-		}
-		if err.Error() == "this provider account is already connected" {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"error": "this provider account is already connected",
-			})
-		}
-		logger.Error("Failed to connect provider", "error", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to connect provider",
-		})
+			return fiber.NewError(fiber.StatusBadRequest, "invalid provider token")
+		}
+		if errors.Is(err, services.ErrAccountAlreadyConnected) {
+			return fiber.NewError(fiber.StatusConflict, "this provider account is already connected")
+		}
+		return serviceError(c, err, "failed to connect provider")
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(account.ToResponse())
@@ -91,18 +83,13 @@ func (h *ProviderHandler) ListAccounts(c *fiber.Ctx) error {
 	// Get user ID from context (set by auth middleware)
 	userID, ok := c.Locals("user_id").(uuid.UUID)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "unauthorized",
-		})
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	// Call provider service
-	accounts, err := h.providerService.ListAccounts(c.Context(), userID)
+	accounts, err := h.providerService.ListAccounts(c.UserContext(), userID)
 	if err != nil {
-		logger.Error("Failed to list accounts", "error", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to list accounts",
-		})
+		return serviceError(c, err, "failed to list accounts")
 	}
 
 	// Convert to response format
@@ -116,45 +103,369 @@ func (h *ProviderHandler) ListAccounts(c *fiber.Ctx) error {
 	})
 }
 
+// ListProviders handles listing supported providers with the user's connection status
+func (h *ProviderHandler) ListProviders(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	statuses, err := h.providerService.ListProviders(c.UserContext(), userID)
+	if err != nil {
+		return serviceError(c, err, "failed to list providers")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"providers": statuses,
+	})
+}
+
 // DisconnectAccount handles disconnecting a provider account
 func (h *ProviderHandler) DisconnectAccount(c *fiber.Ctx) error {
 	// Get user ID from context (set by auth middleware)
 	userID, ok := c.Locals("user_id").(uuid.UUID)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "unauthorized",
-		})
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
 	}
 
 	// Get account ID from URL param
 	accountIDStr := c.Params("id")
 	accountID, err := uuid.Parse(accountIDStr)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid account id",
-		})
+		return fiber.NewError(fiber.StatusBadRequest, "invalid account id")
 	}
 
 	// Call provider service
-	err = h.providerService.DisconnectAccount(c.Context(), userID, accountID)
+	err = h.providerService.DisconnectAccount(c.UserContext(), userID, accountID)
 	if err != nil {
 		if errors.Is(err, repository.ErrAccountNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "account not found",
-			})
+			return fiber.NewError(fiber.StatusNotFound, "account not found")
 		}
 		if errors.Is(err, services.ErrAccountNotOwned) {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "account not owned by user",
-			})
+			return fiber.NewError(fiber.StatusForbidden, "account not owned by user")
 		}
-		logger.Error("Failed to disconnect account", "error", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to disconnect account",
-		})
+		return serviceError(c, err, "failed to disconnect account")
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "account disconnected successfully",
 	})
 }
+
+// ReconnectAccount replaces the provider token of a connected account
+// PUT /api/v1/accounts/:id/reconnect
+func (h *ProviderHandler) ReconnectAccount(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	accountID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid account id")
+	}
+
+	var req ReconnectAccountRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	if req.Token == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "token is required")
+	}
+
+	account, err := h.providerService.ReconnectAccount(c.UserContext(), userID, accountID, req.Token)
+	if err != nil {
+		if respondNotImplemented(c, err) {
+			return nil
+		}
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "account not found")
+		}
+		if errors.Is(err, services.ErrAccountNotOwned) {
+			return fiber.NewError(fiber.StatusForbidden, "account not owned by user")
+		}
+		if errors.Is(err, providers.ErrProviderDisabled) {
+			return fiber.NewError(fiber.StatusBadRequest, "provider is disabled")
+		}
+		if errors.Is(err, services.ErrInvalidToken) {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid provider token")
+		}
+		if errors.Is(err, services.ErrProviderAccountMismatch) {
+			return fiber.NewError(fiber.StatusConflict, "token belongs to a different provider account")
+		}
+		return serviceError(c, err, "failed to reconnect account")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(account.ToResponse())
+}
+
+// UpdateAccountLabelRequest represents a request to label a connected account
+type UpdateAccountLabelRequest struct {
+	Label string `json:"label"`
+}
+
+// UpdateAccountLabel sets the display label of a connected account
+// PUT /api/v1/accounts/:id/label
+func (h *ProviderHandler) UpdateAccountLabel(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	accountID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid account id")
+	}
+
+	var req UpdateAccountLabelRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	account, err := h.providerService.UpdateAccountLabel(c.UserContext(), userID, accountID, req.Label)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAccountLabel) {
+			return fiber.NewError(fiber.StatusBadRequest, "label must be between 1 and 100 characters")
+		}
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "account not found")
+		}
+		if errors.Is(err, services.ErrAccountNotOwned) {
+			return fiber.NewError(fiber.StatusForbidden, "account not owned by user")
+		}
+		return serviceError(c, err, "failed to update account label")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(account.ToResponse())
+}
+
+// CheckAccountHealth reports whether a connected account's provider token is still valid
+func (h *ProviderHandler) CheckAccountHealth(c *fiber.Ctx) error {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	accountID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid account id")
+	}
+
+	health, err := h.providerService.CheckAccountHealth(c.UserContext(), userID, accountID)
+	if err != nil {
+		if respondNotImplemented(c, err) {
+			return nil
+		}
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "account not found")
+		}
+		if errors.Is(err, services.ErrAccountNotOwned) {
+			return fiber.NewError(fiber.StatusForbidden, "account not owned by user")
+		}
+		logger.Error("Failed to check account health", "error", err)
+		return fiber.NewError(fiber.StatusBadGateway, "failed to reach provider")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(health)
+}
+
+// AuthorizeOAuth starts connecting an account of a provider with the OAuth2 authorization
+// code flow, redirecting to the provider's authorization page
+// GET /api/v1/auth/oauth/:provider/authorize?code_challenge=<>&code_challenge_method=S256&state=<>
+func (h *ProviderHandler) AuthorizeOAuth(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	authorizeURL, err := h.providerService.AuthorizeURL(c.UserContext(), userID, c.Params("provider"),
+		c.Query("state"), c.Query("code_challenge"), c.Query("code_challenge_method"))
+	if err != nil {
+		return serviceError(c, err, "failed to start authorization")
+	}
+
+	return c.Redirect(authorizeURL, fiber.StatusFound)
+}
+
+// OAuthCallback completes an OAuth2 authorization once the provider redirects back,
+// connecting the account and redirecting to the app's success page
+// GET /api/v1/auth/oauth/:provider/callback?code=<>&state=<>
+func (h *ProviderHandler) OAuthCallback(c *fiber.Ctx) error {
+	// The user declined, or the provider rejected the request
+	if providerErr := c.Query("error"); providerErr != "" {
+		return fiber.NewError(fiber.StatusBadRequest, "authorization failed: "+providerErr)
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "code is required")
+	}
+
+	successURL, err := h.providerService.CompleteAuthorization(c.UserContext(), c.Params("provider"), c.Query("state"), code)
+	if err != nil {
+		return serviceError(c, err, "failed to connect provider")
+	}
+
+	return c.Redirect(successURL, fiber.StatusFound)
+}
+
+// CollectOAuthRequest represents the request body collecting an OAuth2 authorization
+type CollectOAuthRequest struct {
+	State        string `json:"state" validate:"required"`
+	CodeVerifier string `json:"code_verifier" validate:"required"`
+}
+
+// CollectOAuth returns the account connected by an OAuth2 authorization the user started,
+// given the PKCE code verifier of the code challenge it was started with
+// POST /api/v1/auth/oauth/:provider/result
+func (h *ProviderHandler) CollectOAuth(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "unauthorized")
+	}
+
+	var req CollectOAuthRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	account, err := h.providerService.CollectAuthorization(c.UserContext(), userID, c.Params("provider"), req.State, req.CodeVerifier)
+	if err != nil {
+		return serviceError(c, err, "failed to collect authorization")
+	}
+
+	return c.JSON(account.ToResponse())
+}
+
+// ProviderInfo describes a provider in the public catalogue
+type ProviderInfo struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Description      string   `json:"description"`
+	Status           string   `json:"status"` // "available" or "coming_soon"
+	SupportedActions []string `json:"supported_actions"`
+	DocsURL          string   `json:"docs_url"`
+}
+
+// ProviderCapabilities details what a provider can do with its devices
+type ProviderCapabilities struct {
+	ProviderInfo
+	Effects            []string `json:"effects"`             // Effects accepted by the "effect" action
+	DeviceCapabilities []string `json:"device_capabilities"` // Capabilities devices of the provider may report
+}
+
+// providerCatalog lists every supported provider with its capabilities, in display order
+var providerCatalog = []ProviderCapabilities{
+	{
+		ProviderInfo: ProviderInfo{
+			ID:               string(providers.ProviderLIFX),
+			Name:             "LIFX",
+			Description:      "Wi-Fi smart bulbs and light strips controlled through the LIFX cloud",
+			Status:           "available",
+			SupportedActions: []string{"power", "toggle", "brightness", "color", "temperature", "effect", "infrared"},
+			DocsURL:          "https://api.developer.lifx.com/",
+		},
+		Effects:            []string{"pulse", "breathe", "flame", "move", "waveform"},
+		DeviceCapabilities: []string{"brightness", "color", "temperature", "effects", "infrared"},
+	},
+	{
+		ProviderInfo: ProviderInfo{
+			ID:               string(providers.ProviderHue),
+			Name:             "Philips Hue",
+			Description:      "Zigbee lights controlled through a Hue bridge",
+			Status:           "available",
+			SupportedActions: []string{"power", "toggle", "brightness", "color", "temperature"},
+			DocsURL:          "https://developers.meethue.com/",
+		},
+		Effects:            []string{},
+		DeviceCapabilities: []string{"brightness", "color", "temperature"},
+	},
+	{
+		ProviderInfo: ProviderInfo{
+			ID:               string(providers.ProviderNanoleaf),
+			Name:             "Nanoleaf",
+			Description:      "Light panels controlled through their local OpenAPI",
+			Status:           "available",
+			SupportedActions: []string{"power", "toggle", "brightness", "color", "temperature", "effect"},
+			DocsURL:          "https://forum.nanoleaf.me/docs",
+		},
+		Effects:            []string{"pulse", "breathe"},
+		DeviceCapabilities: []string{"brightness", "color", "temperature", "effects"},
+	},
+	{
+		ProviderInfo: ProviderInfo{
+			ID:               string(providers.ProviderWiZ),
+			Name:             "WiZ",
+			Description:      "Wi-Fi smart bulbs controlled over the local network",
+			Status:           "available",
+			SupportedActions: []string{"power", "toggle", "brightness", "color", "temperature", "effect"},
+			DocsURL:          "https://www.wizconnected.com/",
+		},
+		Effects:            []string{"flame"},
+		DeviceCapabilities: []string{"brightness", "color", "temperature", "effects"},
+	},
+	{
+		ProviderInfo: ProviderInfo{
+			ID:               string(providers.ProviderGovee),
+			Name:             "Govee",
+			Description:      "Wi-Fi lights and light strips controlled through the Govee developer API",
+			Status:           "available",
+			SupportedActions: []string{"power", "toggle", "brightness", "color", "temperature"},
+			DocsURL:          "https://developer.govee.com/",
+		},
+		Effects:            []string{},
+		DeviceCapabilities: []string{"brightness", "color", "temperature"},
+	},
+	{
+		ProviderInfo: ProviderInfo{
+			ID:               string(providers.ProviderHomeAssistant),
+			Name:             "Home Assistant",
+			Description:      "Any light entity exposed by a Home Assistant instance",
+			Status:           "available",
+			SupportedActions: []string{"power", "toggle", "brightness", "color", "temperature"},
+			DocsURL:          "https://developers.home-assistant.io/docs/api/rest/",
+		},
+		Effects:            []string{},
+		DeviceCapabilities: []string{"brightness", "color", "temperature"},
+	},
+}
+
+// ProviderCatalog returns the public provider catalogue handler, listing the providers
+// enabled by the feature flags
+// GET /api/v1/providers
+func ProviderCatalog(load func() config.FeaturesConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		enabled := load().EnabledProviders
+		infos := make([]ProviderInfo, 0, len(providerCatalog))
+		for _, entry := range providerCatalog {
+			if slices.Contains(enabled, entry.ID) {
+				infos = append(infos, entry.ProviderInfo)
+			}
+		}
+		return c.JSON(fiber.Map{
+			"providers": infos,
+		})
+	}
+}
+
+// ProviderCapabilitiesHandler returns the handler detailing the capabilities of an
+// enabled provider
+// GET /api/v1/providers/:id/capabilities
+func ProviderCapabilitiesHandler(load func() config.FeaturesConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := strings.ToLower(c.Params("id"))
+		if slices.Contains(load().EnabledProviders, id) {
+			for _, entry := range providerCatalog {
+				if entry.ID == id {
+					return c.JSON(entry)
+				}
+			}
+		}
+		return fiber.NewError(fiber.StatusNotFound, "provider not found")
+	}
+}
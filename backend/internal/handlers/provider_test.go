@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/config"
+	"github.com/lightshare/backend/internal/middleware"
+	"github.com/lightshare/backend/internal/services"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+func TestRespondNotImplemented_Returns501(t *testing.T) {
+	app := fiber.New()
+	app.Post("/accounts/:accountId/devices/:deviceId/action", func(c *fiber.Ctx) error {
+		if respondNotImplemented(c, &providers.NotImplementedError{Provider: providers.ProviderHue, Operation: "pulse effect"}) {
+			return nil
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest("POST", "/accounts/a/devices/d/action", http.NoBody)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Errorf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != fiber.StatusNotImplemented {
+		t.Fatalf("Expected status 501, got %d", resp.StatusCode)
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body["type"] != apierror.TypeOperationNotImplemented {
+		t.Errorf("Expected type %q, got %v", apierror.TypeOperationNotImplemented, body["type"])
+	}
+	if body["provider"] != "hue" {
+		t.Errorf("Expected provider 'hue', got '%s'", body["provider"])
+	}
+	if body["operation"] != "pulse effect" {
+		t.Errorf("Expected operation 'pulse effect', got '%s'", body["operation"])
+	}
+}
+
+func TestConnectProvider_DisabledProviderReturns400(t *testing.T) {
+	providerService := services.NewProviderService(nil, nil, nil, 0)
+	providerService.SetEnabledProviders(func() []string { return []string{"lifx"} })
+	handler := NewProviderHandler(providerService)
+
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			return RespondProblem(c, ProblemFromError(err), err)
+		},
+	})
+	app.Post("/providers/connect", func(c *fiber.Ctx) error {
+		c.Locals("user_id", uuid.New())
+		return c.Next()
+	}, handler.ConnectProvider)
+
+	req := httptest.NewRequest("POST", "/providers/connect", strings.NewReader(`{"provider":"hue","token":"test-token"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Errorf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", resp.StatusCode)
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body["error"] != "provider is disabled" {
+		t.Errorf("Expected error 'provider is disabled', got '%v'", body["error"])
+	}
+}
+
+func TestProviderCatalog_FiltersDisabledProviders(t *testing.T) {
+	t.Setenv("FEATURE_ENABLED_PROVIDERS", "lifx")
+
+	app := fiber.New()
+	app.Get("/providers", ProviderCatalog(config.LoadFeatures))
+	app.Get("/providers/:id/capabilities", ProviderCapabilitiesHandler(config.LoadFeatures))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/providers", http.NoBody))
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Errorf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Providers []ProviderInfo `json:"providers"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(body.Providers) != 1 || body.Providers[0].ID != "lifx" {
+		t.Fatalf("Expected only lifx, got %+v", body.Providers)
+	}
+	for _, info := range body.Providers {
+		if info.ID == string(providers.ProviderHue) {
+			t.Error("Expected hue to be absent when it is disabled")
+		}
+	}
+	if !slices.Contains(body.Providers[0].SupportedActions, "effect") {
+		t.Errorf("Expected lifx to support effects, got %v", body.Providers[0].SupportedActions)
+	}
+
+	tests := []struct {
+		id         string
+		wantStatus int
+	}{
+		{"lifx", fiber.StatusOK},
+		{"hue", fiber.StatusNotFound},     // Disabled
+		{"unknown", fiber.StatusNotFound}, // Not in the catalogue
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", "/providers/"+tt.id+"/capabilities", http.NoBody))
+			if err != nil {
+				t.Fatalf("Failed to test request: %v", err)
+			}
+			defer func() {
+				if err := resp.Body.Close(); err != nil {
+					t.Errorf("Failed to close response body: %v", err)
+				}
+			}()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestProviderCatalog_CacheHeaders(t *testing.T) {
+	app := fiber.New()
+	app.Get("/providers", middleware.StaticCacheMiddleware(time.Hour), ProviderCatalog(config.LoadFeatures))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/providers", http.NoBody))
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Errorf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if got := resp.Header.Get(fiber.HeaderCacheControl); got != "public, max-age=3600" {
+		t.Errorf("Expected Cache-Control public, max-age=3600, got %q", got)
+	}
+	if resp.Header.Get(fiber.HeaderETag) == "" {
+		t.Error("Expected an ETag")
+	}
+}
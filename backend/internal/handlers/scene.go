@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/services"
+)
+
+// maxSceneTransition caps the transition duration of a scene activation, in seconds
+const maxSceneTransition = 3600
+
+// SceneHandler handles scene-related HTTP requests
+type SceneHandler struct {
+	sceneService *services.SceneService
+}
+
+// NewSceneHandler creates a new scene handler
+func NewSceneHandler(sceneService *services.SceneService) *SceneHandler {
+	return &SceneHandler{
+		sceneService: sceneService,
+	}
+}
+
+// CreateScene snapshots the current state of every device in an account
+// POST /api/v1/accounts/:accountId/scenes
+func (h *SceneHandler) CreateScene(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+
+	var req models.CreateSceneRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	scene, err := h.sceneService.CreateScene(c.UserContext(), userID.String(), accountID, req)
+	if err != nil {
+		return serviceError(c, err, "failed to create scene")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"scene": scene,
+	})
+}
+
+// ListScenes lists the scenes saved for an account
+// GET /api/v1/accounts/:accountId/scenes
+func (h *SceneHandler) ListScenes(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+
+	scenes, err := h.sceneService.ListScenes(c.UserContext(), userID.String(), accountID)
+	if err != nil {
+		if errors.Is(err, services.ErrSceneNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "account not found")
+		}
+		return serviceError(c, err, "failed to list scenes")
+	}
+
+	return c.JSON(fiber.Map{
+		"scenes": scenes,
+	})
+}
+
+// ActivateScene replays the device states saved in a scene
+// POST /api/v1/accounts/:accountId/scenes/:sceneId/activate?duration=1.5
+func (h *SceneHandler) ActivateScene(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+
+	sceneID, err := uuid.Parse(c.Params("sceneId"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid scene ID")
+	}
+
+	duration, err := parseSceneDuration(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.sceneService.ActivateScene(c.UserContext(), userID.String(), accountID, sceneID, duration)
+	if err != nil {
+		return serviceError(c, err, "failed to activate scene")
+	}
+
+	status := fiber.StatusOK
+	switch {
+	case result.Partial():
+		status = fiber.StatusMultiStatus
+	case result.Failed > 0:
+		status = fiber.StatusBadGateway
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"success": result.Failed == 0,
+		"partial": result.Partial(),
+		"results": result.Results,
+	})
+}
+
+// DeleteScene removes a scene
+// DELETE /api/v1/accounts/:accountId/scenes/:sceneId
+func (h *SceneHandler) DeleteScene(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+
+	sceneID, err := uuid.Parse(c.Params("sceneId"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid scene ID")
+	}
+
+	if err := h.sceneService.DeleteScene(c.UserContext(), userID.String(), accountID, sceneID); err != nil {
+		return serviceError(c, err, "failed to delete scene")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// parseSceneDuration reads the optional ?duration= transition time in seconds
+func parseSceneDuration(c *fiber.Ctx) (*float64, error) {
+	durationStr := c.Query("duration")
+	if durationStr == "" {
+		return nil, nil
+	}
+
+	duration, err := strconv.ParseFloat(durationStr, 64)
+	if err != nil || duration < 0 || duration > maxSceneTransition {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid duration (must be 0-3600 seconds)")
+	}
+	return &duration, nil
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/services"
+)
+
+// ScheduleHandler handles scheduled device action endpoints
+type ScheduleHandler struct {
+	scheduleService *services.ScheduleService
+}
+
+// NewScheduleHandler creates a new schedule handler
+func NewScheduleHandler(scheduleService *services.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduleService: scheduleService,
+	}
+}
+
+// CreateSchedule schedules a device action on a cron expression
+// POST /api/v1/schedules
+func (h *ScheduleHandler) CreateSchedule(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	var req models.CreateScheduleRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	schedule, err := h.scheduleService.Create(c.UserContext(), userID, req)
+	if err != nil {
+		return serviceError(c, err, "failed to create schedule")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"schedule": schedule,
+	})
+}
+
+// ListSchedules lists the user's scheduled actions
+// GET /api/v1/schedules
+func (h *ScheduleHandler) ListSchedules(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	schedules, err := h.scheduleService.List(c.UserContext(), userID)
+	if err != nil {
+		return serviceError(c, err, "failed to list schedules")
+	}
+
+	return c.JSON(fiber.Map{
+		"schedules": schedules,
+	})
+}
+
+// DeleteSchedule removes a scheduled action
+// DELETE /api/v1/schedules/:id
+func (h *ScheduleHandler) DeleteSchedule(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	scheduleID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid schedule ID")
+	}
+
+	if err := h.scheduleService.Delete(c.UserContext(), userID, scheduleID); err != nil {
+		return serviceError(c, err, "failed to delete schedule")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/services"
+)
+
+// StateSnapshotHandler handles state snapshot HTTP requests
+type StateSnapshotHandler struct {
+	snapshotService *services.StateSnapshotService
+}
+
+// NewStateSnapshotHandler creates a new state snapshot handler
+func NewStateSnapshotHandler(snapshotService *services.StateSnapshotService) *StateSnapshotHandler {
+	return &StateSnapshotHandler{
+		snapshotService: snapshotService,
+	}
+}
+
+// TakeSnapshot saves the current state of the devices a selector targets
+// POST /api/v1/accounts/:accountId/devices/:selector/state-snapshot
+func (h *StateSnapshotHandler) TakeSnapshot(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	accountID := c.Params("accountId")
+	selector := c.Params("selector")
+
+	if accountID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "account ID is required")
+	}
+	if selector == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "selector is required")
+	}
+
+	var req models.CreateStateSnapshotRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	snapshot, err := h.snapshotService.TakeSnapshot(c.UserContext(), userID.String(), accountID, selector, req.Name)
+	if err != nil {
+		return serviceError(c, err, "failed to take state snapshot")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"snapshot": snapshot,
+	})
+}
+
+// ListSnapshots lists the user's state snapshots across all accounts
+// GET /api/v1/state-snapshots
+func (h *StateSnapshotHandler) ListSnapshots(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	snapshots, err := h.snapshotService.ListSnapshots(c.UserContext(), userID.String())
+	if err != nil {
+		return serviceError(c, err, "failed to list state snapshots")
+	}
+
+	return c.JSON(fiber.Map{
+		"snapshots": snapshots,
+	})
+}
+
+// RestoreSnapshot replays the device states saved in a snapshot
+// POST /api/v1/state-snapshots/:snapshotId/restore?duration=1.5
+func (h *StateSnapshotHandler) RestoreSnapshot(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	snapshotID, err := uuid.Parse(c.Params("snapshotId"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid snapshot ID")
+	}
+
+	duration, err := parseSceneDuration(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.snapshotService.RestoreSnapshot(c.UserContext(), userID.String(), snapshotID, duration)
+	if err != nil {
+		return serviceError(c, err, "failed to restore state snapshot")
+	}
+
+	status := fiber.StatusOK
+	switch {
+	case result.Partial():
+		status = fiber.StatusMultiStatus
+	case result.Failed > 0:
+		status = fiber.StatusBadGateway
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"success": result.Failed == 0,
+		"partial": result.Partial(),
+		"results": result.Results,
+	})
+}
+
+// DeleteSnapshot removes a state snapshot
+// DELETE /api/v1/state-snapshots/:snapshotId
+func (h *StateSnapshotHandler) DeleteSnapshot(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid user context")
+	}
+
+	snapshotID, err := uuid.Parse(c.Params("snapshotId"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid snapshot ID")
+	}
+
+	if err := h.snapshotService.DeleteSnapshot(c.UserContext(), userID.String(), snapshotID); err != nil {
+		return serviceError(c, err, "failed to delete state snapshot")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/lightshare/backend/pkg/apierror"
+)
+
+// ValidateRequest parses the request body into req and checks its `validate:` struct tags.
+// A malformed body gets a 400 problem; invalid fields get a 422 problem listing every one
+// of them under "errors".
+// Returns true if an error occurred (and error response was sent), false otherwise.
+func ValidateRequest(c *fiber.Ctx, req interface{}) bool {
+	if err := c.BodyParser(req); err != nil {
+		_ = writeProblem(c, apierror.NewProblem(apierror.TypeBadRequest, fiber.StatusBadRequest, "invalid request body"))
+		return true
+	}
+
+	if validationErr := apierror.Validate(req); validationErr != nil {
+		problem := apierror.NewProblem(apierror.TypeValidation, fiber.StatusUnprocessableEntity, "request validation failed").
+			With("errors", validationErr.Fields)
+		_ = writeProblem(c, problem)
+		return true
+	}
+	return false
+}
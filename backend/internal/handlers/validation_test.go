@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+)
+
+// newValidationApp routes the handlers under test; invalid requests are rejected before
+// any service is called, so the handlers need none
+func newValidationApp() *fiber.App {
+	app := fiber.New()
+	authHandler := NewAuthHandler(nil)
+	providerHandler := NewProviderHandler(nil)
+
+	app.Post("/signup", authHandler.Signup)
+	app.Post("/login", authHandler.Login)
+	app.Post("/providers/connect", func(c *fiber.Ctx) error {
+		c.Locals("user_id", uuid.New())
+		return c.Next()
+	}, providerHandler.ConnectProvider)
+	app.Post("/action", func(c *fiber.Ctx) error {
+		var action models.ActionRequest
+		if ValidateRequest(c, &action) {
+			return nil
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+// postJSON sends body to path, returning the response status and the validation errors it holds
+func postJSON(t *testing.T, app *fiber.App, path, body string) (int, []apierror.FieldError) {
+	t.Helper()
+
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Errorf("Failed to close response body: %v", err)
+		}
+	}()
+
+	var validationErr apierror.ValidationError
+	if resp.StatusCode == fiber.StatusUnprocessableEntity {
+		if err := json.NewDecoder(resp.Body).Decode(&validationErr); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return resp.StatusCode, validationErr.Fields
+}
+
+func TestValidateRequest_ReportsEveryInvalidField(t *testing.T) {
+	app := newValidationApp()
+
+	status, fields := postJSON(t, app, "/signup", `{"email":"not-an-email","password":"short"}`)
+	if status != fiber.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d", status)
+	}
+
+	want := []apierror.FieldError{
+		{Field: "email", Code: "invalid_email", Message: "not a valid email"},
+		{Field: "password", Code: "too_short", Message: "minimum 8 characters"},
+	}
+	if len(fields) != len(want) {
+		t.Fatalf("Expected %d field errors, got %+v", len(want), fields)
+	}
+	for i := range want {
+		if fields[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], fields[i])
+		}
+	}
+}
+
+func TestValidateRequest_FieldCodes(t *testing.T) {
+	app := newValidationApp()
+
+	testCases := []struct {
+		name      string
+		path      string
+		body      string
+		wantField string
+		wantCode  string
+	}{
+		{"login without password", "/login", `{"email":"user@example.com"}`, "password", "required"},
+		{"connect without token", "/providers/connect", `{"provider":"lifx"}`, "token", "required"},
+		{"missing action", "/action", `{"parameters":{}}`, "action", "required"},
+		{"unknown action", "/action", `{"action":"dance"}`, "action", "invalid_value"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, fields := postJSON(t, app, tc.path, tc.body)
+			if status != fiber.StatusUnprocessableEntity {
+				t.Fatalf("Expected status 422, got %d", status)
+			}
+			if len(fields) != 1 || fields[0].Field != tc.wantField || fields[0].Code != tc.wantCode {
+				t.Errorf("Expected %s error on %s, got %+v", tc.wantCode, tc.wantField, fields)
+			}
+		})
+	}
+}
+
+func TestValidateRequest_MalformedBody(t *testing.T) {
+	status, _ := postJSON(t, newValidationApp(), "/signup", `{"email":`)
+	if status != fiber.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", status)
+	}
+}
+
+func TestValidateRequest_ValidBody(t *testing.T) {
+	status, _ := postJSON(t, newValidationApp(), "/action", `{"action":"toggle"}`)
+	if status != fiber.StatusOK {
+		t.Errorf("Expected status 200, got %d", status)
+	}
+}
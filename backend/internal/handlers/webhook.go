@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/middleware"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/internal/services"
+)
+
+// WebhookHandler handles webhook management endpoints
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// CreateWebhook registers a webhook; the signing secret is only shown in this response
+// POST /api/v1/webhooks
+func (h *WebhookHandler) CreateWebhook(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var req services.CreateWebhookRequest
+	if ValidateRequest(c, &req) {
+		return nil
+	}
+
+	resp, err := h.webhookService.Create(c.UserContext(), userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidWebhookRequest) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return serviceError(c, err, "failed to create webhook")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// ListWebhooks lists the user's webhooks
+// GET /api/v1/webhooks
+func (h *WebhookHandler) ListWebhooks(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	webhooks, err := h.webhookService.List(c.UserContext(), userID)
+	if err != nil {
+		return serviceError(c, err, "failed to list webhooks")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"webhooks": webhooks,
+	})
+}
+
+// DeleteWebhook removes one of the user's webhooks
+// DELETE /api/v1/webhooks/:id
+func (h *WebhookHandler) DeleteWebhook(c *fiber.Ctx) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		return err
+	}
+
+	webhookID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid webhook id")
+	}
+
+	if err := h.webhookService.Delete(c.UserContext(), userID, webhookID); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "webhook not found")
+		}
+		return serviceError(c, err, "failed to delete webhook")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "webhook deleted",
+	})
+}
@@ -2,31 +2,35 @@
 package middleware
 
 import (
+	"context"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
+	"github.com/lightshare/backend/internal/models"
 	"github.com/lightshare/backend/pkg/jwt"
+	"github.com/lightshare/backend/pkg/logger"
 )
 
+// APIKeyAuthenticator resolves a plaintext API key to its stored record
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error)
+}
+
 // AuthMiddleware creates an authentication middleware
 func AuthMiddleware(jwtService *jwt.Service) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get authorization header
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "missing authorization header",
-			})
+			return fiber.NewError(fiber.StatusUnauthorized, "missing authorization header")
 		}
 
 		// Check if it's a Bearer token
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "invalid authorization header format",
-			})
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid authorization header format")
 		}
 
 		token := parts[1]
@@ -35,24 +39,83 @@ func AuthMiddleware(jwtService *jwt.Service) fiber.Handler {
 		claims, err := jwtService.ValidateAccessToken(token)
 		if err != nil {
 			if err == jwt.ErrTokenExpired {
-				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-					"error": "token expired",
-				})
+				return fiber.NewError(fiber.StatusUnauthorized, "token expired")
 			}
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "invalid token",
-			})
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
 		}
 
 		// Store user information in context
 		c.Locals("user_id", claims.UserID)
 		c.Locals("user_email", claims.Email)
 		c.Locals("user_role", claims.Role)
+		c.Locals("session_id", claims.SessionID)
+
+		// Tag every log entry of an impersonated request with the admin behind it
+		if claims.ImpersonatedBy != "" {
+			c.Locals("impersonated_by", claims.ImpersonatedBy)
+			ctx := c.UserContext()
+			c.SetUserContext(logger.NewContext(ctx, logger.WithContext(ctx).With("impersonated_by", claims.ImpersonatedBy)))
+		}
 
 		return c.Next()
 	}
 }
 
+// AuthOrAPIKeyMiddleware accepts either a JWT ("Bearer <token>") or an API key ("ApiKey <key>",
+// or "Bearer <key>" since keys are recognizable by their prefix).
+// API key requests carry the key's scopes, which RequireScope enforces
+func AuthOrAPIKeyMiddleware(jwtService *jwt.Service, apiKeys APIKeyAuthenticator) fiber.Handler {
+	jwtAuth := AuthMiddleware(jwtService)
+
+	return func(c *fiber.Ctx) error {
+		parts := strings.Split(c.Get("Authorization"), " ")
+		if !isAPIKeyAuthorization(parts) {
+			return jwtAuth(c)
+		}
+
+		apiKey, err := apiKeys.AuthenticateAPIKey(c.Context(), parts[1])
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid api key")
+		}
+
+		// Store user information in context
+		c.Locals("user_id", apiKey.UserID)
+		c.Locals("user_email", apiKey.UserEmail)
+		c.Locals("user_role", apiKey.UserRole)
+		c.Locals("api_key_id", apiKey.ID)
+		c.Locals("api_key_scopes", []string(apiKey.Scopes))
+
+		return c.Next()
+	}
+}
+
+// isAPIKeyAuthorization reports whether the Authorization header parts carry an API key
+func isAPIKeyAuthorization(parts []string) bool {
+	if len(parts) != 2 {
+		return false
+	}
+	return parts[0] == "ApiKey" || (parts[0] == "Bearer" && strings.HasPrefix(parts[1], models.APIKeyPrefix))
+}
+
+// RequireScope creates a middleware that requires API key requests to carry a scope
+// Requests authenticated with a JWT have full access and always pass
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		scopes, ok := c.Locals("api_key_scopes").([]string)
+		if !ok {
+			return c.Next()
+		}
+
+		for _, s := range scopes {
+			if s == scope {
+				return c.Next()
+			}
+		}
+
+		return fiber.NewError(fiber.StatusForbidden, "api key lacks required scope: "+scope)
+	}
+}
+
 // GetUserID gets the user ID from the request context
 func GetUserID(c *fiber.Ctx) (uuid.UUID, error) {
 	userID, ok := c.Locals("user_id").(uuid.UUID)
@@ -62,6 +125,13 @@ func GetUserID(c *fiber.Ctx) (uuid.UUID, error) {
 	return userID, nil
 }
 
+// GetSessionID gets the session ID of the access token from the request context.
+// It is uuid.Nil for API key requests and tokens issued before sessions were tracked.
+func GetSessionID(c *fiber.Ctx) uuid.UUID {
+	sessionID, _ := c.Locals("session_id").(uuid.UUID)
+	return sessionID
+}
+
 // GetUserEmail gets the user email from the request context
 func GetUserEmail(c *fiber.Ctx) (string, error) {
 	email, ok := c.Locals("user_email").(string)
@@ -80,6 +150,18 @@ func GetUserRole(c *fiber.Ctx) (string, error) {
 	return role, nil
 }
 
+// DenyImpersonation rejects requests made with an impersonation token. Impersonating admins
+// see what the user sees, but may not mint credentials, change security settings, end
+// sessions or delete the account, nor reach admin routes to impersonate again.
+func DenyImpersonation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if adminID, _ := c.Locals("impersonated_by").(string); adminID != "" {
+			return fiber.NewError(fiber.StatusForbidden, "not allowed while impersonating a user")
+		}
+		return c.Next()
+	}
+}
+
 // RequireRole creates a middleware that requires a specific role
 func RequireRole(requiredRole string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -89,9 +171,7 @@ func RequireRole(requiredRole string) fiber.Handler {
 		}
 
 		if role != requiredRole {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "insufficient permissions",
-			})
+			return fiber.NewError(fiber.StatusForbidden, "insufficient permissions")
 		}
 
 		return c.Next()
@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/jwt"
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+// stubAPIKeys authenticates a single known key
+type stubAPIKeys struct {
+	key    string
+	apiKey *models.APIKey
+}
+
+func (s *stubAPIKeys) AuthenticateAPIKey(_ context.Context, key string) (*models.APIKey, error) {
+	if key != s.key {
+		return nil, errors.New("invalid api key")
+	}
+	return s.apiKey, nil
+}
+
+func newAPIKeyTestApp(apiKeys APIKeyAuthenticator) *fiber.App {
+	jwtService := jwt.New(jwt.Config{Secret: "test-secret", AccessExpiration: time.Hour, RefreshExpiration: time.Hour})
+	auth := AuthOrAPIKeyMiddleware(jwtService, apiKeys)
+
+	app := fiber.New()
+	handler := func(c *fiber.Ctx) error {
+		userID, err := GetUserID(c)
+		if err != nil {
+			return err
+		}
+		return c.SendString(userID.String())
+	}
+	app.Get("/read", auth, RequireScope(models.APIKeyScopeDevicesRead), handler)
+	app.Post("/write", auth, RequireScope(models.APIKeyScopeDevicesWrite), handler)
+	return app
+}
+
+func TestAuthOrAPIKeyMiddleware(t *testing.T) {
+	userID := uuid.New()
+	apiKeys := &stubAPIKeys{
+		key: "lsk_valid",
+		apiKey: &models.APIKey{
+			ID:     uuid.New(),
+			UserID: userID,
+			Scopes: []string{models.APIKeyScopeDevicesRead},
+		},
+	}
+	app := newAPIKeyTestApp(apiKeys)
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		auth       string
+		wantStatus int
+	}{
+		{name: "valid key with scope", method: "GET", path: "/read", auth: "ApiKey lsk_valid", wantStatus: fiber.StatusOK},
+		{name: "valid key as bearer", method: "GET", path: "/read", auth: "Bearer lsk_valid", wantStatus: fiber.StatusOK},
+		{name: "bearer key without scope", method: "POST", path: "/write", auth: "Bearer lsk_valid", wantStatus: fiber.StatusForbidden},
+		{name: "unknown bearer key", method: "GET", path: "/read", auth: "Bearer lsk_revoked", wantStatus: fiber.StatusUnauthorized},
+		{name: "valid key without scope", method: "POST", path: "/write", auth: "ApiKey lsk_valid", wantStatus: fiber.StatusForbidden},
+		{name: "unknown key", method: "GET", path: "/read", auth: "ApiKey lsk_revoked", wantStatus: fiber.StatusUnauthorized},
+		{name: "missing header", method: "GET", path: "/read", auth: "", wantStatus: fiber.StatusUnauthorized},
+		{name: "invalid bearer", method: "GET", path: "/read", auth: "Bearer not-a-jwt", wantStatus: fiber.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, http.NoBody)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to test request: %v", err)
+			}
+			defer func() {
+				if err := resp.Body.Close(); err != nil {
+					t.Errorf("Failed to close response body: %v", err)
+				}
+			}()
+
+			if resp.StatusCode != tc.wantStatus {
+				t.Errorf("Expected status %d, got %d", tc.wantStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestAuthOrAPIKeyMiddleware_SetsOwnerLocals(t *testing.T) {
+	apiKeys := &stubAPIKeys{
+		key: "lsk_valid",
+		apiKey: &models.APIKey{
+			ID:        uuid.New(),
+			UserID:    uuid.New(),
+			UserEmail: "owner@example.com",
+			UserRole:  "admin",
+			Scopes:    []string{models.APIKeyScopeDevicesRead},
+		},
+	}
+	jwtService := jwt.New(jwt.Config{Secret: "test-secret", AccessExpiration: time.Hour, RefreshExpiration: time.Hour})
+
+	app := fiber.New()
+	app.Get("/me", AuthOrAPIKeyMiddleware(jwtService, apiKeys), RequireRole("admin"), func(c *fiber.Ctx) error {
+		email, err := GetUserEmail(c)
+		if err != nil {
+			return err
+		}
+		return c.SendString(email)
+	})
+
+	req := httptest.NewRequest("GET", "/me", http.NoBody)
+	req.Header.Set("Authorization", "Bearer lsk_valid")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusOK || string(body) != "owner@example.com" {
+		t.Errorf("Expected 200 with the owner's email, got %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestAuthMiddleware_TagsImpersonatedRequests(t *testing.T) {
+	var logs bytes.Buffer
+	logger.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { logger.Init("info") })
+
+	jwtService := jwt.New(jwt.Config{Secret: "test-secret", AccessExpiration: time.Hour, RefreshExpiration: time.Hour})
+	adminID := uuid.New()
+	token, _, err := jwtService.GenerateImpersonationToken(uuid.New(), "user@example.com", "user", adminID, "debugging")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	app := fiber.New()
+	app.Get("/devices", AuthMiddleware(jwtService), func(c *fiber.Ctx) error {
+		logger.WithContext(c.UserContext()).Info("Listing devices")
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/devices", http.NoBody)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected 200, got %d", resp.StatusCode)
+	}
+	if !strings.Contains(logs.String(), `"impersonated_by":"`+adminID.String()+`"`) {
+		t.Errorf("Expected the log entry to name the admin, got %s", logs.String())
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// StaticCacheMiddleware lets clients and shared caches keep successful GET responses of
+// slowly changing metadata for maxAge. Responses carry an ETag hashing their body, so
+// conditional requests are answered with 304 Not Modified. Last-Modified is the first
+// time the current body was served: a body changed by a configuration reload gets a new
+// ETag and Last-Modified on its next request.
+func StaticCacheMiddleware(maxAge time.Duration) fiber.Handler {
+	cacheControl := "public, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+
+	var mu sync.Mutex
+	var lastETag string
+	var lastModified time.Time
+
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+			return nil
+		}
+		if c.Response().StatusCode() != fiber.StatusOK || c.Response().IsBodyStream() {
+			return nil
+		}
+
+		sum := sha256.Sum256(c.Response().Body())
+		etag := hex.EncodeToString(sum[:16])
+
+		mu.Lock()
+		if etag != lastETag {
+			lastETag, lastModified = etag, time.Now()
+		}
+		modified := lastModified
+		mu.Unlock()
+
+		now := time.Now()
+		c.Set(fiber.HeaderCacheControl, cacheControl)
+		c.Set(fiber.HeaderExpires, now.Add(maxAge).UTC().Format(http.TimeFormat))
+		c.Set(fiber.HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+		c.Set(fiber.HeaderETag, `"`+etag+`"`)
+		c.Vary(fiber.HeaderAcceptEncoding)
+
+		if ifNoneMatch(c.Get(fiber.HeaderIfNoneMatch), etag) {
+			c.Response().ResetBody()
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+		return nil
+	}
+}
+
+// NoCacheMiddleware forbids caching the responses of mutating requests (anything but
+// GET, HEAD and OPTIONS), which are specific to the request that caused them
+func NoCacheMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		default:
+			c.Set(fiber.HeaderCacheControl, "no-store")
+		}
+		return c.Next()
+	}
+}
+
+// ifNoneMatch reports whether an If-None-Match header lists etag, or is "*"
+func ifNoneMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || strings.Trim(candidate, `"`) == etag {
+			return true
+		}
+	}
+	return false
+}
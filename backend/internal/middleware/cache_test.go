@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestStaticCacheMiddleware(t *testing.T) {
+	body := "v1"
+	app := fiber.New()
+	app.Get("/static", StaticCacheMiddleware(time.Minute), func(c *fiber.Ctx) error {
+		return c.SendString(body)
+	})
+
+	get := func(ifNoneMatch string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/static", http.NoBody)
+		if ifNoneMatch != "" {
+			req.Header.Set(fiber.HeaderIfNoneMatch, ifNoneMatch)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		if err := resp.Body.Close(); err != nil {
+			t.Errorf("Failed to close response body: %v", err)
+		}
+		return resp
+	}
+
+	resp := get("")
+	etag := resp.Header.Get(fiber.HeaderETag)
+	if resp.StatusCode != fiber.StatusOK || etag == "" {
+		t.Fatalf("Expected 200 with an ETag, got %d and %q", resp.StatusCode, etag)
+	}
+	if got := resp.Header.Get(fiber.HeaderCacheControl); got != "public, max-age=60" {
+		t.Errorf("Expected Cache-Control public, max-age=60, got %q", got)
+	}
+	for _, header := range []string{fiber.HeaderExpires, fiber.HeaderLastModified} {
+		if _, err := http.ParseTime(resp.Header.Get(header)); err != nil {
+			t.Errorf("Expected an HTTP date in %s, got %q", header, resp.Header.Get(header))
+		}
+	}
+	if got := resp.Header.Get(fiber.HeaderVary); got != fiber.HeaderAcceptEncoding {
+		t.Errorf("Expected Vary: Accept-Encoding, got %q", got)
+	}
+
+	if resp := get(etag); resp.StatusCode != fiber.StatusNotModified {
+		t.Errorf("Expected 304 for a matching If-None-Match, got %d", resp.StatusCode)
+	}
+
+	// A changed body, say after a configuration reload, invalidates the ETag
+	body = "v2"
+	resp = get(etag)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected 200 once the body changed, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get(fiber.HeaderETag) == etag {
+		t.Errorf("Expected a new ETag once the body changed, got %s again", etag)
+	}
+}
+
+func TestNoCacheMiddleware(t *testing.T) {
+	app := fiber.New()
+	app.Use(NoCacheMiddleware())
+	app.All("/resource", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{"GET", ""},
+		{"POST", "no-store"},
+		{"PUT", "no-store"},
+		{"DELETE", "no-store"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(tt.method, "/resource", http.NoBody))
+			if err != nil {
+				t.Fatalf("Failed to test request: %v", err)
+			}
+			defer func() {
+				if err := resp.Body.Close(); err != nil {
+					t.Errorf("Failed to close response body: %v", err)
+				}
+			}()
+			if got := resp.Header.Get(fiber.HeaderCacheControl); got != tt.want {
+				t.Errorf("Expected Cache-Control %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
+)
+
+// uncompressedSizeKey is the local holding the size of the response body before compression
+const uncompressedSizeKey = "uncompressed_size"
+
+// Compression compresses responses with gzip, deflate or brotli, as negotiated by the
+// client's Accept-Encoding, favoring speed over ratio. Event streams, WebSocket upgrades and
+// /metrics, which negotiates its own encoding, are left alone.
+func Compression() fiber.Handler {
+	return compress.New(compress.Config{
+		Level: compress.LevelBestSpeed,
+		Next:  skipCompression,
+	})
+}
+
+// skipCompression reports whether a request's response must not be compressed. Streams
+// are recognized by the request, since the compressor is chosen before the handler runs;
+// EventSource clients always send Accept: text/event-stream.
+func skipCompression(c *fiber.Ctx) bool {
+	return c.Path() == "/metrics" ||
+		strings.EqualFold(c.Get(fiber.HeaderUpgrade), "websocket") ||
+		strings.Contains(c.Get(fiber.HeaderAccept), "text/event-stream")
+}
+
+// recordUncompressedSize stores the size of the response body, before Compression runs,
+// for RequestLogger. Streamed bodies have no size up front and are not recorded.
+func recordUncompressedSize(c *fiber.Ctx) error {
+	err := c.Next()
+	if size, ok := responseSize(c); ok {
+		c.Locals(uncompressedSizeKey, size)
+	}
+	return err
+}
+
+// responseSize returns the size of the response body as it stands. The Content-Length
+// header is only filled in when the response is written, or up front by handlers that
+// stream a body of known size; ok is false for streams of unknown size.
+func responseSize(c *fiber.Ctx) (size int, ok bool) {
+	if length := c.Response().Header.ContentLength(); length > 0 {
+		return length, true
+	}
+	if c.Response().IsBodyStream() {
+		return 0, false
+	}
+	return len(c.Response().Body()), true
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/lightshare/backend/internal/models"
+)
+
+// testDevices returns n devices shaped like a provider's device list
+func testDevices(n int) []*models.Device {
+	devices := make([]*models.Device, n)
+	for i := range devices {
+		devices[i] = &models.Device{
+			ID:           fmt.Sprintf("d073d5%06x", i),
+			AccountID:    "9b2f6a4e-3c1d-4f5e-8a7b-1c2d3e4f5a6b",
+			AccountLabel: "Home",
+			Provider:     "lifx",
+			Label:        fmt.Sprintf("Lamp %d", i),
+			Power:        "on",
+			Brightness:   0.75,
+			Connected:    true,
+		}
+	}
+	return devices
+}
+
+// bodySizes holds what RequestLogger sees of a response
+type bodySizes struct {
+	sent, uncompressed int
+}
+
+func newCompressionTestApp(sizes *bodySizes) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		err := c.Next()
+		sizes.sent, _ = responseSize(c)
+		sizes.uncompressed, _ = c.Locals(uncompressedSizeKey).(int)
+		return err
+	})
+	app.Use(Compression())
+	app.Use(recordUncompressedSize)
+
+	devices := func(c *fiber.Ctx) error {
+		return c.JSON(models.DevicePage{Devices: testDevices(50)})
+	}
+	app.Get("/devices", devices)
+	app.Get("/metrics", devices)
+	return app
+}
+
+func TestCompression(t *testing.T) {
+	testCases := []struct {
+		name         string
+		path         string
+		headers      map[string]string
+		wantEncoding string
+	}{
+		{name: "gzip", path: "/devices", headers: map[string]string{"Accept-Encoding": "gzip"}, wantEncoding: "gzip"},
+		{name: "deflate", path: "/devices", headers: map[string]string{"Accept-Encoding": "deflate"}, wantEncoding: "deflate"},
+		{name: "not accepted", path: "/devices", wantEncoding: ""},
+		{name: "event stream", path: "/devices", headers: map[string]string{"Accept-Encoding": "gzip", "Accept": "text/event-stream"}, wantEncoding: ""},
+		{name: "websocket upgrade", path: "/devices", headers: map[string]string{"Accept-Encoding": "gzip", "Upgrade": "websocket"}, wantEncoding: ""},
+		{name: "metrics", path: "/metrics", headers: map[string]string{"Accept-Encoding": "gzip"}, wantEncoding: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sizes bodySizes
+			app := newCompressionTestApp(&sizes)
+
+			req := httptest.NewRequest("GET", tc.path, http.NoBody)
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to test request: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+
+			if got := resp.Header.Get("Content-Encoding"); got != tc.wantEncoding {
+				t.Errorf("Expected Content-Encoding %q, got %q", tc.wantEncoding, got)
+			}
+			if sizes.sent != len(body) {
+				t.Errorf("Expected the logged size to be the %d bytes sent, got %d", len(body), sizes.sent)
+			}
+			if tc.wantEncoding == "" && sizes.uncompressed != len(body) {
+				t.Errorf("Expected the uncompressed size to be %d, got %d", len(body), sizes.uncompressed)
+			}
+			if tc.wantEncoding != "" && sizes.uncompressed <= len(body) {
+				t.Errorf("Expected the %d bytes sent to be fewer than the %d uncompressed", len(body), sizes.uncompressed)
+			}
+		})
+	}
+}
+
+// BenchmarkCompression_DeviceList reports how much compression shrinks a 50-device list
+func BenchmarkCompression_DeviceList(b *testing.B) {
+	var sizes bodySizes
+	app := newCompressionTestApp(&sizes)
+
+	uncompressed, err := json.Marshal(models.DevicePage{Devices: testDevices(50)})
+	if err != nil {
+		b.Fatalf("Failed to marshal devices: %v", err)
+	}
+
+	var compressed int
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest("GET", "/devices", http.NoBody)
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := app.Test(req)
+		if err != nil {
+			b.Fatalf("Failed to test request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		compressed = len(body)
+	}
+
+	b.ReportMetric(float64(len(uncompressed)), "uncompressed-bytes")
+	b.ReportMetric(float64(compressed), "compressed-bytes")
+	b.ReportMetric(100*(1-float64(compressed)/float64(len(uncompressed))), "%-saved")
+}
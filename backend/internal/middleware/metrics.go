@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/lightshare/backend/pkg/metrics"
+)
+
+// MetricsMiddleware records the count and latency of every HTTP request.
+// Requests are labelled by route pattern rather than raw path to bound cardinality.
+func MetricsMiddleware(m *metrics.Metrics) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		err := c.Next()
+
+		// The app's error handler only sets the status after the middleware chain returns
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = errorStatus(err)
+		}
+
+		m.ObserveHTTPRequest(c.Method(), c.Route().Path, strconv.Itoa(status), time.Since(start))
+
+		return err
+	}
+}
+
+// errorStatus returns the status code the app's error handler will respond to err with
+func errorStatus(err error) int {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// MetricsAuth protects the metrics endpoint with a static bearer token.
+// An empty token leaves the endpoint open.
+func MetricsAuth(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token == "" {
+			return c.Next()
+		}
+
+		provided, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "invalid metrics token",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/lightshare/backend/pkg/metrics"
+)
+
+func TestMetricsMiddleware_LabelsByRoute(t *testing.T) {
+	m := metrics.New()
+	app := fiber.New()
+	app.Use(MetricsMiddleware(m))
+	app.Get("/devices/:id", func(c *fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusNotFound, "device not found")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/devices/d073d5", http.NoBody))
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if got := testutil.ToFloat64(m.HTTPRequests.WithLabelValues("GET", "/devices/:id", "404")); got != 1 {
+		t.Errorf("Expected 1 request labelled by route and status 404, got %v", got)
+	}
+}
+
+func TestMetricsAuth(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		status int
+	}{
+		{name: "no token configured", token: "", header: "", status: fiber.StatusOK},
+		{name: "missing header", token: "secret", header: "", status: fiber.StatusUnauthorized},
+		{name: "wrong token", token: "secret", header: "Bearer nope", status: fiber.StatusUnauthorized},
+		{name: "valid token", token: "secret", header: "Bearer secret", status: fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/metrics", MetricsAuth(tt.token), func(c *fiber.Ctx) error {
+				return c.SendString("ok")
+			})
+
+			req := httptest.NewRequest("GET", "/metrics", http.NoBody)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to test request: %v", err)
+			}
+			_ = resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+}
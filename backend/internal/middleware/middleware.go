@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -8,11 +10,14 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 
+	"github.com/lightshare/backend/internal/config"
+	"github.com/lightshare/backend/pkg/ctxutil"
 	"github.com/lightshare/backend/pkg/logger"
+	"github.com/lightshare/backend/pkg/metrics"
 )
 
 // Setup sets up all middleware for the Fiber app
-func Setup(app *fiber.App) {
+func Setup(app *fiber.App, cfg config.ServerConfig, m *metrics.Metrics) {
 	// Recover from panics
 	app.Use(recover.New(recover.Config{
 		EnableStackTrace: true,
@@ -20,19 +25,89 @@ func Setup(app *fiber.App) {
 
 	// Request ID
 	app.Use(requestid.New())
+	app.Use(RequestIDContext())
+
+	// Tracing
+	app.Use(TracingMiddleware())
 
 	// CORS
-	app.Use(cors.New(cors.Config{
-		AllowOrigins:     "*",
-		AllowMethods:     "GET,POST,PUT,DELETE,PATCH,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-Request-ID",
-		ExposeHeaders:    "X-Request-ID,X-RateLimit-Limit,X-RateLimit-Remaining,X-RateLimit-Reset",
-		AllowCredentials: false,
-		MaxAge:           86400,
-	}))
+	app.Use(CORS(cfg.CORSAllowedOrigins))
 
 	// Request logging
 	app.Use(RequestLogger())
+
+	// Request metrics
+	app.Use(MetricsMiddleware(m))
+
+	// Response compression, inside the logger so it sees both body sizes
+	app.Use(Compression())
+	app.Use(recordUncompressedSize)
+}
+
+// CORS returns a middleware answering cross-origin requests from allowedOrigins, which
+// may send credentials. Without allowed origins, any origin is allowed without credentials.
+func CORS(allowedOrigins []string) fiber.Handler {
+	origins := make([]string, 0, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		origins = append(origins, strings.TrimSuffix(origin, "/"))
+	}
+
+	// Browsers never send credentials to a wildcard origin, and Fiber refuses to allow both
+	allowOrigins, allowCredentials := strings.Join(origins, ","), true
+	if len(origins) == 0 || slices.Contains(origins, "*") {
+		allowOrigins, allowCredentials = "*", false
+	}
+
+	return cors.New(cors.Config{
+		AllowOrigins:     allowOrigins,
+		AllowMethods:     "GET,POST,PUT,DELETE,PATCH,OPTIONS",
+		AllowHeaders:     "Origin,Content-Type,Accept,Accept-Encoding,Authorization,X-Request-ID,traceparent,tracestate",
+		ExposeHeaders:    "X-Request-ID,X-RateLimit-Limit,X-RateLimit-Remaining,X-RateLimit-Reset,Content-Encoding",
+		AllowCredentials: allowCredentials,
+		MaxAge:           86400,
+	})
+}
+
+// RequestIDContext stores the request ID set by the requestid middleware in
+// c.UserContext(), so that services and provider clients can pass it on to upstream APIs,
+// along with a logger that tags every entry with it
+func RequestIDContext() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if requestID := c.GetRespHeader(fiber.HeaderXRequestID); requestID != "" {
+			ctx := ctxutil.WithRequestID(c.UserContext(), requestID)
+			ctx = logger.NewContext(ctx, logger.With("request_id", requestID))
+			c.SetUserContext(ctx)
+		}
+		return c.Next()
+	}
+}
+
+// Deprecated marks the responses of a route kept for older clients as deprecated, linking
+// to the route replacing it
+func Deprecated(successor string) fiber.Handler {
+	link := "<" + successor + `>; rel="successor-version"`
+	return func(c *fiber.Ctx) error {
+		c.Set("Deprecation", "true")
+		c.Set(fiber.HeaderLink, link)
+		return c.Next()
+	}
+}
+
+// HasAuthorization reports whether a request carries credentials in its Authorization
+// header, valid or not
+func HasAuthorization(c *fiber.Ctx) bool {
+	return c.Get(fiber.HeaderAuthorization) != ""
+}
+
+// RequireFeature hides the routes of a feature that is turned off behind a 404. enabled is
+// called on every request, so the feature can be toggled without a restart.
+func RequireFeature(enabled func() bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !enabled() {
+			return fiber.ErrNotFound
+		}
+		return c.Next()
+	}
 }
 
 // RequestLogger returns a middleware that logs HTTP requests
@@ -46,16 +121,17 @@ func RequestLogger() fiber.Handler {
 		// Calculate latency
 		latency := time.Since(start)
 
-		// Get request ID
-		requestID := c.GetRespHeader("X-Request-ID")
-
-		// Log the request
-		logger.Info("HTTP request",
-			"request_id", requestID,
+		// Log the request, with the body size before and after compression; a streamed
+		// body has no length
+		responseBytes, _ := responseSize(c)
+		uncompressedSize, _ := c.Locals(uncompressedSizeKey).(int)
+		logger.WithContext(c.UserContext()).Info("HTTP request",
 			"method", c.Method(),
 			"path", c.Path(),
 			"status", c.Response().StatusCode(),
 			"latency_ms", latency.Milliseconds(),
+			"response_bytes", responseBytes,
+			"uncompressed_bytes", uncompressedSize,
 			"ip", c.IP(),
 			"user_agent", c.Get("User-Agent"),
 		)
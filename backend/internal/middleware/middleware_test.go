@@ -0,0 +1,209 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/gofiber/fiber/v2/middleware/skip"
+
+	"github.com/lightshare/backend/pkg/ctxutil"
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+func TestRequestIDContext_StoresRequestIDInUserContext(t *testing.T) {
+	useTestTracing(t)
+
+	app := fiber.New()
+	app.Use(requestid.New(), RequestIDContext(), TracingMiddleware())
+
+	var requestID string
+	app.Get("/", func(c *fiber.Ctx) error {
+		requestID = ctxutil.RequestIDFromCtx(c.UserContext())
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/", http.NoBody)
+	req.Header.Set(fiber.HeaderXRequestID, "request-123")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if requestID != "request-123" {
+		t.Errorf("Expected request ID request-123 in the user context, got %q", requestID)
+	}
+}
+
+func TestRequestIDContext_TagsEveryLogEntry(t *testing.T) {
+	useTestTracing(t)
+
+	var output bytes.Buffer
+	previous := logger.Get()
+	logger.SetDefault(slog.New(slog.NewJSONHandler(&output, nil)))
+	t.Cleanup(func() { logger.SetDefault(previous) })
+
+	app := fiber.New()
+	app.Use(requestid.New(), RequestIDContext(), TracingMiddleware(), RequestLogger())
+	app.Get("/", func(c *fiber.Ctx) error {
+		logger.WithContext(c.UserContext()).Info("Handling request")
+		logger.WithContext(c.UserContext()).Warn("Something went wrong", "error", "boom")
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/", http.NoBody)
+	req.Header.Set(fiber.HeaderXRequestID, "request-123")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	lines := bytes.Split(bytes.TrimSpace(output.Bytes()), []byte("\n"))
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 log entries, got %d: %s", len(lines), output.String())
+	}
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("Failed to decode log entry %s: %v", line, err)
+		}
+		if entry["request_id"] != "request-123" {
+			t.Errorf("Expected request_id request-123 in %s", line)
+		}
+	}
+}
+
+func TestCORS_AllowList(t *testing.T) {
+	app := fiber.New()
+	app.Use(CORS([]string{"https://app.lightshare.com", "https://staging.lightshare.com/"}))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	tests := []struct {
+		origin     string
+		wantOrigin string
+	}{
+		{origin: "https://app.lightshare.com", wantOrigin: "https://app.lightshare.com"},
+		{origin: "https://staging.lightshare.com", wantOrigin: "https://staging.lightshare.com"},
+		{origin: "https://evil.com", wantOrigin: ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", http.NoBody)
+		req.Header.Set(fiber.HeaderOrigin, tt.origin)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		_ = resp.Body.Close()
+
+		if got := resp.Header.Get(fiber.HeaderAccessControlAllowOrigin); got != tt.wantOrigin {
+			t.Errorf("Origin %s: expected Access-Control-Allow-Origin %q, got %q", tt.origin, tt.wantOrigin, got)
+		}
+		wantCredentials := ""
+		if tt.wantOrigin != "" {
+			wantCredentials = "true"
+		}
+		if got := resp.Header.Get(fiber.HeaderAccessControlAllowCredentials); got != wantCredentials {
+			t.Errorf("Origin %s: expected Access-Control-Allow-Credentials %q, got %q", tt.origin, wantCredentials, got)
+		}
+	}
+}
+
+func TestCORS_WildcardWithoutAllowList(t *testing.T) {
+	app := fiber.New()
+	app.Use(CORS(nil))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/", http.NoBody)
+	req.Header.Set(fiber.HeaderOrigin, "http://localhost:3000")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if got := resp.Header.Get(fiber.HeaderAccessControlAllowOrigin); got != "*" {
+		t.Errorf("Expected any origin to be allowed, got %q", got)
+	}
+	if got := resp.Header.Get(fiber.HeaderAccessControlAllowCredentials); got != "" {
+		t.Errorf("Expected no credentials with a wildcard origin, got %q", got)
+	}
+}
+
+func TestDeprecated_ServesLegacyResponseToAuthorizedRequests(t *testing.T) {
+	app := fiber.New()
+	app.Get("/providers",
+		skip.New(func(c *fiber.Ctx) error { return c.SendString("catalogue") }, HasAuthorization),
+		Deprecated("/api/v1/providers/connections"),
+		func(c *fiber.Ctx) error { return c.SendString("connections") },
+	)
+
+	testCases := []struct {
+		name           string
+		authorization  string
+		wantBody       string
+		wantDeprecated bool
+	}{
+		{name: "anonymous", wantBody: "catalogue"},
+		{name: "authorized", authorization: "Bearer token", wantBody: "connections", wantDeprecated: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/providers", http.NoBody)
+			if tc.authorization != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tc.authorization)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("Failed to test request: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tc.wantBody {
+				t.Errorf("Expected body %q, got %q", tc.wantBody, body)
+			}
+			if deprecated := resp.Header.Get("Deprecation") == "true"; deprecated != tc.wantDeprecated {
+				t.Errorf("Expected deprecated=%v, got %v", tc.wantDeprecated, deprecated)
+			}
+			if tc.wantDeprecated && resp.Header.Get(fiber.HeaderLink) != `</api/v1/providers/connections>; rel="successor-version"` {
+				t.Errorf("Expected a link to the successor route, got %q", resp.Header.Get(fiber.HeaderLink))
+			}
+		})
+	}
+}
+
+func TestRequireFeature(t *testing.T) {
+	enabled := false
+	app := fiber.New()
+	app.Get("/scenes", RequireFeature(func() bool { return enabled }), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	for _, tc := range []struct {
+		enabled bool
+		status  int
+	}{{false, fiber.StatusNotFound}, {true, fiber.StatusOK}} {
+		enabled = tc.enabled
+		resp, err := app.Test(httptest.NewRequest("GET", "/scenes", nil))
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("Expected %d with the feature enabled=%v, got %d", tc.status, tc.enabled, resp.StatusCode)
+		}
+	}
+}
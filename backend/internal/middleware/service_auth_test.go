@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func newServiceAuthTestApp(now func() time.Time) *fiber.App {
+	app := fiber.New()
+	app.Get("/internal/users/:id", serviceAuth("shared-secret", now), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+	return app
+}
+
+func serviceRequest(t *testing.T, app *fiber.App, path string, timestamp int64, token string) int {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", path, http.NoBody)
+	req.Header.Set(ServiceTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(ServiceTokenHeader, token)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestServiceAuthMiddleware(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	path := "/internal/users/42"
+
+	testCases := []struct {
+		name       string
+		timestamp  int64
+		token      string
+		wantStatus int
+	}{
+		{name: "valid signature", timestamp: now.Unix(), token: SignServiceRequest("shared-secret", "GET", path, now.Unix()), wantStatus: fiber.StatusOK},
+		{name: "clock skew within window", timestamp: now.Unix() + 30, token: SignServiceRequest("shared-secret", "GET", path, now.Unix()+30), wantStatus: fiber.StatusOK},
+		{name: "wrong secret", timestamp: now.Unix(), token: SignServiceRequest("other-secret", "GET", path, now.Unix()), wantStatus: fiber.StatusUnauthorized},
+		{name: "wrong method", timestamp: now.Unix(), token: SignServiceRequest("shared-secret", "POST", path, now.Unix()), wantStatus: fiber.StatusUnauthorized},
+		{name: "wrong path", timestamp: now.Unix(), token: SignServiceRequest("shared-secret", "GET", "/internal/users/43", now.Unix()), wantStatus: fiber.StatusUnauthorized},
+		{name: "timestamp not signed", timestamp: now.Unix() - 1, token: SignServiceRequest("shared-secret", "GET", path, now.Unix()), wantStatus: fiber.StatusUnauthorized},
+		{name: "expired timestamp", timestamp: now.Unix() - 31, token: SignServiceRequest("shared-secret", "GET", path, now.Unix()-31), wantStatus: fiber.StatusUnauthorized},
+		{name: "future timestamp", timestamp: now.Unix() + 31, token: SignServiceRequest("shared-secret", "GET", path, now.Unix()+31), wantStatus: fiber.StatusUnauthorized},
+		{name: "missing token", timestamp: now.Unix(), token: "", wantStatus: fiber.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := newServiceAuthTestApp(func() time.Time { return now })
+			if status := serviceRequest(t, app, path, tc.timestamp, tc.token); status != tc.wantStatus {
+				t.Errorf("Expected status %d, got %d", tc.wantStatus, status)
+			}
+		})
+	}
+}
+
+func TestServiceAuthMiddleware_RejectsReplays(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	app := newServiceAuthTestApp(func() time.Time { return now })
+	path := "/internal/users/42"
+	token := SignServiceRequest("shared-secret", "GET", path, now.Unix())
+
+	if status := serviceRequest(t, app, path, now.Unix(), token); status != fiber.StatusOK {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	if status := serviceRequest(t, app, path, now.Unix(), token); status != fiber.StatusUnauthorized {
+		t.Errorf("Expected a replayed token to be rejected, got %d", status)
+	}
+
+	// A fresh signature for the same call is accepted
+	now = now.Add(time.Second)
+	if status := serviceRequest(t, app, path, now.Unix(), SignServiceRequest("shared-secret", "GET", path, now.Unix())); status != fiber.StatusOK {
+		t.Errorf("Expected a new signature to be accepted, got %d", status)
+	}
+}
+
+func TestServiceAuthMiddleware_EmptySecretRejectsEverything(t *testing.T) {
+	now := time.Now()
+	app := fiber.New()
+	app.Get("/internal/metrics/summary", serviceAuth("", func() time.Time { return now }), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	token := SignServiceRequest("", "GET", "/internal/metrics/summary", now.Unix())
+	if status := serviceRequest(t, app, "/internal/metrics/summary", now.Unix(), token); status != fiber.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", status)
+	}
+}
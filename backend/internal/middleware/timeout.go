@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+// timeoutResult is the outcome of the handler chain run by Timeout
+type timeoutResult struct {
+	err       error
+	recovered interface{}
+}
+
+// unwrap returns the error of the handler chain, re-raising its panic if it had one so that
+// the recover middleware sees it
+func (r timeoutResult) unwrap() error {
+	if r.recovered != nil {
+		panic(r.recovered)
+	}
+	return r.err
+}
+
+// Timeout returns a middleware giving the rest of the handler chain d to complete. The chain
+// runs in its own goroutine with c.UserContext() cancelled after d, so provider calls made
+// with it abort. A chain that overruns returns a 503 error for the app's error handler once
+// it has unwound, since the Fiber context must not outlive the handler.
+func Timeout(d time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(c.UserContext(), d)
+		defer cancel()
+		c.SetUserContext(ctx)
+
+		// The server's done channel is closed when it shuts down
+		serverDone := c.Context().Done()
+
+		done := make(chan timeoutResult, 1)
+		go func() {
+			var result timeoutResult
+			defer func() {
+				if r := recover(); r != nil {
+					result.recovered = r
+				}
+				done <- result
+			}()
+
+			// Abort early when the server is shutting down or the deadline already passed
+			select {
+			case <-serverDone:
+				result.err = fiber.ErrServiceUnavailable
+			case <-ctx.Done():
+				result.err = ctx.Err()
+			default:
+				result.err = c.Next()
+			}
+		}()
+
+		select {
+		case result := <-done:
+			return result.unwrap()
+		case <-serverDone:
+			cancel()
+			return (<-done).unwrap()
+		case <-ctx.Done():
+		}
+
+		// Wait for the chain to unwind before touching the context again
+		err := (<-done).unwrap()
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return err
+		}
+
+		logger.WithContext(c.UserContext()).Warn("Request timed out",
+			"method", c.Method(),
+			"endpoint", c.Route().Path,
+			"path", c.Path(),
+			"timeout_ms", d.Milliseconds(),
+			"elapsed_ms", time.Since(start).Milliseconds(),
+		)
+
+		c.Response().ResetBody()
+		return fiber.NewError(fiber.StatusServiceUnavailable, "request timeout")
+	}
+}
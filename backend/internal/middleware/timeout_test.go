@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+)
+
+// slowProvider simulates a provider call taking delay, aborted when ctx is cancelled
+func slowProvider(ctx context.Context, delay time.Duration) error {
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestTimeout_SlowProviderReturns503(t *testing.T) {
+	app := fiber.New()
+	aborted := make(chan error, 1)
+	app.Post("/refresh", Timeout(50*time.Millisecond), func(c *fiber.Ctx) error {
+		err := slowProvider(c.UserContext(), time.Second)
+		aborted <- err
+		if err != nil {
+			return err
+		}
+		return c.JSON(fiber.Map{"devices": []string{}})
+	})
+
+	start := time.Now()
+	resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/refresh", http.NoBody), 2000)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the request to be cut short, took %v", elapsed)
+	}
+	if resp.StatusCode != fiber.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", resp.StatusCode)
+	}
+
+	if body, _ := io.ReadAll(resp.Body); string(body) != "request timeout" {
+		t.Errorf("Expected the timeout error, got %q", body)
+	}
+
+	if err := <-aborted; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the provider call to be cancelled, got %v", err)
+	}
+}
+
+func TestTimeout_FastHandlerPassesThrough(t *testing.T) {
+	app := fiber.New()
+	app.Get("/devices", Timeout(time.Second), func(c *fiber.Ctx) error {
+		if err := slowProvider(c.UserContext(), time.Millisecond); err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusTeapot).SendString("ok")
+	})
+	app.Get("/missing", Timeout(time.Second), func(*fiber.Ctx) error {
+		return fiber.ErrNotFound
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/devices", http.NoBody))
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != fiber.StatusTeapot {
+		t.Errorf("Expected the handler's status, got %d", resp.StatusCode)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/missing", http.NoBody))
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Errorf("Expected the handler's error to be returned, got %d", resp.StatusCode)
+	}
+}
+
+func TestTimeout_PanicReachesRecover(t *testing.T) {
+	app := fiber.New()
+	app.Use(recover.New())
+	app.Get("/", Timeout(time.Second), func(*fiber.Ctx) error {
+		panic("boom")
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != fiber.StatusInternalServerError {
+		t.Errorf("Expected the panic to be recovered as a 500, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/lightshare/backend/pkg/tracing"
+)
+
+// TracingMiddleware starts a server span for every request, continuing the trace from
+// the incoming traceparent header, and stores it in c.UserContext() for handlers and
+// services to create child spans from
+func TracingMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		carrier := propagation.HeaderCarrier(http.Header(c.GetReqHeaders()))
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), carrier)
+
+		ctx, span := otel.Tracer(tracing.TracerName).Start(ctx, c.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Method()),
+				attribute.String("url.path", c.Path()),
+			),
+		)
+		defer span.End()
+
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		// The route is only known once the router has matched it
+		span.SetName(c.Method() + " " + c.Route().Path)
+		span.SetAttributes(attribute.String("http.route", c.Route().Path))
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = errorStatus(err)
+			span.RecordError(err)
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, strconv.Itoa(status))
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// useTestTracing installs an in-memory tracer provider and the W3C propagator globally
+func useTestTracing(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return exporter
+}
+
+func TestTracingMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	exporter := useTestTracing(t)
+
+	app := fiber.New()
+	app.Use(TracingMiddleware())
+
+	var handlerSpan trace.SpanContext
+	app.Get("/devices/:id", func(c *fiber.Ctx) error {
+		handlerSpan = trace.SpanContextFromContext(c.UserContext())
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	req := httptest.NewRequest("GET", "/devices/d073d5", http.NoBody)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name != "GET /devices/:id" {
+		t.Errorf("Expected span name 'GET /devices/:id', got '%s'", span.Name)
+	}
+	if span.SpanKind != trace.SpanKindServer {
+		t.Errorf("Expected server span, got %s", span.SpanKind)
+	}
+	if got := span.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected span to continue the incoming trace, got trace ID %s", got)
+	}
+	if got := span.Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("Expected remote parent 00f067aa0ba902b7, got %s", got)
+	}
+	if handlerSpan.SpanID() != span.SpanContext.SpanID() {
+		t.Error("Expected the server span to be available from c.UserContext()")
+	}
+}
@@ -3,18 +3,29 @@ package models
 import (
 	"encoding/json"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
 
+// MaxAccountLabelLength is the maximum number of characters in an account label
+const MaxAccountLabelLength = 100
+
+// MetadataReadOnly is the account metadata key set to true when the account's token can
+// list but not control devices
+const MetadataReadOnly = "read_only"
+
 // Account represents a connected smart lighting provider account
 type Account struct {
 	CreatedAt         time.Time       `db:"created_at" json:"created_at"`
 	UpdatedAt         time.Time       `db:"updated_at" json:"updated_at"`
 	Provider          string          `db:"provider" json:"provider"`
 	ProviderAccountID string          `db:"provider_account_id" json:"provider_account_id"`
+	Label             string          `db:"label" json:"label"` // User-chosen display name, defaults to ProviderAccountID
 	EncryptedToken    []byte          `db:"encrypted_token" json:"-"`
+	KeyID             string          `db:"key_id" json:"-"` // KMS key the token is encrypted with; empty for the local key
 	Metadata          json.RawMessage `db:"metadata" json:"metadata,omitempty"`
+	TokenInvalidAt    *time.Time      `db:"token_invalid_at" json:"token_invalid_at,omitempty"` // When the provider last rejected the token; nil while it works
 	ID                uuid.UUID       `db:"id" json:"id"`
 	OwnerUserID       uuid.UUID       `db:"owner_user_id" json:"owner_user_id"`
 }
@@ -23,10 +34,13 @@ type Account struct {
 // This excludes sensitive fields like EncryptedToken
 type AccountResponse struct {
 	CreatedAt         time.Time              `json:"created_at"`
+	TokenInvalidAt    *time.Time             `json:"token_invalid_at,omitempty"`
 	Metadata          map[string]interface{} `json:"metadata,omitempty"`
 	Provider          string                 `json:"provider"`
 	ProviderAccountID string                 `json:"provider_account_id"`
+	Label             string                 `json:"label"`
 	ID                uuid.UUID              `json:"id"`
+	ReadOnly          bool                   `json:"read_only"`
 }
 
 // ToResponse converts an Account to an AccountResponse
@@ -35,7 +49,9 @@ func (a *Account) ToResponse() *AccountResponse {
 		ID:                a.ID,
 		Provider:          a.Provider,
 		ProviderAccountID: a.ProviderAccountID,
+		Label:             a.Label,
 		CreatedAt:         a.CreatedAt,
+		TokenInvalidAt:    a.TokenInvalidAt,
 	}
 
 	// Parse metadata if present
@@ -43,17 +59,82 @@ func (a *Account) ToResponse() *AccountResponse {
 		var metadata map[string]interface{}
 		if err := json.Unmarshal(a.Metadata, &metadata); err == nil {
 			resp.Metadata = metadata
+			resp.ReadOnly = metadata[MetadataReadOnly] == true
 		}
 	}
 
 	return resp
 }
 
+// IsReadOnly reports whether the account's token can only list devices, not control them
+func (a *Account) IsReadOnly() bool {
+	if len(a.Metadata) == 0 {
+		return false
+	}
+	var metadata struct {
+		ReadOnly bool `json:"read_only"`
+	}
+	if err := json.Unmarshal(a.Metadata, &metadata); err != nil {
+		return false
+	}
+	return metadata.ReadOnly
+}
+
+// DefaultAccountLabel returns the label of a newly connected account: its provider account
+// ID, truncated to MaxAccountLabelLength characters
+func DefaultAccountLabel(providerAccountID string) string {
+	if utf8.RuneCountInString(providerAccountID) <= MaxAccountLabelLength {
+		return providerAccountID
+	}
+	return string([]rune(providerAccountID)[:MaxAccountLabelLength])
+}
+
 // CreateAccountParams holds parameters for creating a new account
 type CreateAccountParams struct {
 	Metadata          map[string]interface{}
 	Provider          string
 	ProviderAccountID string
+	KeyID             string
 	EncryptedToken    []byte
 	OwnerUserID       uuid.UUID
 }
+
+// AccountHealth reports whether a connected account's provider token still works
+type AccountHealth struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Error     string    `json:"error,omitempty"`
+	Healthy   bool      `json:"healthy"`
+	Cached    bool      `json:"cached"`
+}
+
+// Account token statuses reported by an account status check
+const (
+	AccountStatusValid   = "valid"
+	AccountStatusInvalid = "invalid"
+	AccountStatusError   = "error"
+)
+
+// AccountStatus reports whether a connected account's provider token is still accepted,
+// along with the state of its provider circuit breaker
+type AccountStatus struct {
+	*CircuitStatus
+	LastCheckedAt time.Time `json:"last_checked_at"`
+	Status        string    `json:"status"` // "valid", "invalid" or "error"
+	Provider      string    `json:"provider"`
+	ErrorDetail   string    `json:"error_detail,omitempty"`
+}
+
+// CircuitStatus reports the state of an account's provider circuit breaker
+type CircuitStatus struct {
+	State    string `json:"state"` // "closed", "half-open" or "open"
+	Failures uint32 `json:"failures"`
+}
+
+// ProviderStatus describes a supported provider and the user's connection to it
+type ProviderStatus struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	AccountCount int    `json:"account_count"`
+	Implemented  bool   `json:"implemented"`
+	Connected    bool   `json:"connected"`
+}
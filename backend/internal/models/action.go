@@ -2,39 +2,100 @@ package models
 
 import (
 	"fmt"
+	"math"
+
+	"github.com/lightshare/backend/pkg/colorconv"
 )
 
 // ActionRequest represents a control action request from the client
 type ActionRequest struct {
-	Parameters map[string]interface{} `json:"parameters" validate:"required"`
-	Action     string                 `json:"action" validate:"required"`
+	Parameters map[string]interface{} `json:"parameters"` // Optional for toggle
+	Action     string                 `json:"action" validate:"required,oneof=power toggle brightness color temperature effect infrared"`
+	// DeferOnThrottle asks the server to retry the action later instead of failing
+	// when the provider responds with a rate limit
+	DeferOnThrottle bool `json:"defer_on_throttle,omitempty"`
+	// PreFlight asks the server to check the target device supports the action before
+	// calling the provider; set from the ?preflight= query parameter
+	PreFlight bool `json:"-"`
+	// IdempotencyKey identifies retries of the same request, which are answered with the
+	// original result; set from the Idempotency-Key header
+	IdempotencyKey string `json:"-"`
+}
+
+// TransitionComplete describes the state a selector is expected to reach once a transition finishes
+type TransitionComplete struct {
+	State    map[string]interface{} `json:"state"`
+	Selector string                 `json:"selector"`
+	Action   string                 `json:"action"`
+}
+
+// ActionCompleted reports the outcome of a device action sent to a provider
+type ActionCompleted struct {
+	Selector string `json:"selector"`
+	Action   string `json:"action"`
+	Error    string `json:"error,omitempty"`
+	Success  bool   `json:"success"`
 }
 
 // Supported action types
 const (
 	ActionPower       = "power"       // Turn on/off
+	ActionToggle      = "toggle"      // Invert the current power state
 	ActionBrightness  = "brightness"  // Adjust brightness
 	ActionColor       = "color"       // Set color (hue/saturation)
 	ActionTemperature = "temperature" // Set color temperature (kelvin)
 	ActionEffect      = "effect"      // Trigger effect (pulse, breathe, etc.)
+	ActionInfrared    = "infrared"    // Set the infrared level of night vision lights (LIFX+)
+	// ActionRamp gradually raises brightness, like a sunrise alarm. Ramps run in the
+	// background and are started with their own endpoint rather than as an ActionRequest.
+	ActionRamp = "ramp"
 )
 
 // Supported effect names
 const (
-	EffectPulse   = "pulse"
-	EffectBreathe = "breathe"
+	EffectPulse    = "pulse"
+	EffectBreathe  = "breathe"
+	EffectFlame    = "flame"
+	EffectMove     = "move"
+	EffectWaveform = "waveform"
+)
+
+// maxWaveformType is the highest waveform_type: 0 saw, 1 sine, 2 half sine, 3 triangle, 4 pulse
+const maxWaveformType = 4
+
+// Move effect directions
+const (
+	MoveForward = "forward"
+	MoveReverse = "reverse"
 )
 
 // IsValidAction checks if the action type is supported
 func (a *ActionRequest) IsValidAction() bool {
 	switch a.Action {
-	case ActionPower, ActionBrightness, ActionColor, ActionTemperature, ActionEffect:
+	case ActionPower, ActionToggle, ActionBrightness, ActionColor, ActionTemperature, ActionEffect, ActionInfrared:
 		return true
 	default:
 		return false
 	}
 }
 
+// RequiredCapability returns the device capability the action needs, or "" when every
+// device supports it
+func (a *ActionRequest) RequiredCapability() string {
+	switch a.Action {
+	case ActionColor:
+		return CapabilityColor
+	case ActionTemperature:
+		return CapabilityTemperature
+	case ActionEffect:
+		return CapabilityEffects
+	case ActionInfrared:
+		return CapabilityInfrared
+	default:
+		return ""
+	}
+}
+
 // ValidateParameters validates that required parameters are present and valid
 func (a *ActionRequest) ValidateParameters() error {
 	if !a.IsValidAction() {
@@ -44,6 +105,8 @@ func (a *ActionRequest) ValidateParameters() error {
 	switch a.Action {
 	case ActionPower:
 		return a.validatePowerParameters()
+	case ActionToggle:
+		return nil // Only the optional duration
 	case ActionBrightness:
 		return a.validateBrightnessParameters()
 	case ActionColor:
@@ -52,6 +115,8 @@ func (a *ActionRequest) ValidateParameters() error {
 		return a.validateTemperatureParameters()
 	case ActionEffect:
 		return a.validateEffectParameters()
+	case ActionInfrared:
+		return a.validateInfraredParameters()
 	default:
 		return fmt.Errorf("unknown action: %s", a.Action)
 	}
@@ -79,7 +144,27 @@ func (a *ActionRequest) validateBrightnessParameters() error {
 	return nil
 }
 
+func (a *ActionRequest) validateInfraredParameters() error {
+	level, ok := a.Parameters["level"].(float64)
+	if !ok {
+		return fmt.Errorf("missing or invalid 'level' parameter (must be number)")
+	}
+	if level < 0.0 || level > 1.0 {
+		return fmt.Errorf("invalid infrared level: %f (must be 0.0-1.0)", level)
+	}
+	return nil
+}
+
+// validateColorParameters also accepts a "hex" color in place of hue and saturation,
+// converting it so the action is dispatched like any other color change. The hex
+// color's brightness is ignored; brightness is set with its own action.
 func (a *ActionRequest) validateColorParameters() error {
+	if hex, ok := a.Parameters["hex"]; ok {
+		if err := a.convertHexColor(hex); err != nil {
+			return err
+		}
+	}
+
 	hue, hueOk := a.Parameters["hue"].(float64)
 	saturation, satOk := a.Parameters["saturation"].(float64)
 
@@ -100,13 +185,36 @@ func (a *ActionRequest) validateColorParameters() error {
 	return nil
 }
 
+// convertHexColor replaces the "hex" parameter with the equivalent hue and saturation
+func (a *ActionRequest) convertHexColor(value interface{}) error {
+	hex, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("invalid 'hex' parameter (must be string)")
+	}
+	_, hasHue := a.Parameters["hue"]
+	_, hasSaturation := a.Parameters["saturation"]
+	if hasHue || hasSaturation {
+		return fmt.Errorf("'hex' cannot be combined with 'hue' or 'saturation'")
+	}
+
+	hue, saturation, _, err := colorconv.HexToHSB(hex)
+	if err != nil {
+		return fmt.Errorf("invalid hex value: %s (must be #RRGGBB or #RGB)", hex)
+	}
+
+	delete(a.Parameters, "hex")
+	a.Parameters["hue"] = hue
+	a.Parameters["saturation"] = saturation
+	return nil
+}
+
 func (a *ActionRequest) validateTemperatureParameters() error {
 	kelvin, ok := a.Parameters["kelvin"].(float64)
 	if !ok {
 		return fmt.Errorf("missing or invalid 'kelvin' parameter (must be number)")
 	}
-	if kelvin < 1500 || kelvin > 9000 {
-		return fmt.Errorf("invalid kelvin value: %f (must be 1500-9000)", kelvin)
+	if kelvin < MinKelvin || kelvin > MaxKelvin {
+		return fmt.Errorf("invalid kelvin value: %f (must be %d-%d)", kelvin, MinKelvin, MaxKelvin)
 	}
 	return nil
 }
@@ -117,8 +225,30 @@ func (a *ActionRequest) validateEffectParameters() error {
 		return fmt.Errorf("missing or invalid 'name' parameter (must be string)")
 	}
 
-	if name != EffectPulse && name != EffectBreathe {
-		return fmt.Errorf("invalid effect name: %s (must be 'pulse' or 'breathe')", name)
+	switch name {
+	case EffectPulse, EffectBreathe, EffectFlame, EffectMove, EffectWaveform:
+	default:
+		return fmt.Errorf("invalid effect name: %s (must be 'pulse', 'breathe', 'flame', 'move' or 'waveform')", name)
+	}
+
+	for _, key := range []string{"period", "duration"} {
+		if value, ok := a.Parameters[key]; ok {
+			if seconds, isNumber := value.(float64); !isNumber || seconds < 0 {
+				return fmt.Errorf("invalid effect %s: must be a non-negative number of seconds", key)
+			}
+		}
+	}
+
+	if name == EffectMove {
+		if direction, ok := a.Parameters["direction"]; ok && direction != MoveForward && direction != MoveReverse {
+			return fmt.Errorf("invalid move direction: %v (must be 'forward' or 'reverse')", direction)
+		}
+	}
+
+	if name == EffectWaveform {
+		if err := a.validateWaveformParameters(); err != nil {
+			return err
+		}
 	}
 
 	// Color is optional for effects, but if provided should be valid
@@ -138,6 +268,24 @@ func (a *ActionRequest) validateEffectParameters() error {
 	return nil
 }
 
+func (a *ActionRequest) validateWaveformParameters() error {
+	waveformType, ok := a.Parameters["waveform_type"].(float64)
+	if !ok || waveformType != math.Trunc(waveformType) || waveformType < 0 || waveformType > maxWaveformType {
+		return fmt.Errorf("missing or invalid 'waveform_type' parameter (must be an integer 0-%d)", maxWaveformType)
+	}
+	if value, ok := a.Parameters["peak"]; ok {
+		if peak, isNumber := value.(float64); !isNumber || peak < 0.0 || peak > 1.0 {
+			return fmt.Errorf("invalid waveform peak: %v (must be 0.0-1.0)", value)
+		}
+	}
+	if value, ok := a.Parameters["persist"]; ok {
+		if _, isBool := value.(bool); !isBool {
+			return fmt.Errorf("invalid waveform persist: %v (must be boolean)", value)
+		}
+	}
+	return nil
+}
+
 // GetPowerState returns the desired power state for power actions
 func (a *ActionRequest) GetPowerState() (bool, error) {
 	if a.Action != ActionPower {
@@ -162,10 +310,112 @@ func (a *ActionRequest) GetBrightnessLevel() (float64, error) {
 	return level, nil
 }
 
-// GetDuration returns the duration parameter (optional, defaults to 0.5 seconds)
+// GetInfraredLevel returns the infrared level for infrared actions
+func (a *ActionRequest) GetInfraredLevel() (float64, error) {
+	if a.Action != ActionInfrared {
+		return 0, fmt.Errorf("not an infrared action")
+	}
+	level, ok := a.Parameters["level"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("invalid level parameter")
+	}
+	return level, nil
+}
+
+// HasTransition reports whether the action explicitly requested a gradual transition
+// Effects are excluded since they run on their own cycle/period schedule
+func (a *ActionRequest) HasTransition() bool {
+	duration, ok := a.Parameters["duration"].(float64)
+	return ok && duration > 0 && a.Action != ActionEffect
+}
+
+// DefaultTransitionDuration is the transition, in seconds, of actions that do not set one
+const DefaultTransitionDuration = 0.5
+
+// GetDuration returns the duration parameter (optional, defaults to DefaultTransitionDuration)
 func (a *ActionRequest) GetDuration() float64 {
 	if duration, ok := a.Parameters["duration"].(float64); ok {
 		return duration
 	}
-	return 0.5 // Default transition duration
+	return DefaultTransitionDuration
+}
+
+// MaxBulkActions is the most actions accepted in a single bulk action request
+const MaxBulkActions = 50
+
+// BulkActionRequest applies several actions, each to its own selector, in one request
+type BulkActionRequest struct {
+	Actions []BulkActionItem `json:"actions"`
+}
+
+// BulkActionItem is a single action within a bulk action request
+type BulkActionItem struct {
+	Parameters map[string]interface{} `json:"parameters"`
+	Selector   string                 `json:"selector"`
+	Action     string                 `json:"action"`
+}
+
+// ActionRequest returns the item as a single-selector action request
+func (i BulkActionItem) ActionRequest() *ActionRequest {
+	return &ActionRequest{Action: i.Action, Parameters: i.Parameters}
+}
+
+// Validate checks the bulk request size and every action in it. Only state actions
+// (power, brightness, color and temperature) can be batched.
+func (r *BulkActionRequest) Validate() error {
+	if len(r.Actions) == 0 {
+		return fmt.Errorf("at least one action is required")
+	}
+	if len(r.Actions) > MaxBulkActions {
+		return fmt.Errorf("too many actions: %d (maximum %d)", len(r.Actions), MaxBulkActions)
+	}
+
+	for i, item := range r.Actions {
+		if item.Selector == "" {
+			return fmt.Errorf("action %d: selector is required", i)
+		}
+		switch item.Action {
+		case ActionPower, ActionBrightness, ActionColor, ActionTemperature:
+		default:
+			return fmt.Errorf("action %d: %s actions cannot be batched", i, item.Action)
+		}
+		if err := item.ActionRequest().ValidateParameters(); err != nil {
+			return fmt.Errorf("action %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// BulkActionResult reports which actions of a bulk request were applied
+type BulkActionResult struct {
+	Succeeded []BulkActionOutcome `json:"succeeded"`
+	Failed    []BulkActionOutcome `json:"failed"`
+}
+
+// BulkActionOutcome is the outcome of a single action within a bulk request
+type BulkActionOutcome struct {
+	Selector string `json:"selector"`
+	Action   string `json:"action"`
+	Error    string `json:"error,omitempty"`
+	Index    int    `json:"index"` // Position of the action in the request
+}
+
+// GroupActionRequest applies an action to every device in a group, by name, across all of
+// the user's accounts
+type GroupActionRequest struct {
+	GroupName string        `json:"group_name" validate:"required"`
+	Action    ActionRequest `json:"action"`
+}
+
+// GroupActionResult reports the accounts a group action was applied in
+type GroupActionResult struct {
+	SucceededAccounts []string       `json:"succeeded_accounts"`
+	FailedAccounts    []AccountError `json:"failed_accounts"`
+}
+
+// AccountError reports an account in which an action could not be applied
+type AccountError struct {
+	AccountID string `json:"account_id"`
+	Error     string `json:"error"`
 }
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// API key scopes
+const (
+	APIKeyScopeDevicesRead  = "devices:read"  // List and read devices and locations
+	APIKeyScopeDevicesWrite = "devices:write" // Control devices
+)
+
+// APIKeyPrefix marks LightShare API keys so they are recognizable in logs and secret scanners
+const APIKeyPrefix = "lsk_"
+
+// APIKey represents a long-lived, revocable key for server-to-server automations
+type APIKey struct {
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+	ExpiresAt  *time.Time     `db:"expires_at" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time     `db:"last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time     `db:"revoked_at" json:"-"`
+	Name       string         `db:"name" json:"name"`
+	Prefix     string         `db:"prefix" json:"prefix"`
+	KeyHash    string         `db:"key_hash" json:"-"`
+	Scopes     pq.StringArray `db:"scopes" json:"scopes"`
+	UserEmail  string         `db:"user_email" json:"-"` // Owner's email, only loaded when authenticating
+	UserRole   string         `db:"user_role" json:"-"`  // Owner's role, only loaded when authenticating
+	ID         uuid.UUID      `db:"id" json:"id"`
+	UserID     uuid.UUID      `db:"user_id" json:"user_id"`
+}
+
+// CreateAPIKeyParams holds parameters for creating a new API key
+type CreateAPIKeyParams struct {
+	ExpiresAt *time.Time
+	Name      string
+	Prefix    string
+	KeyHash   string
+	Scopes    []string
+	UserID    uuid.UUID
+}
+
+// IsValidAPIKeyScope checks if the scope is supported
+func IsValidAPIKeyScope(scope string) bool {
+	return scope == APIKeyScopeDevicesRead || scope == APIKeyScopeDevicesWrite
+}
+
+// HasScope checks if the key grants a scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExpired returns true if the key has an expiry in the past
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !k.ExpiresAt.After(now)
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEventType identifies the kind of authentication event
+type AuditEventType string
+
+// Audit event types
+const (
+	EventSignup        AuditEventType = "signup"
+	EventEmailVerified AuditEventType = "email_verified"
+	EventLogin         AuditEventType = "login" // Metadata "method": password, magic_link or totp
+	EventTokenRefresh  AuditEventType = "token_refresh"
+	EventLogout        AuditEventType = "logout"
+	EventLogoutAll     AuditEventType = "logout_all"
+	EventTokenReuse    AuditEventType = "token_reuse_detected" // Metadata "family_id": the revoked token family
+	EventSessionRevoke AuditEventType = "session_revoked"      // Metadata "session_id": the revoked session
+	EventUserDeleted   AuditEventType = "user_deleted"         // Metadata "purge_at": when the user's data is purged
+	EventEmailChanged  AuditEventType = "email_changed"        // Metadata "previous_email": the replaced address
+	EventAccountLocked AuditEventType = "account_locked"       // Metadata "locked_until": when logins are accepted again
+	EventUserRestored  AuditEventType = "user_restored"
+	// EventAdminImpersonation is recorded for the impersonated user; metadata "admin_id" and
+	// "reason": the admin the token was issued to and why
+	EventAdminImpersonation AuditEventType = "admin_impersonation"
+)
+
+// DefaultAuditLogLimit is the audit log page size when none is requested
+const DefaultAuditLogLimit = 50
+
+// AuditEvent is an immutable record of an authentication event
+type AuditEvent struct {
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+	UserID    *uuid.UUID      `db:"user_id" json:"user_id,omitempty"` // Cleared when the user is deleted
+	IPAddress *string         `db:"ip_address" json:"ip_address,omitempty"`
+	UserAgent *string         `db:"user_agent" json:"user_agent,omitempty"`
+	EventType AuditEventType  `db:"event_type" json:"event_type"`
+	Metadata  json.RawMessage `db:"metadata" json:"metadata,omitempty"`
+	ID        uuid.UUID       `db:"id" json:"id"`
+}
+
+// AuditLogPage is a page of a user's audit events, newest first
+type AuditLogPage struct {
+	Events     []*AuditEvent `json:"events"`
+	NextCursor string        `json:"next_cursor,omitempty"` // Pass as ?before= for the next page
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/lightshare/backend/pkg/colorconv"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+const (
+	// MaxHarmonyColors is the most colors a harmony palette may have
+	MaxHarmonyColors = MaxBulkActions
+	// DefaultHarmonySaturation is the saturation of palette colors when none is given
+	DefaultHarmonySaturation = 1.0
+	// DefaultHarmonyBrightness is the brightness of palette colors when none is given
+	DefaultHarmonyBrightness = 0.8
+)
+
+// HarmonyRequest describes a color palette in harmony with a base hue
+type HarmonyRequest struct {
+	Saturation *float64 `json:"saturation,omitempty"` // Defaults to DefaultHarmonySaturation
+	Brightness *float64 `json:"brightness,omitempty"` // Defaults to DefaultHarmonyBrightness
+	Mode       string   `json:"mode"`
+	Hue        float64  `json:"hue"`
+	Count      int      `json:"count,omitempty"` // Number of colors; 0 uses the mode's own palette
+}
+
+// HarmonyColor is a single color of a harmony palette
+type HarmonyColor struct {
+	Hex        string  `json:"hex"`
+	Hue        float64 `json:"hue"`
+	Saturation float64 `json:"saturation"`
+	Brightness float64 `json:"brightness"`
+}
+
+// Validate checks the mode and that every value is within range
+func (r *HarmonyRequest) Validate() error {
+	switch r.Mode {
+	case providers.HarmonyComplementary, providers.HarmonyTriadic, providers.HarmonyAnalogous, providers.HarmonySplitComplementary:
+	default:
+		return fmt.Errorf("mode must be one of complementary, triadic, analogous or split_complementary")
+	}
+	if r.Hue < 0 || r.Hue > 360 {
+		return fmt.Errorf("hue must be between 0 and 360")
+	}
+	if r.Count < 0 || r.Count > MaxHarmonyColors {
+		return fmt.Errorf("count must be between 1 and %d", MaxHarmonyColors)
+	}
+	if r.Saturation != nil && (*r.Saturation < 0 || *r.Saturation > 1) {
+		return fmt.Errorf("saturation must be between 0.0 and 1.0")
+	}
+	if r.Brightness != nil && (*r.Brightness < 0 || *r.Brightness > 1) {
+		return fmt.Errorf("brightness must be between 0.0 and 1.0")
+	}
+	return nil
+}
+
+// Palette returns the colors of a validated request
+func (r *HarmonyRequest) Palette() ([]HarmonyColor, error) {
+	hues, err := providers.HarmonyHues(r.Mode, r.Hue, r.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	saturation, brightness := DefaultHarmonySaturation, DefaultHarmonyBrightness
+	if r.Saturation != nil {
+		saturation = *r.Saturation
+	}
+	if r.Brightness != nil {
+		brightness = *r.Brightness
+	}
+
+	palette := make([]HarmonyColor, len(hues))
+	for i, hue := range hues {
+		palette[i] = HarmonyColor{
+			Hue:        hue,
+			Saturation: saturation,
+			Brightness: brightness,
+			Hex:        colorconv.HSBToHex(hue, saturation, brightness),
+		}
+	}
+	return palette, nil
+}
+
+// ApplyHarmonyRequest applies a harmony palette to the color lights of an account
+type ApplyHarmonyRequest struct {
+	HarmonyRequest
+	Duration *float64 `json:"duration,omitempty"` // Transition in seconds; defaults to DefaultTransitionDuration
+}
+
+// GetDuration returns the transition duration, DefaultTransitionDuration when none is given
+func (r *ApplyHarmonyRequest) GetDuration() float64 {
+	if r.Duration != nil {
+		return *r.Duration
+	}
+	return DefaultTransitionDuration
+}
+
+// Validate checks the palette and the duration
+func (r *ApplyHarmonyRequest) Validate() error {
+	if r.Duration != nil && *r.Duration < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+	return r.HarmonyRequest.Validate()
+}
+
+// HarmonyApplication is the outcome of setting one light to a palette color
+type HarmonyApplication struct {
+	Color    HarmonyColor `json:"color"`
+	DeviceID string       `json:"device_id"`
+	Label    string       `json:"label"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// ApplyHarmonyResult reports which lights were set to their palette color
+type ApplyHarmonyResult struct {
+	Succeeded []HarmonyApplication `json:"succeeded"`
+	Failed    []HarmonyApplication `json:"failed"`
+}
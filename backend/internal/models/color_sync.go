@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// ColorSyncDuration is the transition, in seconds, of the lights mirroring the leader
+	ColorSyncDuration = 0.5
+	// DefaultColorSyncInterval is how often a continuous color sync re-reads the leader
+	DefaultColorSyncInterval = 5 * time.Second
+	// MinColorSyncInterval and MaxColorSyncInterval bound the interval of a continuous sync
+	MinColorSyncInterval = 2 * time.Second
+	MaxColorSyncInterval = 5 * time.Minute
+)
+
+// ColorSyncRequest mirrors the current hue and saturation of a leader device, and
+// optionally its brightness, onto the lights a selector targets
+type ColorSyncRequest struct {
+	LeaderDeviceID    string `json:"leader_device_id" validate:"required,max=255"`
+	TargetSelector    string `json:"target_selector" validate:"required,max=255"`
+	IncludeBrightness bool   `json:"include_brightness"`
+}
+
+// Validate checks that a leader and targets are given
+func (r *ColorSyncRequest) Validate() error {
+	if r.LeaderDeviceID == "" {
+		return errors.New("leader_device_id is required")
+	}
+	if r.TargetSelector == "" {
+		return errors.New("target_selector is required")
+	}
+	return nil
+}
+
+// ValidateColorSyncInterval checks the interval of a continuous color sync
+func ValidateColorSyncInterval(interval time.Duration) error {
+	if interval < MinColorSyncInterval || interval > MaxColorSyncInterval {
+		return fmt.Errorf("interval must be between %s and %s", MinColorSyncInterval, MaxColorSyncInterval)
+	}
+	return nil
+}
+
+// ColorSyncUpdate reports one round of a color sync: the leader's color pushed to the
+// targets, or why it could not be
+type ColorSyncUpdate struct {
+	Color      *DeviceColor `json:"color,omitempty"`
+	Brightness *float64     `json:"brightness,omitempty"` // Set when the brightness was mirrored too
+	SyncedAt   time.Time    `json:"synced_at"`
+	Error      string       `json:"error,omitempty"`
+}
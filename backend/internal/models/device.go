@@ -1,11 +1,31 @@
 package models
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+)
+
 // Power state constants
 const (
 	PowerStateOn  = "on"
 	PowerStateOff = "off"
 )
 
+// Device capabilities required by control actions
+const (
+	CapabilityColor       = "color"
+	CapabilityTemperature = "temperature"
+	CapabilityEffects     = "effects"
+	CapabilityInfrared    = "infrared"
+)
+
+// MetadataRawKey is the metadata key holding the provider-native device payload
+const MetadataRawKey = "raw"
+
 // Device represents a smart light device from any provider (LIFX, Hue, etc.)
 type Device struct {
 	Group        *DeviceGroup           `json:"group,omitempty"`
@@ -13,16 +33,44 @@ type Device struct {
 	Location     *DeviceLocation        `json:"location,omitempty"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
 	AccountID    string                 `json:"account_id"`
+	AccountLabel string                 `json:"account_label,omitempty"` // Label of the connection the device belongs to
 	Provider     string                 `json:"provider"`
 	Label        string                 `json:"label"`
 	Power        string                 `json:"power"`
 	ID           string                 `json:"id"`
 	Capabilities []string               `json:"capabilities"`
 	Brightness   float64                `json:"brightness"`
+	HealthScore  int                    `json:"health_score"` // DeviceScore of the device, 0-100
 	Connected    bool                   `json:"connected"`
 	Reachable    bool                   `json:"reachable"`
 }
 
+// DeviceDiscovery is the result of a device refresh, diffed against the previously cached list
+type DeviceDiscovery struct {
+	Devices []*Device `json:"devices"`
+	Added   []*Device `json:"added"`
+	Removed []*Device `json:"removed"`
+}
+
+// DeviceChangeEvent reports the fields of a device whose state changed since it was last polled
+type DeviceChangeEvent struct {
+	ChangedFields map[string]interface{} `json:"changed_fields"`
+	ID            string                 `json:"id"`
+}
+
+// DeviceSummary aggregates the state of every device of a user
+type DeviceSummary struct {
+	ByProvider    map[string]int `json:"by_provider"`
+	ByGroup       map[string]int `json:"by_group"` // Devices without a group are not counted
+	Total         int            `json:"total"`
+	Online        int            `json:"online"`      // Reachable and powered on
+	Offline       int            `json:"offline"`     // Reachable and powered off
+	Unreachable   int            `json:"unreachable"` // Not reachable, whatever their last power state
+	AccountsCount int            `json:"accounts_count"`
+	// AvgBrightness is the average brightness of online devices, 0 when none is on
+	AvgBrightness float64 `json:"avg_brightness"`
+}
+
 // DeviceColor represents the color state of a device
 type DeviceColor struct {
 	Hue        float64 `json:"hue"`        // 0-360 degrees
@@ -59,15 +107,160 @@ func (d *Device) HasCapability(capability string) bool {
 
 // SupportsColor returns true if the device supports color control
 func (d *Device) SupportsColor() bool {
-	return d.HasCapability("color")
+	return d.HasCapability(CapabilityColor)
 }
 
 // SupportsTemperature returns true if the device supports color temperature control
 func (d *Device) SupportsTemperature() bool {
-	return d.HasCapability("temperature")
+	return d.HasCapability(CapabilityTemperature)
 }
 
 // SupportsEffects returns true if the device supports effects (LIFX-specific)
 func (d *Device) SupportsEffects() bool {
-	return d.HasCapability("effects")
+	return d.HasCapability(CapabilityEffects)
+}
+
+// SupportsInfrared returns true if the device has an infrared channel (LIFX+)
+func (d *Device) SupportsInfrared() bool {
+	return d.HasCapability(CapabilityInfrared)
+}
+
+// Health score weights; a healthy device scores 100
+const (
+	healthScoreConnected  = 40
+	healthScoreReachable  = 40
+	healthScorePoweredOn  = 10
+	healthScoreBrightness = 5
+	healthScoreColor      = 5
+	// healthScoreDarkPenalty is taken off devices powered on with zero brightness, which
+	// usually points at a firmware bug
+	healthScoreDarkPenalty = 50
+)
+
+// DeviceScore rates the health of a device from 0 to 100, so lights having issues can
+// be listed first
+func DeviceScore(d *Device) int {
+	score := 0
+	if d.Connected {
+		score += healthScoreConnected
+	}
+	if d.Reachable {
+		score += healthScoreReachable
+	}
+	if d.IsOn() {
+		score += healthScorePoweredOn
+	}
+	if d.Brightness > 0 {
+		score += healthScoreBrightness
+	}
+	if d.Color != nil {
+		score += healthScoreColor
+	}
+	if d.IsOn() && d.Brightness == 0 {
+		score = max(score-healthScoreDarkPenalty, 0)
+	}
+	return score
+}
+
+// Device listing orders besides the default account then provider order
+const (
+	DeviceSortHealthAsc  = "health_asc"  // Least healthy first
+	DeviceSortHealthDesc = "health_desc" // Healthiest first
+)
+
+// ValidDeviceSort reports whether sort is a device listing order; empty is the default order
+func ValidDeviceSort(sort string) bool {
+	return sort == "" || sort == DeviceSortHealthAsc || sort == DeviceSortHealthDesc
+}
+
+// DeviceFilter narrows a device listing; empty fields match every device
+type DeviceFilter struct {
+	LabelContains string // Case-insensitive substring of the label
+	GroupName     string // Group name, compared case-insensitively
+	LocationName  string // Location name, compared case-insensitively
+	PowerState    string // PowerStateOn or PowerStateOff
+	Capability    string // A capability the device must support
+}
+
+// Matches reports whether the device satisfies every set field of the filter
+func (f DeviceFilter) Matches(d *Device) bool {
+	if f.LabelContains != "" && !strings.Contains(strings.ToLower(d.Label), strings.ToLower(f.LabelContains)) {
+		return false
+	}
+	if f.GroupName != "" && (d.Group == nil || !strings.EqualFold(d.Group.Name, f.GroupName)) {
+		return false
+	}
+	if f.LocationName != "" && (d.Location == nil || !strings.EqualFold(d.Location.Name, f.LocationName)) {
+		return false
+	}
+	if f.PowerState != "" && d.Power != f.PowerState {
+		return false
+	}
+	if f.Capability != "" && !d.HasCapability(f.Capability) {
+		return false
+	}
+	return true
+}
+
+// Apply returns the devices matching the filter, in order
+func (f DeviceFilter) Apply(devices []*Device) []*Device {
+	if f == (DeviceFilter{}) {
+		return devices
+	}
+
+	matches := make([]*Device, 0, len(devices))
+	for _, device := range devices {
+		if f.Matches(device) {
+			matches = append(matches, device)
+		}
+	}
+	return matches
+}
+
+// WithoutRawPayload returns the device without the provider-native payload in its
+// metadata. The device itself is left untouched, as it may be shared with other requests.
+func (d *Device) WithoutRawPayload() *Device {
+	if _, ok := d.Metadata[MetadataRawKey]; !ok {
+		return d
+	}
+
+	stripped := *d
+	stripped.Metadata = maps.Clone(d.Metadata)
+	delete(stripped.Metadata, MetadataRawKey)
+	if len(stripped.Metadata) == 0 {
+		stripped.Metadata = nil
+	}
+	return &stripped
+}
+
+// Clone returns a copy of the device that can be modified without affecting d. Values
+// nested in the metadata are shared.
+func (d *Device) Clone() *Device {
+	clone := *d
+	clone.Metadata = maps.Clone(d.Metadata)
+	clone.Capabilities = slices.Clone(d.Capabilities)
+	if d.Group != nil {
+		group := *d.Group
+		clone.Group = &group
+	}
+	if d.Color != nil {
+		color := *d.Color
+		clone.Color = &color
+	}
+	if d.Location != nil {
+		location := *d.Location
+		clone.Location = &location
+	}
+	return &clone
+}
+
+// DevicesETag returns a hash of the JSON of devices, identifying a device list so
+// clients can skip downloading it again while it is unchanged
+func DevicesETag(devices []*Device) string {
+	data, err := json.Marshal(devices)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
 }
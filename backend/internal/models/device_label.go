@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxDeviceLabelLength is the maximum length of a user's nickname for a device
+const MaxDeviceLabelLength = 100
+
+// DeviceMetadataProviderLabel is the device metadata key holding the label the provider
+// reports, set when a user's nickname replaces it
+const DeviceMetadataProviderLabel = "provider_label"
+
+// DeviceLabel is a user's nickname for a device, shown instead of the provider's label
+type DeviceLabel struct {
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	Provider  string    `db:"provider" json:"provider"`
+	DeviceID  string    `db:"device_id" json:"device_id"`
+	Label     string    `db:"label" json:"label"`
+	UserID    uuid.UUID `db:"user_id" json:"-"`
+}
+
+// SetDeviceLabelRequest represents the request body for renaming a device
+type SetDeviceLabelRequest struct {
+	Label string `json:"label" validate:"required,max=100"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeviceStateEventType identifies what produced a device state event
+type DeviceStateEventType string
+
+// Device state event types
+const (
+	// DeviceStateAction is an action sent to the device. NewState holds the action and
+	// its parameters, OldState the device's state before it (null when unknown).
+	DeviceStateAction DeviceStateEventType = "action"
+	// DeviceStateChanged is a change detected when refreshing the account's devices.
+	// OldState and NewState hold the device's state before and after.
+	DeviceStateChanged DeviceStateEventType = "state_changed"
+)
+
+// DefaultDeviceHistoryLimit is the number of device state events returned when none is requested
+const DefaultDeviceHistoryLimit = 100
+
+// MaxDeviceHistoryLimit is the maximum number of device state events returned at once
+const MaxDeviceHistoryLimit = 500
+
+// DeviceStateEvent is an immutable record of a device's state changing
+type DeviceStateEvent struct {
+	CreatedAt time.Time            `db:"created_at" json:"created_at"`
+	DeviceID  string               `db:"device_id" json:"device_id"`
+	Provider  string               `db:"provider" json:"provider"`
+	EventType DeviceStateEventType `db:"event_type" json:"event_type"`
+	OldState  json.RawMessage      `db:"old_state_json" json:"old_state"`
+	NewState  json.RawMessage      `db:"new_state_json" json:"new_state"`
+	ID        uuid.UUID            `db:"id" json:"id"`
+	AccountID uuid.UUID            `db:"account_id" json:"account_id"`
+}
+
+// DeviceHistory is a device's state events in a time range, oldest first
+type DeviceHistory struct {
+	From   time.Time           `json:"from"`
+	To     time.Time           `json:"to"`
+	Events []*DeviceStateEvent `json:"events"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "testing"
+
+func TestDeviceScore(t *testing.T) {
+	color := &DeviceColor{Hue: 120, Saturation: 1, Kelvin: 3500}
+
+	tests := []struct {
+		name   string
+		device Device
+		want   int
+	}{
+		{"healthy light on", Device{Connected: true, Reachable: true, Power: PowerStateOn, Brightness: 0.8, Color: color}, 100},
+		{"healthy light off", Device{Connected: true, Reachable: true, Power: PowerStateOff, Brightness: 0.8, Color: color}, 90},
+		{"white light on", Device{Connected: true, Reachable: true, Power: PowerStateOn, Brightness: 0.8}, 95},
+		{"connected but unreachable", Device{Connected: true, Power: PowerStateOn, Brightness: 0.8, Color: color}, 60},
+		{"reachable but disconnected", Device{Reachable: true, Power: PowerStateOn, Brightness: 0.8, Color: color}, 60},
+		{"disconnected and unreachable", Device{Power: PowerStateOff, Brightness: 0.8, Color: color}, 10},
+		{"disconnected, unreachable and no state", Device{}, 0},
+		{"on at zero brightness", Device{Connected: true, Reachable: true, Power: PowerStateOn, Color: color}, 45},
+		{"unreachable on at zero brightness", Device{Connected: true, Power: PowerStateOn}, 0},
+		{"off at zero brightness", Device{Connected: true, Reachable: true, Power: PowerStateOff, Color: color}, 85},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DeviceScore(&tt.device); got != tt.want {
+				t.Errorf("Expected score %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrEmptyStateRequest is returned when a state request sets no properties
+var ErrEmptyStateRequest = errors.New("state request must set at least one property")
+
+// Location represents a home/location derived from an account's device list
+type Location struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Groups      []DeviceGroup `json:"groups"`
+	DeviceCount int           `json:"device_count"`
+}
+
+// Group represents a group/room derived from an account's device list, with the number of
+// devices it holds
+type Group struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DeviceCount int    `json:"device_count"`
+}
+
+// StateRequest is a combined state applied to several devices at once
+type StateRequest struct {
+	Power      *string  `json:"power,omitempty"`
+	Brightness *float64 `json:"brightness,omitempty"`
+	Hue        *float64 `json:"hue,omitempty"`
+	Saturation *float64 `json:"saturation,omitempty"`
+	Kelvin     *float64 `json:"kelvin,omitempty"`
+	Duration   *float64 `json:"duration,omitempty"`
+}
+
+// StateResult reports the outcome of each property applied by a state request
+type StateResult struct {
+	Results   []ActionResult `json:"results"`
+	Succeeded int            `json:"succeeded"`
+	Failed    int            `json:"failed"`
+}
+
+// ActionResult is the outcome of a single action within a combined request
+type ActionResult struct {
+	DeviceID string `json:"device_id,omitempty"` // Set when the request targeted several devices individually
+	Action   string `json:"action"`
+	Error    string `json:"error,omitempty"`
+	Success  bool   `json:"success"`
+}
+
+// Actions splits the combined state into validated single-property actions
+func (r *StateRequest) Actions() ([]*ActionRequest, error) {
+	actions := make([]*ActionRequest, 0, 4)
+
+	add := func(action string, params map[string]interface{}) {
+		if r.Duration != nil {
+			params["duration"] = *r.Duration
+		}
+		actions = append(actions, &ActionRequest{Action: action, Parameters: params})
+	}
+
+	if r.Power != nil {
+		add(ActionPower, map[string]interface{}{"state": *r.Power})
+	}
+	if r.Brightness != nil {
+		add(ActionBrightness, map[string]interface{}{"level": *r.Brightness})
+	}
+	if r.Hue != nil || r.Saturation != nil {
+		if r.Hue == nil || r.Saturation == nil {
+			return nil, fmt.Errorf("hue and saturation must be set together")
+		}
+		add(ActionColor, map[string]interface{}{"hue": *r.Hue, "saturation": *r.Saturation})
+	}
+	if r.Kelvin != nil {
+		add(ActionTemperature, map[string]interface{}{"kelvin": *r.Kelvin})
+	}
+
+	if len(actions) == 0 {
+		return nil, ErrEmptyStateRequest
+	}
+
+	for _, action := range actions {
+		if err := action.ValidateParameters(); err != nil {
+			return nil, err
+		}
+	}
+
+	return actions, nil
+}
+
+// Partial reports whether some, but not all, actions failed
+func (r *StateResult) Partial() bool {
+	return r.Failed > 0 && r.Succeeded > 0
+}
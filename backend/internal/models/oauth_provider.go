@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OAuthProvider links a user to their identity at a social sign-in provider
+type OAuthProvider struct {
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	Provider       string    `db:"provider" json:"provider"`
+	ProviderUserID string    `db:"provider_user_id" json:"provider_user_id"`
+	ID             uuid.UUID `db:"id" json:"id"`
+	UserID         uuid.UUID `db:"user_id" json:"user_id"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page size bounds for paginated listings
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// PaginationOptions selects a page of a cursor-paginated listing
+type PaginationOptions struct {
+	Cursor string // Empty for the first page
+	Sort   string // DeviceSortHealthAsc or DeviceSortHealthDesc; empty for the default order
+	Limit  int
+}
+
+// PageLimit returns the limit clamped to (0, MaxPageLimit], defaulting to DefaultPageLimit
+func (o PaginationOptions) PageLimit() int {
+	if o.Limit <= 0 {
+		return DefaultPageLimit
+	}
+	if o.Limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return o.Limit
+}
+
+// DevicePage is one page of a device listing
+type DevicePage struct {
+	Devices       []*Device `json:"devices"`
+	NextCursor    string    `json:"next_cursor"` // Empty on the last page
+	ETag          string    `json:"-"`           // DevicesETag of Devices, when already known
+	Total         int       `json:"total"`
+	FilteredCount int       `json:"filtered_count"` // Devices matching the filter, across all pages
+	// Errors lists the accounts whose devices could not be fetched; their devices are
+	// missing from the page rather than failing the whole listing
+	Errors []ProviderError `json:"errors,omitempty"`
+}
+
+// ProviderError reports an account whose devices could not be fetched. Error is the
+// client-safe message of Cause, filled in when the page is presented.
+type ProviderError struct {
+	Cause     error  `json:"-"`
+	AccountID string `json:"account_id"`
+	Error     string `json:"error"`
+}
+
+// EncodeDeviceCursor encodes the position of a device within its account's device list
+func EncodeDeviceCursor(accountID string, index int) string {
+	return base64.URLEncoding.EncodeToString([]byte(accountID + ":" + strconv.Itoa(index)))
+}
+
+// DecodeDeviceCursor decodes a cursor produced by EncodeDeviceCursor
+func DecodeDeviceCursor(cursor string) (string, int, error) {
+	decoded, err := base64.URLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+
+	accountID, indexStr, ok := strings.Cut(string(decoded), ":")
+	if !ok || accountID == "" {
+		return "", 0, ErrInvalidCursor
+	}
+
+	index, err := strconv.Atoi(indexStr)
+	if err != nil || index < 0 {
+		return "", 0, ErrInvalidCursor
+	}
+
+	return accountID, index, nil
+}
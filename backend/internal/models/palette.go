@@ -0,0 +1,109 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Ways ApplyPaletteRequest spreads a palette across lights
+const (
+	// PaletteDistributionCycle gives each light the next palette color, wrapping around
+	PaletteDistributionCycle = "cycle"
+	// PaletteDistributionGradient fades from the first palette color to the last across the lights
+	PaletteDistributionGradient = "gradient"
+	// PaletteDistributionRandom gives the lights the colors of a cycle in random order
+	PaletteDistributionRandom = "random"
+)
+
+// MaxPaletteColors is the maximum number of colors in a palette
+const MaxPaletteColors = 32
+
+// ColorEntry is a color of a palette. An entry with a kelvin and no saturation is a white
+// set as a color temperature; a brightness of 0 leaves the light's brightness unchanged.
+type ColorEntry struct {
+	Hue        float64 `json:"hue"`        // 0-360 degrees
+	Saturation float64 `json:"saturation"` // 0.0-1.0
+	Brightness float64 `json:"brightness"` // 0.0-1.0
+	Kelvin     int     `json:"kelvin"`     // 1500-9000, or 0
+}
+
+// KelvinOnly reports whether the entry is a white set as a color temperature
+func (e ColorEntry) KelvinOnly() bool {
+	return e.Kelvin > 0 && e.Saturation == 0
+}
+
+// Validate checks the entry's values are in range
+func (e ColorEntry) Validate() error {
+	if e.Hue < 0 || e.Hue > 360 {
+		return fmt.Errorf("hue must be between 0 and 360")
+	}
+	if e.Saturation < 0 || e.Saturation > 1 {
+		return fmt.Errorf("saturation must be between 0 and 1")
+	}
+	if e.Brightness < 0 || e.Brightness > 1 {
+		return fmt.Errorf("brightness must be between 0 and 1")
+	}
+	if e.Kelvin != 0 && (e.Kelvin < MinKelvin || e.Kelvin > MaxKelvin) {
+		return fmt.Errorf("kelvin must be between %d and %d", MinKelvin, MaxKelvin)
+	}
+	return nil
+}
+
+// ApplyPaletteRequest spreads a palette across the lights of an account a selector targets,
+// in alphabetical order of label
+type ApplyPaletteRequest struct {
+	Duration     *float64     `json:"duration,omitempty"` // Transition in seconds; defaults to DefaultTransitionDuration
+	AccountID    string       `json:"account_id" validate:"required"`
+	Selector     string       `json:"selector" validate:"required,max=255"`
+	Distribution string       `json:"distribution"` // cycle (default), gradient or random
+	Palette      []ColorEntry `json:"palette"`
+}
+
+// GetDuration returns the transition duration, DefaultTransitionDuration when none is given
+func (r *ApplyPaletteRequest) GetDuration() float64 {
+	if r.Duration != nil {
+		return *r.Duration
+	}
+	return DefaultTransitionDuration
+}
+
+// GetDistribution returns the distribution, PaletteDistributionCycle when none is given
+func (r *ApplyPaletteRequest) GetDistribution() string {
+	if r.Distribution == "" {
+		return PaletteDistributionCycle
+	}
+	return r.Distribution
+}
+
+// Validate checks the palette, the distribution and the duration
+func (r *ApplyPaletteRequest) Validate() error {
+	if len(r.Palette) == 0 {
+		return errors.New("palette must have at least one color")
+	}
+	if len(r.Palette) > MaxPaletteColors {
+		return fmt.Errorf("palette must have at most %d colors", MaxPaletteColors)
+	}
+	for i, entry := range r.Palette {
+		if err := entry.Validate(); err != nil {
+			return fmt.Errorf("palette[%d]: %w", i, err)
+		}
+	}
+
+	switch r.GetDistribution() {
+	case PaletteDistributionCycle, PaletteDistributionGradient, PaletteDistributionRandom:
+	default:
+		return fmt.Errorf("distribution must be one of %s, %s or %s",
+			PaletteDistributionCycle, PaletteDistributionGradient, PaletteDistributionRandom)
+	}
+
+	if r.Duration != nil && *r.Duration < 0 {
+		return errors.New("duration must not be negative")
+	}
+	return nil
+}
+
+// ApplyPaletteResult counts the lights set to their palette color and those that failed
+type ApplyPaletteResult struct {
+	AppliedTo int `json:"applied_to"`
+	Failed    int `json:"failed"`
+}
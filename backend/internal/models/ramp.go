@@ -0,0 +1,52 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// MaxRampMinutes is the longest brightness ramp that can be started
+	MaxRampMinutes = 180
+	// RampStartKelvin is the warm white a ramp with a target temperature starts from
+	RampStartKelvin = 2000
+)
+
+// RampRequest starts a brightness ramp: the lights are turned on at zero brightness and
+// brought up to TargetBrightness over RampMinutes, warming from RampStartKelvin to
+// TargetKelvin along the way when it is set
+type RampRequest struct {
+	TargetKelvin     *int    `json:"target_kelvin,omitempty"`
+	TargetBrightness float64 `json:"target_brightness"`
+	RampMinutes      int     `json:"ramp_minutes"`
+}
+
+// Duration returns how long the ramp takes
+func (r *RampRequest) Duration() time.Duration {
+	return time.Duration(r.RampMinutes) * time.Minute
+}
+
+// Validate checks the target brightness, duration and temperature
+func (r *RampRequest) Validate() error {
+	if r.TargetBrightness <= 0 || r.TargetBrightness > 1 {
+		return fmt.Errorf("target_brightness must be greater than 0 and at most 1")
+	}
+	if r.RampMinutes < 1 || r.RampMinutes > MaxRampMinutes {
+		return fmt.Errorf("ramp_minutes must be between 1 and %d", MaxRampMinutes)
+	}
+	if r.TargetKelvin != nil && (*r.TargetKelvin < MinKelvin || *r.TargetKelvin > MaxKelvin) {
+		return fmt.Errorf("target_kelvin must be between %d and %d", MinKelvin, MaxKelvin)
+	}
+	return nil
+}
+
+// RampStep returns the brightness and, when the ramp has a target temperature, the kelvin
+// of step n of steps, linearly interpolated from zero and RampStartKelvin
+func (r *RampRequest) RampStep(n, steps int) (brightness float64, kelvin int) {
+	progress := float64(n) / float64(steps)
+	brightness = r.TargetBrightness * progress
+	if r.TargetKelvin != nil {
+		kelvin = RampStartKelvin + int(float64(*r.TargetKelvin-RampStartKelvin)*progress)
+	}
+	return brightness, kelvin
+}
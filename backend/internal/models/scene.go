@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxSceneNameLength is the longest scene name accepted
+const MaxSceneNameLength = 100
+
+// Scene is a saved snapshot of the state of every device in an account
+type Scene struct {
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+	Name      string          `db:"name" json:"name"`
+	States    json.RawMessage `db:"state_json" json:"states"` // JSON-encoded []SceneDeviceState
+	ID        uuid.UUID       `db:"id" json:"id"`
+	UserID    uuid.UUID       `db:"user_id" json:"user_id"`
+	AccountID uuid.UUID       `db:"account_id" json:"account_id"`
+}
+
+// CreateSceneRequest represents the request body for saving a scene
+type CreateSceneRequest struct {
+	Name string `json:"name"`
+}
+
+// SceneDeviceState is the captured state of a single device within a scene
+type SceneDeviceState struct {
+	Color      *DeviceColor `json:"color,omitempty"` // Only set for colored (saturated) light
+	DeviceID   string       `json:"device_id"`
+	Power      string       `json:"power"`
+	Brightness float64      `json:"brightness"`
+	Kelvin     int          `json:"kelvin,omitempty"`
+}
+
+// NewSceneDeviceState captures the current state of a device
+func NewSceneDeviceState(device *Device) SceneDeviceState {
+	state := SceneDeviceState{
+		DeviceID:   device.ID,
+		Power:      device.Power,
+		Brightness: device.Brightness,
+	}
+	if device.Color != nil {
+		state.Kelvin = device.Color.Kelvin
+		if device.Color.Saturation > 0 {
+			state.Color = &DeviceColor{Hue: device.Color.Hue, Saturation: device.Color.Saturation}
+		}
+	}
+	return state
+}
+
+// StateRequest converts the captured state into a combined state request. Colored
+// light is restored by hue and saturation, white light by its color temperature.
+func (s SceneDeviceState) StateRequest(duration *float64) *StateRequest {
+	request := &StateRequest{Duration: duration}
+	if s.Power != "" {
+		power := s.Power
+		request.Power = &power
+	}
+	brightness := s.Brightness
+	request.Brightness = &brightness
+
+	switch {
+	case s.Color != nil:
+		hue, saturation := s.Color.Hue, s.Color.Saturation
+		request.Hue = &hue
+		request.Saturation = &saturation
+	case s.Kelvin > 0:
+		kelvin := float64(s.Kelvin)
+		request.Kelvin = &kelvin
+	}
+
+	return request
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScheduledAction is a device action executed every time its cron expression fires, or
+// once at NextRunAt when RunOnce is set
+type ScheduledAction struct {
+	CreatedAt      time.Time       `db:"created_at" json:"created_at"`
+	NextRunAt      time.Time       `db:"next_run_at" json:"next_run_at"`
+	LastRunAt      *time.Time      `db:"last_run_at" json:"last_run_at,omitempty"`
+	Selector       string          `db:"selector" json:"selector"`
+	CronExpression string          `db:"cron_expression" json:"cron"` // Empty when RunOnce is set
+	Action         json.RawMessage `db:"action_json" json:"action"`   // JSON-encoded ActionRequest
+	ID             uuid.UUID       `db:"id" json:"id"`
+	UserID         uuid.UUID       `db:"user_id" json:"user_id"`
+	AccountID      uuid.UUID       `db:"account_id" json:"account_id"`
+	Enabled        bool            `db:"enabled" json:"enabled"`
+	RunOnce        bool            `db:"run_once" json:"run_once"`
+}
+
+// CreateScheduleRequest represents the request body for scheduling a device action
+type CreateScheduleRequest struct {
+	Parameters map[string]interface{} `json:"parameters"`
+	AccountID  string                 `json:"account_id" validate:"required"`
+	Selector   string                 `json:"selector" validate:"required,max=255"`
+	Action     string                 `json:"action" validate:"required,oneof=power toggle brightness color temperature effect infrared"`
+	Cron       string                 `json:"cron" validate:"required,max=100"` // Standard 5-field cron expression, in UTC
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StateSnapshot is a saved snapshot of the state of the devices a selector targets
+type StateSnapshot struct {
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+	Name      string          `db:"name" json:"name"`
+	Selector  string          `db:"selector" json:"selector"`
+	States    json.RawMessage `db:"state_json" json:"states"` // JSON-encoded []SceneDeviceState
+	ID        uuid.UUID       `db:"id" json:"id"`
+	UserID    uuid.UUID       `db:"user_id" json:"user_id"`
+	AccountID uuid.UUID       `db:"account_id" json:"account_id"`
+}
+
+// CreateStateSnapshotRequest represents the request body for taking a state snapshot
+type CreateStateSnapshotRequest struct {
+	Name string `json:"name"`
+}
@@ -2,25 +2,80 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// AccountDeletionGracePeriod is how long a deleted user can restore their account before
+// their data is purged
+const AccountDeletionGracePeriod = 30 * 24 * time.Hour
+
 // User represents a user in the system
 type User struct {
 	CreatedAt                  time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt                  time.Time  `db:"updated_at" json:"updated_at"`
 	MagicLinkExpiresAt         *time.Time `db:"magic_link_expires_at" json:"-"`
+	PasswordResetExpiresAt     *time.Time `db:"password_reset_expires_at" json:"-"`
 	EmailVerificationExpiresAt *time.Time `db:"email_verification_expires_at" json:"-"`
 	EmailVerificationToken     *string    `db:"email_verification_token" json:"-"`
 	MagicLinkToken             *string    `db:"magic_link_token" json:"-"`
+	PasswordResetToken         *string    `db:"password_reset_token" json:"-"` // SHA-256 hash of the emailed token
+	PendingEmailExpiresAt      *time.Time `db:"pending_email_expires_at" json:"-"`
+	PendingEmail               *string    `db:"pending_email" json:"pending_email,omitempty"` // Replaces Email once verified
+	PendingEmailToken          *string    `db:"pending_email_token" json:"-"`                 // SHA-256 hash of the emailed token
 	StripeCustomerID           *string    `db:"stripe_customer_id" json:"stripe_customer_id,omitempty"`
+	TOTPSecret                 *[]byte    `db:"totp_secret" json:"-"` // AES-256-GCM encrypted
+	DisplayName                *string    `db:"display_name" json:"display_name,omitempty"`
+	DeletedAt                  *time.Time `db:"deleted_at" json:"-"` // Set while the account awaits its purge
 	Email                      string     `db:"email" json:"email"`
 	Role                       string     `db:"role" json:"role"`
-	PasswordHash               string     `db:"password_hash" json:"-"`
+	PasswordHash               string     `db:"password_hash" json:"-"` // Empty for users who only sign in with a provider
 	ID                         uuid.UUID  `db:"id" json:"id"`
 	EmailVerified              bool       `db:"email_verified" json:"email_verified"`
+	TOTPEnabled                bool       `db:"totp_enabled" json:"totp_enabled"`
+	// NotificationPreferences is a JSON object of client-defined settings
+	NotificationPreferences json.RawMessage `db:"notification_preferences" json:"notification_preferences,omitempty"`
+}
+
+// UserResponse is the user's profile as returned by the API, without credentials or tokens
+type UserResponse struct {
+	CreatedAt               time.Time       `json:"created_at"`
+	DisplayName             *string         `json:"display_name"`
+	PendingEmail            *string         `json:"pending_email,omitempty"`
+	NotificationPreferences json.RawMessage `json:"notification_preferences"`
+	Email                   string          `json:"email"`
+	Role                    string          `json:"role"`
+	ID                      uuid.UUID       `json:"id"`
+	EmailVerified           bool            `json:"email_verified"`
+	TOTPEnabled             bool            `json:"totp_enabled"`
+}
+
+// ToResponse converts a user to its API response
+func (u *User) ToResponse() *UserResponse {
+	preferences := u.NotificationPreferences
+	if len(preferences) == 0 {
+		preferences = json.RawMessage("{}")
+	}
+
+	return &UserResponse{
+		ID:                      u.ID,
+		Email:                   u.Email,
+		Role:                    u.Role,
+		EmailVerified:           u.EmailVerified,
+		TOTPEnabled:             u.TOTPEnabled,
+		PendingEmail:            u.PendingEmail,
+		DisplayName:             u.DisplayName,
+		NotificationPreferences: preferences,
+		CreatedAt:               u.CreatedAt,
+	}
+}
+
+// UpdateProfileParams holds the profile fields to update; nil fields are left unchanged
+type UpdateProfileParams struct {
+	DisplayName             *string // An empty name clears it
+	NotificationPreferences *json.RawMessage
 }
 
 // CreateUserParams holds parameters for creating a new user
@@ -29,6 +84,7 @@ type CreateUserParams struct {
 	Email                      string
 	PasswordHash               string
 	EmailVerificationToken     string
+	EmailVerified              bool // Set for users whose address a sign-in provider already verified
 }
 
 // RefreshToken represents a refresh token in the database
@@ -41,4 +97,16 @@ type RefreshToken struct {
 	TokenHash string     `db:"token_hash" json:"-"`
 	ID        uuid.UUID  `db:"id" json:"id"`
 	UserID    uuid.UUID  `db:"user_id" json:"user_id"`
+	FamilyID  uuid.UUID  `db:"family_id" json:"family_id"`
+	SessionID uuid.UUID  `db:"session_id" json:"session_id"`
+}
+
+// Session is a logged-in device of a user, backed by its active refresh token
+type Session struct {
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserAgent *string   `json:"user_agent"`
+	IPAddress *string   `json:"ip_address"`
+	ID        uuid.UUID `json:"id"`
+	IsCurrent bool      `json:"is_current"`
 }
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kelvin range accepted for color temperatures
+const (
+	MinKelvin = 1500
+	MaxKelvin = 9000
+)
+
+// UserPreferences are a user's defaults for device actions. Unset preferences are nil.
+type UserPreferences struct {
+	UpdatedAt          time.Time `db:"updated_at" json:"updated_at"`
+	DefaultBrightness  *float64  `db:"default_brightness" json:"default_brightness"`
+	DefaultKelvin      *int      `db:"default_kelvin" json:"default_kelvin"`
+	FavoriteHue        *float64  `db:"favorite_hue" json:"favorite_hue"`
+	FavoriteSaturation *float64  `db:"favorite_saturation" json:"favorite_saturation"`
+	WakeKelvin         *int      `db:"wake_kelvin" json:"wake_kelvin"`
+	SleepKelvin        *int      `db:"sleep_kelvin" json:"sleep_kelvin"`
+	UserID             uuid.UUID `db:"user_id" json:"-"`
+}
+
+// UpdatePreferencesRequest replaces every preference of a user; omitted preferences are unset
+type UpdatePreferencesRequest struct {
+	DefaultBrightness  *float64 `json:"default_brightness"`
+	DefaultKelvin      *int     `json:"default_kelvin"`
+	FavoriteHue        *float64 `json:"favorite_hue"`
+	FavoriteSaturation *float64 `json:"favorite_saturation"`
+	WakeKelvin         *int     `json:"wake_kelvin"`
+	SleepKelvin        *int     `json:"sleep_kelvin"`
+}
+
+// Validate checks that every set preference is within range, and that the favorite color
+// is set or unset as a whole
+func (r *UpdatePreferencesRequest) Validate() error {
+	if r.DefaultBrightness != nil && (*r.DefaultBrightness < 0 || *r.DefaultBrightness > 1) {
+		return errors.New("default_brightness must be between 0.0 and 1.0")
+	}
+	if r.FavoriteHue != nil && (*r.FavoriteHue < 0 || *r.FavoriteHue > 360) {
+		return errors.New("favorite_hue must be between 0 and 360")
+	}
+	if r.FavoriteSaturation != nil && (*r.FavoriteSaturation < 0 || *r.FavoriteSaturation > 1) {
+		return errors.New("favorite_saturation must be between 0.0 and 1.0")
+	}
+	if (r.FavoriteHue == nil) != (r.FavoriteSaturation == nil) {
+		return errors.New("favorite_hue and favorite_saturation must be set together")
+	}
+	if err := validateKelvin("default_kelvin", r.DefaultKelvin); err != nil {
+		return err
+	}
+	if err := validateKelvin("wake_kelvin", r.WakeKelvin); err != nil {
+		return err
+	}
+	return validateKelvin("sleep_kelvin", r.SleepKelvin)
+}
+
+func validateKelvin(name string, kelvin *int) error {
+	if kelvin != nil && (*kelvin < MinKelvin || *kelvin > MaxKelvin) {
+		return fmt.Errorf("%s must be between %d and %d", name, MinKelvin, MaxKelvin)
+	}
+	return nil
+}
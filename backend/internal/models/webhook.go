@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Webhook event types
+const (
+	WebhookEventActionCompleted = "device.action.completed" // A device action succeeded or failed
+)
+
+// IsValidWebhookEventType checks if webhooks can subscribe to the event type
+func IsValidWebhookEventType(eventType string) bool {
+	return eventType == WebhookEventActionCompleted
+}
+
+// Webhook is a URL that receives signed POST requests for the events it subscribes to
+type Webhook struct {
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+	URL        string         `db:"url" json:"url"`
+	Secret     string         `db:"secret" json:"-"` // HMAC signing key, only returned on creation
+	EventTypes pq.StringArray `db:"event_types" json:"event_types"`
+	ID         uuid.UUID      `db:"id" json:"id"`
+	UserID     uuid.UUID      `db:"user_id" json:"user_id"`
+	Active     bool           `db:"active" json:"active"`
+}
+
+// CreateWebhookParams holds parameters for creating a new webhook
+type CreateWebhookParams struct {
+	URL        string
+	Secret     string
+	EventTypes []string
+	UserID     uuid.UUID
+}
+
+// HasEventType checks if the webhook subscribes to an event type
+func (w *Webhook) HasEventType(eventType string) bool {
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery logs a single attempt to deliver an event to a webhook
+type WebhookDelivery struct {
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	StatusCode *int      `db:"status_code" json:"status_code,omitempty"` // Unset when no response was received
+	Error      *string   `db:"error" json:"error,omitempty"`
+	EventType  string    `db:"event_type" json:"event_type"`
+	Attempt    int       `db:"attempt" json:"attempt"`
+	ID         uuid.UUID `db:"id" json:"id"`
+	WebhookID  uuid.UUID `db:"webhook_id" json:"webhook_id"`
+	Success    bool      `db:"success" json:"success"`
+}
+
+// WebhookPayload is the JSON body POSTed to a webhook for a device action
+type WebhookPayload struct {
+	Timestamp time.Time `json:"timestamp"`
+	Event     string    `json:"event"`
+	AccountID string    `json:"account_id"`
+	Selector  string    `json:"selector"`
+	Action    string    `json:"action"`
+	Error     string    `json:"error,omitempty"`
+	Success   bool      `json:"success"`
+}
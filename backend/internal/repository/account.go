@@ -12,12 +12,13 @@ import (
 	"github.com/jmoiron/sqlx"
 
 	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
 	"github.com/lightshare/backend/pkg/crypto"
 )
 
 var (
 	// ErrAccountNotFound is returned when an account is not found in the database
-	ErrAccountNotFound = errors.New("account not found")
+	ErrAccountNotFound = apierror.New(apierror.ErrNotFound, "account not found")
 	// ErrAccountAlreadyExists is returned when attempting to create a duplicate account
 	ErrAccountAlreadyExists = errors.New("account already exists for this provider")
 )
@@ -27,20 +28,27 @@ type AccountRepositoryInterface interface {
 	Create(ctx context.Context, params *models.CreateAccountParams) (*models.Account, error)
 	FindByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Account, error)
 	FindByID(ctx context.Context, accountID uuid.UUID) (*models.Account, error)
+	FindByIDString(ctx context.Context, accountID string) (*models.Account, error)
+	GetDecryptedToken(ctx context.Context, accountID string) (string, error)
+	UpdateToken(ctx context.Context, accountID, userID uuid.UUID, encryptedToken []byte, keyID string, metadata map[string]interface{}) error
+	UpdateLabel(ctx context.Context, accountID, userID uuid.UUID, label string) error
 	Delete(ctx context.Context, accountID, userID uuid.UUID) error
 }
 
 // AccountRepository handles account database operations
 type AccountRepository struct {
-	db            *sqlx.DB
-	encryptionKey []byte
+	db        *sqlx.DB
+	keys      crypto.KeyProvider
+	localKeys *crypto.LocalKeyProvider
 }
 
-// NewAccountRepository creates a new account repository
-func NewAccountRepository(db *sqlx.DB, encryptionKey []byte) *AccountRepository {
+// NewAccountRepository creates a new account repository. Tokens are decrypted with keys,
+// or with localKeys when they were stored without a key ID.
+func NewAccountRepository(db *sqlx.DB, keys crypto.KeyProvider, localKeys *crypto.LocalKeyProvider) *AccountRepository {
 	return &AccountRepository{
-		db:            db,
-		encryptionKey: encryptionKey,
+		db:        db,
+		keys:      keys,
+		localKeys: localKeys,
 	}
 }
 
@@ -51,7 +59,9 @@ func (r *AccountRepository) Create(ctx context.Context, params *models.CreateAcc
 		OwnerUserID:       params.OwnerUserID,
 		Provider:          params.Provider,
 		ProviderAccountID: params.ProviderAccountID,
+		Label:             models.DefaultAccountLabel(params.ProviderAccountID),
 		EncryptedToken:    params.EncryptedToken,
+		KeyID:             params.KeyID,
 		CreatedAt:         time.Now(),
 		UpdatedAt:         time.Now(),
 	}
@@ -67,18 +77,18 @@ func (r *AccountRepository) Create(ctx context.Context, params *models.CreateAcc
 
 	query := `
 		INSERT INTO accounts (
-			id, owner_user_id, provider, provider_account_id,
-			encrypted_token, metadata, created_at, updated_at
+			id, owner_user_id, provider, provider_account_id, label,
+			encrypted_token, key_id, metadata, created_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8
+			$1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10
 		)
-		RETURNING id, owner_user_id, provider, provider_account_id,
-			encrypted_token, metadata, created_at, updated_at
+		RETURNING id, owner_user_id, provider, provider_account_id, label,
+			encrypted_token, COALESCE(key_id, '') AS key_id, metadata, token_invalid_at, created_at, updated_at
 	`
 
 	err := r.db.GetContext(ctx, account, query,
-		account.ID, account.OwnerUserID, account.Provider, account.ProviderAccountID,
-		account.EncryptedToken, account.Metadata, account.CreatedAt, account.UpdatedAt,
+		account.ID, account.OwnerUserID, account.Provider, account.ProviderAccountID, account.Label,
+		account.EncryptedToken, account.KeyID, account.Metadata, account.CreatedAt, account.UpdatedAt,
 	)
 
 	if err != nil {
@@ -96,8 +106,8 @@ func (r *AccountRepository) Create(ctx context.Context, params *models.CreateAcc
 func (r *AccountRepository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Account, error) {
 	var accounts []*models.Account
 	query := `
-		SELECT id, owner_user_id, provider, provider_account_id,
-			encrypted_token, metadata, created_at, updated_at
+		SELECT id, owner_user_id, provider, provider_account_id, label,
+			encrypted_token, COALESCE(key_id, '') AS key_id, metadata, token_invalid_at, created_at, updated_at
 		FROM accounts
 		WHERE owner_user_id = $1
 		ORDER BY created_at DESC
@@ -115,8 +125,8 @@ func (r *AccountRepository) FindByUserID(ctx context.Context, userID uuid.UUID)
 func (r *AccountRepository) FindByID(ctx context.Context, accountID uuid.UUID) (*models.Account, error) {
 	var account models.Account
 	query := `
-		SELECT id, owner_user_id, provider, provider_account_id,
-			encrypted_token, metadata, created_at, updated_at
+		SELECT id, owner_user_id, provider, provider_account_id, label,
+			encrypted_token, COALESCE(key_id, '') AS key_id, metadata, token_invalid_at, created_at, updated_at
 		FROM accounts
 		WHERE id = $1
 	`
@@ -132,6 +142,123 @@ func (r *AccountRepository) FindByID(ctx context.Context, accountID uuid.UUID) (
 	return &account, nil
 }
 
+// UpdateToken replaces the encrypted provider token, the key it was encrypted with, and the
+// metadata of an account. The new token is presumed valid, so the account is unflagged.
+func (r *AccountRepository) UpdateToken(ctx context.Context, accountID, userID uuid.UUID, encryptedToken []byte, keyID string, metadata map[string]interface{}) error {
+	var metadataJSON []byte
+	if metadata != nil {
+		var err error
+		metadataJSON, err = json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+	}
+
+	query := `
+		UPDATE accounts
+		SET encrypted_token = $1, key_id = NULLIF($2, ''), metadata = $3, token_invalid_at = NULL, updated_at = $4
+		WHERE id = $5 AND owner_user_id = $6
+	`
+
+	result, err := r.db.ExecContext(ctx, query, encryptedToken, keyID, metadataJSON, time.Now(), accountID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update account token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrAccountNotFound
+	}
+
+	return nil
+}
+
+// FindAll retrieves a page of limit accounts of every user, skipping the first offset.
+// Accounts of users awaiting deletion are left out. Pages are ordered by created_at, then
+// id, so that accounts created at the same instant keep a stable position across pages.
+func (r *AccountRepository) FindAll(ctx context.Context, offset, limit int) ([]*models.Account, error) {
+	var accounts []*models.Account
+	query := `
+		SELECT a.id, a.owner_user_id, a.provider, a.provider_account_id, a.label,
+			a.encrypted_token, COALESCE(a.key_id, '') AS key_id, a.metadata, a.token_invalid_at,
+			a.created_at, a.updated_at
+		FROM accounts a
+		JOIN users u ON u.id = a.owner_user_id AND u.deleted_at IS NULL
+		ORDER BY a.created_at, a.id
+		LIMIT $1 OFFSET $2
+	`
+
+	err := r.db.SelectContext(ctx, &accounts, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find accounts: %w", err)
+	}
+
+	return accounts, nil
+}
+
+// MarkTokenInvalid flags the token of an account as rejected by its provider at invalidAt
+func (r *AccountRepository) MarkTokenInvalid(ctx context.Context, accountID uuid.UUID, invalidAt time.Time) error {
+	return r.setTokenInvalidAt(ctx, accountID, &invalidAt)
+}
+
+// MarkTokenValid clears the invalid token flag of an account
+func (r *AccountRepository) MarkTokenValid(ctx context.Context, accountID uuid.UUID) error {
+	return r.setTokenInvalidAt(ctx, accountID, nil)
+}
+
+func (r *AccountRepository) setTokenInvalidAt(ctx context.Context, accountID uuid.UUID, invalidAt *time.Time) error {
+	query := `
+		UPDATE accounts
+		SET token_invalid_at = $1, updated_at = $2
+		WHERE id = $3
+	`
+
+	result, err := r.db.ExecContext(ctx, query, invalidAt, time.Now(), accountID)
+	if err != nil {
+		return fmt.Errorf("failed to update account token status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrAccountNotFound
+	}
+
+	return nil
+}
+
+// UpdateLabel sets the display label of an account
+func (r *AccountRepository) UpdateLabel(ctx context.Context, accountID, userID uuid.UUID, label string) error {
+	query := `
+		UPDATE accounts
+		SET label = $1, updated_at = $2
+		WHERE id = $3 AND owner_user_id = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, label, time.Now(), accountID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update account label: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrAccountNotFound
+	}
+
+	return nil
+}
+
 // Delete deletes an account
 func (r *AccountRepository) Delete(ctx context.Context, accountID, userID uuid.UUID) error {
 	query := `
@@ -160,7 +287,7 @@ func (r *AccountRepository) Delete(ctx context.Context, accountID, userID uuid.U
 func (r *AccountRepository) FindByIDString(ctx context.Context, accountID string) (*models.Account, error) {
 	id, err := uuid.Parse(accountID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid account ID: %w", err)
+		return nil, &apierror.BadRequestError{Message: "invalid account ID", Cause: err}
 	}
 	return r.FindByID(ctx, id)
 }
@@ -172,11 +299,20 @@ func (r *AccountRepository) GetDecryptedToken(ctx context.Context, accountID str
 		return "", err
 	}
 
-	// Decrypt the token
-	token, err := crypto.DecryptToken(account.EncryptedToken, r.encryptionKey)
+	// Decrypt the token with the key it was stored with
+	token, err := r.keyProvider(account.KeyID).DecryptToken(account.EncryptedToken, account.KeyID)
 	if err != nil {
 		return "", fmt.Errorf("failed to decrypt token: %w", err)
 	}
 
 	return token, nil
 }
+
+// keyProvider returns the key provider for tokens encrypted with keyID; an empty key ID
+// is the local key
+func (r *AccountRepository) keyProvider(keyID string) crypto.KeyProvider {
+	if keyID == "" {
+		return r.localKeys
+	}
+	return r.keys
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+)
+
+// ErrAPIKeyNotFound is returned when an API key is not found in the database
+var ErrAPIKeyNotFound = apierror.New(apierror.ErrNotFound, "api key not found")
+
+// APIKeyRepositoryInterface defines the interface for API key repository operations
+type APIKeyRepositoryInterface interface {
+	Create(ctx context.Context, params *models.CreateAPIKeyParams) (*models.APIKey, error)
+	FindByUserID(ctx context.Context, userID uuid.UUID) ([]*models.APIKey, error)
+	FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	Revoke(ctx context.Context, id, userID uuid.UUID) error
+	TouchLastUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
+
+// APIKeyRepository handles API key database operations
+type APIKeyRepository struct {
+	db *sqlx.DB
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *sqlx.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+// Create creates a new API key
+func (r *APIKeyRepository) Create(ctx context.Context, params *models.CreateAPIKeyParams) (*models.APIKey, error) {
+	var key models.APIKey
+	query := `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + apiKeyColumns
+
+	err := r.db.GetContext(ctx, &key, query,
+		uuid.New(), params.UserID, params.Name, params.Prefix, params.KeyHash,
+		pq.StringArray(params.Scopes), params.ExpiresAt, time.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// FindByUserID returns all non-revoked API keys for a user
+func (r *APIKeyRepository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]*models.APIKey, error) {
+	keys := make([]*models.APIKey, 0)
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	if err := r.db.SelectContext(ctx, &keys, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// FindByHash retrieves an API key by its hash, including revoked keys, along with
+// its owner's email and role. Keys of deleted users are not found.
+func (r *APIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	query := `
+		SELECT k.id, k.user_id, k.name, k.prefix, k.key_hash, k.scopes, k.expires_at,
+			k.last_used_at, k.revoked_at, k.created_at,
+			u.email AS user_email, u.role AS user_role
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1 AND u.deleted_at IS NULL
+	`
+
+	err := r.db.GetContext(ctx, &key, query, keyHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// Revoke revokes an API key owned by the user
+func (r *APIKeyRepository) Revoke(ctx context.Context, id, userID uuid.UUID) error {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = $1
+		WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// TouchLastUsed records when an API key was last used
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, usedAt, id); err != nil {
+		return fmt.Errorf("failed to update api key last used: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/lightshare/backend/internal/models"
+)
+
+// AuditRepositoryInterface defines the interface for audit event repository operations
+type AuditRepositoryInterface interface {
+	Create(ctx context.Context, event models.AuditEvent) error
+	ListByUser(ctx context.Context, userID uuid.UUID, before *uuid.UUID, limit int) ([]*models.AuditEvent, error)
+}
+
+// AuditRepository handles audit event database operations. Events are append-only;
+// the repository deliberately has no update or delete methods.
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+const auditEventColumns = `id, user_id, event_type, ip_address, user_agent, metadata, created_at`
+
+// Create stores an audit event
+func (r *AuditRepository) Create(ctx context.Context, event models.AuditEvent) error {
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+	if len(event.Metadata) == 0 {
+		event.Metadata = []byte("{}")
+	}
+
+	query := `
+		INSERT INTO audit_events (id, user_id, event_type, ip_address, user_agent, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		event.ID, event.UserID, event.EventType, event.IPAddress, event.UserAgent, event.Metadata,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create audit event: %w", err)
+	}
+
+	return nil
+}
+
+// ListByUser returns up to limit of the user's events, newest first, starting after
+// the event before (when set)
+func (r *AuditRepository) ListByUser(ctx context.Context, userID uuid.UUID, before *uuid.UUID, limit int) ([]*models.AuditEvent, error) {
+	events := make([]*models.AuditEvent, 0)
+	query := `
+		SELECT ` + auditEventColumns + `
+		FROM audit_events
+		WHERE user_id = $1
+			AND ($2::uuid IS NULL OR (created_at, id) < (
+				SELECT created_at, id FROM audit_events WHERE id = $2 AND user_id = $1
+			))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3
+	`
+
+	if err := r.db.SelectContext(ctx, &events, query, userID, before, limit); err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	return events, nil
+}
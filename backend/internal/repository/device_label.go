@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+)
+
+// ErrDeviceLabelNotFound is returned when a user set no label for a device
+var ErrDeviceLabelNotFound = apierror.New(apierror.ErrNotFound, "device label not found")
+
+// DeviceLabelRepositoryInterface defines the interface for device label operations
+type DeviceLabelRepositoryInterface interface {
+	Upsert(ctx context.Context, label *models.DeviceLabel) error
+	Get(ctx context.Context, userID uuid.UUID, provider, deviceID string) (*models.DeviceLabel, error)
+	Delete(ctx context.Context, userID uuid.UUID, provider, deviceID string) error
+	GetByUserAndProvider(ctx context.Context, userID uuid.UUID, provider string) ([]*models.DeviceLabel, error)
+}
+
+// DeviceLabelRepository handles device label database operations
+type DeviceLabelRepository struct {
+	db *sqlx.DB
+}
+
+// NewDeviceLabelRepository creates a new device label repository
+func NewDeviceLabelRepository(db *sqlx.DB) *DeviceLabelRepository {
+	return &DeviceLabelRepository{db: db}
+}
+
+// Upsert stores a user's label for a device, replacing any set before, and fills in the
+// label's update time
+func (r *DeviceLabelRepository) Upsert(ctx context.Context, label *models.DeviceLabel) error {
+	query := `
+		INSERT INTO device_labels (user_id, provider, device_id, label)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, provider, device_id) DO UPDATE SET
+			label = EXCLUDED.label,
+			updated_at = NOW()
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRowxContext(ctx, query, label.UserID, label.Provider, label.DeviceID, label.Label).Scan(&label.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save device label: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns a user's label for a device
+func (r *DeviceLabelRepository) Get(ctx context.Context, userID uuid.UUID, provider, deviceID string) (*models.DeviceLabel, error) {
+	var label models.DeviceLabel
+	query := `
+		SELECT user_id, provider, device_id, label, updated_at
+		FROM device_labels
+		WHERE user_id = $1 AND provider = $2 AND device_id = $3
+	`
+
+	err := r.db.GetContext(ctx, &label, query, userID, provider, deviceID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDeviceLabelNotFound
+		}
+		return nil, fmt.Errorf("failed to get device label: %w", err)
+	}
+
+	return &label, nil
+}
+
+// Delete removes a user's label for a device
+func (r *DeviceLabelRepository) Delete(ctx context.Context, userID uuid.UUID, provider, deviceID string) error {
+	query := `DELETE FROM device_labels WHERE user_id = $1 AND provider = $2 AND device_id = $3`
+
+	result, err := r.db.ExecContext(ctx, query, userID, provider, deviceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete device label: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrDeviceLabelNotFound
+	}
+
+	return nil
+}
+
+// GetByUserAndProvider returns every label a user set for the devices of a provider
+func (r *DeviceLabelRepository) GetByUserAndProvider(ctx context.Context, userID uuid.UUID, provider string) ([]*models.DeviceLabel, error) {
+	var labels []*models.DeviceLabel
+	query := `
+		SELECT user_id, provider, device_id, label, updated_at
+		FROM device_labels
+		WHERE user_id = $1 AND provider = $2
+	`
+
+	if err := r.db.SelectContext(ctx, &labels, query, userID, provider); err != nil {
+		return nil, fmt.Errorf("failed to get device labels: %w", err)
+	}
+
+	return labels, nil
+}
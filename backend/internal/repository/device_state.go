@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/lightshare/backend/internal/models"
+)
+
+// DeviceStateRepositoryInterface defines the interface for device state history operations
+type DeviceStateRepositoryInterface interface {
+	Record(ctx context.Context, event *models.DeviceStateEvent) error
+	FindByDeviceID(ctx context.Context, accountID, deviceID string, from, to time.Time, limit int) ([]*models.DeviceStateEvent, error)
+}
+
+// DeviceStateRepository handles device state history database operations
+type DeviceStateRepository struct {
+	db *sqlx.DB
+}
+
+// NewDeviceStateRepository creates a new device state repository
+func NewDeviceStateRepository(db *sqlx.DB) *DeviceStateRepository {
+	return &DeviceStateRepository{db: db}
+}
+
+const deviceStateEventColumns = `id, account_id, device_id, provider, event_type, old_state_json, new_state_json, created_at`
+
+// Record appends an event to a device's history
+func (r *DeviceStateRepository) Record(ctx context.Context, event *models.DeviceStateEvent) error {
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	if len(event.OldState) == 0 {
+		event.OldState = []byte("null")
+	}
+	if len(event.NewState) == 0 {
+		event.NewState = []byte("null")
+	}
+
+	query := `
+		INSERT INTO device_state_events (` + deviceStateEventColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		event.ID, event.AccountID, event.DeviceID, event.Provider, event.EventType,
+		event.OldState, event.NewState, event.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record device state event: %w", err)
+	}
+
+	return nil
+}
+
+// FindByDeviceID returns up to limit of a device's events created in [from, to), oldest first
+func (r *DeviceStateRepository) FindByDeviceID(ctx context.Context, accountID, deviceID string, from, to time.Time, limit int) ([]*models.DeviceStateEvent, error) {
+	events := make([]*models.DeviceStateEvent, 0)
+	query := `
+		SELECT ` + deviceStateEventColumns + `
+		FROM device_state_events
+		WHERE account_id = $1 AND device_id = $2 AND created_at >= $3 AND created_at < $4
+		ORDER BY created_at ASC, id ASC
+		LIMIT $5
+	`
+
+	if err := r.db.SelectContext(ctx, &events, query, accountID, deviceID, from, to, limit); err != nil {
+		return nil, fmt.Errorf("failed to find device state events: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,421 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+
+	"github.com/lightshare/backend/internal/models"
+)
+
+// integrationDSN is the connection string of the database shared by the integration
+// tests, empty unless TEST_INTEGRATION is set
+var integrationDSN string
+
+func TestMain(m *testing.M) {
+	if os.Getenv("TEST_INTEGRATION") != "true" {
+		os.Exit(m.Run())
+	}
+
+	ctx := context.Background()
+	container, err := postgres.Run(ctx, "postgres:15-alpine",
+		postgres.WithDatabase("lightshare_test"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		postgres.BasicWaitStrategies(),
+	)
+	if err != nil {
+		log.Fatalf("Failed to start postgres container: %v", err)
+	}
+
+	code := 1
+	if integrationDSN, err = container.ConnectionString(ctx, "sslmode=disable"); err != nil {
+		log.Printf("Failed to get postgres connection string: %v", err)
+	} else if err := migrateUp(integrationDSN); err != nil {
+		log.Printf("Failed to run migrations: %v", err)
+	} else {
+		code = m.Run()
+	}
+
+	if err := testcontainers.TerminateContainer(container); err != nil {
+		log.Printf("Failed to terminate postgres container: %v", err)
+	}
+	os.Exit(code)
+}
+
+// migrateUp applies every migration to the database at dsn
+func migrateUp(dsn string) error {
+	m, err := migrate.New("file://../../migrations", dsn)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// newIntegrationDB returns a connection to the shared test database whose changes are
+// rolled back when the test ends. The pool holds a single connection, so every query of
+// the test runs in the transaction opened on it. Postgres aborts a transaction on the
+// first failed statement: a test expecting a constraint violation must check it last.
+func newIntegrationDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	if integrationDSN == "" {
+		t.Skip("Set TEST_INTEGRATION=true to run the integration tests")
+	}
+
+	db, err := sqlx.Connect("postgres", integrationDSN)
+	if err != nil {
+		t.Fatalf("Failed to connect to postgres: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+
+	if _, err := db.Exec("BEGIN"); err != nil {
+		_ = db.Close()
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.Exec("ROLLBACK"); err != nil {
+			t.Errorf("Failed to roll back transaction: %v", err)
+		}
+		_ = db.Close()
+	})
+	return db
+}
+
+// createIntegrationUser creates an unverified user whose verification token expires at
+// expiresAt
+func createIntegrationUser(t *testing.T, repo *UserRepository, email string, expiresAt time.Time) *models.User {
+	t.Helper()
+	user, err := repo.Create(context.Background(), models.CreateUserParams{
+		Email:                      email,
+		PasswordHash:               "hash",
+		EmailVerificationToken:     "verify-" + email,
+		EmailVerificationExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	return user
+}
+
+func TestUserRepository_CreateDuplicateEmail(t *testing.T) {
+	repo := NewUserRepository(newIntegrationDB(t))
+	createIntegrationUser(t, repo, "dup@example.com", time.Now().Add(time.Hour))
+
+	_, err := repo.Create(context.Background(), models.CreateUserParams{Email: "dup@example.com", PasswordHash: "hash"})
+	if !errors.Is(err, ErrUserAlreadyExists) {
+		t.Errorf("Expected ErrUserAlreadyExists, got %v", err)
+	}
+}
+
+func TestUserRepository_GetByEmailVerificationToken(t *testing.T) {
+	repo := NewUserRepository(newIntegrationDB(t))
+	ctx := context.Background()
+	valid := createIntegrationUser(t, repo, "valid@example.com", time.Now().Add(time.Hour))
+	createIntegrationUser(t, repo, "expired@example.com", time.Now().Add(-time.Hour))
+
+	got, err := repo.GetByEmailVerificationToken(ctx, "verify-valid@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmailVerificationToken failed: %v", err)
+	}
+	if got.ID != valid.ID {
+		t.Errorf("Expected user %s, got %s", valid.ID, got.ID)
+	}
+
+	if _, err := repo.GetByEmailVerificationToken(ctx, "verify-expired@example.com"); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Expected ErrTokenExpired for an expired token, got %v", err)
+	}
+}
+
+func TestUserRepository_VerifyEmail(t *testing.T) {
+	repo := NewUserRepository(newIntegrationDB(t))
+	ctx := context.Background()
+	user := createIntegrationUser(t, repo, "verify@example.com", time.Now().Add(time.Hour))
+	createIntegrationUser(t, repo, "late@example.com", time.Now().Add(-time.Hour))
+
+	if err := repo.VerifyEmail(ctx, "verify-verify@example.com"); err != nil {
+		t.Fatalf("VerifyEmail failed: %v", err)
+	}
+	got, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if !got.EmailVerified || got.EmailVerificationToken != nil {
+		t.Errorf("Expected a verified user without a verification token, got %+v", got)
+	}
+
+	if err := repo.VerifyEmail(ctx, "verify-verify@example.com"); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Expected ErrTokenExpired redeeming a token twice, got %v", err)
+	}
+	if err := repo.VerifyEmail(ctx, "verify-late@example.com"); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Expected ErrTokenExpired for an expired token, got %v", err)
+	}
+}
+
+func TestAccountRepository_CreateDuplicate(t *testing.T) {
+	db := newIntegrationDB(t)
+	user := createIntegrationUser(t, NewUserRepository(db), "owner@example.com", time.Now().Add(time.Hour))
+	repo := NewAccountRepository(db, nil, nil)
+	ctx := context.Background()
+
+	params := &models.CreateAccountParams{
+		OwnerUserID:       user.ID,
+		Provider:          "lifx",
+		ProviderAccountID: "lifx-account",
+		EncryptedToken:    []byte("token"),
+	}
+	if _, err := repo.Create(ctx, params); err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	// The same provider account may be connected once per user and provider
+	other := *params
+	other.Provider = "hue"
+	if _, err := repo.Create(ctx, &other); err != nil {
+		t.Fatalf("Failed to create account of another provider: %v", err)
+	}
+
+	if _, err := repo.Create(ctx, params); !errors.Is(err, ErrAccountAlreadyExists) {
+		t.Errorf("Expected ErrAccountAlreadyExists, got %v", err)
+	}
+}
+
+func TestAccountRepository_FindAll(t *testing.T) {
+	db := newIntegrationDB(t)
+	users := NewUserRepository(db)
+	repo := NewAccountRepository(db, nil, nil)
+	ctx := context.Background()
+
+	var created []*models.Account
+	for _, email := range []string{"first@example.com", "second@example.com", "deleted@example.com"} {
+		user := createIntegrationUser(t, users, email, time.Now().Add(time.Hour))
+		account, err := repo.Create(ctx, &models.CreateAccountParams{
+			OwnerUserID:       user.ID,
+			Provider:          "lifx",
+			ProviderAccountID: "lifx-" + email,
+			EncryptedToken:    []byte("token"),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+		created = append(created, account)
+	}
+	if err := users.SoftDelete(ctx, created[2].OwnerUserID, time.Now()); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+
+	first, err := repo.FindAll(ctx, 0, 1)
+	if err != nil {
+		t.Fatalf("FindAll failed: %v", err)
+	}
+	rest, err := repo.FindAll(ctx, 1, 10)
+	if err != nil {
+		t.Fatalf("FindAll failed: %v", err)
+	}
+	if len(first) != 1 || first[0].ID != created[0].ID || len(rest) != 1 || rest[0].ID != created[1].ID {
+		t.Errorf("Expected the accounts of active users in creation order, got %d and %d", len(first), len(rest))
+	}
+}
+
+// createIntegrationToken creates a refresh token of user expiring at expiresAt
+func createIntegrationToken(t *testing.T, repo *RefreshTokenRepository, userID uuid.UUID, expiresAt time.Time) *models.RefreshToken {
+	t.Helper()
+	token, err := repo.Create(context.Background(), userID, uuid.New(), uuid.New(), fmt.Sprintf("hash-%s", uuid.New()), expiresAt, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create refresh token: %v", err)
+	}
+	return token
+}
+
+func TestRefreshTokenRepository_RevokeAllForUser(t *testing.T) {
+	db := newIntegrationDB(t)
+	users := NewUserRepository(db)
+	user := createIntegrationUser(t, users, "revoke@example.com", time.Now().Add(time.Hour))
+	other := createIntegrationUser(t, users, "other@example.com", time.Now().Add(time.Hour))
+	repo := NewRefreshTokenRepository(db)
+	ctx := context.Background()
+
+	for range 2 {
+		createIntegrationToken(t, repo, user.ID, time.Now().Add(time.Hour))
+	}
+	createIntegrationToken(t, repo, other.ID, time.Now().Add(time.Hour))
+
+	if err := repo.RevokeAllForUser(ctx, user.ID); err != nil {
+		t.Fatalf("RevokeAllForUser failed: %v", err)
+	}
+
+	active, err := repo.FindActiveByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("FindActiveByUserID failed: %v", err)
+	}
+	if len(active) != 0 {
+		t.Errorf("Expected no active tokens, got %d", len(active))
+	}
+	if active, err = repo.FindActiveByUserID(ctx, other.ID); err != nil || len(active) != 1 {
+		t.Errorf("Expected the other user to keep their token, got %d tokens and error %v", len(active), err)
+	}
+}
+
+func TestRefreshTokenRepository_DeleteExpiredIntegration(t *testing.T) {
+	db := newIntegrationDB(t)
+	user := createIntegrationUser(t, NewUserRepository(db), "expired@example.com", time.Now().Add(time.Hour))
+	repo := NewRefreshTokenRepository(db)
+	ctx := context.Background()
+
+	longExpired := createIntegrationToken(t, repo, user.ID, time.Now().Add(-8*24*time.Hour))
+	recentlyExpired := createIntegrationToken(t, repo, user.ID, time.Now().Add(-time.Hour))
+	active := createIntegrationToken(t, repo, user.ID, time.Now().Add(time.Hour))
+
+	deleted, err := repo.DeleteExpired(ctx)
+	if err != nil {
+		t.Fatalf("DeleteExpired failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 deleted token, got %d", deleted)
+	}
+
+	var remaining []string
+	if err := db.SelectContext(ctx, &remaining, "SELECT token_hash FROM refresh_tokens WHERE user_id = $1", user.ID); err != nil {
+		t.Fatalf("Failed to list remaining tokens: %v", err)
+	}
+	for _, hash := range remaining {
+		if hash == longExpired.TokenHash {
+			t.Error("Expected the token expired over a week ago to be deleted")
+		}
+	}
+	if len(remaining) != 2 {
+		t.Errorf("Expected %s and %s to remain, got %v", recentlyExpired.TokenHash, active.TokenHash, remaining)
+	}
+}
+
+func TestScheduleRepository_ClaimRun(t *testing.T) {
+	db := newIntegrationDB(t)
+	users := NewUserRepository(db)
+	accounts := NewAccountRepository(db, nil, nil)
+	repo := NewScheduleRepository(db)
+	ctx := context.Background()
+	dueAt := time.Date(2025, 6, 1, 23, 0, 0, 0, time.UTC)
+
+	createSchedule := func(email string) *models.ScheduledAction {
+		user := createIntegrationUser(t, users, email, time.Now().Add(time.Hour))
+		account, err := accounts.Create(ctx, &models.CreateAccountParams{
+			OwnerUserID:       user.ID,
+			Provider:          "lifx",
+			ProviderAccountID: "lifx-" + email,
+			EncryptedToken:    []byte("token"),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+		schedule := &models.ScheduledAction{
+			UserID:         user.ID,
+			AccountID:      account.ID,
+			Selector:       "all",
+			Action:         []byte(`{"action":"toggle"}`),
+			CronExpression: "0 23 * * *",
+			Enabled:        true,
+			NextRunAt:      dueAt,
+		}
+		if err := repo.Create(ctx, schedule); err != nil {
+			t.Fatalf("Failed to create schedule: %v", err)
+		}
+		return schedule
+	}
+	schedule := createSchedule("active@example.com")
+	deleted := createSchedule("deleted@example.com")
+	if err := users.SoftDelete(ctx, deleted.UserID, dueAt); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+
+	due, err := repo.ListDue(ctx, dueAt)
+	if err != nil {
+		t.Fatalf("ListDue failed: %v", err)
+	}
+	if len(due) != 1 || due[0].ID != schedule.ID {
+		t.Fatalf("Expected only the schedule of the active user to be due, got %d", len(due))
+	}
+
+	next := dueAt.Add(24 * time.Hour)
+	claimed, err := repo.ClaimRun(ctx, schedule.ID, due[0].NextRunAt, dueAt, next)
+	if err != nil || !claimed {
+		t.Fatalf("Expected the first claim to succeed, got %v, %v", claimed, err)
+	}
+
+	// Another instance that listed the same run finds it claimed
+	claimed, err = repo.ClaimRun(ctx, schedule.ID, due[0].NextRunAt, dueAt, next)
+	if err != nil || claimed {
+		t.Errorf("Expected the second claim to fail, got %v, %v", claimed, err)
+	}
+}
+
+func TestScheduleRepository_ClaimOnce(t *testing.T) {
+	db := newIntegrationDB(t)
+	users := NewUserRepository(db)
+	accounts := NewAccountRepository(db, nil, nil)
+	repo := NewScheduleRepository(db)
+	ctx := context.Background()
+	dueAt := time.Date(2025, 6, 1, 23, 0, 0, 0, time.UTC)
+
+	user := createIntegrationUser(t, users, "once@example.com", time.Now().Add(time.Hour))
+	account, err := accounts.Create(ctx, &models.CreateAccountParams{
+		OwnerUserID:       user.ID,
+		Provider:          "lifx",
+		ProviderAccountID: "lifx-once",
+		EncryptedToken:    []byte("token"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	schedule := &models.ScheduledAction{
+		UserID:    user.ID,
+		AccountID: account.ID,
+		Selector:  "all",
+		Action:    []byte(`{"action":"toggle"}`),
+		Enabled:   true,
+		RunOnce:   true,
+		NextRunAt: dueAt,
+	}
+	if err := repo.Create(ctx, schedule); err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+
+	due, err := repo.ListDue(ctx, dueAt)
+	if err != nil {
+		t.Fatalf("ListDue failed: %v", err)
+	}
+	if len(due) != 1 || !due[0].RunOnce {
+		t.Fatalf("Expected the one-shot schedule to be due, got %+v", due)
+	}
+
+	claimed, err := repo.ClaimOnce(ctx, schedule.ID, due[0].NextRunAt)
+	if err != nil || !claimed {
+		t.Fatalf("Expected the first claim to succeed, got %v, %v", claimed, err)
+	}
+	claimed, err = repo.ClaimOnce(ctx, schedule.ID, due[0].NextRunAt)
+	if err != nil || claimed {
+		t.Errorf("Expected the second claim to fail, got %v, %v", claimed, err)
+	}
+	if remaining, _ := repo.ListByUser(ctx, user.ID); len(remaining) != 0 {
+		t.Errorf("Expected the claimed schedule to be removed, got %d", len(remaining))
+	}
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+)
+
+// ErrOAuthProviderNotFound is returned when no user is linked to a provider identity.
+var ErrOAuthProviderNotFound = apierror.New(apierror.ErrNotFound, "oauth provider link not found")
+
+// OAuthProviderRepositoryInterface defines the interface for OAuth provider link operations
+type OAuthProviderRepositoryInterface interface {
+	FindByProviderUserID(ctx context.Context, provider, providerUserID string) (*models.OAuthProvider, error)
+	Link(ctx context.Context, userID uuid.UUID, provider, providerUserID string) error
+}
+
+// OAuthProviderRepository handles OAuth provider link database operations
+type OAuthProviderRepository struct {
+	db *sqlx.DB
+}
+
+// NewOAuthProviderRepository creates a new OAuth provider repository
+func NewOAuthProviderRepository(db *sqlx.DB) *OAuthProviderRepository {
+	return &OAuthProviderRepository{db: db}
+}
+
+// FindByProviderUserID retrieves the link of a provider identity
+func (r *OAuthProviderRepository) FindByProviderUserID(ctx context.Context, provider, providerUserID string) (*models.OAuthProvider, error) {
+	var link models.OAuthProvider
+	query := `
+		SELECT id, user_id, provider, provider_user_id, created_at
+		FROM oauth_providers
+		WHERE provider = $1 AND provider_user_id = $2
+	`
+
+	err := r.db.GetContext(ctx, &link, query, provider, providerUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOAuthProviderNotFound
+		}
+		return nil, fmt.Errorf("failed to get oauth provider link: %w", err)
+	}
+
+	return &link, nil
+}
+
+// Link links a user to a provider identity. Linking an identity that is already linked is a no-op.
+func (r *OAuthProviderRepository) Link(ctx context.Context, userID uuid.UUID, provider, providerUserID string) error {
+	query := `
+		INSERT INTO oauth_providers (id, user_id, provider, provider_user_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err := r.db.ExecContext(ctx, query, uuid.New(), userID, provider, providerUserID)
+	if err != nil {
+		return fmt.Errorf("failed to link oauth provider: %w", err)
+	}
+
+	return nil
+}
@@ -21,6 +21,19 @@ var (
 	ErrRefreshTokenRevoked = errors.New("refresh token revoked")
 )
 
+// RefreshTokenRepositoryInterface defines the interface for refresh token repository operations
+type RefreshTokenRepositoryInterface interface {
+	Create(ctx context.Context, userID, familyID, sessionID uuid.UUID, tokenHash string, expiresAt time.Time, userAgent, ipAddress *string) (*models.RefreshToken, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	GetByTokenHashIncludeRevoked(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	FindActiveByUserID(ctx context.Context, userID uuid.UUID) ([]*models.RefreshToken, error)
+	Revoke(ctx context.Context, tokenHash string) error
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+	RevokeByFamilyID(ctx context.Context, familyID uuid.UUID) error
+	RevokeByID(ctx context.Context, sessionID, userID uuid.UUID) error
+	DeleteExpired(ctx context.Context) (int, error)
+}
+
 // RefreshTokenRepository handles refresh token database operations
 type RefreshTokenRepository struct {
 	db *sqlx.DB
@@ -31,11 +44,13 @@ func NewRefreshTokenRepository(db *sqlx.DB) *RefreshTokenRepository {
 	return &RefreshTokenRepository{db: db}
 }
 
-// Create creates a new refresh token
-func (r *RefreshTokenRepository) Create(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time, userAgent, ipAddress *string) (*models.RefreshToken, error) {
+// Create creates a new refresh token in the given token family and session
+func (r *RefreshTokenRepository) Create(ctx context.Context, userID, familyID, sessionID uuid.UUID, tokenHash string, expiresAt time.Time, userAgent, ipAddress *string) (*models.RefreshToken, error) {
 	token := &models.RefreshToken{
 		ID:        uuid.New(),
 		UserID:    userID,
+		FamilyID:  familyID,
+		SessionID: sessionID,
 		TokenHash: tokenHash,
 		ExpiresAt: expiresAt,
 		CreatedAt: time.Now(),
@@ -45,15 +60,15 @@ func (r *RefreshTokenRepository) Create(ctx context.Context, userID uuid.UUID, t
 
 	query := `
 		INSERT INTO refresh_tokens (
-			id, user_id, token_hash, expires_at, created_at, user_agent, ip_address
+			id, user_id, family_id, session_id, token_hash, expires_at, created_at, user_agent, ip_address
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
 		)
-		RETURNING id, user_id, token_hash, expires_at, created_at, revoked_at, user_agent, ip_address
+		RETURNING id, user_id, family_id, session_id, token_hash, expires_at, created_at, revoked_at, user_agent, ip_address
 	`
 
 	err := r.db.GetContext(ctx, token, query,
-		token.ID, token.UserID, token.TokenHash, token.ExpiresAt,
+		token.ID, token.UserID, token.FamilyID, token.SessionID, token.TokenHash, token.ExpiresAt,
 		token.CreatedAt, token.UserAgent, token.IPAddress,
 	)
 
@@ -64,11 +79,32 @@ func (r *RefreshTokenRepository) Create(ctx context.Context, userID uuid.UUID, t
 	return token, nil
 }
 
-// GetByTokenHash retrieves a refresh token by token hash
+// GetByTokenHash retrieves an active refresh token by token hash
 func (r *RefreshTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	token, err := r.GetByTokenHashIncludeRevoked(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if token is revoked
+	if token.RevokedAt != nil {
+		return nil, ErrRefreshTokenRevoked
+	}
+
+	// Check if token is expired
+	if token.ExpiresAt.Before(time.Now()) {
+		return nil, ErrTokenExpired
+	}
+
+	return token, nil
+}
+
+// GetByTokenHashIncludeRevoked retrieves a refresh token by token hash, whether or not
+// it has been revoked or has expired
+func (r *RefreshTokenRepository) GetByTokenHashIncludeRevoked(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
 	var token models.RefreshToken
 	query := `
-		SELECT id, user_id, token_hash, expires_at, created_at, revoked_at, user_agent, ip_address
+		SELECT id, user_id, family_id, session_id, token_hash, expires_at, created_at, revoked_at, user_agent, ip_address
 		FROM refresh_tokens
 		WHERE token_hash = $1
 	`
@@ -81,17 +117,24 @@ func (r *RefreshTokenRepository) GetByTokenHash(ctx context.Context, tokenHash s
 		return nil, fmt.Errorf("failed to get refresh token: %w", err)
 	}
 
-	// Check if token is revoked
-	if token.RevokedAt != nil {
-		return nil, ErrRefreshTokenRevoked
-	}
+	return &token, nil
+}
 
-	// Check if token is expired
-	if token.ExpiresAt.Before(time.Now()) {
-		return nil, ErrTokenExpired
+// FindActiveByUserID returns the unrevoked, unexpired refresh tokens of a user, newest first
+func (r *RefreshTokenRepository) FindActiveByUserID(ctx context.Context, userID uuid.UUID) ([]*models.RefreshToken, error) {
+	tokens := make([]*models.RefreshToken, 0)
+	query := `
+		SELECT id, user_id, family_id, session_id, token_hash, expires_at, created_at, revoked_at, user_agent, ip_address
+		FROM refresh_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`
+
+	if err := r.db.SelectContext(ctx, &tokens, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to find active refresh tokens: %w", err)
 	}
 
-	return &token, nil
+	return tokens, nil
 }
 
 // Revoke revokes a refresh token
@@ -137,19 +180,97 @@ func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID uu
 	return nil
 }
 
-// DeleteExpired deletes all expired refresh tokens
-func (r *RefreshTokenRepository) DeleteExpired(ctx context.Context) error {
+// RevokeByID revokes the active refresh token of a session. Only the session's owner can
+// revoke it; ErrRefreshTokenNotFound is returned for unknown, foreign or already revoked sessions.
+func (r *RefreshTokenRepository) RevokeByID(ctx context.Context, sessionID, userID uuid.UUID) error {
+	now := time.Now()
 	query := `
-		DELETE FROM refresh_tokens
-		WHERE expires_at < $1 OR revoked_at < $1
+		UPDATE refresh_tokens
+		SET revoked_at = $1
+		WHERE session_id = $2 AND user_id = $3 AND revoked_at IS NULL
 	`
 
-	// Delete tokens expired or revoked more than 7 days ago
-	cutoff := time.Now().AddDate(0, 0, -7)
-	_, err := r.db.ExecContext(ctx, query, cutoff)
+	result, err := r.db.ExecContext(ctx, query, now, sessionID, userID)
 	if err != nil {
-		return fmt.Errorf("failed to delete expired refresh tokens: %w", err)
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrRefreshTokenNotFound
+	}
+
+	return nil
+}
+
+// RevokeByFamilyID revokes all refresh tokens in a token family
+func (r *RefreshTokenRepository) RevokeByFamilyID(ctx context.Context, familyID uuid.UUID) error {
+	now := time.Now()
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $1
+		WHERE family_id = $2 AND revoked_at IS NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, now, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
 	}
 
 	return nil
 }
+
+// DeleteExpired deletes refresh tokens that expired or were revoked more than 7 days ago,
+// returning how many were deleted. Tokens are kept for a week past their expiry or
+// revocation so a replayed token is still recognized as revoked rather than unknown.
+func (r *RefreshTokenRepository) DeleteExpired(ctx context.Context) (int, error) {
+	query := `
+		DELETE FROM refresh_tokens
+		WHERE expires_at < NOW() - INTERVAL '7 days'
+		   OR (revoked_at IS NOT NULL AND revoked_at < NOW() - INTERVAL '7 days')
+	`
+
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired refresh tokens: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(deleted), nil
+}
+
+// TokenStats counts the refresh tokens in the database. Revoked tokens are counted as
+// revoked whether or not they have also expired.
+type TokenStats struct {
+	Total   int64 `db:"total" json:"total"`
+	Active  int64 `db:"active" json:"active"`
+	Revoked int64 `db:"revoked" json:"revoked"`
+	Expired int64 `db:"expired" json:"expired"`
+}
+
+// Stats counts the refresh tokens by state
+func (r *RefreshTokenRepository) Stats(ctx context.Context) (*TokenStats, error) {
+	query := `
+		SELECT
+			COUNT(*) AS total,
+			COUNT(*) FILTER (WHERE revoked_at IS NULL AND expires_at > NOW()) AS active,
+			COUNT(*) FILTER (WHERE revoked_at IS NOT NULL) AS revoked,
+			COUNT(*) FILTER (WHERE revoked_at IS NULL AND expires_at <= NOW()) AS expired
+		FROM refresh_tokens
+	`
+
+	var stats TokenStats
+	if err := r.db.GetContext(ctx, &stats, query); err != nil {
+		return nil, fmt.Errorf("failed to count refresh tokens: %w", err)
+	}
+
+	return &stats, nil
+}
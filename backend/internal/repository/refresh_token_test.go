@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+// newMockDB returns a sqlx database backed by sqlmock, failing the test if an expected
+// query was not run
+func newMockDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unmet database expectations: %v", err)
+		}
+		_ = db.Close()
+	})
+	return sqlx.NewDb(db, "sqlmock"), mock
+}
+
+func TestRefreshTokenRepository_DeleteExpired(t *testing.T) {
+	testCases := []struct {
+		execErr     error
+		name        string
+		deleted     int64
+		wantDeleted int
+		wantErr     bool
+	}{
+		{name: "deletes old tokens", deleted: 12, wantDeleted: 12},
+		{name: "nothing to delete"},
+		{name: "database error", execErr: errors.New("connection reset"), wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			repo := NewRefreshTokenRepository(db)
+
+			// Only tokens expired or revoked over a week ago may be deleted
+			exec := mock.ExpectExec(`DELETE FROM refresh_tokens\s+WHERE expires_at < NOW\(\) - INTERVAL '7 days'\s+` +
+				`OR \(revoked_at IS NOT NULL AND revoked_at < NOW\(\) - INTERVAL '7 days'\)`)
+			if tc.execErr != nil {
+				exec.WillReturnError(tc.execErr)
+			} else {
+				exec.WillReturnResult(sqlmock.NewResult(0, tc.deleted))
+			}
+
+			deleted, err := repo.DeleteExpired(context.Background())
+			if tc.wantErr {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DeleteExpired failed: %v", err)
+			}
+			if deleted != tc.wantDeleted {
+				t.Errorf("Expected %d deleted tokens, got %d", tc.wantDeleted, deleted)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenRepository_Stats(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewRefreshTokenRepository(db)
+
+	mock.ExpectQuery(`SELECT\s+COUNT\(\*\) AS total,.+FROM refresh_tokens`).
+		WillReturnRows(sqlmock.NewRows([]string{"total", "active", "revoked", "expired"}).AddRow(10, 6, 3, 1))
+
+	stats, err := repo.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	want := TokenStats{Total: 10, Active: 6, Revoked: 3, Expired: 1}
+	if *stats != want {
+		t.Errorf("Expected %+v, got %+v", want, *stats)
+	}
+}
+
+func TestRefreshTokenRepository_Stats_DatabaseError(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewRefreshTokenRepository(db)
+
+	mock.ExpectQuery(`FROM refresh_tokens`).WillReturnError(errors.New("connection reset"))
+
+	if _, err := repo.Stats(context.Background()); err == nil {
+		t.Error("Expected error, got nil")
+	}
+}
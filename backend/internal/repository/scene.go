@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+)
+
+// ErrSceneNotFound is returned when a scene does not exist or belongs to another user or account.
+var ErrSceneNotFound = apierror.New(apierror.ErrNotFound, "scene not found")
+
+// SceneRepositoryInterface defines the interface for scene repository operations
+type SceneRepositoryInterface interface {
+	Create(ctx context.Context, scene *models.Scene) error
+	ListByAccount(ctx context.Context, userID, accountID uuid.UUID) ([]*models.Scene, error)
+	FindByID(ctx context.Context, id, userID, accountID uuid.UUID) (*models.Scene, error)
+	Delete(ctx context.Context, id, userID, accountID uuid.UUID) error
+}
+
+// SceneRepository handles scene database operations
+type SceneRepository struct {
+	db *sqlx.DB
+}
+
+// NewSceneRepository creates a new scene repository
+func NewSceneRepository(db *sqlx.DB) *SceneRepository {
+	return &SceneRepository{db: db}
+}
+
+const sceneColumns = `id, user_id, account_id, name, state_json, created_at`
+
+// Create stores a scene, filling in its ID and creation time
+func (r *SceneRepository) Create(ctx context.Context, scene *models.Scene) error {
+	if scene.ID == uuid.Nil {
+		scene.ID = uuid.New()
+	}
+
+	query := `
+		INSERT INTO scenes (id, user_id, account_id, name, state_json)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		scene.ID, scene.UserID, scene.AccountID, scene.Name, scene.States,
+	).Scan(&scene.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create scene: %w", err)
+	}
+
+	return nil
+}
+
+// ListByAccount returns the user's scenes for an account, newest first
+func (r *SceneRepository) ListByAccount(ctx context.Context, userID, accountID uuid.UUID) ([]*models.Scene, error) {
+	scenes := make([]*models.Scene, 0)
+	query := `
+		SELECT ` + sceneColumns + `
+		FROM scenes
+		WHERE user_id = $1 AND account_id = $2
+		ORDER BY created_at DESC
+	`
+
+	if err := r.db.SelectContext(ctx, &scenes, query, userID, accountID); err != nil {
+		return nil, fmt.Errorf("failed to list scenes: %w", err)
+	}
+
+	return scenes, nil
+}
+
+// FindByID retrieves one of the user's scenes for an account
+func (r *SceneRepository) FindByID(ctx context.Context, id, userID, accountID uuid.UUID) (*models.Scene, error) {
+	var scene models.Scene
+	query := `
+		SELECT ` + sceneColumns + `
+		FROM scenes
+		WHERE id = $1 AND user_id = $2 AND account_id = $3
+	`
+
+	err := r.db.GetContext(ctx, &scene, query, id, userID, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSceneNotFound
+		}
+		return nil, fmt.Errorf("failed to get scene: %w", err)
+	}
+
+	return &scene, nil
+}
+
+// Delete removes one of the user's scenes for an account
+func (r *SceneRepository) Delete(ctx context.Context, id, userID, accountID uuid.UUID) error {
+	query := `DELETE FROM scenes WHERE id = $1 AND user_id = $2 AND account_id = $3`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to delete scene: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrSceneNotFound
+	}
+
+	return nil
+}
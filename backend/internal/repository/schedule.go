@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+)
+
+// ErrScheduleNotFound is returned when a scheduled action does not exist or belongs to another user.
+var ErrScheduleNotFound = apierror.New(apierror.ErrNotFound, "schedule not found")
+
+// ScheduleRepositoryInterface defines the interface for scheduled action repository operations
+type ScheduleRepositoryInterface interface {
+	Create(ctx context.Context, schedule *models.ScheduledAction) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.ScheduledAction, error)
+	Delete(ctx context.Context, id, userID uuid.UUID) error
+	ListDue(ctx context.Context, dueBy time.Time) ([]*models.ScheduledAction, error)
+	NextRunAt(ctx context.Context) (*time.Time, error)
+	ClaimRun(ctx context.Context, id uuid.UUID, dueAt, lastRunAt, nextRunAt time.Time) (bool, error)
+	ClaimOnce(ctx context.Context, id uuid.UUID, dueAt time.Time) (bool, error)
+}
+
+// ScheduleRepository handles scheduled action database operations
+type ScheduleRepository struct {
+	db *sqlx.DB
+}
+
+// NewScheduleRepository creates a new scheduled action repository
+func NewScheduleRepository(db *sqlx.DB) *ScheduleRepository {
+	return &ScheduleRepository{db: db}
+}
+
+const scheduleColumns = `id, user_id, account_id, selector, action_json, cron_expression, enabled,
+	run_once, last_run_at, next_run_at, created_at`
+
+// scheduleColumnsOf returns scheduleColumns qualified with a table alias, for joins
+func scheduleColumnsOf(alias string) string {
+	columns := strings.Split(scheduleColumns, ",")
+	for i, column := range columns {
+		columns[i] = alias + "." + strings.TrimSpace(column)
+	}
+	return strings.Join(columns, ", ")
+}
+
+// Create stores a scheduled action, filling in its ID and creation time
+func (r *ScheduleRepository) Create(ctx context.Context, schedule *models.ScheduledAction) error {
+	if schedule.ID == uuid.Nil {
+		schedule.ID = uuid.New()
+	}
+
+	query := `
+		INSERT INTO scheduled_actions (id, user_id, account_id, selector, action_json, cron_expression, enabled, run_once, next_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		schedule.ID, schedule.UserID, schedule.AccountID, schedule.Selector, schedule.Action,
+		schedule.CronExpression, schedule.Enabled, schedule.RunOnce, schedule.NextRunAt,
+	).Scan(&schedule.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create scheduled action: %w", err)
+	}
+
+	return nil
+}
+
+// ListByUser returns the user's scheduled actions, newest first
+func (r *ScheduleRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.ScheduledAction, error) {
+	schedules := make([]*models.ScheduledAction, 0)
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM scheduled_actions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	if err := r.db.SelectContext(ctx, &schedules, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list scheduled actions: %w", err)
+	}
+
+	return schedules, nil
+}
+
+// Delete removes one of the user's scheduled actions
+func (r *ScheduleRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	query := `DELETE FROM scheduled_actions WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete scheduled action: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrScheduleNotFound
+	}
+
+	return nil
+}
+
+// ListDue returns the enabled scheduled actions due to run by dueBy, earliest first.
+// Actions of users awaiting deletion are left out. Listed actions must still be claimed
+// with ClaimRun before running, since other instances list them too.
+func (r *ScheduleRepository) ListDue(ctx context.Context, dueBy time.Time) ([]*models.ScheduledAction, error) {
+	schedules := make([]*models.ScheduledAction, 0)
+	query := `
+		SELECT ` + scheduleColumnsOf("s") + `
+		FROM scheduled_actions s
+		JOIN users u ON u.id = s.user_id AND u.deleted_at IS NULL
+		WHERE s.next_run_at <= $1 AND s.enabled = true
+		ORDER BY s.next_run_at
+	`
+
+	if err := r.db.SelectContext(ctx, &schedules, query, dueBy); err != nil {
+		return nil, fmt.Errorf("failed to list due scheduled actions: %w", err)
+	}
+
+	return schedules, nil
+}
+
+// NextRunAt returns when the earliest enabled scheduled action is due, or nil if there is
+// none. Actions of users awaiting deletion are left out.
+func (r *ScheduleRepository) NextRunAt(ctx context.Context) (*time.Time, error) {
+	var nextRunAt sql.NullTime
+	query := `
+		SELECT MIN(s.next_run_at)
+		FROM scheduled_actions s
+		JOIN users u ON u.id = s.user_id AND u.deleted_at IS NULL
+		WHERE s.enabled = true
+	`
+
+	if err := r.db.GetContext(ctx, &nextRunAt, query); err != nil {
+		return nil, fmt.Errorf("failed to get next scheduled run: %w", err)
+	}
+
+	if !nextRunAt.Valid {
+		return nil, nil
+	}
+	return &nextRunAt.Time, nil
+}
+
+// ClaimRun reschedules a scheduled action listed as due at dueAt to nextRunAt, recording
+// lastRunAt as its last run, and reports whether this call did so. Only one of the
+// instances listing the same due run claims it; the others find next_run_at moved on and
+// must not run the action.
+func (r *ScheduleRepository) ClaimRun(ctx context.Context, id uuid.UUID, dueAt, lastRunAt, nextRunAt time.Time) (bool, error) {
+	query := `
+		UPDATE scheduled_actions
+		SET last_run_at = $3, next_run_at = $4
+		WHERE id = $1 AND next_run_at = $2 AND enabled = true
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, dueAt, lastRunAt, nextRunAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim scheduled action: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// ClaimOnce removes a one-shot scheduled action listed as due at dueAt and reports
+// whether this call did so. Like ClaimRun, only one of the instances listing it claims it.
+func (r *ScheduleRepository) ClaimOnce(ctx context.Context, id uuid.UUID, dueAt time.Time) (bool, error) {
+	query := `
+		DELETE FROM scheduled_actions
+		WHERE id = $1 AND next_run_at = $2 AND enabled = true AND run_once = true
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, dueAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim scheduled action: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+)
+
+// ErrStateSnapshotNotFound is returned when a state snapshot does not exist or belongs to another user.
+var ErrStateSnapshotNotFound = apierror.New(apierror.ErrNotFound, "state snapshot not found")
+
+// StateSnapshotRepositoryInterface defines the interface for state snapshot repository operations
+type StateSnapshotRepositoryInterface interface {
+	Create(ctx context.Context, snapshot *models.StateSnapshot) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.StateSnapshot, error)
+	FindByID(ctx context.Context, id, userID uuid.UUID) (*models.StateSnapshot, error)
+	Delete(ctx context.Context, id, userID uuid.UUID) error
+}
+
+// StateSnapshotRepository handles state snapshot database operations
+type StateSnapshotRepository struct {
+	db *sqlx.DB
+}
+
+// NewStateSnapshotRepository creates a new state snapshot repository
+func NewStateSnapshotRepository(db *sqlx.DB) *StateSnapshotRepository {
+	return &StateSnapshotRepository{db: db}
+}
+
+const stateSnapshotColumns = `id, user_id, account_id, selector, name, state_json, created_at`
+
+// Create stores a state snapshot, filling in its ID and creation time
+func (r *StateSnapshotRepository) Create(ctx context.Context, snapshot *models.StateSnapshot) error {
+	if snapshot.ID == uuid.Nil {
+		snapshot.ID = uuid.New()
+	}
+
+	query := `
+		INSERT INTO state_snapshots (id, user_id, account_id, selector, name, state_json)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		snapshot.ID, snapshot.UserID, snapshot.AccountID, snapshot.Selector, snapshot.Name, snapshot.States,
+	).Scan(&snapshot.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create state snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// ListByUser returns the user's state snapshots across all accounts, newest first
+func (r *StateSnapshotRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.StateSnapshot, error) {
+	snapshots := make([]*models.StateSnapshot, 0)
+	query := `
+		SELECT ` + stateSnapshotColumns + `
+		FROM state_snapshots
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	if err := r.db.SelectContext(ctx, &snapshots, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list state snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
+
+// FindByID retrieves one of the user's state snapshots
+func (r *StateSnapshotRepository) FindByID(ctx context.Context, id, userID uuid.UUID) (*models.StateSnapshot, error) {
+	var snapshot models.StateSnapshot
+	query := `
+		SELECT ` + stateSnapshotColumns + `
+		FROM state_snapshots
+		WHERE id = $1 AND user_id = $2
+	`
+
+	err := r.db.GetContext(ctx, &snapshot, query, id, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrStateSnapshotNotFound
+		}
+		return nil, fmt.Errorf("failed to get state snapshot: %w", err)
+	}
+
+	return &snapshot, nil
+}
+
+// Delete removes one of the user's state snapshots
+func (r *StateSnapshotRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	query := `DELETE FROM state_snapshots WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete state snapshot: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrStateSnapshotNotFound
+	}
+
+	return nil
+}
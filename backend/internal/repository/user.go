@@ -9,21 +9,54 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 
 	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
 )
 
 var (
 	// ErrUserNotFound is returned when a user is not found in the database.
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserNotFound = apierror.New(apierror.ErrNotFound, "user not found")
 	// ErrUserAlreadyExists is returned when attempting to create a user with an email that already exists.
 	ErrUserAlreadyExists = errors.New("user already exists")
 	// ErrTokenExpired is returned when a verification or magic link token has expired.
 	ErrTokenExpired = errors.New("token expired")
 	// ErrTokenNotFound is returned when a token is not found in the database.
 	ErrTokenNotFound = errors.New("token not found")
+	// ErrUserPendingDeletion is returned when attempting to create a user with the email of a
+	// deleted user whose data has not been purged yet.
+	ErrUserPendingDeletion = errors.New("email belongs to a deleted account that can still be restored")
 )
 
+// UserRepositoryInterface defines the interface for user repository operations
+type UserRepositoryInterface interface {
+	Create(ctx context.Context, params models.CreateUserParams) (*models.User, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	UpdateProfile(ctx context.Context, userID uuid.UUID, params models.UpdateProfileParams) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	GetByEmailIncludeDeleted(ctx context.Context, email string) (*models.User, error)
+	GetByEmailVerificationToken(ctx context.Context, token string) (*models.User, error)
+	VerifyEmail(ctx context.Context, token string) error
+	SetNewVerificationToken(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time) error
+	SetMagicLinkToken(ctx context.Context, email, token string, expiresAt time.Time) error
+	GetByMagicLinkToken(ctx context.Context, token string) (*models.User, error)
+	ClearMagicLinkToken(ctx context.Context, userID uuid.UUID) error
+	ClearExpiredMagicLinks(ctx context.Context, expiredBefore time.Time) (int, error)
+	SetPasswordResetToken(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	GetByPasswordResetToken(ctx context.Context, tokenHash string) (*models.User, error)
+	UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash, resetTokenHash string) error
+	SetPendingEmail(ctx context.Context, userID uuid.UUID, pendingEmail, tokenHash string, expiresAt time.Time) error
+	GetByPendingEmailToken(ctx context.Context, tokenHash string) (*models.User, error)
+	ConfirmEmailChange(ctx context.Context, userID uuid.UUID, tokenHash string) error
+	SetTOTPSecret(ctx context.Context, userID uuid.UUID, encryptedSecret []byte) error
+	ClearTOTPSecret(ctx context.Context, userID uuid.UUID) error
+	Update(ctx context.Context, user *models.User) error
+	SoftDelete(ctx context.Context, userID uuid.UUID, deletedAt time.Time) error
+	Restore(ctx context.Context, userID uuid.UUID, deletedAfter time.Time) error
+	HardDeleteExpiredAccounts(ctx context.Context, deletedBefore time.Time) (int, error)
+}
+
 // UserRepository handles user database operations
 type UserRepository struct {
 	db *sqlx.DB
@@ -40,7 +73,7 @@ func (r *UserRepository) Create(ctx context.Context, params models.CreateUserPar
 		ID:                         uuid.New(),
 		Email:                      params.Email,
 		PasswordHash:               params.PasswordHash,
-		EmailVerified:              false,
+		EmailVerified:              params.EmailVerified,
 		EmailVerificationToken:     &params.EmailVerificationToken,
 		EmailVerificationExpiresAt: &params.EmailVerificationExpiresAt,
 		Role:                       "user",
@@ -48,6 +81,12 @@ func (r *UserRepository) Create(ctx context.Context, params models.CreateUserPar
 		UpdatedAt:                  time.Now(),
 	}
 
+	// Verified users have no verification token to redeem
+	if params.EmailVerified {
+		user.EmailVerificationToken = nil
+		user.EmailVerificationExpiresAt = nil
+	}
+
 	query := `
 		INSERT INTO users (
 			id, email, password_hash, email_verified,
@@ -59,6 +98,7 @@ func (r *UserRepository) Create(ctx context.Context, params models.CreateUserPar
 		RETURNING id, email, password_hash, email_verified,
 			email_verification_token, email_verification_expires_at,
 			magic_link_token, magic_link_expires_at,
+			totp_secret, totp_enabled,
 			stripe_customer_id, role, created_at, updated_at
 	`
 
@@ -71,7 +111,7 @@ func (r *UserRepository) Create(ctx context.Context, params models.CreateUserPar
 	if err != nil {
 		// Check for unique constraint violation
 		if err.Error() == "pq: duplicate key value violates unique constraint \"users_email_key\"" {
-			return nil, ErrUserAlreadyExists
+			return nil, r.existingUserError(ctx, user.Email)
 		}
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
@@ -86,9 +126,11 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 		SELECT id, email, password_hash, email_verified,
 			email_verification_token, email_verification_expires_at,
 			magic_link_token, magic_link_expires_at,
+			totp_secret, totp_enabled,
+			pending_email, display_name, notification_preferences,
 			stripe_customer_id, role, created_at, updated_at
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	err := r.db.GetContext(ctx, &user, query, id)
@@ -109,8 +151,34 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 		SELECT id, email, password_hash, email_verified,
 			email_verification_token, email_verification_expires_at,
 			magic_link_token, magic_link_expires_at,
+			totp_secret, totp_enabled,
 			stripe_customer_id, role, created_at, updated_at
 		FROM users
+		WHERE email = $1 AND deleted_at IS NULL
+	`
+
+	err := r.db.GetContext(ctx, &user, query, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+
+	return &user, nil
+}
+
+// GetByEmailIncludeDeleted retrieves a user by email, including a deleted user whose data
+// has not been purged yet
+func (r *UserRepository) GetByEmailIncludeDeleted(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	query := `
+		SELECT id, email, password_hash, email_verified,
+			email_verification_token, email_verification_expires_at,
+			magic_link_token, magic_link_expires_at,
+			totp_secret, totp_enabled,
+			stripe_customer_id, role, created_at, updated_at, deleted_at
+		FROM users
 		WHERE email = $1
 	`
 
@@ -125,6 +193,16 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 	return &user, nil
 }
 
+// existingUserError returns the error reporting that email is already taken, telling
+// apart an account deleted but not purged yet
+func (r *UserRepository) existingUserError(ctx context.Context, email string) error {
+	existing, err := r.GetByEmailIncludeDeleted(ctx, email)
+	if err == nil && existing.DeletedAt != nil {
+		return ErrUserPendingDeletion
+	}
+	return ErrUserAlreadyExists
+}
+
 // GetByEmailVerificationToken retrieves a user by email verification token
 func (r *UserRepository) GetByEmailVerificationToken(ctx context.Context, token string) (*models.User, error) {
 	var user models.User
@@ -132,9 +210,10 @@ func (r *UserRepository) GetByEmailVerificationToken(ctx context.Context, token
 		SELECT id, email, password_hash, email_verified,
 			email_verification_token, email_verification_expires_at,
 			magic_link_token, magic_link_expires_at,
+			totp_secret, totp_enabled,
 			stripe_customer_id, role, created_at, updated_at
 		FROM users
-		WHERE email_verification_token = $1
+		WHERE email_verification_token = $1 AND deleted_at IS NULL
 			AND email_verification_expires_at > $2
 	`
 
@@ -178,6 +257,33 @@ func (r *UserRepository) VerifyEmail(ctx context.Context, token string) error {
 	return nil
 }
 
+// SetNewVerificationToken replaces the email verification token of an unverified user
+func (r *UserRepository) SetNewVerificationToken(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time) error {
+	query := `
+		UPDATE users
+		SET email_verification_token = $1,
+			email_verification_expires_at = $2,
+			updated_at = $3
+		WHERE id = $4 AND email_verified = false AND deleted_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, token, expiresAt, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to set verification token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
 // SetMagicLinkToken sets a magic link token for password-less login
 func (r *UserRepository) SetMagicLinkToken(ctx context.Context, email, token string, expiresAt time.Time) error {
 	query := `
@@ -185,7 +291,7 @@ func (r *UserRepository) SetMagicLinkToken(ctx context.Context, email, token str
 		SET magic_link_token = $1,
 			magic_link_expires_at = $2,
 			updated_at = $3
-		WHERE email = $4
+		WHERE email = $4 AND deleted_at IS NULL
 	`
 
 	result, err := r.db.ExecContext(ctx, query, token, expiresAt, time.Now(), email)
@@ -212,9 +318,10 @@ func (r *UserRepository) GetByMagicLinkToken(ctx context.Context, token string)
 		SELECT id, email, password_hash, email_verified,
 			email_verification_token, email_verification_expires_at,
 			magic_link_token, magic_link_expires_at,
+			totp_secret, totp_enabled,
 			stripe_customer_id, role, created_at, updated_at
 		FROM users
-		WHERE magic_link_token = $1
+		WHERE magic_link_token = $1 AND deleted_at IS NULL
 			AND magic_link_expires_at > $2
 	`
 
@@ -247,6 +354,284 @@ func (r *UserRepository) ClearMagicLinkToken(ctx context.Context, userID uuid.UU
 	return nil
 }
 
+// ClearExpiredMagicLinks clears the magic link tokens that expired before expiredBefore,
+// returning how many were cleared
+func (r *UserRepository) ClearExpiredMagicLinks(ctx context.Context, expiredBefore time.Time) (int, error) {
+	query := `
+		UPDATE users
+		SET magic_link_token = NULL,
+			magic_link_expires_at = NULL,
+			updated_at = $1
+		WHERE magic_link_expires_at < $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), expiredBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear expired magic links: %w", err)
+	}
+
+	cleared, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(cleared), nil
+}
+
+// SetPasswordResetToken stores the hash of a password reset token for the user
+func (r *UserRepository) SetPasswordResetToken(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	query := `
+		UPDATE users
+		SET password_reset_token = $1,
+			password_reset_expires_at = $2,
+			updated_at = $3
+		WHERE id = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, tokenHash, expiresAt, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to set password reset token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetByPasswordResetToken retrieves a user by password reset token hash
+// Expiry is left to the caller so an expired token can be reported as such
+func (r *UserRepository) GetByPasswordResetToken(ctx context.Context, tokenHash string) (*models.User, error) {
+	var user models.User
+	query := `
+		SELECT id, email, password_hash, email_verified,
+			email_verification_token, email_verification_expires_at,
+			magic_link_token, magic_link_expires_at,
+			password_reset_token, password_reset_expires_at,
+			totp_secret, totp_enabled,
+			stripe_customer_id, role, created_at, updated_at
+		FROM users
+		WHERE password_reset_token = $1 AND deleted_at IS NULL
+	`
+
+	err := r.db.GetContext(ctx, &user, query, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by password reset token: %w", err)
+	}
+
+	return &user, nil
+}
+
+// UpdatePassword sets a new password hash and clears any pending password reset token
+// The token is matched in the same statement so a reset token can only be redeemed once
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash, resetTokenHash string) error {
+	query := `
+		UPDATE users
+		SET password_hash = $1,
+			password_reset_token = NULL,
+			password_reset_expires_at = NULL,
+			updated_at = $2
+		WHERE id = $3
+			AND password_reset_token = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, passwordHash, time.Now(), userID, resetTokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrTokenNotFound
+	}
+
+	return nil
+}
+
+// SetPendingEmail stores an email change awaiting verification, replacing any pending one
+func (r *UserRepository) SetPendingEmail(ctx context.Context, userID uuid.UUID, pendingEmail, tokenHash string, expiresAt time.Time) error {
+	query := `
+		UPDATE users
+		SET pending_email = $1,
+			pending_email_token = $2,
+			pending_email_expires_at = $3,
+			updated_at = $4
+		WHERE id = $5 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, pendingEmail, tokenHash, expiresAt, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to set pending email: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetByPendingEmailToken retrieves a user by pending email token hash
+// Expiry is left to the caller so an expired token can be reported as such
+func (r *UserRepository) GetByPendingEmailToken(ctx context.Context, tokenHash string) (*models.User, error) {
+	var user models.User
+	query := `
+		SELECT id, email, password_hash, email_verified,
+			pending_email, pending_email_token, pending_email_expires_at,
+			totp_secret, totp_enabled,
+			stripe_customer_id, role, created_at, updated_at
+		FROM users
+		WHERE pending_email_token = $1 AND deleted_at IS NULL
+	`
+
+	err := r.db.GetContext(ctx, &user, query, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by pending email token: %w", err)
+	}
+
+	return &user, nil
+}
+
+// ConfirmEmailChange replaces the user's email with their pending email and clears the
+// pending change. The token is matched in the same statement so it can only be redeemed once.
+func (r *UserRepository) ConfirmEmailChange(ctx context.Context, userID uuid.UUID, tokenHash string) error {
+	query := `
+		UPDATE users
+		SET email = pending_email,
+			email_verified = true,
+			pending_email = NULL,
+			pending_email_token = NULL,
+			pending_email_expires_at = NULL,
+			updated_at = $1
+		WHERE id = $2
+			AND pending_email_token = $3
+			AND deleted_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), userID, tokenHash)
+	if err != nil {
+		// The address may have been registered since the change was requested
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrUserAlreadyExists
+		}
+		return fmt.Errorf("failed to confirm email change: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrTokenNotFound
+	}
+
+	return nil
+}
+
+// SetTOTPSecret stores the user's encrypted TOTP secret and enables two-factor authentication
+func (r *UserRepository) SetTOTPSecret(ctx context.Context, userID uuid.UUID, encryptedSecret []byte) error {
+	query := `
+		UPDATE users
+		SET totp_secret = $1,
+			totp_enabled = true,
+			updated_at = $2
+		WHERE id = $3
+	`
+
+	result, err := r.db.ExecContext(ctx, query, encryptedSecret, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to set totp secret: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ClearTOTPSecret removes the user's TOTP secret and disables two-factor authentication
+func (r *UserRepository) ClearTOTPSecret(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET totp_secret = NULL,
+			totp_enabled = false,
+			updated_at = $1
+		WHERE id = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to clear totp secret: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateProfile updates the profile fields set in params and returns the updated user
+func (r *UserRepository) UpdateProfile(ctx context.Context, userID uuid.UUID, params models.UpdateProfileParams) (*models.User, error) {
+	// Left untyped so unchanged preferences are sent as NULL rather than empty bytes
+	var preferences interface{}
+	if params.NotificationPreferences != nil {
+		preferences = []byte(*params.NotificationPreferences)
+	}
+
+	var user models.User
+	query := `
+		UPDATE users
+		SET display_name = CASE WHEN $2 THEN NULLIF($3, '') ELSE display_name END,
+			notification_preferences = COALESCE($4::jsonb, notification_preferences),
+			updated_at = $5
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, email, password_hash, email_verified,
+			email_verification_token, email_verification_expires_at,
+			magic_link_token, magic_link_expires_at,
+			totp_secret, totp_enabled,
+			pending_email, display_name, notification_preferences,
+			stripe_customer_id, role, created_at, updated_at
+	`
+
+	err := r.db.GetContext(ctx, &user, query,
+		userID, params.DisplayName != nil, params.DisplayName, preferences, time.Now(),
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to update user profile: %w", err)
+	}
+
+	return &user, nil
+}
+
 // Update updates a user
 func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 	user.UpdatedAt = time.Now()
@@ -289,3 +674,111 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 
 	return nil
 }
+
+// SoftDelete marks a user as deleted. Deleted users can no longer be found or sign in,
+// but their data is kept until HardDeleteExpiredAccounts removes it.
+func (r *UserRepository) SoftDelete(ctx context.Context, userID uuid.UUID, deletedAt time.Time) error {
+	query := `
+		UPDATE users
+		SET deleted_at = $1, updated_at = $1
+		WHERE id = $2 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, deletedAt, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// Restore undoes the deletion of a user deleted after deletedAfter. Users deleted earlier,
+// whose grace period has passed, are reported as not found.
+func (r *UserRepository) Restore(ctx context.Context, userID uuid.UUID, deletedAfter time.Time) error {
+	query := `
+		UPDATE users
+		SET deleted_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NOT NULL AND deleted_at > $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, deletedAfter)
+	if err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// purgedUserTables lists the tables holding a user's data, in the order they are purged.
+// Audit events are removed explicitly since they otherwise outlive their user.
+var purgedUserTables = []struct {
+	table  string
+	column string
+}{
+	{table: "scenes", column: "user_id"},
+	{table: "webhooks", column: "user_id"},
+	{table: "api_keys", column: "user_id"},
+	{table: "accounts", column: "owner_user_id"},
+	{table: "refresh_tokens", column: "user_id"},
+	{table: "oauth_providers", column: "user_id"},
+	{table: "audit_events", column: "user_id"},
+}
+
+// HardDeleteExpiredAccounts permanently deletes users soft-deleted before deletedBefore along
+// with all of their data, in a single transaction. It returns the number of users purged.
+func (r *UserRepository) HardDeleteExpiredAccounts(ctx context.Context, deletedBefore time.Time) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var userIDs []uuid.UUID
+	query := `
+		SELECT id FROM users
+		WHERE deleted_at IS NOT NULL AND deleted_at < $1
+		FOR UPDATE
+	`
+	if err := tx.SelectContext(ctx, &userIDs, query, deletedBefore); err != nil {
+		return 0, fmt.Errorf("failed to find deleted users: %w", err)
+	}
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	ids := pq.Array(userIDs)
+	for _, t := range purgedUserTables {
+		query := fmt.Sprintf(`DELETE FROM %s WHERE %s = ANY($1)`, t.table, t.column)
+		if _, err := tx.ExecContext(ctx, query, ids); err != nil {
+			return 0, fmt.Errorf("failed to purge %s: %w", t.table, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ANY($1)`, ids); err != nil {
+		return 0, fmt.Errorf("failed to purge users: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit purge: %w", err)
+	}
+
+	return len(userIDs), nil
+}
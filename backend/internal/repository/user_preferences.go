@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/lightshare/backend/internal/models"
+)
+
+// UserPreferencesRepositoryInterface defines the interface for user preferences operations
+type UserPreferencesRepositoryInterface interface {
+	Get(ctx context.Context, userID uuid.UUID) (*models.UserPreferences, error)
+	Upsert(ctx context.Context, userID uuid.UUID, prefs *models.UserPreferences) error
+}
+
+// UserPreferencesRepository handles user preferences database operations
+type UserPreferencesRepository struct {
+	db *sqlx.DB
+}
+
+// NewUserPreferencesRepository creates a new user preferences repository
+func NewUserPreferencesRepository(db *sqlx.DB) *UserPreferencesRepository {
+	return &UserPreferencesRepository{db: db}
+}
+
+// Get returns the preferences of a user. A user who never saved any gets empty preferences.
+func (r *UserPreferencesRepository) Get(ctx context.Context, userID uuid.UUID) (*models.UserPreferences, error) {
+	var prefs models.UserPreferences
+	query := `
+		SELECT user_id, default_brightness, default_kelvin, favorite_hue, favorite_saturation,
+			wake_kelvin, sleep_kelvin, updated_at
+		FROM user_preferences
+		WHERE user_id = $1
+	`
+
+	err := r.db.GetContext(ctx, &prefs, query, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &models.UserPreferences{UserID: userID}, nil
+		}
+		return nil, fmt.Errorf("failed to get user preferences: %w", err)
+	}
+
+	return &prefs, nil
+}
+
+// Upsert stores every preference of a user, replacing any saved before, and fills in
+// prefs' user ID and update time
+func (r *UserPreferencesRepository) Upsert(ctx context.Context, userID uuid.UUID, prefs *models.UserPreferences) error {
+	query := `
+		INSERT INTO user_preferences (user_id, default_brightness, default_kelvin, favorite_hue,
+			favorite_saturation, wake_kelvin, sleep_kelvin)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE SET
+			default_brightness = EXCLUDED.default_brightness,
+			default_kelvin = EXCLUDED.default_kelvin,
+			favorite_hue = EXCLUDED.favorite_hue,
+			favorite_saturation = EXCLUDED.favorite_saturation,
+			wake_kelvin = EXCLUDED.wake_kelvin,
+			sleep_kelvin = EXCLUDED.sleep_kelvin,
+			updated_at = NOW()
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		userID, prefs.DefaultBrightness, prefs.DefaultKelvin, prefs.FavoriteHue,
+		prefs.FavoriteSaturation, prefs.WakeKelvin, prefs.SleepKelvin,
+	).Scan(&prefs.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save user preferences: %w", err)
+	}
+	prefs.UserID = userID
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+)
+
+// ErrWebhookNotFound is returned when a webhook is not found in the database
+var ErrWebhookNotFound = apierror.New(apierror.ErrNotFound, "webhook not found")
+
+// WebhookRepositoryInterface defines the interface for webhook repository operations
+type WebhookRepositoryInterface interface {
+	Create(ctx context.Context, params *models.CreateWebhookParams) (*models.Webhook, error)
+	FindByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Webhook, error)
+	FindActiveByEventType(ctx context.Context, userID uuid.UUID, eventType string) ([]*models.Webhook, error)
+	Delete(ctx context.Context, id, userID uuid.UUID) error
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+}
+
+// WebhookRepository handles webhook database operations
+type WebhookRepository struct {
+	db *sqlx.DB
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *sqlx.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const webhookColumns = `id, user_id, url, secret, event_types, active, created_at`
+
+// Create creates a new active webhook
+func (r *WebhookRepository) Create(ctx context.Context, params *models.CreateWebhookParams) (*models.Webhook, error) {
+	var webhook models.Webhook
+	query := `
+		INSERT INTO webhooks (id, user_id, url, secret, event_types, active, created_at)
+		VALUES ($1, $2, $3, $4, $5, TRUE, $6)
+		RETURNING ` + webhookColumns
+
+	err := r.db.GetContext(ctx, &webhook, query,
+		uuid.New(), params.UserID, params.URL, params.Secret, pq.StringArray(params.EventTypes), time.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+// FindByUserID returns all webhooks for a user, newest first
+func (r *WebhookRepository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Webhook, error) {
+	webhooks := make([]*models.Webhook, 0)
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	if err := r.db.SelectContext(ctx, &webhooks, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// FindActiveByEventType returns a user's active webhooks subscribed to an event type
+func (r *WebhookRepository) FindActiveByEventType(ctx context.Context, userID uuid.UUID, eventType string) ([]*models.Webhook, error) {
+	webhooks := make([]*models.Webhook, 0)
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE user_id = $1 AND active AND $2 = ANY(event_types)
+	`
+
+	if err := r.db.SelectContext(ctx, &webhooks, query, userID, eventType); err != nil {
+		return nil, fmt.Errorf("failed to find webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// Delete deletes a webhook owned by the user
+func (r *WebhookRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	query := `
+		DELETE FROM webhooks
+		WHERE id = $1 AND user_id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// CreateDelivery logs a webhook delivery attempt
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (id, webhook_id, event_type, attempt, status_code, success, error, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	delivery.ID = uuid.New()
+	delivery.CreatedAt = time.Now()
+	_, err := r.db.ExecContext(ctx, query,
+		delivery.ID, delivery.WebhookID, delivery.EventType, delivery.Attempt,
+		delivery.StatusCode, delivery.Success, delivery.Error, delivery.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to log webhook delivery: %w", err)
+	}
+
+	return nil
+}
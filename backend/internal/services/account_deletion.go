@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/crypto"
+)
+
+// AccountDeletionGracePeriod is how long a deleted user's data is kept before it is purged
+const AccountDeletionGracePeriod = models.AccountDeletionGracePeriod
+
+var (
+	// ErrDeletionNotConfirmed is returned when a user without a password deletes their account
+	// without explicitly confirming it
+	ErrDeletionNotConfirmed = errors.New("account deletion not confirmed")
+	// ErrAccountNotDeleted is returned when restoring an account that is not deleted
+	ErrAccountNotDeleted = errors.New("account is not deleted")
+	// ErrRestorePeriodExpired is returned when restoring an account deleted more than
+	// AccountDeletionGracePeriod ago
+	ErrRestorePeriodExpired = errors.New("account can no longer be restored")
+	// ErrEmailPendingDeletion is returned when signing up with the email of a deleted account
+	// that can still be restored
+	ErrEmailPendingDeletion = errors.New("email belongs to a deleted account that can still be restored")
+)
+
+// AccountDeletedError is returned by Login for a deleted account that can still be restored
+// with RestoreAccount
+type AccountDeletedError struct {
+	CanRestoreUntil time.Time `json:"can_restore_until"`
+}
+
+func (e *AccountDeletedError) Error() string {
+	return "account deleted"
+}
+
+// DeleteAccount deletes a user after verifying their password. Users who only sign in with
+// a provider have no password and must set confirmDelete instead. All of the user's sessions
+// are revoked immediately; their data is purged once the grace period has passed. It returns
+// when the purge is due.
+func (s *AuthService) DeleteAccount(ctx context.Context, userID uuid.UUID, password string, confirmDelete bool) (time.Time, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.PasswordHash == "" {
+		if !confirmDelete {
+			return time.Time{}, ErrDeletionNotConfirmed
+		}
+	} else if err := crypto.ComparePassword(password, user.PasswordHash); err != nil {
+		return time.Time{}, ErrInvalidCredentials
+	}
+
+	if err := s.refreshTokenRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return time.Time{}, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	deletedAt := time.Now().UTC()
+	if err := s.userRepo.SoftDelete(ctx, userID, deletedAt); err != nil {
+		return time.Time{}, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	purgeAt := deletedAt.Add(AccountDeletionGracePeriod)
+	s.recordAudit(ctx, models.EventUserDeleted, userID, nil, nil, map[string]interface{}{"purge_at": purgeAt})
+
+	return purgeAt, nil
+}
+
+// RestoreAccount undoes the deletion of an account within its grace period, after verifying
+// the password, and signs the user in as Login does
+func (s *AuthService) RestoreAccount(ctx context.Context, req LoginRequest, userAgent, ipAddress *string) (*LoginResponse, error) {
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+
+	if err := s.checkLockout(ctx, req.Email); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByEmailIncludeDeleted(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.PasswordHash == "" {
+		return nil, ErrInvalidCredentials
+	}
+	if err := crypto.ComparePassword(req.Password, user.PasswordHash); err != nil {
+		s.recordLoginFailure(ctx, user, userAgent, ipAddress)
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.DeletedAt == nil {
+		return nil, ErrAccountNotDeleted
+	}
+	if err := s.userRepo.Restore(ctx, user.ID, time.Now().Add(-AccountDeletionGracePeriod)); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrRestorePeriodExpired
+		}
+		return nil, fmt.Errorf("failed to restore user: %w", err)
+	}
+	user.DeletedAt = nil
+
+	s.recordAudit(ctx, models.EventUserRestored, user.ID, userAgent, ipAddress, nil)
+
+	return s.completePasswordLogin(ctx, user, userAgent, ipAddress)
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/email"
+)
+
+func TestDeleteAccount_WithPassword(t *testing.T) {
+	service := newTestAuthService(t, withPassword("correct-horse-battery"), withSession())
+	user := service.user
+
+	if _, err := service.DeleteAccount(context.Background(), user.ID, "wrong-password", true); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Expected ErrInvalidCredentials, got %v", err)
+	}
+	if active := service.tokens.activeTokens(); active != 1 {
+		t.Fatalf("Expected session to survive a failed deletion, got %d active", active)
+	}
+
+	purgeAt, err := service.DeleteAccount(context.Background(), user.ID, "correct-horse-battery", false)
+	if err != nil {
+		t.Fatalf("DeleteAccount failed: %v", err)
+	}
+
+	if until := time.Until(purgeAt); until < AccountDeletionGracePeriod-time.Minute || until > AccountDeletionGracePeriod {
+		t.Errorf("Expected purge after the grace period, got %s", purgeAt)
+	}
+	if active := service.tokens.activeTokens(); active != 0 {
+		t.Errorf("Expected all sessions to be revoked, got %d active", active)
+	}
+	if _, err := service.userRepo.GetByID(context.Background(), user.ID); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("Expected deleted user to no longer be found, got %v", err)
+	}
+}
+
+func TestDeleteAccount_WithoutPasswordRequiresConfirmation(t *testing.T) {
+	service := newTestAuthService(t, withSession())
+	user := service.user
+
+	if _, err := service.DeleteAccount(context.Background(), user.ID, "", false); !errors.Is(err, ErrDeletionNotConfirmed) {
+		t.Fatalf("Expected ErrDeletionNotConfirmed, got %v", err)
+	}
+
+	if _, err := service.DeleteAccount(context.Background(), user.ID, "", true); err != nil {
+		t.Fatalf("DeleteAccount failed: %v", err)
+	}
+}
+
+func TestLogin_DeletedAccountCanBeRestored(t *testing.T) {
+	service := newTestAuthService(t, withPassword("correct-horse-battery"), withSession())
+	user := service.user
+	user.EmailVerified = true
+	ctx := context.Background()
+	credentials := LoginRequest{Email: "user@example.com", Password: "correct-horse-battery"}
+
+	if _, err := service.RestoreAccount(ctx, credentials, nil, nil); !errors.Is(err, ErrAccountNotDeleted) {
+		t.Fatalf("Expected ErrAccountNotDeleted, got %v", err)
+	}
+
+	purgeAt, err := service.DeleteAccount(ctx, user.ID, "correct-horse-battery", false)
+	if err != nil {
+		t.Fatalf("DeleteAccount failed: %v", err)
+	}
+
+	// A wrong password doesn't reveal that the account was deleted
+	if _, err := service.Login(ctx, LoginRequest{Email: "user@example.com", Password: "wrong-password"}, nil, nil); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+
+	_, err = service.Login(ctx, credentials, nil, nil)
+	var deletedErr *AccountDeletedError
+	if !errors.As(err, &deletedErr) {
+		t.Fatalf("Expected AccountDeletedError, got %v", err)
+	}
+	if !deletedErr.CanRestoreUntil.Equal(purgeAt) {
+		t.Errorf("Expected the account to be restorable until %v, got %v", purgeAt, deletedErr.CanRestoreUntil)
+	}
+
+	if _, err := service.RestoreAccount(ctx, LoginRequest{Email: "user@example.com", Password: "wrong-password"}, nil, nil); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+
+	resp, err := service.RestoreAccount(ctx, credentials, nil, nil)
+	if err != nil {
+		t.Fatalf("RestoreAccount failed: %v", err)
+	}
+	if resp.AccessToken == "" || resp.RefreshToken == "" {
+		t.Error("Expected a fresh token pair")
+	}
+	if _, err := service.userRepo.GetByID(ctx, user.ID); err != nil {
+		t.Errorf("Expected the restored user to be found, got %v", err)
+	}
+}
+
+func TestRestoreAccount_AfterGracePeriod(t *testing.T) {
+	service := newTestAuthService(t, withPassword("correct-horse-battery"), withSession())
+	user := service.user
+	deletedAt := time.Now().Add(-AccountDeletionGracePeriod - time.Hour)
+	user.DeletedAt = &deletedAt
+
+	_, err := service.RestoreAccount(context.Background(), LoginRequest{Email: "user@example.com", Password: "correct-horse-battery"}, nil, nil)
+	if !errors.Is(err, ErrRestorePeriodExpired) {
+		t.Errorf("Expected ErrRestorePeriodExpired, got %v", err)
+	}
+}
+
+func TestSignup_EmailOfDeletedAccount(t *testing.T) {
+	service := newTestAuthService(t, withSession())
+	user := service.user
+	service.emailService = email.New(&email.Config{FromEmail: "noreply@lightshare.com"})
+	deletedAt := time.Now()
+	user.DeletedAt = &deletedAt
+
+	_, err := service.Signup(context.Background(), SignupRequest{Email: "user@example.com", Password: "correct-horse-battery"}, nil, nil)
+	if !errors.Is(err, ErrEmailPendingDeletion) {
+		t.Errorf("Expected ErrEmailPendingDeletion, got %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+const (
+	// lockoutThreshold is how many failed logins within lockoutWindow lock an account
+	lockoutThreshold = 5
+	// lockoutWindow is how far back failed logins are counted
+	lockoutWindow = 15 * time.Minute
+	// lockoutDuration is how long a locked account rejects logins
+	lockoutDuration = 30 * time.Minute
+)
+
+// AccountLockedError is returned by Login while an account is locked after too many failed
+// logins
+type AccountLockedError struct {
+	LockedUntil time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return "account temporarily locked after too many failed login attempts"
+}
+
+func loginAttemptsKey(email string) string {
+	return "login_attempts:" + email
+}
+
+func lockedUntilKey(email string) string {
+	return "locked_until:email:" + email
+}
+
+// AccountLockoutService locks accounts by email after repeated failed logins
+type AccountLockoutService struct {
+	cache *redis.Client
+	now   func() time.Time
+}
+
+// NewAccountLockoutService creates a lockout service storing failures and locks in cache
+func NewAccountLockoutService(cache *redis.Client) *AccountLockoutService {
+	return &AccountLockoutService{cache: cache, now: time.Now}
+}
+
+// IsLocked reports whether email is locked and, if so, until when
+func (s *AccountLockoutService) IsLocked(ctx context.Context, email string) (time.Time, bool, error) {
+	value, err := s.cache.Get(ctx, lockedUntilKey(email)).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, fmt.Errorf("failed to get account lock: %w", err)
+	}
+	return time.Unix(value, 0), true, nil
+}
+
+// RecordFailure records a failed login for email and locks it once lockoutThreshold
+// failures fall within lockoutWindow. Returns when the account is locked until, or the zero
+// time if this failure did not lock it.
+func (s *AccountLockoutService) RecordFailure(ctx context.Context, email string) (time.Time, error) {
+	now := s.now()
+	key := loginAttemptsKey(email)
+
+	var count *redis.IntCmd
+	_, err := s.cache.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-lockoutWindow).UnixNano(), 10))
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixNano()), Member: now.UnixNano()})
+		count = pipe.ZCard(ctx, key)
+		pipe.Expire(ctx, key, lockoutWindow)
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	if count.Val() < lockoutThreshold {
+		return time.Time{}, nil
+	}
+
+	lockedUntil := now.Add(lockoutDuration).Truncate(time.Second)
+	// Only the failure that creates the lock reports it, so the alert is sent once
+	locked, err := s.cache.SetNX(ctx, lockedUntilKey(email), lockedUntil.Unix(), lockoutDuration).Result()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to lock account: %w", err)
+	}
+	// Failures counted toward this lock do not count toward the next one
+	s.cache.Del(ctx, key)
+	if !locked {
+		return time.Time{}, nil
+	}
+	return lockedUntil, nil
+}
+
+// Reset forgets the failed logins of email, e.g. after a successful login
+func (s *AccountLockoutService) Reset(ctx context.Context, email string) {
+	s.cache.Del(ctx, loginAttemptsKey(email))
+}
+
+// Unlock lifts the lock of email and forgets its failed logins
+func (s *AccountLockoutService) Unlock(ctx context.Context, email string) error {
+	if err := s.cache.Del(ctx, lockedUntilKey(email), loginAttemptsKey(email)).Err(); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	return nil
+}
+
+// checkLockout returns an AccountLockedError while email is locked
+func (s *AuthService) checkLockout(ctx context.Context, email string) error {
+	if s.lockout == nil {
+		return nil
+	}
+
+	lockedUntil, locked, err := s.lockout.IsLocked(ctx, email)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return &AccountLockedError{LockedUntil: lockedUntil}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a wrong password for user and, when it locks the account,
+// alerts the user by email. Failures to record are logged so the login still fails as
+// invalid credentials.
+func (s *AuthService) recordLoginFailure(ctx context.Context, user *models.User, userAgent, ipAddress *string) {
+	if s.lockout == nil {
+		return
+	}
+
+	lockedUntil, err := s.lockout.RecordFailure(ctx, user.Email)
+	if err != nil {
+		logger.WithContext(ctx).Error("Failed to record login failure", "user_id", user.ID, "error", err)
+		return
+	}
+	if lockedUntil.IsZero() {
+		return
+	}
+
+	s.recordAudit(ctx, models.EventAccountLocked, user.ID, userAgent, ipAddress, map[string]interface{}{"locked_until": lockedUntil})
+	if err := s.emailWorker.Enqueue(s.emailService.AccountLockedMessage(user.Email, lockedUntil)); err != nil {
+		logger.WithContext(ctx).Warn("Failed to queue account locked email", "error", err, "user_id", user.ID)
+	}
+}
+
+// UnlockAccount lifts the lock of the account registered with emailAddr
+func (s *AuthService) UnlockAccount(ctx context.Context, emailAddr string) error {
+	if s.lockout == nil {
+		return nil
+	}
+	return s.lockout.Unlock(ctx, strings.TrimSpace(strings.ToLower(emailAddr)))
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/crypto"
+	"github.com/lightshare/backend/pkg/email"
+	"github.com/lightshare/backend/pkg/jwt"
+)
+
+// newTestLockoutService returns an AuthService with account lockout enabled, holding a
+// verified user with the password "correct-horse-battery"
+func newTestLockoutService(t *testing.T) (*AuthService, *miniredis.Miniredis, *MockAuditRepository, *models.User) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = cache.Close() })
+
+	hash, err := crypto.HashPassword("correct-horse-battery")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	user := &models.User{ID: uuid.New(), Email: "user@example.com", PasswordHash: hash, EmailVerified: true, Role: "user"}
+
+	auditRepo := &MockAuditRepository{}
+	service := &AuthService{
+		userRepo:         &mockUserRepository{users: []*models.User{user}},
+		refreshTokenRepo: NewMockRefreshTokenRepository(),
+		auditRepo:        auditRepo,
+		jwtService:       jwt.New(jwt.Config{Secret: "test-secret", AccessExpiration: time.Hour, RefreshExpiration: time.Hour}),
+		emailService:     email.New(&email.Config{FromEmail: "noreply@lightshare.com"}),
+		emailWorker:      email.NewWorker(nil, 10),
+		cache:            cache,
+	}
+	service.SetLockout(NewAccountLockoutService(cache))
+	return service, mr, auditRepo, user
+}
+
+func loginWith(service *AuthService, password string) error {
+	_, err := service.Login(context.Background(), LoginRequest{Email: "User@Example.com", Password: password}, nil, nil)
+	return err
+}
+
+func TestLogin_LocksAccountAfterRepeatedFailures(t *testing.T) {
+	service, mr, auditRepo, _ := newTestLockoutService(t)
+
+	for i := 0; i < lockoutThreshold; i++ {
+		if err := loginWith(service, "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Attempt %d: expected ErrInvalidCredentials, got %v", i+1, err)
+		}
+	}
+	if !mr.Exists(lockedUntilKey("user@example.com")) {
+		t.Fatal("Expected the account to be locked")
+	}
+
+	// The correct password is rejected while the account is locked
+	err := loginWith(service, "correct-horse-battery")
+	var lockedErr *AccountLockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("Expected AccountLockedError, got %v", err)
+	}
+	if remaining := time.Until(lockedErr.LockedUntil); remaining < lockoutDuration-time.Minute || remaining > lockoutDuration {
+		t.Errorf("Expected the account to be locked for %v, got %v", lockoutDuration, remaining)
+	}
+
+	locked := 0
+	for _, event := range auditRepo.events {
+		if event.EventType == models.EventAccountLocked {
+			locked++
+		}
+	}
+	if locked != 1 {
+		t.Errorf("Expected 1 account_locked audit event, got %d", locked)
+	}
+}
+
+func TestLogin_SuccessResetsFailures(t *testing.T) {
+	service, _, _, _ := newTestLockoutService(t)
+
+	for i := 0; i < lockoutThreshold-1; i++ {
+		_ = loginWith(service, "wrong-password")
+	}
+	if err := loginWith(service, "correct-horse-battery"); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if err := loginWith(service, "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Expected ErrInvalidCredentials, got %v", err)
+	}
+	if err := loginWith(service, "correct-horse-battery"); err != nil {
+		t.Errorf("Expected failures before a successful login not to count, got %v", err)
+	}
+}
+
+// lockAccount records enough failures to lock user@example.com without checking passwords
+func lockAccount(t *testing.T, lockout *AccountLockoutService) time.Time {
+	t.Helper()
+
+	var lockedUntil time.Time
+	for i := 0; i < lockoutThreshold; i++ {
+		var err error
+		if lockedUntil, err = lockout.RecordFailure(context.Background(), "user@example.com"); err != nil {
+			t.Fatalf("RecordFailure failed: %v", err)
+		}
+	}
+	if lockedUntil.IsZero() {
+		t.Fatal("Expected the last failure to lock the account")
+	}
+	return lockedUntil
+}
+
+func TestAccountLockout_FailuresOutsideWindowAreIgnored(t *testing.T) {
+	service, _, _, _ := newTestLockoutService(t)
+	ctx := context.Background()
+	now := time.Now()
+	service.lockout.now = func() time.Time { return now }
+
+	for i := 0; i < lockoutThreshold-1; i++ {
+		_, _ = service.lockout.RecordFailure(ctx, "user@example.com")
+	}
+	now = now.Add(lockoutWindow + time.Second)
+	lockedUntil, err := service.lockout.RecordFailure(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("RecordFailure failed: %v", err)
+	}
+	if !lockedUntil.IsZero() {
+		t.Error("Expected failures outside the window not to lock the account")
+	}
+}
+
+func TestAccountLockout_Expires(t *testing.T) {
+	service, mr, _, _ := newTestLockoutService(t)
+	ctx := context.Background()
+	lockedUntil := lockAccount(t, service.lockout)
+
+	got, locked, err := service.lockout.IsLocked(ctx, "user@example.com")
+	if err != nil || !locked || !got.Equal(lockedUntil) {
+		t.Fatalf("Expected the account to be locked until %v, got %v, %v, %v", lockedUntil, got, locked, err)
+	}
+
+	// Another failure while locked neither extends the lock nor reports it again
+	if again, _ := service.lockout.RecordFailure(ctx, "user@example.com"); !again.IsZero() {
+		t.Errorf("Expected no new lock, got %v", again)
+	}
+
+	mr.FastForward(lockoutDuration)
+	if _, locked, _ := service.lockout.IsLocked(ctx, "user@example.com"); locked {
+		t.Error("Expected the lock to expire")
+	}
+}
+
+func TestUnlockAccount(t *testing.T) {
+	service, _, _, _ := newTestLockoutService(t)
+	ctx := context.Background()
+	lockAccount(t, service.lockout)
+
+	if err := service.UnlockAccount(ctx, " USER@example.com "); err != nil {
+		t.Fatalf("UnlockAccount failed: %v", err)
+	}
+	if err := loginWith(service, "correct-horse-battery"); err != nil {
+		t.Errorf("Expected login to succeed once unlocked, got %v", err)
+	}
+}
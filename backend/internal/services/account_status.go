@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// accountStatusTTL is how long a token status check is cached before the provider is asked again
+const accountStatusTTL = 5 * time.Minute
+
+func accountStatusKey(accountID string) string {
+	return fmt.Sprintf("status:account:%s", accountID)
+}
+
+// CheckAccountStatus validates the stored provider token of an account. Rejected tokens are
+// reported with an "invalid" status rather than an error, and provider failures with an
+// "error" status. Valid and invalid results are cached so repeated checks don't hammer
+// the provider.
+func (s *DeviceService) CheckAccountStatus(ctx context.Context, userID, accountID string) (*models.AccountStatus, error) {
+	account, err := s.accountRepo.FindByIDString(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	if account.OwnerUserID.String() != userID {
+		return nil, ErrAccountNotOwned
+	}
+
+	if status, err := s.getCachedAccountStatus(ctx, accountID); err == nil {
+		status.CircuitStatus = s.breakers.status(accountID)
+		return status, nil
+	}
+
+	status := &models.AccountStatus{
+		Provider:      account.Provider,
+		LastCheckedAt: time.Now().UTC(),
+	}
+
+	token, err := s.accountRepo.GetDecryptedToken(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	client, err := s.newClient(providers.Provider(account.Provider))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider client: %w", err)
+	}
+
+	err = s.callProvider(ctx, account, "validate_token", "all", func(ctx context.Context) error {
+		_, err := providers.WithContext(ctx, client).ValidateToken(token)
+		return err
+	})
+	switch {
+	case err == nil:
+		status.Status = models.AccountStatusValid
+	case errors.Is(err, providers.ErrUnauthorized):
+		s.validations.invalidateOnUnauthorized(ctx, accountID, err)
+		status.Status = models.AccountStatusInvalid
+		status.ErrorDetail = "provider token rejected"
+	default:
+		status.Status = models.AccountStatusError
+		status.ErrorDetail = err.Error()
+	}
+
+	// Provider failures are transient, so only a definitive answer is cached
+	if status.Status != models.AccountStatusError {
+		if err := s.setCachedAccountStatus(ctx, accountID, status); err != nil {
+			// Log error but don't fail the request
+			_ = err
+		}
+	}
+
+	status.CircuitStatus = s.breakers.status(accountID)
+	return status, nil
+}
+
+// getCachedAccountStatus retrieves an account's last token status check from cache
+func (s *DeviceService) getCachedAccountStatus(ctx context.Context, accountID string) (*models.AccountStatus, error) {
+	data, err := s.cache.Get(ctx, accountStatusKey(accountID)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var status models.AccountStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// setCachedAccountStatus stores an account's token status check in cache
+func (s *DeviceService) setCachedAccountStatus(ctx context.Context, accountID string, status *models.AccountStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	return s.cache.Set(ctx, accountStatusKey(accountID), data, accountStatusTTL).Err()
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+func TestCheckAccountStatus_CachesValidToken(t *testing.T) {
+	client := newFakeProviderClient()
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	first, err := service.CheckAccountStatus(context.Background(), userID, accountID)
+	if err != nil {
+		t.Fatalf("CheckAccountStatus failed: %v", err)
+	}
+	if first.Status != models.AccountStatusValid || first.Provider != account.Provider {
+		t.Errorf("Expected valid %s status, got %+v", account.Provider, first)
+	}
+	if first.CircuitStatus == nil || first.State != "closed" {
+		t.Errorf("Expected closed circuit, got %+v", first.CircuitStatus)
+	}
+
+	second, err := service.CheckAccountStatus(context.Background(), userID, accountID)
+	if err != nil {
+		t.Fatalf("Second CheckAccountStatus failed: %v", err)
+	}
+	if second.Status != models.AccountStatusValid || !second.LastCheckedAt.Equal(first.LastCheckedAt) {
+		t.Errorf("Expected cached valid status, got %+v", second)
+	}
+
+	if calls := client.callCount("ValidateToken"); calls != 1 {
+		t.Errorf("Expected 1 provider validation within the TTL, got %d", calls)
+	}
+}
+
+func TestCheckAccountStatus_InvalidToken(t *testing.T) {
+	client := newFakeProviderClient()
+	client.errs["ValidateToken"] = []error{fmt.Errorf("%w: token revoked", providers.ErrUnauthorized)}
+	service, account := newTestDeviceService(t, client)
+
+	status, err := service.CheckAccountStatus(context.Background(), account.OwnerUserID.String(), account.ID.String())
+	if err != nil {
+		t.Fatalf("CheckAccountStatus failed: %v", err)
+	}
+	if status.Status != models.AccountStatusInvalid || status.ErrorDetail == "" {
+		t.Errorf("Expected invalid status with detail, got %+v", status)
+	}
+}
+
+func TestCheckAccountStatus_ProviderErrorNotCached(t *testing.T) {
+	client := newFakeProviderClient()
+	client.errs["ValidateToken"] = []error{errors.New("connection refused")}
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	status, err := service.CheckAccountStatus(context.Background(), userID, accountID)
+	if err != nil {
+		t.Fatalf("CheckAccountStatus failed: %v", err)
+	}
+	if status.Status != models.AccountStatusError {
+		t.Errorf("Expected error status, got %+v", status)
+	}
+
+	status, err = service.CheckAccountStatus(context.Background(), userID, accountID)
+	if err != nil {
+		t.Fatalf("Second CheckAccountStatus failed: %v", err)
+	}
+	if status.Status != models.AccountStatusValid {
+		t.Errorf("Expected provider to be asked again and report valid, got %+v", status)
+	}
+}
+
+func TestCheckAccountStatus_OtherUser(t *testing.T) {
+	service, account := newTestDeviceService(t, newFakeProviderClient())
+
+	_, err := service.CheckAccountStatus(context.Background(), uuid.NewString(), account.ID.String())
+	if !errors.Is(err, ErrAccountNotOwned) {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+}
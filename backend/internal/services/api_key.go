@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/crypto"
+	"github.com/lightshare/backend/pkg/jwt"
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+var (
+	// ErrInvalidAPIKey is returned when an API key is unknown, malformed or revoked
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrAPIKeyExpired is returned when an API key is past its expiry
+	ErrAPIKeyExpired = errors.New("api key expired")
+	// ErrInvalidAPIKeyRequest is returned when an API key creation request is invalid
+	ErrInvalidAPIKeyRequest = errors.New("invalid api key request")
+)
+
+const (
+	// apiKeySecretBytes is the amount of randomness in a generated key
+	apiKeySecretBytes = 32
+	// apiKeyDisplayPrefixLen is how many characters of the key are stored for display
+	apiKeyDisplayPrefixLen = 12
+	// lastUsedResolution avoids a database write on every request made with the same key
+	lastUsedResolution = time.Minute
+)
+
+// APIKeyService handles API key issuance and authentication
+type APIKeyService struct {
+	apiKeyRepo repository.APIKeyRepositoryInterface
+	now        func() time.Time
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepositoryInterface) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo: apiKeyRepo,
+		now:        time.Now,
+	}
+}
+
+// CreateAPIKeyRequest represents a request to create an API key. The expiry may be given
+// either as an absolute ExpiresAt or as a Go duration in ExpiresIn (e.g. "8760h").
+type CreateAPIKeyRequest struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	ExpiresIn string     `json:"expires_in,omitempty"`
+	Name      string     `json:"name"`
+	Label     string     `json:"label,omitempty"` // Alias of Name
+	Scopes    []string   `json:"scopes"`
+}
+
+// CreateAPIKeyResponse contains the created key; the plaintext key is only ever returned here
+type CreateAPIKeyResponse struct {
+	APIKey *models.APIKey `json:"api_key"`
+	Key    string         `json:"key"`
+}
+
+// Create issues a new API key for the user
+func (s *APIKeyService) Create(ctx context.Context, userID uuid.UUID, req CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	if req.Name == "" {
+		req.Name = req.Label
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidAPIKeyRequest)
+	}
+
+	if len(req.Scopes) == 0 {
+		req.Scopes = []string{models.APIKeyScopeDevicesRead, models.APIKeyScopeDevicesWrite}
+	}
+	for _, scope := range req.Scopes {
+		if !models.IsValidAPIKeyScope(scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKeyRequest, scope)
+		}
+	}
+
+	if req.ExpiresIn != "" {
+		if req.ExpiresAt != nil {
+			return nil, fmt.Errorf("%w: expires_at and expires_in are mutually exclusive", ErrInvalidAPIKeyRequest)
+		}
+		expiresIn, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || expiresIn <= 0 {
+			return nil, fmt.Errorf("%w: expires_in must be a positive duration", ErrInvalidAPIKeyRequest)
+		}
+		expiresAt := s.now().Add(expiresIn)
+		req.ExpiresAt = &expiresAt
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIKeyRequest)
+	}
+
+	secret, err := jwt.GenerateRandomToken(apiKeySecretBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key := models.APIKeyPrefix + strings.TrimRight(secret, "=")
+
+	apiKey, err := s.apiKeyRepo.Create(ctx, &models.CreateAPIKeyParams{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    key[:apiKeyDisplayPrefixLen],
+		KeyHash:   crypto.HashToken(key),
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreateAPIKeyResponse{APIKey: apiKey, Key: key}, nil
+}
+
+// List returns the user's active API keys
+func (s *APIKeyService) List(ctx context.Context, userID uuid.UUID) ([]*models.APIKey, error) {
+	return s.apiKeyRepo.FindByUserID(ctx, userID)
+}
+
+// Revoke revokes one of the user's API keys
+func (s *APIKeyService) Revoke(ctx context.Context, userID, keyID uuid.UUID) error {
+	return s.apiKeyRepo.Revoke(ctx, keyID, userID)
+}
+
+// AuthenticateAPIKey resolves a plaintext API key to its stored record
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	if !strings.HasPrefix(key, models.APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.apiKeyRepo.FindByHash(ctx, crypto.HashToken(key))
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if apiKey.RevokedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := s.now()
+	if apiKey.IsExpired(now) {
+		return nil, ErrAPIKeyExpired
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= lastUsedResolution {
+		if err := s.apiKeyRepo.TouchLastUsed(ctx, apiKey.ID, now); err != nil {
+			// Log error but don't fail the request
+			logger.WithContext(ctx).Warn("Failed to record API key use", "error", err, "api_key_id", apiKey.ID)
+		}
+	}
+
+	return apiKey, nil
+}
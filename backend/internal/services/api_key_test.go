@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+)
+
+// MockAPIKeyRepository is a simple in-memory implementation for testing
+type MockAPIKeyRepository struct {
+	keys map[uuid.UUID]*models.APIKey
+}
+
+func NewMockAPIKeyRepository() *MockAPIKeyRepository {
+	return &MockAPIKeyRepository{
+		keys: make(map[uuid.UUID]*models.APIKey),
+	}
+}
+
+func (m *MockAPIKeyRepository) Create(_ context.Context, params *models.CreateAPIKeyParams) (*models.APIKey, error) {
+	key := &models.APIKey{
+		ID:        uuid.New(),
+		UserID:    params.UserID,
+		Name:      params.Name,
+		Prefix:    params.Prefix,
+		KeyHash:   params.KeyHash,
+		Scopes:    params.Scopes,
+		ExpiresAt: params.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	m.keys[key.ID] = key
+	return key, nil
+}
+
+func (m *MockAPIKeyRepository) FindByUserID(_ context.Context, userID uuid.UUID) ([]*models.APIKey, error) {
+	result := make([]*models.APIKey, 0)
+	for _, key := range m.keys {
+		if key.UserID == userID && key.RevokedAt == nil {
+			result = append(result, key)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockAPIKeyRepository) FindByHash(_ context.Context, keyHash string) (*models.APIKey, error) {
+	for _, key := range m.keys {
+		if key.KeyHash == keyHash {
+			return key, nil
+		}
+	}
+	return nil, repository.ErrAPIKeyNotFound
+}
+
+func (m *MockAPIKeyRepository) Revoke(_ context.Context, id, userID uuid.UUID) error {
+	key, ok := m.keys[id]
+	if !ok || key.UserID != userID || key.RevokedAt != nil {
+		return repository.ErrAPIKeyNotFound
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	return nil
+}
+
+func (m *MockAPIKeyRepository) TouchLastUsed(_ context.Context, id uuid.UUID, usedAt time.Time) error {
+	if key, ok := m.keys[id]; ok {
+		key.LastUsedAt = &usedAt
+	}
+	return nil
+}
+
+func TestAPIKey_AuthenticateAndRevoke(t *testing.T) {
+	repo := NewMockAPIKeyRepository()
+	service := NewAPIKeyService(repo)
+	userID := uuid.New()
+	ctx := context.Background()
+
+	created, err := service.Create(ctx, userID, CreateAPIKeyRequest{Name: "Home Assistant"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !strings.HasPrefix(created.Key, models.APIKeyPrefix) {
+		t.Errorf("Expected key to start with %q, got %q", models.APIKeyPrefix, created.Key)
+	}
+	if created.APIKey.KeyHash == created.Key || !strings.HasPrefix(created.Key, created.APIKey.Prefix) {
+		t.Error("Expected only a hash and display prefix of the key to be stored")
+	}
+
+	apiKey, err := service.AuthenticateAPIKey(ctx, created.Key)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey failed: %v", err)
+	}
+	if apiKey.UserID != userID {
+		t.Errorf("Expected key to resolve to user %s, got %s", userID, apiKey.UserID)
+	}
+	if !apiKey.HasScope(models.APIKeyScopeDevicesWrite) {
+		t.Error("Expected default scopes to include devices:write")
+	}
+	if apiKey.LastUsedAt == nil {
+		t.Error("Expected last used time to be recorded")
+	}
+
+	if err := service.Revoke(ctx, userID, created.APIKey.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+
+	if _, err := service.AuthenticateAPIKey(ctx, created.Key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("Expected ErrInvalidAPIKey after revocation, got %v", err)
+	}
+}
+
+func TestAPIKey_Expired(t *testing.T) {
+	service := NewAPIKeyService(NewMockAPIKeyRepository())
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(time.Hour)
+	created, err := service.Create(ctx, uuid.New(), CreateAPIKeyRequest{Name: "Cron", ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	service.now = func() time.Time { return expiresAt.Add(time.Second) }
+	if _, err := service.AuthenticateAPIKey(ctx, created.Key); !errors.Is(err, ErrAPIKeyExpired) {
+		t.Fatalf("Expected ErrAPIKeyExpired, got %v", err)
+	}
+}
+
+func TestAPIKey_CreateRejectsUnknownScope(t *testing.T) {
+	service := NewAPIKeyService(NewMockAPIKeyRepository())
+
+	_, err := service.Create(context.Background(), uuid.New(), CreateAPIKeyRequest{Name: "Hub", Scopes: []string{"admin"}})
+	if !errors.Is(err, ErrInvalidAPIKeyRequest) {
+		t.Fatalf("Expected ErrInvalidAPIKeyRequest, got %v", err)
+	}
+}
+
+func TestAPIKey_CreateWithLabelAndExpiresIn(t *testing.T) {
+	service := NewAPIKeyService(NewMockAPIKeyRepository())
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	created, err := service.Create(context.Background(), uuid.New(), CreateAPIKeyRequest{Label: "CI pipeline", ExpiresIn: "8760h"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.APIKey.Name != "CI pipeline" {
+		t.Errorf("Expected name 'CI pipeline', got '%s'", created.APIKey.Name)
+	}
+	if created.APIKey.ExpiresAt == nil || !created.APIKey.ExpiresAt.Equal(now.Add(8760*time.Hour)) {
+		t.Errorf("Expected expiry in 8760h, got %v", created.APIKey.ExpiresAt)
+	}
+
+	for _, expiresIn := range []string{"soon", "-1h", "0s"} {
+		_, err := service.Create(context.Background(), uuid.New(), CreateAPIKeyRequest{Name: "Hub", ExpiresIn: expiresIn})
+		if !errors.Is(err, ErrInvalidAPIKeyRequest) {
+			t.Errorf("Expected ErrInvalidAPIKeyRequest for expires_in %q, got %v", expiresIn, err)
+		}
+	}
+}
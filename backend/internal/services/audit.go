@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+// recordAudit stores an authentication event. Failures are logged rather than returned
+// so that an audit outage never locks users out.
+func (s *AuthService) recordAudit(ctx context.Context, eventType models.AuditEventType, userID uuid.UUID, userAgent, ipAddress *string, metadata map[string]interface{}) {
+	event := models.AuditEvent{
+		UserID:    &userID,
+		EventType: eventType,
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+	}
+
+	if metadata != nil {
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			logger.WithContext(ctx).Error("Failed to encode audit metadata", "error", err, "event_type", eventType)
+		} else {
+			event.Metadata = encoded
+		}
+	}
+
+	if err := s.auditRepo.Create(ctx, event); err != nil {
+		logger.WithContext(ctx).Error("Failed to record audit event", "error", err, "event_type", eventType, "user_id", userID)
+	}
+}
+
+// AuditLog returns a page of the user's own audit events, newest first.
+// opts.Cursor is the ID of the last event of the previous page.
+func (s *AuthService) AuditLog(ctx context.Context, userID uuid.UUID, opts models.PaginationOptions) (*models.AuditLogPage, error) {
+	var before *uuid.UUID
+	if opts.Cursor != "" {
+		cursor, err := uuid.Parse(opts.Cursor)
+		if err != nil {
+			return nil, models.ErrInvalidCursor
+		}
+		before = &cursor
+	}
+
+	if opts.Limit <= 0 {
+		opts.Limit = models.DefaultAuditLogLimit
+	}
+	limit := opts.PageLimit()
+
+	// Fetch one extra event to learn whether another page follows
+	events, err := s.auditRepo.ListByUser(ctx, userID, before, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	page := &models.AuditLogPage{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		page.NextCursor = page.Events[limit-1].ID.String()
+	}
+
+	return page, nil
+}
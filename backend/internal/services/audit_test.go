@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+)
+
+// MockAuditRepository is an in-memory audit repository for testing
+type MockAuditRepository struct {
+	events []*models.AuditEvent
+	mu     sync.Mutex
+}
+
+func (m *MockAuditRepository) Create(_ context.Context, event models.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event.ID = uuid.New()
+	// Events are appended in order; distinct timestamps keep the ordering stable
+	event.CreatedAt = time.Unix(int64(len(m.events)), 0)
+	m.events = append(m.events, &event)
+	return nil
+}
+
+func (m *MockAuditRepository) ListByUser(_ context.Context, userID uuid.UUID, before *uuid.UUID, limit int) ([]*models.AuditEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := make([]*models.AuditEvent, 0)
+	skipping := before != nil
+	for i := len(m.events) - 1; i >= 0 && len(events) < limit; i-- {
+		event := m.events[i]
+		if event.UserID == nil || *event.UserID != userID {
+			continue
+		}
+		if skipping {
+			skipping = event.ID != *before
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func TestRecordAudit_StoresEventWithMetadata(t *testing.T) {
+	repo := &MockAuditRepository{}
+	service := &AuthService{auditRepo: repo}
+	userID := uuid.New()
+	userAgent, ip := "test-agent", "203.0.113.7"
+
+	service.recordAudit(context.Background(), models.EventLogin, userID, &userAgent, &ip, map[string]interface{}{"method": "password"})
+
+	if len(repo.events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(repo.events))
+	}
+	event := repo.events[0]
+	if event.EventType != models.EventLogin || *event.UserID != userID {
+		t.Errorf("Expected login event for %s, got %s for %v", userID, event.EventType, event.UserID)
+	}
+	if *event.IPAddress != ip || *event.UserAgent != userAgent {
+		t.Errorf("Expected ip %s and agent %s, got %s and %s", ip, userAgent, *event.IPAddress, *event.UserAgent)
+	}
+
+	var metadata map[string]string
+	if err := json.Unmarshal(event.Metadata, &metadata); err != nil {
+		t.Fatalf("Failed to decode metadata: %v", err)
+	}
+	if metadata["method"] != "password" {
+		t.Errorf("Expected method 'password', got '%s'", metadata["method"])
+	}
+}
+
+func TestAuditLog_PagesThroughOwnEvents(t *testing.T) {
+	repo := &MockAuditRepository{}
+	service := &AuthService{auditRepo: repo}
+	ctx := context.Background()
+	userID, otherID := uuid.New(), uuid.New()
+
+	for _, eventType := range []models.AuditEventType{models.EventSignup, models.EventEmailVerified, models.EventLogin} {
+		service.recordAudit(ctx, eventType, userID, nil, nil, nil)
+		service.recordAudit(ctx, models.EventLogin, otherID, nil, nil, nil)
+	}
+
+	first, err := service.AuditLog(ctx, userID, models.PaginationOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("AuditLog failed: %v", err)
+	}
+	if len(first.Events) != 2 || first.NextCursor == "" {
+		t.Fatalf("Expected 2 events and a next cursor, got %d events and cursor %q", len(first.Events), first.NextCursor)
+	}
+	if first.Events[0].EventType != models.EventLogin || first.Events[1].EventType != models.EventEmailVerified {
+		t.Errorf("Expected newest events first, got %s, %s", first.Events[0].EventType, first.Events[1].EventType)
+	}
+
+	second, err := service.AuditLog(ctx, userID, models.PaginationOptions{Cursor: first.NextCursor, Limit: 2})
+	if err != nil {
+		t.Fatalf("AuditLog failed: %v", err)
+	}
+	if len(second.Events) != 1 || second.Events[0].EventType != models.EventSignup {
+		t.Fatalf("Expected only the signup event on the last page, got %d events", len(second.Events))
+	}
+	if second.NextCursor != "" {
+		t.Errorf("Expected no next cursor on the last page, got %q", second.NextCursor)
+	}
+
+	for _, event := range append(first.Events, second.Events...) {
+		if *event.UserID != userID {
+			t.Errorf("Expected only the requesting user's events, got one for %s", event.UserID)
+		}
+	}
+}
+
+func TestAuditLog_InvalidCursor(t *testing.T) {
+	service := &AuthService{auditRepo: &MockAuditRepository{}}
+
+	_, err := service.AuditLog(context.Background(), uuid.New(), models.PaginationOptions{Cursor: "not-a-uuid"})
+	if !errors.Is(err, models.ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
@@ -9,12 +9,14 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/lightshare/backend/internal/models"
 	"github.com/lightshare/backend/internal/repository"
 	"github.com/lightshare/backend/pkg/crypto"
 	"github.com/lightshare/backend/pkg/email"
 	"github.com/lightshare/backend/pkg/jwt"
+	"github.com/lightshare/backend/pkg/logger"
 )
 
 var (
@@ -24,31 +26,106 @@ var (
 	ErrEmailNotVerified = errors.New("email not verified")
 	// ErrWeakPassword is returned when a password does not meet minimum requirements.
 	ErrWeakPassword = errors.New("password too weak")
+	// ErrTestEmailRateLimited is returned when a user requests too many test emails.
+	ErrTestEmailRateLimited = errors.New("too many test emails requested")
+	// ErrInvalidResetToken is returned when a password reset token is unknown or already used.
+	ErrInvalidResetToken = errors.New("invalid password reset token")
+	// ErrResetTokenExpired is returned when a password reset token has expired.
+	ErrResetTokenExpired = errors.New("password reset token expired")
+	// ErrTokenFamilyCompromised is returned when an already revoked refresh token is presented;
+	// every token of its family is revoked in response.
+	ErrTokenFamilyCompromised = errors.New("refresh token reuse detected")
+	// ErrEmailAlreadyRegistered is returned when signing up with an email address that already has an account.
+	ErrEmailAlreadyRegistered = errors.New("email already registered")
+	// ErrMagicLinkExpired is returned when a magic link token has expired.
+	ErrMagicLinkExpired = errors.New("magic link expired")
+	// ErrMagicLinkGlobalLimit is returned when more magic links are requested across all
+	// addresses than the global limit allows.
+	ErrMagicLinkGlobalLimit = errors.New("too many magic links requested")
+	// ErrInvalidRefreshToken is returned when a refresh token is unknown.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrAlreadyVerified is returned when resending the verification email of an address
+	// that is already verified.
+	ErrAlreadyVerified = errors.New("email already verified")
+)
+
+const (
+	// testEmailLimit is the maximum number of test emails a user can request per window
+	testEmailLimit = 3
+	// testEmailWindow is the rate limit window for test emails
+	testEmailWindow = time.Hour
+	// passwordResetTTL is how long a password reset link stays valid
+	passwordResetTTL = time.Hour
+	// magicLinkEmailLimit is the maximum number of magic links an address can request per window
+	magicLinkEmailLimit = 3
+	// magicLinkEmailWindow is the rate limit window for magic links to an address
+	magicLinkEmailWindow = 15 * time.Minute
+	// magicLinkGlobalLimit is the maximum number of magic links requested across all
+	// addresses per window, which stops bulk enumeration
+	magicLinkGlobalLimit = 100
+	// magicLinkGlobalWindow is the rate limit window for magic links across all addresses
+	magicLinkGlobalWindow = time.Minute
+	// magicLinkGlobalKey counts the magic links requested across all addresses
+	magicLinkGlobalKey = "magiclink:global:count"
+	// emailVerificationTTL is how long an email verification link stays valid
+	emailVerificationTTL = 24 * time.Hour
+	// verificationResendLimit is the maximum number of verification emails an address can
+	// request per window
+	verificationResendLimit = 3
+	// verificationResendWindow is the rate limit window for verification emails to an address
+	verificationResendWindow = time.Hour
 )
 
 // AuthService handles authentication operations
 type AuthService struct {
-	userRepo         *repository.UserRepository
-	refreshTokenRepo *repository.RefreshTokenRepository
+	userRepo         repository.UserRepositoryInterface
+	refreshTokenRepo repository.RefreshTokenRepositoryInterface
+	auditRepo        repository.AuditRepositoryInterface
+	oauthRepo        repository.OAuthProviderRepositoryInterface
 	jwtService       *jwt.Service
 	emailService     *email.Service
+	emailWorker      *email.Worker
+	domainValidator  *email.DomainValidator
+	googleVerifier   IDTokenVerifier        // nil disables Google sign-in
+	lockout          *AccountLockoutService // nil disables account lockout
+	cache            *redis.Client
+	secretKeys       *crypto.LocalKeyProvider // Encrypts two-factor secrets
 }
 
 // NewAuthService creates a new auth service
 func NewAuthService(
-	userRepo *repository.UserRepository,
-	refreshTokenRepo *repository.RefreshTokenRepository,
+	userRepo repository.UserRepositoryInterface,
+	refreshTokenRepo repository.RefreshTokenRepositoryInterface,
+	auditRepo repository.AuditRepositoryInterface,
+	oauthRepo repository.OAuthProviderRepositoryInterface,
 	jwtService *jwt.Service,
 	emailService *email.Service,
+	emailWorker *email.Worker,
+	domainValidator *email.DomainValidator,
+	googleVerifier IDTokenVerifier,
+	cache *redis.Client,
+	secretKeys *crypto.LocalKeyProvider,
 ) *AuthService {
 	return &AuthService{
 		userRepo:         userRepo,
 		refreshTokenRepo: refreshTokenRepo,
+		auditRepo:        auditRepo,
+		oauthRepo:        oauthRepo,
 		jwtService:       jwtService,
 		emailService:     emailService,
+		emailWorker:      emailWorker,
+		domainValidator:  domainValidator,
+		googleVerifier:   googleVerifier,
+		cache:            cache,
+		secretKeys:       secretKeys,
 	}
 }
 
+// SetLockout enables locking accounts after repeated failed logins
+func (s *AuthService) SetLockout(lockout *AccountLockoutService) {
+	s.lockout = lockout
+}
+
 // SignupRequest represents a signup request
 type SignupRequest struct {
 	Email    string
@@ -62,11 +139,16 @@ type SignupResponse struct {
 }
 
 // Signup creates a new user account
-func (s *AuthService) Signup(ctx context.Context, req SignupRequest) (*SignupResponse, error) {
+func (s *AuthService) Signup(ctx context.Context, req SignupRequest, userAgent, ipAddress *string) (*SignupResponse, error) {
 	// Validate email
 	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
 	if !email.ValidateEmail(req.Email) {
-		return nil, errors.New("invalid email address")
+		return nil, ErrInvalidEmail
+	}
+
+	// Reject domains without a mail exchanger (no-op unless MX lookups are enabled)
+	if !s.domainValidator.HasMailExchanger(ctx, req.Email) {
+		return nil, ErrInvalidEmail
 	}
 
 	// Validate password
@@ -91,11 +173,14 @@ func (s *AuthService) Signup(ctx context.Context, req SignupRequest) (*SignupRes
 		Email:                      req.Email,
 		PasswordHash:               passwordHash,
 		EmailVerificationToken:     verificationToken,
-		EmailVerificationExpiresAt: time.Now().Add(24 * time.Hour),
+		EmailVerificationExpiresAt: time.Now().Add(emailVerificationTTL),
 	})
 	if err != nil {
 		if errors.Is(err, repository.ErrUserAlreadyExists) {
-			return nil, errors.New("email already registered")
+			return nil, ErrEmailAlreadyRegistered
+		}
+		if errors.Is(err, repository.ErrUserPendingDeletion) {
+			return nil, ErrEmailPendingDeletion
 		}
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
@@ -107,6 +192,8 @@ func (s *AuthService) Signup(ctx context.Context, req SignupRequest) (*SignupRes
 		fmt.Printf("failed to send verification email: %v\n", err)
 	}
 
+	s.recordAudit(ctx, models.EventSignup, user.ID, userAgent, ipAddress, nil)
+
 	return &SignupResponse{
 		User:    user,
 		Message: "Account created successfully. Please check your email to verify your account.",
@@ -133,8 +220,13 @@ func (s *AuthService) Login(ctx context.Context, req LoginRequest, userAgent, ip
 	// Normalize email
 	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
 
-	// Get user by email
-	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	// Locked accounts are rejected before the password is checked
+	if err := s.checkLockout(ctx, req.Email); err != nil {
+		return nil, err
+	}
+
+	// Get user by email, including deleted users who may still restore their account
+	user, err := s.userRepo.GetByEmailIncludeDeleted(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
 			return nil, ErrInvalidCredentials
@@ -142,37 +234,64 @@ func (s *AuthService) Login(ctx context.Context, req LoginRequest, userAgent, ip
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
+	// Users who only sign in with a provider have no password to compare
+	if user.PasswordHash == "" {
+		return nil, ErrInvalidCredentials
+	}
+
 	// Compare password
 	err = crypto.ComparePassword(req.Password, user.PasswordHash)
 	if err != nil {
+		s.recordLoginFailure(ctx, user, userAgent, ipAddress)
 		return nil, ErrInvalidCredentials
 	}
+	if s.lockout != nil {
+		s.lockout.Reset(ctx, user.Email)
+	}
 
+	// Deleted accounts are only reported once the password proves the caller owns them
+	if user.DeletedAt != nil {
+		return nil, &AccountDeletedError{CanRestoreUntil: user.DeletedAt.Add(AccountDeletionGracePeriod)}
+	}
+
+	return s.completePasswordLogin(ctx, user, userAgent, ipAddress)
+}
+
+// completePasswordLogin signs in a user whose password was verified: it requires a verified
+// email, and a second factor when 2FA is enabled, before creating a session
+func (s *AuthService) completePasswordLogin(ctx context.Context, user *models.User, userAgent, ipAddress *string) (*LoginResponse, error) {
 	// Check if email is verified
 	if !user.EmailVerified {
 		return nil, ErrEmailNotVerified
 	}
 
-	// Generate token pair
-	tokenPair, err := s.jwtService.GenerateTokenPair(user.ID, user.Email, user.Role)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	// With 2FA enabled the password alone only earns a pending token
+	if err := s.requireSecondFactor(user); err != nil {
+		return nil, err
 	}
 
-	// Store refresh token in database
-	refreshTokenHash := crypto.HashToken(tokenPair.RefreshToken)
-	_, err = s.refreshTokenRepo.Create(ctx, user.ID, refreshTokenHash, tokenPair.ExpiresAt.Add(29*24*time.Hour), userAgent, ipAddress)
+	resp, err := s.createSession(ctx, user, userAgent, ipAddress)
 	if err != nil {
-		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+		return nil, err
 	}
 
-	return &LoginResponse{
-		User:         user,
-		AccessToken:  tokenPair.AccessToken,
-		RefreshToken: tokenPair.RefreshToken,
-		ExpiresAt:    tokenPair.ExpiresAt,
-		TokenType:    tokenPair.TokenType,
-	}, nil
+	s.recordAudit(ctx, models.EventLogin, user.ID, userAgent, ipAddress, map[string]interface{}{"method": "password"})
+
+	return resp, nil
+}
+
+// requireSecondFactor returns an MFARequiredError carrying a pending token when the user
+// has 2FA enabled, so a single factor never creates a session on its own
+func (s *AuthService) requireSecondFactor(user *models.User) error {
+	if !user.TOTPEnabled {
+		return nil
+	}
+
+	pendingToken, expiresAt, err := s.jwtService.GenerateMFAPendingToken(user.ID, user.Email, user.Role)
+	if err != nil {
+		return fmt.Errorf("failed to generate mfa pending token: %w", err)
+	}
+	return &MFARequiredError{PendingToken: pendingToken, ExpiresAt: expiresAt}
 }
 
 // VerifyEmail verifies a user's email with the verification token and returns JWT tokens
@@ -194,27 +313,64 @@ func (s *AuthService) VerifyEmail(ctx context.Context, token string, userAgent,
 
 	// Update user's email_verified status for the response
 	user.EmailVerified = true
+	s.recordAudit(ctx, models.EventEmailVerified, user.ID, userAgent, ipAddress, nil)
+
+	// The address is verified either way, but reading the inbox is not a second factor
+	if err := s.requireSecondFactor(user); err != nil {
+		return nil, err
+	}
 
-	// Generate token pair
-	tokenPair, err := s.jwtService.GenerateTokenPair(user.ID, user.Email, user.Role)
+	resp, err := s.createSession(ctx, user, userAgent, ipAddress)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+		return nil, err
 	}
 
-	// Store refresh token in database
-	refreshTokenHash := crypto.HashToken(tokenPair.RefreshToken)
-	_, err = s.refreshTokenRepo.Create(ctx, user.ID, refreshTokenHash, tokenPair.ExpiresAt.Add(29*24*time.Hour), userAgent, ipAddress)
+	return resp, nil
+}
+
+// ResendVerificationEmail sends a new verification link to an unverified address, replacing
+// the previous one. Unknown and rate limited addresses are ignored without an error, so
+// callers can't tell them apart; a verified address returns ErrAlreadyVerified.
+func (s *AuthService) ResendVerificationEmail(ctx context.Context, emailAddr string) error {
+	emailAddr = strings.TrimSpace(strings.ToLower(emailAddr))
+
+	// Rate limit before looking the user up, as for magic links
+	emailHash := crypto.HashToken(emailAddr)
+	count, err := s.incrementWindow(ctx, "verification:ratelimit:"+emailHash, verificationResendWindow)
 	if err != nil {
-		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+		return fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	if count > verificationResendLimit {
+		logger.WithContext(ctx).Warn("Verification email rate limit exceeded", "email_hash", emailHash, "count", count)
+		return nil
 	}
 
-	return &LoginResponse{
-		User:         user,
-		AccessToken:  tokenPair.AccessToken,
-		RefreshToken: tokenPair.RefreshToken,
-		ExpiresAt:    tokenPair.ExpiresAt,
-		TokenType:    tokenPair.TokenType,
-	}, nil
+	user, err := s.userRepo.GetByEmail(ctx, emailAddr)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.EmailVerified {
+		return ErrAlreadyVerified
+	}
+
+	verificationToken, err := jwt.GenerateRandomToken(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
+	}
+
+	if err := s.userRepo.SetNewVerificationToken(ctx, user.ID, verificationToken, time.Now().Add(emailVerificationTTL)); err != nil {
+		return fmt.Errorf("failed to set verification token: %w", err)
+	}
+
+	if err := s.emailService.SendVerificationEmail(user.Email, verificationToken); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	return nil
 }
 
 // RequestMagicLink sends a magic link to the user's email
@@ -222,6 +378,17 @@ func (s *AuthService) RequestMagicLink(ctx context.Context, emailAddr string) er
 	// Normalize email
 	emailAddr = strings.TrimSpace(strings.ToLower(emailAddr))
 
+	// Rate limit before looking the user up, so that limited requests look the same whether
+	// or not the address exists
+	allowed, err := s.allowMagicLink(ctx, emailAddr)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		// Don't reveal the limit to callers
+		return nil
+	}
+
 	// Check if user exists
 	user, err := s.userRepo.GetByEmail(ctx, emailAddr)
 	if err != nil {
@@ -252,13 +419,53 @@ func (s *AuthService) RequestMagicLink(ctx context.Context, emailAddr string) er
 	return nil
 }
 
+// allowMagicLink counts a magic link request against the global and per-address limits. It
+// returns ErrMagicLinkGlobalLimit when the global limit is exceeded, and false when only the
+// address's limit is.
+func (s *AuthService) allowMagicLink(ctx context.Context, emailAddr string) (bool, error) {
+	globalCount, err := s.incrementWindow(ctx, magicLinkGlobalKey, magicLinkGlobalWindow)
+	if err != nil {
+		return false, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	if globalCount > magicLinkGlobalLimit {
+		return false, ErrMagicLinkGlobalLimit
+	}
+
+	emailHash := crypto.HashToken(emailAddr)
+	count, err := s.incrementWindow(ctx, "magiclink:ratelimit:"+emailHash, magicLinkEmailWindow)
+	if err != nil {
+		return false, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	if count > magicLinkEmailLimit {
+		logger.WithContext(ctx).Warn("Magic link rate limit exceeded", "email_hash", emailHash, "count", count)
+		return false, nil
+	}
+	return true, nil
+}
+
+// incrementWindow increments the counter at key, starting a window of the given length
+// on the first increment, and returns the new count. Both run in one transaction, so the
+// counter cannot be left without an expiry.
+func (s *AuthService) incrementWindow(ctx context.Context, key string, window time.Duration) (int64, error) {
+	var count *redis.IntCmd
+	_, err := s.cache.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.Incr(ctx, key)
+		pipe.ExpireNX(ctx, key, window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
 // LoginWithMagicLink authenticates a user with a magic link token
 func (s *AuthService) LoginWithMagicLink(ctx context.Context, token string, userAgent, ipAddress *string) (*LoginResponse, error) {
 	// Get user by magic link token
 	user, err := s.userRepo.GetByMagicLinkToken(ctx, token)
 	if err != nil {
 		if errors.Is(err, repository.ErrTokenExpired) {
-			return nil, errors.New("magic link expired")
+			return nil, ErrMagicLinkExpired
 		}
 		return nil, fmt.Errorf("failed to get user by magic link: %w", err)
 	}
@@ -269,26 +476,98 @@ func (s *AuthService) LoginWithMagicLink(ctx context.Context, token string, user
 		return nil, fmt.Errorf("failed to clear magic link token: %w", err)
 	}
 
-	// Generate token pair
-	tokenPair, err := s.jwtService.GenerateTokenPair(user.ID, user.Email, user.Role)
+	// With 2FA enabled the link alone only earns a pending token
+	if err := s.requireSecondFactor(user); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.createSession(ctx, user, userAgent, ipAddress)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+		return nil, err
 	}
 
-	// Store refresh token in database
-	refreshTokenHash := crypto.HashToken(tokenPair.RefreshToken)
-	_, err = s.refreshTokenRepo.Create(ctx, user.ID, refreshTokenHash, tokenPair.ExpiresAt.Add(29*24*time.Hour), userAgent, ipAddress)
+	s.recordAudit(ctx, models.EventLogin, user.ID, userAgent, ipAddress, map[string]interface{}{"method": "magic_link"})
+
+	return resp, nil
+}
+
+// RequestPasswordReset emails a password reset link to the user
+func (s *AuthService) RequestPasswordReset(ctx context.Context, emailAddr string) error {
+	// Normalize email
+	emailAddr = strings.TrimSpace(strings.ToLower(emailAddr))
+
+	// Check if user exists
+	user, err := s.userRepo.GetByEmail(ctx, emailAddr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			// Don't reveal if email exists or not for security
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
 	}
 
-	return &LoginResponse{
-		User:         user,
-		AccessToken:  tokenPair.AccessToken,
-		RefreshToken: tokenPair.RefreshToken,
-		ExpiresAt:    tokenPair.ExpiresAt,
-		TokenType:    tokenPair.TokenType,
-	}, nil
+	// Generate reset token; only its hash is stored
+	resetToken, err := jwt.GenerateRandomToken(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate password reset token: %w", err)
+	}
+
+	expiresAt := time.Now().Add(passwordResetTTL)
+	if err := s.userRepo.SetPasswordResetToken(ctx, user.ID, crypto.HashToken(resetToken), expiresAt); err != nil {
+		return fmt.Errorf("failed to set password reset token: %w", err)
+	}
+
+	// Queue the email so the response doesn't wait on SMTP, nor take longer for known addresses
+	message, err := s.emailService.PasswordResetMessage(user.Email, resetToken)
+	if err != nil {
+		return fmt.Errorf("failed to render password reset email: %w", err)
+	}
+	if err := s.emailWorker.Enqueue(message); err != nil {
+		return fmt.Errorf("failed to queue password reset email: %w", err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password using a password reset token.
+// All of the user's sessions are revoked once the password has changed.
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	tokenHash := crypto.HashToken(token)
+
+	user, err := s.userRepo.GetByPasswordResetToken(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotFound) {
+			return ErrInvalidResetToken
+		}
+		return fmt.Errorf("failed to get user by password reset token: %w", err)
+	}
+
+	if user.PasswordResetExpiresAt == nil || time.Now().After(*user.PasswordResetExpiresAt) {
+		return ErrResetTokenExpired
+	}
+
+	// Validate password
+	if len(newPassword) < 8 {
+		return ErrWeakPassword
+	}
+
+	passwordHash, err := crypto.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, passwordHash, tokenHash); err != nil {
+		if errors.Is(err, repository.ErrTokenNotFound) {
+			return ErrInvalidResetToken
+		}
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if err := s.refreshTokenRepo.RevokeAllForUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return nil
 }
 
 // RefreshToken refreshes an access token using a refresh token
@@ -299,27 +578,39 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, use
 		return nil, err
 	}
 
-	// Check if refresh token exists and is not revoked
+	// Check if refresh token exists, including revoked tokens so that reuse can be detected
 	refreshTokenHash := crypto.HashToken(refreshToken)
-	storedToken, err := s.refreshTokenRepo.GetByTokenHash(ctx, refreshTokenHash)
+	storedToken, err := s.refreshTokenRepo.GetByTokenHashIncludeRevoked(ctx, refreshTokenHash)
 	if err != nil {
 		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
-			return nil, errors.New("invalid refresh token")
-		}
-		if errors.Is(err, repository.ErrRefreshTokenRevoked) {
-			return nil, errors.New("refresh token revoked")
+			return nil, ErrInvalidRefreshToken
 		}
 		return nil, fmt.Errorf("failed to get refresh token: %w", err)
 	}
 
+	// A revoked token is only ever presented again if it was stolen: either the attacker or
+	// the legitimate client already rotated it. Revoke the whole family so both must log in again.
+	if storedToken.RevokedAt != nil {
+		if err := s.refreshTokenRepo.RevokeByFamilyID(ctx, storedToken.FamilyID); err != nil {
+			return nil, fmt.Errorf("failed to revoke token family: %w", err)
+		}
+		logger.WithContext(ctx).Warn("Refresh token reuse detected", "user_id", storedToken.UserID, "family_id", storedToken.FamilyID)
+		s.recordAudit(ctx, models.EventTokenReuse, storedToken.UserID, userAgent, ipAddress, map[string]interface{}{"family_id": storedToken.FamilyID})
+		return nil, ErrTokenFamilyCompromised
+	}
+
+	if storedToken.ExpiresAt.Before(time.Now()) {
+		return nil, fmt.Errorf("failed to get refresh token: %w", repository.ErrTokenExpired)
+	}
+
 	// Get user
 	user, err := s.userRepo.GetByID(ctx, storedToken.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	// Generate new token pair
-	tokenPair, err := s.jwtService.GenerateTokenPair(user.ID, user.Email, user.Role)
+	// Generate new token pair for the same session
+	tokenPair, err := s.jwtService.GenerateTokenPair(user.ID, user.Email, user.Role, storedToken.SessionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
@@ -330,13 +621,15 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, use
 		return nil, fmt.Errorf("failed to revoke old refresh token: %w", err)
 	}
 
-	// Store new refresh token
+	// Store new refresh token in the family and session of the token it replaces
 	newRefreshTokenHash := crypto.HashToken(tokenPair.RefreshToken)
-	_, err = s.refreshTokenRepo.Create(ctx, user.ID, newRefreshTokenHash, tokenPair.ExpiresAt.Add(29*24*time.Hour), userAgent, ipAddress)
+	_, err = s.refreshTokenRepo.Create(ctx, user.ID, storedToken.FamilyID, storedToken.SessionID, newRefreshTokenHash, tokenPair.ExpiresAt.Add(29*24*time.Hour), userAgent, ipAddress)
 	if err != nil {
 		return nil, fmt.Errorf("failed to store new refresh token: %w", err)
 	}
 
+	s.recordAudit(ctx, models.EventTokenRefresh, user.ID, userAgent, ipAddress, nil)
+
 	return &LoginResponse{
 		User:         user,
 		AccessToken:  tokenPair.AccessToken,
@@ -347,12 +640,58 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, use
 }
 
 // Logout logs out a user by revoking their refresh token
-func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+func (s *AuthService) Logout(ctx context.Context, refreshToken string, userAgent, ipAddress *string) error {
 	refreshTokenHash := crypto.HashToken(refreshToken)
-	return s.refreshTokenRepo.Revoke(ctx, refreshTokenHash)
+
+	// Look the token up first to attribute the event; unknown or revoked tokens are not audited
+	storedToken, lookupErr := s.refreshTokenRepo.GetByTokenHash(ctx, refreshTokenHash)
+
+	if err := s.refreshTokenRepo.Revoke(ctx, refreshTokenHash); err != nil {
+		return err
+	}
+
+	if lookupErr == nil {
+		s.recordAudit(ctx, models.EventLogout, storedToken.UserID, userAgent, ipAddress, nil)
+	}
+
+	return nil
 }
 
 // LogoutAll logs out a user from all devices
-func (s *AuthService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
-	return s.refreshTokenRepo.RevokeAllForUser(ctx, userID)
+func (s *AuthService) LogoutAll(ctx context.Context, userID uuid.UUID, userAgent, ipAddress *string) error {
+	if err := s.refreshTokenRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, models.EventLogoutAll, userID, userAgent, ipAddress, nil)
+
+	return nil
+}
+
+// SendTestEmail queues a benign test email to the user's verified address
+func (s *AuthService) SendTestEmail(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !user.EmailVerified {
+		return ErrEmailNotVerified
+	}
+
+	// Rate limit per user
+	key := fmt.Sprintf("ratelimit:test-email:user:%s", userID)
+	count, err := s.incrementWindow(ctx, key, testEmailWindow)
+	if err != nil {
+		return fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	if count > testEmailLimit {
+		return ErrTestEmailRateLimited
+	}
+
+	if err := s.emailWorker.Enqueue(s.emailService.TestEmailMessage(user.Email)); err != nil {
+		return fmt.Errorf("failed to queue test email: %w", err)
+	}
+
+	return nil
 }
@@ -0,0 +1,699 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/crypto"
+	"github.com/lightshare/backend/pkg/email"
+	"github.com/lightshare/backend/pkg/jwt"
+)
+
+// MockRefreshTokenRepository is an in-memory refresh token repository for testing
+type MockRefreshTokenRepository struct {
+	tokens map[string]*models.RefreshToken
+	mu     sync.Mutex
+}
+
+func NewMockRefreshTokenRepository() *MockRefreshTokenRepository {
+	return &MockRefreshTokenRepository{tokens: make(map[string]*models.RefreshToken)}
+}
+
+func (m *MockRefreshTokenRepository) Create(_ context.Context, userID, familyID, sessionID uuid.UUID, tokenHash string, expiresAt time.Time, userAgent, ipAddress *string) (*models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token := &models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		SessionID: sessionID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+	}
+	m.tokens[tokenHash] = token
+	return token, nil
+}
+
+func (m *MockRefreshTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	token, err := m.GetByTokenHashIncludeRevoked(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if token.RevokedAt != nil {
+		return nil, repository.ErrRefreshTokenRevoked
+	}
+	return token, nil
+}
+
+func (m *MockRefreshTokenRepository) GetByTokenHashIncludeRevoked(_ context.Context, tokenHash string) (*models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.tokens[tokenHash]
+	if !ok {
+		return nil, repository.ErrRefreshTokenNotFound
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (m *MockRefreshTokenRepository) FindActiveByUserID(_ context.Context, userID uuid.UUID) ([]*models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokens := make([]*models.RefreshToken, 0)
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.RevokedAt == nil && token.ExpiresAt.After(time.Now()) {
+			copied := *token
+			tokens = append(tokens, &copied)
+		}
+	}
+	return tokens, nil
+}
+
+func (m *MockRefreshTokenRepository) Revoke(_ context.Context, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.tokens[tokenHash]
+	if !ok {
+		return repository.ErrRefreshTokenNotFound
+	}
+	now := time.Now()
+	token.RevokedAt = &now
+	return nil
+}
+
+func (m *MockRefreshTokenRepository) RevokeAllForUser(_ context.Context, userID uuid.UUID) error {
+	return m.revokeWhere(func(token *models.RefreshToken) bool { return token.UserID == userID })
+}
+
+func (m *MockRefreshTokenRepository) RevokeByID(_ context.Context, sessionID, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	revoked := false
+	for _, token := range m.tokens {
+		if token.SessionID == sessionID && token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			revoked = true
+		}
+	}
+	if !revoked {
+		return repository.ErrRefreshTokenNotFound
+	}
+	return nil
+}
+
+func (m *MockRefreshTokenRepository) RevokeByFamilyID(_ context.Context, familyID uuid.UUID) error {
+	return m.revokeWhere(func(token *models.RefreshToken) bool { return token.FamilyID == familyID })
+}
+
+func (m *MockRefreshTokenRepository) DeleteExpired(context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *MockRefreshTokenRepository) revokeWhere(match func(*models.RefreshToken) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.RevokedAt == nil && match(token) {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// activeTokens returns the number of unrevoked tokens
+func (m *MockRefreshTokenRepository) activeTokens() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	active := 0
+	for _, token := range m.tokens {
+		if token.RevokedAt == nil {
+			active++
+		}
+	}
+	return active
+}
+
+// mockUserRepository serves users from memory; other user repository methods are not used by these tests
+type mockUserRepository struct {
+	repository.UserRepositoryInterface
+	users []*models.User
+}
+
+func (m *mockUserRepository) GetByID(_ context.Context, id uuid.UUID) (*models.User, error) {
+	for _, user := range m.users {
+		if user.ID == id && user.DeletedAt == nil {
+			return user, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (m *mockUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := m.GetByEmailIncludeDeleted(ctx, email)
+	if err != nil || user.DeletedAt != nil {
+		return nil, repository.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (m *mockUserRepository) GetByEmailIncludeDeleted(_ context.Context, email string) (*models.User, error) {
+	for _, user := range m.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (m *mockUserRepository) SoftDelete(ctx context.Context, userID uuid.UUID, deletedAt time.Time) error {
+	user, err := m.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	user.DeletedAt = &deletedAt
+	return nil
+}
+
+func (m *mockUserRepository) Restore(_ context.Context, userID uuid.UUID, deletedAfter time.Time) error {
+	for _, user := range m.users {
+		if user.ID == userID && user.DeletedAt != nil && user.DeletedAt.After(deletedAfter) {
+			user.DeletedAt = nil
+			return nil
+		}
+	}
+	return repository.ErrUserNotFound
+}
+
+func (m *mockUserRepository) UpdateProfile(ctx context.Context, userID uuid.UUID, params models.UpdateProfileParams) (*models.User, error) {
+	user, err := m.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if params.DisplayName != nil {
+		user.DisplayName = params.DisplayName
+		if *params.DisplayName == "" {
+			user.DisplayName = nil
+		}
+	}
+	if params.NotificationPreferences != nil {
+		user.NotificationPreferences = *params.NotificationPreferences
+	}
+	return user, nil
+}
+
+func (m *mockUserRepository) SetPendingEmail(ctx context.Context, userID uuid.UUID, pendingEmail, tokenHash string, expiresAt time.Time) error {
+	user, err := m.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	user.PendingEmail, user.PendingEmailToken, user.PendingEmailExpiresAt = &pendingEmail, &tokenHash, &expiresAt
+	return nil
+}
+
+func (m *mockUserRepository) GetByPendingEmailToken(_ context.Context, tokenHash string) (*models.User, error) {
+	for _, user := range m.users {
+		if user.PendingEmailToken != nil && *user.PendingEmailToken == tokenHash {
+			return user, nil
+		}
+	}
+	return nil, repository.ErrTokenNotFound
+}
+
+func (m *mockUserRepository) ConfirmEmailChange(ctx context.Context, userID uuid.UUID, tokenHash string) error {
+	user, err := m.GetByPendingEmailToken(ctx, tokenHash)
+	if err != nil || user.ID != userID {
+		return repository.ErrTokenNotFound
+	}
+	if _, err := m.GetByEmail(ctx, *user.PendingEmail); err == nil {
+		return repository.ErrUserAlreadyExists
+	}
+	user.Email, user.EmailVerified = *user.PendingEmail, true
+	user.PendingEmail, user.PendingEmailToken, user.PendingEmailExpiresAt = nil, nil, nil
+	return nil
+}
+
+func (m *mockUserRepository) Create(ctx context.Context, params models.CreateUserParams) (*models.User, error) {
+	if existing, err := m.GetByEmailIncludeDeleted(ctx, params.Email); err == nil {
+		if existing.DeletedAt != nil {
+			return nil, repository.ErrUserPendingDeletion
+		}
+		return nil, repository.ErrUserAlreadyExists
+	}
+
+	user := &models.User{
+		ID:            uuid.New(),
+		Email:         params.Email,
+		PasswordHash:  params.PasswordHash,
+		EmailVerified: params.EmailVerified,
+		Role:          "user",
+	}
+	m.users = append(m.users, user)
+	return user, nil
+}
+
+func (m *mockUserRepository) GetByEmailVerificationToken(_ context.Context, token string) (*models.User, error) {
+	for _, user := range m.users {
+		if user.EmailVerificationToken != nil && *user.EmailVerificationToken == token {
+			if user.EmailVerificationExpiresAt != nil && time.Now().After(*user.EmailVerificationExpiresAt) {
+				return nil, repository.ErrTokenExpired
+			}
+			return user, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (m *mockUserRepository) VerifyEmail(ctx context.Context, token string) error {
+	user, err := m.GetByEmailVerificationToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	user.EmailVerified = true
+	user.EmailVerificationToken = nil
+	user.EmailVerificationExpiresAt = nil
+	return nil
+}
+
+func (m *mockUserRepository) GetByMagicLinkToken(_ context.Context, token string) (*models.User, error) {
+	for _, user := range m.users {
+		if user.MagicLinkToken != nil && *user.MagicLinkToken == token && user.DeletedAt == nil {
+			if user.MagicLinkExpiresAt != nil && time.Now().After(*user.MagicLinkExpiresAt) {
+				return nil, repository.ErrTokenExpired
+			}
+			return user, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (m *mockUserRepository) ClearMagicLinkToken(ctx context.Context, userID uuid.UUID) error {
+	user, err := m.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	user.MagicLinkToken = nil
+	user.MagicLinkExpiresAt = nil
+	return nil
+}
+
+func (m *mockUserRepository) SetPasswordResetToken(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	user, err := m.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	user.PasswordResetToken, user.PasswordResetExpiresAt = &tokenHash, &expiresAt
+	return nil
+}
+
+func (m *mockUserRepository) GetByPasswordResetToken(_ context.Context, tokenHash string) (*models.User, error) {
+	for _, user := range m.users {
+		if user.PasswordResetToken != nil && *user.PasswordResetToken == tokenHash && user.DeletedAt == nil {
+			return user, nil
+		}
+	}
+	return nil, repository.ErrTokenNotFound
+}
+
+func (m *mockUserRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash, resetTokenHash string) error {
+	user, err := m.GetByPasswordResetToken(ctx, resetTokenHash)
+	if err != nil || user.ID != userID {
+		return repository.ErrTokenNotFound
+	}
+	user.PasswordHash = passwordHash
+	user.PasswordResetToken, user.PasswordResetExpiresAt = nil, nil
+	return nil
+}
+
+// testAuthService is an AuthService built by newTestAuthService, with the fakes behind it
+// and the user it holds
+type testAuthService struct {
+	*AuthService
+	users        *mockUserRepository
+	tokens       *MockRefreshTokenRepository
+	audit        *MockAuditRepository
+	redis        *miniredis.Miniredis // Set by withCache
+	user         *models.User
+	refreshToken string // Set by withSession
+}
+
+type testAuthConfig struct {
+	password string
+	cache    bool
+	session  bool
+	email    bool
+	sender   email.Sender
+}
+
+// testAuthOption adds a dependency or fixture to the service newTestAuthService builds
+type testAuthOption func(*testAuthConfig)
+
+// withPassword gives the user the password
+func withPassword(password string) testAuthOption {
+	return func(c *testAuthConfig) { c.password = password }
+}
+
+// withCache backs the service with a miniredis server
+func withCache() testAuthOption {
+	return func(c *testAuthConfig) { c.cache = true }
+}
+
+// withSession starts a session for the user
+func withSession() testAuthOption {
+	return func(c *testAuthConfig) { c.session = true }
+}
+
+// withEmail queues the service's emails on a worker delivering them to sender. The worker
+// is not started when sender is nil, so queued emails are never delivered.
+func withEmail(sender email.Sender) testAuthOption {
+	return func(c *testAuthConfig) { c.email, c.sender = true, sender }
+}
+
+// newTestAuthService returns an AuthService holding one verified user, with in-memory
+// user, refresh token and audit repositories and a JWT service, plus the dependencies
+// opts add
+func newTestAuthService(t *testing.T, opts ...testAuthOption) *testAuthService {
+	t.Helper()
+
+	var config testAuthConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	user := &models.User{ID: uuid.New(), Email: "user@example.com", EmailVerified: true, Role: "user"}
+	if config.password != "" {
+		hash, err := crypto.HashPassword(config.password)
+		if err != nil {
+			t.Fatalf("Failed to hash password: %v", err)
+		}
+		user.PasswordHash = hash
+	}
+
+	env := &testAuthService{
+		users:  &mockUserRepository{users: []*models.User{user}},
+		tokens: NewMockRefreshTokenRepository(),
+		audit:  &MockAuditRepository{},
+		user:   user,
+	}
+	env.AuthService = &AuthService{
+		userRepo:         env.users,
+		refreshTokenRepo: env.tokens,
+		auditRepo:        env.audit,
+		jwtService:       jwt.New(jwt.Config{Secret: "test-secret", AccessExpiration: time.Hour, RefreshExpiration: time.Hour}),
+	}
+
+	if config.cache {
+		env.redis = miniredis.RunT(t)
+		env.cache = redis.NewClient(&redis.Options{Addr: env.redis.Addr()})
+		t.Cleanup(func() { _ = env.cache.Close() })
+	}
+
+	if config.email {
+		env.emailService = email.New(&email.Config{FromEmail: "noreply@lightshare.com", BaseURL: "https://lightshare.com", MobileDeepLinkScheme: "lightshare"})
+		env.emailWorker = email.NewWorker(config.sender, 10)
+		if config.sender != nil {
+			ctx, cancel := context.WithCancel(context.Background())
+			env.emailWorker.Start(ctx)
+			t.Cleanup(cancel)
+		}
+	}
+
+	if config.session {
+		session, err := env.createSession(context.Background(), user, nil, nil)
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		env.refreshToken = session.RefreshToken
+	}
+	return env
+}
+
+func TestRefreshToken_RotatesWithinFamily(t *testing.T) {
+	service := newTestAuthService(t, withSession())
+	ctx := context.Background()
+
+	first, err := service.RefreshToken(ctx, service.refreshToken, nil, nil)
+	if err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+	second, err := service.RefreshToken(ctx, first.RefreshToken, nil, nil)
+	if err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+
+	original, _ := service.tokens.GetByTokenHashIncludeRevoked(ctx, crypto.HashToken(service.refreshToken))
+	latest, _ := service.tokens.GetByTokenHashIncludeRevoked(ctx, crypto.HashToken(second.RefreshToken))
+	if original.RevokedAt == nil {
+		t.Error("Expected the rotated-away token to be revoked")
+	}
+	if latest.RevokedAt != nil {
+		t.Error("Expected the latest token to be active")
+	}
+	if latest.FamilyID != original.FamilyID {
+		t.Errorf("Expected rotated token to inherit family %s, got %s", original.FamilyID, latest.FamilyID)
+	}
+	if active := service.tokens.activeTokens(); active != 1 {
+		t.Errorf("Expected 1 active token, got %d", active)
+	}
+}
+
+func TestRefreshToken_ReuseOfRotatedTokenRevokesFamily(t *testing.T) {
+	service := newTestAuthService(t, withSession())
+	ctx := context.Background()
+
+	// A second, unrelated session must survive the family revocation
+	if _, err := service.createSession(ctx, service.userRepo.(*mockUserRepository).users[0], nil, nil); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	rotated, err := service.RefreshToken(ctx, service.refreshToken, nil, nil)
+	if err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+
+	_, err = service.RefreshToken(ctx, service.refreshToken, nil, nil)
+	if !errors.Is(err, ErrTokenFamilyCompromised) {
+		t.Fatalf("Expected ErrTokenFamilyCompromised, got %v", err)
+	}
+
+	if _, err := service.RefreshToken(ctx, rotated.RefreshToken, nil, nil); !errors.Is(err, ErrTokenFamilyCompromised) {
+		t.Errorf("Expected the rotated token to be revoked with its family, got %v", err)
+	}
+	if active := service.tokens.activeTokens(); active != 1 {
+		t.Errorf("Expected only the unrelated session to stay active, got %d active tokens", active)
+	}
+}
+
+func TestRefreshToken_ReuseAfterLogout(t *testing.T) {
+	service := newTestAuthService(t, withSession())
+	ctx := context.Background()
+
+	if err := service.Logout(ctx, service.refreshToken, nil, nil); err != nil {
+		t.Fatalf("Logout failed: %v", err)
+	}
+
+	_, err := service.RefreshToken(ctx, service.refreshToken, nil, nil)
+	if !errors.Is(err, ErrTokenFamilyCompromised) {
+		t.Errorf("Expected ErrTokenFamilyCompromised, got %v", err)
+	}
+	if active := service.tokens.activeTokens(); active != 0 {
+		t.Errorf("Expected no active tokens, got %d", active)
+	}
+}
+
+// lookupCountingUserRepository counts the email lookups made against a user repository.
+// Tests swap it in empty, so that no magic link is ever sent.
+type lookupCountingUserRepository struct {
+	mockUserRepository
+	lookups int
+}
+
+func (m *lookupCountingUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	m.lookups++
+	return m.mockUserRepository.GetByEmail(ctx, email)
+}
+
+func TestRequestMagicLink_RateLimitsEachAddress(t *testing.T) {
+	service := newTestAuthService(t, withCache())
+	repo := &lookupCountingUserRepository{}
+	service.userRepo = repo
+	mr := service.redis
+	ctx := context.Background()
+
+	for i := 0; i < magicLinkEmailLimit+2; i++ {
+		if err := service.RequestMagicLink(ctx, " Someone@Example.com"); err != nil {
+			t.Fatalf("Request %d: expected the limit to stay hidden, got %v", i+1, err)
+		}
+	}
+	if repo.lookups != magicLinkEmailLimit {
+		t.Errorf("Expected only %d requests to get past the limit, got %d", magicLinkEmailLimit, repo.lookups)
+	}
+
+	key := "magiclink:ratelimit:" + crypto.HashToken("someone@example.com")
+	if ttl := mr.TTL(key); ttl != magicLinkEmailWindow {
+		t.Errorf("Expected the window to last %v, got %v", magicLinkEmailWindow, ttl)
+	}
+
+	// Other addresses have their own limit
+	if err := service.RequestMagicLink(ctx, "other@example.com"); err != nil || repo.lookups != magicLinkEmailLimit+1 {
+		t.Errorf("Expected another address to be allowed, got %v after %d lookups", err, repo.lookups)
+	}
+
+	// The limit lifts once the window has passed
+	mr.FastForward(magicLinkEmailWindow)
+	if err := service.RequestMagicLink(ctx, "someone@example.com"); err != nil || repo.lookups != magicLinkEmailLimit+2 {
+		t.Errorf("Expected the address to be allowed again, got %v after %d lookups", err, repo.lookups)
+	}
+}
+
+func TestIncrementWindow_ExpiresWithTheFirstIncrement(t *testing.T) {
+	service := newTestAuthService(t, withCache())
+	mr := service.redis
+	ctx := context.Background()
+
+	if count, err := service.incrementWindow(ctx, "window", time.Minute); err != nil || count != 1 {
+		t.Fatalf("Expected a count of 1, got %d, %v", count, err)
+	}
+
+	// Later increments do not extend the window
+	mr.FastForward(20 * time.Second)
+	if count, err := service.incrementWindow(ctx, "window", time.Minute); err != nil || count != 2 {
+		t.Fatalf("Expected a count of 2, got %d, %v", count, err)
+	}
+	if ttl := mr.TTL("window"); ttl != 40*time.Second {
+		t.Errorf("Expected the window to end 40s from now, got %v", ttl)
+	}
+
+	// A counter left without an expiry gets one
+	if err := mr.Set("stale", "5"); err != nil {
+		t.Fatalf("Failed to set counter: %v", err)
+	}
+	if _, err := service.incrementWindow(ctx, "stale", time.Minute); err != nil {
+		t.Fatalf("incrementWindow failed: %v", err)
+	}
+	if ttl := mr.TTL("stale"); ttl != time.Minute {
+		t.Errorf("Expected the counter to expire in a minute, got %v", ttl)
+	}
+}
+
+func TestRequestMagicLink_GlobalLimit(t *testing.T) {
+	service := newTestAuthService(t, withCache())
+	repo := &lookupCountingUserRepository{}
+	service.userRepo = repo
+	mr := service.redis
+	ctx := context.Background()
+
+	if err := mr.Set(magicLinkGlobalKey, "99"); err != nil {
+		t.Fatalf("Failed to set global count: %v", err)
+	}
+	if err := service.RequestMagicLink(ctx, "someone@example.com"); err != nil {
+		t.Fatalf("Expected the 100th request to be allowed, got %v", err)
+	}
+	if err := service.RequestMagicLink(ctx, "other@example.com"); !errors.Is(err, ErrMagicLinkGlobalLimit) {
+		t.Errorf("Expected ErrMagicLinkGlobalLimit, got %v", err)
+	}
+	if repo.lookups != 1 {
+		t.Errorf("Expected 1 lookup, got %d", repo.lookups)
+	}
+}
+
+func TestResendVerificationEmail_RateLimitsEachAddress(t *testing.T) {
+	service := newTestAuthService(t, withCache())
+	repo := &lookupCountingUserRepository{}
+	service.userRepo = repo
+	mr := service.redis
+	ctx := context.Background()
+
+	for i := 0; i < verificationResendLimit+2; i++ {
+		if err := service.ResendVerificationEmail(ctx, " Someone@Example.com"); err != nil {
+			t.Fatalf("Request %d: expected the limit to stay hidden, got %v", i+1, err)
+		}
+	}
+	if repo.lookups != verificationResendLimit {
+		t.Errorf("Expected only %d requests to get past the limit, got %d", verificationResendLimit, repo.lookups)
+	}
+
+	key := "verification:ratelimit:" + crypto.HashToken("someone@example.com")
+	if ttl := mr.TTL(key); ttl != verificationResendWindow {
+		t.Errorf("Expected the window to last %v, got %v", verificationResendWindow, ttl)
+	}
+}
+
+func TestResendVerificationEmail_AlreadyVerified(t *testing.T) {
+	service := newTestAuthService(t, withCache())
+	repo := &lookupCountingUserRepository{}
+	service.userRepo = repo
+	repo.users = []*models.User{{ID: uuid.New(), Email: "verified@example.com", EmailVerified: true}}
+
+	err := service.ResendVerificationEmail(context.Background(), "Verified@example.com")
+	if !errors.Is(err, ErrAlreadyVerified) {
+		t.Errorf("Expected ErrAlreadyVerified, got %v", err)
+	}
+}
+
+// newTestSecondFactorUser returns a verified user with 2FA enabled, holding a pending
+// magic link and email verification token
+func newTestSecondFactorUser() *models.User {
+	magicLink, verification := "magic-token", "verification-token"
+	expiresAt := time.Now().Add(time.Hour)
+	return &models.User{
+		ID:                         uuid.New(),
+		Email:                      "user@example.com",
+		Role:                       "user",
+		EmailVerified:              true,
+		TOTPEnabled:                true,
+		MagicLinkToken:             &magicLink,
+		MagicLinkExpiresAt:         &expiresAt,
+		EmailVerificationToken:     &verification,
+		EmailVerificationExpiresAt: &expiresAt,
+	}
+}
+
+func TestLoginWithMagicLink_RequiresSecondFactor(t *testing.T) {
+	user := newTestSecondFactorUser()
+	service, _, _ := newTestOAuthService(user)
+
+	_, err := service.LoginWithMagicLink(context.Background(), "magic-token", nil, nil)
+	var mfaErr *MFARequiredError
+	if !errors.As(err, &mfaErr) || mfaErr.PendingToken == "" {
+		t.Fatalf("Expected MFARequiredError with a pending token, got %v", err)
+	}
+	if user.MagicLinkToken != nil {
+		t.Error("Expected the magic link to be used up")
+	}
+}
+
+func TestVerifyEmail_RequiresSecondFactor(t *testing.T) {
+	user := newTestSecondFactorUser()
+	user.EmailVerified = false
+	service, _, _ := newTestOAuthService(user)
+
+	_, err := service.VerifyEmail(context.Background(), "verification-token", nil, nil)
+	var mfaErr *MFARequiredError
+	if !errors.As(err, &mfaErr) || mfaErr.PendingToken == "" {
+		t.Fatalf("Expected MFARequiredError with a pending token, got %v", err)
+	}
+	if !user.EmailVerified {
+		t.Error("Expected the email to be verified")
+	}
+}
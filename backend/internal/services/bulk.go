@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// BulkExecuteAction applies several state actions, each to its own selector, with a single
+// provider SetStates call. Providers with a batch endpoint (LIFX) apply them in one
+// round-trip; others fall back to one call per property. One write is counted against the
+// rate limits for the whole batch. Failures of individual actions are reported in the
+// result; an error is returned only when the batch could not be sent at all.
+func (s *DeviceService) BulkExecuteAction(ctx context.Context, userID, accountID string, items []models.BulkActionItem) (*models.BulkActionResult, error) {
+	request := models.BulkActionRequest{Actions: items}
+	if err := request.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid bulk action: %w", err)
+	}
+
+	// Get account and verify ownership
+	account, err := s.accountRepo.FindByIDString(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	if account.OwnerUserID.String() != userID {
+		return nil, ErrAccountNotOwned
+	}
+	if account.IsReadOnly() {
+		return nil, ErrAccountReadOnly
+	}
+
+	// Check rate limit
+	if rateLimitErr := s.checkRateLimit(ctx, userID, account, rateLimitWrite); rateLimitErr != nil {
+		return nil, rateLimitErr
+	}
+
+	// Get decrypted token
+	token, err := s.accountRepo.GetDecryptedToken(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	// Create provider client
+	client, err := s.newClient(providers.Provider(account.Provider))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider client: %w", err)
+	}
+
+	states := make([]providers.DeviceState, len(items))
+	for i, item := range items {
+		states[i] = bulkDeviceState(item)
+	}
+
+	err = s.callProvider(ctx, account, "set_states", "bulk", func(ctx context.Context) error {
+		return providers.WithContext(ctx, client).SetStates(token, states)
+	})
+
+	var statesErr *providers.StatesError
+	if err != nil && !errors.As(err, &statesErr) {
+		s.validations.invalidateOnUnauthorized(ctx, accountID, err)
+		return nil, err
+	}
+
+	result := &models.BulkActionResult{
+		Succeeded: make([]models.BulkActionOutcome, 0, len(items)),
+		Failed:    make([]models.BulkActionOutcome, 0),
+	}
+	for i, item := range items {
+		outcome := models.BulkActionOutcome{Index: i, Selector: item.Selector, Action: item.Action}
+		if statesErr != nil && i < len(statesErr.Errors) && statesErr.Errors[i] != nil {
+			s.validations.invalidateOnUnauthorized(ctx, accountID, statesErr.Errors[i])
+			outcome.Error = statesErr.Errors[i].Error()
+			result.Failed = append(result.Failed, outcome)
+			continue
+		}
+		result.Succeeded = append(result.Succeeded, outcome)
+	}
+
+	// Invalidate cache for this account
+	if err := s.invalidateCache(ctx, userID, accountID); err != nil {
+		// Log error but don't fail the request
+		_ = err
+	}
+
+	return result, nil
+}
+
+// bulkDeviceState converts a validated bulk action into a provider device state
+func bulkDeviceState(item models.BulkActionItem) providers.DeviceState {
+	action := item.ActionRequest()
+	state := providers.DeviceState{Selector: item.Selector, Duration: action.GetDuration()}
+
+	switch action.Action {
+	case models.ActionPower:
+		power, _ := action.GetPowerState()
+		state.Power = &power
+	case models.ActionBrightness:
+		level, _ := action.GetBrightnessLevel()
+		state.Brightness = &level
+	case models.ActionColor:
+		hue, _ := action.Parameters["hue"].(float64)
+		saturation, _ := action.Parameters["saturation"].(float64)
+		color := &providers.DeviceColor{Hue: hue, Saturation: saturation}
+		if kelvin, ok := action.Parameters["kelvin"].(float64); ok {
+			color.Kelvin = int(kelvin)
+		}
+		state.Color = color
+	case models.ActionTemperature:
+		kelvin, _ := action.Parameters["kelvin"].(float64)
+		state.Kelvin = int(kelvin)
+	}
+
+	return state
+}
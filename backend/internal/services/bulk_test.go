@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// sequentialClient is a fake provider without a batch endpoint
+type sequentialClient struct {
+	*fakeProviderClient
+}
+
+func (s *sequentialClient) SetStates(token string, states []providers.DeviceState) error {
+	return providers.SetStatesSequentially(s, token, states)
+}
+
+func newBulkItems() []models.BulkActionItem {
+	return []models.BulkActionItem{
+		{Selector: "id:bulb-1", Action: models.ActionPower, Parameters: map[string]interface{}{"state": "on"}},
+		{Selector: "id:bulb-2", Action: models.ActionBrightness, Parameters: map[string]interface{}{"level": 0.3}},
+		{Selector: "group_id:grp-kitchen", Action: models.ActionTemperature, Parameters: map[string]interface{}{"kelvin": 2700.0}},
+	}
+}
+
+func TestBulkExecuteAction_SendsOneBatch(t *testing.T) {
+	client := newFakeProviderClient()
+	service, account := newTestDeviceService(t, client)
+
+	result, err := service.BulkExecuteAction(context.Background(), account.OwnerUserID.String(), account.ID.String(), newBulkItems())
+	if err != nil {
+		t.Fatalf("BulkExecuteAction failed: %v", err)
+	}
+
+	if client.callCount("SetStates") != 1 {
+		t.Errorf("Expected 1 SetStates call, got %d", client.callCount("SetStates"))
+	}
+	if client.callCount("SetPower") != 0 || client.callCount("SetBrightness") != 0 {
+		t.Error("Expected no per-property calls for a batch")
+	}
+	if len(result.Succeeded) != 3 || len(result.Failed) != 0 {
+		t.Errorf("Expected 3 successes, got %+v", result)
+	}
+	if len(client.selectors) != 3 || client.selectors[2] != "group_id:grp-kitchen" {
+		t.Errorf("Expected each item's selector in the batch, got %v", client.selectors)
+	}
+}
+
+func TestBulkExecuteAction_FallsBackToSequentialCalls(t *testing.T) {
+	fake := newFakeProviderClient()
+	fake.errs["SetBrightness"] = []error{errors.New("unexpected status code: 500")}
+	service, account := newTestDeviceService(t, &sequentialClient{fake})
+
+	result, err := service.BulkExecuteAction(context.Background(), account.OwnerUserID.String(), account.ID.String(), newBulkItems())
+	if err != nil {
+		t.Fatalf("BulkExecuteAction failed: %v", err)
+	}
+
+	if fake.callCount("SetPower") != 1 || fake.callCount("SetBrightness") != 1 || fake.callCount("SetColorTemperature") != 1 {
+		t.Errorf("Expected one call per property, got %v", fake.calls)
+	}
+	if len(result.Succeeded) != 2 || len(result.Failed) != 1 {
+		t.Fatalf("Expected 2 successes and 1 failure, got %+v", result)
+	}
+	if failed := result.Failed[0]; failed.Index != 1 || failed.Selector != "id:bulb-2" || failed.Error == "" {
+		t.Errorf("Expected the brightness action to fail, got %+v", failed)
+	}
+}
+
+func TestBulkExecuteAction_RejectsUnbatchableAction(t *testing.T) {
+	client := newFakeProviderClient()
+	service, account := newTestDeviceService(t, client)
+
+	items := []models.BulkActionItem{{Selector: "all", Action: models.ActionToggle}}
+	if _, err := service.BulkExecuteAction(context.Background(), account.OwnerUserID.String(), account.ID.String(), items); err == nil {
+		t.Error("Expected toggle to be rejected in a bulk request")
+	}
+	if client.callCount("SetStates") != 0 {
+		t.Errorf("Expected no provider call, got %d", client.callCount("SetStates"))
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lightshare/backend/internal/models"
+)
+
+// ErrCapabilityNotSupported matches any CapabilityError via errors.Is
+var ErrCapabilityNotSupported = errors.New("device does not support action")
+
+// CapabilityError is returned when the devices targeted by an action lack the capability
+// it requires
+type CapabilityError struct {
+	Capability         string   // The capability the action requires
+	DeviceCapabilities []string // The capabilities the targeted devices do support
+}
+
+func (e *CapabilityError) Error() string {
+	return fmt.Sprintf("device does not support %s", e.Capability)
+}
+
+// Is reports whether target is ErrCapabilityNotSupported
+func (e *CapabilityError) Is(target error) bool {
+	return target == ErrCapabilityNotSupported
+}
+
+// ValidateActionCapability checks that a device supports the capability an action requires
+func (s *DeviceService) ValidateActionCapability(device *models.Device, action *models.ActionRequest) error {
+	return validateCapability([]*models.Device{device}, action)
+}
+
+// preflightCapability fails fast when no device targeted by selector supports the action.
+// Actions requested with PreFlight are checked against the device list from cache or the
+// provider. Group and location selectors are always checked, but only against a cached
+// device list so that the check never costs an extra provider call. Selectors matching no
+// known device are left for the provider to resolve.
+func (s *DeviceService) preflightCapability(ctx context.Context, userID string, account *models.Account, selector string, action *models.ActionRequest) error {
+	if action.RequiredCapability() == "" {
+		return nil
+	}
+
+	var devices []*models.Device
+	switch {
+	case action.PreFlight:
+		var err error
+		devices, err = s.cachedOrFetchDevices(ctx, userID, account)
+		if err != nil {
+			return err
+		}
+	case isGroupSelector(selector):
+		cached, _, err := s.getCachedDevices(ctx, account.ID.String())
+		if err != nil {
+			return nil
+		}
+		devices = cached
+	default:
+		return nil
+	}
+
+	selected := selectDevices(devices, selector)
+	if len(selected) == 0 {
+		return nil
+	}
+	return validateCapability(selected, action)
+}
+
+// validateCapability fails when none of devices supports the capability action requires
+func validateCapability(devices []*models.Device, action *models.ActionRequest) error {
+	capability := action.RequiredCapability()
+	if capability == "" {
+		return nil
+	}
+
+	supported := make(map[string]struct{})
+	for _, device := range devices {
+		if device.HasCapability(capability) {
+			return nil
+		}
+		for _, c := range device.Capabilities {
+			supported[c] = struct{}{}
+		}
+	}
+
+	deviceCapabilities := make([]string, 0, len(supported))
+	for c := range supported {
+		deviceCapabilities = append(deviceCapabilities, c)
+	}
+	sort.Strings(deviceCapabilities)
+
+	return &CapabilityError{Capability: capability, DeviceCapabilities: deviceCapabilities}
+}
+
+// isGroupSelector reports whether selector targets a group or location of devices
+func isGroupSelector(selector string) bool {
+	return strings.HasPrefix(selector, "group_id:") || strings.HasPrefix(selector, "location_id:")
+}
+
+// selectDevices returns the devices a selector targets
+func selectDevices(devices []*models.Device, selector string) []*models.Device {
+	selected := make([]*models.Device, 0)
+	for _, device := range devices {
+		if selectorMatches(selector, device) {
+			selected = append(selected, device)
+		}
+	}
+	return selected
+}
+
+// selectorMatches reports whether selector targets device
+func selectorMatches(selector string, device *models.Device) bool {
+	switch {
+	case selector == "all":
+		return true
+	case strings.HasPrefix(selector, "id:"):
+		return device.ID == strings.TrimPrefix(selector, "id:")
+	case strings.HasPrefix(selector, "group_id:"):
+		return device.Group != nil && device.Group.ID == strings.TrimPrefix(selector, "group_id:")
+	case strings.HasPrefix(selector, "location_id:"):
+		return device.Location != nil && device.Location.ID == strings.TrimPrefix(selector, "location_id:")
+	default:
+		return false
+	}
+}
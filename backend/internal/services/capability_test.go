@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// newWhiteDevices returns two white-only bulbs in one group and a color bulb in another
+func newWhiteDevices() []*providers.Device {
+	white := []string{"brightness", "temperature"}
+	return []*providers.Device{
+		{ID: "white-1", Label: "Hall 1", Capabilities: white, Group: &providers.DeviceGroup{ID: "grp-hall", Name: "Hall"}},
+		{ID: "white-2", Label: "Hall 2", Capabilities: white, Group: &providers.DeviceGroup{ID: "grp-hall", Name: "Hall"}},
+		{ID: "color-1", Label: "Lounge", Capabilities: []string{"brightness", "color", "temperature"}, Group: &providers.DeviceGroup{ID: "grp-lounge", Name: "Lounge"}},
+	}
+}
+
+func colorAction() *models.ActionRequest {
+	return &models.ActionRequest{
+		Action:     models.ActionColor,
+		Parameters: map[string]interface{}{"hue": 120.0, "saturation": 1.0},
+	}
+}
+
+func TestExecuteAction_PreFlightRejectsUnsupportedAction(t *testing.T) {
+	client := newFakeProviderClient(newWhiteDevices()...)
+	service, account := newTestDeviceService(t, client)
+
+	action := colorAction()
+	action.PreFlight = true
+	err := service.ExecuteAction(context.Background(), account.OwnerUserID.String(), account.ID.String(), "id:white-1", action)
+
+	var capabilityErr *CapabilityError
+	if !errors.As(err, &capabilityErr) {
+		t.Fatalf("Expected CapabilityError, got %v", err)
+	}
+	if capabilityErr.Capability != models.CapabilityColor {
+		t.Errorf("Expected color capability to be required, got %q", capabilityErr.Capability)
+	}
+	if len(capabilityErr.DeviceCapabilities) != 2 || capabilityErr.DeviceCapabilities[1] != "temperature" {
+		t.Errorf("Expected the device's capabilities, got %v", capabilityErr.DeviceCapabilities)
+	}
+	if client.callCount("SetColor") != 0 {
+		t.Errorf("Expected the provider not to be called, got %d SetColor calls", client.callCount("SetColor"))
+	}
+}
+
+func TestExecuteAction_PreFlightAllowsSupportedAction(t *testing.T) {
+	client := newFakeProviderClient(newWhiteDevices()...)
+	service, account := newTestDeviceService(t, client)
+
+	action := colorAction()
+	action.PreFlight = true
+	if err := service.ExecuteAction(context.Background(), account.OwnerUserID.String(), account.ID.String(), "id:color-1", action); err != nil {
+		t.Fatalf("ExecuteAction failed: %v", err)
+	}
+	if client.callCount("SetColor") != 1 {
+		t.Errorf("Expected 1 SetColor call, got %d", client.callCount("SetColor"))
+	}
+}
+
+func TestExecuteAction_GroupCheckedAgainstCachedDevices(t *testing.T) {
+	client := newFakeProviderClient(newWhiteDevices()...)
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	// Without a cached device list the group is not checked
+	if err := service.ExecuteAction(context.Background(), userID, accountID, "group_id:grp-hall", colorAction()); err != nil {
+		t.Fatalf("Expected uncached group action to reach the provider, got %v", err)
+	}
+
+	if _, err := service.accountDevices(context.Background(), userID, accountID); err != nil {
+		t.Fatalf("Failed to cache devices: %v", err)
+	}
+
+	err := service.ExecuteAction(context.Background(), userID, accountID, "group_id:grp-hall", colorAction())
+	if !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Expected ErrCapabilityNotSupported, got %v", err)
+	}
+	if err := service.ExecuteAction(context.Background(), userID, accountID, "group_id:grp-lounge", colorAction()); err != nil {
+		t.Errorf("Expected supported group action to succeed, got %v", err)
+	}
+	if client.callCount("SetColor") != 2 {
+		t.Errorf("Expected 2 SetColor calls, got %d", client.callCount("SetColor"))
+	}
+}
+
+func TestValidateActionCapability(t *testing.T) {
+	service := &DeviceService{}
+	device := &models.Device{ID: "white-1", Capabilities: []string{"brightness", "temperature"}}
+
+	if err := service.ValidateActionCapability(device, colorAction()); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Expected color to be unsupported, got %v", err)
+	}
+
+	temperature := &models.ActionRequest{Action: models.ActionTemperature, Parameters: map[string]interface{}{"kelvin": 2700.0}}
+	if err := service.ValidateActionCapability(device, temperature); err != nil {
+		t.Errorf("Expected temperature to be supported, got %v", err)
+	}
+
+	power := &models.ActionRequest{Action: models.ActionPower, Parameters: map[string]interface{}{"state": "on"}}
+	if err := service.ValidateActionCapability(device, power); err != nil {
+		t.Errorf("Expected power to need no capability, got %v", err)
+	}
+}
+
+func TestExecuteAction_Infrared(t *testing.T) {
+	devices := append(newWhiteDevices(), &providers.Device{ID: "porch", Label: "Porch", Capabilities: []string{"brightness", "infrared"}, SupportsInfrared: true})
+	client := newFakeProviderClient(devices...)
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	infrared := func(level float64) *models.ActionRequest {
+		return &models.ActionRequest{Action: models.ActionInfrared, Parameters: map[string]interface{}{"level": level}, PreFlight: true}
+	}
+
+	if err := service.ExecuteAction(context.Background(), userID, accountID, "id:white-1", infrared(0.5)); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Expected a light without infrared to be rejected, got %v", err)
+	}
+	if err := service.ExecuteAction(context.Background(), userID, accountID, "id:porch", infrared(1.5)); err == nil {
+		t.Error("Expected an infrared level above 1 to be rejected")
+	}
+	if err := service.ExecuteAction(context.Background(), userID, accountID, "id:porch", infrared(0.5)); err != nil {
+		t.Fatalf("ExecuteAction failed: %v", err)
+	}
+	if client.callCount("SetInfrared") != 1 {
+		t.Errorf("Expected 1 SetInfrared call, got %d", client.callCount("SetInfrared"))
+	}
+
+	page, err := service.ListAccountDevices(context.Background(), userID, accountID,
+		models.DeviceFilter{Capability: models.CapabilityInfrared}, models.PaginationOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListAccountDevices failed: %v", err)
+	}
+	if len(page.Devices) != 1 || page.Devices[0].ID != "porch" {
+		t.Errorf("Expected only the infrared light to match the filter, got %d devices", len(page.Devices))
+	}
+}
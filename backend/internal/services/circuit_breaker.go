@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sony/gobreaker"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// circuitFailureThreshold is how many consecutive provider failures open an account's circuit
+const circuitFailureThreshold = 5
+
+// ErrProviderCircuitOpen is returned when an account's provider calls are short-circuited
+// because recent calls failed
+var ErrProviderCircuitOpen = errors.New("provider temporarily unavailable: circuit open")
+
+// CircuitBreakerConfig configures the per-account provider circuit breakers
+type CircuitBreakerConfig struct {
+	MaxRequests uint32        // Trial requests allowed while half-open
+	Interval    time.Duration // How often failure counts are cleared while closed (0 never clears)
+	Timeout     time.Duration // How long the breaker stays open before going half-open
+}
+
+// circuitBreakers keeps one circuit breaker per account so that one failing provider
+// account does not affect the others
+type circuitBreakers struct {
+	breakers sync.Map // accountID -> *gobreaker.CircuitBreaker
+	config   CircuitBreakerConfig
+}
+
+func newCircuitBreakers(config CircuitBreakerConfig) *circuitBreakers {
+	return &circuitBreakers{config: config}
+}
+
+// get returns the breaker of an account, creating it on first use
+func (b *circuitBreakers) get(accountID string) *gobreaker.CircuitBreaker {
+	if breaker, ok := b.breakers.Load(accountID); ok {
+		return breaker.(*gobreaker.CircuitBreaker)
+	}
+
+	breaker, _ := b.breakers.LoadOrStore(accountID, gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:         accountID,
+		MaxRequests:  b.config.MaxRequests,
+		Interval:     b.config.Interval,
+		Timeout:      b.config.Timeout,
+		IsSuccessful: isProviderAvailable,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= circuitFailureThreshold
+		},
+	}))
+	return breaker.(*gobreaker.CircuitBreaker)
+}
+
+// execute runs a provider call through the account's breaker
+func (b *circuitBreakers) execute(accountID string, call func() error) error {
+	_, err := b.get(accountID).Execute(func() (interface{}, error) {
+		return nil, call()
+	})
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		return ErrProviderCircuitOpen
+	}
+	return err
+}
+
+// status reports the state and consecutive failures of an account's breaker
+func (b *circuitBreakers) status(accountID string) *models.CircuitStatus {
+	breaker := b.get(accountID)
+	return &models.CircuitStatus{
+		State:    breaker.State().String(),
+		Failures: breaker.Counts().ConsecutiveFailures,
+	}
+}
+
+// isProviderAvailable reports whether a provider call error still shows a reachable
+// provider. Rejected tokens, unsupported operations, throttling and partly applied actions
+// are answered by a healthy API, so they must not open the circuit.
+func isProviderAvailable(err error) bool {
+	if err == nil {
+		return true
+	}
+	var rateLimitErr *providers.RateLimitError
+	return errors.Is(err, providers.ErrUnauthorized) ||
+		errors.Is(err, providers.ErrNotImplemented) ||
+		errors.Is(err, providers.ErrPartialSuccess) ||
+		errors.As(err, &rateLimitErr)
+}
+
+// AccountStatus reports the provider circuit breaker status of an account
+func (s *DeviceService) AccountStatus(ctx context.Context, userID, accountID string) (*models.CircuitStatus, error) {
+	account, err := s.accountRepo.FindByIDString(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	if account.OwnerUserID.String() != userID {
+		return nil, ErrAccountNotOwned
+	}
+
+	return s.breakers.status(accountID), nil
+}
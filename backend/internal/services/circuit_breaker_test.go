@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+func TestCircuitBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	client := newFakeProviderClient()
+	for i := 0; i < circuitFailureThreshold; i++ {
+		client.errs["SetPower"] = append(client.errs["SetPower"], errors.New("connection timed out"))
+	}
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	action := &models.ActionRequest{
+		Action:     models.ActionPower,
+		Parameters: map[string]interface{}{"state": "on"},
+	}
+
+	for i := 0; i < circuitFailureThreshold; i++ {
+		if err := service.ExecuteAction(context.Background(), userID, accountID, "all", action); err == nil {
+			t.Fatalf("Expected call %d to fail", i+1)
+		}
+	}
+
+	err := service.ExecuteAction(context.Background(), userID, accountID, "all", action)
+	if !errors.Is(err, ErrProviderCircuitOpen) {
+		t.Fatalf("Expected ErrProviderCircuitOpen, got %v", err)
+	}
+	if calls := client.callCount("SetPower"); calls != circuitFailureThreshold {
+		t.Errorf("Expected the open circuit to skip the provider (%d calls), got %d", circuitFailureThreshold, calls)
+	}
+
+	status, err := service.AccountStatus(context.Background(), userID, accountID)
+	if err != nil {
+		t.Fatalf("AccountStatus failed: %v", err)
+	}
+	if status.State != "open" {
+		t.Errorf("Expected state 'open', got '%s'", status.State)
+	}
+}
+
+func TestCircuitBreaker_IgnoresUnauthorized(t *testing.T) {
+	client := newFakeProviderClient()
+	for i := 0; i < 10; i++ {
+		client.errs["ListDevices"] = append(client.errs["ListDevices"], providers.ErrUnauthorized)
+	}
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	for i := 0; i < 10; i++ {
+		_, err := service.RefreshDevices(context.Background(), userID, accountID)
+		if errors.Is(err, ErrProviderCircuitOpen) {
+			t.Fatalf("Expected rejected tokens not to open the circuit (call %d)", i+1)
+		}
+	}
+
+	status, err := service.AccountStatus(context.Background(), userID, accountID)
+	if err != nil {
+		t.Fatalf("AccountStatus failed: %v", err)
+	}
+	if status.State != "closed" || status.Failures != 0 {
+		t.Errorf("Expected closed circuit without failures, got %+v", status)
+	}
+}
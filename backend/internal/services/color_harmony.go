@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/logger"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// ApplyHarmony sets the color lights of an account, in alphabetical order of label, to the
+// colors of a harmony palette: the first light to the first color and so on, until either
+// runs out. Lights are set in parallel, as applyColorsToLights does, and one write is
+// counted against the rate limits for the whole palette. Failures of individual lights are reported in the result; an
+// error is returned only when the palette could not be applied at all.
+func (s *DeviceService) ApplyHarmony(ctx context.Context, userID, accountID string, req *models.ApplyHarmonyRequest) (*models.ApplyHarmonyResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, &apierror.BadRequestError{Message: err.Error(), Cause: err}
+	}
+	palette, err := req.Palette()
+	if err != nil {
+		return nil, &apierror.BadRequestError{Message: err.Error(), Cause: err}
+	}
+
+	// Get account and verify ownership
+	account, err := s.accountRepo.FindByIDString(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	if account.OwnerUserID.String() != userID {
+		return nil, ErrAccountNotOwned
+	}
+	if account.IsReadOnly() {
+		return nil, ErrAccountReadOnly
+	}
+
+	devices, err := s.cachedOrFetchDevices(ctx, userID, account)
+	if err != nil {
+		return nil, err
+	}
+	lights := harmonyLights(devices, len(palette))
+	if len(lights) == 0 {
+		return nil, &apierror.BadRequestError{Message: "account has no color lights"}
+	}
+
+	// Check rate limit
+	if rateLimitErr := s.checkRateLimit(ctx, userID, account, rateLimitWrite); rateLimitErr != nil {
+		return nil, rateLimitErr
+	}
+
+	colors := make([]models.ColorEntry, len(lights))
+	for i := range lights {
+		colors[i] = models.ColorEntry{Hue: palette[i].Hue, Saturation: palette[i].Saturation, Brightness: palette[i].Brightness, Kelvin: 3500}
+	}
+	errs, err := s.applyColorsToLights(ctx, account, lights, colors, req.GetDuration())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ApplyHarmonyResult{
+		Succeeded: make([]models.HarmonyApplication, 0, len(lights)),
+		Failed:    make([]models.HarmonyApplication, 0),
+	}
+	for i, light := range lights {
+		application := models.HarmonyApplication{DeviceID: light.ID, Label: light.Label, Color: palette[i]}
+		if errs[i] != nil {
+			application.Error = errs[i].Error()
+			result.Failed = append(result.Failed, application)
+			continue
+		}
+		result.Succeeded = append(result.Succeeded, application)
+	}
+
+	// Invalidate cache for this account
+	if err := s.invalidateCache(ctx, userID, accountID); err != nil {
+		// Log error but don't fail the request
+		logger.WithContext(ctx).Warn("Failed to clear device cache", "error", err, "account_id", accountID)
+	}
+
+	return result, nil
+}
+
+// harmonyLights returns at most limit color lights of devices, sorted by label
+func harmonyLights(devices []*models.Device, limit int) []*models.Device {
+	lights := make([]*models.Device, 0, len(devices))
+	for _, device := range devices {
+		if device.SupportsColor() {
+			lights = append(lights, device)
+		}
+	}
+
+	sort.SliceStable(lights, func(i, j int) bool {
+		return strings.ToLower(lights[i].Label) < strings.ToLower(lights[j].Label)
+	})
+	if len(lights) > limit {
+		lights = lights[:limit]
+	}
+	return lights
+}
+
+// applyColorsToLights sets each of lights to the color at the same index, calling the
+// provider for at most fetch.concurrency lights at once, and returns the error of each
+// light. Whites given as a color temperature are set as such, and a brightness of 0 leaves
+// the brightness unchanged. An error is returned alone when the account's token or
+// provider client cannot be obtained.
+func (s *DeviceService) applyColorsToLights(ctx context.Context, account *models.Account, lights []*models.Device, colors []models.ColorEntry, duration float64) ([]error, error) {
+	accountID := account.ID.String()
+
+	// Get decrypted token
+	token, err := s.accountRepo.GetDecryptedToken(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	// Create provider client
+	client, err := s.newClient(providers.Provider(account.Provider))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider client: %w", err)
+	}
+
+	errs := make([]error, len(lights))
+	semaphore := make(chan struct{}, s.fetch.concurrency)
+	var wg sync.WaitGroup
+	for i, light := range lights {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			selector := "id:" + light.ID
+			entry := colors[i]
+			errs[i] = s.callProvider(ctx, account, "set_color", selector, func(ctx context.Context) error {
+				client := providers.WithContext(ctx, client)
+				var err error
+				if entry.KelvinOnly() {
+					err = client.SetColorTemperature(token, selector, entry.Kelvin, duration)
+				} else {
+					err = client.SetColor(token, selector, &providers.DeviceColor{Hue: entry.Hue, Saturation: entry.Saturation, Kelvin: entry.Kelvin}, duration)
+				}
+				if err != nil || entry.Brightness == 0 {
+					return err
+				}
+				return client.SetBrightness(token, selector, entry.Brightness, duration)
+			})
+			if errs[i] != nil {
+				s.validations.invalidateOnUnauthorized(ctx, accountID, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errs, nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+func TestApplyHarmony_SetsLightsInLabelOrder(t *testing.T) {
+	colorCapabilities := []string{models.CapabilityColor}
+	client := newFakeProviderClient(
+		&providers.Device{ID: "d1", Label: "kitchen", Capabilities: colorCapabilities},
+		&providers.Device{ID: "d2", Label: "Bedroom", Capabilities: colorCapabilities},
+		&providers.Device{ID: "d3", Label: "Attic"},
+		&providers.Device{ID: "d4", Label: "Hall", Capabilities: colorCapabilities},
+		&providers.Device{ID: "d5", Label: "Porch", Capabilities: colorCapabilities},
+	)
+	service, account := newTestDeviceService(t, client)
+
+	req := &models.ApplyHarmonyRequest{HarmonyRequest: models.HarmonyRequest{Mode: providers.HarmonyTriadic, Hue: 30}}
+	result, err := service.ApplyHarmony(context.Background(), account.OwnerUserID.String(), account.ID.String(), req)
+	if err != nil {
+		t.Fatalf("ApplyHarmony failed: %v", err)
+	}
+
+	if len(result.Failed) != 0 || len(result.Succeeded) != 3 {
+		t.Fatalf("Expected 3 lights set, got %+v", result)
+	}
+	wantIDs := []string{"d2", "d4", "d1"}
+	wantHues := []float64{30, 150, 270}
+	for i, application := range result.Succeeded {
+		if application.DeviceID != wantIDs[i] || application.Color.Hue != wantHues[i] {
+			t.Errorf("Expected %s set to hue %v, got %s set to %v", wantIDs[i], wantHues[i], application.DeviceID, application.Color.Hue)
+		}
+		if application.Color.Saturation != 1 || application.Color.Brightness != 0.8 {
+			t.Errorf("Expected default saturation and brightness, got %+v", application.Color)
+		}
+	}
+	if client.callCount("SetColor") != 3 || client.callCount("SetBrightness") != 3 {
+		t.Errorf("Expected 3 SetColor and SetBrightness calls, got %d and %d", client.callCount("SetColor"), client.callCount("SetBrightness"))
+	}
+}
+
+func TestApplyHarmony_ReportsFailedLights(t *testing.T) {
+	colorCapabilities := []string{models.CapabilityColor}
+	client := newFakeProviderClient(
+		&providers.Device{ID: "d1", Label: "A", Capabilities: colorCapabilities},
+		&providers.Device{ID: "d2", Label: "B", Capabilities: colorCapabilities},
+	)
+	client.errs["SetColor"] = []error{errors.New("device offline")}
+	service, account := newTestDeviceService(t, client)
+
+	req := &models.ApplyHarmonyRequest{HarmonyRequest: models.HarmonyRequest{Mode: providers.HarmonyComplementary, Hue: 0}}
+	result, err := service.ApplyHarmony(context.Background(), account.OwnerUserID.String(), account.ID.String(), req)
+	if err != nil {
+		t.Fatalf("ApplyHarmony failed: %v", err)
+	}
+	if len(result.Succeeded) != 1 || len(result.Failed) != 1 || result.Failed[0].Error == "" {
+		t.Errorf("Expected one light set and one failure, got %+v", result)
+	}
+}
+
+func TestApplyHarmony_RejectsAccountWithoutColorLights(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "d1", Label: "Plug"})
+	service, account := newTestDeviceService(t, client)
+
+	req := &models.ApplyHarmonyRequest{HarmonyRequest: models.HarmonyRequest{Mode: providers.HarmonyAnalogous, Hue: 0}}
+	_, err := service.ApplyHarmony(context.Background(), account.OwnerUserID.String(), account.ID.String(), req)
+
+	var badRequestErr *apierror.BadRequestError
+	if !errors.As(err, &badRequestErr) {
+		t.Errorf("Expected a BadRequestError, got %v", err)
+	}
+	if client.callCount("SetColor") != 0 {
+		t.Errorf("Expected no SetColor calls, got %d", client.callCount("SetColor"))
+	}
+}
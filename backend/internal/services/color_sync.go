@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/logger"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// ErrColorSyncNotFound is returned when stopping a color sync that is not running
+var ErrColorSyncNotFound = apierror.New(apierror.ErrNotFound, "no color sync is running for this account")
+
+// activeColorSync is a continuous color sync running on this instance
+type activeColorSync struct {
+	cancel context.CancelFunc
+}
+
+// colorSyncTarget holds what a color sync needs to read the leader and set the targets
+type colorSyncTarget struct {
+	account *models.Account
+	client  providers.Client
+	token   string
+}
+
+// ColorSync sets the lights the request's selector targets to the current hue and
+// saturation of its leader device, and to its brightness when asked to. A leader
+// reporting no color is a bad request.
+func (s *DeviceService) ColorSync(ctx context.Context, userID, accountID string, req models.ColorSyncRequest) error {
+	target, err := s.prepareColorSync(ctx, userID, accountID, &req)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.syncColor(ctx, target, &req); err != nil {
+		return err
+	}
+
+	// Invalidate cache for this account
+	if err := s.invalidateCache(ctx, userID, accountID); err != nil {
+		// Log error but don't fail the request
+		logger.WithContext(ctx).Warn("Failed to clear device cache", "error", err, "account_id", accountID)
+	}
+	return nil
+}
+
+// StartColorSync syncs the targets with the leader right away, then keeps them in sync in
+// the background, re-reading the leader every interval, until the returned stop function
+// or StopColorSync is called. Each round is reported on the returned channel, which is
+// closed when the sync stops. A sync already running for the account is replaced.
+//
+// Running syncs are held in memory: StopColorSync only reaches those started on the
+// same instance.
+func (s *DeviceService) StartColorSync(ctx context.Context, userID, accountID string, req models.ColorSyncRequest, interval time.Duration) (<-chan models.ColorSyncUpdate, func(), error) {
+	if err := models.ValidateColorSyncInterval(interval); err != nil {
+		return nil, nil, &apierror.BadRequestError{Message: err.Error(), Cause: err}
+	}
+
+	target, err := s.prepareColorSync(ctx, userID, accountID, &req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The first round fails the request, so a leader without color is reported as such
+	first, err := s.syncColor(ctx, target, &req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if previous, ok := s.colorSyncs.LoadAndDelete(accountID); ok {
+		previous.(*activeColorSync).cancel()
+	}
+
+	// The sync outlives the request that started it, until stopped
+	syncCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	active := &activeColorSync{cancel: cancel}
+	s.colorSyncs.Store(accountID, active)
+
+	updates := make(chan models.ColorSyncUpdate, 1)
+	updates <- *first
+
+	go func() {
+		defer close(updates)
+		defer cancel()
+		s.runColorSync(syncCtx, userID, target, &req, interval, updates)
+
+		s.colorSyncs.CompareAndDelete(accountID, active)
+		if err := s.invalidateCache(context.WithoutCancel(syncCtx), userID, accountID); err != nil {
+			logger.Warn("Failed to clear device cache", "error", err, "account_id", accountID)
+		}
+		logger.Info("Color sync stopped", "account_id", accountID, "leader", req.LeaderDeviceID)
+	}()
+
+	logger.Info("Color sync started",
+		"account_id", accountID,
+		"leader", req.LeaderDeviceID,
+		"targets", req.TargetSelector,
+		"interval", interval,
+	)
+
+	stop := func() {
+		cancel()
+		s.colorSyncs.CompareAndDelete(accountID, active)
+	}
+	return updates, stop, nil
+}
+
+// runColorSync syncs the targets with the leader every interval until ctx is cancelled or
+// the provider rejects the token. A failed round is reported and the next one tried anyway.
+func (s *DeviceService) runColorSync(ctx context.Context, userID string, target *colorSyncTarget, req *models.ColorSyncRequest, interval time.Duration, updates chan<- models.ColorSyncUpdate) {
+	accountID := target.account.ID.String()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Every round is a write against the account's rate limits
+		var update *models.ColorSyncUpdate
+		err := s.checkRateLimit(ctx, userID, target.account, rateLimitWrite)
+		if err == nil {
+			update, err = s.syncColor(ctx, target, req)
+		}
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if err != nil {
+			logger.Warn("Color sync round failed", "error", err, "account_id", accountID, "leader", req.LeaderDeviceID)
+			update = &models.ColorSyncUpdate{SyncedAt: time.Now().UTC(), Error: err.Error()}
+		}
+
+		select {
+		case updates <- *update:
+		case <-ctx.Done():
+			return
+		}
+
+		if errors.Is(err, providers.ErrUnauthorized) {
+			return
+		}
+	}
+}
+
+// prepareColorSync checks the request and the user's access to the account, counts a write
+// against the rate limits and returns the account's provider client and token
+func (s *DeviceService) prepareColorSync(ctx context.Context, userID, accountID string, req *models.ColorSyncRequest) (*colorSyncTarget, error) {
+	if err := req.Validate(); err != nil {
+		return nil, &apierror.BadRequestError{Message: err.Error(), Cause: err}
+	}
+
+	// Get account and verify ownership
+	account, err := s.accountRepo.FindByIDString(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	if account.OwnerUserID.String() != userID {
+		return nil, ErrAccountNotOwned
+	}
+	if account.IsReadOnly() {
+		return nil, ErrAccountReadOnly
+	}
+
+	// Check rate limit
+	if rateLimitErr := s.checkRateLimit(ctx, userID, account, rateLimitWrite); rateLimitErr != nil {
+		return nil, rateLimitErr
+	}
+
+	// Get decrypted token
+	token, err := s.accountRepo.GetDecryptedToken(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	// Create provider client
+	client, err := s.newClient(providers.Provider(account.Provider))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider client: %w", err)
+	}
+
+	return &colorSyncTarget{account: account, client: client, token: token}, nil
+}
+
+// syncColor reads the leader's current color and sets it on the targets
+func (s *DeviceService) syncColor(ctx context.Context, target *colorSyncTarget, req *models.ColorSyncRequest) (*models.ColorSyncUpdate, error) {
+	accountID := target.account.ID.String()
+
+	var leader *providers.Device
+	err := s.callProvider(ctx, target.account, "get_device", "id:"+req.LeaderDeviceID, func(ctx context.Context) (callErr error) {
+		leader, callErr = providers.WithContext(ctx, target.client).GetDevice(target.token, req.LeaderDeviceID)
+		return callErr
+	})
+	if err != nil {
+		s.validations.invalidateOnUnauthorized(ctx, accountID, err)
+		return nil, fmt.Errorf("failed to get leader device from provider: %w", err)
+	}
+	if leader.Color == nil {
+		return nil, &apierror.BadRequestError{Message: "leader device has no color data"}
+	}
+
+	color := &providers.DeviceColor{Hue: leader.Color.Hue, Saturation: leader.Color.Saturation, Kelvin: leader.Color.Kelvin}
+	err = s.callProvider(ctx, target.account, "color_sync", req.TargetSelector, func(ctx context.Context) error {
+		client := providers.WithContext(ctx, target.client)
+		if err := client.SetColor(target.token, req.TargetSelector, color, models.ColorSyncDuration); err != nil {
+			return err
+		}
+		if !req.IncludeBrightness {
+			return nil
+		}
+		return client.SetBrightness(target.token, req.TargetSelector, leader.Brightness, models.ColorSyncDuration)
+	})
+	if err != nil {
+		s.validations.invalidateOnUnauthorized(ctx, accountID, err)
+		return nil, err
+	}
+
+	update := &models.ColorSyncUpdate{
+		Color:    &models.DeviceColor{Hue: color.Hue, Saturation: color.Saturation, Kelvin: color.Kelvin},
+		SyncedAt: time.Now().UTC(),
+	}
+	if req.IncludeBrightness {
+		brightness := leader.Brightness
+		update.Brightness = &brightness
+	}
+	return update, nil
+}
+
+// StopColorSync stops the continuous color sync running for the account
+func (s *DeviceService) StopColorSync(ctx context.Context, userID, accountID string) error {
+	// Get account and verify ownership
+	account, err := s.accountRepo.FindByIDString(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("account not found: %w", err)
+	}
+
+	if account.OwnerUserID.String() != userID {
+		return ErrAccountNotOwned
+	}
+
+	active, running := s.colorSyncs.LoadAndDelete(accountID)
+	if !running {
+		return ErrColorSyncNotFound
+	}
+	active.(*activeColorSync).cancel()
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+func TestColorSync(t *testing.T) {
+	leader := &providers.Device{ID: "leader", Connected: true, Brightness: 0.7, Color: &providers.DeviceColor{Hue: 300, Saturation: 0.9, Kelvin: 3500}}
+	plain := &providers.Device{ID: "plain", Connected: true, Brightness: 0.7}
+
+	t.Run("mirrors the color", func(t *testing.T) {
+		client := newFakeProviderClient(leader)
+		service, account := newTestDeviceService(t, client)
+
+		req := models.ColorSyncRequest{LeaderDeviceID: "leader", TargetSelector: "group_id:living"}
+		if err := service.ColorSync(context.Background(), account.OwnerUserID.String(), account.ID.String(), req); err != nil {
+			t.Fatalf("ColorSync failed: %v", err)
+		}
+
+		if client.callCount("SetColor") != 1 || client.callCount("SetBrightness") != 0 {
+			t.Errorf("Expected only the color to be set, got %d color and %d brightness calls",
+				client.callCount("SetColor"), client.callCount("SetBrightness"))
+		}
+		if len(client.selectors) != 1 || client.selectors[0] != "group_id:living" {
+			t.Errorf("Expected the targets to be set, got %v", client.selectors)
+		}
+	})
+
+	t.Run("mirrors the brightness when asked to", func(t *testing.T) {
+		client := newFakeProviderClient(leader)
+		service, account := newTestDeviceService(t, client)
+
+		req := models.ColorSyncRequest{LeaderDeviceID: "leader", TargetSelector: "all", IncludeBrightness: true}
+		if err := service.ColorSync(context.Background(), account.OwnerUserID.String(), account.ID.String(), req); err != nil {
+			t.Fatalf("ColorSync failed: %v", err)
+		}
+
+		if client.callCount("SetColor") != 1 || client.callCount("SetBrightness") != 1 {
+			t.Errorf("Expected the color and brightness to be set, got %d color and %d brightness calls",
+				client.callCount("SetColor"), client.callCount("SetBrightness"))
+		}
+	})
+
+	t.Run("rejects a leader without color", func(t *testing.T) {
+		client := newFakeProviderClient(plain)
+		service, account := newTestDeviceService(t, client)
+
+		req := models.ColorSyncRequest{LeaderDeviceID: "plain", TargetSelector: "all"}
+		err := service.ColorSync(context.Background(), account.OwnerUserID.String(), account.ID.String(), req)
+		var badRequestErr *apierror.BadRequestError
+		if !errors.As(err, &badRequestErr) {
+			t.Fatalf("Expected a bad request, got %v", err)
+		}
+		if client.callCount("SetColor") != 0 {
+			t.Error("Expected no color to be set")
+		}
+	})
+
+	t.Run("requires a leader and targets", func(t *testing.T) {
+		service, account := newTestDeviceService(t, newFakeProviderClient(leader))
+
+		err := service.ColorSync(context.Background(), account.OwnerUserID.String(), account.ID.String(), models.ColorSyncRequest{LeaderDeviceID: "leader"})
+		var badRequestErr *apierror.BadRequestError
+		if !errors.As(err, &badRequestErr) {
+			t.Errorf("Expected a bad request, got %v", err)
+		}
+	})
+
+	t.Run("rejects other users", func(t *testing.T) {
+		service, account := newTestDeviceService(t, newFakeProviderClient(leader))
+
+		req := models.ColorSyncRequest{LeaderDeviceID: "leader", TargetSelector: "all"}
+		if err := service.ColorSync(context.Background(), "someone-else", account.ID.String(), req); !errors.Is(err, ErrAccountNotOwned) {
+			t.Errorf("Expected ErrAccountNotOwned, got %v", err)
+		}
+	})
+}
+
+func TestStartColorSync(t *testing.T) {
+	leader := &providers.Device{ID: "leader", Connected: true, Color: &providers.DeviceColor{Hue: 120, Saturation: 1}}
+	client := newFakeProviderClient(leader)
+	service, account := newTestDeviceService(t, client)
+	userID := account.OwnerUserID.String()
+	accountID := account.ID.String()
+	req := models.ColorSyncRequest{LeaderDeviceID: "leader", TargetSelector: "all"}
+
+	if _, _, err := service.StartColorSync(context.Background(), userID, accountID, req, time.Second); err == nil {
+		t.Error("Expected an interval below the minimum to be rejected")
+	}
+
+	updates, stop, err := service.StartColorSync(context.Background(), userID, accountID, req, models.MaxColorSyncInterval)
+	if err != nil {
+		t.Fatalf("StartColorSync failed: %v", err)
+	}
+	defer stop()
+
+	first := <-updates
+	if first.Color == nil || first.Color.Hue != 120 || first.Error != "" {
+		t.Errorf("Expected the first round to report the leader's color, got %+v", first)
+	}
+
+	if err := service.StopColorSync(context.Background(), "someone-else", accountID); !errors.Is(err, ErrAccountNotOwned) {
+		t.Errorf("Expected ErrAccountNotOwned, got %v", err)
+	}
+	if err := service.StopColorSync(context.Background(), userID, accountID); err != nil {
+		t.Fatalf("StopColorSync failed: %v", err)
+	}
+
+	select {
+	case _, open := <-updates:
+		if open {
+			t.Error("Expected no round after the sync was stopped")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the sync to stop")
+	}
+
+	if err := service.StopColorSync(context.Background(), userID, accountID); !errors.Is(err, ErrColorSyncNotFound) {
+		t.Errorf("Expected ErrColorSyncNotFound, got %v", err)
+	}
+}
+
+func TestStartColorSync_ReplacesRunningSync(t *testing.T) {
+	leader := &providers.Device{ID: "leader", Connected: true, Color: &providers.DeviceColor{Hue: 120, Saturation: 1}}
+	service, account := newTestDeviceService(t, newFakeProviderClient(leader))
+	userID := account.OwnerUserID.String()
+	accountID := account.ID.String()
+	req := models.ColorSyncRequest{LeaderDeviceID: "leader", TargetSelector: "all"}
+
+	previous, stopPrevious, err := service.StartColorSync(context.Background(), userID, accountID, req, models.MaxColorSyncInterval)
+	if err != nil {
+		t.Fatalf("StartColorSync failed: %v", err)
+	}
+	defer stopPrevious()
+	<-previous
+
+	_, stop, err := service.StartColorSync(context.Background(), userID, accountID, req, models.MaxColorSyncInterval)
+	if err != nil {
+		t.Fatalf("StartColorSync failed: %v", err)
+	}
+	defer stop()
+
+	waitFor(t, "the previous sync to stop", func() bool {
+		select {
+		case _, open := <-previous:
+			return !open
+		default:
+			return false
+		}
+	})
+
+	// Stopping the replaced sync leaves the new one running
+	stopPrevious()
+	if _, running := service.colorSyncs.Load(accountID); !running {
+		t.Error("Expected the new sync to keep running")
+	}
+}
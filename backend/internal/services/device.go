@@ -3,41 +3,254 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lightshare/backend/internal/models"
 	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/events"
+	"github.com/lightshare/backend/pkg/logger"
+	"github.com/lightshare/backend/pkg/metrics"
 	"github.com/lightshare/backend/pkg/providers"
+	"github.com/lightshare/backend/pkg/ratelimit"
+	"github.com/lightshare/backend/pkg/tracing"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"golang.org/x/sync/singleflight"
 )
 
+const (
+	// maxThrottleDeferral is the longest Retry-After interval we are willing to defer an action for
+	maxThrottleDeferral = 5 * time.Minute
+)
+
+var (
+	// ErrRateLimitExceeded matches any RateLimitExceededError via errors.Is
+	ErrRateLimitExceeded = errors.New("rate limit exceeded")
+	// ErrUserRateLimitExceeded matches a RateLimitExceededError for the user-wide limit via errors.Is
+	ErrUserRateLimitExceeded = errors.New("user rate limit exceeded")
+	// ErrDeviceNotFound is returned when a device is not among its account's devices
+	ErrDeviceNotFound = apierror.New(apierror.ErrNotFound, "device not found")
+	// ErrAccountReadOnly is returned when controlling the devices of an account whose token
+	// can only list them
+	ErrAccountReadOnly = apierror.New(apierror.ErrForbidden, "account token is read-only")
+	// ErrAccountTokenInvalid is returned when listing the devices of an account whose token
+	// the provider rejected during re-validation, until the account is reconnected
+	ErrAccountTokenInvalid = errors.New("provider token is invalid")
+)
+
+// rateLimitScopeUser is the RateLimitExceededError scope of the user-wide limit
+const rateLimitScopeUser = "user"
+
+// RateLimitExceededError is returned when a user or account exceeds one of its rate limits
+type RateLimitExceededError struct {
+	Scope             string // "user", "read", "write" or the provider name
+	Limit             int
+	RetryAfterSeconds int
+}
+
+func (e *RateLimitExceededError) Error() string {
+	return fmt.Sprintf("rate limit exceeded: max %d %s requests per minute", e.Limit, e.Scope)
+}
+
+// Is reports whether target is ErrRateLimitExceeded, or ErrUserRateLimitExceeded for the user-wide limit
+func (e *RateLimitExceededError) Is(target error) bool {
+	return target == ErrRateLimitExceeded || (target == ErrUserRateLimitExceeded && e.Scope == rateLimitScopeUser)
+}
+
+// rateLimitKind distinguishes cheap reads from state-changing writes
+type rateLimitKind string
+
+const (
+	rateLimitRead  rateLimitKind = "read"
+	rateLimitWrite rateLimitKind = "write"
+)
+
+// RateLimits holds the per-account limits for reads and writes, plus an overall
+// per-account budget for each provider matching the provider's own API quota.
+// User caps the requests of a user across all of their accounts.
+type RateLimits struct {
+	Providers map[providers.Provider]ratelimit.Limit
+	User      ratelimit.Limit // PerMinute 0 disables the user-wide limit
+	Read      ratelimit.Limit
+	Write     ratelimit.Limit
+}
+
+// ActionDeferredError is returned when a throttled action was scheduled to run later
+type ActionDeferredError struct {
+	ScheduledAt time.Time
+	RetryAfter  time.Duration
+	ScheduleID  uuid.UUID // The one-shot schedule running the action, which may be deleted to cancel it
+}
+
+func (e *ActionDeferredError) Error() string {
+	return fmt.Sprintf("action deferred until %s", e.ScheduledAt.UTC().Format(time.RFC3339))
+}
+
 // DeviceService handles device-related business logic
 type DeviceService struct {
-	accountRepo     *repository.AccountRepository
-	cache           *redis.Client
-	cacheTTL        time.Duration
-	rateLimitPerMin int
+	accountRepo  repository.AccountRepositoryInterface
+	stateHistory repository.DeviceStateRepositoryInterface
+	preferences  *UserPreferencesService
+	labels       *DeviceLabelService
+	schedules    repository.ScheduleRepositoryInterface
+	cache        *redis.Client
+	events       *events.Bus
+	limiter      *ratelimit.Limiter
+	validations  *tokenValidationCache
+	transitions  *transitionTracker
+	breakers     *circuitBreakers
+	metrics      *metrics.Metrics
+	newClient    func(provider providers.Provider) (providers.Client, error)
+	limits       RateLimits
+	cacheTTL     time.Duration
+	fetch        fetchConfig
+	streams      *deviceStreamHub
+	streamEvery  time.Duration
+	sfGroup      singleflight.Group // Deduplicates concurrent device fetches of an account
+	ramps        sync.Map           // Ramps running on this instance, by ramp key
+	rampInterval time.Duration      // Time between ramp steps (default 30s)
+	colorSyncs   sync.Map           // Continuous color syncs running on this instance, by account ID
+}
+
+// DeviceServiceConfig holds the tunables of a DeviceService
+type DeviceServiceConfig struct {
+	RateLimits     RateLimits
+	Metrics        *metrics.Metrics // Optional; nil disables metrics
+	CircuitBreaker CircuitBreakerConfig
+	CacheTTL       time.Duration
+	EnableRetry    bool // Retry transient provider failures with exponential backoff
+	// FetchConcurrency bounds how many accounts ListDevices fetches, and how many lights a
+	// palette is applied to, in parallel (default 5)
+	FetchConcurrency int
+	// FetchTimeout bounds the time spent fetching a single account's devices (default 5s)
+	FetchTimeout time.Duration
+	// StreamInterval is how often accounts with streaming clients are polled for changes (default 30s)
+	StreamInterval time.Duration
+	// ProviderTimeouts is the HTTP timeout of provider API requests, by provider (default 10s)
+	ProviderTimeouts map[string]time.Duration
+	// LIFXLANMode controls LIFX devices found on the local network over the LAN protocol
+	LIFXLANMode bool
+	// EnabledProviders returns the providers that may be used, and is called for every
+	// provider client created (nil enables every provider)
+	EnabledProviders func() []string
+	// StateHistory records actions and detected state changes (nil disables device history)
+	StateHistory repository.DeviceStateRepositoryInterface
+	// Preferences fills in the parameters actions leave out from the user's preferences
+	// (nil disables backfilling)
+	Preferences *UserPreferencesService
+	// Labels replaces the providers' device labels with the users' own (nil disables relabeling)
+	Labels *DeviceLabelService
+	// Schedules stores the throttled actions deferred until the provider's rate limit resets,
+	// for the schedule runner to execute (nil fails throttled actions instead)
+	Schedules repository.ScheduleRepositoryInterface
+	// NewClient creates the client of a provider (nil creates the providers' API clients)
+	NewClient func(provider providers.Provider) (providers.Client, error)
+}
+
+const (
+	// defaultFetchConcurrency is how many accounts ListDevices fetches in parallel by default
+	defaultFetchConcurrency = 5
+	// defaultFetchTimeout bounds a single account's device fetch by default
+	defaultFetchTimeout = 5 * time.Second
+	// defaultStreamInterval is how often streamed accounts are polled by default
+	defaultStreamInterval = 30 * time.Second
+)
+
+// fetchConfig bounds the parallel device fetches of ListDevices
+type fetchConfig struct {
+	concurrency int
+	timeout     time.Duration
 }
 
 // NewDeviceService creates a new device service
 func NewDeviceService(
-	accountRepo *repository.AccountRepository,
+	accountRepo repository.AccountRepositoryInterface,
 	cache *redis.Client,
-	cacheTTL time.Duration,
-	rateLimitPerMin int,
+	eventBus *events.Bus,
+	config DeviceServiceConfig,
 ) *DeviceService {
+	if config.StreamInterval <= 0 {
+		config.StreamInterval = defaultStreamInterval
+	}
+
+	newClient := config.NewClient
+	if newClient == nil {
+		newClient = providerClientFactory(config.ProviderTimeouts, config.LIFXLANMode, config.EnabledProviders)
+	}
+	if config.EnableRetry {
+		newProviderClient := newClient
+		newClient = func(provider providers.Provider) (providers.Client, error) {
+			client, err := newProviderClient(provider)
+			if err != nil {
+				return nil, err
+			}
+			return providers.NewRetryClient(client), nil
+		}
+	}
+
 	return &DeviceService{
-		accountRepo:     accountRepo,
-		cache:           cache,
-		cacheTTL:        cacheTTL,
-		rateLimitPerMin: rateLimitPerMin,
+		accountRepo:  accountRepo,
+		stateHistory: config.StateHistory,
+		preferences:  config.Preferences,
+		labels:       config.Labels,
+		schedules:    config.Schedules,
+		cache:        cache,
+		events:       eventBus,
+		limiter:      ratelimit.New(cache),
+		validations:  newTokenValidationCache(cache, 0),
+		transitions:  newTransitionTracker(eventBus),
+		breakers:     newCircuitBreakers(config.CircuitBreaker),
+		metrics:      config.Metrics,
+		newClient:    newClient,
+		limits:       config.RateLimits,
+		cacheTTL:     config.CacheTTL,
+		fetch:        newFetchConfig(config.FetchConcurrency, config.FetchTimeout),
+		streams:      newDeviceStreamHub(),
+		streamEvery:  config.StreamInterval,
+	}
+}
+
+// providerClientFactory returns a constructor of provider clients whose requests time out
+// after the provider's entry in timeouts, or the provider's default when it has none.
+// With lifxLAN, LIFX clients control the devices they can reach over the local network.
+// Providers missing from the list enabledProviders returns fail with
+// providers.ErrProviderDisabled; a nil enabledProviders enables every provider.
+func providerClientFactory(timeouts map[string]time.Duration, lifxLAN bool, enabledProviders func() []string) func(providers.Provider) (providers.Client, error) {
+	return func(provider providers.Provider) (providers.Client, error) {
+		var enabled []string
+		if enabledProviders != nil {
+			enabled = enabledProviders()
+		}
+		return providers.NewClient(provider,
+			providers.WithTimeout(timeouts[string(provider)]),
+			providers.WithLAN(lifxLAN),
+			providers.WithEnabledProviders(enabled),
+		)
 	}
 }
 
-// ListDevices returns all devices for a user's accounts
-func (s *DeviceService) ListDevices(ctx context.Context, userID string) ([]*models.Device, error) {
+// newFetchConfig applies the defaults to unset fetch tunables
+func newFetchConfig(concurrency int, timeout time.Duration) fetchConfig {
+	if concurrency <= 0 {
+		concurrency = defaultFetchConcurrency
+	}
+	if timeout <= 0 {
+		timeout = defaultFetchTimeout
+	}
+	return fetchConfig{concurrency: concurrency, timeout: timeout}
+}
+
+// ListDevices returns a page of devices across all of the user's accounts. Accounts are
+// fetched in parallel; an account that cannot be fetched is reported in the page's Errors
+// instead of failing the listing, and a cursor within it resumes with the next account.
+func (s *DeviceService) ListDevices(ctx context.Context, userID string, opts models.PaginationOptions) (*models.DevicePage, error) {
 	// Parse user ID
 	userUUID, err := uuid.Parse(userID)
 	if err != nil {
@@ -50,72 +263,200 @@ func (s *DeviceService) ListDevices(ctx context.Context, userID string) ([]*mode
 		return nil, fmt.Errorf("failed to get accounts: %w", err)
 	}
 
-	allDevices := make([]*models.Device, 0)
-
-	// Fetch devices for each account
-	for _, account := range accounts {
-		// Check cache first
-		devices, err := s.getCachedDevices(ctx, account.ID.String())
-		if err == nil {
-			// Cache hit
-			allDevices = append(allDevices, devices...)
+	// Fetch devices for each account, keeping the accounts' order
+	results := s.fetchAccountsDevices(ctx, userID, accounts)
+
+	lists := make([]accountDeviceList, 0, len(accounts))
+	var providerErrors []models.ProviderError
+	for i, result := range results {
+		accountID := accounts[i].ID.String()
+		if result.err != nil {
+			// Listed without devices, so that a cursor naming the account resumes after it
+			providerErrors = append(providerErrors, models.ProviderError{AccountID: accountID, Cause: result.err})
+			lists = append(lists, accountDeviceList{accountID: accountID})
 			continue
 		}
+		devices, _ := s.applyDeviceLabels(ctx, userID, result.devices)
+		lists = append(lists, accountDeviceList{accountID: accountID, devices: devices})
+	}
+
+	page, err := paginateDevices(lists, opts)
+	if err != nil {
+		return nil, err
+	}
+	page.Errors = providerErrors
+
+	return page, nil
+}
+
+// accountFetchResult is the outcome of fetching the devices of one account
+type accountFetchResult struct {
+	err     error
+	devices []*models.Device
+	index   int
+}
+
+// fetchAccountsDevices fetches the devices of every account, from cache when possible, running
+// at most fetch.concurrency fetches at once. Results are returned in the order of accounts.
+func (s *DeviceService) fetchAccountsDevices(ctx context.Context, userID string, accounts []*models.Account) []accountFetchResult {
+	resultsCh := make(chan accountFetchResult, len(accounts))
+	semaphore := make(chan struct{}, s.fetch.concurrency)
+
+	var wg sync.WaitGroup
+	for i, account := range accounts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
 
-		// Cache miss - fetch from provider
-		devices, err = s.fetchDevicesFromProvider(ctx, account)
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			fetchCtx, cancel := context.WithTimeout(ctx, s.fetch.timeout)
+			defer cancel()
+
+			devices, err := s.cachedOrFetchDevices(fetchCtx, userID, account)
+			resultsCh <- accountFetchResult{index: i, devices: devices, err: err}
+		}()
+	}
+	wg.Wait()
+	close(resultsCh)
+
+	results := make([]accountFetchResult, len(accounts))
+	for result := range resultsCh {
+		results[result.index] = result
+	}
+	return results
+}
+
+// cachedOrFetchDevices returns the devices of an account from cache, or fetches and caches
+// them. A stale cached list is returned as is while it is refreshed in the background.
+func (s *DeviceService) cachedOrFetchDevices(ctx context.Context, userID string, account *models.Account) ([]*models.Device, error) {
+	// Check cache first
+	devices, isStale, err := s.getCachedDevices(ctx, account.ID.String())
+	if err == nil {
+		s.metrics.CacheHit(account.ID.String())
+		if isStale {
+			go s.backgroundRefresh(ctx, userID, account)
+		}
+		return devices, nil
+	}
+
+	// Cache miss - fetch from provider
+	s.metrics.CacheMiss(account.ID.String())
+	return s.fetchAndCacheDevices(ctx, userID, account)
+}
+
+// fetchAndCacheDevices fetches the devices of an account from the provider and caches them.
+// Concurrent calls for the same account share a single provider call, so that requests
+// arriving together on an expired cache do not each hit the provider. The shared fetch
+// outlives the cancellation of the caller that started it, and each caller gets its own
+// copy of the devices. Each caller still stops waiting when its own ctx is done.
+func (s *DeviceService) fetchAndCacheDevices(ctx context.Context, userID string, account *models.Account) ([]*models.Device, error) {
+	accountID := account.ID.String()
+	resultCh := s.sfGroup.DoChan("devices:"+accountID, func() (interface{}, error) {
+		ctx := context.WithoutCancel(ctx)
+		devices, err := s.fetchDevicesFromProvider(ctx, userID, account)
 		if err != nil {
-			// Log error but continue with other accounts
-			continue
+			return nil, err
 		}
 
-		// Cache the devices
-		if err := s.setCachedDevices(ctx, account.ID.String(), devices); err != nil {
+		// Cache the devices before releasing the waiting callers
+		if err := s.setCachedDevices(ctx, accountID, devices); err != nil {
 			// Log error but continue
-			_ = err
+			logger.WithContext(ctx).Warn("Failed to cache devices", "error", err, "account_id", accountID)
 		}
+		return devices, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-resultCh:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		shared := result.Val.([]*models.Device)
+		devices := make([]*models.Device, len(shared))
+		for i, device := range shared {
+			devices[i] = device.Clone()
+		}
+		return devices, nil
+	}
+}
 
-		allDevices = append(allDevices, devices...)
+// backgroundRefresh refetches and caches the devices of an account whose cached list is
+// stale. The refresh lock lets a single refresh of the account run at a time across
+// instances; it is left to expire, so a failing refresh is retried at most once per
+// refreshLockTTL.
+func (s *DeviceService) backgroundRefresh(ctx context.Context, userID string, account *models.Account) {
+	accountID := account.ID.String()
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), refreshLockTTL)
+	defer cancel()
+
+	acquired, err := s.cache.SetNX(ctx, refreshLockKey(accountID), 1, refreshLockTTL).Result()
+	if err != nil || !acquired {
+		return
 	}
 
-	return allDevices, nil
+	if _, err := s.fetchAndCacheDevices(ctx, userID, account); err != nil {
+		logger.WithContext(ctx).Warn("Failed to refresh stale devices", "error", err, "account_id", accountID)
+	}
 }
 
-// ListAccountDevices returns devices for a specific account
-func (s *DeviceService) ListAccountDevices(ctx context.Context, userID, accountID string) ([]*models.Device, error) {
-	// Get account and verify ownership
-	account, err := s.accountRepo.FindByIDString(ctx, accountID)
+// ListAccountDevices returns a page of the devices of a specific account that match filter.
+// Total counts every device of the account; FilteredCount only the matching ones.
+func (s *DeviceService) ListAccountDevices(ctx context.Context, userID, accountID string, filter models.DeviceFilter, opts models.PaginationOptions) (*models.DevicePage, error) {
+	devices, err := s.accountDevices(ctx, userID, accountID)
 	if err != nil {
-		return nil, fmt.Errorf("account not found: %w", err)
+		return nil, err
 	}
+	devices, relabeled := s.applyDeviceLabels(ctx, userID, devices)
 
-	if account.OwnerUserID.String() != userID {
-		return nil, fmt.Errorf("unauthorized: user does not own this account")
+	page, err := paginateDevices([]accountDeviceList{{accountID: accountID, devices: filter.Apply(devices)}}, opts)
+	if err != nil {
+		return nil, err
 	}
+	page.Total = len(devices)
 
-	// Check cache first
-	devices, err := s.getCachedDevices(ctx, accountID)
-	if err == nil {
-		return devices, nil
+	// A page holding the whole list in its default order can reuse the ETag cached with
+	// it, unless the user's labels changed the list
+	if len(page.Devices) == len(devices) && !relabeled && opts.Sort == "" {
+		page.ETag = s.getCachedDevicesETag(ctx, accountID)
 	}
 
-	// Cache miss - fetch from provider
-	devices, err = s.fetchDevicesFromProvider(ctx, account)
+	return page, nil
+}
+
+// accountDevices returns every device of a specific account, from cache when possible
+func (s *DeviceService) accountDevices(ctx context.Context, userID, accountID string) ([]*models.Device, error) {
+	// Get account and verify ownership
+	account, err := s.accountRepo.FindByIDString(ctx, accountID)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("account not found: %w", err)
 	}
 
-	// Cache the devices
-	if err := s.setCachedDevices(ctx, accountID, devices); err != nil {
-		// Log error but continue
-		_ = err
+	if account.OwnerUserID.String() != userID {
+		return nil, ErrAccountNotOwned
 	}
 
-	return devices, nil
+	if account.TokenInvalidAt != nil {
+		return nil, ErrAccountTokenInvalid
+	}
+
+	return s.cachedOrFetchDevices(ctx, userID, account)
 }
 
 // GetDevice returns a specific device by ID
 func (s *DeviceService) GetDevice(ctx context.Context, userID, accountID, deviceID string) (*models.Device, error) {
+	device, err := s.getProviderDevice(ctx, userID, accountID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	return s.applyDeviceLabel(ctx, userID, device), nil
+}
+
+// getProviderDevice fetches a specific device from its provider, with the provider's label
+func (s *DeviceService) getProviderDevice(ctx context.Context, userID, accountID, deviceID string) (*models.Device, error) {
 	// Get account and verify ownership
 	account, err := s.accountRepo.FindByIDString(ctx, accountID)
 	if err != nil {
@@ -123,11 +464,11 @@ func (s *DeviceService) GetDevice(ctx context.Context, userID, accountID, device
 	}
 
 	if account.OwnerUserID.String() != userID {
-		return nil, fmt.Errorf("unauthorized: user does not own this account")
+		return nil, ErrAccountNotOwned
 	}
 
 	// Check rate limit
-	if rateLimitErr := s.checkRateLimit(ctx, accountID); rateLimitErr != nil {
+	if rateLimitErr := s.checkRateLimit(ctx, userID, account, rateLimitRead); rateLimitErr != nil {
 		return nil, rateLimitErr
 	}
 
@@ -138,28 +479,74 @@ func (s *DeviceService) GetDevice(ctx context.Context, userID, accountID, device
 	}
 
 	// Create provider client
-	client, err := providers.NewClient(providers.Provider(account.Provider))
+	client, err := s.newClient(providers.Provider(account.Provider))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create provider client: %w", err)
 	}
 
 	// Get device from provider
-	providerDevice, err := client.GetDevice(token, deviceID)
+	var providerDevice *providers.Device
+	err = s.callProvider(ctx, account, "get_device", "id:"+deviceID, func(ctx context.Context) (callErr error) {
+		providerDevice, callErr = providers.WithContext(ctx, client).GetDevice(token, deviceID)
+		return callErr
+	})
 	if err != nil {
+		s.validations.invalidateOnUnauthorized(ctx, accountID, err)
 		return nil, fmt.Errorf("failed to get device from provider: %w", err)
 	}
 
 	// Convert to our device model
-	device := s.convertProviderDevice(providerDevice, accountID, account.Provider)
+	device := s.convertProviderDevice(providerDevice, account)
 
 	return device, nil
 }
 
+// GetLiveDevice fetches a device's state from its provider, bypassing the cache, and
+// updates the device's entry in the cached device list
+func (s *DeviceService) GetLiveDevice(ctx context.Context, userID, accountID, deviceID string) (*models.Device, error) {
+	device, err := s.getProviderDevice(ctx, userID, accountID, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.setCachedDeviceState(ctx, accountID, device); err != nil {
+		// Log error but continue
+		logger.WithContext(ctx).Warn("Failed to cache device state", "error", err, "account_id", accountID)
+	}
+
+	return s.applyDeviceLabel(ctx, userID, device), nil
+}
+
+// GetCachedDevice returns a device from its account's cached device list, fetching the list
+// when nothing is cached
+func (s *DeviceService) GetCachedDevice(ctx context.Context, userID, accountID, deviceID string) (*models.Device, error) {
+	devices, err := s.accountDevices(ctx, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, device := range devices {
+		if device.ID == deviceID {
+			return s.applyDeviceLabel(ctx, userID, device), nil
+		}
+	}
+	return nil, ErrDeviceNotFound
+}
+
 // ExecuteAction executes a control action on device(s)
 func (s *DeviceService) ExecuteAction(ctx context.Context, userID, accountID, selector string, action *models.ActionRequest) error {
+	// A key is bound to the request as sent, before preferences fill it in
+	var fingerprint string
+	if action.IdempotencyKey != "" {
+		fingerprint = actionFingerprint(accountID, selector, action)
+	}
+
+	// Fill in parameters left out from the user's preferences before requiring them
+	s.backfillPreferences(ctx, userID, action)
+
 	// Validate action
 	if err := action.ValidateParameters(); err != nil {
-		return fmt.Errorf("invalid action parameters: %w", err)
+		return &apierror.BadRequestError{Message: err.Error(), Cause: err}
 	}
 
 	// Get account and verify ownership
@@ -169,11 +556,31 @@ func (s *DeviceService) ExecuteAction(ctx context.Context, userID, accountID, se
 	}
 
 	if account.OwnerUserID.String() != userID {
-		return fmt.Errorf("unauthorized: user does not own this account")
+		return ErrAccountNotOwned
+	}
+
+	// A retried request is answered with the original result instead of acting twice. The
+	// key is held until the provider's answer is recorded, so concurrent retries don't act
+	// either; an action failing before reaching the provider frees it.
+	recorded := false
+	if action.IdempotencyKey != "" {
+		if err := s.reserveIdempotencyKey(ctx, userID, action.IdempotencyKey, fingerprint); err != nil {
+			return err
+		}
+		defer func() {
+			if !recorded {
+				s.releaseIdempotencyKey(ctx, userID, action.IdempotencyKey)
+			}
+		}()
+	}
+
+	// Fail fast when the targeted devices cannot perform the action
+	if err := s.preflightCapability(ctx, userID, account, selector, action); err != nil {
+		return err
 	}
 
 	// Check rate limit
-	if rateLimitErr := s.checkRateLimit(ctx, accountID); rateLimitErr != nil {
+	if rateLimitErr := s.checkRateLimit(ctx, userID, account, rateLimitWrite); rateLimitErr != nil {
 		return rateLimitErr
 	}
 
@@ -184,27 +591,133 @@ func (s *DeviceService) ExecuteAction(ctx context.Context, userID, accountID, se
 	}
 
 	// Create provider client
-	client, err := providers.NewClient(providers.Provider(account.Provider))
+	client, err := s.newClient(providers.Provider(account.Provider))
 	if err != nil {
 		return fmt.Errorf("failed to create provider client: %w", err)
 	}
 
+	// The cached devices give the old state of the targeted devices in their history
+	var previous []*models.Device
+	if s.stateHistory != nil {
+		previous, _, _ = s.getCachedDevices(ctx, accountID)
+	}
+
 	// Execute action based on type
-	if err := s.executeProviderAction(client, token, selector, action); err != nil {
+	if err := s.executeProviderAction(ctx, account, client, token, selector, action); err != nil {
+		s.validations.invalidateOnUnauthorized(ctx, accountID, err)
+		var rateLimitErr *providers.RateLimitError
+		if action.DeferOnThrottle && s.schedules != nil && errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter <= maxThrottleDeferral {
+			deferredErr, deferErr := s.deferAction(ctx, account, selector, action, rateLimitErr.RetryAfter)
+			if deferErr == nil {
+				return deferredErr
+			}
+			// Fail with the rate limit, which tells the client when to retry
+			logger.WithContext(ctx).Error("Failed to defer throttled action", "error", deferErr, "account_id", accountID)
+		}
+		s.publishActionCompleted(userID, accountID, selector, action, err)
+		recorded = s.recordIdempotentResult(ctx, userID, action, fingerprint, err)
+
+		// Only the devices the action reached have changed
+		var partialErr *providers.PartialSuccessError
+		if errors.As(err, &partialErr) {
+			if invalidateErr := s.invalidateCachedDevices(ctx, userID, accountID, partialErr.Succeeded); invalidateErr != nil {
+				// Log error but don't fail the request
+				logger.WithContext(ctx).Warn("Failed to clear device cache", "error", invalidateErr, "account_id", accountID)
+			}
+		}
 		return err
 	}
 
+	s.publishActionCompleted(userID, accountID, selector, action, nil)
+	recorded = s.recordIdempotentResult(ctx, userID, action, fingerprint, nil)
+	s.recordActionEvents(account, selector, action, previous)
+
+	// Announce when the transition should be complete, superseding any earlier one
+	s.transitions.track(userID, accountID, selector, action)
+
 	// Invalidate cache for this account
-	if err := s.invalidateCache(ctx, accountID); err != nil {
+	if err := s.invalidateCache(ctx, userID, accountID); err != nil {
 		// Log error but don't fail the request
-		_ = err
+		logger.WithContext(ctx).Warn("Failed to clear device cache", "error", err, "account_id", accountID)
 	}
 
 	return nil
 }
 
+// recordIdempotentResult stores the result of an action sent with an idempotency key.
+// Returns false when there is no key or the result could not be stored.
+func (s *DeviceService) recordIdempotentResult(ctx context.Context, userID string, action *models.ActionRequest, fingerprint string, err error) bool {
+	if action.IdempotencyKey == "" {
+		return false
+	}
+	// Stored even if the client gave up waiting, since that is when it retries
+	if storeErr := s.setIdempotentResult(context.WithoutCancel(ctx), userID, action.IdempotencyKey, fingerprint, err); storeErr != nil {
+		// Log error but don't fail the request
+		logger.WithContext(ctx).Warn("Failed to store idempotent result", "error", storeErr, "user_id", userID)
+		return false
+	}
+	return true
+}
+
+// publishActionCompleted announces the outcome of an action sent to the provider
+func (s *DeviceService) publishActionCompleted(userID, accountID, selector string, action *models.ActionRequest, err error) {
+	completed := &models.ActionCompleted{
+		Selector: selector,
+		Action:   action.Action,
+		Success:  err == nil,
+	}
+	if err != nil {
+		completed.Error = err.Error()
+	}
+
+	s.events.Publish(events.Event{Type: events.ActionCompleted, UserID: userID, AccountID: accountID, Payload: completed})
+}
+
+// deferAction schedules a throttled action to run once the provider's Retry-After interval
+// has elapsed. It is stored as a one-shot schedule, so the schedule runner of any instance
+// executes it, even after a restart.
+func (s *DeviceService) deferAction(ctx context.Context, account *models.Account, selector string, action *models.ActionRequest, retryAfter time.Duration) (*ActionDeferredError, error) {
+	retry := *action
+	retry.DeferOnThrottle = false // Only defer once; a second throttle fails normally
+
+	actionJSON, err := json.Marshal(retry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode action: %w", err)
+	}
+
+	scheduled := &models.ScheduledAction{
+		UserID:    account.OwnerUserID,
+		AccountID: account.ID,
+		Selector:  selector,
+		Action:    actionJSON,
+		Enabled:   true,
+		RunOnce:   true,
+		NextRunAt: time.Now().Add(retryAfter).UTC(),
+	}
+	if err := s.schedules.Create(ctx, scheduled); err != nil {
+		return nil, err
+	}
+
+	return &ActionDeferredError{
+		ScheduledAt: scheduled.NextRunAt,
+		RetryAfter:  retryAfter,
+		ScheduleID:  scheduled.ID,
+	}, nil
+}
+
 // RefreshDevices forces a cache refresh for an account
 func (s *DeviceService) RefreshDevices(ctx context.Context, userID, accountID string) ([]*models.Device, error) {
+	discovery, err := s.DiscoverDevices(ctx, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return discovery.Devices, nil
+}
+
+// DiscoverDevices forces a cache refresh for an account and reports which devices were
+// added or removed since the last cached list. When nothing was cached there is no
+// baseline to diff against, so Added and Removed are empty.
+func (s *DeviceService) DiscoverDevices(ctx context.Context, userID, accountID string) (*models.DeviceDiscovery, error) {
 	// Get account and verify ownership
 	account, err := s.accountRepo.FindByIDString(ctx, accountID)
 	if err != nil {
@@ -212,36 +725,51 @@ func (s *DeviceService) RefreshDevices(ctx context.Context, userID, accountID st
 	}
 
 	if account.OwnerUserID.String() != userID {
-		return nil, fmt.Errorf("unauthorized: user does not own this account")
+		return nil, ErrAccountNotOwned
 	}
 
+	// Capture the cached list as the diff baseline before invalidating it
+	previous, _, cacheErr := s.getCachedDevices(ctx, accountID)
+
 	// Invalidate cache
-	if invalidateErr := s.invalidateCache(ctx, accountID); invalidateErr != nil {
+	if invalidateErr := s.invalidateCache(ctx, userID, accountID); invalidateErr != nil {
 		// Log error but continue
-		_ = invalidateErr
+		logger.WithContext(ctx).Warn("Failed to clear device cache", "error", invalidateErr, "account_id", accountID)
 	}
 
 	// Fetch fresh data from provider
-	devices, err := s.fetchDevicesFromProvider(ctx, account)
+	devices, err := s.fetchAndCacheDevices(ctx, userID, account)
 	if err != nil {
 		return nil, err
 	}
 
-	// Cache the devices
-	if err := s.setCachedDevices(ctx, accountID, devices); err != nil {
-		// Log error but continue
-		_ = err
+	discovery := &models.DeviceDiscovery{
+		Devices: devices,
+		Added:   make([]*models.Device, 0),
+		Removed: make([]*models.Device, 0),
+	}
+	if cacheErr == nil {
+		discovery.Added = diffDevices(devices, previous)
+		discovery.Removed = diffDevices(previous, devices)
+		s.recordStateChanges(account, previous, devices)
 	}
 
-	return devices, nil
+	for _, device := range discovery.Added {
+		s.events.Publish(events.Event{Type: events.DeviceAdded, UserID: userID, AccountID: accountID, Payload: device})
+	}
+	for _, device := range discovery.Removed {
+		s.events.Publish(events.Event{Type: events.DeviceRemoved, UserID: userID, AccountID: accountID, Payload: device})
+	}
+
+	return discovery, nil
 }
 
 // --- Private helper methods ---
 
 // fetchDevicesFromProvider fetches devices from the provider API
-func (s *DeviceService) fetchDevicesFromProvider(ctx context.Context, account *models.Account) ([]*models.Device, error) {
+func (s *DeviceService) fetchDevicesFromProvider(ctx context.Context, userID string, account *models.Account) ([]*models.Device, error) {
 	// Check rate limit
-	if err := s.checkRateLimit(ctx, account.ID.String()); err != nil {
+	if err := s.checkRateLimit(ctx, userID, account, rateLimitRead); err != nil {
 		return nil, err
 	}
 
@@ -252,32 +780,38 @@ func (s *DeviceService) fetchDevicesFromProvider(ctx context.Context, account *m
 	}
 
 	// Create provider client
-	client, err := providers.NewClient(providers.Provider(account.Provider))
+	client, err := s.newClient(providers.Provider(account.Provider))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create provider client: %w", err)
 	}
 
 	// Get devices from provider
-	providerDevices, err := client.ListDevices(token)
+	var providerDevices []*providers.Device
+	err = s.callProvider(ctx, account, "list_devices", "all", func(ctx context.Context) (callErr error) {
+		providerDevices, callErr = providers.WithContext(ctx, client).ListDevices(token)
+		return callErr
+	})
 	if err != nil {
+		s.validations.invalidateOnUnauthorized(ctx, account.ID.String(), err)
 		return nil, fmt.Errorf("failed to list devices from provider: %w", err)
 	}
 
 	// Convert to our device model
 	devices := make([]*models.Device, len(providerDevices))
 	for i, pd := range providerDevices {
-		devices[i] = s.convertProviderDevice(pd, account.ID.String(), account.Provider)
+		devices[i] = s.convertProviderDevice(pd, account)
 	}
 
 	return devices, nil
 }
 
-// convertProviderDevice converts a provider device to our device model
-func (s *DeviceService) convertProviderDevice(pd *providers.Device, accountID, provider string) *models.Device {
+// convertProviderDevice converts a provider device of account to our device model
+func (s *DeviceService) convertProviderDevice(pd *providers.Device, account *models.Account) *models.Device {
 	device := &models.Device{
 		ID:           pd.ID,
-		AccountID:    accountID,
-		Provider:     provider,
+		AccountID:    account.ID.String(),
+		AccountLabel: account.Label,
+		Provider:     account.Provider,
 		Label:        pd.Label,
 		Power:        pd.Power,
 		Brightness:   pd.Brightness,
@@ -287,6 +821,13 @@ func (s *DeviceService) convertProviderDevice(pd *providers.Device, accountID, p
 		Metadata:     pd.Metadata,
 	}
 
+	if len(pd.Raw) > 0 {
+		if device.Metadata == nil {
+			device.Metadata = make(map[string]interface{})
+		}
+		device.Metadata[models.MetadataRawKey] = pd.Raw
+	}
+
 	if pd.Color != nil {
 		device.Color = &models.DeviceColor{
 			Hue:        pd.Color.Hue,
@@ -309,11 +850,87 @@ func (s *DeviceService) convertProviderDevice(pd *providers.Device, accountID, p
 		}
 	}
 
+	device.HealthScore = models.DeviceScore(device)
+
 	return device
 }
 
 // executeProviderAction executes an action via the provider client
-func (s *DeviceService) executeProviderAction(client providers.Client, token, selector string, action *models.ActionRequest) error {
+func (s *DeviceService) executeProviderAction(ctx context.Context, account *models.Account, client providers.Client, token, selector string, action *models.ActionRequest) error {
+	if account.IsReadOnly() {
+		return ErrAccountReadOnly
+	}
+	return s.callProvider(ctx, account, action.Action, selector, func(ctx context.Context) error {
+		return callProviderAction(providers.WithContext(ctx, client), token, selector, action)
+	})
+}
+
+// callProvider runs a provider API call through the account's circuit breaker, inside
+// a span, and records its latency and outcome. Calls short-circuited by an open breaker
+// are not recorded.
+func (s *DeviceService) callProvider(ctx context.Context, account *models.Account, operation, selector string, call func(ctx context.Context) error) error {
+	ctx, span := tracing.StartSpan(ctx, "provider."+operation,
+		attribute.String("provider", account.Provider),
+		attribute.String("account_id", account.ID.String()),
+		attribute.String("selector", selector),
+	)
+	defer span.End()
+
+	err := s.breakers.execute(account.ID.String(), func() error {
+		start := time.Now()
+		err := call(ctx)
+		s.metrics.ObserveProviderCall(account.Provider, operation, err, time.Since(start))
+		return err
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return wrapProviderError(account.Provider, err)
+}
+
+// wrapProviderError wraps an error reported by a provider call in an apierror.ProviderError,
+// or an apierror.RateLimitedError when the provider throttled the call. An open circuit is
+// returned as is, since the provider was not called.
+func wrapProviderError(provider string, err error) error {
+	if err == nil || errors.Is(err, ErrProviderCircuitOpen) {
+		return err
+	}
+
+	providerErr := &apierror.ProviderError{Provider: provider, Cause: err}
+	var rateLimitErr *providers.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return &apierror.RateLimitedError{Cause: providerErr, RetryAfter: rateLimitErr.RetryAfter}
+	}
+	return providerErr
+}
+
+// effectDuration returns how long a continuous effect runs; 0 runs it until the lights
+// are next changed
+func effectDuration(action *models.ActionRequest) float64 {
+	duration, _ := action.Parameters["duration"].(float64)
+	return duration
+}
+
+// waveformParams builds the parameters of a validated waveform effect
+func waveformParams(action *models.ActionRequest, color *providers.DeviceColor, cycles int, period float64) providers.WaveformParams {
+	waveformType, _ := action.Parameters["waveform_type"].(float64)
+	persist, _ := action.Parameters["persist"].(bool)
+	params := providers.WaveformParams{
+		Color:   color,
+		Type:    int(waveformType),
+		Period:  period,
+		Cycles:  float64(cycles),
+		Persist: persist,
+	}
+	if peak, ok := action.Parameters["peak"].(float64); ok {
+		params.Peak = &peak
+	}
+	return params
+}
+
+// callProviderAction maps an action onto the matching provider client call
+func callProviderAction(client providers.Client, token, selector string, action *models.ActionRequest) error {
 	duration := action.GetDuration()
 
 	switch action.Action {
@@ -324,6 +941,9 @@ func (s *DeviceService) executeProviderAction(client providers.Client, token, se
 		}
 		return client.SetPower(token, selector, state, duration)
 
+	case models.ActionToggle:
+		return client.TogglePower(token, selector, duration)
+
 	case models.ActionBrightness:
 		level, err := action.GetBrightnessLevel()
 		if err != nil {
@@ -349,6 +969,13 @@ func (s *DeviceService) executeProviderAction(client providers.Client, token, se
 		kelvin, _ := action.Parameters["kelvin"].(float64)
 		return client.SetColorTemperature(token, selector, int(kelvin), duration)
 
+	case models.ActionInfrared:
+		level, err := action.GetInfraredLevel()
+		if err != nil {
+			return err
+		}
+		return client.SetInfrared(token, selector, level)
+
 	case models.ActionEffect:
 		name, _ := action.Parameters["name"].(string)
 		cycles := 3 // Default cycles
@@ -380,6 +1007,16 @@ func (s *DeviceService) executeProviderAction(client providers.Client, token, se
 			return client.Pulse(token, selector, color, cycles, period)
 		case models.EffectBreathe:
 			return client.Breathe(token, selector, color, cycles, period)
+		case models.EffectFlame:
+			return client.Flame(token, selector, period, effectDuration(action))
+		case models.EffectMove:
+			direction := models.MoveForward
+			if d, ok := action.Parameters["direction"].(string); ok {
+				direction = d
+			}
+			return client.Move(token, selector, direction, period, effectDuration(action))
+		case models.EffectWaveform:
+			return client.Waveform(token, selector, waveformParams(action, color, cycles, period))
 		default:
 			return fmt.Errorf("unknown effect: %s", name)
 		}
@@ -389,58 +1026,250 @@ func (s *DeviceService) executeProviderAction(client providers.Client, token, se
 	}
 }
 
-// getCachedDevices retrieves devices from cache
-func (s *DeviceService) getCachedDevices(ctx context.Context, accountID string) ([]*models.Device, error) {
-	key := fmt.Sprintf("devices:account:%s", accountID)
-	data, err := s.cache.Get(ctx, key).Bytes()
+// diffDevices returns the devices in a whose IDs are not present in b
+func diffDevices(a, b []*models.Device) []*models.Device {
+	seen := make(map[string]struct{}, len(b))
+	for _, device := range b {
+		seen[device.ID] = struct{}{}
+	}
+
+	diff := make([]*models.Device, 0)
+	for _, device := range a {
+		if _, ok := seen[device.ID]; !ok {
+			diff = append(diff, device)
+		}
+	}
+	return diff
+}
+
+func devicesCacheKey(accountID string) string {
+	return fmt.Sprintf("devices:account:%s", accountID)
+}
+
+// devicesETagKey holds the models.DevicesETag of an account's cached device list. It is
+// removed whenever the cached devices change other than by caching a whole new list.
+func devicesETagKey(accountID string) string {
+	return fmt.Sprintf("devices:etag:account:%s", accountID)
+}
+
+// staleCacheFactor is how many cache TTLs a cached device list is kept for. Past its TTL,
+// the list is stale: it is still served, while being refreshed in the background.
+const staleCacheFactor = 5
+
+// refreshLockTTL bounds a background refresh of an account's devices, and how often one
+// can start
+const refreshLockTTL = 30 * time.Second
+
+// refreshLockKey is held while an account's stale devices are refreshed in the background
+func refreshLockKey(accountID string) string {
+	return fmt.Sprintf("refresh_lock:account:%s", accountID)
+}
+
+// cachedDeviceList is an account's device list as cached
+type cachedDeviceList struct {
+	Devices  []*models.Device `json:"devices"`
+	CachedAt float64          `json:"cached_at"` // Unix time the list was cached, in seconds
+}
+
+// staleDeviceState replaces the cached state of a device changed by a partly applied action
+const staleDeviceState = "stale"
+
+// errStaleDeviceCache is returned for a cached device list holding a stale device, which
+// callers treat as a cache miss
+var errStaleDeviceCache = errors.New("cached devices are stale")
+
+// deviceStatesCacheKey is the hash of an account's devices fetched one at a time since its
+// device list was cached, keyed by device ID
+func deviceStatesCacheKey(accountID string) string {
+	return fmt.Sprintf("devices:account:%s:states", accountID)
+}
+
+// getCachedDevices retrieves devices from cache, with the devices fetched individually
+// since the list was cached replacing their listed state. isStale reports whether the list
+// outlived the cache TTL.
+func (s *DeviceService) getCachedDevices(ctx context.Context, accountID string) (devices []*models.Device, isStale bool, err error) {
+	data, err := s.cache.Get(ctx, devicesCacheKey(accountID)).Bytes()
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	var devices []*models.Device
-	if err := json.Unmarshal(data, &devices); err != nil {
-		return nil, err
+	var cached cachedDeviceList
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, false, err
 	}
+	devices = cached.Devices
+	age := time.Since(time.UnixMilli(int64(cached.CachedAt * 1000)))
+	isStale = age > s.cacheTTL
 
-	return devices, nil
+	states, err := s.cache.HGetAll(ctx, deviceStatesCacheKey(accountID)).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	for i, device := range devices {
+		state, ok := states[device.ID]
+		if !ok {
+			continue
+		}
+		if state == staleDeviceState {
+			return nil, false, errStaleDeviceCache
+		}
+		var updated models.Device
+		if err := json.Unmarshal([]byte(state), &updated); err == nil {
+			devices[i] = &updated
+		}
+	}
+
+	return devices, isStale, nil
 }
 
-// setCachedDevices stores devices in cache
+// getCachedDevicesETag returns the ETag of an account's cached device list, or "" when
+// there is none
+func (s *DeviceService) getCachedDevicesETag(ctx context.Context, accountID string) string {
+	etag, err := s.cache.Get(ctx, devicesETagKey(accountID)).Result()
+	if err != nil {
+		return ""
+	}
+	return etag
+}
+
+// setCachedDevices stores devices in cache along with their ETag, kept staleCacheFactor
+// times the cache TTL. The freshly listed devices supersede any fetched individually
+// before, and the groups derived from the previous list.
 func (s *DeviceService) setCachedDevices(ctx context.Context, accountID string, devices []*models.Device) error {
-	key := fmt.Sprintf("devices:account:%s", accountID)
-	data, err := json.Marshal(devices)
+	data, err := json.Marshal(cachedDeviceList{
+		Devices:  devices,
+		CachedAt: float64(time.Now().UnixMilli()) / 1000,
+	})
 	if err != nil {
 		return err
 	}
 
-	return s.cache.Set(ctx, key, data, s.cacheTTL).Err()
+	_, err = s.cache.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, devicesCacheKey(accountID), data, s.cacheTTL*staleCacheFactor)
+		pipe.Set(ctx, devicesETagKey(accountID), models.DevicesETag(devices), s.cacheTTL*staleCacheFactor)
+		pipe.Del(ctx, deviceStatesCacheKey(accountID), groupsCacheKey(accountID))
+		return nil
+	})
+	return err
 }
 
-// invalidateCache removes devices from cache
-func (s *DeviceService) invalidateCache(ctx context.Context, accountID string) error {
-	key := fmt.Sprintf("devices:account:%s", accountID)
-	return s.cache.Del(ctx, key).Err()
+// setCachedDeviceState stores a device fetched on its own in its account's cache, leaving
+// the rest of the cached list untouched
+func (s *DeviceService) setCachedDeviceState(ctx context.Context, accountID string, device *models.Device) error {
+	data, err := json.Marshal(device)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.cache.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, deviceStatesCacheKey(accountID), device.ID, data)
+		pipe.Expire(ctx, deviceStatesCacheKey(accountID), s.cacheTTL)
+		pipe.Del(ctx, devicesETagKey(accountID))
+		return nil
+	})
+	return err
 }
 
-// checkRateLimit checks if the account has exceeded the rate limit
-func (s *DeviceService) checkRateLimit(ctx context.Context, accountID string) error {
-	key := fmt.Sprintf("ratelimit:account:%s", accountID)
+// invalidateCache removes an account's devices and groups, and the summary of its owner's
+// devices, from cache
+func (s *DeviceService) invalidateCache(ctx context.Context, userID, accountID string) error {
+	return s.cache.Del(ctx, devicesCacheKey(accountID), deviceStatesCacheKey(accountID), devicesETagKey(accountID), groupsCacheKey(accountID), deviceSummaryKey(userID)).Err()
+}
 
-	// Increment counter
-	count, err := s.cache.Incr(ctx, key).Result()
-	if err != nil {
-		return fmt.Errorf("failed to check rate limit: %w", err)
+// invalidateCachedDevices marks the given devices of an account stale in cache, and removes
+// the summary of its owner's devices. The account's devices are refetched by the next read
+// that needs a stale one; nothing is invalidated when deviceIDs is empty.
+func (s *DeviceService) invalidateCachedDevices(ctx context.Context, userID, accountID string, deviceIDs []string) error {
+	if len(deviceIDs) == 0 {
+		return nil
 	}
 
-	// Set expiry on first request
-	if count == 1 {
-		s.cache.Expire(ctx, key, 60*time.Second)
+	_, err := s.cache.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, deviceID := range deviceIDs {
+			pipe.HSet(ctx, deviceStatesCacheKey(accountID), deviceID, staleDeviceState)
+		}
+		pipe.Expire(ctx, deviceStatesCacheKey(accountID), s.cacheTTL)
+		pipe.Del(ctx, devicesETagKey(accountID), deviceSummaryKey(userID))
+		return nil
+	})
+	return err
+}
+
+// checkRateLimit records a read or write against the user's overall limit, the account's
+// sliding-window limit and its provider's overall budget, if those are configured.
+// The user limit is checked first so that spreading calls over many accounts doesn't help.
+func (s *DeviceService) checkRateLimit(ctx context.Context, userID string, account *models.Account, kind rateLimitKind) error {
+	if s.limits.User.PerMinute > 0 {
+		if err := s.allow(ctx, userRateLimitKey(userID), s.limits.User, rateLimitScopeUser); err != nil {
+			return err
+		}
+	}
+
+	accountID := account.ID.String()
+	if err := s.allow(ctx, rateLimitKey(accountID, kind), s.limitFor(kind), string(kind)); err != nil {
+		return err
+	}
+
+	provider := providers.Provider(account.Provider)
+	if limit, ok := s.limits.Providers[provider]; ok && limit.PerMinute > 0 {
+		return s.allow(ctx, providerRateLimitKey(accountID), limit, provider.String())
 	}
 
-	// Check limit
-	if count > int64(s.rateLimitPerMin) {
-		return fmt.Errorf("rate limit exceeded: max %d requests per minute", s.rateLimitPerMin)
+	return nil
+}
+
+// allow records a request against key, returning a RateLimitExceededError wrapped in an
+// apierror.RateLimitedError if it doesn't fit
+func (s *DeviceService) allow(ctx context.Context, key string, limit ratelimit.Limit, scope string) error {
+	result, err := s.limiter.Allow(ctx, key, limit)
+	if err != nil {
+		return err
+	}
+
+	if !result.Allowed {
+		return &apierror.RateLimitedError{
+			Cause: &RateLimitExceededError{
+				Scope:             scope,
+				Limit:             limit.PerMinute,
+				RetryAfterSeconds: int(math.Ceil(result.RetryAfter.Seconds())),
+			},
+			RetryAfter: result.RetryAfter,
+		}
 	}
 
 	return nil
 }
+
+// RateLimitStatus reports the remaining read and write budgets for an account
+func (s *DeviceService) RateLimitStatus(ctx context.Context, accountID string) (read, write *ratelimit.Result, err error) {
+	read, err = s.limiter.Peek(ctx, rateLimitKey(accountID, rateLimitRead), s.limits.Read)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	write, err = s.limiter.Peek(ctx, rateLimitKey(accountID, rateLimitWrite), s.limits.Write)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return read, write, nil
+}
+
+func (s *DeviceService) limitFor(kind rateLimitKind) ratelimit.Limit {
+	if kind == rateLimitWrite {
+		return s.limits.Write
+	}
+	return s.limits.Read
+}
+
+func rateLimitKey(accountID string, kind rateLimitKind) string {
+	return fmt.Sprintf("ratelimit:account:%s:%s", accountID, kind)
+}
+
+func userRateLimitKey(userID string) string {
+	return fmt.Sprintf("ratelimit:user:%s", userID)
+}
+
+func providerRateLimitKey(accountID string) string {
+	return fmt.Sprintf("ratelimit:account:%s", accountID)
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+const (
+	// defaultDeviceHistoryWindow is how far back a device's history goes when no start is given
+	defaultDeviceHistoryWindow = 7 * 24 * time.Hour
+	// stateEventWriteTimeout bounds the background write of a batch of device state events
+	stateEventWriteTimeout = 5 * time.Second
+)
+
+// actionState is the new state recorded for an action sent to a device
+type actionState struct {
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	Action     string                 `json:"action"`
+}
+
+// GetDeviceHistory returns up to limit of a device's state events created in [from, to),
+// oldest first. A zero to defaults to now and a zero from to a week before to.
+func (s *DeviceService) GetDeviceHistory(ctx context.Context, userID, accountID, deviceID string, from, to time.Time, limit int) (*models.DeviceHistory, error) {
+	account, err := s.accountRepo.FindByIDString(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	if account.OwnerUserID.String() != userID {
+		return nil, ErrAccountNotOwned
+	}
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultDeviceHistoryWindow)
+	}
+	if limit <= 0 {
+		limit = models.DefaultDeviceHistoryLimit
+	}
+	limit = min(limit, models.MaxDeviceHistoryLimit)
+
+	history := &models.DeviceHistory{From: from, To: to, Events: make([]*models.DeviceStateEvent, 0)}
+	if s.stateHistory == nil {
+		return history, nil
+	}
+
+	history.Events, err = s.stateHistory.FindByDeviceID(ctx, account.ID.String(), deviceID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device history: %w", err)
+	}
+	return history, nil
+}
+
+// diffDeviceState returns the names of the fields whose state differs between prev and next,
+// in alphabetical order
+func (s *DeviceService) diffDeviceState(prev, next *models.Device) []string {
+	changed := newDeviceSnapshot(next).changedFields(newDeviceSnapshot(prev))
+	return slices.Sorted(maps.Keys(changed))
+}
+
+// recordActionEvents records an action sent to the devices selector targets. Devices are
+// looked up in previous, the account's cached devices before the action, which also gives
+// their old state; an id selector is recorded even when the device was not cached.
+func (s *DeviceService) recordActionEvents(account *models.Account, selector string, action *models.ActionRequest, previous []*models.Device) {
+	if s.stateHistory == nil {
+		return
+	}
+
+	newState, err := json.Marshal(actionState{Action: action.Action, Parameters: action.Parameters})
+	if err != nil {
+		return
+	}
+
+	newEvent := func(deviceID string) *models.DeviceStateEvent {
+		return &models.DeviceStateEvent{
+			AccountID: account.ID,
+			DeviceID:  deviceID,
+			Provider:  account.Provider,
+			EventType: models.DeviceStateAction,
+			NewState:  newState,
+		}
+	}
+
+	events := make([]*models.DeviceStateEvent, 0)
+	for _, device := range selectDevices(previous, selector) {
+		event := newEvent(device.ID)
+		event.OldState, _ = json.Marshal(newDeviceSnapshot(device))
+		events = append(events, event)
+	}
+	if len(events) == 0 && strings.HasPrefix(selector, "id:") {
+		events = append(events, newEvent(strings.TrimPrefix(selector, "id:")))
+	}
+	s.recordStateEvents(events)
+}
+
+// recordStateChanges records the devices whose state differs between previous and devices
+func (s *DeviceService) recordStateChanges(account *models.Account, previous, devices []*models.Device) {
+	if s.stateHistory == nil {
+		return
+	}
+
+	previousByID := make(map[string]*models.Device, len(previous))
+	for _, device := range previous {
+		previousByID[device.ID] = device
+	}
+
+	events := make([]*models.DeviceStateEvent, 0)
+	for _, device := range devices {
+		prev, ok := previousByID[device.ID]
+		if !ok || len(s.diffDeviceState(prev, device)) == 0 {
+			continue
+		}
+
+		oldState, _ := json.Marshal(newDeviceSnapshot(prev))
+		newState, _ := json.Marshal(newDeviceSnapshot(device))
+		events = append(events, &models.DeviceStateEvent{
+			AccountID: account.ID,
+			DeviceID:  device.ID,
+			Provider:  account.Provider,
+			EventType: models.DeviceStateChanged,
+			OldState:  oldState,
+			NewState:  newState,
+		})
+	}
+	s.recordStateEvents(events)
+}
+
+// recordStateEvents writes events in the background, so recording history never slows down
+// or fails the request that produced them
+func (s *DeviceService) recordStateEvents(events []*models.DeviceStateEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	createdAt := time.Now()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), stateEventWriteTimeout)
+		defer cancel()
+
+		for _, event := range events {
+			event.CreatedAt = createdAt
+			if err := s.stateHistory.Record(ctx, event); err != nil {
+				logger.Error("Failed to record device state event", "error", err, "account_id", event.AccountID, "device_id", event.DeviceID)
+			}
+		}
+	}()
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// fakeDeviceStateRepository collects recorded events, which are written in the background
+type fakeDeviceStateRepository struct {
+	recorded chan *models.DeviceStateEvent
+	found    []*models.DeviceStateEvent
+	query    struct {
+		from, to time.Time
+		limit    int
+	}
+	mu sync.Mutex
+}
+
+func newFakeDeviceStateRepository() *fakeDeviceStateRepository {
+	return &fakeDeviceStateRepository{recorded: make(chan *models.DeviceStateEvent, 16)}
+}
+
+func (f *fakeDeviceStateRepository) Record(_ context.Context, event *models.DeviceStateEvent) error {
+	f.recorded <- event
+	return nil
+}
+
+func (f *fakeDeviceStateRepository) FindByDeviceID(_ context.Context, _, _ string, from, to time.Time, limit int) ([]*models.DeviceStateEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.query.from, f.query.to, f.query.limit = from, to, limit
+	return f.found, nil
+}
+
+// next waits for the next recorded event
+func (f *fakeDeviceStateRepository) next(t *testing.T) *models.DeviceStateEvent {
+	t.Helper()
+	select {
+	case event := <-f.recorded:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Expected a device state event to be recorded")
+		return nil
+	}
+}
+
+func newTestDeviceServiceWithHistory(t *testing.T, client providers.Client) (*DeviceService, *models.Account, *fakeDeviceStateRepository) {
+	t.Helper()
+
+	service, account := newTestDeviceService(t, client)
+	history := newFakeDeviceStateRepository()
+	service.stateHistory = history
+	return service, account, history
+}
+
+func TestDiffDeviceState(t *testing.T) {
+	service := &DeviceService{}
+	prev := &models.Device{ID: "d1", Label: "Kitchen", Power: models.PowerStateOff, Brightness: 0.5, Connected: true}
+
+	next := *prev
+	if changed := service.diffDeviceState(prev, &next); len(changed) != 0 {
+		t.Errorf("Expected no changed fields, got %v", changed)
+	}
+
+	next.Power = models.PowerStateOn
+	next.Brightness = 1
+	next.Color = &models.DeviceColor{Hue: 120}
+	want := []string{"brightness", "color", "power"}
+	if changed := service.diffDeviceState(prev, &next); !reflect.DeepEqual(changed, want) {
+		t.Errorf("Expected changed fields %v, got %v", want, changed)
+	}
+}
+
+func TestExecuteAction_RecordsStateEvent(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "d1", Label: "Bedroom", Power: "off", Connected: true})
+	service, account, history := newTestDeviceServiceWithHistory(t, client)
+	userID := account.OwnerUserID.String()
+
+	// Cache the devices, so the action's old state is known
+	if _, err := service.RefreshDevices(context.Background(), userID, account.ID.String()); err != nil {
+		t.Fatalf("RefreshDevices failed: %v", err)
+	}
+
+	action := &models.ActionRequest{Action: models.ActionPower, Parameters: map[string]interface{}{"state": "on"}}
+	if err := service.ExecuteAction(context.Background(), userID, account.ID.String(), "id:d1", action); err != nil {
+		t.Fatalf("ExecuteAction failed: %v", err)
+	}
+
+	event := history.next(t)
+	if event.EventType != models.DeviceStateAction || event.DeviceID != "d1" || event.AccountID != account.ID {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	var oldState, newState map[string]interface{}
+	if err := json.Unmarshal(event.OldState, &oldState); err != nil || oldState["power"] != "off" {
+		t.Errorf("Expected old power off, got %s", event.OldState)
+	}
+	if err := json.Unmarshal(event.NewState, &newState); err != nil || newState["action"] != models.ActionPower {
+		t.Errorf("Expected new state of the power action, got %s", event.NewState)
+	}
+}
+
+func TestRefreshDevices_RecordsStateChanges(t *testing.T) {
+	device := &providers.Device{ID: "d1", Label: "Bedroom", Power: "off", Connected: true}
+	unchanged := &providers.Device{ID: "d2", Label: "Hall", Power: "on", Connected: true}
+	client := newFakeProviderClient(device, unchanged)
+	service, account, history := newTestDeviceServiceWithHistory(t, client)
+	userID := account.OwnerUserID.String()
+
+	if _, err := service.RefreshDevices(context.Background(), userID, account.ID.String()); err != nil {
+		t.Fatalf("RefreshDevices failed: %v", err)
+	}
+
+	device.Power = "on"
+	if _, err := service.RefreshDevices(context.Background(), userID, account.ID.String()); err != nil {
+		t.Fatalf("RefreshDevices failed: %v", err)
+	}
+
+	event := history.next(t)
+	if event.EventType != models.DeviceStateChanged || event.DeviceID != "d1" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	select {
+	case extra := <-history.recorded:
+		t.Errorf("Expected only the changed device to be recorded, got %+v", extra)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestGetDeviceHistory(t *testing.T) {
+	service, account, history := newTestDeviceServiceWithHistory(t, newFakeProviderClient())
+	history.found = []*models.DeviceStateEvent{{DeviceID: "d1", EventType: models.DeviceStateAction}}
+
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	result, err := service.GetDeviceHistory(context.Background(), account.OwnerUserID.String(), account.ID.String(), "d1", time.Time{}, to, 1000)
+	if err != nil {
+		t.Fatalf("GetDeviceHistory failed: %v", err)
+	}
+
+	if len(result.Events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(result.Events))
+	}
+	if history.query.limit != models.MaxDeviceHistoryLimit {
+		t.Errorf("Expected limit clamped to %d, got %d", models.MaxDeviceHistoryLimit, history.query.limit)
+	}
+	if want := to.Add(-defaultDeviceHistoryWindow); !history.query.from.Equal(want) {
+		t.Errorf("Expected from to default to %v, got %v", want, history.query.from)
+	}
+
+	if _, err := service.GetDeviceHistory(context.Background(), "someone-else", account.ID.String(), "d1", time.Time{}, to, 10); err != ErrAccountNotOwned {
+		t.Errorf("Expected ErrAccountNotOwned, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+// DeviceLabelService manages users' nicknames for their devices
+type DeviceLabelService struct {
+	repo repository.DeviceLabelRepositoryInterface
+}
+
+// NewDeviceLabelService creates a new device label service
+func NewDeviceLabelService(repo repository.DeviceLabelRepositoryInterface) *DeviceLabelService {
+	return &DeviceLabelService{repo: repo}
+}
+
+// SetLabel stores a user's label for a device of a provider, replacing any set before
+func (s *DeviceLabelService) SetLabel(ctx context.Context, userID uuid.UUID, provider, deviceID, label string) (*models.DeviceLabel, error) {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return nil, &apierror.BadRequestError{Message: "label is required"}
+	}
+	if utf8.RuneCountInString(label) > models.MaxDeviceLabelLength {
+		return nil, &apierror.BadRequestError{Message: fmt.Sprintf("label must be at most %d characters", models.MaxDeviceLabelLength)}
+	}
+
+	deviceLabel := &models.DeviceLabel{UserID: userID, Provider: provider, DeviceID: deviceID, Label: label}
+	if err := s.repo.Upsert(ctx, deviceLabel); err != nil {
+		return nil, err
+	}
+	return deviceLabel, nil
+}
+
+// DeleteLabel removes a user's label for a device, restoring the provider's label
+func (s *DeviceLabelService) DeleteLabel(ctx context.Context, userID uuid.UUID, provider, deviceID string) error {
+	return s.repo.Delete(ctx, userID, provider, deviceID)
+}
+
+// labelsByDevice returns a user's labels for the devices of a provider, by device ID
+func (s *DeviceLabelService) labelsByDevice(ctx context.Context, userID uuid.UUID, provider string) (map[string]string, error) {
+	labels, err := s.repo.GetByUserAndProvider(ctx, userID, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	byDevice := make(map[string]string, len(labels))
+	for _, label := range labels {
+		byDevice[label.DeviceID] = label.Label
+	}
+	return byDevice, nil
+}
+
+// applyDeviceLabels returns devices with the labels the user set replacing the providers'
+// labels, which are kept in the devices' metadata, and whether any label was replaced.
+// Relabeled devices are copies, since devices may be shared with other callers. Labels
+// that cannot be loaded are skipped, leaving the providers' labels.
+func (s *DeviceService) applyDeviceLabels(ctx context.Context, userID string, devices []*models.Device) ([]*models.Device, bool) {
+	if s.labels == nil || len(devices) == 0 {
+		return devices, false
+	}
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return devices, false
+	}
+
+	labels := make(map[string]map[string]string)
+	for _, device := range devices {
+		if _, ok := labels[device.Provider]; ok {
+			continue
+		}
+		byDevice, err := s.labels.labelsByDevice(ctx, userUUID, device.Provider)
+		if err != nil {
+			logger.WithContext(ctx).Warn("Failed to load device labels", "error", err, "provider", device.Provider)
+		}
+		labels[device.Provider] = byDevice
+	}
+
+	labeled := make([]*models.Device, len(devices))
+	relabeled := false
+	for i, device := range devices {
+		label, ok := labels[device.Provider][device.ID]
+		if !ok {
+			labeled[i] = device
+			continue
+		}
+
+		relabeledDevice := *device
+		relabeledDevice.Metadata = maps.Clone(device.Metadata)
+		if relabeledDevice.Metadata == nil {
+			relabeledDevice.Metadata = make(map[string]interface{})
+		}
+		relabeledDevice.Metadata[models.DeviceMetadataProviderLabel] = device.Label
+		relabeledDevice.Label = label
+		labeled[i] = &relabeledDevice
+		relabeled = true
+	}
+	return labeled, relabeled
+}
+
+// applyDeviceLabel returns device with the label the user set for it, if any
+func (s *DeviceService) applyDeviceLabel(ctx context.Context, userID string, device *models.Device) *models.Device {
+	labeled, _ := s.applyDeviceLabels(ctx, userID, []*models.Device{device})
+	return labeled[0]
+}
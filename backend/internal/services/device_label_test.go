@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// mockDeviceLabelRepository is an in-memory device label repository for testing
+type mockDeviceLabelRepository struct {
+	labels map[string]*models.DeviceLabel
+}
+
+func newMockDeviceLabelRepository() *mockDeviceLabelRepository {
+	return &mockDeviceLabelRepository{labels: make(map[string]*models.DeviceLabel)}
+}
+
+func deviceLabelKey(userID uuid.UUID, provider, deviceID string) string {
+	return userID.String() + "|" + provider + "|" + deviceID
+}
+
+func (m *mockDeviceLabelRepository) Upsert(_ context.Context, label *models.DeviceLabel) error {
+	stored := *label
+	m.labels[deviceLabelKey(label.UserID, label.Provider, label.DeviceID)] = &stored
+	return nil
+}
+
+func (m *mockDeviceLabelRepository) Get(_ context.Context, userID uuid.UUID, provider, deviceID string) (*models.DeviceLabel, error) {
+	label, ok := m.labels[deviceLabelKey(userID, provider, deviceID)]
+	if !ok {
+		return nil, repository.ErrDeviceLabelNotFound
+	}
+	return label, nil
+}
+
+func (m *mockDeviceLabelRepository) Delete(_ context.Context, userID uuid.UUID, provider, deviceID string) error {
+	key := deviceLabelKey(userID, provider, deviceID)
+	if _, ok := m.labels[key]; !ok {
+		return repository.ErrDeviceLabelNotFound
+	}
+	delete(m.labels, key)
+	return nil
+}
+
+func (m *mockDeviceLabelRepository) GetByUserAndProvider(_ context.Context, userID uuid.UUID, provider string) ([]*models.DeviceLabel, error) {
+	var labels []*models.DeviceLabel
+	for _, label := range m.labels {
+		if label.UserID == userID && label.Provider == provider {
+			labels = append(labels, label)
+		}
+	}
+	return labels, nil
+}
+
+// newTestLabeledDeviceService returns a DeviceService relabeling devices from an in-memory repository
+func newTestLabeledDeviceService(t *testing.T, client providers.Client) (*DeviceService, *DeviceLabelService, *models.Account) {
+	t.Helper()
+	service, account := newTestDeviceService(t, client)
+	service.labels = NewDeviceLabelService(newMockDeviceLabelRepository())
+	return service, service.labels, account
+}
+
+func TestSetLabel_Validation(t *testing.T) {
+	labels := NewDeviceLabelService(newMockDeviceLabelRepository())
+	ctx := context.Background()
+
+	var badRequest *apierror.BadRequestError
+	for _, label := range []string{"", "   ", strings.Repeat("é", models.MaxDeviceLabelLength+1)} {
+		if _, err := labels.SetLabel(ctx, uuid.New(), "lifx", "d1", label); !errors.As(err, &badRequest) {
+			t.Errorf("Expected a BadRequestError for %q, got %v", label, err)
+		}
+	}
+
+	label, err := labels.SetLabel(ctx, uuid.New(), "lifx", "d1", "  Nightstand Right ")
+	if err != nil {
+		t.Fatalf("SetLabel failed: %v", err)
+	}
+	if label.Label != "Nightstand Right" {
+		t.Errorf("Expected the trimmed label, got %q", label.Label)
+	}
+}
+
+func TestListAccountDevices_AppliesUserLabels(t *testing.T) {
+	client := newFakeProviderClient(
+		&providers.Device{ID: "d1", Label: "LIFX Bulb 1"},
+		&providers.Device{ID: "d2", Label: "LIFX Bulb 2"},
+	)
+	service, labels, account := newTestLabeledDeviceService(t, client)
+	ctx := context.Background()
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	if _, err := labels.SetLabel(ctx, account.OwnerUserID, account.Provider, "d2", "Nightstand Right"); err != nil {
+		t.Fatalf("SetLabel failed: %v", err)
+	}
+	// Labels of another user or provider are ignored
+	if _, err := labels.SetLabel(ctx, uuid.New(), account.Provider, "d1", "Someone Else's"); err != nil {
+		t.Fatalf("SetLabel failed: %v", err)
+	}
+	if _, err := labels.SetLabel(ctx, account.OwnerUserID, "hue", "d1", "Other Provider"); err != nil {
+		t.Fatalf("SetLabel failed: %v", err)
+	}
+
+	page, err := service.ListAccountDevices(ctx, userID, accountID, models.DeviceFilter{}, models.PaginationOptions{})
+	if err != nil {
+		t.Fatalf("ListAccountDevices failed: %v", err)
+	}
+	if page.Devices[0].Label != "LIFX Bulb 1" || page.Devices[0].Metadata[models.DeviceMetadataProviderLabel] != nil {
+		t.Errorf("Expected the first device to keep its provider label, got %+v", page.Devices[0])
+	}
+	relabeled := page.Devices[1]
+	if relabeled.Label != "Nightstand Right" || relabeled.Metadata[models.DeviceMetadataProviderLabel] != "LIFX Bulb 2" {
+		t.Errorf("Expected the user's label with the provider's in metadata, got %+v", relabeled)
+	}
+	if page.ETag != "" {
+		t.Errorf("Expected the cached ETag not to be reused for a relabeled list, got %q", page.ETag)
+	}
+
+	// The cached list keeps the provider labels
+	cached, _, err := service.getCachedDevices(ctx, accountID)
+	if err != nil || cached[1].Label != "LIFX Bulb 2" {
+		t.Errorf("Expected the provider label in the cache, got %+v, %v", cached, err)
+	}
+
+	device, err := service.GetCachedDevice(ctx, userID, accountID, "d2")
+	if err != nil || device.Label != "Nightstand Right" {
+		t.Errorf("Expected GetCachedDevice to apply the label, got %+v, %v", device, err)
+	}
+
+	// Removing the label restores the provider's
+	if err := labels.DeleteLabel(ctx, account.OwnerUserID, account.Provider, "d2"); err != nil {
+		t.Fatalf("DeleteLabel failed: %v", err)
+	}
+	if err := labels.DeleteLabel(ctx, account.OwnerUserID, account.Provider, "d2"); !errors.Is(err, repository.ErrDeviceLabelNotFound) {
+		t.Errorf("Expected ErrDeviceLabelNotFound deleting twice, got %v", err)
+	}
+	device, err = service.GetCachedDevice(ctx, userID, accountID, "d2")
+	if err != nil || device.Label != "LIFX Bulb 2" {
+		t.Errorf("Expected the provider label back, got %+v, %v", device, err)
+	}
+}
+
+func TestGetLiveDevice_CachesProviderLabel(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "d1", Label: "LIFX Bulb 1"})
+	service, labels, account := newTestLabeledDeviceService(t, client)
+	ctx := context.Background()
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	if _, err := service.ListAccountDevices(ctx, userID, accountID, models.DeviceFilter{}, models.PaginationOptions{}); err != nil {
+		t.Fatalf("ListAccountDevices failed: %v", err)
+	}
+	if _, err := labels.SetLabel(ctx, account.OwnerUserID, account.Provider, "d1", "Desk"); err != nil {
+		t.Fatalf("SetLabel failed: %v", err)
+	}
+
+	device, err := service.GetLiveDevice(ctx, userID, accountID, "d1")
+	if err != nil {
+		t.Fatalf("GetLiveDevice failed: %v", err)
+	}
+	if device.Label != "Desk" {
+		t.Errorf("Expected the user's label, got %q", device.Label)
+	}
+
+	cached, _, err := service.getCachedDevices(ctx, accountID)
+	if err != nil || cached[0].Label != "LIFX Bulb 1" || cached[0].Metadata[models.DeviceMetadataProviderLabel] != nil {
+		t.Errorf("Expected the provider label in the cache, got %+v, %v", cached, err)
+	}
+}
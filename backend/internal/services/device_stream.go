@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/logger"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// streamSubscriberBuffer is how many change events a slow streaming client may fall behind
+// before further events are dropped for it
+const streamSubscriberBuffer = 32
+
+// deviceSnapshot is the streamed state of a device, as last seen by the poller
+type deviceSnapshot struct {
+	Color      *models.DeviceColor `json:"color,omitempty"`
+	Label      string              `json:"label"`
+	Power      string              `json:"power"`
+	Brightness float64             `json:"brightness"`
+	Connected  bool                `json:"connected"`
+}
+
+func newDeviceSnapshot(device *models.Device) deviceSnapshot {
+	return deviceSnapshot{
+		Color:      device.Color,
+		Label:      device.Label,
+		Power:      device.Power,
+		Brightness: device.Brightness,
+		Connected:  device.Connected,
+	}
+}
+
+// fields returns every streamed field of the snapshot
+func (snapshot deviceSnapshot) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"power":      snapshot.Power,
+		"brightness": snapshot.Brightness,
+		"connected":  snapshot.Connected,
+		"label":      snapshot.Label,
+		"color":      snapshot.Color,
+	}
+}
+
+// changedFields returns the fields of the snapshot that differ from previous
+func (snapshot deviceSnapshot) changedFields(previous deviceSnapshot) map[string]interface{} {
+	changed := snapshot.fields()
+	if snapshot.Power == previous.Power {
+		delete(changed, "power")
+	}
+	if snapshot.Brightness == previous.Brightness {
+		delete(changed, "brightness")
+	}
+	if snapshot.Connected == previous.Connected {
+		delete(changed, "connected")
+	}
+	if snapshot.Label == previous.Label {
+		delete(changed, "label")
+	}
+	if sameColor(snapshot.Color, previous.Color) {
+		delete(changed, "color")
+	}
+	return changed
+}
+
+func sameColor(a, b *models.DeviceColor) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func deviceStateKey(accountID string) string {
+	return fmt.Sprintf("devices:state:account:%s", accountID)
+}
+
+// SubscribeDeviceChanges streams the device state changes of an account. Every client
+// streaming the same account shares a single polling goroutine, which stops once the last
+// client unsubscribes. The returned channel is closed if polling stops on its own, e.g.
+// because the provider rejected the account's token.
+func (s *DeviceService) SubscribeDeviceChanges(ctx context.Context, userID, accountID string) (<-chan models.DeviceChangeEvent, func(), error) {
+	// Get account and verify ownership
+	account, err := s.accountRepo.FindByIDString(ctx, accountID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	if account.OwnerUserID.String() != userID {
+		return nil, nil, ErrAccountNotOwned
+	}
+
+	// Get decrypted token
+	token, err := s.accountRepo.GetDecryptedToken(ctx, accountID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	changes, unsubscribe := s.streams.subscribe(accountID, func(ctx context.Context, eventCh chan<- models.DeviceChangeEvent) {
+		err := s.StreamDeviceChanges(ctx, accountID, token, account.Provider, eventCh)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.Warn("Device change stream stopped", "error", err, "account_id", accountID)
+		}
+	})
+	return changes, unsubscribe, nil
+}
+
+// StreamDeviceChanges polls an account's devices until ctx is cancelled, sending an event
+// to eventCh for every device whose state differs from the previous poll. The last polled
+// state is kept in Redis, so a stream restarted within a polling interval picks up where
+// the previous one stopped. Polling stops early if the provider rejects the token.
+func (s *DeviceService) StreamDeviceChanges(ctx context.Context, accountID, token, provider string, eventCh chan<- models.DeviceChangeEvent) error {
+	accountUUID, err := uuid.Parse(accountID)
+	if err != nil {
+		return fmt.Errorf("invalid account ID: %w", err)
+	}
+	account := &models.Account{ID: accountUUID, Provider: provider}
+
+	client, err := s.newClient(providers.Provider(provider))
+	if err != nil {
+		return fmt.Errorf("failed to create provider client: %w", err)
+	}
+
+	ticker := time.NewTicker(s.streamEvery)
+	defer ticker.Stop()
+
+	for {
+		if err := s.pollDeviceChanges(ctx, account, client, token, eventCh); err != nil {
+			if errors.Is(err, providers.ErrUnauthorized) || errors.Is(err, context.Canceled) {
+				return err
+			}
+			// Transient provider failures are retried on the next tick
+			logger.Warn("Failed to poll device changes", "error", err, "account_id", accountID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollDeviceChanges fetches an account's devices once and sends an event for each device
+// that changed since the previous poll. The first poll only records a baseline.
+func (s *DeviceService) pollDeviceChanges(ctx context.Context, account *models.Account, client providers.Client, token string, eventCh chan<- models.DeviceChangeEvent) error {
+	accountID := account.ID.String()
+
+	var providerDevices []*providers.Device
+	err := s.callProvider(ctx, account, "list_devices", "all", func(ctx context.Context) (callErr error) {
+		providerDevices, callErr = providers.WithContext(ctx, client).ListDevices(token)
+		return callErr
+	})
+	if err != nil {
+		s.validations.invalidateOnUnauthorized(ctx, accountID, err)
+		return fmt.Errorf("failed to list devices from provider: %w", err)
+	}
+
+	current := make(map[string]deviceSnapshot, len(providerDevices))
+	changes := make([]models.DeviceChangeEvent, 0)
+	previous, baseline := s.getDeviceSnapshots(ctx, accountID)
+	for _, pd := range providerDevices {
+		snapshot := newDeviceSnapshot(s.convertProviderDevice(pd, account))
+		current[pd.ID] = snapshot
+
+		if !baseline {
+			continue
+		}
+		// Devices that just appeared report every field
+		changed := snapshot.fields()
+		if last, ok := previous[pd.ID]; ok {
+			changed = snapshot.changedFields(last)
+		}
+		if len(changed) > 0 {
+			changes = append(changes, models.DeviceChangeEvent{ID: pd.ID, ChangedFields: changed})
+		}
+	}
+
+	if err := s.setDeviceSnapshots(ctx, accountID, current); err != nil {
+		// Log error but keep streaming; the next poll diffs against the same baseline
+		logger.Warn("Failed to store device state", "error", err, "account_id", accountID)
+	}
+
+	for _, change := range changes {
+		select {
+		case eventCh <- change:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// getDeviceSnapshots retrieves the device states recorded by the previous poll, reporting
+// whether there was one
+func (s *DeviceService) getDeviceSnapshots(ctx context.Context, accountID string) (map[string]deviceSnapshot, bool) {
+	data, err := s.cache.Get(ctx, deviceStateKey(accountID)).Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	var snapshots map[string]deviceSnapshot
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return nil, false
+	}
+	return snapshots, true
+}
+
+// setDeviceSnapshots records the device states of a poll. They expire after two polling
+// intervals, so a stream restarted much later starts from a fresh baseline.
+func (s *DeviceService) setDeviceSnapshots(ctx context.Context, accountID string, snapshots map[string]deviceSnapshot) error {
+	data, err := json.Marshal(snapshots)
+	if err != nil {
+		return err
+	}
+
+	return s.cache.Set(ctx, deviceStateKey(accountID), data, 2*s.streamEvery).Err()
+}
+
+// deviceStreamHub fans the changes polled for an account out to every client streaming it
+type deviceStreamHub struct {
+	streams map[string]*deviceStream
+	mu      sync.Mutex
+}
+
+// deviceStream is the polling goroutine of one account and the clients subscribed to it
+type deviceStream struct {
+	subscribers map[chan models.DeviceChangeEvent]struct{}
+	cancel      context.CancelFunc
+}
+
+func newDeviceStreamHub() *deviceStreamHub {
+	return &deviceStreamHub{
+		streams: make(map[string]*deviceStream),
+	}
+}
+
+// subscribe adds a client to an account's stream, starting poll if it is the first one.
+// The returned function unsubscribes the client and is safe to call more than once.
+func (h *deviceStreamHub) subscribe(accountID string, poll func(ctx context.Context, eventCh chan<- models.DeviceChangeEvent)) (<-chan models.DeviceChangeEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stream, ok := h.streams[accountID]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		stream = &deviceStream{
+			subscribers: make(map[chan models.DeviceChangeEvent]struct{}),
+			cancel:      cancel,
+		}
+		h.streams[accountID] = stream
+
+		events := make(chan models.DeviceChangeEvent)
+		go func() {
+			defer close(events)
+			poll(ctx, events)
+		}()
+		go h.fanOut(accountID, stream, events)
+	}
+
+	subscriber := make(chan models.DeviceChangeEvent, streamSubscriberBuffer)
+	stream.subscribers[subscriber] = struct{}{}
+
+	return subscriber, func() { h.unsubscribe(accountID, stream, subscriber) }
+}
+
+// fanOut copies polled events to every subscriber without blocking on slow clients, and
+// closes the subscribers once polling stops
+func (h *deviceStreamHub) fanOut(accountID string, stream *deviceStream, events <-chan models.DeviceChangeEvent) {
+	for event := range events {
+		h.mu.Lock()
+		for subscriber := range stream.subscribers {
+			select {
+			case subscriber <- event:
+			default:
+			}
+		}
+		h.mu.Unlock()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.streams[accountID] == stream {
+		delete(h.streams, accountID)
+	}
+	for subscriber := range stream.subscribers {
+		close(subscriber)
+		delete(stream.subscribers, subscriber)
+	}
+	stream.cancel()
+}
+
+// unsubscribe removes a client from an account's stream, stopping polling once no client remains
+func (h *deviceStreamHub) unsubscribe(accountID string, stream *deviceStream, subscriber chan models.DeviceChangeEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := stream.subscribers[subscriber]; !ok {
+		return
+	}
+	delete(stream.subscribers, subscriber)
+	close(subscriber)
+
+	if len(stream.subscribers) == 0 {
+		stream.cancel()
+		if h.streams[accountID] == stream {
+			delete(h.streams, accountID)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+func TestPollDeviceChanges_EmitsChangedFields(t *testing.T) {
+	client := newFakeProviderClient(
+		&providers.Device{ID: "bulb-1", Label: "Lamp", Power: models.PowerStateOn, Brightness: 0.5, Connected: true},
+		&providers.Device{ID: "bulb-2", Label: "Desk", Power: models.PowerStateOff, Brightness: 0.2, Connected: true},
+	)
+	service, account := newTestDeviceService(t, client)
+	eventCh := make(chan models.DeviceChangeEvent, 10)
+
+	// The first poll only records a baseline
+	if err := service.pollDeviceChanges(context.Background(), account, client, "test-token", eventCh); err != nil {
+		t.Fatalf("First poll failed: %v", err)
+	}
+	if len(eventCh) != 0 {
+		t.Fatalf("Expected no events for the baseline poll, got %d", len(eventCh))
+	}
+
+	client.devices[0].Power = models.PowerStateOff
+	client.devices[0].Brightness = 0.8
+	client.devices = append(client.devices, &providers.Device{ID: "bulb-3", Label: "Hall", Power: models.PowerStateOn})
+
+	if err := service.pollDeviceChanges(context.Background(), account, client, "test-token", eventCh); err != nil {
+		t.Fatalf("Second poll failed: %v", err)
+	}
+	close(eventCh)
+
+	changes := make(map[string]map[string]interface{})
+	for change := range eventCh {
+		changes[change.ID] = change.ChangedFields
+	}
+
+	if len(changes) != 2 {
+		t.Fatalf("Expected changes for 2 devices, got %v", changes)
+	}
+	if fields := changes["bulb-1"]; len(fields) != 2 || fields["power"] != models.PowerStateOff || fields["brightness"] != 0.8 {
+		t.Errorf("Expected power and brightness changes for bulb-1, got %v", fields)
+	}
+	if fields := changes["bulb-3"]; len(fields) != 5 {
+		t.Errorf("Expected every field for new bulb-3, got %v", fields)
+	}
+}
+
+func TestDeviceStreamHub_SharesPolling(t *testing.T) {
+	hub := newDeviceStreamHub()
+	started := make(chan struct{}, 2)
+	stopped := make(chan struct{})
+	poll := func(ctx context.Context, eventCh chan<- models.DeviceChangeEvent) {
+		started <- struct{}{}
+		eventCh <- models.DeviceChangeEvent{ID: "bulb-1"}
+		<-ctx.Done()
+		close(stopped)
+	}
+
+	first, unsubscribeFirst := hub.subscribe("account-1", poll)
+	second, unsubscribeSecond := hub.subscribe("account-1", poll)
+
+	for _, ch := range []<-chan models.DeviceChangeEvent{first, second} {
+		select {
+		case event := <-ch:
+			if event.ID != "bulb-1" {
+				t.Errorf("Expected bulb-1 event, got %+v", event)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected both subscribers to receive the event")
+		}
+	}
+	if len(started) != 1 {
+		t.Errorf("Expected one polling goroutine, got %d", len(started))
+	}
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	select {
+	case <-stopped:
+		t.Fatal("Expected polling to continue while a subscriber remains")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	unsubscribeSecond()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Expected polling to stop after the last subscriber left")
+	}
+}
+
+func TestSubscribeDeviceChanges_OtherUser(t *testing.T) {
+	service, account := newTestDeviceService(t, newFakeProviderClient())
+
+	_, _, err := service.SubscribeDeviceChanges(context.Background(), uuid.NewString(), account.ID.String())
+	if !errors.Is(err, ErrAccountNotOwned) {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+}
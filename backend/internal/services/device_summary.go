@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+)
+
+// deviceSummaryTTL is how long a user's device summary is cached
+const deviceSummaryTTL = 60 * time.Second
+
+func deviceSummaryKey(userID string) string {
+	return fmt.Sprintf("summary:user:%s", userID)
+}
+
+// GetDeviceSummary aggregates the state of the devices of all of the user's accounts. Accounts
+// whose devices cannot be fetched are counted in AccountsCount but contribute no devices.
+func (s *DeviceService) GetDeviceSummary(ctx context.Context, userID string) (*models.DeviceSummary, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	if summary, err := s.getCachedDeviceSummary(ctx, userID); err == nil {
+		return summary, nil
+	}
+
+	accounts, err := s.accountRepo.FindByUserID(ctx, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounts: %w", err)
+	}
+
+	var devices []*models.Device
+	complete := true
+	for _, result := range s.fetchAccountsDevices(ctx, userID, accounts) {
+		if result.err != nil {
+			complete = false
+			continue
+		}
+		devices = append(devices, result.devices...)
+	}
+
+	summary := summarizeDevices(devices)
+	summary.AccountsCount = len(accounts)
+
+	// A partial summary is not cached, so a transient provider failure is not pinned
+	if complete {
+		if err := s.setCachedDeviceSummary(ctx, userID, summary); err != nil {
+			// Log error but don't fail the request
+			_ = err
+		}
+	}
+
+	return summary, nil
+}
+
+// summarizeDevices aggregates the state of devices
+func summarizeDevices(devices []*models.Device) *models.DeviceSummary {
+	summary := &models.DeviceSummary{
+		ByProvider: make(map[string]int),
+		ByGroup:    make(map[string]int),
+		Total:      len(devices),
+	}
+
+	var brightness float64
+	for _, device := range devices {
+		summary.ByProvider[device.Provider]++
+		if device.Group != nil && device.Group.Name != "" {
+			summary.ByGroup[device.Group.Name]++
+		}
+
+		switch {
+		case !device.Reachable:
+			summary.Unreachable++
+		case device.IsOn():
+			summary.Online++
+			brightness += device.Brightness
+		default:
+			summary.Offline++
+		}
+	}
+
+	if summary.Online > 0 {
+		summary.AvgBrightness = brightness / float64(summary.Online)
+	}
+	return summary
+}
+
+// getCachedDeviceSummary retrieves a user's device summary from cache
+func (s *DeviceService) getCachedDeviceSummary(ctx context.Context, userID string) (*models.DeviceSummary, error) {
+	data, err := s.cache.Get(ctx, deviceSummaryKey(userID)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var summary models.DeviceSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
+
+// setCachedDeviceSummary stores a user's device summary in cache
+func (s *DeviceService) setCachedDeviceSummary(ctx context.Context, userID string, summary *models.DeviceSummary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	return s.cache.Set(ctx, deviceSummaryKey(userID), data, deviceSummaryTTL).Err()
+}
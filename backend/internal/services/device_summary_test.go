@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+func TestSummarizeDevices(t *testing.T) {
+	kitchen := &models.DeviceGroup{ID: "g1", Name: "Kitchen"}
+	bedroom := &models.DeviceGroup{ID: "g2", Name: "Bedroom"}
+
+	tests := []struct {
+		want    *models.DeviceSummary
+		name    string
+		devices []*models.Device
+	}{
+		{
+			name:    "empty",
+			devices: nil,
+			want: &models.DeviceSummary{
+				ByProvider: map[string]int{},
+				ByGroup:    map[string]int{},
+			},
+		},
+		{
+			name: "all off",
+			devices: []*models.Device{
+				{Provider: "lifx", Power: models.PowerStateOff, Brightness: 0.8, Reachable: true, Group: kitchen},
+				{Provider: "lifx", Power: models.PowerStateOff, Brightness: 0.3, Reachable: true, Group: kitchen},
+				{Provider: "hue", Power: models.PowerStateOff, Reachable: true},
+			},
+			want: &models.DeviceSummary{
+				ByProvider: map[string]int{"lifx": 2, "hue": 1},
+				ByGroup:    map[string]int{"Kitchen": 2},
+				Total:      3,
+				Offline:    3,
+			},
+		},
+		{
+			name: "mixed",
+			devices: []*models.Device{
+				{Provider: "lifx", Power: models.PowerStateOn, Brightness: 1, Reachable: true, Group: kitchen},
+				{Provider: "lifx", Power: models.PowerStateOn, Brightness: 0.5, Reachable: true, Group: bedroom},
+				{Provider: "hue", Power: models.PowerStateOff, Brightness: 0.9, Reachable: true, Group: bedroom},
+				{Provider: "hue", Power: models.PowerStateOn, Brightness: 0.1, Reachable: false},
+				{Provider: "nanoleaf", Power: models.PowerStateOn, Brightness: 0.3, Reachable: true},
+			},
+			want: &models.DeviceSummary{
+				ByProvider:    map[string]int{"lifx": 2, "hue": 2, "nanoleaf": 1},
+				ByGroup:       map[string]int{"Kitchen": 1, "Bedroom": 2},
+				Total:         5,
+				Online:        3,
+				Offline:       1,
+				Unreachable:   1,
+				AvgBrightness: 0.6,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarizeDevices(tt.devices)
+
+			if math.Abs(got.AvgBrightness-tt.want.AvgBrightness) > 1e-9 {
+				t.Errorf("Expected average brightness %v, got %v", tt.want.AvgBrightness, got.AvgBrightness)
+			}
+			got.AvgBrightness = tt.want.AvgBrightness
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetDeviceSummary_CachedUntilInvalidated(t *testing.T) {
+	client := newFakeProviderClient(
+		&providers.Device{ID: "bulb-1", Power: models.PowerStateOn, Brightness: 0.4, Reachable: true},
+		&providers.Device{ID: "bulb-2", Power: models.PowerStateOff, Reachable: true},
+	)
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+	ctx := context.Background()
+
+	summary, err := service.GetDeviceSummary(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetDeviceSummary failed: %v", err)
+	}
+	if summary.Total != 2 || summary.Online != 1 || summary.Offline != 1 || summary.AccountsCount != 1 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if summary.ByProvider[string(providers.ProviderLIFX)] != 2 {
+		t.Errorf("Expected 2 LIFX devices, got %v", summary.ByProvider)
+	}
+
+	if exists, _ := service.cache.Exists(ctx, deviceSummaryKey(userID)).Result(); exists != 1 {
+		t.Fatal("Expected the summary to be cached")
+	}
+
+	action := &models.ActionRequest{Action: models.ActionPower, Parameters: map[string]interface{}{"state": models.PowerStateOn}}
+	if err := service.ExecuteAction(ctx, userID, accountID, "all", action); err != nil {
+		t.Fatalf("ExecuteAction failed: %v", err)
+	}
+
+	if exists, _ := service.cache.Exists(ctx, deviceSummaryKey(userID)).Result(); exists != 0 {
+		t.Error("Expected the action to invalidate the cached summary")
+	}
+
+	if _, err := service.GetDeviceSummary(ctx, userID); err != nil {
+		t.Fatalf("GetDeviceSummary failed: %v", err)
+	}
+	if client.callCount("ListDevices") != 2 {
+		t.Errorf("Expected devices to be fetched again after invalidation, got %d fetches", client.callCount("ListDevices"))
+	}
+}
+
+func TestGetDeviceSummary_NotCachedWhenAccountFails(t *testing.T) {
+	client := newFakeProviderClient()
+	client.errs["ListDevices"] = []error{&providers.StatusError{Provider: providers.ProviderLIFX, StatusCode: 500}}
+	service, account := newTestDeviceService(t, client)
+	userID := account.OwnerUserID.String()
+	ctx := context.Background()
+
+	summary, err := service.GetDeviceSummary(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetDeviceSummary failed: %v", err)
+	}
+	if summary.Total != 0 || summary.AccountsCount != 1 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if exists, _ := service.cache.Exists(ctx, deviceSummaryKey(userID)).Result(); exists != 0 {
+		t.Error("Expected a partial summary not to be cached")
+	}
+}
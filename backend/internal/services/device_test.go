@@ -0,0 +1,1106 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/events"
+	"github.com/lightshare/backend/pkg/providers"
+	"github.com/lightshare/backend/pkg/ratelimit"
+)
+
+// fakeProviderClient is a configurable providers.Client for device service tests.
+// Errors queued in errs are returned by successive calls to the named method.
+type fakeProviderClient struct {
+	calls     map[string]int
+	errs      map[string][]error
+	devices   []*providers.Device
+	selectors []string
+	delay     time.Duration // Latency added to every ListDevices call
+	mu        sync.Mutex
+}
+
+func newFakeProviderClient(devices ...*providers.Device) *fakeProviderClient {
+	return &fakeProviderClient{
+		calls:   make(map[string]int),
+		errs:    make(map[string][]error),
+		devices: devices,
+	}
+}
+
+// record counts a call to method and returns the next queued error, if any
+func (f *fakeProviderClient) record(method string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls[method]++
+	return f.nextErr(method)
+}
+
+// recordControl records a control call along with the selector it targeted
+func (f *fakeProviderClient) recordControl(method, selector string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls[method]++
+	f.selectors = append(f.selectors, selector)
+	return f.nextErr(method)
+}
+
+// nextErr pops the next queued error for method; callers must hold mu
+func (f *fakeProviderClient) nextErr(method string) error {
+	if queued := f.errs[method]; len(queued) > 0 {
+		f.errs[method] = queued[1:]
+		return queued[0]
+	}
+	return nil
+}
+
+func (f *fakeProviderClient) callCount(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+func (f *fakeProviderClient) ValidateToken(_ string) (*providers.AccountInfo, error) {
+	if err := f.record("ValidateToken"); err != nil {
+		return nil, err
+	}
+	return &providers.AccountInfo{ProviderAccountID: "fake-account", Metadata: map[string]interface{}{}}, nil
+}
+
+func (f *fakeProviderClient) GetAccountInfo(token string) (*providers.AccountInfo, error) {
+	return f.ValidateToken(token)
+}
+
+func (f *fakeProviderClient) ListDevices(_ string) ([]*providers.Device, error) {
+	time.Sleep(f.delay)
+	if err := f.record("ListDevices"); err != nil {
+		return nil, err
+	}
+	return f.devices, nil
+}
+
+func (f *fakeProviderClient) GetDevice(_, deviceID string) (*providers.Device, error) {
+	if err := f.record("GetDevice"); err != nil {
+		return nil, err
+	}
+	for _, d := range f.devices {
+		if d.ID == deviceID {
+			return d, nil
+		}
+	}
+	return nil, errors.New("device not found")
+}
+
+func (f *fakeProviderClient) SetPower(_, selector string, _ bool, _ float64) error {
+	return f.recordControl("SetPower", selector)
+}
+
+func (f *fakeProviderClient) SetBrightness(_, selector string, _, _ float64) error {
+	return f.recordControl("SetBrightness", selector)
+}
+
+func (f *fakeProviderClient) SetColor(_, selector string, _ *providers.DeviceColor, _ float64) error {
+	return f.recordControl("SetColor", selector)
+}
+
+func (f *fakeProviderClient) SetColorTemperature(_, selector string, _ int, _ float64) error {
+	return f.recordControl("SetColorTemperature", selector)
+}
+
+func (f *fakeProviderClient) SetInfrared(_, selector string, _ float64) error {
+	return f.recordControl("SetInfrared", selector)
+}
+
+func (f *fakeProviderClient) TogglePower(_, selector string, _ float64) error {
+	return f.recordControl("TogglePower", selector)
+}
+
+func (f *fakeProviderClient) Flame(_, selector string, _, _ float64) error {
+	return f.recordControl("Flame", selector)
+}
+
+func (f *fakeProviderClient) Move(_, selector, _ string, _, _ float64) error {
+	return f.recordControl("Move", selector)
+}
+
+func (f *fakeProviderClient) Waveform(_, selector string, _ providers.WaveformParams) error {
+	return f.recordControl("Waveform", selector)
+}
+
+// SetStates stands in for a native batch endpoint: the whole batch is one call
+func (f *fakeProviderClient) SetStates(_ string, states []providers.DeviceState) error {
+	for _, state := range states {
+		f.mu.Lock()
+		f.selectors = append(f.selectors, state.Selector)
+		f.mu.Unlock()
+	}
+	return f.record("SetStates")
+}
+
+func (f *fakeProviderClient) Pulse(_, selector string, _ *providers.DeviceColor, _ int, _ float64) error {
+	return f.recordControl("Pulse", selector)
+}
+
+func (f *fakeProviderClient) Breathe(_, selector string, _ *providers.DeviceColor, _ int, _ float64) error {
+	return f.recordControl("Breathe", selector)
+}
+
+// newTestDeviceService wires a DeviceService to an in-memory repository, miniredis and the given client
+func newTestDeviceService(t testing.TB, client providers.Client) (*DeviceService, *models.Account) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = cache.Close() })
+
+	repo := NewMockAccountRepository()
+	account, err := repo.Create(context.Background(), &models.CreateAccountParams{
+		OwnerUserID:       uuid.New(),
+		Provider:          string(providers.ProviderLIFX),
+		ProviderAccountID: "test-account-1",
+		EncryptedToken:    []byte("test-token"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	service := NewDeviceService(repo, cache, events.NewBus(), DeviceServiceConfig{
+		CacheTTL: time.Minute,
+		RateLimits: RateLimits{
+			Read:  ratelimit.Limit{PerMinute: 30},
+			Write: ratelimit.Limit{PerMinute: 30},
+		},
+		CircuitBreaker: CircuitBreakerConfig{MaxRequests: 1, Timeout: time.Minute},
+	})
+	service.newClient = func(_ providers.Provider) (providers.Client, error) {
+		return client, nil
+	}
+
+	return service, account
+}
+
+func TestExecuteAction_DefersOnThrottle(t *testing.T) {
+	client := newFakeProviderClient()
+	client.errs["SetPower"] = []error{
+		&providers.RateLimitError{Provider: providers.ProviderLIFX, RetryAfter: 20 * time.Millisecond},
+	}
+	service, account := newTestDeviceService(t, client)
+	schedules := &MockScheduleRepository{}
+	service.schedules = schedules
+
+	action := &models.ActionRequest{
+		Action:          models.ActionPower,
+		Parameters:      map[string]interface{}{"state": "on"},
+		DeferOnThrottle: true,
+	}
+
+	err := service.ExecuteAction(context.Background(), account.OwnerUserID.String(), account.ID.String(), "all", action)
+
+	var deferredErr *ActionDeferredError
+	if !errors.As(err, &deferredErr) {
+		t.Fatalf("Expected ActionDeferredError, got %v", err)
+	}
+	if deferredErr.RetryAfter != 20*time.Millisecond {
+		t.Errorf("Expected RetryAfter 20ms, got %s", deferredErr.RetryAfter)
+	}
+
+	// The action is stored as a one-shot schedule rather than held in memory
+	stored, _ := schedules.ListByUser(context.Background(), account.OwnerUserID)
+	if len(stored) != 1 || !stored[0].RunOnce || stored[0].ID != deferredErr.ScheduleID || !stored[0].NextRunAt.Equal(deferredErr.ScheduledAt) {
+		t.Fatalf("Expected a one-shot schedule at %v, got %+v", deferredErr.ScheduledAt, stored)
+	}
+
+	// The schedule runner retries SetPower once the interval elapses, and only once
+	runner := NewScheduleRunner(schedules, service)
+	runner.now = fixedClock(deferredErr.ScheduledAt)
+	if runs := runner.RunDue(context.Background()); runs != 1 {
+		t.Errorf("Expected the deferred action to run, got %d runs", runs)
+	}
+	if runs := runner.RunDue(context.Background()); runs != 0 {
+		t.Errorf("Expected the deferred action to run once, got %d runs", runs)
+	}
+	if calls := client.callCount("SetPower"); calls != 2 {
+		t.Fatalf("Expected 2 SetPower calls (throttled + deferred), got %d", calls)
+	}
+	if stored, _ := schedules.ListByUser(context.Background(), account.OwnerUserID); len(stored) != 0 {
+		t.Errorf("Expected the one-shot schedule to be removed, got %d", len(stored))
+	}
+}
+
+func TestExecuteAction_ThrottleWithoutDeferFails(t *testing.T) {
+	client := newFakeProviderClient()
+	client.errs["SetPower"] = []error{
+		&providers.RateLimitError{Provider: providers.ProviderLIFX, RetryAfter: time.Second},
+	}
+	service, account := newTestDeviceService(t, client)
+
+	action := &models.ActionRequest{
+		Action:     models.ActionPower,
+		Parameters: map[string]interface{}{"state": "on"},
+	}
+
+	err := service.ExecuteAction(context.Background(), account.OwnerUserID.String(), account.ID.String(), "all", action)
+
+	var rateLimitErr *providers.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("Expected provider RateLimitError, got %v", err)
+	}
+}
+
+func TestDiscoverDevices_ReportsAddedAndRemoved(t *testing.T) {
+	client := newFakeProviderClient(
+		&providers.Device{ID: "bulb-1", Label: "Kitchen"},
+		&providers.Device{ID: "bulb-2", Label: "Hallway"},
+	)
+	service, account := newTestDeviceService(t, client)
+
+	var published []events.Event
+	service.events.Subscribe(func(e events.Event) {
+		published = append(published, e)
+	})
+
+	ctx := context.Background()
+	userID := account.OwnerUserID.String()
+	accountID := account.ID.String()
+
+	// First fetch has no cached baseline, so nothing is reported as added or removed
+	first, err := service.DiscoverDevices(ctx, userID, accountID)
+	if err != nil {
+		t.Fatalf("First discovery failed: %v", err)
+	}
+	if len(first.Devices) != 2 || len(first.Added) != 0 || len(first.Removed) != 0 {
+		t.Fatalf("Expected 2 devices and no diff, got %d devices, %d added, %d removed",
+			len(first.Devices), len(first.Added), len(first.Removed))
+	}
+
+	// A bulb is unplugged and a new one is set up
+	client.devices = []*providers.Device{
+		{ID: "bulb-2", Label: "Hallway"},
+		{ID: "bulb-3", Label: "Bedroom"},
+	}
+
+	second, err := service.DiscoverDevices(ctx, userID, accountID)
+	if err != nil {
+		t.Fatalf("Second discovery failed: %v", err)
+	}
+	if len(second.Devices) != 2 {
+		t.Errorf("Expected 2 devices, got %d", len(second.Devices))
+	}
+	if len(second.Added) != 1 || second.Added[0].ID != "bulb-3" {
+		t.Errorf("Expected bulb-3 to be added, got %+v", second.Added)
+	}
+	if len(second.Removed) != 1 || second.Removed[0].ID != "bulb-1" {
+		t.Errorf("Expected bulb-1 to be removed, got %+v", second.Removed)
+	}
+
+	if len(published) != 2 {
+		t.Fatalf("Expected 2 published events, got %d", len(published))
+	}
+	if published[0].Type != events.DeviceAdded || published[1].Type != events.DeviceRemoved {
+		t.Errorf("Unexpected event types: %s, %s", published[0].Type, published[1].Type)
+	}
+	if published[0].AccountID != accountID {
+		t.Errorf("Expected event account ID %s, got %s", accountID, published[0].AccountID)
+	}
+}
+
+func TestRateLimits_ReadsAndWritesCountedSeparately(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Kitchen"})
+	service, account := newTestDeviceService(t, client)
+	service.limits = RateLimits{
+		Read:  ratelimit.Limit{PerMinute: 2},
+		Write: ratelimit.Limit{PerMinute: 3},
+	}
+
+	ctx := context.Background()
+	userID := account.OwnerUserID.String()
+	accountID := account.ID.String()
+	action := &models.ActionRequest{
+		Action:     models.ActionPower,
+		Parameters: map[string]interface{}{"state": "on"},
+	}
+
+	// Exhaust the read budget
+	for i := 0; i < 2; i++ {
+		if _, err := service.GetDevice(ctx, userID, accountID, "bulb-1"); err != nil {
+			t.Fatalf("Read %d failed: %v", i+1, err)
+		}
+	}
+	if _, err := service.GetDevice(ctx, userID, accountID, "bulb-1"); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Fatalf("Expected read to be rate limited, got %v", err)
+	}
+
+	// Writes still have their own budget
+	for i := 0; i < 3; i++ {
+		if err := service.ExecuteAction(ctx, userID, accountID, "all", action); err != nil {
+			t.Fatalf("Write %d failed despite exhausted read budget: %v", i+1, err)
+		}
+	}
+	if err := service.ExecuteAction(ctx, userID, accountID, "all", action); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Fatalf("Expected write to be rate limited, got %v", err)
+	}
+
+	read, write, err := service.RateLimitStatus(ctx, accountID)
+	if err != nil {
+		t.Fatalf("RateLimitStatus failed: %v", err)
+	}
+	if read.Limit != 2 || read.Remaining != 0 {
+		t.Errorf("Expected read budget 2 with 0 remaining, got %+v", read)
+	}
+	if write.Limit != 3 || write.Remaining != 0 {
+		t.Errorf("Expected write budget 3 with 0 remaining, got %+v", write)
+	}
+}
+
+func TestRateLimits_ProviderBudgetSpansReadsAndWrites(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Kitchen"})
+	service, account := newTestDeviceService(t, client)
+	service.limits = RateLimits{
+		Read:  ratelimit.Limit{PerMinute: 10},
+		Write: ratelimit.Limit{PerMinute: 10},
+		Providers: map[providers.Provider]ratelimit.Limit{
+			providers.ProviderLIFX: {PerMinute: 2},
+		},
+	}
+
+	ctx := context.Background()
+	userID := account.OwnerUserID.String()
+	accountID := account.ID.String()
+	action := &models.ActionRequest{
+		Action:     models.ActionPower,
+		Parameters: map[string]interface{}{"state": "on"},
+	}
+
+	if _, err := service.GetDevice(ctx, userID, accountID, "bulb-1"); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if err := service.ExecuteAction(ctx, userID, accountID, "all", action); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	err := service.ExecuteAction(ctx, userID, accountID, "all", action)
+	var limitErr *RateLimitExceededError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("Expected RateLimitExceededError, got %v", err)
+	}
+	if limitErr.Scope != "lifx" || limitErr.Limit != 2 {
+		t.Errorf("Expected lifx budget of 2, got %+v", limitErr)
+	}
+	if limitErr.RetryAfterSeconds < 1 || limitErr.RetryAfterSeconds > 60 {
+		t.Errorf("Expected RetryAfterSeconds within the window, got %d", limitErr.RetryAfterSeconds)
+	}
+}
+
+func TestExecuteAction_TransitionCompleteEvent(t *testing.T) {
+	service, account := newTestDeviceService(t, newFakeProviderClient())
+
+	completed := make(chan events.Event, 4)
+	service.events.Subscribe(func(e events.Event) {
+		if e.Type == events.TransitionComplete {
+			completed <- e
+		}
+	})
+
+	ctx := context.Background()
+	userID := account.OwnerUserID.String()
+	accountID := account.ID.String()
+	brightness := func(level, duration float64) *models.ActionRequest {
+		return &models.ActionRequest{
+			Action:     models.ActionBrightness,
+			Parameters: map[string]interface{}{"level": level, "duration": duration},
+		}
+	}
+
+	// A long transition is superseded by a newer, shorter one on the same device
+	if err := service.ExecuteAction(ctx, userID, accountID, "id:bulb-1", brightness(0.2, 10)); err != nil {
+		t.Fatalf("First action failed: %v", err)
+	}
+	start := time.Now()
+	if err := service.ExecuteAction(ctx, userID, accountID, "id:bulb-1", brightness(0.8, 0.05)); err != nil {
+		t.Fatalf("Second action failed: %v", err)
+	}
+
+	select {
+	case e := <-completed:
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("Event fired before the transition duration elapsed (%s)", elapsed)
+		}
+		payload, ok := e.Payload.(*models.TransitionComplete)
+		if !ok {
+			t.Fatalf("Unexpected payload type %T", e.Payload)
+		}
+		if payload.Selector != "id:bulb-1" || payload.State["level"] != 0.8 {
+			t.Errorf("Expected final level 0.8 for id:bulb-1, got %+v", payload)
+		}
+		if _, ok := payload.State["duration"]; ok {
+			t.Error("Expected duration to be omitted from the final state")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("transition_complete event was not published")
+	}
+
+	// The superseded transition must never fire
+	service.transitions.mu.Lock()
+	pending := len(service.transitions.pending)
+	service.transitions.mu.Unlock()
+	if pending != 0 {
+		t.Errorf("Expected no pending transitions, got %d", pending)
+	}
+	select {
+	case e := <-completed:
+		t.Errorf("Unexpected extra event: %+v", e.Payload)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestExecuteAction_CreatesProviderSpan(t *testing.T) {
+	service, account := newTestDeviceService(t, newFakeProviderClient())
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+
+	action := &models.ActionRequest{
+		Action:     models.ActionPower,
+		Parameters: map[string]interface{}{"state": "on"},
+	}
+	if err := service.ExecuteAction(ctx, account.OwnerUserID.String(), account.ID.String(), "id:bulb-1", action); err != nil {
+		t.Fatalf("ExecuteAction failed: %v", err)
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans (request + provider), got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "provider.power" {
+		t.Errorf("Expected span 'provider.power', got '%s'", span.Name)
+	}
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("Expected the provider span to be a child of the request span")
+	}
+
+	attrs := make(map[attribute.Key]string)
+	for _, attr := range span.Attributes {
+		attrs[attr.Key] = attr.Value.AsString()
+	}
+	expected := map[attribute.Key]string{
+		"provider":   string(providers.ProviderLIFX),
+		"account_id": account.ID.String(),
+		"selector":   "id:bulb-1",
+	}
+	for key, want := range expected {
+		if attrs[key] != want {
+			t.Errorf("Expected attribute %s '%s', got '%s'", key, want, attrs[key])
+		}
+	}
+}
+
+func TestRateLimits_UserLimitSpansAccounts(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Kitchen"})
+	service, account := newTestDeviceService(t, client)
+	service.limits = RateLimits{
+		User:  ratelimit.Limit{PerMinute: 3},
+		Read:  ratelimit.Limit{PerMinute: 10},
+		Write: ratelimit.Limit{PerMinute: 10},
+	}
+
+	ctx := context.Background()
+	other, err := service.accountRepo.Create(ctx, &models.CreateAccountParams{
+		OwnerUserID:       account.OwnerUserID,
+		Provider:          string(providers.ProviderLIFX),
+		ProviderAccountID: "test-account-2",
+		EncryptedToken:    []byte("test-token"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	userID := account.OwnerUserID.String()
+	action := &models.ActionRequest{
+		Action:     models.ActionPower,
+		Parameters: map[string]interface{}{"state": "on"},
+	}
+
+	if _, err := service.GetDevice(ctx, userID, account.ID.String(), "bulb-1"); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if err := service.ExecuteAction(ctx, userID, other.ID.String(), "all", action); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, err := service.GetDevice(ctx, userID, other.ID.String(), "bulb-1"); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	// The user key counts every call; each account key only counts its own
+	counts := map[string]int64{
+		userRateLimitKey(userID):                          3,
+		rateLimitKey(account.ID.String(), rateLimitRead):  1,
+		rateLimitKey(other.ID.String(), rateLimitRead):    1,
+		rateLimitKey(other.ID.String(), rateLimitWrite):   1,
+		rateLimitKey(account.ID.String(), rateLimitWrite): 0,
+	}
+	for key, want := range counts {
+		if got := service.cache.ZCard(ctx, key).Val(); got != want {
+			t.Errorf("Expected %d requests recorded under %s, got %d", want, key, got)
+		}
+	}
+
+	// Both accounts still have budget, but the user does not
+	err = service.ExecuteAction(ctx, userID, account.ID.String(), "all", action)
+	if !errors.Is(err, ErrUserRateLimitExceeded) {
+		t.Fatalf("Expected ErrUserRateLimitExceeded, got %v", err)
+	}
+	var limitErr *RateLimitExceededError
+	if !errors.As(err, &limitErr) || limitErr.Limit != 3 || limitErr.RetryAfterSeconds < 1 {
+		t.Errorf("Expected user budget of 3 with a Retry-After, got %+v", limitErr)
+	}
+	if got := service.cache.ZCard(ctx, rateLimitKey(account.ID.String(), rateLimitWrite)).Val(); got != 0 {
+		t.Errorf("Expected a rejected call not to count against the account, got %d", got)
+	}
+}
+
+func TestRateLimits_AccountLimitIsNotUserLimit(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Kitchen"})
+	service, account := newTestDeviceService(t, client)
+	service.limits = RateLimits{
+		User:  ratelimit.Limit{PerMinute: 10},
+		Read:  ratelimit.Limit{PerMinute: 1},
+		Write: ratelimit.Limit{PerMinute: 1},
+	}
+
+	ctx := context.Background()
+	userID := account.OwnerUserID.String()
+	if _, err := service.GetDevice(ctx, userID, account.ID.String(), "bulb-1"); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	_, err := service.GetDevice(ctx, userID, account.ID.String(), "bulb-1")
+	if !errors.Is(err, ErrRateLimitExceeded) || errors.Is(err, ErrUserRateLimitExceeded) {
+		t.Errorf("Expected an account rate limit error, got %v", err)
+	}
+}
+
+// addTestAccounts creates n more accounts owned by the owner of account
+func addTestAccounts(t *testing.T, service *DeviceService, account *models.Account, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		_, err := service.accountRepo.Create(context.Background(), &models.CreateAccountParams{
+			OwnerUserID:       account.OwnerUserID,
+			Provider:          string(providers.ProviderLIFX),
+			ProviderAccountID: fmt.Sprintf("test-account-extra-%d", i),
+			EncryptedToken:    []byte("test-token"),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+	}
+}
+
+func TestListDevices_FetchesAccountsInParallel(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Kitchen"})
+	client.delay = 100 * time.Millisecond
+	service, account := newTestDeviceService(t, client)
+	addTestAccounts(t, service, account, 4)
+
+	start := time.Now()
+	page, err := service.ListDevices(context.Background(), account.OwnerUserID.String(), models.PaginationOptions{})
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+
+	if page.Total != 5 {
+		t.Errorf("Expected 5 devices, got %d", page.Total)
+	}
+	// Sequential fetches would take at least 500ms
+	if elapsed >= 300*time.Millisecond {
+		t.Errorf("Expected accounts to be fetched in parallel, took %v", elapsed)
+	}
+}
+
+func TestListDevices_ConcurrencyIsBounded(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Kitchen"})
+	client.delay = 50 * time.Millisecond
+	service, account := newTestDeviceService(t, client)
+	service.fetch = newFetchConfig(2, 0)
+	addTestAccounts(t, service, account, 3)
+
+	start := time.Now()
+	if _, err := service.ListDevices(context.Background(), account.OwnerUserID.String(), models.PaginationOptions{}); err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+
+	// Four accounts two at a time take at least two rounds
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected at most 2 concurrent fetches, took %v", elapsed)
+	}
+}
+
+func TestListDevices_ReportsFailedAccounts(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Kitchen"})
+	client.errs["ListDevices"] = []error{errors.New("unexpected status code: 500")}
+	service, account := newTestDeviceService(t, client)
+	addTestAccounts(t, service, account, 2)
+
+	page, err := service.ListDevices(context.Background(), account.OwnerUserID.String(), models.PaginationOptions{})
+	if err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+
+	if page.Total != 2 {
+		t.Errorf("Expected devices of the 2 healthy accounts, got %d", page.Total)
+	}
+	if len(page.Errors) != 1 || page.Errors[0].AccountID == "" || page.Errors[0].Cause == nil {
+		t.Errorf("Expected one provider error, got %+v", page.Errors)
+	}
+}
+
+func TestListAccountDevices_ConcurrentCacheMissFetchesOnce(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Kitchen"})
+	client.delay = 50 * time.Millisecond
+	service, account := newTestDeviceService(t, client)
+	userID := account.OwnerUserID.String()
+	accountID := account.ID.String()
+
+	const callers = 20
+	errs := make([]error, callers)
+	totals := make([]int, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			page, err := service.ListAccountDevices(context.Background(), userID, accountID, models.DeviceFilter{}, models.PaginationOptions{})
+			if err == nil {
+				totals[i] = page.Total
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("ListAccountDevices %d failed: %v", i, err)
+		}
+		if totals[i] != 1 {
+			t.Errorf("Expected caller %d to get 1 device, got %d", i, totals[i])
+		}
+	}
+	if calls := client.callCount("ListDevices"); calls != 1 {
+		t.Errorf("Expected the provider to be called once, got %d calls", calls)
+	}
+	if _, _, err := service.getCachedDevices(context.Background(), accountID); err != nil {
+		t.Errorf("Expected the shared fetch to be cached: %v", err)
+	}
+}
+
+func TestExecuteAction_Toggle(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Kitchen"})
+	service, account := newTestDeviceService(t, client)
+
+	action := &models.ActionRequest{Action: models.ActionToggle}
+	if err := service.ExecuteAction(context.Background(), account.OwnerUserID.String(), account.ID.String(), "id:bulb-1", action); err != nil {
+		t.Fatalf("ExecuteAction failed: %v", err)
+	}
+
+	if client.callCount("TogglePower") != 1 {
+		t.Errorf("Expected 1 TogglePower call, got %d", client.callCount("TogglePower"))
+	}
+	if client.callCount("SetPower") != 0 {
+		t.Errorf("Expected no SetPower call, got %d", client.callCount("SetPower"))
+	}
+}
+
+func TestExecuteAction_MoveEffect(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "beam-1", Label: "Beam"})
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	action := &models.ActionRequest{
+		Action:     models.ActionEffect,
+		Parameters: map[string]interface{}{"name": models.EffectMove, "direction": models.MoveReverse, "period": 2.0},
+	}
+	if err := service.ExecuteAction(context.Background(), userID, accountID, "id:beam-1", action); err != nil {
+		t.Fatalf("ExecuteAction failed: %v", err)
+	}
+	if client.callCount("Move") != 1 {
+		t.Errorf("Expected 1 Move call, got %d", client.callCount("Move"))
+	}
+
+	action.Parameters["direction"] = "sideways"
+	if err := service.ExecuteAction(context.Background(), userID, accountID, "id:beam-1", action); err == nil {
+		t.Error("Expected an invalid direction to be rejected")
+	}
+	if client.callCount("Move") != 1 {
+		t.Errorf("Expected the invalid move not to reach the provider, got %d calls", client.callCount("Move"))
+	}
+}
+
+func TestGetDevice_IncludesAccountLabel(t *testing.T) {
+	service, account := newTestDeviceService(t, newFakeProviderClient(&providers.Device{ID: "d1", Label: "Lamp"}))
+	account.Label = "Bedroom Hub"
+
+	device, err := service.GetDevice(context.Background(), account.OwnerUserID.String(), account.ID.String(), "d1")
+	if err != nil {
+		t.Fatalf("GetDevice failed: %v", err)
+	}
+	if device.AccountLabel != "Bedroom Hub" {
+		t.Errorf("Expected account label 'Bedroom Hub', got %q", device.AccountLabel)
+	}
+}
+
+func TestGetLiveDevice_UpdatesCachedEntry(t *testing.T) {
+	lamp := &providers.Device{ID: "d1", Label: "Lamp", Power: "on"}
+	client := newFakeProviderClient(lamp, &providers.Device{ID: "d2", Label: "Strip", Power: "on"})
+	service, account := newTestDeviceService(t, client)
+	ctx := context.Background()
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	if _, err := service.GetCachedDevice(ctx, userID, accountID, "d1"); err != nil {
+		t.Fatalf("GetCachedDevice failed: %v", err)
+	}
+
+	// The cached path keeps serving the listed state after the device changes
+	lamp.Power = "off"
+	cached, err := service.GetCachedDevice(ctx, userID, accountID, "d1")
+	if err != nil {
+		t.Fatalf("GetCachedDevice failed: %v", err)
+	}
+	if cached.Power != "on" || client.callCount("ListDevices") != 1 || client.callCount("GetDevice") != 0 {
+		t.Errorf("Expected the cached state without provider calls, got power %q", cached.Power)
+	}
+
+	live, err := service.GetLiveDevice(ctx, userID, accountID, "d1")
+	if err != nil {
+		t.Fatalf("GetLiveDevice failed: %v", err)
+	}
+	if live.Power != "off" || client.callCount("GetDevice") != 1 {
+		t.Errorf("Expected the live state from the provider, got power %q", live.Power)
+	}
+
+	// The live state replaces the device's cached entry and leaves the rest of the list intact
+	page, err := service.ListAccountDevices(ctx, userID, accountID, models.DeviceFilter{}, models.PaginationOptions{})
+	if err != nil {
+		t.Fatalf("ListAccountDevices failed: %v", err)
+	}
+	if page.Total != 2 || client.callCount("ListDevices") != 1 {
+		t.Fatalf("Expected the 2 cached devices, got %d after %d list calls", page.Total, client.callCount("ListDevices"))
+	}
+	for _, device := range page.Devices {
+		if device.ID == "d1" && device.Power != "off" {
+			t.Errorf("Expected the cached lamp to be off, got %q", device.Power)
+		}
+		if device.ID == "d2" && device.Power != "on" {
+			t.Errorf("Expected the cached strip to be unchanged, got %q", device.Power)
+		}
+	}
+
+	if _, err := service.GetCachedDevice(ctx, userID, accountID, "unknown"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("Expected ErrDeviceNotFound, got %v", err)
+	}
+}
+
+func TestSetCachedDevices_SupersedesLiveStates(t *testing.T) {
+	lamp := &providers.Device{ID: "d1", Label: "Lamp", Power: "on"}
+	service, account := newTestDeviceService(t, newFakeProviderClient(lamp))
+	ctx := context.Background()
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	if _, err := service.GetLiveDevice(ctx, userID, accountID, "d1"); err != nil {
+		t.Fatalf("GetLiveDevice failed: %v", err)
+	}
+
+	lamp.Power = "off"
+	if _, err := service.RefreshDevices(ctx, userID, accountID); err != nil {
+		t.Fatalf("RefreshDevices failed: %v", err)
+	}
+
+	device, err := service.GetCachedDevice(ctx, userID, accountID, "d1")
+	if err != nil {
+		t.Fatalf("GetCachedDevice failed: %v", err)
+	}
+	if device.Power != "off" {
+		t.Errorf("Expected the refreshed list to replace the earlier live state, got %q", device.Power)
+	}
+}
+
+func TestExecuteAction_ConvertsHexColor(t *testing.T) {
+	client := newFakeProviderClient(newWhiteDevices()...)
+	service, account := newTestDeviceService(t, client)
+
+	action := &models.ActionRequest{
+		Action:     models.ActionColor,
+		Parameters: map[string]interface{}{"hex": "#00FF00"},
+	}
+	if err := service.ExecuteAction(context.Background(), account.OwnerUserID.String(), account.ID.String(), "id:color-1", action); err != nil {
+		t.Fatalf("ExecuteAction failed: %v", err)
+	}
+	if client.callCount("SetColor") != 1 {
+		t.Errorf("Expected 1 SetColor call, got %d", client.callCount("SetColor"))
+	}
+	if action.Parameters["hue"] != 120.0 || action.Parameters["saturation"] != 1.0 {
+		t.Errorf("Expected hex to be converted to hue 120 and saturation 1, got %v", action.Parameters)
+	}
+
+	mixed := &models.ActionRequest{
+		Action:     models.ActionColor,
+		Parameters: map[string]interface{}{"hex": "#00FF00", "hue": 120.0},
+	}
+	if err := mixed.ValidateParameters(); err == nil {
+		t.Error("Expected hex combined with hue to be rejected")
+	}
+
+	invalid := &models.ActionRequest{
+		Action:     models.ActionColor,
+		Parameters: map[string]interface{}{"hex": "green"},
+	}
+	if err := invalid.ValidateParameters(); err == nil {
+		t.Error("Expected an invalid hex color to be rejected")
+	}
+}
+
+func TestExecuteAction_WaveformEffect(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Bulb"})
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	action := &models.ActionRequest{
+		Action:     models.ActionEffect,
+		Parameters: map[string]interface{}{"name": models.EffectWaveform, "waveform_type": 1.0, "peak": 0.5, "persist": true},
+	}
+	if err := service.ExecuteAction(context.Background(), userID, accountID, "id:bulb-1", action); err != nil {
+		t.Fatalf("ExecuteAction failed: %v", err)
+	}
+	if client.callCount("Waveform") != 1 {
+		t.Errorf("Expected 1 Waveform call, got %d", client.callCount("Waveform"))
+	}
+
+	for _, invalid := range []map[string]interface{}{
+		{"name": models.EffectWaveform},
+		{"name": models.EffectWaveform, "waveform_type": 5.0},
+		{"name": models.EffectWaveform, "waveform_type": 1.5},
+		{"name": models.EffectWaveform, "waveform_type": 1.0, "peak": 1.5},
+		{"name": models.EffectWaveform, "waveform_type": 1.0, "persist": "yes"},
+	} {
+		action.Parameters = invalid
+		if err := service.ExecuteAction(context.Background(), userID, accountID, "id:bulb-1", action); err == nil {
+			t.Errorf("Expected %v to be rejected", invalid)
+		}
+	}
+	if client.callCount("Waveform") != 1 {
+		t.Errorf("Expected invalid waveforms not to reach the provider, got %d calls", client.callCount("Waveform"))
+	}
+}
+
+func TestExecuteAction_RejectsReadOnlyAccount(t *testing.T) {
+	client := newFakeProviderClient()
+	service, account := newTestDeviceService(t, client)
+	account.Metadata = []byte(`{"token_scopes":"read","read_only":true}`)
+
+	action := &models.ActionRequest{Action: models.ActionPower, Parameters: map[string]interface{}{"state": "on"}}
+	err := service.ExecuteAction(context.Background(), account.OwnerUserID.String(), account.ID.String(), "all", action)
+	if !errors.Is(err, ErrAccountReadOnly) {
+		t.Fatalf("Expected ErrAccountReadOnly, got %v", err)
+	}
+	if client.callCount("SetPower") != 0 {
+		t.Errorf("Expected no SetPower calls, got %d", client.callCount("SetPower"))
+	}
+}
+
+func TestExecuteAction_PartialSuccessInvalidatesSucceededDevices(t *testing.T) {
+	tests := []struct {
+		name          string
+		succeeded     []string
+		wantListCalls int
+	}{
+		{name: "some lights reached", succeeded: []string{"d1"}, wantListCalls: 2},
+		{name: "no light reached", succeeded: []string{}, wantListCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeProviderClient(
+				&providers.Device{ID: "d1", Label: "Lamp", Power: "on"},
+				&providers.Device{ID: "d2", Label: "Strip", Power: "on"},
+			)
+			client.errs["SetPower"] = []error{&providers.PartialSuccessError{
+				Provider:  providers.ProviderLIFX,
+				Succeeded: tt.succeeded,
+				Failed:    []string{"d2"},
+			}}
+			service, account := newTestDeviceService(t, client)
+			ctx := context.Background()
+			userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+			if _, err := service.GetCachedDevice(ctx, userID, accountID, "d2"); err != nil {
+				t.Fatalf("GetCachedDevice failed: %v", err)
+			}
+
+			action := &models.ActionRequest{Action: models.ActionPower, Parameters: map[string]interface{}{"state": "off"}}
+			err := service.ExecuteAction(ctx, userID, accountID, "all", action)
+			var partialErr *providers.PartialSuccessError
+			if !errors.As(err, &partialErr) || !errors.Is(err, providers.ErrPartialSuccess) {
+				t.Fatalf("Expected PartialSuccessError, got %v", err)
+			}
+
+			if _, err := service.GetCachedDevice(ctx, userID, accountID, "d2"); err != nil {
+				t.Fatalf("GetCachedDevice failed: %v", err)
+			}
+			if calls := client.callCount("ListDevices"); calls != tt.wantListCalls {
+				t.Errorf("Expected %d list calls, got %d", tt.wantListCalls, calls)
+			}
+		})
+	}
+}
+
+// cacheDevicesAt caches devices as the account's device list as if it was cached at cachedAt
+func cacheDevicesAt(tb testing.TB, service *DeviceService, accountID string, cachedAt time.Time, devices ...*models.Device) {
+	tb.Helper()
+	data, err := json.Marshal(cachedDeviceList{Devices: devices, CachedAt: float64(cachedAt.UnixMilli()) / 1000})
+	if err != nil {
+		tb.Fatalf("Failed to encode devices: %v", err)
+	}
+	if err := service.cache.Set(context.Background(), devicesCacheKey(accountID), data, service.cacheTTL*staleCacheFactor).Err(); err != nil {
+		tb.Fatalf("Failed to cache devices: %v", err)
+	}
+}
+
+func TestListAccountDevices_ServesStaleDevicesWhileRefreshing(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Fresh"})
+	client.delay = 20 * time.Millisecond
+	service, account := newTestDeviceService(t, client)
+	ctx := context.Background()
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	cacheDevicesAt(t, service, accountID, time.Now().Add(-2*time.Minute), &models.Device{ID: "bulb-1", Label: "Stale"})
+
+	start := time.Now()
+	page, err := service.ListAccountDevices(ctx, userID, accountID, models.DeviceFilter{}, models.PaginationOptions{})
+	if err != nil {
+		t.Fatalf("ListAccountDevices failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= client.delay {
+		t.Errorf("Expected the stale devices without waiting for the provider, took %v", elapsed)
+	}
+	if page.Devices[0].Label != "Stale" {
+		t.Errorf("Expected the stale device, got %+v", page.Devices[0])
+	}
+
+	waitFor(t, "the background refresh", func() bool {
+		devices, isStale, err := service.getCachedDevices(ctx, accountID)
+		return err == nil && !isStale && devices[0].Label == "Fresh"
+	})
+	if calls := client.callCount("ListDevices"); calls != 1 {
+		t.Errorf("Expected 1 provider call, got %d", calls)
+	}
+	if ttl := service.cache.TTL(ctx, devicesCacheKey(accountID)).Val(); ttl != staleCacheFactor*time.Minute {
+		t.Errorf("Expected the refreshed list to be kept %v, got %v", staleCacheFactor*time.Minute, ttl)
+	}
+}
+
+func TestListAccountDevices_RefreshLockSkipsBackgroundRefresh(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Fresh"})
+	service, account := newTestDeviceService(t, client)
+	ctx := context.Background()
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	cacheDevicesAt(t, service, accountID, time.Now().Add(-2*time.Minute), &models.Device{ID: "bulb-1", Label: "Stale"})
+	service.cache.Set(ctx, refreshLockKey(accountID), 1, refreshLockTTL)
+
+	for range 5 {
+		if _, err := service.ListAccountDevices(ctx, userID, accountID, models.DeviceFilter{}, models.PaginationOptions{}); err != nil {
+			t.Fatalf("ListAccountDevices failed: %v", err)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	if calls := client.callCount("ListDevices"); calls != 0 {
+		t.Errorf("Expected the held lock to skip refreshing, got %d provider calls", calls)
+	}
+
+	// Only one of many concurrent stale reads refreshes once the lock is free
+	service.cache.Del(ctx, refreshLockKey(accountID))
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = service.ListAccountDevices(ctx, userID, accountID, models.DeviceFilter{}, models.PaginationOptions{})
+		}()
+	}
+	wg.Wait()
+	waitFor(t, "the background refresh", func() bool { return client.callCount("ListDevices") == 1 })
+	time.Sleep(20 * time.Millisecond)
+	if calls := client.callCount("ListDevices"); calls != 1 {
+		t.Errorf("Expected 1 provider call, got %d", calls)
+	}
+}
+
+// BenchmarkListAccountDevices_ExpiredCache reports the p99 latency of listing the devices
+// of an account whose cached list expired, with a provider taking 5ms to list them: either
+// waiting for the provider as on a cache miss, or serving the stale list while it is
+// refreshed in the background
+func BenchmarkListAccountDevices_ExpiredCache(b *testing.B) {
+	devices := make([]*models.Device, 20)
+	for i := range devices {
+		devices[i] = &models.Device{ID: fmt.Sprintf("bulb-%d", i), Label: fmt.Sprintf("Bulb %d", i)}
+	}
+
+	for _, staleWhileRevalidate := range []bool{false, true} {
+		name := "blocking"
+		if staleWhileRevalidate {
+			name = "stale-while-revalidate"
+		}
+		b.Run(name, func(b *testing.B) {
+			client := newFakeProviderClient(&providers.Device{ID: "bulb-0", Label: "Bulb 0"})
+			client.delay = 5 * time.Millisecond
+			service, account := newTestDeviceService(b, client)
+			service.limits.Read = ratelimit.Limit{PerMinute: 1_000_000}
+			ctx := context.Background()
+			userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+			latencies := make([]time.Duration, 0, b.N)
+			for range b.N {
+				b.StopTimer()
+				if staleWhileRevalidate {
+					cacheDevicesAt(b, service, accountID, time.Now().Add(-2*time.Minute), devices...)
+					service.cache.Del(ctx, refreshLockKey(accountID))
+				} else {
+					service.cache.Del(ctx, devicesCacheKey(accountID))
+				}
+				b.StartTimer()
+
+				start := time.Now()
+				if _, err := service.ListAccountDevices(ctx, userID, accountID, models.DeviceFilter{}, models.PaginationOptions{}); err != nil {
+					b.Fatalf("ListAccountDevices failed: %v", err)
+				}
+				latencies = append(latencies, time.Since(start))
+			}
+			// Let the last background refresh finish before the cache is closed
+			time.Sleep(2 * client.delay)
+
+			slices.Sort(latencies)
+			p99 := latencies[(len(latencies)*99)/100]
+			b.ReportMetric(float64(p99.Microseconds())/1000, "p99-ms")
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/crypto"
+	"github.com/lightshare/backend/pkg/email"
+	"github.com/lightshare/backend/pkg/jwt"
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+// emailChangeTTL is how long the verification link of an email change stays valid
+const emailChangeTTL = 24 * time.Hour
+
+var (
+	// ErrInvalidEmail is returned when an email address is malformed or its domain cannot receive mail.
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrEmailAlreadyInUse is returned when changing to an email address another user already has.
+	ErrEmailAlreadyInUse = errors.New("email already in use")
+	// ErrInvalidEmailChangeToken is returned when an email change token is unknown or already used.
+	ErrInvalidEmailChangeToken = errors.New("invalid email change token")
+	// ErrEmailChangeTokenExpired is returned when an email change token has expired.
+	ErrEmailChangeTokenExpired = errors.New("email change token expired")
+)
+
+// RequestEmailChange starts changing a user's email after verifying their password. A
+// verification link is sent to the new address and a security notice to the current one;
+// the email only changes once the link is redeemed. A new request replaces any pending one.
+func (s *AuthService) RequestEmailChange(ctx context.Context, userID uuid.UUID, newEmail, password string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Users who only sign in with a provider have no password to compare
+	if user.PasswordHash == "" || crypto.ComparePassword(password, user.PasswordHash) != nil {
+		return ErrInvalidCredentials
+	}
+
+	newEmail = strings.TrimSpace(strings.ToLower(newEmail))
+	if !email.ValidateEmail(newEmail) || !s.domainValidator.HasMailExchanger(ctx, newEmail) {
+		return ErrInvalidEmail
+	}
+
+	if newEmail == user.Email {
+		return ErrEmailAlreadyInUse
+	}
+	if _, err := s.userRepo.GetByEmail(ctx, newEmail); err == nil {
+		return ErrEmailAlreadyInUse
+	} else if !errors.Is(err, repository.ErrUserNotFound) {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Generate verification token; only its hash is stored
+	token, err := jwt.GenerateRandomToken(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate email change token: %w", err)
+	}
+
+	expiresAt := time.Now().Add(emailChangeTTL)
+	if err := s.userRepo.SetPendingEmail(ctx, userID, newEmail, crypto.HashToken(token), expiresAt); err != nil {
+		return fmt.Errorf("failed to set pending email: %w", err)
+	}
+	s.invalidateProfile(ctx, userID)
+
+	verification, err := s.emailService.EmailChangeVerificationMessage(newEmail, token)
+	if err != nil {
+		return fmt.Errorf("failed to render email change verification: %w", err)
+	}
+	if err := s.emailWorker.Enqueue(verification); err != nil {
+		return fmt.Errorf("failed to queue email change verification: %w", err)
+	}
+
+	if err := s.emailWorker.Enqueue(s.emailService.EmailChangeNoticeMessage(user.Email, newEmail)); err != nil {
+		// Log error but don't fail the request; the change still needs the new address verified
+		logger.WithContext(ctx).Warn("Failed to queue email change notice", "error", err, "user_id", userID)
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange replaces a user's email with the pending one using an email change
+// token. All of the user's sessions are revoked so they sign in again with the new address.
+func (s *AuthService) ConfirmEmailChange(ctx context.Context, token string) error {
+	tokenHash := crypto.HashToken(token)
+
+	user, err := s.userRepo.GetByPendingEmailToken(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotFound) {
+			return ErrInvalidEmailChangeToken
+		}
+		return fmt.Errorf("failed to get user by email change token: %w", err)
+	}
+
+	if user.PendingEmailExpiresAt == nil || time.Now().After(*user.PendingEmailExpiresAt) {
+		return ErrEmailChangeTokenExpired
+	}
+
+	if err := s.userRepo.ConfirmEmailChange(ctx, user.ID, tokenHash); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrUserAlreadyExists):
+			return ErrEmailAlreadyInUse
+		case errors.Is(err, repository.ErrTokenNotFound):
+			return ErrInvalidEmailChangeToken
+		}
+		return fmt.Errorf("failed to confirm email change: %w", err)
+	}
+
+	s.invalidateProfile(ctx, user.ID)
+
+	if err := s.refreshTokenRepo.RevokeAllForUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	s.recordAudit(ctx, models.EventEmailChanged, user.ID, nil, nil, map[string]interface{}{"previous_email": user.Email})
+
+	return nil
+}
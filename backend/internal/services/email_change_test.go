@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/crypto"
+)
+
+// setTestPendingEmail replaces the user's pending email token with a known one
+func setTestPendingEmail(t *testing.T, service *AuthService, user *models.User, expiresAt time.Time) string {
+	t.Helper()
+
+	token := "email-change-token"
+	if err := service.userRepo.SetPendingEmail(context.Background(), user.ID, *user.PendingEmail, crypto.HashToken(token), expiresAt); err != nil {
+		t.Fatalf("SetPendingEmail failed: %v", err)
+	}
+	return token
+}
+
+func TestRequestEmailChange(t *testing.T) {
+	service := newTestAuthService(t, withPassword("correct-horse-battery"), withSession(), withEmail(nil))
+	user := service.user
+	service.users.users = append(service.users.users, &models.User{ID: uuid.New(), Email: "taken@example.com", Role: "user"})
+	ctx := context.Background()
+
+	if err := service.RequestEmailChange(ctx, user.ID, "new@example.com", "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+	if err := service.RequestEmailChange(ctx, user.ID, "not-an-email", "correct-horse-battery"); !errors.Is(err, ErrInvalidEmail) {
+		t.Errorf("Expected ErrInvalidEmail, got %v", err)
+	}
+	if err := service.RequestEmailChange(ctx, user.ID, "Taken@Example.com", "correct-horse-battery"); !errors.Is(err, ErrEmailAlreadyInUse) {
+		t.Errorf("Expected ErrEmailAlreadyInUse, got %v", err)
+	}
+
+	if err := service.RequestEmailChange(ctx, user.ID, "first@example.com", "correct-horse-battery"); err != nil {
+		t.Fatalf("RequestEmailChange failed: %v", err)
+	}
+	firstToken := *user.PendingEmailToken
+
+	// A second request replaces the pending change
+	if err := service.RequestEmailChange(ctx, user.ID, " New@Example.com ", "correct-horse-battery"); err != nil {
+		t.Fatalf("RequestEmailChange failed: %v", err)
+	}
+
+	if user.Email != "user@example.com" {
+		t.Errorf("Expected email to stay unchanged until verified, got %s", user.Email)
+	}
+	if user.PendingEmail == nil || *user.PendingEmail != "new@example.com" {
+		t.Errorf("Expected pending email new@example.com, got %v", user.PendingEmail)
+	}
+	if *user.PendingEmailToken == firstToken {
+		t.Error("Expected a new email change token")
+	}
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	service := newTestAuthService(t, withPassword("correct-horse-battery"), withSession(), withEmail(nil))
+	user := service.user
+	ctx := context.Background()
+
+	if err := service.RequestEmailChange(ctx, user.ID, "new@example.com", "correct-horse-battery"); err != nil {
+		t.Fatalf("RequestEmailChange failed: %v", err)
+	}
+	token := setTestPendingEmail(t, service.AuthService, user, time.Now().Add(time.Hour))
+
+	if err := service.ConfirmEmailChange(ctx, token); err != nil {
+		t.Fatalf("ConfirmEmailChange failed: %v", err)
+	}
+
+	if user.Email != "new@example.com" || user.PendingEmail != nil {
+		t.Errorf("Expected email new@example.com with no pending change, got %s and %v", user.Email, user.PendingEmail)
+	}
+	if active := service.tokens.activeTokens(); active != 0 {
+		t.Errorf("Expected all sessions to be revoked, got %d active", active)
+	}
+
+	if err := service.ConfirmEmailChange(ctx, token); !errors.Is(err, ErrInvalidEmailChangeToken) {
+		t.Errorf("Expected ErrInvalidEmailChangeToken, got %v", err)
+	}
+}
+
+func TestConfirmEmailChange_Expired(t *testing.T) {
+	service := newTestAuthService(t, withPassword("correct-horse-battery"), withSession(), withEmail(nil))
+	user := service.user
+	ctx := context.Background()
+
+	if err := service.RequestEmailChange(ctx, user.ID, "new@example.com", "correct-horse-battery"); err != nil {
+		t.Fatalf("RequestEmailChange failed: %v", err)
+	}
+	token := setTestPendingEmail(t, service.AuthService, user, time.Now().Add(-time.Minute))
+
+	if err := service.ConfirmEmailChange(ctx, token); !errors.Is(err, ErrEmailChangeTokenExpired) {
+		t.Errorf("Expected ErrEmailChangeTokenExpired, got %v", err)
+	}
+	if user.Email != "user@example.com" {
+		t.Errorf("Expected email to stay unchanged, got %s", user.Email)
+	}
+}
+
+func TestConfirmEmailChange_AddressTakenMeanwhile(t *testing.T) {
+	service := newTestAuthService(t, withPassword("correct-horse-battery"), withSession(), withEmail(nil))
+	user := service.user
+	ctx := context.Background()
+
+	if err := service.RequestEmailChange(ctx, user.ID, "new@example.com", "correct-horse-battery"); err != nil {
+		t.Fatalf("RequestEmailChange failed: %v", err)
+	}
+	token := setTestPendingEmail(t, service.AuthService, user, time.Now().Add(time.Hour))
+
+	if _, err := service.userRepo.Create(ctx, models.CreateUserParams{Email: "new@example.com"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if err := service.ConfirmEmailChange(ctx, token); !errors.Is(err, ErrEmailAlreadyInUse) {
+		t.Errorf("Expected ErrEmailAlreadyInUse, got %v", err)
+	}
+}
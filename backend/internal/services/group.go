@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+// groupsCacheKey holds the groups derived from an account's device list
+func groupsCacheKey(accountID string) string {
+	return fmt.Sprintf("devices:account:%s:groups", accountID)
+}
+
+// ListGroups returns the groups of an account, derived from its device list, so clients can
+// build "group_id:" selectors. The groups are cached for half as long as the device list.
+func (s *DeviceService) ListGroups(ctx context.Context, userID, accountID string) ([]*models.Group, error) {
+	// Get account and verify ownership
+	account, err := s.accountRepo.FindByIDString(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	if account.OwnerUserID.String() != userID {
+		return nil, ErrAccountNotOwned
+	}
+
+	if groups, err := s.getCachedGroups(ctx, accountID); err == nil {
+		return groups, nil
+	}
+
+	devices, err := s.cachedOrFetchDevices(ctx, userID, account)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := groupDevices(devices)
+	if err := s.setCachedGroups(ctx, accountID, groups); err != nil {
+		// Log error but don't fail the request
+		logger.WithContext(ctx).Warn("Failed to cache groups", "error", err, "account_id", accountID)
+	}
+
+	return groups, nil
+}
+
+// groupDevices aggregates devices into their groups, sorted by name. Devices without a
+// group are left out.
+func groupDevices(devices []*models.Device) []*models.Group {
+	byID := make(map[string]*models.Group)
+	for _, device := range devices {
+		if device.Group == nil || device.Group.ID == "" {
+			continue
+		}
+
+		group, ok := byID[device.Group.ID]
+		if !ok {
+			group = &models.Group{ID: device.Group.ID, Name: device.Group.Name}
+			byID[device.Group.ID] = group
+		}
+		group.DeviceCount++
+	}
+
+	groups := make([]*models.Group, 0, len(byID))
+	for _, group := range byID {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Name != groups[j].Name {
+			return groups[i].Name < groups[j].Name
+		}
+		return groups[i].ID < groups[j].ID
+	})
+
+	return groups
+}
+
+// getCachedGroups retrieves an account's groups from cache
+func (s *DeviceService) getCachedGroups(ctx context.Context, accountID string) ([]*models.Group, error) {
+	data, err := s.cache.Get(ctx, groupsCacheKey(accountID)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []*models.Group
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// setCachedGroups stores an account's groups in cache for half the device list's TTL
+func (s *DeviceService) setCachedGroups(ctx context.Context, accountID string, groups []*models.Group) error {
+	data, err := json.Marshal(groups)
+	if err != nil {
+		return err
+	}
+
+	return s.cache.Set(ctx, groupsCacheKey(accountID), data, s.cacheTTL/2).Err()
+}
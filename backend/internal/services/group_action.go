@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// ErrGroupNotFound is returned when no device of the user belongs to the requested group
+var ErrGroupNotFound = apierror.New(apierror.ErrNotFound, "group not found")
+
+// ExecuteGroupAction applies an action to every device whose group is named groupName,
+// compared case-insensitively, in any of the user's accounts. Each account gets a single
+// provider call targeting its matching devices by ID. Accounts whose devices could not be
+// listed, or in which the action failed, are reported in the result; an error is returned
+// when the action is invalid or no device belongs to the group.
+func (s *DeviceService) ExecuteGroupAction(ctx context.Context, userID, groupName string, action *models.ActionRequest) (*models.GroupActionResult, error) {
+	s.backfillPreferences(ctx, userID, action)
+	if err := action.ValidateParameters(); err != nil {
+		return nil, &apierror.BadRequestError{Message: err.Error(), Cause: err}
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	accounts, err := s.accountRepo.FindByUserID(ctx, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounts: %w", err)
+	}
+
+	result := &models.GroupActionResult{
+		SucceededAccounts: make([]string, 0),
+		FailedAccounts:    make([]models.AccountError, 0),
+	}
+
+	// Find the group's devices in every account, keeping the accounts' order
+	type accountDevices struct {
+		account   *models.Account
+		deviceIDs []string
+	}
+	var targets []accountDevices
+	for i, fetched := range s.fetchAccountsDevices(ctx, userID, accounts) {
+		accountID := accounts[i].ID.String()
+		if fetched.err != nil {
+			result.FailedAccounts = append(result.FailedAccounts, models.AccountError{AccountID: accountID, Error: fetched.err.Error()})
+			continue
+		}
+		if deviceIDs := groupDeviceIDs(fetched.devices, groupName); len(deviceIDs) > 0 {
+			targets = append(targets, accountDevices{account: accounts[i], deviceIDs: deviceIDs})
+		}
+	}
+	if len(targets) == 0 {
+		if len(result.FailedAccounts) > 0 {
+			return result, nil
+		}
+		return nil, ErrGroupNotFound
+	}
+
+	for _, target := range targets {
+		accountID := target.account.ID.String()
+		if err := s.executeAccountAction(ctx, userID, target.account, deviceIDSelector(target.deviceIDs), action); err != nil {
+			result.FailedAccounts = append(result.FailedAccounts, models.AccountError{AccountID: accountID, Error: err.Error()})
+			continue
+		}
+		result.SucceededAccounts = append(result.SucceededAccounts, accountID)
+	}
+
+	return result, nil
+}
+
+// executeAccountAction sends a validated action for selector to an account's provider,
+// counting it against the account's rate limits
+func (s *DeviceService) executeAccountAction(ctx context.Context, userID string, account *models.Account, selector string, action *models.ActionRequest) error {
+	accountID := account.ID.String()
+
+	// Check rate limit
+	if rateLimitErr := s.checkRateLimit(ctx, userID, account, rateLimitWrite); rateLimitErr != nil {
+		return rateLimitErr
+	}
+
+	// Get decrypted token
+	token, err := s.accountRepo.GetDecryptedToken(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to get token: %w", err)
+	}
+
+	// Create provider client
+	client, err := s.newClient(providers.Provider(account.Provider))
+	if err != nil {
+		return fmt.Errorf("failed to create provider client: %w", err)
+	}
+
+	err = s.executeProviderAction(ctx, account, client, token, selector, action)
+	if err != nil {
+		s.validations.invalidateOnUnauthorized(ctx, accountID, err)
+	}
+	s.publishActionCompleted(userID, accountID, selector, action, err)
+	if err != nil {
+		return err
+	}
+
+	// Invalidate cache for this account
+	if err := s.invalidateCache(ctx, userID, accountID); err != nil {
+		// Log error but don't fail the request
+		_ = err
+	}
+
+	return nil
+}
+
+// groupDeviceIDs returns the sorted IDs of the devices whose group is named groupName
+func groupDeviceIDs(devices []*models.Device, groupName string) []string {
+	var ids []string
+	for _, device := range devices {
+		if device.Group != nil && strings.EqualFold(device.Group.Name, groupName) {
+			ids = append(ids, device.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// deviceIDSelector returns a selector matching each of deviceIDs, e.g. "id:d1,id:d2"
+func deviceIDSelector(deviceIDs []string) string {
+	selectors := make([]string, len(deviceIDs))
+	for i, id := range deviceIDs {
+		selectors[i] = "id:" + id
+	}
+	return strings.Join(selectors, ",")
+}
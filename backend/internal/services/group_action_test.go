@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+func kitchenDevice(id string) *providers.Device {
+	return &providers.Device{ID: id, Label: id, Group: &providers.DeviceGroup{ID: "g-" + id, Name: "Kitchen"}}
+}
+
+func TestExecuteGroupAction_ActsInEveryAccount(t *testing.T) {
+	lifxClient := newFakeProviderClient(
+		kitchenDevice("l2"),
+		kitchenDevice("l1"),
+		&providers.Device{ID: "l3", Label: "Bedroom", Group: &providers.DeviceGroup{ID: "g2", Name: "Bedroom"}},
+	)
+	hueClient := newFakeProviderClient(kitchenDevice("h1"))
+	service, account := newTestDeviceService(t, lifxClient)
+	service.newClient = func(provider providers.Provider) (providers.Client, error) {
+		if provider == providers.ProviderHue {
+			return hueClient, nil
+		}
+		return lifxClient, nil
+	}
+
+	ctx := context.Background()
+	other, err := service.accountRepo.Create(ctx, &models.CreateAccountParams{
+		OwnerUserID:       account.OwnerUserID,
+		Provider:          string(providers.ProviderHue),
+		ProviderAccountID: "test-account-2",
+		EncryptedToken:    []byte("test-token"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	action := &models.ActionRequest{Action: models.ActionPower, Parameters: map[string]interface{}{"state": "off"}}
+	result, err := service.ExecuteGroupAction(ctx, account.OwnerUserID.String(), "kitchen", action)
+	if err != nil {
+		t.Fatalf("ExecuteGroupAction failed: %v", err)
+	}
+
+	if len(result.FailedAccounts) != 0 || len(result.SucceededAccounts) != 2 {
+		t.Fatalf("Expected both accounts to succeed, got %+v", result)
+	}
+	if !slices.Contains(result.SucceededAccounts, account.ID.String()) || !slices.Contains(result.SucceededAccounts, other.ID.String()) {
+		t.Errorf("Expected accounts %s and %s, got %v", account.ID, other.ID, result.SucceededAccounts)
+	}
+	if len(lifxClient.selectors) != 1 || lifxClient.selectors[0] != "id:l1,id:l2" {
+		t.Errorf("Expected the LIFX kitchen lights to be targeted together, got %v", lifxClient.selectors)
+	}
+	if len(hueClient.selectors) != 1 || hueClient.selectors[0] != "id:h1" {
+		t.Errorf("Expected the Hue kitchen light to be targeted, got %v", hueClient.selectors)
+	}
+}
+
+func TestExecuteGroupAction_ReportsFailedAccount(t *testing.T) {
+	client := newFakeProviderClient(kitchenDevice("l1"))
+	client.errs["SetPower"] = []error{errors.New("provider down")}
+	service, account := newTestDeviceService(t, client)
+
+	action := &models.ActionRequest{Action: models.ActionPower, Parameters: map[string]interface{}{"state": "on"}}
+	result, err := service.ExecuteGroupAction(context.Background(), account.OwnerUserID.String(), "Kitchen", action)
+	if err != nil {
+		t.Fatalf("ExecuteGroupAction failed: %v", err)
+	}
+	if len(result.SucceededAccounts) != 0 || len(result.FailedAccounts) != 1 || result.FailedAccounts[0].AccountID != account.ID.String() {
+		t.Errorf("Expected the account to be reported as failed, got %+v", result)
+	}
+}
+
+func TestExecuteGroupAction_UnknownGroup(t *testing.T) {
+	client := newFakeProviderClient(kitchenDevice("l1"))
+	service, account := newTestDeviceService(t, client)
+
+	action := &models.ActionRequest{Action: models.ActionPower, Parameters: map[string]interface{}{"state": "on"}}
+	_, err := service.ExecuteGroupAction(context.Background(), account.OwnerUserID.String(), "Garage", action)
+	if !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("Expected ErrGroupNotFound, got %v", err)
+	}
+	if client.callCount("SetPower") != 0 {
+		t.Errorf("Expected no SetPower calls, got %d", client.callCount("SetPower"))
+	}
+}
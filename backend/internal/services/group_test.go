@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+)
+
+func TestGroupDevices(t *testing.T) {
+	kitchen := &models.DeviceGroup{ID: "grp-kitchen", Name: "Kitchen"}
+	devices := []*models.Device{
+		{ID: "bulb-1", Group: kitchen},
+		{ID: "bulb-2", Group: &models.DeviceGroup{ID: "grp-bedroom", Name: "Bedroom"}},
+		{ID: "bulb-3", Group: kitchen},
+		{ID: "bulb-4"},
+		{ID: "bulb-5", Group: &models.DeviceGroup{}},
+		{ID: "bulb-6", Group: &models.DeviceGroup{ID: "grp-kitchen-2", Name: "Kitchen"}},
+	}
+
+	groups := groupDevices(devices)
+
+	want := []models.Group{
+		{ID: "grp-bedroom", Name: "Bedroom", DeviceCount: 1},
+		{ID: "grp-kitchen", Name: "Kitchen", DeviceCount: 2},
+		{ID: "grp-kitchen-2", Name: "Kitchen", DeviceCount: 1},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("Expected %d groups, got %d", len(want), len(groups))
+	}
+	for i, group := range groups {
+		if *group != want[i] {
+			t.Errorf("Group %d: expected %+v, got %+v", i, want[i], *group)
+		}
+	}
+
+	if groups := groupDevices(nil); groups == nil || len(groups) != 0 {
+		t.Errorf("Expected an empty, non-nil list without devices, got %v", groups)
+	}
+}
+
+func TestListGroups_CachedForHalfTheDeviceTTL(t *testing.T) {
+	client := newFakeProviderClient(newHomeDevices()...)
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	groups, err := service.ListGroups(context.Background(), userID, accountID)
+	if err != nil {
+		t.Fatalf("ListGroups failed: %v", err)
+	}
+	if len(groups) != 3 || groups[1].ID != "grp-desk" || groups[2].DeviceCount != 2 {
+		t.Errorf("Expected the bedroom, desk and kitchen groups, got %+v", groups)
+	}
+
+	ttl := service.cache.TTL(context.Background(), groupsCacheKey(accountID)).Val()
+	if ttl <= 0 || ttl > 30*time.Second {
+		t.Errorf("Expected the groups cached for half the 1m device TTL, got %v", ttl)
+	}
+
+	// Served from the group cache even once the device list is gone
+	service.cache.Del(context.Background(), devicesCacheKey(accountID))
+	if _, err := service.ListGroups(context.Background(), userID, accountID); err != nil {
+		t.Fatalf("ListGroups failed: %v", err)
+	}
+	if client.callCount("ListDevices") != 1 {
+		t.Errorf("Expected the devices to be listed once, got %d calls", client.callCount("ListDevices"))
+	}
+
+	// Invalidating the account's cache drops its groups too
+	if err := service.invalidateCache(context.Background(), userID, accountID); err != nil {
+		t.Fatalf("invalidateCache failed: %v", err)
+	}
+	if service.cache.Exists(context.Background(), groupsCacheKey(accountID)).Val() != 0 {
+		t.Error("Expected the cached groups to be invalidated with the devices")
+	}
+}
+
+func TestListGroups_NotOwned(t *testing.T) {
+	service, account := newTestDeviceService(t, newFakeProviderClient(newHomeDevices()...))
+
+	if _, err := service.ListGroups(context.Background(), uuid.NewString(), account.ID.String()); !errors.Is(err, ErrAccountNotOwned) {
+		t.Errorf("Expected ErrAccountNotOwned, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/logger"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+const (
+	// idempotencyTTL is how long the result of an action sent with an idempotency key is kept
+	idempotencyTTL = 24 * time.Hour
+	// idempotencyPendingTTL bounds how long a key stays reserved by an action that never
+	// records its result, e.g. because the instance running it stopped
+	idempotencyPendingTTL = 5 * time.Minute
+)
+
+var (
+	// ErrIdempotentActionInProgress is returned when an action is sent again with the key of
+	// an action still running
+	ErrIdempotentActionInProgress = apierror.New(apierror.ErrConflict, "an action with this Idempotency-Key is still in progress")
+	// ErrIdempotencyKeyReused is returned when a key is sent again with a different request
+	ErrIdempotencyKeyReused = &apierror.BadRequestError{Message: "Idempotency-Key was already used for a different request"}
+)
+
+func idempotencyKey(userID, key string) string {
+	return fmt.Sprintf("idempotency:%s:%s", userID, key)
+}
+
+// ActionReplayedError is returned when an action carries an idempotency key that was
+// already used; it holds the result of the original execution, which is not repeated
+type ActionReplayedError struct {
+	Message string // Client-safe error of the original execution, empty when it succeeded
+	Success bool
+}
+
+func (e *ActionReplayedError) Error() string {
+	if e.Success {
+		return "action already executed"
+	}
+	return fmt.Sprintf("action already executed: %s", e.Message)
+}
+
+// idempotentResult is the stored state of an action sent with an idempotency key. Pending
+// holds the key while the action runs; Fingerprint identifies the request the key was
+// first sent with.
+type idempotentResult struct {
+	Fingerprint string `json:"fingerprint"`
+	Error       string `json:"error,omitempty"`
+	Pending     bool   `json:"pending,omitempty"`
+	Success     bool   `json:"success"`
+}
+
+// actionFingerprint identifies an action request by its account, selector and action, so
+// a key is only replayed for the request it was first sent with
+func actionFingerprint(accountID, selector string, action *models.ActionRequest) string {
+	data, _ := json.Marshal(struct {
+		Parameters      map[string]interface{} `json:"parameters"`
+		AccountID       string                 `json:"account_id"`
+		Selector        string                 `json:"selector"`
+		Action          string                 `json:"action"`
+		DeferOnThrottle bool                   `json:"defer_on_throttle"`
+	}{action.Parameters, accountID, selector, action.Action, action.DeferOnThrottle})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// reserveIdempotencyKey holds the user's key for the request identified by fingerprint
+// until its result is recorded. A key already used is answered with the original result,
+// ErrIdempotentActionInProgress while that action runs, or ErrIdempotencyKeyReused when it
+// was sent with another request. Should the cache be unavailable, the action runs unguarded.
+func (s *DeviceService) reserveIdempotencyKey(ctx context.Context, userID, key, fingerprint string) error {
+	cacheKey := idempotencyKey(userID, key)
+	pending, err := json.Marshal(idempotentResult{Fingerprint: fingerprint, Pending: true})
+	if err != nil {
+		return err
+	}
+
+	// A result expiring between both calls frees the key, which is then reserved again
+	for attempt := 0; attempt < 2; attempt++ {
+		reserved, err := s.cache.SetNX(ctx, cacheKey, pending, idempotencyPendingTTL).Result()
+		if err != nil {
+			logger.WithContext(ctx).Warn("Failed to reserve idempotency key", "error", err, "user_id", userID)
+			return nil
+		}
+		if reserved {
+			return nil
+		}
+
+		data, err := s.cache.Get(ctx, cacheKey).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			logger.WithContext(ctx).Warn("Failed to read idempotency key", "error", err, "user_id", userID)
+			return nil
+		}
+
+		var result idempotentResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			return fmt.Errorf("failed to decode idempotent result: %w", err)
+		}
+		switch {
+		case result.Fingerprint != fingerprint:
+			return ErrIdempotencyKeyReused
+		case result.Pending:
+			return ErrIdempotentActionInProgress
+		default:
+			return &ActionReplayedError{Success: result.Success, Message: result.Error}
+		}
+	}
+	return ErrIdempotentActionInProgress
+}
+
+// releaseIdempotencyKey frees a key reserved by an action that failed before reaching the
+// provider, so it can be retried with the same key
+func (s *DeviceService) releaseIdempotencyKey(ctx context.Context, userID, key string) {
+	if err := s.cache.Del(context.WithoutCancel(ctx), idempotencyKey(userID, key)).Err(); err != nil {
+		logger.WithContext(ctx).Warn("Failed to release idempotency key", "error", err, "user_id", userID)
+	}
+}
+
+// setIdempotentResult stores the result of an action sent by the user with key, replacing
+// its reservation
+func (s *DeviceService) setIdempotentResult(ctx context.Context, userID, key, fingerprint string, actionErr error) error {
+	result := idempotentResult{Fingerprint: fingerprint, Success: actionErr == nil}
+	if actionErr != nil {
+		result.Error = replayedErrorMessage(actionErr)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	return s.cache.Set(ctx, idempotencyKey(userID, key), data, idempotencyTTL).Err()
+}
+
+// replayedErrorMessage returns what a replay of a failed action tells the client: the
+// message of errors meant for clients, and a generic one otherwise, since the text of
+// provider and internal errors is not
+func replayedErrorMessage(err error) string {
+	var badRequestErr *apierror.BadRequestError
+	var kindErr *apierror.Error
+	var capabilityErr *CapabilityError
+	var partialErr *providers.PartialSuccessError
+	var providerErr *apierror.ProviderError
+
+	switch {
+	case errors.As(err, &badRequestErr):
+		return badRequestErr.Message
+	case errors.As(err, &kindErr):
+		return kindErr.Message
+	case errors.As(err, &capabilityErr):
+		return capabilityErr.Error()
+	case errors.As(err, &partialErr):
+		return "action failed on some devices"
+	case errors.As(err, &providerErr):
+		return "provider request failed"
+	default:
+		return "action failed"
+	}
+}
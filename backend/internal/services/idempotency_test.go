@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+func TestExecuteAction_IdempotencyKeyExecutesOnce(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Bulb"})
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	newAction := func() *models.ActionRequest {
+		return &models.ActionRequest{
+			Action:         models.ActionPower,
+			Parameters:     map[string]interface{}{"state": models.PowerStateOn},
+			IdempotencyKey: "5f0c2a6e-8d1b-4c3e-9f2a-1b2c3d4e5f60",
+		}
+	}
+
+	if err := service.ExecuteAction(context.Background(), userID, accountID, "id:bulb-1", newAction()); err != nil {
+		t.Fatalf("ExecuteAction failed: %v", err)
+	}
+
+	err := service.ExecuteAction(context.Background(), userID, accountID, "id:bulb-1", newAction())
+	var replayed *ActionReplayedError
+	if !errors.As(err, &replayed) {
+		t.Fatalf("Expected ActionReplayedError, got %v", err)
+	}
+	if !replayed.Success {
+		t.Errorf("Expected the replayed result to be a success, got %+v", replayed)
+	}
+	if client.callCount("SetPower") != 1 {
+		t.Errorf("Expected 1 SetPower call, got %d", client.callCount("SetPower"))
+	}
+
+	// Another key executes again
+	action := newAction()
+	action.IdempotencyKey = "0d9e8f7a-6b5c-4d3e-2f1a-0b9c8d7e6f5a"
+	if err := service.ExecuteAction(context.Background(), userID, accountID, "id:bulb-1", action); err != nil {
+		t.Fatalf("ExecuteAction failed: %v", err)
+	}
+	if client.callCount("SetPower") != 2 {
+		t.Errorf("Expected 2 SetPower calls, got %d", client.callCount("SetPower"))
+	}
+}
+
+func TestExecuteAction_IdempotencyKeyReplaysProviderError(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Bulb"})
+	client.errs["SetPower"] = []error{errors.New("bulb offline")}
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	action := &models.ActionRequest{
+		Action:         models.ActionPower,
+		Parameters:     map[string]interface{}{"state": models.PowerStateOff},
+		IdempotencyKey: "5f0c2a6e-8d1b-4c3e-9f2a-1b2c3d4e5f60",
+	}
+	if err := service.ExecuteAction(context.Background(), userID, accountID, "id:bulb-1", action); err == nil {
+		t.Fatal("Expected the provider error")
+	}
+
+	err := service.ExecuteAction(context.Background(), userID, accountID, "id:bulb-1", action)
+	var replayed *ActionReplayedError
+	if !errors.As(err, &replayed) {
+		t.Fatalf("Expected ActionReplayedError, got %v", err)
+	}
+	if replayed.Success || replayed.Message != "provider request failed" {
+		t.Errorf("Expected the replayed result to report a failure without the provider's error, got %+v", replayed)
+	}
+	if client.callCount("SetPower") != 1 {
+		t.Errorf("Expected 1 SetPower call, got %d", client.callCount("SetPower"))
+	}
+}
+
+func TestExecuteAction_IdempotencyKeyConcurrentRetriesExecuteOnce(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Bulb"})
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	const retries = 10
+	errs := make([]error, retries)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = service.ExecuteAction(context.Background(), userID, accountID, "id:bulb-1", &models.ActionRequest{
+				Action:         models.ActionPower,
+				Parameters:     map[string]interface{}{"state": models.PowerStateOn},
+				IdempotencyKey: "5f0c2a6e-8d1b-4c3e-9f2a-1b2c3d4e5f60",
+			})
+		}()
+	}
+	wg.Wait()
+
+	if client.callCount("SetPower") != 1 {
+		t.Errorf("Expected 1 SetPower call, got %d", client.callCount("SetPower"))
+	}
+	for _, err := range errs {
+		var replayed *ActionReplayedError
+		if err != nil && !errors.Is(err, ErrIdempotentActionInProgress) && !errors.As(err, &replayed) {
+			t.Errorf("Expected the retries to be refused or replayed, got %v", err)
+		}
+	}
+}
+
+func TestExecuteAction_IdempotencyKeyBoundToRequest(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Bulb"})
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	action := &models.ActionRequest{
+		Action:         models.ActionPower,
+		Parameters:     map[string]interface{}{"state": models.PowerStateOn},
+		IdempotencyKey: "5f0c2a6e-8d1b-4c3e-9f2a-1b2c3d4e5f60",
+	}
+	if err := service.ExecuteAction(context.Background(), userID, accountID, "id:bulb-1", action); err != nil {
+		t.Fatalf("ExecuteAction failed: %v", err)
+	}
+
+	// The same key on another selector is not a retry
+	err := service.ExecuteAction(context.Background(), userID, accountID, "id:bulb-2", action)
+	if !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("Expected ErrIdempotencyKeyReused, got %v", err)
+	}
+	if client.callCount("SetPower") != 1 {
+		t.Errorf("Expected 1 SetPower call, got %d", client.callCount("SetPower"))
+	}
+}
+
+func TestExecuteAction_IdempotencyKeyInProgress(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Bulb"})
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	action := &models.ActionRequest{
+		Action:         models.ActionPower,
+		Parameters:     map[string]interface{}{"state": models.PowerStateOn},
+		IdempotencyKey: "5f0c2a6e-8d1b-4c3e-9f2a-1b2c3d4e5f60",
+	}
+
+	// Held by the same action, still running
+	fingerprint := actionFingerprint(accountID, "id:bulb-1", action)
+	if err := service.reserveIdempotencyKey(context.Background(), userID, action.IdempotencyKey, fingerprint); err != nil {
+		t.Fatalf("Failed to reserve key: %v", err)
+	}
+
+	err := service.ExecuteAction(context.Background(), userID, accountID, "id:bulb-1", action)
+	if !errors.Is(err, ErrIdempotentActionInProgress) {
+		t.Fatalf("Expected ErrIdempotentActionInProgress, got %v", err)
+	}
+	if client.callCount("SetPower") != 0 {
+		t.Errorf("Expected no SetPower call, got %d", client.callCount("SetPower"))
+	}
+}
+
+func TestExecuteAction_IdempotencyKeyReleasedBeforeProviderCall(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "bulb-1", Label: "Bulb"})
+	client.errs["ListDevices"] = []error{errors.New("provider down")}
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	action := &models.ActionRequest{
+		Action:         models.ActionTemperature,
+		Parameters:     map[string]interface{}{"kelvin": float64(3500)},
+		PreFlight:      true,
+		IdempotencyKey: "5f0c2a6e-8d1b-4c3e-9f2a-1b2c3d4e5f60",
+	}
+
+	// The preflight check fails before the provider is asked to act
+	if err := service.ExecuteAction(context.Background(), userID, accountID, "id:bulb-1", action); err == nil {
+		t.Fatal("Expected the preflight check to fail")
+	}
+
+	// So the retry acts
+	action.PreFlight = false
+	if err := service.ExecuteAction(context.Background(), userID, accountID, "id:bulb-1", action); err != nil {
+		t.Fatalf("ExecuteAction failed: %v", err)
+	}
+	if client.callCount("SetColorTemperature") != 1 {
+		t.Errorf("Expected 1 SetColorTemperature call, got %d", client.callCount("SetColorTemperature"))
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+)
+
+func TestImpersonateUser(t *testing.T) {
+	service := newTestAuthService(t)
+	user := service.user
+	adminID := uuid.New()
+
+	resp, err := service.ImpersonateUser(context.Background(), adminID, user.ID, " debugging ticket #1234 ", nil, nil)
+	if err != nil {
+		t.Fatalf("ImpersonateUser failed: %v", err)
+	}
+
+	claims, err := service.jwtService.ValidateAccessToken(resp.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected a valid access token, got %v", err)
+	}
+	if claims.UserID != user.ID || claims.ImpersonatedBy != adminID.String() || claims.ImpersonationReason != "debugging ticket #1234" {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+	if lifetime := time.Until(resp.ExpiresAt); lifetime > 15*time.Minute || lifetime < 14*time.Minute {
+		t.Errorf("Expected the token to last 15 minutes, got %v", lifetime)
+	}
+	if service.tokens.activeTokens() != 0 {
+		t.Errorf("Expected no refresh token, got %d", service.tokens.activeTokens())
+	}
+
+	if len(service.audit.events) != 1 || service.audit.events[0].EventType != models.EventAdminImpersonation || *service.audit.events[0].UserID != user.ID {
+		t.Fatalf("Expected an impersonation event for the user, got %+v", service.audit.events)
+	}
+	var metadata map[string]string
+	if err := json.Unmarshal(service.audit.events[0].Metadata, &metadata); err != nil {
+		t.Fatalf("Failed to decode metadata: %v", err)
+	}
+	if metadata["admin_id"] != adminID.String() || metadata["reason"] != "debugging ticket #1234" {
+		t.Errorf("Expected the admin ID and reason in metadata, got %v", metadata)
+	}
+}
+
+func TestImpersonateUser_Errors(t *testing.T) {
+	service := newTestAuthService(t)
+	user := service.user
+	ctx := context.Background()
+
+	var badRequest *apierror.BadRequestError
+	if _, err := service.ImpersonateUser(ctx, uuid.New(), user.ID, "  ", nil, nil); !errors.As(err, &badRequest) {
+		t.Errorf("Expected a BadRequestError without a reason, got %v", err)
+	}
+	if _, err := service.ImpersonateUser(ctx, user.ID, user.ID, "testing", nil, nil); !errors.As(err, &badRequest) {
+		t.Errorf("Expected a BadRequestError impersonating yourself, got %v", err)
+	}
+	if _, err := service.ImpersonateUser(ctx, uuid.New(), uuid.New(), "testing", nil, nil); !errors.Is(err, ErrImpersonatedUserNotFound) {
+		t.Errorf("Expected ErrImpersonatedUserNotFound, got %v", err)
+	}
+	user.Role = "admin"
+	if _, err := service.ImpersonateUser(ctx, uuid.New(), user.ID, "testing", nil, nil); !errors.Is(err, apierror.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden impersonating an admin, got %v", err)
+	}
+	if len(service.audit.events) != 0 {
+		t.Errorf("Expected no audit events, got %d", len(service.audit.events))
+	}
+}
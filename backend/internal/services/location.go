@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// ErrLocationNotFound is returned when no cached device belongs to the requested location
+var ErrLocationNotFound = apierror.New(apierror.ErrNotFound, "location not found")
+
+// ListLocations returns the locations of an account, derived from its device list
+func (s *DeviceService) ListLocations(ctx context.Context, userID, accountID string) ([]*models.Location, error) {
+	devices, err := s.accountDevices(ctx, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupLocations(devices), nil
+}
+
+// ApplyLocationState applies a combined state to every device in a location using the
+// provider's location selector. Each property is applied independently so that one
+// failing property does not prevent the others from being applied.
+func (s *DeviceService) ApplyLocationState(ctx context.Context, userID, accountID, locationID string, state *models.StateRequest) (*models.StateResult, error) {
+	actions, err := state.Actions()
+	if err != nil {
+		return nil, fmt.Errorf("invalid state: %w", err)
+	}
+
+	// Validate the location against the (cached) device list; this also verifies ownership
+	devices, err := s.accountDevices(ctx, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasLocation(devices, locationID) {
+		return nil, ErrLocationNotFound
+	}
+
+	account, err := s.accountRepo.FindByIDString(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	// Check rate limit
+	if rateLimitErr := s.checkRateLimit(ctx, userID, account, rateLimitWrite); rateLimitErr != nil {
+		return nil, rateLimitErr
+	}
+
+	// Get decrypted token
+	token, err := s.accountRepo.GetDecryptedToken(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	// Create provider client
+	client, err := s.newClient(providers.Provider(account.Provider))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider client: %w", err)
+	}
+
+	selector := "location_id:" + locationID
+	result := &models.StateResult{Results: make([]models.ActionResult, 0, len(actions))}
+	for _, action := range actions {
+		actionResult := models.ActionResult{Action: action.Action, Success: true}
+		if err := s.executeProviderAction(ctx, account, client, token, selector, action); err != nil {
+			s.validations.invalidateOnUnauthorized(ctx, accountID, err)
+			actionResult.Success = false
+			actionResult.Error = err.Error()
+			result.Failed++
+		} else {
+			result.Succeeded++
+		}
+		result.Results = append(result.Results, actionResult)
+	}
+
+	// Invalidate cache for this account
+	if err := s.invalidateCache(ctx, userID, accountID); err != nil {
+		// Log error but don't fail the request
+		_ = err
+	}
+
+	return result, nil
+}
+
+// groupLocations aggregates devices into locations, listing each location's groups once
+func groupLocations(devices []*models.Device) []*models.Location {
+	byID := make(map[string]*models.Location)
+	seenGroups := make(map[string]map[string]struct{})
+
+	for _, device := range devices {
+		if device.Location == nil || device.Location.ID == "" {
+			continue
+		}
+
+		location, ok := byID[device.Location.ID]
+		if !ok {
+			location = &models.Location{
+				ID:     device.Location.ID,
+				Name:   device.Location.Name,
+				Groups: make([]models.DeviceGroup, 0),
+			}
+			byID[device.Location.ID] = location
+			seenGroups[device.Location.ID] = make(map[string]struct{})
+		}
+		location.DeviceCount++
+
+		if device.Group != nil && device.Group.ID != "" {
+			if _, seen := seenGroups[location.ID][device.Group.ID]; !seen {
+				seenGroups[location.ID][device.Group.ID] = struct{}{}
+				location.Groups = append(location.Groups, *device.Group)
+			}
+		}
+	}
+
+	locations := make([]*models.Location, 0, len(byID))
+	for _, location := range byID {
+		sort.Slice(location.Groups, func(i, j int) bool {
+			return location.Groups[i].Name < location.Groups[j].Name
+		})
+		locations = append(locations, location)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i].Name < locations[j].Name
+	})
+
+	return locations
+}
+
+// hasLocation reports whether any device belongs to the location
+func hasLocation(devices []*models.Device, locationID string) bool {
+	for _, device := range devices {
+		if device.Location != nil && device.Location.ID == locationID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// newHomeDevices returns a home location spread across two rooms plus a device in another location
+func newHomeDevices() []*providers.Device {
+	home := &providers.DeviceLocation{ID: "loc-home", Name: "Home"}
+	office := &providers.DeviceLocation{ID: "loc-office", Name: "Office"}
+
+	return []*providers.Device{
+		{ID: "bulb-1", Label: "Kitchen 1", Location: home, Group: &providers.DeviceGroup{ID: "grp-kitchen", Name: "Kitchen"}},
+		{ID: "bulb-2", Label: "Kitchen 2", Location: home, Group: &providers.DeviceGroup{ID: "grp-kitchen", Name: "Kitchen"}},
+		{ID: "bulb-3", Label: "Bedroom", Location: home, Group: &providers.DeviceGroup{ID: "grp-bedroom", Name: "Bedroom"}},
+		{ID: "bulb-4", Label: "Desk", Location: office, Group: &providers.DeviceGroup{ID: "grp-desk", Name: "Desk"}},
+	}
+}
+
+func TestListLocations_GroupsDevicesByLocation(t *testing.T) {
+	service, account := newTestDeviceService(t, newFakeProviderClient(newHomeDevices()...))
+
+	locations, err := service.ListLocations(context.Background(), account.OwnerUserID.String(), account.ID.String())
+	if err != nil {
+		t.Fatalf("ListLocations failed: %v", err)
+	}
+
+	if len(locations) != 2 {
+		t.Fatalf("Expected 2 locations, got %d", len(locations))
+	}
+
+	home := locations[0]
+	if home.ID != "loc-home" || home.DeviceCount != 3 {
+		t.Errorf("Expected loc-home with 3 devices, got %s with %d", home.ID, home.DeviceCount)
+	}
+	if len(home.Groups) != 2 || home.Groups[0].ID != "grp-bedroom" || home.Groups[1].ID != "grp-kitchen" {
+		t.Errorf("Expected bedroom and kitchen groups, got %+v", home.Groups)
+	}
+}
+
+func TestApplyLocationState_UsesLocationSelectorAndReportsPartialFailure(t *testing.T) {
+	client := newFakeProviderClient(newHomeDevices()...)
+	client.errs["SetBrightness"] = []error{errors.New("unexpected status code: 500")}
+	service, account := newTestDeviceService(t, client)
+
+	power := models.PowerStateOn
+	brightness := 0.4
+	state := &models.StateRequest{Power: &power, Brightness: &brightness}
+
+	result, err := service.ApplyLocationState(context.Background(), account.OwnerUserID.String(), account.ID.String(), "loc-home", state)
+	if err != nil {
+		t.Fatalf("ApplyLocationState failed: %v", err)
+	}
+
+	if !result.Partial() {
+		t.Errorf("Expected partial result, got %+v", result)
+	}
+	if result.Succeeded != 1 || result.Failed != 1 {
+		t.Errorf("Expected 1 success and 1 failure, got %d and %d", result.Succeeded, result.Failed)
+	}
+	if result.Results[1].Action != models.ActionBrightness || result.Results[1].Success {
+		t.Errorf("Expected brightness to fail, got %+v", result.Results[1])
+	}
+
+	for _, selector := range client.selectors {
+		if selector != "location_id:loc-home" {
+			t.Errorf("Expected location selector, got %q", selector)
+		}
+	}
+}
+
+func TestApplyLocationState_UnknownLocation(t *testing.T) {
+	service, account := newTestDeviceService(t, newFakeProviderClient(newHomeDevices()...))
+
+	power := models.PowerStateOff
+	_, err := service.ApplyLocationState(context.Background(), account.OwnerUserID.String(), account.ID.String(), "loc-missing", &models.StateRequest{Power: &power})
+	if !errors.Is(err, ErrLocationNotFound) {
+		t.Fatalf("Expected ErrLocationNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/oauth"
+)
+
+var (
+	// ErrGoogleSignInDisabled is returned when Google sign-in is used without a configured client ID.
+	ErrGoogleSignInDisabled = errors.New("google sign-in is not configured")
+	// ErrOAuthEmailNotVerified is returned when the provider has not verified the user's email.
+	ErrOAuthEmailNotVerified = errors.New("provider email not verified")
+	// ErrOAuthAccountConflict is returned when the email belongs to a password account whose
+	// email was never verified, so it cannot be proven to belong to the same person.
+	ErrOAuthAccountConflict = errors.New("email already registered to an unverified account")
+)
+
+// IDTokenVerifier verifies a provider ID token and returns the identity it asserts
+type IDTokenVerifier interface {
+	Verify(ctx context.Context, idToken string) (*oauth.Identity, error)
+}
+
+// LoginWithGoogle signs a user in with a Google ID token. Unknown users are signed up
+// without a password; an existing user with the same verified email is linked to the
+// Google account. Users with 2FA enabled get a pending token, as with a password login.
+func (s *AuthService) LoginWithGoogle(ctx context.Context, idToken string, userAgent, ipAddress *string) (*LoginResponse, error) {
+	if s.googleVerifier == nil {
+		return nil, ErrGoogleSignInDisabled
+	}
+
+	identity, err := s.googleVerifier.Verify(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+	if !identity.EmailVerified {
+		return nil, ErrOAuthEmailNotVerified
+	}
+
+	user, err := s.userForIdentity(ctx, oauth.ProviderGoogle, identity, userAgent, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	// With 2FA enabled the provider alone only earns a pending token
+	if err := s.requireSecondFactor(user); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.createSession(ctx, user, userAgent, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, models.EventLogin, user.ID, userAgent, ipAddress, map[string]interface{}{"method": oauth.ProviderGoogle})
+
+	return resp, nil
+}
+
+// userForIdentity returns the user linked to a provider identity, linking or creating one by email
+func (s *AuthService) userForIdentity(ctx context.Context, provider string, identity *oauth.Identity, userAgent, ipAddress *string) (*models.User, error) {
+	link, err := s.oauthRepo.FindByProviderUserID(ctx, provider, identity.Subject)
+	if err == nil {
+		user, err := s.userRepo.GetByID(ctx, link.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, repository.ErrOAuthProviderNotFound) {
+		return nil, err
+	}
+
+	emailAddr := strings.TrimSpace(strings.ToLower(identity.Email))
+	user, err := s.userRepo.GetByEmail(ctx, emailAddr)
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		user, err = s.userRepo.Create(ctx, models.CreateUserParams{
+			Email:         emailAddr,
+			EmailVerified: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+		s.recordAudit(ctx, models.EventSignup, user.ID, userAgent, ipAddress, map[string]interface{}{"method": provider})
+	case err != nil:
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	case !user.EmailVerified:
+		// Anyone can sign up with an address they don't own; merging would hand them this login
+		return nil, ErrOAuthAccountConflict
+	}
+
+	if err := s.oauthRepo.Link(ctx, user.ID, provider, identity.Subject); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
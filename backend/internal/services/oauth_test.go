@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/jwt"
+	"github.com/lightshare/backend/pkg/oauth"
+)
+
+// MockOAuthProviderRepository is an in-memory OAuth provider link repository for testing
+type MockOAuthProviderRepository struct {
+	links []*models.OAuthProvider
+}
+
+func (m *MockOAuthProviderRepository) FindByProviderUserID(_ context.Context, provider, providerUserID string) (*models.OAuthProvider, error) {
+	for _, link := range m.links {
+		if link.Provider == provider && link.ProviderUserID == providerUserID {
+			return link, nil
+		}
+	}
+	return nil, repository.ErrOAuthProviderNotFound
+}
+
+func (m *MockOAuthProviderRepository) Link(_ context.Context, userID uuid.UUID, provider, providerUserID string) error {
+	m.links = append(m.links, &models.OAuthProvider{
+		ID:             uuid.New(),
+		UserID:         userID,
+		Provider:       provider,
+		ProviderUserID: providerUserID,
+		CreatedAt:      time.Now(),
+	})
+	return nil
+}
+
+// fakeIDTokenVerifier accepts the token "valid" as its identity
+type fakeIDTokenVerifier struct {
+	identity oauth.Identity
+}
+
+func (f *fakeIDTokenVerifier) Verify(_ context.Context, idToken string) (*oauth.Identity, error) {
+	if idToken != "valid" {
+		return nil, oauth.ErrInvalidIDToken
+	}
+	identity := f.identity
+	return &identity, nil
+}
+
+// newTestOAuthService returns an AuthService with the dependencies Google sign-in needs
+func newTestOAuthService(users ...*models.User) (*AuthService, *mockUserRepository, *MockOAuthProviderRepository) {
+	userRepo := &mockUserRepository{users: users}
+	oauthRepo := &MockOAuthProviderRepository{}
+
+	service := &AuthService{
+		userRepo:         userRepo,
+		refreshTokenRepo: NewMockRefreshTokenRepository(),
+		auditRepo:        &MockAuditRepository{},
+		oauthRepo:        oauthRepo,
+		jwtService:       jwt.New(jwt.Config{Secret: "test-secret", AccessExpiration: time.Hour, RefreshExpiration: time.Hour}),
+		googleVerifier: &fakeIDTokenVerifier{identity: oauth.Identity{
+			Subject:       "google-user-1",
+			Email:         "User@Example.com",
+			EmailVerified: true,
+		}},
+	}
+	return service, userRepo, oauthRepo
+}
+
+func TestLoginWithGoogle_SignsUpNewUser(t *testing.T) {
+	service, userRepo, oauthRepo := newTestOAuthService()
+	ctx := context.Background()
+
+	resp, err := service.LoginWithGoogle(ctx, "valid", nil, nil)
+	if err != nil {
+		t.Fatalf("LoginWithGoogle failed: %v", err)
+	}
+	if resp.AccessToken == "" || resp.RefreshToken == "" {
+		t.Error("Expected a token pair")
+	}
+
+	if len(userRepo.users) != 1 {
+		t.Fatalf("Expected 1 user, got %d", len(userRepo.users))
+	}
+	user := userRepo.users[0]
+	if user.Email != "user@example.com" || !user.EmailVerified || user.PasswordHash != "" {
+		t.Errorf("Expected a verified, passwordless user, got %+v", user)
+	}
+	if len(oauthRepo.links) != 1 || oauthRepo.links[0].UserID != user.ID {
+		t.Fatalf("Expected the Google account to be linked to the new user")
+	}
+
+	// Signing in again reuses the link instead of creating another user
+	if _, err := service.LoginWithGoogle(ctx, "valid", nil, nil); err != nil {
+		t.Fatalf("LoginWithGoogle failed: %v", err)
+	}
+	if len(userRepo.users) != 1 || len(oauthRepo.links) != 1 {
+		t.Errorf("Expected no new user or link, got %d users and %d links", len(userRepo.users), len(oauthRepo.links))
+	}
+
+	// The passwordless user cannot log in with an empty password
+	if _, err := service.Login(ctx, LoginRequest{Email: "user@example.com"}, nil, nil); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+}
+
+func TestLoginWithGoogle_LinksVerifiedPasswordUser(t *testing.T) {
+	existing := &models.User{ID: uuid.New(), Email: "user@example.com", PasswordHash: "hash", EmailVerified: true, Role: "user"}
+	service, userRepo, oauthRepo := newTestOAuthService(existing)
+
+	resp, err := service.LoginWithGoogle(context.Background(), "valid", nil, nil)
+	if err != nil {
+		t.Fatalf("LoginWithGoogle failed: %v", err)
+	}
+	if resp.User.ID != existing.ID {
+		t.Errorf("Expected to sign in as the existing user, got %s", resp.User.ID)
+	}
+	if len(userRepo.users) != 1 {
+		t.Errorf("Expected no new user, got %d users", len(userRepo.users))
+	}
+	if len(oauthRepo.links) != 1 || oauthRepo.links[0].UserID != existing.ID {
+		t.Error("Expected the Google account to be linked to the existing user")
+	}
+}
+
+func TestLoginWithGoogle_RefusesUnverifiedPasswordUser(t *testing.T) {
+	existing := &models.User{ID: uuid.New(), Email: "user@example.com", PasswordHash: "hash", Role: "user"}
+	service, _, oauthRepo := newTestOAuthService(existing)
+
+	if _, err := service.LoginWithGoogle(context.Background(), "valid", nil, nil); !errors.Is(err, ErrOAuthAccountConflict) {
+		t.Fatalf("Expected ErrOAuthAccountConflict, got %v", err)
+	}
+	if len(oauthRepo.links) != 0 {
+		t.Error("Expected no link to be created")
+	}
+}
+
+func TestLoginWithGoogle_Rejections(t *testing.T) {
+	service, _, _ := newTestOAuthService()
+	ctx := context.Background()
+
+	if _, err := service.LoginWithGoogle(ctx, "forged", nil, nil); !errors.Is(err, oauth.ErrInvalidIDToken) {
+		t.Errorf("Expected ErrInvalidIDToken, got %v", err)
+	}
+
+	service.googleVerifier.(*fakeIDTokenVerifier).identity.EmailVerified = false
+	if _, err := service.LoginWithGoogle(ctx, "valid", nil, nil); !errors.Is(err, ErrOAuthEmailNotVerified) {
+		t.Errorf("Expected ErrOAuthEmailNotVerified, got %v", err)
+	}
+
+	service.googleVerifier = nil
+	if _, err := service.LoginWithGoogle(ctx, "valid", nil, nil); !errors.Is(err, ErrGoogleSignInDisabled) {
+		t.Errorf("Expected ErrGoogleSignInDisabled, got %v", err)
+	}
+}
+
+func TestLoginWithGoogle_RequiresSecondFactor(t *testing.T) {
+	existing := &models.User{ID: uuid.New(), Email: "user@example.com", EmailVerified: true, TOTPEnabled: true, Role: "user"}
+	service, _, _ := newTestOAuthService(existing)
+
+	_, err := service.LoginWithGoogle(context.Background(), "valid", nil, nil)
+	var mfaErr *MFARequiredError
+	if !errors.As(err, &mfaErr) || mfaErr.PendingToken == "" {
+		t.Errorf("Expected MFARequiredError with a pending token, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+)
+
+// accountDeviceList is the device list of a single account, in provider order
+type accountDeviceList struct {
+	accountID string
+	devices   []*models.Device
+}
+
+// paginateDevices returns a page of devices across accounts, in account then provider order.
+// Cursors point at the first device of the next page by account and index, so a page
+// boundary stays put when other accounts' device lists change.
+func paginateDevices(lists []accountDeviceList, opts models.PaginationOptions) (*models.DevicePage, error) {
+	type position struct {
+		accountID string
+		index     int
+	}
+
+	total := 0
+	for _, list := range lists {
+		total += len(list.devices)
+	}
+
+	devices := make([]*models.Device, 0, total)
+	positions := make([]position, 0, total)
+	start := 0
+	found := opts.Cursor == ""
+
+	var cursorAccountID string
+	var cursorIndex int
+	if !found {
+		var err error
+		cursorAccountID, cursorIndex, err = models.DecodeDeviceCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, list := range lists {
+		if list.accountID == cursorAccountID {
+			// The account may have lost devices since the cursor was issued
+			start = len(devices) + min(cursorIndex, len(list.devices))
+			found = true
+		}
+		for i, device := range list.devices {
+			devices = append(devices, device)
+			positions = append(positions, position{accountID: list.accountID, index: i})
+		}
+	}
+
+	if !found {
+		return nil, models.ErrInvalidCursor
+	}
+
+	if opts.Sort != "" {
+		return sortedDevicePage(devices, opts)
+	}
+
+	end := min(start+opts.PageLimit(), len(devices))
+	page := &models.DevicePage{
+		Devices:       devices[start:end],
+		Total:         total,
+		FilteredCount: total,
+	}
+	if end < len(devices) {
+		page.NextCursor = models.EncodeDeviceCursor(positions[end].accountID, positions[end].index)
+	}
+
+	return page, nil
+}
+
+// sortedDevicePage returns the first page of devices in the order opts.Sort selects, ties
+// keeping the default order. A sorted listing is a single page: its order shifts as device
+// states change, so no cursor could hold a position in it.
+func sortedDevicePage(devices []*models.Device, opts models.PaginationOptions) (*models.DevicePage, error) {
+	if opts.Cursor != "" {
+		return nil, &apierror.BadRequestError{Message: "sorted device listings have a single page and take no cursor"}
+	}
+
+	sorted := slices.Clone(devices)
+	slices.SortStableFunc(sorted, func(a, b *models.Device) int {
+		if opts.Sort == models.DeviceSortHealthDesc {
+			return cmp.Compare(b.HealthScore, a.HealthScore)
+		}
+		return cmp.Compare(a.HealthScore, b.HealthScore)
+	})
+
+	return &models.DevicePage{
+		Devices:       sorted[:min(opts.PageLimit(), len(sorted))],
+		Total:         len(sorted),
+		FilteredCount: len(sorted),
+	}, nil
+}
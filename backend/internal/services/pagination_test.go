@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+func TestListAccountDevices_Paginates(t *testing.T) {
+	devices := make([]*providers.Device, 5)
+	for i := range devices {
+		devices[i] = &providers.Device{ID: fmt.Sprintf("d%d", i), Label: fmt.Sprintf("Light %d", i)}
+	}
+	client := newFakeProviderClient(devices...)
+	service, account := newTestDeviceService(t, client)
+	ctx := context.Background()
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	var seen []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("Expected pagination to terminate")
+		}
+
+		page, err := service.ListAccountDevices(ctx, userID, accountID, models.DeviceFilter{}, models.PaginationOptions{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("ListAccountDevices failed: %v", err)
+		}
+		if page.Total != 5 {
+			t.Errorf("Expected total 5, got %d", page.Total)
+		}
+		for _, device := range page.Devices {
+			seen = append(seen, device.ID)
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	if fmt.Sprint(seen) != "[d0 d1 d2 d3 d4]" {
+		t.Errorf("Expected every device exactly once in order, got %v", seen)
+	}
+
+	// Later pages come from the cache rather than the provider
+	if calls := client.callCount("ListDevices"); calls != 1 {
+		t.Errorf("Expected 1 provider call, got %d", calls)
+	}
+}
+
+func TestListAccountDevices_RejectsForeignCursor(t *testing.T) {
+	service, account := newTestDeviceService(t, newFakeProviderClient(&providers.Device{ID: "d0"}))
+	ctx := context.Background()
+
+	for _, cursor := range []string{"not base64!", models.EncodeDeviceCursor("other-account", 0)} {
+		_, err := service.ListAccountDevices(ctx, account.OwnerUserID.String(), account.ID.String(), models.DeviceFilter{}, models.PaginationOptions{Cursor: cursor})
+		if !errors.Is(err, models.ErrInvalidCursor) {
+			t.Errorf("Cursor %q: expected ErrInvalidCursor, got %v", cursor, err)
+		}
+	}
+}
+
+func TestListDevices_CursorOfFailedAccountResumesAfterIt(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "d0"}, &providers.Device{ID: "d1"})
+	service, account := newTestDeviceService(t, client)
+	addTestAccounts(t, service, account, 2)
+	ctx := context.Background()
+	userID := account.OwnerUserID.String()
+
+	first, err := service.ListDevices(ctx, userID, models.PaginationOptions{Limit: 3})
+	if err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	failedAccountID, _, err := models.DecodeDeviceCursor(first.NextCursor)
+	if err != nil {
+		t.Fatalf("Expected a cursor within the second account, got %q: %v", first.NextCursor, err)
+	}
+
+	// The account of the cursor can't be fetched on the next page
+	if err := service.cache.Del(ctx, devicesCacheKey(failedAccountID)).Err(); err != nil {
+		t.Fatalf("Failed to clear cache: %v", err)
+	}
+	client.errs["ListDevices"] = []error{errors.New("unexpected status code: 503")}
+
+	second, err := service.ListDevices(ctx, userID, models.PaginationOptions{Limit: 3, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("Expected the listing to go on past the failed account, got %v", err)
+	}
+	if len(second.Devices) != 2 || len(second.Errors) != 1 || second.Errors[0].AccountID != failedAccountID {
+		t.Errorf("Expected the third account's devices and the failed account's error, got %d devices and %+v", len(second.Devices), second.Errors)
+	}
+}
+
+func TestListAccountDevices_Filters(t *testing.T) {
+	kitchen := &providers.DeviceGroup{ID: "g1", Name: "Kitchen"}
+	living := &providers.DeviceGroup{ID: "g2", Name: "Living Room"}
+	home := &providers.DeviceLocation{ID: "l1", Name: "Home"}
+	office := &providers.DeviceLocation{ID: "l2", Name: "Office"}
+	client := newFakeProviderClient(
+		&providers.Device{ID: "d0", Label: "Kitchen Ceiling", Power: "on", Group: kitchen, Location: home, Capabilities: []string{"color"}},
+		&providers.Device{ID: "d1", Label: "Kitchen Counter", Power: "off", Group: kitchen, Location: home},
+		&providers.Device{ID: "d2", Label: "Sofa Lamp", Power: "on", Group: living, Location: home, Capabilities: []string{"color", "temperature"}},
+		&providers.Device{ID: "d3", Label: "Desk", Power: "on", Group: living, Location: office, Capabilities: []string{"temperature"}},
+	)
+	service, account := newTestDeviceService(t, client)
+	ctx := context.Background()
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	tests := []struct {
+		name   string
+		filter models.DeviceFilter
+		want   string
+	}{
+		{name: "no filter", filter: models.DeviceFilter{}, want: "[d0 d1 d2 d3]"},
+		{name: "label substring is case-insensitive", filter: models.DeviceFilter{LabelContains: "kitchen"}, want: "[d0 d1]"},
+		{name: "group", filter: models.DeviceFilter{GroupName: "living room"}, want: "[d2 d3]"},
+		{name: "location", filter: models.DeviceFilter{LocationName: "Office"}, want: "[d3]"},
+		{name: "power", filter: models.DeviceFilter{PowerState: models.PowerStateOff}, want: "[d1]"},
+		{name: "capability", filter: models.DeviceFilter{Capability: "color"}, want: "[d0 d2]"},
+		{name: "label and power", filter: models.DeviceFilter{LabelContains: "kitchen", PowerState: models.PowerStateOn}, want: "[d0]"},
+		{name: "location, group and capability", filter: models.DeviceFilter{LocationName: "Home", GroupName: "Living Room", Capability: "temperature"}, want: "[d2]"},
+		{name: "no match", filter: models.DeviceFilter{GroupName: "Kitchen", Capability: "temperature"}, want: "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := service.ListAccountDevices(ctx, userID, accountID, tt.filter, models.PaginationOptions{})
+			if err != nil {
+				t.Fatalf("ListAccountDevices failed: %v", err)
+			}
+
+			ids := make([]string, 0, len(page.Devices))
+			for _, device := range page.Devices {
+				ids = append(ids, device.ID)
+			}
+			if fmt.Sprint(ids) != tt.want {
+				t.Errorf("Expected %s, got %v", tt.want, ids)
+			}
+			if page.Total != 4 {
+				t.Errorf("Expected total 4, got %d", page.Total)
+			}
+			if page.FilteredCount != len(ids) {
+				t.Errorf("Expected filtered count %d, got %d", len(ids), page.FilteredCount)
+			}
+		})
+	}
+}
+
+func TestListAccountDevices_PaginatesFilteredDevices(t *testing.T) {
+	devices := make([]*providers.Device, 6)
+	for i := range devices {
+		power := "off"
+		if i%2 == 0 {
+			power = "on"
+		}
+		devices[i] = &providers.Device{ID: fmt.Sprintf("d%d", i), Label: fmt.Sprintf("Light %d", i), Power: power}
+	}
+	service, account := newTestDeviceService(t, newFakeProviderClient(devices...))
+	ctx := context.Background()
+	filter := models.DeviceFilter{PowerState: models.PowerStateOn}
+
+	first, err := service.ListAccountDevices(ctx, account.OwnerUserID.String(), account.ID.String(), filter, models.PaginationOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListAccountDevices failed: %v", err)
+	}
+	second, err := service.ListAccountDevices(ctx, account.OwnerUserID.String(), account.ID.String(), filter, models.PaginationOptions{Limit: 2, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("ListAccountDevices failed: %v", err)
+	}
+
+	if first.FilteredCount != 3 || first.Total != 6 {
+		t.Errorf("Expected 3 of 6 devices to match, got %d of %d", first.FilteredCount, first.Total)
+	}
+	if len(second.Devices) != 1 || second.Devices[0].ID != "d4" || second.NextCursor != "" {
+		t.Errorf("Expected the last page to hold only d4, got %d devices", len(second.Devices))
+	}
+}
+
+func TestListDevices_SortsByHealth(t *testing.T) {
+	client := newFakeProviderClient(
+		&providers.Device{ID: "healthy", Connected: true, Reachable: true, Power: models.PowerStateOn, Brightness: 1},
+		&providers.Device{ID: "offline"},
+		&providers.Device{ID: "dark", Connected: true, Reachable: true, Power: models.PowerStateOn},
+		&providers.Device{ID: "off", Connected: true, Reachable: true, Power: models.PowerStateOff, Brightness: 1},
+	)
+	service, account := newTestDeviceService(t, client)
+	ctx := context.Background()
+	userID := account.OwnerUserID.String()
+
+	tests := []struct {
+		sort string
+		want string
+	}{
+		{models.DeviceSortHealthAsc, "[offline dark]"},
+		{models.DeviceSortHealthDesc, "[healthy off]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			page, err := service.ListDevices(ctx, userID, models.PaginationOptions{Sort: tt.sort, Limit: 2})
+			if err != nil {
+				t.Fatalf("ListDevices failed: %v", err)
+			}
+
+			var ids []string
+			for _, device := range page.Devices {
+				ids = append(ids, device.ID)
+			}
+			if fmt.Sprint(ids) != tt.want {
+				t.Errorf("Expected %s, got %v", tt.want, ids)
+			}
+			if page.Total != 4 || page.NextCursor != "" {
+				t.Errorf("Expected a single page out of 4 devices, got total %d and cursor %q", page.Total, page.NextCursor)
+			}
+		})
+	}
+
+	cursor := models.EncodeDeviceCursor(account.ID.String(), 1)
+	_, err := service.ListDevices(ctx, userID, models.PaginationOptions{Sort: models.DeviceSortHealthAsc, Cursor: cursor})
+	var badRequestErr *apierror.BadRequestError
+	if !errors.As(err, &badRequestErr) {
+		t.Errorf("Expected a bad request for a sorted listing with a cursor, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/colorconv"
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+// ApplyPalette spreads a palette across the lights of an account a selector targets, in
+// alphabetical order of label, following the request's distribution. Lights are set in
+// parallel, as applyColorsToLights does, and one write is counted against the rate limits
+// for the whole palette.
+// Failures of individual lights are counted in the result; an error is returned only
+// when the palette could not be applied at all.
+func (s *DeviceService) ApplyPalette(ctx context.Context, userID string, req *models.ApplyPaletteRequest) (*models.ApplyPaletteResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, &apierror.BadRequestError{Message: err.Error(), Cause: err}
+	}
+
+	// Get account and verify ownership
+	account, err := s.accountRepo.FindByIDString(ctx, req.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	if account.OwnerUserID.String() != userID {
+		return nil, ErrAccountNotOwned
+	}
+	if account.IsReadOnly() {
+		return nil, ErrAccountReadOnly
+	}
+
+	devices, err := s.cachedOrFetchDevices(ctx, userID, account)
+	if err != nil {
+		return nil, err
+	}
+	lights := paletteLights(selectDevices(devices, req.Selector))
+	if len(lights) == 0 {
+		return nil, ErrNoDevicesMatchSelector
+	}
+	colors := distributePalette(req.Palette, len(lights), req.GetDistribution())
+
+	// Check rate limit
+	if rateLimitErr := s.checkRateLimit(ctx, userID, account, rateLimitWrite); rateLimitErr != nil {
+		return nil, rateLimitErr
+	}
+
+	errs, err := s.applyColorsToLights(ctx, account, lights, colors, req.GetDuration())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ApplyPaletteResult{}
+	for _, err := range errs {
+		if err != nil {
+			result.Failed++
+			continue
+		}
+		result.AppliedTo++
+	}
+
+	// Invalidate cache for this account
+	if err := s.invalidateCache(ctx, userID, req.AccountID); err != nil {
+		// Log error but don't fail the request
+		logger.WithContext(ctx).Warn("Failed to clear device cache", "error", err, "account_id", req.AccountID)
+	}
+
+	return result, nil
+}
+
+// paletteLights returns the devices that take a color or a color temperature, sorted by label
+func paletteLights(devices []*models.Device) []*models.Device {
+	lights := make([]*models.Device, 0, len(devices))
+	for _, device := range devices {
+		if device.SupportsColor() || device.SupportsTemperature() {
+			lights = append(lights, device)
+		}
+	}
+
+	sort.SliceStable(lights, func(i, j int) bool {
+		return strings.ToLower(lights[i].Label) < strings.ToLower(lights[j].Label)
+	})
+	return lights
+}
+
+// distributePalette returns the color of each of count lights, following distribution
+func distributePalette(palette []models.ColorEntry, count int, distribution string) []models.ColorEntry {
+	colors := make([]models.ColorEntry, count)
+	for i := range colors {
+		if distribution == models.PaletteDistributionGradient {
+			colors[i] = gradientColor(palette, i, count)
+		} else {
+			colors[i] = palette[i%len(palette)]
+		}
+	}
+
+	if distribution == models.PaletteDistributionRandom {
+		rand.Shuffle(len(colors), func(i, j int) {
+			colors[i], colors[j] = colors[j], colors[i]
+		})
+	}
+	return colors
+}
+
+// gradientColor returns the color of light i of count on a gradient running through the
+// palette colors, evenly spaced from the first light to the last. The hue of a white has
+// no meaning, so fading from or to one keeps the other color's hue.
+func gradientColor(palette []models.ColorEntry, i, count int) models.ColorEntry {
+	if count == 1 || len(palette) == 1 {
+		return palette[0]
+	}
+
+	position := float64(i) * float64(len(palette)-1) / float64(count-1)
+	index := int(position)
+	if index >= len(palette)-1 {
+		return palette[len(palette)-1]
+	}
+	t := position - float64(index)
+	from, to := palette[index], palette[index+1]
+
+	entry := models.ColorEntry{
+		Hue:        colorconv.InterpolateHue(from.Hue, to.Hue, t),
+		Saturation: colorconv.Interpolate(from.Saturation, to.Saturation, t),
+		Brightness: colorconv.Interpolate(from.Brightness, to.Brightness, t),
+	}
+	switch {
+	case from.Saturation == 0:
+		entry.Hue = to.Hue
+	case to.Saturation == 0:
+		entry.Hue = from.Hue
+	}
+
+	switch {
+	case from.Kelvin > 0 && to.Kelvin > 0:
+		entry.Kelvin = int(math.Round(colorconv.Interpolate(float64(from.Kelvin), float64(to.Kelvin), t)))
+	case t < 0.5:
+		entry.Kelvin = from.Kelvin
+	default:
+		entry.Kelvin = to.Kelvin
+	}
+	return entry
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// testPalette is red, green and blue at full saturation
+var testPalette = []models.ColorEntry{
+	{Hue: 0, Saturation: 1, Brightness: 1},
+	{Hue: 120, Saturation: 1, Brightness: 0.8},
+	{Hue: 240, Saturation: 1, Brightness: 0.6},
+}
+
+// newPaletteDevices returns six color lights, out of label order, and a plug
+func newPaletteDevices() []*providers.Device {
+	colorCapabilities := []string{models.CapabilityColor, models.CapabilityTemperature}
+	return []*providers.Device{
+		{ID: "d6", Label: "Strip 6", Capabilities: colorCapabilities},
+		{ID: "d1", Label: "strip 1", Capabilities: colorCapabilities},
+		{ID: "d4", Label: "Strip 4", Capabilities: colorCapabilities},
+		{ID: "plug", Label: "Strip 0"},
+		{ID: "d2", Label: "Strip 2", Capabilities: colorCapabilities},
+		{ID: "d5", Label: "Strip 5", Capabilities: colorCapabilities},
+		{ID: "d3", Label: "Strip 3", Capabilities: colorCapabilities},
+	}
+}
+
+func TestPaletteLights_SortedByLabel(t *testing.T) {
+	colorCapabilities := []string{models.CapabilityColor}
+	lights := paletteLights([]*models.Device{
+		{ID: "d2", Label: "Strip 2", Capabilities: colorCapabilities},
+		{ID: "plug", Label: "Plug"},
+		{ID: "d3", Label: "strip 3", Capabilities: []string{models.CapabilityTemperature}},
+		{ID: "d1", Label: "Strip 1", Capabilities: colorCapabilities},
+	})
+
+	wantIDs := []string{"d1", "d2", "d3"}
+	if len(lights) != len(wantIDs) {
+		t.Fatalf("Expected %d lights, got %d", len(wantIDs), len(lights))
+	}
+	for i, light := range lights {
+		if light.ID != wantIDs[i] {
+			t.Errorf("Light %d: expected %s, got %s", i, wantIDs[i], light.ID)
+		}
+	}
+}
+
+func TestDistributePalette_Cycle(t *testing.T) {
+	colors := distributePalette(testPalette, 6, models.PaletteDistributionCycle)
+
+	wantHues := []float64{0, 120, 240, 0, 120, 240}
+	for i, color := range colors {
+		if color != testPalette[i%3] || color.Hue != wantHues[i] {
+			t.Errorf("Light %d: expected hue %v, got %+v", i, wantHues[i], color)
+		}
+	}
+}
+
+func TestDistributePalette_Gradient(t *testing.T) {
+	colors := distributePalette(testPalette, 6, models.PaletteDistributionGradient)
+
+	// The 6 lights sit at 0, 0.4, 0.8, 1.2, 1.6 and 2 palette steps
+	wantHues := []float64{0, 48, 96, 144, 192, 240}
+	wantBrightness := []float64{1, 0.92, 0.84, 0.76, 0.68, 0.6}
+	for i, color := range colors {
+		if math.Abs(color.Hue-wantHues[i]) > 1e-9 || math.Abs(color.Brightness-wantBrightness[i]) > 1e-9 {
+			t.Errorf("Light %d: expected hue %v and brightness %v, got %+v", i, wantHues[i], wantBrightness[i], color)
+		}
+		if color.Saturation != 1 {
+			t.Errorf("Light %d: expected full saturation, got %v", i, color.Saturation)
+		}
+	}
+
+	// Fading to a white keeps the color's hue and blends the temperatures of whites only
+	whites := []models.ColorEntry{{Hue: 30, Saturation: 1, Brightness: 1}, {Kelvin: 2700, Brightness: 1}, {Kelvin: 6500, Brightness: 1}}
+	colors = distributePalette(whites, 5, models.PaletteDistributionGradient)
+	if colors[1].Hue != 30 || colors[1].Saturation != 0.5 {
+		t.Errorf("Expected a half-saturated hue 30 between the color and the white, got %+v", colors[1])
+	}
+	if !colors[3].KelvinOnly() || colors[3].Kelvin != 4600 {
+		t.Errorf("Expected a 4600K white between the whites, got %+v", colors[3])
+	}
+}
+
+func TestDistributePalette_Random(t *testing.T) {
+	colors := distributePalette(testPalette, 6, models.PaletteDistributionRandom)
+
+	if len(colors) != 6 {
+		t.Fatalf("Expected 6 colors, got %d", len(colors))
+	}
+	counts := make(map[float64]int)
+	for _, color := range colors {
+		counts[color.Hue]++
+	}
+	for _, entry := range testPalette {
+		if counts[entry.Hue] != 2 {
+			t.Errorf("Expected hue %v given to 2 lights, got %d", entry.Hue, counts[entry.Hue])
+		}
+	}
+}
+
+func TestApplyPalette_SetsWhitesAsTemperature(t *testing.T) {
+	client := newFakeProviderClient(newPaletteDevices()...)
+	service, account := newTestDeviceService(t, client)
+
+	palette := append([]models.ColorEntry{{Kelvin: 2700}}, testPalette[1:]...)
+	req := &models.ApplyPaletteRequest{AccountID: account.ID.String(), Selector: "all", Palette: palette}
+	result, err := service.ApplyPalette(context.Background(), account.OwnerUserID.String(), req)
+	if err != nil {
+		t.Fatalf("ApplyPalette failed: %v", err)
+	}
+
+	if result.AppliedTo != 6 || result.Failed != 0 {
+		t.Errorf("Expected 6 lights set, got %+v", result)
+	}
+	// The white has no brightness, so it leaves the brightness of its 2 lights unchanged
+	if client.callCount("SetColorTemperature") != 2 || client.callCount("SetColor") != 4 || client.callCount("SetBrightness") != 4 {
+		t.Errorf("Expected 2 SetColorTemperature, 4 SetColor and 4 SetBrightness calls, got %d, %d and %d",
+			client.callCount("SetColorTemperature"), client.callCount("SetColor"), client.callCount("SetBrightness"))
+	}
+	for _, selector := range client.selectors {
+		if selector == "id:plug" {
+			t.Error("Expected the plug to be left alone")
+		}
+	}
+}
+
+func TestApplyPalette_CountsFailedLights(t *testing.T) {
+	client := newFakeProviderClient(newPaletteDevices()...)
+	client.errs["SetColor"] = []error{errors.New("device offline")}
+	service, account := newTestDeviceService(t, client)
+
+	req := &models.ApplyPaletteRequest{AccountID: account.ID.String(), Selector: "all", Palette: testPalette, Distribution: models.PaletteDistributionRandom}
+	result, err := service.ApplyPalette(context.Background(), account.OwnerUserID.String(), req)
+	if err != nil {
+		t.Fatalf("ApplyPalette failed: %v", err)
+	}
+	if result.AppliedTo != 5 || result.Failed != 1 {
+		t.Errorf("Expected 5 lights set and 1 failure, got %+v", result)
+	}
+}
+
+// concurrencyTrackingClient records the most SetColor calls in flight at once
+type concurrencyTrackingClient struct {
+	*fakeProviderClient
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (c *concurrencyTrackingClient) SetColor(token, selector string, color *providers.DeviceColor, duration float64) error {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for {
+		peak := c.peak.Load()
+		if n <= peak || c.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return c.fakeProviderClient.SetColor(token, selector, color, duration)
+}
+
+func TestApplyPalette_BoundsConcurrentProviderCalls(t *testing.T) {
+	client := &concurrencyTrackingClient{fakeProviderClient: newFakeProviderClient(newPaletteDevices()...)}
+	service, account := newTestDeviceService(t, client)
+	service.fetch.concurrency = 2
+
+	req := &models.ApplyPaletteRequest{AccountID: account.ID.String(), Selector: "all", Palette: testPalette}
+	result, err := service.ApplyPalette(context.Background(), account.OwnerUserID.String(), req)
+	if err != nil {
+		t.Fatalf("ApplyPalette failed: %v", err)
+	}
+
+	if result.AppliedTo != 6 {
+		t.Errorf("Expected 6 lights set, got %+v", result)
+	}
+	if peak := client.peak.Load(); peak > 2 {
+		t.Errorf("Expected at most 2 lights set at once, got %d", peak)
+	}
+}
+
+func TestApplyPalette_Errors(t *testing.T) {
+	client := newFakeProviderClient(newPaletteDevices()...)
+	service, account := newTestDeviceService(t, client)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	var badRequest *apierror.BadRequestError
+	invalid := []*models.ApplyPaletteRequest{
+		{AccountID: accountID, Selector: "all"},
+		{AccountID: accountID, Selector: "all", Palette: testPalette, Distribution: "spiral"},
+		{AccountID: accountID, Selector: "all", Palette: []models.ColorEntry{{Hue: 400}}},
+	}
+	for _, req := range invalid {
+		if _, err := service.ApplyPalette(context.Background(), userID, req); !errors.As(err, &badRequest) {
+			t.Errorf("Expected a BadRequestError for %+v, got %v", req, err)
+		}
+	}
+
+	req := &models.ApplyPaletteRequest{AccountID: accountID, Selector: "id:plug", Palette: testPalette}
+	if _, err := service.ApplyPalette(context.Background(), userID, req); !errors.Is(err, ErrNoDevicesMatchSelector) {
+		t.Errorf("Expected ErrNoDevicesMatchSelector for a selector without lights, got %v", err)
+	}
+	if client.callCount("SetColor") != 0 {
+		t.Errorf("Expected no SetColor calls, got %d", client.callCount("SetColor"))
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lightshare/backend/pkg/crypto"
+	"github.com/lightshare/backend/pkg/email"
+)
+
+// recordingSender captures the emails the worker delivers
+type recordingSender struct {
+	sent chan email.Message
+}
+
+func newRecordingSender() *recordingSender {
+	return &recordingSender{sent: make(chan email.Message, 10)}
+}
+
+func (r *recordingSender) Send(msg email.Message) error {
+	r.sent <- msg
+	return nil
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	sender := newRecordingSender()
+	service := newTestAuthService(t, withSession(), withEmail(sender))
+	user := service.user
+	ctx := context.Background()
+
+	if err := service.RequestPasswordReset(ctx, " User@Example.com "); err != nil {
+		t.Fatalf("RequestPasswordReset failed: %v", err)
+	}
+	if user.PasswordResetToken == nil || user.PasswordResetExpiresAt == nil {
+		t.Fatal("Expected a password reset token to be stored")
+	}
+
+	select {
+	case msg := <-sender.sent:
+		if msg.To != user.Email {
+			t.Errorf("Expected the email to go to %s, got %s", user.Email, msg.To)
+		}
+		// The emailed link carries the token whose hash is stored
+		_, link, _ := strings.Cut(msg.Body, "/reset-password?token=")
+		token, _, _ := strings.Cut(link, `"`)
+		if token == "" || crypto.HashToken(token) != *user.PasswordResetToken {
+			t.Errorf("Expected the email to carry the stored reset token, got %q", token)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a password reset email to be delivered")
+	}
+}
+
+func TestRequestPasswordReset_UnknownEmail(t *testing.T) {
+	sender := newRecordingSender()
+	service := newTestAuthService(t, withSession(), withEmail(sender))
+
+	// Unknown addresses succeed too, so they can't be told apart from known ones
+	if err := service.RequestPasswordReset(context.Background(), "unknown@example.com"); err != nil {
+		t.Fatalf("RequestPasswordReset failed: %v", err)
+	}
+
+	select {
+	case msg := <-sender.sent:
+		t.Errorf("Expected no email, got one to %s", msg.To)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	service := newTestAuthService(t, withSession())
+	user := service.user
+	ctx := context.Background()
+
+	token := "reset-token"
+	if err := service.userRepo.SetPasswordResetToken(ctx, user.ID, crypto.HashToken(token), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("SetPasswordResetToken failed: %v", err)
+	}
+
+	if err := service.ResetPassword(ctx, "unknown-token", "new-password"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("Expected ErrInvalidResetToken, got %v", err)
+	}
+	if err := service.ResetPassword(ctx, token, "short"); !errors.Is(err, ErrWeakPassword) {
+		t.Errorf("Expected ErrWeakPassword, got %v", err)
+	}
+
+	if err := service.ResetPassword(ctx, token, "new-password"); err != nil {
+		t.Fatalf("ResetPassword failed: %v", err)
+	}
+	if err := crypto.ComparePassword("new-password", user.PasswordHash); err != nil {
+		t.Errorf("Expected the new password to be set: %v", err)
+	}
+	if active := service.tokens.activeTokens(); active != 0 {
+		t.Errorf("Expected all sessions to be revoked, got %d active", active)
+	}
+
+	// A token is redeemed once
+	if err := service.ResetPassword(ctx, token, "another-password"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("Expected ErrInvalidResetToken on reuse, got %v", err)
+	}
+}
+
+func TestResetPassword_ExpiredToken(t *testing.T) {
+	service := newTestAuthService(t, withPassword("old-password"))
+	user := service.user
+	ctx := context.Background()
+
+	token := "reset-token"
+	if err := service.userRepo.SetPasswordResetToken(ctx, user.ID, crypto.HashToken(token), time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("SetPasswordResetToken failed: %v", err)
+	}
+
+	if err := service.ResetPassword(ctx, token, "new-password"); !errors.Is(err, ErrResetTokenExpired) {
+		t.Errorf("Expected ErrResetTokenExpired, got %v", err)
+	}
+	if err := crypto.ComparePassword("old-password", user.PasswordHash); err != nil {
+		t.Error("Expected the password to stay unchanged")
+	}
+}
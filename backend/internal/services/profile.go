@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+)
+
+const (
+	// userProfileTTL is how long a user's profile is cached
+	userProfileTTL = 30 * time.Second
+	// maxDisplayNameLength is the maximum length of a display name, in characters
+	maxDisplayNameLength = 100
+)
+
+var (
+	// ErrInvalidDisplayName is returned when a display name is longer than 100 characters
+	ErrInvalidDisplayName = errors.New("display name must be at most 100 characters")
+	// ErrInvalidNotificationPreferences is returned when notification preferences are not a JSON object
+	ErrInvalidNotificationPreferences = errors.New("notification preferences must be a JSON object")
+)
+
+// UpdateProfileRequest holds the profile fields to update; omitted fields are left unchanged
+type UpdateProfileRequest struct {
+	DisplayName             *string          `json:"display_name"` // An empty name clears it
+	NotificationPreferences *json.RawMessage `json:"notification_preferences"`
+}
+
+func userProfileKey(userID uuid.UUID) string {
+	return fmt.Sprintf("user:profile:%s", userID)
+}
+
+// GetProfile returns a user's profile, cached for 30s
+func (s *AuthService) GetProfile(ctx context.Context, userID uuid.UUID) (*models.UserResponse, error) {
+	if s.cache != nil {
+		if data, err := s.cache.Get(ctx, userProfileKey(userID)).Bytes(); err == nil {
+			var profile models.UserResponse
+			if err := json.Unmarshal(data, &profile); err == nil {
+				return &profile, nil
+			}
+		}
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	profile := user.ToResponse()
+	s.cacheProfile(ctx, profile)
+	return profile, nil
+}
+
+// UpdateProfile updates a user's display name and notification preferences
+func (s *AuthService) UpdateProfile(ctx context.Context, userID uuid.UUID, req UpdateProfileRequest) (*models.UserResponse, error) {
+	params := models.UpdateProfileParams{NotificationPreferences: req.NotificationPreferences}
+
+	if req.DisplayName != nil {
+		displayName := strings.TrimSpace(*req.DisplayName)
+		if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+			return nil, ErrInvalidDisplayName
+		}
+		params.DisplayName = &displayName
+	}
+
+	if req.NotificationPreferences != nil {
+		var preferences map[string]interface{}
+		if err := json.Unmarshal(*req.NotificationPreferences, &preferences); err != nil || preferences == nil {
+			return nil, ErrInvalidNotificationPreferences
+		}
+	}
+
+	user, err := s.userRepo.UpdateProfile(ctx, userID, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	profile := user.ToResponse()
+	s.cacheProfile(ctx, profile)
+	return profile, nil
+}
+
+// cacheProfile caches a user's profile. Errors are ignored; the profile is read from the
+// database next time.
+func (s *AuthService) cacheProfile(ctx context.Context, profile *models.UserResponse) {
+	if s.cache == nil {
+		return
+	}
+	if data, err := json.Marshal(profile); err == nil {
+		_ = s.cache.Set(ctx, userProfileKey(profile.ID), data, userProfileTTL).Err()
+	}
+}
+
+// invalidateProfile drops a user's cached profile after a change to a field it includes
+func (s *AuthService) invalidateProfile(ctx context.Context, userID uuid.UUID) {
+	if s.cache == nil {
+		return
+	}
+	_ = s.cache.Del(ctx, userProfileKey(userID)).Err()
+}
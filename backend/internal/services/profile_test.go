@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGetProfile_CachesProfile(t *testing.T) {
+	service := newTestAuthService(t, withCache())
+	user := service.user
+	ctx := context.Background()
+
+	profile, err := service.GetProfile(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetProfile failed: %v", err)
+	}
+	if profile.Email != "user@example.com" || string(profile.NotificationPreferences) != "{}" {
+		t.Errorf("Unexpected profile: %+v", profile)
+	}
+
+	// Served from the cache once the user is gone from the database
+	service.users.users = nil
+	if _, err := service.GetProfile(ctx, user.ID); err != nil {
+		t.Errorf("Expected the cached profile, got %v", err)
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	service := newTestAuthService(t, withCache())
+	user := service.user
+	ctx := context.Background()
+
+	// Cache the profile, which the update must replace
+	if _, err := service.GetProfile(ctx, user.ID); err != nil {
+		t.Fatalf("GetProfile failed: %v", err)
+	}
+
+	displayName := "  Ada  "
+	preferences := json.RawMessage(`{"email":false}`)
+	profile, err := service.UpdateProfile(ctx, user.ID, UpdateProfileRequest{DisplayName: &displayName, NotificationPreferences: &preferences})
+	if err != nil {
+		t.Fatalf("UpdateProfile failed: %v", err)
+	}
+	if profile.DisplayName == nil || *profile.DisplayName != "Ada" {
+		t.Errorf("Expected display name 'Ada', got %v", profile.DisplayName)
+	}
+	if string(profile.NotificationPreferences) != `{"email":false}` {
+		t.Errorf("Expected updated preferences, got %s", profile.NotificationPreferences)
+	}
+
+	cached, err := service.GetProfile(ctx, user.ID)
+	if err != nil || cached.DisplayName == nil || *cached.DisplayName != "Ada" {
+		t.Errorf("Expected the cached profile to be updated, got %+v, %v", cached, err)
+	}
+}
+
+func TestUpdateProfile_Validation(t *testing.T) {
+	service := newTestAuthService(t, withCache())
+	user := service.user
+	ctx := context.Background()
+
+	tooLong := strings.Repeat("é", maxDisplayNameLength+1)
+	if _, err := service.UpdateProfile(ctx, user.ID, UpdateProfileRequest{DisplayName: &tooLong}); !errors.Is(err, ErrInvalidDisplayName) {
+		t.Errorf("Expected ErrInvalidDisplayName, got %v", err)
+	}
+
+	maxLength := strings.Repeat("é", maxDisplayNameLength)
+	if _, err := service.UpdateProfile(ctx, user.ID, UpdateProfileRequest{DisplayName: &maxLength}); err != nil {
+		t.Errorf("Expected a 100 character name to be accepted, got %v", err)
+	}
+
+	for _, raw := range []string{`["email"]`, `"email"`, `null`} {
+		preferences := json.RawMessage(raw)
+		if _, err := service.UpdateProfile(ctx, user.ID, UpdateProfileRequest{NotificationPreferences: &preferences}); !errors.Is(err, ErrInvalidNotificationPreferences) {
+			t.Errorf("Expected ErrInvalidNotificationPreferences for %s, got %v", raw, err)
+		}
+	}
+}
@@ -4,12 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/lightshare/backend/internal/models"
 	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/apierror"
 	"github.com/lightshare/backend/pkg/crypto"
+	"github.com/lightshare/backend/pkg/logger"
 	"github.com/lightshare/backend/pkg/providers"
 )
 
@@ -17,25 +23,50 @@ var (
 	// ErrInvalidProvider is returned when an invalid provider type is specified
 	ErrInvalidProvider = errors.New("invalid provider type")
 	// ErrInvalidToken is returned when a provider token is invalid
-	ErrInvalidToken = errors.New("invalid provider token")
+	ErrInvalidToken = &apierror.BadRequestError{Message: "invalid provider token"}
 	// ErrAccountNotOwned is returned when trying to access an account not owned by the user
-	ErrAccountNotOwned = errors.New("account not owned by user")
+	ErrAccountNotOwned = apierror.New(apierror.ErrForbidden, "account not owned by user")
+	// ErrProviderAccountMismatch is returned when a reconnect token belongs to a different provider account
+	ErrProviderAccountMismatch = errors.New("token belongs to a different provider account")
+	// ErrInvalidAccountLabel is returned when an account label is empty or too long
+	ErrInvalidAccountLabel = errors.New("invalid account label")
+	// ErrAccountAlreadyConnected is returned when connecting a provider account the user already connected
+	ErrAccountAlreadyConnected = apierror.New(apierror.ErrConflict, "this provider account is already connected")
 )
 
 // ProviderService handles provider connection operations
 type ProviderService struct {
-	accountRepo   repository.AccountRepositoryInterface
-	encryptionKey []byte
+	accountRepo repository.AccountRepositoryInterface
+	cache       *redis.Client
+	validations *tokenValidationCache
+	newClient   func(provider providers.Provider) (providers.Client, error)
+	oauth       ProviderOAuthConfig
+	keys        crypto.KeyProvider
 }
 
-// NewProviderService creates a new provider service
-func NewProviderService(accountRepo repository.AccountRepositoryInterface, encryptionKey []byte) *ProviderService {
+// NewProviderService creates a new provider service, encrypting tokens with keys
+// Successful token validations are cached in Redis for validationTTL (a nil cache disables caching)
+func NewProviderService(
+	accountRepo repository.AccountRepositoryInterface,
+	keys crypto.KeyProvider,
+	cache *redis.Client,
+	validationTTL time.Duration,
+) *ProviderService {
 	return &ProviderService{
-		accountRepo:   accountRepo,
-		encryptionKey: encryptionKey,
+		accountRepo: accountRepo,
+		cache:       cache,
+		validations: newTokenValidationCache(cache, validationTTL),
+		newClient:   providerClientFactory(nil, false, nil),
+		keys:        keys,
 	}
 }
 
+// SetEnabledProviders restricts the providers accounts can be connected to and validated
+// with to those enabledProviders returns, which is called for every provider client created
+func (s *ProviderService) SetEnabledProviders(enabledProviders func() []string) {
+	s.newClient = providerClientFactory(nil, false, enabledProviders)
+}
+
 // ConnectProviderRequest represents a request to connect a provider
 type ConnectProviderRequest struct {
 	Provider string `json:"provider"`
@@ -51,19 +82,21 @@ func (s *ProviderService) ConnectProvider(ctx context.Context, userID uuid.UUID,
 	}
 
 	// Create provider client
-	client, err := providers.NewClient(providerType)
+	client, err := s.newClient(providerType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create provider client: %w", err)
 	}
 
 	// Validate token by calling provider API
-	accountInfo, err := client.ValidateToken(req.Token)
+	accountInfo, err := providers.WithContext(ctx, client).ValidateToken(req.Token)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
 	}
+	// A read-only token is accepted, but its account cannot control devices
+	markReadOnly(accountInfo)
 
 	// Encrypt the token
-	encryptedToken, err := crypto.EncryptToken(req.Token, s.encryptionKey)
+	encryptedToken, keyID, err := s.keys.EncryptToken(req.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encrypt token: %w", err)
 	}
@@ -74,16 +107,173 @@ func (s *ProviderService) ConnectProvider(ctx context.Context, userID uuid.UUID,
 		Provider:          req.Provider,
 		ProviderAccountID: accountInfo.ProviderAccountID,
 		EncryptedToken:    encryptedToken,
+		KeyID:             keyID,
 		Metadata:          accountInfo.Metadata,
 	})
 
 	if err != nil {
 		if errors.Is(err, repository.ErrAccountAlreadyExists) {
-			return nil, errors.New("this provider account is already connected")
+			return nil, ErrAccountAlreadyConnected
 		}
 		return nil, fmt.Errorf("failed to create account: %w", err)
 	}
 
+	// An explicit connect always validates against the provider; seed the cache for health checks
+	if err := s.validations.set(ctx, account.ID.String(), &tokenValidation{CheckedAt: time.Now().UTC(), Info: accountInfo}); err != nil {
+		// Log error but don't fail the request
+		logger.WithContext(ctx).Warn("Failed to cache token validation", "error", err, "account_id", account.ID)
+	}
+
+	return account, nil
+}
+
+// markReadOnly flags, in its metadata, an account whose token can list but not control devices
+func markReadOnly(info *providers.AccountInfo) {
+	if info.Metadata[providers.MetadataTokenScopes] == providers.TokenScopesRead {
+		info.Metadata[models.MetadataReadOnly] = true
+	}
+}
+
+// CheckAccountHealth validates the stored provider token for an account.
+// A successful validation is cached, so repeated checks within the TTL don't call the provider.
+func (s *ProviderService) CheckAccountHealth(ctx context.Context, userID, accountID uuid.UUID) (*models.AccountHealth, error) {
+	account, err := s.accountRepo.FindByID(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			return nil, repository.ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to find account: %w", err)
+	}
+
+	if account.OwnerUserID != userID {
+		return nil, ErrAccountNotOwned
+	}
+
+	if cached, ok := s.validations.get(ctx, accountID.String()); ok {
+		return &models.AccountHealth{Healthy: true, Cached: true, CheckedAt: cached.CheckedAt}, nil
+	}
+
+	token, err := s.accountRepo.GetDecryptedToken(ctx, accountID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	client, err := s.newClient(providers.Provider(account.Provider))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider client: %w", err)
+	}
+
+	checkedAt := time.Now().UTC()
+	accountInfo, err := providers.WithContext(ctx, client).ValidateToken(token)
+	if err != nil {
+		if errors.Is(err, providers.ErrUnauthorized) {
+			s.validations.invalidateOnUnauthorized(ctx, accountID.String(), err)
+			return &models.AccountHealth{Healthy: false, CheckedAt: checkedAt, Error: "provider token rejected"}, nil
+		}
+		return nil, fmt.Errorf("failed to validate token: %w", err)
+	}
+
+	if err := s.validations.set(ctx, accountID.String(), &tokenValidation{CheckedAt: checkedAt, Info: accountInfo}); err != nil {
+		// Log error but don't fail the request
+		logger.WithContext(ctx).Warn("Failed to cache token validation", "error", err, "account_id", accountID)
+	}
+
+	return &models.AccountHealth{Healthy: true, CheckedAt: checkedAt}, nil
+}
+
+// ReconnectAccount replaces the stored provider token of an account, so that a revoked
+// or expired token can be updated without disconnecting the account. The new token must
+// belong to the same provider account.
+func (s *ProviderService) ReconnectAccount(ctx context.Context, userID, accountID uuid.UUID, newToken string) (*models.Account, error) {
+	account, err := s.accountRepo.FindByID(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			return nil, repository.ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to find account: %w", err)
+	}
+
+	if account.OwnerUserID != userID {
+		return nil, ErrAccountNotOwned
+	}
+
+	client, err := s.newClient(providers.Provider(account.Provider))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider client: %w", err)
+	}
+
+	checkedAt := time.Now().UTC()
+	accountInfo, err := providers.WithContext(ctx, client).ValidateToken(newToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	markReadOnly(accountInfo)
+
+	if accountInfo.ProviderAccountID != account.ProviderAccountID {
+		return nil, ErrProviderAccountMismatch
+	}
+
+	encryptedToken, keyID, err := s.keys.EncryptToken(newToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt token: %w", err)
+	}
+
+	if err := s.accountRepo.UpdateToken(ctx, accountID, userID, encryptedToken, keyID, accountInfo.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to update account: %w", err)
+	}
+
+	if err := s.validations.set(ctx, accountID.String(), &tokenValidation{CheckedAt: checkedAt, Info: accountInfo}); err != nil {
+		// Log error but don't fail the request
+		logger.WithContext(ctx).Warn("Failed to cache token validation", "error", err, "account_id", accountID)
+	}
+
+	// Drop the last status check, which may still report the old token as invalid, and the
+	// devices cached while the old token was failing
+	if s.cache != nil {
+		keys := []string{accountStatusKey(accountID.String()), devicesCacheKey(accountID.String()), deviceStatesCacheKey(accountID.String()), devicesETagKey(accountID.String()), groupsCacheKey(accountID.String()), deviceSummaryKey(userID.String())}
+		if err := s.cache.Del(ctx, keys...).Err(); err != nil {
+			// Log error but don't fail the request
+			logger.WithContext(ctx).Warn("Failed to clear account cache", "error", err, "account_id", accountID)
+		}
+	}
+
+	return s.accountRepo.FindByID(ctx, accountID)
+}
+
+// UpdateAccountLabel sets the display label of an account. The label is trimmed and must
+// hold between 1 and models.MaxAccountLabelLength characters.
+func (s *ProviderService) UpdateAccountLabel(ctx context.Context, userID, accountID uuid.UUID, label string) (*models.Account, error) {
+	label = strings.TrimSpace(label)
+	if label == "" || utf8.RuneCountInString(label) > models.MaxAccountLabelLength {
+		return nil, ErrInvalidAccountLabel
+	}
+
+	// Verify the account belongs to the user before updating
+	account, err := s.accountRepo.FindByID(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			return nil, repository.ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to find account: %w", err)
+	}
+
+	if account.OwnerUserID != userID {
+		return nil, ErrAccountNotOwned
+	}
+
+	if err := s.accountRepo.UpdateLabel(ctx, accountID, userID, label); err != nil {
+		return nil, fmt.Errorf("failed to update account label: %w", err)
+	}
+
+	// Drop cached devices, which carry the previous label
+	if s.cache != nil {
+		if err := s.cache.Del(ctx, devicesCacheKey(accountID.String()), deviceStatesCacheKey(accountID.String()), devicesETagKey(accountID.String())).Err(); err != nil {
+			// Log error but don't fail the request
+			logger.WithContext(ctx).Warn("Failed to clear device cache", "error", err, "account_id", accountID)
+		}
+	}
+
+	account.Label = label
 	return account, nil
 }
 
@@ -97,6 +287,34 @@ func (s *ProviderService) ListAccounts(ctx context.Context, userID uuid.UUID) ([
 	return accounts, nil
 }
 
+// ListProviders returns every registered provider with the user's connection status
+func (s *ProviderService) ListProviders(ctx context.Context, userID uuid.UUID) ([]*models.ProviderStatus, error) {
+	accounts, err := s.accountRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+
+	counts := make(map[string]int)
+	for _, account := range accounts {
+		counts[account.Provider]++
+	}
+
+	registered := providers.Registered()
+	statuses := make([]*models.ProviderStatus, 0, len(registered))
+	for _, info := range registered {
+		count := counts[info.ID.String()]
+		statuses = append(statuses, &models.ProviderStatus{
+			ID:           info.ID.String(),
+			Name:         info.Name,
+			Implemented:  info.Implemented,
+			Connected:    count > 0,
+			AccountCount: count,
+		})
+	}
+
+	return statuses, nil
+}
+
 // DisconnectAccount disconnects a provider account
 func (s *ProviderService) DisconnectAccount(ctx context.Context, userID, accountID uuid.UUID) error {
 	// Verify the account belongs to the user before deleting
@@ -118,5 +336,10 @@ func (s *ProviderService) DisconnectAccount(ctx context.Context, userID, account
 		return fmt.Errorf("failed to disconnect account: %w", err)
 	}
 
+	if err := s.validations.invalidate(ctx, accountID.String()); err != nil {
+		// Log error but don't fail the request
+		logger.WithContext(ctx).Warn("Failed to clear token validation", "error", err, "account_id", accountID)
+	}
+
 	return nil
 }
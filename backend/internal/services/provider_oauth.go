@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/oauth"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// pkceStateTTL is how long a user has to authorize us with the provider
+const pkceStateTTL = 5 * time.Minute
+
+var (
+	// ErrOAuthNotConfigured is returned when a provider has no OAuth2 client configured
+	ErrOAuthNotConfigured = apierror.New(apierror.ErrNotFound, "oauth is not configured for this provider")
+	// ErrInvalidOAuthState is returned when an OAuth2 state is malformed, unknown, expired or already used
+	ErrInvalidOAuthState = &apierror.BadRequestError{Message: "invalid or expired oauth state"}
+	// ErrInvalidCodeChallenge is returned when a PKCE code challenge is malformed or not S256
+	ErrInvalidCodeChallenge = &apierror.BadRequestError{Message: "code_challenge must be an S256 PKCE code challenge"}
+	// ErrInvalidCodeVerifier is returned when collecting an authorization with a PKCE code
+	// verifier that does not match the challenge it was started with
+	ErrInvalidCodeVerifier = &apierror.BadRequestError{Message: "code_verifier does not match the code_challenge"}
+)
+
+// oauthState matches the states clients may pass: 16 to 128 URL-safe characters, so
+// they are unguessable and can be used as a Redis key as is
+var oauthState = regexp.MustCompile(`^[A-Za-z0-9\-._~]{16,128}$`)
+
+// ProviderOAuthConfig configures connecting provider accounts with the OAuth2
+// authorization code flow
+type ProviderOAuthConfig struct {
+	Providers  map[providers.Provider]*oauth.AuthCodeConfig // Providers without an entry do not support OAuth2
+	SuccessURL string                                       // Where users are sent once the account is connected
+}
+
+// pendingAuthorization is an authorization started by a user, stored under its state
+// until the provider redirects back. CodeVerifier is ours, redeeming the code with the
+// provider; CodeChallenge is the client's, checked when it collects the result.
+type pendingAuthorization struct {
+	UserID        uuid.UUID `json:"user_id"`
+	Provider      string    `json:"provider"`
+	CodeVerifier  string    `json:"code_verifier"`
+	CodeChallenge string    `json:"code_challenge"`
+}
+
+// completedAuthorization is the account an authorization connected, stored under its state
+// until the client that started it collects it
+type completedAuthorization struct {
+	UserID        uuid.UUID `json:"user_id"`
+	AccountID     uuid.UUID `json:"account_id"`
+	Provider      string    `json:"provider"`
+	CodeChallenge string    `json:"code_challenge"`
+}
+
+func pkceStateKey(state string) string {
+	return "pkce:" + state
+}
+
+func pkceResultKey(state string) string {
+	return "pkce:result:" + state
+}
+
+// SetOAuth enables connecting accounts of the configured providers with OAuth2
+func (s *ProviderService) SetOAuth(config ProviderOAuthConfig) {
+	s.oauth = config
+}
+
+// AuthorizeURL starts connecting an account with OAuth2 and returns the provider URL the
+// user must visit to authorize us. The client's state identifies the authorization. We
+// redeem the code ourselves, so the provider is sent the challenge of our own PKCE code
+// verifier; the client's S256 challenge is kept to check the verifier it collects the
+// result with.
+func (s *ProviderService) AuthorizeURL(ctx context.Context, userID uuid.UUID, provider, state, codeChallenge, codeChallengeMethod string) (string, error) {
+	config, ok := s.oauth.Providers[providers.Provider(provider)]
+	if !ok || s.cache == nil {
+		return "", ErrOAuthNotConfigured
+	}
+
+	if !oauthState.MatchString(state) {
+		return "", ErrInvalidOAuthState
+	}
+	if codeChallengeMethod != oauth.CodeChallengeMethodS256 || !oauth.ValidCodeChallenge(codeChallenge) {
+		return "", ErrInvalidCodeChallenge
+	}
+
+	codeVerifier, err := oauth.NewCodeVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(pendingAuthorization{
+		UserID:        userID,
+		Provider:      provider,
+		CodeVerifier:  codeVerifier,
+		CodeChallenge: codeChallenge,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode authorization: %w", err)
+	}
+
+	// A state already in use is rejected rather than overwritten
+	stored, err := s.cache.SetNX(ctx, pkceStateKey(state), data, pkceStateTTL).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to store authorization: %w", err)
+	}
+	if !stored {
+		return "", ErrInvalidOAuthState
+	}
+
+	return config.AuthCodeURL(state, oauth.CodeChallengeS256(codeVerifier)), nil
+}
+
+// CompleteAuthorization redeems the authorization code the provider redirected back with
+// and connects the account with the resulting access token. Each state can only be
+// completed once. Returns the URL to send the user to, from where the client collects
+// the connected account with CollectAuthorization.
+func (s *ProviderService) CompleteAuthorization(ctx context.Context, provider, state, code string) (string, error) {
+	config, ok := s.oauth.Providers[providers.Provider(provider)]
+	if !ok || s.cache == nil {
+		return "", ErrOAuthNotConfigured
+	}
+
+	if !oauthState.MatchString(state) {
+		return "", ErrInvalidOAuthState
+	}
+
+	data, err := s.cache.GetDel(ctx, pkceStateKey(state)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", ErrInvalidOAuthState
+		}
+		return "", fmt.Errorf("failed to get authorization: %w", err)
+	}
+
+	var pending pendingAuthorization
+	if err := json.Unmarshal(data, &pending); err != nil || pending.Provider != provider {
+		return "", ErrInvalidOAuthState
+	}
+
+	token, err := config.Exchange(ctx, code, pending.CodeVerifier)
+	if err != nil {
+		if errors.Is(err, oauth.ErrInvalidGrant) {
+			return "", &apierror.BadRequestError{Message: "invalid authorization code", Cause: err}
+		}
+		return "", err
+	}
+
+	account, err := s.ConnectProvider(ctx, pending.UserID, ConnectProviderRequest{Provider: provider, Token: token})
+	if err != nil {
+		return "", err
+	}
+
+	data, err = json.Marshal(completedAuthorization{
+		UserID:        pending.UserID,
+		AccountID:     account.ID,
+		Provider:      provider,
+		CodeChallenge: pending.CodeChallenge,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode authorization result: %w", err)
+	}
+	if err := s.cache.Set(ctx, pkceResultKey(state), data, pkceStateTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store authorization result: %w", err)
+	}
+
+	return oauthSuccessURL(s.oauth.SuccessURL, state), nil
+}
+
+// CollectAuthorization returns the account connected by the user's authorization
+// identified by state, once the client proves it started it with the PKCE code verifier
+// of its code challenge. The result can be collected once.
+func (s *ProviderService) CollectAuthorization(ctx context.Context, userID uuid.UUID, provider, state, codeVerifier string) (*models.Account, error) {
+	if _, ok := s.oauth.Providers[providers.Provider(provider)]; !ok || s.cache == nil {
+		return nil, ErrOAuthNotConfigured
+	}
+
+	if !oauthState.MatchString(state) {
+		return nil, ErrInvalidOAuthState
+	}
+
+	data, err := s.cache.Get(ctx, pkceResultKey(state)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrInvalidOAuthState
+		}
+		return nil, fmt.Errorf("failed to get authorization result: %w", err)
+	}
+
+	var result completedAuthorization
+	if err := json.Unmarshal(data, &result); err != nil || result.Provider != provider || result.UserID != userID {
+		return nil, ErrInvalidOAuthState
+	}
+
+	challenge := oauth.CodeChallengeS256(codeVerifier)
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(result.CodeChallenge)) != 1 {
+		return nil, ErrInvalidCodeVerifier
+	}
+
+	if err := s.cache.Del(ctx, pkceResultKey(state)).Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete authorization result: %w", err)
+	}
+
+	return s.accountRepo.FindByID(ctx, result.AccountID)
+}
+
+// oauthSuccessURL appends the state to successURL
+func oauthSuccessURL(successURL, state string) string {
+	return successURL + "?" + url.Values{"state": {state}}.Encode()
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lightshare/backend/pkg/oauth"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+const (
+	testOAuthState     = "state-0123456789abcdef"
+	testCodeVerifier   = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testCodeChallenge  = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" // S256 challenge of testCodeVerifier
+	testSuccessURL     = "https://app.example.com/oauth/success"
+	testAuthorizedCode = "valid-code"
+)
+
+// newTestProviderOAuthService returns a provider service connecting LIFX accounts through a fake
+// provider whose token endpoint checks the PKCE verifier against the challenge it was sent
+func newTestProviderOAuthService(t *testing.T) (*ProviderService, *miniredis.Miniredis, *MockAccountRepository) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = cache.Close() })
+
+	var challenge string
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/authorize" {
+			challenge = r.URL.Query().Get("code_challenge")
+			return
+		}
+		_ = r.ParseForm()
+		if r.PostForm.Get("code") != testAuthorizedCode || oauth.CodeChallengeS256(r.PostForm.Get("code_verifier")) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"oauth-token","token_type":"Bearer"}`))
+	}))
+	t.Cleanup(provider.Close)
+
+	repo := NewMockAccountRepository()
+	service := NewProviderService(repo, newTestKeys(t), cache, 0)
+	service.newClient = func(_ providers.Provider) (providers.Client, error) {
+		return newFakeProviderClient(), nil
+	}
+	service.SetOAuth(ProviderOAuthConfig{
+		Providers: map[providers.Provider]*oauth.AuthCodeConfig{
+			providers.ProviderLIFX: oauth.NewAuthCodeConfig(
+				oauth.Endpoint{AuthURL: provider.URL + "/authorize", TokenURL: provider.URL + "/token"},
+				"client-id", "client-secret", "https://api.example.com/callback",
+			),
+		},
+		SuccessURL: testSuccessURL,
+	})
+	return service, mr, repo
+}
+
+// startAuthorization starts an authorization and follows the redirect to the fake provider
+func startAuthorization(t *testing.T, service *ProviderService, userID uuid.UUID, state string) {
+	t.Helper()
+
+	authorizeURL, err := service.AuthorizeURL(context.Background(), userID, "lifx", state, testCodeChallenge, "S256")
+	if err != nil {
+		t.Fatalf("AuthorizeURL failed: %v", err)
+	}
+	resp, err := http.Get(authorizeURL)
+	if err != nil {
+		t.Fatalf("Failed to visit the authorization URL: %v", err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestOAuthFlow_ConnectsAccount(t *testing.T) {
+	service, mr, repo := newTestProviderOAuthService(t)
+	userID := uuid.New()
+
+	startAuthorization(t, service, userID, testOAuthState)
+	if !mr.Exists(pkceStateKey(testOAuthState)) {
+		t.Fatal("Expected the authorization to be stored under its state")
+	}
+
+	redirectURL, err := service.CompleteAuthorization(context.Background(), "lifx", testOAuthState, testAuthorizedCode)
+	if err != nil {
+		t.Fatalf("CompleteAuthorization failed: %v", err)
+	}
+
+	accounts, _ := repo.FindByUserID(context.Background(), userID)
+	if len(accounts) != 1 {
+		t.Fatalf("Expected 1 connected account, got %d", len(accounts))
+	}
+
+	parsed, err := url.Parse(redirectURL)
+	if err != nil {
+		t.Fatalf("Failed to parse redirect URL: %v", err)
+	}
+	if parsed.Query().Get("state") != testOAuthState || parsed.Query().Has("account_id") {
+		t.Errorf("Unexpected redirect URL %s", redirectURL)
+	}
+	if mr.Exists(pkceStateKey(testOAuthState)) {
+		t.Error("Expected the state to be consumed")
+	}
+}
+
+func TestOAuthFlow_StateValidation(t *testing.T) {
+	service, mr, _ := newTestProviderOAuthService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	if _, err := service.AuthorizeURL(ctx, userID, "lifx", "short", testCodeChallenge, "S256"); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Errorf("Expected ErrInvalidOAuthState for a short state, got %v", err)
+	}
+
+	startAuthorization(t, service, userID, testOAuthState)
+	if _, err := service.AuthorizeURL(ctx, userID, "lifx", testOAuthState, testCodeChallenge, "S256"); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Errorf("Expected ErrInvalidOAuthState for a state in use, got %v", err)
+	}
+
+	if _, err := service.CompleteAuthorization(ctx, "lifx", "unknown-state-0123456789", testAuthorizedCode); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Errorf("Expected ErrInvalidOAuthState for an unknown state, got %v", err)
+	}
+
+	if _, err := service.CompleteAuthorization(ctx, "lifx", testOAuthState, testAuthorizedCode); err != nil {
+		t.Fatalf("CompleteAuthorization failed: %v", err)
+	}
+	if _, err := service.CompleteAuthorization(ctx, "lifx", testOAuthState, testAuthorizedCode); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Errorf("Expected ErrInvalidOAuthState for a reused state, got %v", err)
+	}
+
+	startAuthorization(t, service, userID, "expiring-state-0123456789")
+	mr.FastForward(pkceStateTTL)
+	if _, err := service.CompleteAuthorization(ctx, "lifx", "expiring-state-0123456789", testAuthorizedCode); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Errorf("Expected ErrInvalidOAuthState for an expired state, got %v", err)
+	}
+}
+
+func TestOAuthFlow_CodeVerifierChecking(t *testing.T) {
+	service, mr, repo := newTestProviderOAuthService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	testCases := []struct {
+		name      string
+		challenge string
+		method    string
+	}{
+		{name: "plain method", challenge: testCodeChallenge, method: "plain"},
+		{name: "missing method", challenge: testCodeChallenge},
+		{name: "malformed challenge", challenge: "not+a/valid=challenge", method: "S256"},
+	}
+	for _, tc := range testCases {
+		if _, err := service.AuthorizeURL(ctx, userID, "lifx", testOAuthState, tc.challenge, tc.method); !errors.Is(err, ErrInvalidCodeChallenge) {
+			t.Errorf("%s: expected ErrInvalidCodeChallenge, got %v", tc.name, err)
+		}
+	}
+
+	// A code redeemed with a verifier other than the one whose challenge the provider was
+	// sent is rejected
+	startAuthorization(t, service, userID, testOAuthState)
+	other, _ := oauth.NewCodeVerifier()
+	pending := pendingAuthorization{UserID: userID, Provider: "lifx", CodeVerifier: other}
+	data, _ := json.Marshal(pending)
+	mr.Set(pkceStateKey(testOAuthState), string(data))
+
+	if _, err := service.CompleteAuthorization(ctx, "lifx", testOAuthState, testAuthorizedCode); !errors.Is(err, oauth.ErrInvalidGrant) {
+		t.Errorf("Expected ErrInvalidGrant, got %v", err)
+	}
+	if accounts, _ := repo.FindByUserID(ctx, userID); len(accounts) != 0 {
+		t.Errorf("Expected no account to be connected, got %d", len(accounts))
+	}
+}
+
+func TestOAuthFlow_NotConfigured(t *testing.T) {
+	service, _, _ := newTestProviderOAuthService(t)
+
+	if _, err := service.AuthorizeURL(context.Background(), uuid.New(), "hue", testOAuthState, testCodeChallenge, "S256"); !errors.Is(err, ErrOAuthNotConfigured) {
+		t.Errorf("Expected ErrOAuthNotConfigured, got %v", err)
+	}
+}
+
+func TestOAuthFlow_CollectRequiresCodeVerifier(t *testing.T) {
+	service, _, repo := newTestProviderOAuthService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	startAuthorization(t, service, userID, testOAuthState)
+	if _, err := service.CompleteAuthorization(ctx, "lifx", testOAuthState, testAuthorizedCode); err != nil {
+		t.Fatalf("CompleteAuthorization failed: %v", err)
+	}
+
+	// Only the client that started the authorization can collect it
+	other, _ := oauth.NewCodeVerifier()
+	if _, err := service.CollectAuthorization(ctx, userID, "lifx", testOAuthState, other); !errors.Is(err, ErrInvalidCodeVerifier) {
+		t.Errorf("Expected ErrInvalidCodeVerifier, got %v", err)
+	}
+	if _, err := service.CollectAuthorization(ctx, uuid.New(), "lifx", testOAuthState, testCodeVerifier); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Errorf("Expected ErrInvalidOAuthState for another user, got %v", err)
+	}
+
+	account, err := service.CollectAuthorization(ctx, userID, "lifx", testOAuthState, testCodeVerifier)
+	if err != nil {
+		t.Fatalf("CollectAuthorization failed: %v", err)
+	}
+	accounts, _ := repo.FindByUserID(ctx, userID)
+	if len(accounts) != 1 || account.ID != accounts[0].ID {
+		t.Errorf("Expected the connected account, got %v", account.ID)
+	}
+
+	if _, err := service.CollectAuthorization(ctx, userID, "lifx", testOAuthState, testCodeVerifier); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Errorf("Expected ErrInvalidOAuthState for a collected result, got %v", err)
+	}
+}
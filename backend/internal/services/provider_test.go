@@ -2,14 +2,23 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/lightshare/backend/internal/models"
 	"github.com/lightshare/backend/internal/repository"
 	"github.com/lightshare/backend/pkg/crypto"
 	"github.com/lightshare/backend/pkg/providers"
+	"github.com/lightshare/backend/pkg/providers/mock"
 )
 
 // MockAccountRepository is a simple in-memory implementation for testing
@@ -38,13 +47,20 @@ func (m *MockAccountRepository) Create(_ context.Context, params *models.CreateA
 		OwnerUserID:       params.OwnerUserID,
 		Provider:          params.Provider,
 		ProviderAccountID: params.ProviderAccountID,
+		Label:             models.DefaultAccountLabel(params.ProviderAccountID),
 		EncryptedToken:    params.EncryptedToken,
+		KeyID:             params.KeyID,
+	}
+	if params.Metadata != nil {
+		account.Metadata, _ = json.Marshal(params.Metadata)
 	}
 
 	m.accounts[account.ID] = account
 	return account, nil
 }
 
+// FindByUserID returns the accounts of a user ordered by ID, so that listings keep the
+// same order from one call to the next
 func (m *MockAccountRepository) FindByUserID(_ context.Context, userID uuid.UUID) ([]*models.Account, error) {
 	var result []*models.Account
 	for _, account := range m.accounts {
@@ -52,6 +68,9 @@ func (m *MockAccountRepository) FindByUserID(_ context.Context, userID uuid.UUID
 			result = append(result, account)
 		}
 	}
+	slices.SortFunc(result, func(a, b *models.Account) int {
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
 	return result, nil
 }
 
@@ -62,6 +81,79 @@ func (m *MockAccountRepository) FindByID(_ context.Context, accountID uuid.UUID)
 	return nil, repository.ErrAccountNotFound
 }
 
+func (m *MockAccountRepository) FindByIDString(ctx context.Context, accountID string) (*models.Account, error) {
+	id, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, repository.ErrAccountNotFound
+	}
+	return m.FindByID(ctx, id)
+}
+
+// GetDecryptedToken returns the stored token bytes as-is; tests store plaintext tokens
+func (m *MockAccountRepository) GetDecryptedToken(ctx context.Context, accountID string) (string, error) {
+	account, err := m.FindByIDString(ctx, accountID)
+	if err != nil {
+		return "", err
+	}
+	return string(account.EncryptedToken), nil
+}
+
+func (m *MockAccountRepository) UpdateToken(_ context.Context, accountID, userID uuid.UUID, encryptedToken []byte, keyID string, metadata map[string]interface{}) error {
+	account, ok := m.accounts[accountID]
+	if !ok || account.OwnerUserID != userID {
+		return repository.ErrAccountNotFound
+	}
+	account.EncryptedToken = encryptedToken
+	account.KeyID = keyID
+	account.Metadata, _ = json.Marshal(metadata)
+	account.TokenInvalidAt = nil
+	account.UpdatedAt = time.Now()
+	return nil
+}
+
+// FindAll returns a page of the accounts ordered by ID
+func (m *MockAccountRepository) FindAll(_ context.Context, offset, limit int) ([]*models.Account, error) {
+	accounts := make([]*models.Account, 0, len(m.accounts))
+	for _, account := range m.accounts {
+		accounts = append(accounts, account)
+	}
+	slices.SortFunc(accounts, func(a, b *models.Account) int {
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+	if offset >= len(accounts) {
+		return nil, nil
+	}
+	return accounts[offset:min(offset+limit, len(accounts))], nil
+}
+
+func (m *MockAccountRepository) MarkTokenInvalid(_ context.Context, accountID uuid.UUID, invalidAt time.Time) error {
+	account, ok := m.accounts[accountID]
+	if !ok {
+		return repository.ErrAccountNotFound
+	}
+	account.TokenInvalidAt = &invalidAt
+	return nil
+}
+
+func (m *MockAccountRepository) MarkTokenValid(_ context.Context, accountID uuid.UUID) error {
+	account, ok := m.accounts[accountID]
+	if !ok {
+		return repository.ErrAccountNotFound
+	}
+	account.TokenInvalidAt = nil
+	return nil
+}
+
+func (m *MockAccountRepository) UpdateLabel(_ context.Context, accountID, userID uuid.UUID, label string) error {
+	account, ok := m.accounts[accountID]
+	if !ok || account.OwnerUserID != userID {
+		return repository.ErrAccountNotFound
+	}
+	account.Label = label
+	account.UpdatedAt = time.Now()
+	return nil
+}
+
 func (m *MockAccountRepository) Delete(_ context.Context, accountID, userID uuid.UUID) error {
 	if account, ok := m.accounts[accountID]; ok {
 		if account.OwnerUserID != userID {
@@ -73,44 +165,65 @@ func (m *MockAccountRepository) Delete(_ context.Context, accountID, userID uuid
 	return repository.ErrAccountNotFound
 }
 
+// newTestKeys returns a key provider encrypting with a fixed local key
+func newTestKeys(t *testing.T) *crypto.LocalKeyProvider {
+	t.Helper()
+	keys, err := crypto.NewLocalKeyProvider([]byte("12345678901234567890123456789012"))
+	if err != nil {
+		t.Fatalf("Failed to create key provider: %v", err)
+	}
+	return keys
+}
+
 func TestConnectProvider_Success(t *testing.T) {
-	// Setup
 	repo := NewMockAccountRepository()
-	encryptionKey, _ := crypto.GenerateEncryptionKey()
-	key, _ := crypto.LoadEncryptionKey() // This will fail, so use generated key
-	if key == nil {
-		keyBytes := []byte(encryptionKey)
-		if len(keyBytes) >= 32 {
-			key = keyBytes[:32]
-		} else {
-			key = []byte("12345678901234567890123456789012") // Fallback
-		}
+	service := NewProviderService(repo, newTestKeys(t), nil, 0)
+	client := mock.NewMockClient()
+	service.newClient = func(_ providers.Provider) (providers.Client, error) {
+		return client, nil
 	}
-
-	service := NewProviderService(repo, key)
 	userID := uuid.New()
 
-	// Note: This test will fail in CI without a real LIFX token
-	// For now, we're just testing the basic flow
 	req := ConnectProviderRequest{
 		Provider: string(providers.ProviderLIFX),
 		Token:    "mock-token",
 	}
 
-	// This will fail because we don't have a valid token
-	// But it tests the validation flow
-	_, err := service.ConnectProvider(context.Background(), userID, req)
+	account, err := service.ConnectProvider(context.Background(), userID, req)
+	if err != nil {
+		t.Fatalf("ConnectProvider failed: %v", err)
+	}
+	if account.OwnerUserID != userID || account.ProviderAccountID != mock.AccountID {
+		t.Errorf("Expected %s's account %s, got %+v", userID, mock.AccountID, account)
+	}
+	if calls := client.Calls["ValidateToken"]; calls != 1 {
+		t.Errorf("Expected the token to be validated once, got %d calls", calls)
+	}
+}
 
-	// We expect an error because the token is invalid
-	if err == nil {
-		t.Fatal("Expected error for invalid token, got nil")
+func TestConnectProvider_InvalidToken(t *testing.T) {
+	repo := NewMockAccountRepository()
+	service := NewProviderService(repo, newTestKeys(t), nil, 0)
+	client := mock.NewMockClient()
+	client.ValidateTokenFn = func(string) (*providers.AccountInfo, error) {
+		return nil, providers.ErrUnauthorized
+	}
+	service.newClient = func(_ providers.Provider) (providers.Client, error) {
+		return client, nil
+	}
+
+	req := ConnectProviderRequest{Provider: string(providers.ProviderLIFX), Token: "bad-token"}
+	if _, err := service.ConnectProvider(context.Background(), uuid.New(), req); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Expected ErrInvalidToken, got %v", err)
+	}
+	if len(repo.accounts) != 0 {
+		t.Error("Expected no account to be stored for an invalid token")
 	}
 }
 
 func TestConnectProvider_InvalidProvider(t *testing.T) {
 	repo := NewMockAccountRepository()
-	key := []byte("12345678901234567890123456789012")
-	service := NewProviderService(repo, key)
+	service := NewProviderService(repo, newTestKeys(t), nil, 0)
 	userID := uuid.New()
 
 	req := ConnectProviderRequest{
@@ -129,10 +242,24 @@ func TestConnectProvider_InvalidProvider(t *testing.T) {
 	}
 }
 
+func TestConnectProvider_DisabledProvider(t *testing.T) {
+	repo := NewMockAccountRepository()
+	service := NewProviderService(repo, newTestKeys(t), nil, 0)
+	service.SetEnabledProviders(func() []string { return []string{"lifx"} })
+
+	_, err := service.ConnectProvider(context.Background(), uuid.New(), ConnectProviderRequest{
+		Provider: string(providers.ProviderHue),
+		Token:    "test-token",
+	})
+	if !errors.Is(err, providers.ErrProviderDisabled) {
+		t.Fatalf("Expected ErrProviderDisabled, got %v", err)
+	}
+}
+
 func TestListAccounts(t *testing.T) {
 	repo := NewMockAccountRepository()
 	key := []byte("12345678901234567890123456789012")
-	service := NewProviderService(repo, key)
+	service := NewProviderService(repo, newTestKeys(t), nil, 0)
 	userID := uuid.New()
 
 	// Create a mock account directly in the repo
@@ -162,7 +289,7 @@ func TestListAccounts(t *testing.T) {
 func TestDisconnectAccount_Success(t *testing.T) {
 	repo := NewMockAccountRepository()
 	key := []byte("12345678901234567890123456789012")
-	service := NewProviderService(repo, key)
+	service := NewProviderService(repo, newTestKeys(t), nil, 0)
 	userID := uuid.New()
 
 	// Create a mock account
@@ -190,7 +317,7 @@ func TestDisconnectAccount_Success(t *testing.T) {
 func TestDisconnectAccount_NotOwned(t *testing.T) {
 	repo := NewMockAccountRepository()
 	key := []byte("12345678901234567890123456789012")
-	service := NewProviderService(repo, key)
+	service := NewProviderService(repo, newTestKeys(t), nil, 0)
 	userID := uuid.New()
 	otherUserID := uuid.New()
 
@@ -213,3 +340,300 @@ func TestDisconnectAccount_NotOwned(t *testing.T) {
 		t.Fatalf("Expected ErrAccountNotOwned, got %v", err)
 	}
 }
+
+func TestCheckAccountHealth_CachesValidation(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = cache.Close() })
+
+	repo := NewMockAccountRepository()
+	service := NewProviderService(repo, newTestKeys(t), cache, time.Minute)
+	client := mock.NewMockClient()
+	service.newClient = func(_ providers.Provider) (providers.Client, error) {
+		return client, nil
+	}
+
+	userID := uuid.New()
+	account, _ := repo.Create(context.Background(), &models.CreateAccountParams{
+		OwnerUserID:       userID,
+		Provider:          string(providers.ProviderLIFX),
+		ProviderAccountID: "test-account-1",
+		EncryptedToken:    []byte("test-token"),
+	})
+
+	first, err := service.CheckAccountHealth(context.Background(), userID, account.ID)
+	if err != nil {
+		t.Fatalf("First health check failed: %v", err)
+	}
+	if !first.Healthy || first.Cached {
+		t.Errorf("Expected fresh healthy result, got %+v", first)
+	}
+
+	second, err := service.CheckAccountHealth(context.Background(), userID, account.ID)
+	if err != nil {
+		t.Fatalf("Second health check failed: %v", err)
+	}
+	if !second.Healthy || !second.Cached {
+		t.Errorf("Expected cached healthy result, got %+v", second)
+	}
+
+	if calls := client.CallCount("ValidateToken"); calls != 1 {
+		t.Fatalf("Expected 1 provider validation within the TTL, got %d", calls)
+	}
+
+	// A 401 from any provider call drops the cached validation
+	service.validations.invalidateOnUnauthorized(context.Background(), account.ID.String(), fmt.Errorf("%w: token revoked", providers.ErrUnauthorized))
+	client.ValidateTokenFn = func(string) (*providers.AccountInfo, error) {
+		return nil, providers.ErrUnauthorized
+	}
+
+	third, err := service.CheckAccountHealth(context.Background(), userID, account.ID)
+	if err != nil {
+		t.Fatalf("Third health check failed: %v", err)
+	}
+	if third.Healthy {
+		t.Errorf("Expected unhealthy result after 401, got %+v", third)
+	}
+	if calls := client.CallCount("ValidateToken"); calls != 2 {
+		t.Errorf("Expected provider to be called again after invalidation, got %d calls", calls)
+	}
+}
+
+func TestListProviders_ConnectionStatus(t *testing.T) {
+	repo := NewMockAccountRepository()
+	service := NewProviderService(repo, newTestKeys(t), nil, 0)
+	userID := uuid.New()
+
+	for _, providerAccountID := range []string{"lifx-account-1", "lifx-account-2"} {
+		_, _ = repo.Create(context.Background(), &models.CreateAccountParams{
+			OwnerUserID:       userID,
+			Provider:          string(providers.ProviderLIFX),
+			ProviderAccountID: providerAccountID,
+			EncryptedToken:    []byte("test-token"),
+		})
+	}
+
+	// Another user's connection must not count
+	_, _ = repo.Create(context.Background(), &models.CreateAccountParams{
+		OwnerUserID:       uuid.New(),
+		Provider:          string(providers.ProviderHue),
+		ProviderAccountID: "hue-account-1",
+		EncryptedToken:    []byte("test-token"),
+	})
+
+	statuses, err := service.ListProviders(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("ListProviders failed: %v", err)
+	}
+
+	if len(statuses) != 6 {
+		t.Fatalf("Expected 6 providers, got %d", len(statuses))
+	}
+
+	lifx, hue := statuses[0], statuses[1]
+	if lifx.ID != string(providers.ProviderLIFX) || !lifx.Connected || lifx.AccountCount != 2 {
+		t.Errorf("Expected LIFX connected with 2 accounts, got %+v", lifx)
+	}
+	if hue.ID != string(providers.ProviderHue) || hue.Connected || hue.AccountCount != 0 {
+		t.Errorf("Expected Hue not connected, got %+v", hue)
+	}
+}
+
+func TestReconnectAccount(t *testing.T) {
+	repo := NewMockAccountRepository()
+	service := NewProviderService(repo, newTestKeys(t), nil, 0)
+	client := mock.NewMockClient()
+	service.newClient = func(_ providers.Provider) (providers.Client, error) {
+		return client, nil
+	}
+
+	userID := uuid.New()
+	account, _ := repo.Create(context.Background(), &models.CreateAccountParams{
+		OwnerUserID:       userID,
+		Provider:          string(providers.ProviderLIFX),
+		ProviderAccountID: mock.AccountID,
+		EncryptedToken:    []byte("old-token"),
+	})
+
+	updated, err := service.ReconnectAccount(context.Background(), userID, account.ID, "new-token")
+	if err != nil {
+		t.Fatalf("ReconnectAccount failed: %v", err)
+	}
+	if updated.ID != account.ID {
+		t.Errorf("Expected account %s to be kept, got %s", account.ID, updated.ID)
+	}
+	if string(updated.EncryptedToken) == "old-token" {
+		t.Error("Expected stored token to be replaced")
+	}
+
+	if _, err := service.ReconnectAccount(context.Background(), uuid.New(), account.ID, "new-token"); !errors.Is(err, ErrAccountNotOwned) {
+		t.Errorf("Expected ErrAccountNotOwned, got %v", err)
+	}
+
+	client.ValidateTokenFn = func(string) (*providers.AccountInfo, error) {
+		return nil, providers.ErrUnauthorized
+	}
+	if _, err := service.ReconnectAccount(context.Background(), userID, account.ID, "bad-token"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken, got %v", err)
+	}
+}
+
+// fakeKMSKeys "encrypts" tokens as is, naming a fixed KMS key
+type fakeKMSKeys struct{}
+
+func (fakeKMSKeys) EncryptToken(plaintext string) ([]byte, string, error) {
+	return []byte(plaintext), "arn:aws:kms:eu-west-1:111122223333:key/test", nil
+}
+
+func (fakeKMSKeys) DecryptToken(ciphertext []byte, _ string) (string, error) {
+	return string(ciphertext), nil
+}
+
+func TestReconnectAccount_StoresKeyID(t *testing.T) {
+	repo := NewMockAccountRepository()
+	service := NewProviderService(repo, fakeKMSKeys{}, nil, 0)
+	service.newClient = func(_ providers.Provider) (providers.Client, error) {
+		return mock.NewMockClient(), nil
+	}
+
+	// Stored before switching to KMS, with the local key
+	userID := uuid.New()
+	account, _ := repo.Create(context.Background(), &models.CreateAccountParams{
+		OwnerUserID:       userID,
+		Provider:          string(providers.ProviderLIFX),
+		ProviderAccountID: mock.AccountID,
+		EncryptedToken:    []byte("old-token"),
+	})
+
+	updated, err := service.ReconnectAccount(context.Background(), userID, account.ID, "new-token")
+	if err != nil {
+		t.Fatalf("ReconnectAccount failed: %v", err)
+	}
+	if updated.KeyID != "arn:aws:kms:eu-west-1:111122223333:key/test" {
+		t.Errorf("Expected the KMS key ID to be stored, got %q", updated.KeyID)
+	}
+}
+
+func TestReconnectAccount_DifferentProviderAccount(t *testing.T) {
+	repo := NewMockAccountRepository()
+	service := NewProviderService(repo, newTestKeys(t), nil, 0)
+	service.newClient = func(_ providers.Provider) (providers.Client, error) {
+		return mock.NewMockClient(), nil
+	}
+
+	userID := uuid.New()
+	account, _ := repo.Create(context.Background(), &models.CreateAccountParams{
+		OwnerUserID:       userID,
+		Provider:          string(providers.ProviderLIFX),
+		ProviderAccountID: "test-account-1",
+		EncryptedToken:    []byte("old-token"),
+	})
+
+	_, err := service.ReconnectAccount(context.Background(), userID, account.ID, "other-token")
+	if !errors.Is(err, ErrProviderAccountMismatch) {
+		t.Fatalf("Expected ErrProviderAccountMismatch, got %v", err)
+	}
+	if string(account.EncryptedToken) != "old-token" {
+		t.Error("Expected stored token to be left unchanged")
+	}
+}
+
+func TestReconnectAccount_InvalidatesDeviceCache(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = cache.Close() })
+
+	repo := NewMockAccountRepository()
+	service := NewProviderService(repo, newTestKeys(t), cache, 0)
+	service.newClient = func(_ providers.Provider) (providers.Client, error) {
+		return mock.NewMockClient(), nil
+	}
+
+	userID := uuid.New()
+	account, _ := repo.Create(context.Background(), &models.CreateAccountParams{
+		OwnerUserID:       userID,
+		Provider:          string(providers.ProviderLIFX),
+		ProviderAccountID: mock.AccountID,
+		EncryptedToken:    []byte("old-token"),
+	})
+	mr.Set(devicesCacheKey(account.ID.String()), "[]")
+	mr.Set(deviceSummaryKey(userID.String()), "{}")
+
+	if _, err := service.ReconnectAccount(context.Background(), userID, account.ID, "new-token"); err != nil {
+		t.Fatalf("ReconnectAccount failed: %v", err)
+	}
+	if mr.Exists(devicesCacheKey(account.ID.String())) || mr.Exists(deviceSummaryKey(userID.String())) {
+		t.Error("Expected the account's cached devices and the user's summary to be invalidated")
+	}
+}
+
+func TestUpdateAccountLabel(t *testing.T) {
+	repo := NewMockAccountRepository()
+	service := NewProviderService(repo, newTestKeys(t), nil, 0)
+
+	userID := uuid.New()
+	account, _ := repo.Create(context.Background(), &models.CreateAccountParams{
+		OwnerUserID:       userID,
+		Provider:          string(providers.ProviderLIFX),
+		ProviderAccountID: "test-account-1",
+		EncryptedToken:    []byte("test-token"),
+	})
+	if account.Label != "test-account-1" {
+		t.Errorf("Expected label to default to the provider account ID, got %q", account.Label)
+	}
+
+	updated, err := service.UpdateAccountLabel(context.Background(), userID, account.ID, "  Bedroom Hub  ")
+	if err != nil {
+		t.Fatalf("UpdateAccountLabel failed: %v", err)
+	}
+	if updated.Label != "Bedroom Hub" || updated.ToResponse().Label != "Bedroom Hub" {
+		t.Errorf("Expected trimmed label 'Bedroom Hub', got %q", updated.Label)
+	}
+
+	if _, err := service.UpdateAccountLabel(context.Background(), uuid.New(), account.ID, "Kitchen"); !errors.Is(err, ErrAccountNotOwned) {
+		t.Errorf("Expected ErrAccountNotOwned, got %v", err)
+	}
+	if _, err := service.UpdateAccountLabel(context.Background(), userID, account.ID, "   "); !errors.Is(err, ErrInvalidAccountLabel) {
+		t.Errorf("Expected ErrInvalidAccountLabel for blank label, got %v", err)
+	}
+	if _, err := service.UpdateAccountLabel(context.Background(), userID, account.ID, strings.Repeat("é", 101)); !errors.Is(err, ErrInvalidAccountLabel) {
+		t.Errorf("Expected ErrInvalidAccountLabel for long label, got %v", err)
+	}
+	if _, err := service.UpdateAccountLabel(context.Background(), userID, account.ID, strings.Repeat("é", 100)); err != nil {
+		t.Errorf("Expected a 100 character label to be accepted, got %v", err)
+	}
+}
+
+func TestConnectProvider_MarksReadOnlyToken(t *testing.T) {
+	repo := NewMockAccountRepository()
+	service := NewProviderService(repo, newTestKeys(t), nil, 0)
+	scopes := providers.TokenScopesRead
+	client := mock.NewMockClient()
+	client.ValidateTokenFn = func(string) (*providers.AccountInfo, error) {
+		return &providers.AccountInfo{
+			ProviderAccountID: mock.AccountID,
+			Metadata:          map[string]interface{}{providers.MetadataTokenScopes: scopes},
+		}, nil
+	}
+	service.newClient = func(_ providers.Provider) (providers.Client, error) {
+		return client, nil
+	}
+	req := ConnectProviderRequest{Provider: string(providers.ProviderLIFX), Token: "read-token"}
+
+	account, err := service.ConnectProvider(context.Background(), uuid.New(), req)
+	if err != nil {
+		t.Fatalf("ConnectProvider failed: %v", err)
+	}
+	if !account.IsReadOnly() || !account.ToResponse().ReadOnly {
+		t.Error("Expected an account connected with a read-only token to be read-only")
+	}
+
+	scopes = providers.TokenScopesReadWrite
+	account, err = service.ConnectProvider(context.Background(), uuid.New(), req)
+	if err != nil {
+		t.Fatalf("ConnectProvider failed: %v", err)
+	}
+	if account.IsReadOnly() || account.ToResponse().ReadOnly {
+		t.Error("Expected an account connected with a read-write token not to be read-only")
+	}
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/logger"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// defaultRampInterval is how often a running ramp steps the brightness up
+const defaultRampInterval = 30 * time.Second
+
+// ErrRampNotFound is returned when cancelling a ramp that is not running
+var ErrRampNotFound = apierror.New(apierror.ErrNotFound, "no ramp is running for this selector")
+
+// activeRamp is a ramp running on this instance
+type activeRamp struct {
+	cancel context.CancelFunc
+}
+
+// rampKey marks a running ramp in Redis; it expires when the ramp would have finished
+func rampKey(accountID, selector string) string {
+	return fmt.Sprintf("ramp:account:%s:device:%s", accountID, selector)
+}
+
+// rampSteps returns how many interval-long steps a ramp of duration takes
+func rampSteps(duration, interval time.Duration) int {
+	steps := int((duration + interval - 1) / interval)
+	if steps < 1 {
+		return 1
+	}
+	return steps
+}
+
+// ExecuteRamp turns the selected lights on at zero brightness and brings them up to the
+// requested brightness (and temperature) in the background, stepping every 30 seconds.
+// A ramp already running for the selector is replaced.
+func (s *DeviceService) ExecuteRamp(ctx context.Context, userID, accountID, selector string, req *models.RampRequest) error {
+	if err := req.Validate(); err != nil {
+		return &apierror.BadRequestError{Message: err.Error(), Cause: err}
+	}
+
+	// Get account and verify ownership
+	account, err := s.accountRepo.FindByIDString(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("account not found: %w", err)
+	}
+
+	if account.OwnerUserID.String() != userID {
+		return ErrAccountNotOwned
+	}
+	if account.IsReadOnly() {
+		return ErrAccountReadOnly
+	}
+
+	// Check rate limit
+	if rateLimitErr := s.checkRateLimit(ctx, userID, account, rateLimitWrite); rateLimitErr != nil {
+		return rateLimitErr
+	}
+
+	// Get decrypted token
+	token, err := s.accountRepo.GetDecryptedToken(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to get token: %w", err)
+	}
+
+	// Create provider client
+	client, err := s.newClient(providers.Provider(account.Provider))
+	if err != nil {
+		return fmt.Errorf("failed to create provider client: %w", err)
+	}
+
+	interval := s.rampInterval
+	if interval <= 0 {
+		interval = defaultRampInterval
+	}
+
+	if err := s.startRamp(ctx, account, client, token, selector, req, rampSteps(req.Duration(), interval), interval); err != nil {
+		return err
+	}
+
+	logger.Info("Ramp started",
+		"account_id", accountID,
+		"selector", selector,
+		"target_brightness", req.TargetBrightness,
+		"ramp_minutes", req.RampMinutes,
+	)
+	return nil
+}
+
+// startRamp records the ramp in Redis and runs it in the background in steps steps, one
+// every interval, replacing any ramp already running for the selector
+func (s *DeviceService) startRamp(ctx context.Context, account *models.Account, client providers.Client, token, selector string, req *models.RampRequest, steps int, interval time.Duration) error {
+	accountID := account.ID.String()
+	key := rampKey(accountID, selector)
+	if previous, ok := s.ramps.LoadAndDelete(key); ok {
+		previous.(*activeRamp).cancel()
+	}
+	// The key outlives the ramp by one step so the final step still sees it
+	if err := s.cache.Set(ctx, key, account.OwnerUserID.String(), time.Duration(steps+1)*interval).Err(); err != nil {
+		return fmt.Errorf("failed to store ramp: %w", err)
+	}
+
+	// The ramp outlives the request that started it
+	rampCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	ramp := &activeRamp{cancel: cancel}
+	s.ramps.Store(key, ramp)
+
+	go func() {
+		defer cancel()
+		s.runRamp(rampCtx, account, client, token, selector, req, steps, interval)
+
+		// Only clear the key if the ramp was not replaced or cancelled meanwhile
+		if s.ramps.CompareAndDelete(key, ramp) {
+			if err := s.cache.Del(context.WithoutCancel(rampCtx), key).Err(); err != nil {
+				logger.Warn("Failed to clear finished ramp", "error", err, "account_id", accountID, "selector", selector)
+			}
+		}
+	}()
+	return nil
+}
+
+// runRamp steps the lights up until the ramp completes, ctx is cancelled or the ramp's
+// key disappears from Redis (cancelled through another instance)
+func (s *DeviceService) runRamp(ctx context.Context, account *models.Account, client providers.Client, token, selector string, req *models.RampRequest, steps int, interval time.Duration) {
+	accountID := account.ID.String()
+	key := rampKey(accountID, selector)
+
+	err := s.callProvider(ctx, account, "ramp_start", selector, func(ctx context.Context) error {
+		client := providers.WithContext(ctx, client)
+		if req.TargetKelvin != nil {
+			if err := client.SetColorTemperature(token, selector, models.RampStartKelvin, 0); err != nil {
+				return err
+			}
+		}
+		if err := client.SetBrightness(token, selector, 0, 0); err != nil {
+			return err
+		}
+		return client.SetPower(token, selector, true, 0)
+	})
+	if err != nil {
+		logger.Warn("Failed to start ramp", "error", err, "account_id", accountID, "selector", selector)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for step := 1; step <= steps; step++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if exists, err := s.cache.Exists(ctx, key).Result(); err == nil && exists == 0 {
+			return
+		}
+
+		brightness, kelvin := req.RampStep(step, steps)
+		err := s.callProvider(ctx, account, "ramp_step", selector, func(ctx context.Context) error {
+			client := providers.WithContext(ctx, client)
+			// Fade into each step so the ramp looks continuous
+			if kelvin > 0 {
+				if err := client.SetColorTemperature(token, selector, kelvin, interval.Seconds()); err != nil {
+					return err
+				}
+			}
+			return client.SetBrightness(token, selector, brightness, interval.Seconds())
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			// Keep going: the next step catches up with the brightness the ramp is at
+			logger.Warn("Ramp step failed", "error", err, "account_id", accountID, "selector", selector, "step", step)
+		}
+	}
+}
+
+// CancelRamp stops the ramp running for the selector
+func (s *DeviceService) CancelRamp(ctx context.Context, userID, accountID, selector string) error {
+	// Get account and verify ownership
+	account, err := s.accountRepo.FindByIDString(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("account not found: %w", err)
+	}
+
+	if account.OwnerUserID.String() != userID {
+		return ErrAccountNotOwned
+	}
+
+	key := rampKey(accountID, selector)
+	deleted, err := s.cache.Del(ctx, key).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to cancel ramp: %w", err)
+	}
+
+	ramp, running := s.ramps.LoadAndDelete(key)
+	if running {
+		ramp.(*activeRamp).cancel()
+	}
+	if deleted == 0 && !running {
+		return ErrRampNotFound
+	}
+
+	logger.Info("Ramp cancelled", "account_id", accountID, "selector", selector)
+	return nil
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// waitFor polls cond until it holds or a second has passed
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestExecuteRamp_Start(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "d1", Label: "Bedroom", Power: "off", Connected: true})
+	service, account := newTestDeviceService(t, client)
+	service.rampInterval = time.Hour // Only the initial state is applied during the test
+	userID := account.OwnerUserID.String()
+	accountID := account.ID.String()
+
+	kelvin := 5000
+	req := &models.RampRequest{TargetBrightness: 1, RampMinutes: 30, TargetKelvin: &kelvin}
+	if err := service.ExecuteRamp(context.Background(), userID, accountID, "id:d1", req); err != nil {
+		t.Fatalf("ExecuteRamp failed: %v", err)
+	}
+	t.Cleanup(func() { _ = service.CancelRamp(context.Background(), userID, accountID, "id:d1") })
+
+	waitFor(t, "the lights to turn on", func() bool { return client.callCount("SetPower") == 1 })
+	if client.callCount("SetBrightness") != 1 || client.callCount("SetColorTemperature") != 1 {
+		t.Errorf("Expected the ramp to start dimmed at the start temperature, got %d brightness and %d temperature calls",
+			client.callCount("SetBrightness"), client.callCount("SetColorTemperature"))
+	}
+
+	ttl, err := service.cache.TTL(context.Background(), rampKey(accountID, "id:d1")).Result()
+	if err != nil {
+		t.Fatalf("Failed to read ramp TTL: %v", err)
+	}
+	if ttl < 30*time.Minute {
+		t.Errorf("Expected the ramp key to live at least the ramp duration, got %v", ttl)
+	}
+}
+
+func TestExecuteRamp_Validation(t *testing.T) {
+	service, account := newTestDeviceService(t, newFakeProviderClient())
+	userID := account.OwnerUserID.String()
+
+	kelvin := 12000
+	testCases := map[string]*models.RampRequest{
+		"zero brightness": {TargetBrightness: 0, RampMinutes: 30},
+		"too long":        {TargetBrightness: 1, RampMinutes: models.MaxRampMinutes + 1},
+		"bad kelvin":      {TargetBrightness: 1, RampMinutes: 30, TargetKelvin: &kelvin},
+	}
+	for name, req := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := service.ExecuteRamp(context.Background(), userID, account.ID.String(), "all", req)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+		})
+	}
+
+	if err := service.ExecuteRamp(context.Background(), "someone-else", account.ID.String(), "all",
+		&models.RampRequest{TargetBrightness: 1, RampMinutes: 30}); !errors.Is(err, ErrAccountNotOwned) {
+		t.Errorf("Expected ErrAccountNotOwned, got %v", err)
+	}
+}
+
+func TestCancelRamp(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "d1", Label: "Bedroom", Power: "off", Connected: true})
+	service, account := newTestDeviceService(t, client)
+	service.rampInterval = 5 * time.Millisecond
+	userID := account.OwnerUserID.String()
+	accountID := account.ID.String()
+	key := rampKey(accountID, "all")
+
+	req := &models.RampRequest{TargetBrightness: 0.8, RampMinutes: 30}
+	if err := service.ExecuteRamp(context.Background(), userID, accountID, "all", req); err != nil {
+		t.Fatalf("ExecuteRamp failed: %v", err)
+	}
+	waitFor(t, "the first step", func() bool { return client.callCount("SetBrightness") >= 2 })
+
+	if err := service.CancelRamp(context.Background(), userID, accountID, "all"); err != nil {
+		t.Fatalf("CancelRamp failed: %v", err)
+	}
+	if exists := service.cache.Exists(context.Background(), key).Val(); exists != 0 {
+		t.Error("Expected the ramp key to be deleted")
+	}
+	if _, ok := service.ramps.Load(key); ok {
+		t.Error("Expected the ramp to be removed")
+	}
+
+	// Let any step in flight when the ramp was cancelled finish
+	time.Sleep(20 * time.Millisecond)
+	calls := client.callCount("SetBrightness")
+	time.Sleep(20 * time.Millisecond)
+	if got := client.callCount("SetBrightness"); got != calls {
+		t.Errorf("Expected no steps after cancelling, got %d more", got-calls)
+	}
+
+	if err := service.CancelRamp(context.Background(), userID, accountID, "all"); !errors.Is(err, ErrRampNotFound) {
+		t.Errorf("Expected ErrRampNotFound cancelling twice, got %v", err)
+	}
+}
+
+func TestRamp_Completion(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "d1", Label: "Bedroom", Power: "off", Connected: true})
+	service, account := newTestDeviceService(t, client)
+	key := rampKey(account.ID.String(), "all")
+
+	kelvin := 5000
+	req := &models.RampRequest{TargetBrightness: 1, RampMinutes: 1, TargetKelvin: &kelvin}
+	if err := service.startRamp(context.Background(), account, client, "test-token", "all", req, 4, time.Millisecond); err != nil {
+		t.Fatalf("startRamp failed: %v", err)
+	}
+
+	waitFor(t, "the ramp to finish", func() bool {
+		_, running := service.ramps.Load(key)
+		return !running && service.cache.Exists(context.Background(), key).Val() == 0
+	})
+
+	// One call for the initial state and one per step
+	if got := client.callCount("SetBrightness"); got != 5 {
+		t.Errorf("Expected 5 brightness calls, got %d", got)
+	}
+	if got := client.callCount("SetColorTemperature"); got != 5 {
+		t.Errorf("Expected 5 temperature calls, got %d", got)
+	}
+}
+
+func TestRampRequest_RampStep(t *testing.T) {
+	kelvin := 5000
+	req := &models.RampRequest{TargetBrightness: 0.8, RampMinutes: 30, TargetKelvin: &kelvin}
+
+	if brightness, k := req.RampStep(0, 4); brightness != 0 || k != models.RampStartKelvin {
+		t.Errorf("Expected the ramp to start at 0 and %dK, got %v and %dK", models.RampStartKelvin, brightness, k)
+	}
+	if brightness, k := req.RampStep(2, 4); brightness != 0.4 || k != 3500 {
+		t.Errorf("Expected halfway to be 0.4 and 3500K, got %v and %dK", brightness, k)
+	}
+	if brightness, k := req.RampStep(4, 4); brightness != 0.8 || k != 5000 {
+		t.Errorf("Expected the ramp to end at its target, got %v and %dK", brightness, k)
+	}
+}
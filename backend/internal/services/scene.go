@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+var (
+	// ErrSceneNotFound is returned when a scene does not exist or belongs to another user or account
+	ErrSceneNotFound = apierror.New(apierror.ErrNotFound, "scene not found")
+	// ErrInvalidSceneRequest is returned when a scene creation request is invalid
+	ErrInvalidSceneRequest = errors.New("invalid scene request")
+)
+
+// SceneService saves snapshots of an account's device states and restores them
+type SceneService struct {
+	sceneRepo     repository.SceneRepositoryInterface
+	deviceService *DeviceService
+}
+
+// NewSceneService creates a new scene service
+func NewSceneService(sceneRepo repository.SceneRepositoryInterface, deviceService *DeviceService) *SceneService {
+	return &SceneService{
+		sceneRepo:     sceneRepo,
+		deviceService: deviceService,
+	}
+}
+
+// CreateScene snapshots the current state of every device in an account
+func (s *SceneService) CreateScene(ctx context.Context, userID, accountID string, req models.CreateSceneRequest) (*models.Scene, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidSceneRequest)
+	}
+	if len(name) > models.MaxSceneNameLength {
+		return nil, fmt.Errorf("%w: name must be at most %d characters", ErrInvalidSceneRequest, models.MaxSceneNameLength)
+	}
+
+	// Fetch devices from cache or provider; this also verifies ownership
+	devices, err := s.deviceService.accountDevices(ctx, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]models.SceneDeviceState, 0, len(devices))
+	for _, device := range devices {
+		states = append(states, models.NewSceneDeviceState(device))
+	}
+
+	stateJSON, err := json.Marshal(states)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode scene: %w", err)
+	}
+
+	userUUID, accountUUID, err := parseSceneOwner(userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	scene := &models.Scene{
+		UserID:    userUUID,
+		AccountID: accountUUID,
+		Name:      name,
+		States:    stateJSON,
+	}
+	if err := s.sceneRepo.Create(ctx, scene); err != nil {
+		return nil, err
+	}
+
+	return scene, nil
+}
+
+// ListScenes returns the user's scenes for an account, newest first
+func (s *SceneService) ListScenes(ctx context.Context, userID, accountID string) ([]*models.Scene, error) {
+	userUUID, accountUUID, err := parseSceneOwner(userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.sceneRepo.ListByAccount(ctx, userUUID, accountUUID)
+}
+
+// ActivateScene replays every device state stored in a scene. A nil duration uses the
+// default transition duration.
+func (s *SceneService) ActivateScene(ctx context.Context, userID, accountID string, sceneID uuid.UUID, duration *float64) (*models.StateResult, error) {
+	scene, err := s.findScene(ctx, userID, accountID, sceneID)
+	if err != nil {
+		return nil, err
+	}
+
+	var states []models.SceneDeviceState
+	if err := json.Unmarshal(scene.States, &states); err != nil {
+		return nil, fmt.Errorf("failed to decode scene: %w", err)
+	}
+
+	return s.deviceService.ApplyDeviceStates(ctx, userID, accountID, states, duration)
+}
+
+// DeleteScene removes one of the user's scenes
+func (s *SceneService) DeleteScene(ctx context.Context, userID, accountID string, sceneID uuid.UUID) error {
+	userUUID, accountUUID, err := parseSceneOwner(userID, accountID)
+	if err != nil {
+		return err
+	}
+
+	err = s.sceneRepo.Delete(ctx, sceneID, userUUID, accountUUID)
+	if errors.Is(err, repository.ErrSceneNotFound) {
+		return ErrSceneNotFound
+	}
+	return err
+}
+
+// findScene retrieves one of the user's scenes for an account
+func (s *SceneService) findScene(ctx context.Context, userID, accountID string, sceneID uuid.UUID) (*models.Scene, error) {
+	userUUID, accountUUID, err := parseSceneOwner(userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	scene, err := s.sceneRepo.FindByID(ctx, sceneID, userUUID, accountUUID)
+	if errors.Is(err, repository.ErrSceneNotFound) {
+		return nil, ErrSceneNotFound
+	}
+	return scene, err
+}
+
+// parseSceneOwner parses the IDs that scope a scene. An account ID that is not a UUID
+// cannot own any scene.
+func parseSceneOwner(userID, accountID string) (uuid.UUID, uuid.UUID, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+	accountUUID, err := uuid.Parse(accountID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, ErrSceneNotFound
+	}
+	return userUUID, accountUUID, nil
+}
+
+// ApplyDeviceStates applies a separate state to each device of an account, addressing
+// every device by ID. One write is counted against the rate limits for the whole batch,
+// and a failing device or property does not prevent the others from being applied.
+func (s *DeviceService) ApplyDeviceStates(ctx context.Context, userID, accountID string, states []models.SceneDeviceState, duration *float64) (*models.StateResult, error) {
+	// Get account and verify ownership
+	account, err := s.accountRepo.FindByIDString(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	if account.OwnerUserID.String() != userID {
+		return nil, ErrAccountNotOwned
+	}
+
+	// Check rate limit
+	if rateLimitErr := s.checkRateLimit(ctx, userID, account, rateLimitWrite); rateLimitErr != nil {
+		return nil, rateLimitErr
+	}
+
+	// Get decrypted token
+	token, err := s.accountRepo.GetDecryptedToken(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	// Create provider client
+	client, err := s.newClient(providers.Provider(account.Provider))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider client: %w", err)
+	}
+
+	result := &models.StateResult{Results: make([]models.ActionResult, 0, len(states)*3)}
+	for _, state := range states {
+		actions, err := state.StateRequest(duration).Actions()
+		if err != nil {
+			result.Results = append(result.Results, models.ActionResult{DeviceID: state.DeviceID, Error: err.Error()})
+			result.Failed++
+			continue
+		}
+
+		selector := "id:" + state.DeviceID
+		for _, action := range actions {
+			actionResult := models.ActionResult{DeviceID: state.DeviceID, Action: action.Action, Success: true}
+			if err := s.executeProviderAction(ctx, account, client, token, selector, action); err != nil {
+				s.validations.invalidateOnUnauthorized(ctx, accountID, err)
+				actionResult.Success = false
+				actionResult.Error = err.Error()
+				result.Failed++
+			} else {
+				result.Succeeded++
+			}
+			result.Results = append(result.Results, actionResult)
+		}
+	}
+
+	// Invalidate cache for this account
+	if err := s.invalidateCache(ctx, userID, accountID); err != nil {
+		// Log error but don't fail the request
+		_ = err
+	}
+
+	return result, nil
+}
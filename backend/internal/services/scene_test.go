@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// MockSceneRepository is an in-memory scene repository for testing
+type MockSceneRepository struct {
+	scenes []*models.Scene
+}
+
+func (m *MockSceneRepository) Create(_ context.Context, scene *models.Scene) error {
+	scene.ID = uuid.New()
+	scene.CreatedAt = time.Now()
+	m.scenes = append(m.scenes, scene)
+	return nil
+}
+
+func (m *MockSceneRepository) ListByAccount(_ context.Context, userID, accountID uuid.UUID) ([]*models.Scene, error) {
+	scenes := make([]*models.Scene, 0)
+	for i := len(m.scenes) - 1; i >= 0; i-- {
+		if m.scenes[i].UserID == userID && m.scenes[i].AccountID == accountID {
+			scenes = append(scenes, m.scenes[i])
+		}
+	}
+	return scenes, nil
+}
+
+func (m *MockSceneRepository) FindByID(_ context.Context, id, userID, accountID uuid.UUID) (*models.Scene, error) {
+	for _, scene := range m.scenes {
+		if scene.ID == id && scene.UserID == userID && scene.AccountID == accountID {
+			return scene, nil
+		}
+	}
+	return nil, repository.ErrSceneNotFound
+}
+
+func (m *MockSceneRepository) Delete(_ context.Context, id, userID, accountID uuid.UUID) error {
+	for i, scene := range m.scenes {
+		if scene.ID == id && scene.UserID == userID && scene.AccountID == accountID {
+			m.scenes = append(m.scenes[:i], m.scenes[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrSceneNotFound
+}
+
+// newSceneDevices returns a colored light, a white light and a light that is off
+func newSceneDevices() []*providers.Device {
+	return []*providers.Device{
+		{ID: "bulb-1", Label: "Lamp", Power: models.PowerStateOn, Brightness: 0.8, Color: &providers.DeviceColor{Hue: 240, Saturation: 1, Kelvin: 3500}},
+		{ID: "bulb-2", Label: "Desk", Power: models.PowerStateOn, Brightness: 0.5, Color: &providers.DeviceColor{Kelvin: 2700}},
+		{ID: "bulb-3", Label: "Hall", Power: models.PowerStateOff, Brightness: 0.2},
+	}
+}
+
+func TestCreateScene_SnapshotsDeviceStates(t *testing.T) {
+	deviceService, account := newTestDeviceService(t, newFakeProviderClient(newSceneDevices()...))
+	service := NewSceneService(&MockSceneRepository{}, deviceService)
+
+	scene, err := service.CreateScene(context.Background(), account.OwnerUserID.String(), account.ID.String(), models.CreateSceneRequest{Name: " Movie night "})
+	if err != nil {
+		t.Fatalf("CreateScene failed: %v", err)
+	}
+
+	if scene.Name != "Movie night" {
+		t.Errorf("Expected trimmed name, got %q", scene.Name)
+	}
+
+	var states []models.SceneDeviceState
+	if err := json.Unmarshal(scene.States, &states); err != nil {
+		t.Fatalf("Failed to decode scene states: %v", err)
+	}
+	if len(states) != 3 {
+		t.Fatalf("Expected 3 device states, got %d", len(states))
+	}
+	if states[0].Color == nil || states[0].Color.Hue != 240 {
+		t.Errorf("Expected colored state for bulb-1, got %+v", states[0])
+	}
+	if states[1].Color != nil || states[1].Kelvin != 2700 {
+		t.Errorf("Expected white state at 2700K for bulb-2, got %+v", states[1])
+	}
+	if states[2].Power != models.PowerStateOff {
+		t.Errorf("Expected bulb-3 to be off, got %q", states[2].Power)
+	}
+}
+
+func TestCreateScene_RequiresName(t *testing.T) {
+	deviceService, account := newTestDeviceService(t, newFakeProviderClient(newSceneDevices()...))
+	service := NewSceneService(&MockSceneRepository{}, deviceService)
+
+	_, err := service.CreateScene(context.Background(), account.OwnerUserID.String(), account.ID.String(), models.CreateSceneRequest{Name: "  "})
+	if !errors.Is(err, ErrInvalidSceneRequest) {
+		t.Errorf("Expected ErrInvalidSceneRequest, got %v", err)
+	}
+}
+
+func TestActivateScene_ReplaysEachDeviceState(t *testing.T) {
+	client := newFakeProviderClient(newSceneDevices()...)
+	deviceService, account := newTestDeviceService(t, client)
+	service := NewSceneService(&MockSceneRepository{}, deviceService)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	scene, err := service.CreateScene(context.Background(), userID, accountID, models.CreateSceneRequest{Name: "Evening"})
+	if err != nil {
+		t.Fatalf("CreateScene failed: %v", err)
+	}
+
+	duration := 1.5
+	result, err := service.ActivateScene(context.Background(), userID, accountID, scene.ID, &duration)
+	if err != nil {
+		t.Fatalf("ActivateScene failed: %v", err)
+	}
+
+	if result.Failed != 0 || result.Succeeded != 8 {
+		t.Errorf("Expected 8 successes, got %d and %d failures", result.Succeeded, result.Failed)
+	}
+	if client.callCount("SetColor") != 1 || client.callCount("SetColorTemperature") != 1 {
+		t.Errorf("Expected one color and one temperature call, got %d and %d",
+			client.callCount("SetColor"), client.callCount("SetColorTemperature"))
+	}
+	for _, selector := range client.selectors {
+		if selector != "id:bulb-1" && selector != "id:bulb-2" && selector != "id:bulb-3" {
+			t.Errorf("Expected device ID selector, got %q", selector)
+		}
+	}
+}
+
+func TestActivateScene_OtherUser(t *testing.T) {
+	deviceService, account := newTestDeviceService(t, newFakeProviderClient(newSceneDevices()...))
+	service := NewSceneService(&MockSceneRepository{}, deviceService)
+
+	scene, err := service.CreateScene(context.Background(), account.OwnerUserID.String(), account.ID.String(), models.CreateSceneRequest{Name: "Evening"})
+	if err != nil {
+		t.Fatalf("CreateScene failed: %v", err)
+	}
+
+	_, err = service.ActivateScene(context.Background(), uuid.NewString(), account.ID.String(), scene.ID, nil)
+	if !errors.Is(err, ErrSceneNotFound) {
+		t.Errorf("Expected ErrSceneNotFound, got %v", err)
+	}
+}
+
+func TestDeleteScene(t *testing.T) {
+	deviceService, account := newTestDeviceService(t, newFakeProviderClient(newSceneDevices()...))
+	service := NewSceneService(&MockSceneRepository{}, deviceService)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	scene, err := service.CreateScene(context.Background(), userID, accountID, models.CreateSceneRequest{Name: "Evening"})
+	if err != nil {
+		t.Fatalf("CreateScene failed: %v", err)
+	}
+
+	if err := service.DeleteScene(context.Background(), userID, accountID, scene.ID); err != nil {
+		t.Fatalf("DeleteScene failed: %v", err)
+	}
+
+	scenes, err := service.ListScenes(context.Background(), userID, accountID)
+	if err != nil {
+		t.Fatalf("ListScenes failed: %v", err)
+	}
+	if len(scenes) != 0 {
+		t.Errorf("Expected no scenes, got %d", len(scenes))
+	}
+
+	if err := service.DeleteScene(context.Background(), userID, accountID, scene.ID); !errors.Is(err, ErrSceneNotFound) {
+		t.Errorf("Expected ErrSceneNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/apierror"
+)
+
+// ErrScheduleNotFound is returned when a scheduled action does not exist or belongs to another user
+var ErrScheduleNotFound = apierror.New(apierror.ErrNotFound, "schedule not found")
+
+// ScheduleService manages device actions executed on a cron schedule
+type ScheduleService struct {
+	scheduleRepo  repository.ScheduleRepositoryInterface
+	deviceService *DeviceService
+	now           func() time.Time
+}
+
+// NewScheduleService creates a new schedule service
+func NewScheduleService(scheduleRepo repository.ScheduleRepositoryInterface, deviceService *DeviceService) *ScheduleService {
+	return &ScheduleService{
+		scheduleRepo:  scheduleRepo,
+		deviceService: deviceService,
+		now:           time.Now,
+	}
+}
+
+// Create schedules a device action on one of the user's accounts. The action is validated
+// as it would be when executed, filling in omitted parameters from the user's preferences,
+// but is stored as sent so later preference changes still apply.
+func (s *ScheduleService) Create(ctx context.Context, userID uuid.UUID, req models.CreateScheduleRequest) (*models.ScheduledAction, error) {
+	schedule, err := parseCron(req.Cron)
+	if err != nil {
+		return nil, err
+	}
+
+	action := models.ActionRequest{Action: req.Action, Parameters: req.Parameters}
+	validated := models.ActionRequest{Action: req.Action, Parameters: maps.Clone(req.Parameters)}
+	s.deviceService.backfillPreferences(ctx, userID.String(), &validated)
+	if err := validated.ValidateParameters(); err != nil {
+		return nil, &apierror.BadRequestError{Message: err.Error(), Cause: err}
+	}
+
+	// Verify ownership
+	account, err := s.deviceService.accountRepo.FindByIDString(ctx, req.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.OwnerUserID != userID {
+		return nil, ErrAccountNotOwned
+	}
+
+	actionJSON, err := json.Marshal(action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode action: %w", err)
+	}
+
+	scheduled := &models.ScheduledAction{
+		UserID:         userID,
+		AccountID:      account.ID,
+		Selector:       req.Selector,
+		Action:         actionJSON,
+		CronExpression: req.Cron,
+		Enabled:        true,
+		NextRunAt:      schedule.Next(s.now()).UTC(),
+	}
+	if err := s.scheduleRepo.Create(ctx, scheduled); err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+// List returns the user's scheduled actions, newest first
+func (s *ScheduleService) List(ctx context.Context, userID uuid.UUID) ([]*models.ScheduledAction, error) {
+	return s.scheduleRepo.ListByUser(ctx, userID)
+}
+
+// Delete removes one of the user's scheduled actions
+func (s *ScheduleService) Delete(ctx context.Context, userID, scheduleID uuid.UUID) error {
+	err := s.scheduleRepo.Delete(ctx, scheduleID, userID)
+	if errors.Is(err, repository.ErrScheduleNotFound) {
+		return ErrScheduleNotFound
+	}
+	return err
+}
+
+// parseCron parses a standard 5-field cron expression
+func parseCron(expression string) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(expression)
+	if err != nil {
+		return nil, &apierror.BadRequestError{Message: "invalid cron expression: " + err.Error(), Cause: err}
+	}
+	return schedule, nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+const (
+	// scheduleTolerance is how early a scheduled action may run, so a runner that wakes up
+	// slightly before the next run because of clock drift does not sleep through it
+	scheduleTolerance = 5 * time.Second
+	// maxScheduleSleep bounds how long the runner sleeps, so schedules created while it
+	// sleeps are picked up within a minute
+	maxScheduleSleep = time.Minute
+)
+
+// ScheduleRunner executes scheduled actions when they are due. It runs every due action,
+// then sleeps until the next one is due.
+type ScheduleRunner struct {
+	scheduleRepo  repository.ScheduleRepositoryInterface
+	deviceService *DeviceService
+	now           func() time.Time
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+}
+
+// NewScheduleRunner creates a runner executing scheduled actions through deviceService
+func NewScheduleRunner(scheduleRepo repository.ScheduleRepositoryInterface, deviceService *DeviceService) *ScheduleRunner {
+	return &ScheduleRunner{
+		scheduleRepo:  scheduleRepo,
+		deviceService: deviceService,
+		now:           time.Now,
+	}
+}
+
+// Start runs due actions, starting with the ones missed while the server was down, until
+// ctx is cancelled or Stop is called
+func (r *ScheduleRunner) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		for {
+			r.RunDue(ctx)
+
+			timer := time.NewTimer(r.sleepDuration(ctx))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels the runner and waits for actions in progress to finish
+func (r *ScheduleRunner) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+// RunDue executes every enabled action due within the tolerance window that this runner
+// claims, scheduling its next run first. An action claimed by another instance is left
+// to it, and a failing action is logged and stays rescheduled. Returns how many ran.
+func (r *ScheduleRunner) RunDue(ctx context.Context) int {
+	now := r.now()
+	due, err := r.scheduleRepo.ListDue(ctx, now.Add(scheduleTolerance))
+	if err != nil {
+		logger.Error("Failed to list due scheduled actions", "error", err)
+		return 0
+	}
+
+	ran := 0
+	for _, scheduled := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		if r.run(ctx, scheduled, now) {
+			ran++
+		}
+	}
+	return ran
+}
+
+// run claims a scheduled action by recording its next run, or removing it if it only runs
+// once, then executes it. Returns whether it was claimed.
+func (r *ScheduleRunner) run(ctx context.Context, scheduled *models.ScheduledAction, now time.Time) bool {
+	log := logger.WithContext(ctx).With("schedule_id", scheduled.ID, "account_id", scheduled.AccountID)
+
+	claimed, err := r.claim(ctx, scheduled, now)
+	if err != nil {
+		log.Error("Failed to claim scheduled action", "error", err)
+		return false
+	}
+	if !claimed {
+		return false // Another instance runs it
+	}
+
+	if err := r.execute(ctx, scheduled); err != nil {
+		log.Warn("Scheduled action failed", "selector", scheduled.Selector, "error", err)
+	}
+	return true
+}
+
+// claim reschedules a recurring action to its next run after now, or removes a one-shot
+// action, and reports whether this runner claimed it
+func (r *ScheduleRunner) claim(ctx context.Context, scheduled *models.ScheduledAction, now time.Time) (bool, error) {
+	if scheduled.RunOnce {
+		return r.scheduleRepo.ClaimOnce(ctx, scheduled.ID, scheduled.NextRunAt)
+	}
+
+	schedule, err := parseCron(scheduled.CronExpression)
+	if err != nil {
+		return false, fmt.Errorf("invalid stored cron expression %q: %w", scheduled.CronExpression, err)
+	}
+
+	// Runs missed while the server was down are skipped rather than replayed
+	next := schedule.Next(scheduled.NextRunAt)
+	if !next.After(now) {
+		next = schedule.Next(now)
+	}
+	return r.scheduleRepo.ClaimRun(ctx, scheduled.ID, scheduled.NextRunAt, now, next.UTC())
+}
+
+// execute sends a scheduled action to the provider on behalf of its owner
+func (r *ScheduleRunner) execute(ctx context.Context, scheduled *models.ScheduledAction) error {
+	var action models.ActionRequest
+	if err := json.Unmarshal(scheduled.Action, &action); err != nil {
+		return fmt.Errorf("failed to decode action: %w", err)
+	}
+
+	return r.deviceService.ExecuteAction(ctx, scheduled.UserID.String(), scheduled.AccountID.String(), scheduled.Selector, &action)
+}
+
+// sleepDuration returns how long to wait until the next action is due, at most maxScheduleSleep
+func (r *ScheduleRunner) sleepDuration(ctx context.Context) time.Duration {
+	next, err := r.scheduleRepo.NextRunAt(ctx)
+	if err != nil {
+		logger.Error("Failed to get next scheduled run", "error", err)
+		return maxScheduleSleep
+	}
+	if next == nil {
+		return maxScheduleSleep
+	}
+
+	// Actions due within the tolerance window already ran; one still due failed to be
+	// rescheduled and is retried after the window rather than in a busy loop
+	wait := next.Sub(r.now())
+	switch {
+	case wait < scheduleTolerance:
+		return scheduleTolerance
+	case wait > maxScheduleSleep:
+		return maxScheduleSleep
+	default:
+		return wait
+	}
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/apierror"
+)
+
+// MockScheduleRepository is an in-memory scheduled action repository for testing
+type MockScheduleRepository struct {
+	schedules []*models.ScheduledAction
+	mu        sync.Mutex
+}
+
+func (m *MockScheduleRepository) Create(_ context.Context, schedule *models.ScheduledAction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedule.ID = uuid.New()
+	schedule.CreatedAt = time.Now()
+	m.schedules = append(m.schedules, schedule)
+	return nil
+}
+
+func (m *MockScheduleRepository) ListByUser(_ context.Context, userID uuid.UUID) ([]*models.ScheduledAction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedules := make([]*models.ScheduledAction, 0)
+	for i := len(m.schedules) - 1; i >= 0; i-- {
+		if m.schedules[i].UserID == userID {
+			schedules = append(schedules, m.schedules[i])
+		}
+	}
+	return schedules, nil
+}
+
+func (m *MockScheduleRepository) Delete(_ context.Context, id, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, schedule := range m.schedules {
+		if schedule.ID == id && schedule.UserID == userID {
+			m.schedules = append(m.schedules[:i], m.schedules[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrScheduleNotFound
+}
+
+func (m *MockScheduleRepository) ListDue(_ context.Context, dueBy time.Time) ([]*models.ScheduledAction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	due := make([]*models.ScheduledAction, 0)
+	for _, schedule := range m.schedules {
+		if schedule.Enabled && !schedule.NextRunAt.After(dueBy) {
+			copied := *schedule
+			due = append(due, &copied)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextRunAt.Before(due[j].NextRunAt) })
+	return due, nil
+}
+
+func (m *MockScheduleRepository) NextRunAt(_ context.Context) (*time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var next *time.Time
+	for _, schedule := range m.schedules {
+		if schedule.Enabled && (next == nil || schedule.NextRunAt.Before(*next)) {
+			nextRunAt := schedule.NextRunAt
+			next = &nextRunAt
+		}
+	}
+	return next, nil
+}
+
+func (m *MockScheduleRepository) ClaimRun(_ context.Context, id uuid.UUID, dueAt, lastRunAt, nextRunAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, schedule := range m.schedules {
+		if schedule.ID == id && schedule.Enabled && schedule.NextRunAt.Equal(dueAt) {
+			schedule.LastRunAt = &lastRunAt
+			schedule.NextRunAt = nextRunAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MockScheduleRepository) ClaimOnce(_ context.Context, id uuid.UUID, dueAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, schedule := range m.schedules {
+		if schedule.ID == id && schedule.Enabled && schedule.RunOnce && schedule.NextRunAt.Equal(dueAt) {
+			m.schedules = append(m.schedules[:i], m.schedules[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fixedClock returns a clock stopped at t
+func fixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+// newLightsOutRequest schedules the account's lights to turn off at 11pm
+func newLightsOutRequest(account *models.Account) models.CreateScheduleRequest {
+	return models.CreateScheduleRequest{
+		AccountID:  account.ID.String(),
+		Selector:   "all",
+		Action:     models.ActionPower,
+		Parameters: map[string]interface{}{"state": models.PowerStateOff},
+		Cron:       "0 23 * * *",
+	}
+}
+
+func TestScheduleService_Create_ComputesNextRun(t *testing.T) {
+	deviceService, account := newTestDeviceService(t, newFakeProviderClient())
+	service := NewScheduleService(&MockScheduleRepository{}, deviceService)
+	service.now = fixedClock(time.Date(2025, 6, 1, 22, 30, 0, 0, time.UTC))
+
+	schedule, err := service.Create(context.Background(), account.OwnerUserID, newLightsOutRequest(account))
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if want := time.Date(2025, 6, 1, 23, 0, 0, 0, time.UTC); !schedule.NextRunAt.Equal(want) {
+		t.Errorf("Expected next run at %v, got %v", want, schedule.NextRunAt)
+	}
+	if !schedule.Enabled || schedule.LastRunAt != nil {
+		t.Errorf("Expected an enabled schedule that never ran, got %+v", schedule)
+	}
+}
+
+func TestScheduleService_Create_InvalidCron(t *testing.T) {
+	deviceService, account := newTestDeviceService(t, newFakeProviderClient())
+	service := NewScheduleService(&MockScheduleRepository{}, deviceService)
+
+	req := newLightsOutRequest(account)
+	req.Cron = "0 25 * * *"
+
+	_, err := service.Create(context.Background(), account.OwnerUserID, req)
+	var badRequestErr *apierror.BadRequestError
+	if !errors.As(err, &badRequestErr) {
+		t.Errorf("Expected BadRequestError, got %v", err)
+	}
+}
+
+func TestScheduleService_Create_InvalidAction(t *testing.T) {
+	deviceService, account := newTestDeviceService(t, newFakeProviderClient())
+	service := NewScheduleService(&MockScheduleRepository{}, deviceService)
+
+	req := newLightsOutRequest(account)
+	req.Parameters = map[string]interface{}{"state": "dim"}
+
+	_, err := service.Create(context.Background(), account.OwnerUserID, req)
+	var badRequestErr *apierror.BadRequestError
+	if !errors.As(err, &badRequestErr) {
+		t.Errorf("Expected BadRequestError, got %v", err)
+	}
+}
+
+func TestScheduleService_Create_OtherUsersAccount(t *testing.T) {
+	deviceService, account := newTestDeviceService(t, newFakeProviderClient())
+	service := NewScheduleService(&MockScheduleRepository{}, deviceService)
+
+	_, err := service.Create(context.Background(), uuid.New(), newLightsOutRequest(account))
+	if !errors.Is(err, ErrAccountNotOwned) {
+		t.Errorf("Expected ErrAccountNotOwned, got %v", err)
+	}
+}
+
+func TestScheduleService_Delete_OtherUser(t *testing.T) {
+	deviceService, account := newTestDeviceService(t, newFakeProviderClient())
+	service := NewScheduleService(&MockScheduleRepository{}, deviceService)
+
+	schedule, err := service.Create(context.Background(), account.OwnerUserID, newLightsOutRequest(account))
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if err := service.Delete(context.Background(), uuid.New(), schedule.ID); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("Expected ErrScheduleNotFound, got %v", err)
+	}
+	if err := service.Delete(context.Background(), account.OwnerUserID, schedule.ID); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+}
+
+func TestScheduleRunner_RunDue(t *testing.T) {
+	created := time.Date(2025, 6, 1, 22, 30, 0, 0, time.UTC)
+	tests := []struct {
+		now      time.Time
+		name     string
+		wantNext time.Time
+		wantRuns int
+	}{
+		{
+			name:     "not yet due",
+			now:      time.Date(2025, 6, 1, 22, 59, 50, 0, time.UTC),
+			wantRuns: 0,
+			wantNext: time.Date(2025, 6, 1, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "early within clock drift tolerance",
+			now:      time.Date(2025, 6, 1, 22, 59, 57, 0, time.UTC),
+			wantRuns: 1,
+			wantNext: time.Date(2025, 6, 2, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "on time",
+			now:      time.Date(2025, 6, 1, 23, 0, 1, 0, time.UTC),
+			wantRuns: 1,
+			wantNext: time.Date(2025, 6, 2, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "missed runs are skipped",
+			now:      time.Date(2025, 6, 4, 12, 0, 0, 0, time.UTC),
+			wantRuns: 1,
+			wantNext: time.Date(2025, 6, 4, 23, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeProviderClient()
+			deviceService, account := newTestDeviceService(t, client)
+			repo := &MockScheduleRepository{}
+
+			service := NewScheduleService(repo, deviceService)
+			service.now = fixedClock(created)
+			schedule, err := service.Create(context.Background(), account.OwnerUserID, newLightsOutRequest(account))
+			if err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+
+			runner := NewScheduleRunner(repo, deviceService)
+			runner.now = fixedClock(tt.now)
+
+			if runs := runner.RunDue(context.Background()); runs != tt.wantRuns {
+				t.Errorf("Expected %d runs, got %d", tt.wantRuns, runs)
+			}
+			if calls := client.callCount("SetPower"); calls != tt.wantRuns {
+				t.Errorf("Expected %d SetPower calls, got %d", tt.wantRuns, calls)
+			}
+			if !schedule.NextRunAt.Equal(tt.wantNext) {
+				t.Errorf("Expected next run at %v, got %v", tt.wantNext, schedule.NextRunAt)
+			}
+			if tt.wantRuns > 0 && (schedule.LastRunAt == nil || !schedule.LastRunAt.Equal(tt.now)) {
+				t.Errorf("Expected last run at %v, got %v", tt.now, schedule.LastRunAt)
+			}
+		})
+	}
+}
+
+func TestScheduleRunner_FailedActionIsRescheduled(t *testing.T) {
+	client := newFakeProviderClient()
+	client.errs["SetPower"] = []error{errors.New("bulb unreachable")}
+	deviceService, account := newTestDeviceService(t, client)
+	repo := &MockScheduleRepository{}
+
+	service := NewScheduleService(repo, deviceService)
+	service.now = fixedClock(time.Date(2025, 6, 1, 22, 30, 0, 0, time.UTC))
+	schedule, err := service.Create(context.Background(), account.OwnerUserID, newLightsOutRequest(account))
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	runner := NewScheduleRunner(repo, deviceService)
+	runner.now = fixedClock(time.Date(2025, 6, 1, 23, 0, 0, 0, time.UTC))
+	runner.RunDue(context.Background())
+
+	if want := time.Date(2025, 6, 2, 23, 0, 0, 0, time.UTC); !schedule.NextRunAt.Equal(want) {
+		t.Errorf("Expected next run at %v, got %v", want, schedule.NextRunAt)
+	}
+}
+
+// staleScheduleRepository lists the due actions another instance listed before any was
+// claimed
+type staleScheduleRepository struct {
+	*MockScheduleRepository
+	due []*models.ScheduledAction
+}
+
+func (r *staleScheduleRepository) ListDue(context.Context, time.Time) ([]*models.ScheduledAction, error) {
+	return r.due, nil
+}
+
+func TestScheduleRunner_RunsOnceAcrossInstances(t *testing.T) {
+	client := newFakeProviderClient()
+	deviceService, account := newTestDeviceService(t, client)
+	repo := &MockScheduleRepository{}
+
+	service := NewScheduleService(repo, deviceService)
+	service.now = fixedClock(time.Date(2025, 6, 1, 22, 30, 0, 0, time.UTC))
+	if _, err := service.Create(context.Background(), account.OwnerUserID, newLightsOutRequest(account)); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	now := time.Date(2025, 6, 1, 23, 0, 0, 0, time.UTC)
+	due, _ := repo.ListDue(context.Background(), now)
+
+	first := NewScheduleRunner(repo, deviceService)
+	first.now = fixedClock(now)
+	second := NewScheduleRunner(&staleScheduleRepository{MockScheduleRepository: repo, due: due}, deviceService)
+	second.now = fixedClock(now)
+
+	if runs := first.RunDue(context.Background()); runs != 1 {
+		t.Errorf("Expected the first instance to run the action, got %d runs", runs)
+	}
+	if runs := second.RunDue(context.Background()); runs != 0 {
+		t.Errorf("Expected the second instance to leave the claimed action, got %d runs", runs)
+	}
+	if calls := client.callCount("SetPower"); calls != 1 {
+		t.Errorf("Expected one SetPower call, got %d", calls)
+	}
+}
+
+func TestScheduleRunner_SleepDuration(t *testing.T) {
+	deviceService, account := newTestDeviceService(t, newFakeProviderClient())
+	repo := &MockScheduleRepository{}
+	runner := NewScheduleRunner(repo, deviceService)
+	now := time.Date(2025, 6, 1, 22, 59, 30, 0, time.UTC)
+	runner.now = fixedClock(now)
+
+	if wait := runner.sleepDuration(context.Background()); wait != maxScheduleSleep {
+		t.Errorf("Expected %v without schedules, got %v", maxScheduleSleep, wait)
+	}
+
+	service := NewScheduleService(repo, deviceService)
+	service.now = fixedClock(now)
+	if _, err := service.Create(context.Background(), account.OwnerUserID, newLightsOutRequest(account)); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if wait := runner.sleepDuration(context.Background()); wait != 30*time.Second {
+		t.Errorf("Expected to sleep until the next run, got %v", wait)
+	}
+}
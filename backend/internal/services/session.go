@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/apierror"
+)
+
+// ErrSessionNotFound is returned when a session is unknown, already revoked or owned by another user
+var ErrSessionNotFound = apierror.New(apierror.ErrNotFound, "session not found")
+
+// ListSessions returns the user's active sessions, newest first. The session matching
+// currentSessionID, the session of the calling access token, is marked as current.
+func (s *AuthService) ListSessions(ctx context.Context, userID, currentSessionID uuid.UUID) ([]*models.Session, error) {
+	tokens, err := s.refreshTokenRepo.FindActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	sessions := make([]*models.Session, 0, len(tokens))
+	for _, token := range tokens {
+		sessions = append(sessions, &models.Session{
+			ID:        token.SessionID,
+			UserAgent: token.UserAgent,
+			IPAddress: token.IPAddress,
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+			IsCurrent: currentSessionID != uuid.Nil && token.SessionID == currentSessionID,
+		})
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession logs out one of the user's sessions by revoking its refresh token.
+// Access tokens already issued to the session stay valid until they expire.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID, userAgent, ipAddress *string) error {
+	if err := s.refreshTokenRepo.RevokeByID(ctx, sessionID, userID); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return ErrSessionNotFound
+		}
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	s.recordAudit(ctx, models.EventSessionRevoke, userID, userAgent, ipAddress, map[string]interface{}{"session_id": sessionID})
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestListSessions_MarksCurrentSession(t *testing.T) {
+	service := newTestAuthService(t, withSession())
+	ctx := context.Background()
+	user := service.user
+
+	other, err := service.createSession(ctx, user, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	// Rotation keeps the session ID, which the new access token carries
+	rotated, err := service.RefreshToken(ctx, service.refreshToken, nil, nil)
+	if err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+	claims, err := service.jwtService.ValidateAccessToken(rotated.AccessToken)
+	if err != nil {
+		t.Fatalf("Failed to validate access token: %v", err)
+	}
+
+	sessions, err := service.ListSessions(ctx, user.ID, claims.SessionID)
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+	}
+
+	otherClaims, err := service.jwtService.ValidateAccessToken(other.AccessToken)
+	if err != nil {
+		t.Fatalf("Failed to validate access token: %v", err)
+	}
+	for _, session := range sessions {
+		switch session.ID {
+		case claims.SessionID:
+			if !session.IsCurrent {
+				t.Error("Expected the calling session to be current")
+			}
+		case otherClaims.SessionID:
+			if session.IsCurrent {
+				t.Error("Expected the other session not to be current")
+			}
+		default:
+			t.Errorf("Unexpected session %s", session.ID)
+		}
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	service := newTestAuthService(t, withSession())
+	ctx := context.Background()
+	user := service.user
+
+	sessions, err := service.ListSessions(ctx, user.ID, uuid.Nil)
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("Expected 1 session, got %d (%v)", len(sessions), err)
+	}
+	sessionID := sessions[0].ID
+
+	if err := service.RevokeSession(ctx, uuid.New(), sessionID, nil, nil); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected another user's revocation to fail with ErrSessionNotFound, got %v", err)
+	}
+	if active := service.tokens.activeTokens(); active != 1 {
+		t.Fatalf("Expected the session to survive a foreign revocation, got %d active tokens", active)
+	}
+
+	if err := service.RevokeSession(ctx, user.ID, sessionID, nil, nil); err != nil {
+		t.Fatalf("RevokeSession failed: %v", err)
+	}
+	if active := service.tokens.activeTokens(); active != 0 {
+		t.Errorf("Expected no active tokens, got %d", active)
+	}
+	if err := service.RevokeSession(ctx, user.ID, sessionID, nil, nil); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound for a revoked session, got %v", err)
+	}
+	if _, err := service.RefreshToken(ctx, service.refreshToken, nil, nil); err == nil {
+		t.Error("Expected the revoked session's refresh token to be rejected")
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/apierror"
+)
+
+// ErrNoDevicesMatchSelector is returned when snapshotting a selector that targets no device
+var ErrNoDevicesMatchSelector = apierror.New(apierror.ErrNotFound, "no devices match the selector")
+
+// StateSnapshotService saves the state of the devices a selector targets, e.g. before a
+// destructive action, and restores it
+type StateSnapshotService struct {
+	snapshotRepo  repository.StateSnapshotRepositoryInterface
+	deviceService *DeviceService
+}
+
+// NewStateSnapshotService creates a new state snapshot service
+func NewStateSnapshotService(snapshotRepo repository.StateSnapshotRepositoryInterface, deviceService *DeviceService) *StateSnapshotService {
+	return &StateSnapshotService{
+		snapshotRepo:  snapshotRepo,
+		deviceService: deviceService,
+	}
+}
+
+// TakeSnapshot saves the current state of the devices of an account that selector targets
+func (s *StateSnapshotService) TakeSnapshot(ctx context.Context, userID, accountID, selector, name string) (*models.StateSnapshot, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, &apierror.BadRequestError{Message: "name is required"}
+	}
+	if len(name) > models.MaxSceneNameLength {
+		return nil, &apierror.BadRequestError{Message: fmt.Sprintf("name must be at most %d characters", models.MaxSceneNameLength)}
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	// Fetch the devices from the provider; this also verifies ownership
+	account, devices, err := s.deviceService.liveSelectedDevices(ctx, userID, accountID, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]models.SceneDeviceState, 0, len(devices))
+	for _, device := range devices {
+		states = append(states, models.NewSceneDeviceState(device))
+	}
+
+	stateJSON, err := json.Marshal(states)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode state snapshot: %w", err)
+	}
+
+	snapshot := &models.StateSnapshot{
+		UserID:    userUUID,
+		AccountID: account.ID,
+		Selector:  selector,
+		Name:      name,
+		States:    stateJSON,
+	}
+	if err := s.snapshotRepo.Create(ctx, snapshot); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// ListSnapshots returns the user's state snapshots across all accounts, newest first
+func (s *StateSnapshotService) ListSnapshots(ctx context.Context, userID string) ([]*models.StateSnapshot, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	return s.snapshotRepo.ListByUser(ctx, userUUID)
+}
+
+// RestoreSnapshot replays every device state stored in a snapshot. A nil duration uses
+// the default transition duration.
+func (s *StateSnapshotService) RestoreSnapshot(ctx context.Context, userID string, snapshotID uuid.UUID, duration *float64) (*models.StateResult, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	snapshot, err := s.snapshotRepo.FindByID(ctx, snapshotID, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	var states []models.SceneDeviceState
+	if err := json.Unmarshal(snapshot.States, &states); err != nil {
+		return nil, fmt.Errorf("failed to decode state snapshot: %w", err)
+	}
+
+	return s.deviceService.ApplyDeviceStates(ctx, userID, snapshot.AccountID.String(), states, duration)
+}
+
+// DeleteSnapshot removes one of the user's state snapshots
+func (s *StateSnapshotService) DeleteSnapshot(ctx context.Context, userID string, snapshotID uuid.UUID) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	return s.snapshotRepo.Delete(ctx, snapshotID, userUUID)
+}
+
+// liveSelectedDevices fetches the current state of the devices of an account that selector
+// targets, bypassing the cache. A single device is fetched with GetDevice; other selectors
+// list the account's devices and keep the ones they target.
+func (s *DeviceService) liveSelectedDevices(ctx context.Context, userID, accountID, selector string) (*models.Account, []*models.Device, error) {
+	// Get account and verify ownership
+	account, err := s.accountRepo.FindByIDString(ctx, accountID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("account not found: %w", err)
+	}
+
+	if account.OwnerUserID.String() != userID {
+		return nil, nil, ErrAccountNotOwned
+	}
+
+	if deviceID, ok := strings.CutPrefix(selector, "id:"); ok && !strings.Contains(deviceID, ",") {
+		device, err := s.GetDevice(ctx, userID, accountID, deviceID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return account, []*models.Device{device}, nil
+	}
+
+	devices, err := s.fetchAndCacheDevices(ctx, userID, account)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	selected := selectDevices(devices, selector)
+	if len(selected) == 0 {
+		return nil, nil, ErrNoDevicesMatchSelector
+	}
+	return account, selected, nil
+}
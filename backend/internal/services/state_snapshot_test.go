@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// MockStateSnapshotRepository is an in-memory state snapshot repository for testing
+type MockStateSnapshotRepository struct {
+	snapshots []*models.StateSnapshot
+}
+
+func (m *MockStateSnapshotRepository) Create(_ context.Context, snapshot *models.StateSnapshot) error {
+	snapshot.ID = uuid.New()
+	snapshot.CreatedAt = time.Now()
+	m.snapshots = append(m.snapshots, snapshot)
+	return nil
+}
+
+func (m *MockStateSnapshotRepository) ListByUser(_ context.Context, userID uuid.UUID) ([]*models.StateSnapshot, error) {
+	snapshots := make([]*models.StateSnapshot, 0)
+	for i := len(m.snapshots) - 1; i >= 0; i-- {
+		if m.snapshots[i].UserID == userID {
+			snapshots = append(snapshots, m.snapshots[i])
+		}
+	}
+	return snapshots, nil
+}
+
+func (m *MockStateSnapshotRepository) FindByID(_ context.Context, id, userID uuid.UUID) (*models.StateSnapshot, error) {
+	for _, snapshot := range m.snapshots {
+		if snapshot.ID == id && snapshot.UserID == userID {
+			return snapshot, nil
+		}
+	}
+	return nil, repository.ErrStateSnapshotNotFound
+}
+
+func (m *MockStateSnapshotRepository) Delete(_ context.Context, id, userID uuid.UUID) error {
+	for i, snapshot := range m.snapshots {
+		if snapshot.ID == id && snapshot.UserID == userID {
+			m.snapshots = append(m.snapshots[:i], m.snapshots[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrStateSnapshotNotFound
+}
+
+// newSnapshotDevices returns the scene devices, the first two in the living room group
+func newSnapshotDevices() []*providers.Device {
+	devices := newSceneDevices()
+	livingRoom := &providers.DeviceGroup{ID: "living-room", Name: "Living Room"}
+	devices[0].Group = livingRoom
+	devices[1].Group = livingRoom
+	return devices
+}
+
+func TestTakeSnapshot_GroupSelector(t *testing.T) {
+	client := newFakeProviderClient(newSnapshotDevices()...)
+	deviceService, account := newTestDeviceService(t, client)
+	service := NewStateSnapshotService(&MockStateSnapshotRepository{}, deviceService)
+
+	snapshot, err := service.TakeSnapshot(context.Background(), account.OwnerUserID.String(), account.ID.String(), "group_id:living-room", "Before demo")
+	if err != nil {
+		t.Fatalf("TakeSnapshot failed: %v", err)
+	}
+
+	if snapshot.Selector != "group_id:living-room" || snapshot.AccountID != account.ID {
+		t.Errorf("Expected the snapshot to record the selector and account, got %+v", snapshot)
+	}
+	var states []models.SceneDeviceState
+	if err := json.Unmarshal(snapshot.States, &states); err != nil {
+		t.Fatalf("Failed to decode snapshot states: %v", err)
+	}
+	if len(states) != 2 || states[0].DeviceID != "bulb-1" || states[1].DeviceID != "bulb-2" {
+		t.Errorf("Expected the states of the 2 living room devices, got %+v", states)
+	}
+	if client.callCount("ListDevices") != 1 {
+		t.Errorf("Expected the devices to be listed from the provider, got %d calls", client.callCount("ListDevices"))
+	}
+}
+
+func TestTakeSnapshot_DeviceSelector(t *testing.T) {
+	client := newFakeProviderClient(newSnapshotDevices()...)
+	deviceService, account := newTestDeviceService(t, client)
+	service := NewStateSnapshotService(&MockStateSnapshotRepository{}, deviceService)
+
+	snapshot, err := service.TakeSnapshot(context.Background(), account.OwnerUserID.String(), account.ID.String(), "id:bulb-3", "Hall")
+	if err != nil {
+		t.Fatalf("TakeSnapshot failed: %v", err)
+	}
+
+	var states []models.SceneDeviceState
+	if err := json.Unmarshal(snapshot.States, &states); err != nil {
+		t.Fatalf("Failed to decode snapshot states: %v", err)
+	}
+	if len(states) != 1 || states[0].Power != models.PowerStateOff {
+		t.Errorf("Expected the state of bulb-3, got %+v", states)
+	}
+	if client.callCount("GetDevice") != 1 || client.callCount("ListDevices") != 0 {
+		t.Errorf("Expected a single GetDevice call, got %d GetDevice and %d ListDevices calls",
+			client.callCount("GetDevice"), client.callCount("ListDevices"))
+	}
+}
+
+func TestTakeSnapshot_Errors(t *testing.T) {
+	deviceService, account := newTestDeviceService(t, newFakeProviderClient(newSnapshotDevices()...))
+	service := NewStateSnapshotService(&MockStateSnapshotRepository{}, deviceService)
+	userID, accountID := account.OwnerUserID.String(), account.ID.String()
+
+	var badRequest *apierror.BadRequestError
+	if _, err := service.TakeSnapshot(context.Background(), userID, accountID, "all", "  "); !errors.As(err, &badRequest) {
+		t.Errorf("Expected a bad request without a name, got %v", err)
+	}
+	if _, err := service.TakeSnapshot(context.Background(), userID, accountID, "group_id:attic", "Attic"); !errors.Is(err, ErrNoDevicesMatchSelector) {
+		t.Errorf("Expected ErrNoDevicesMatchSelector, got %v", err)
+	}
+	if _, err := service.TakeSnapshot(context.Background(), uuid.NewString(), accountID, "all", "All"); !errors.Is(err, ErrAccountNotOwned) {
+		t.Errorf("Expected ErrAccountNotOwned, got %v", err)
+	}
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	client := newFakeProviderClient(newSnapshotDevices()...)
+	deviceService, account := newTestDeviceService(t, client)
+	service := NewStateSnapshotService(&MockStateSnapshotRepository{}, deviceService)
+	userID := account.OwnerUserID.String()
+
+	snapshot, err := service.TakeSnapshot(context.Background(), userID, account.ID.String(), "group_id:living-room", "Before demo")
+	if err != nil {
+		t.Fatalf("TakeSnapshot failed: %v", err)
+	}
+
+	duration := 2.0
+	result, err := service.RestoreSnapshot(context.Background(), userID, snapshot.ID, &duration)
+	if err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+
+	if result.Failed != 0 || result.Succeeded != 6 {
+		t.Errorf("Expected 6 successes, got %d and %d failures", result.Succeeded, result.Failed)
+	}
+	for _, selector := range client.selectors {
+		if selector != "id:bulb-1" && selector != "id:bulb-2" {
+			t.Errorf("Expected only the snapshot's devices to be restored, got %q", selector)
+		}
+	}
+
+	if _, err := service.RestoreSnapshot(context.Background(), uuid.NewString(), snapshot.ID, nil); !errors.Is(err, repository.ErrStateSnapshotNotFound) {
+		t.Errorf("Expected another user's restore to fail with ErrStateSnapshotNotFound, got %v", err)
+	}
+}
+
+func TestListAndDeleteSnapshots(t *testing.T) {
+	deviceService, account := newTestDeviceService(t, newFakeProviderClient(newSnapshotDevices()...))
+	service := NewStateSnapshotService(&MockStateSnapshotRepository{}, deviceService)
+	userID := account.OwnerUserID.String()
+
+	first, err := service.TakeSnapshot(context.Background(), userID, account.ID.String(), "all", "Everything")
+	if err != nil {
+		t.Fatalf("TakeSnapshot failed: %v", err)
+	}
+	second, err := service.TakeSnapshot(context.Background(), userID, account.ID.String(), "id:bulb-1", "Lamp")
+	if err != nil {
+		t.Fatalf("TakeSnapshot failed: %v", err)
+	}
+
+	snapshots, err := service.ListSnapshots(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("ListSnapshots failed: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].ID != second.ID {
+		t.Errorf("Expected 2 snapshots, newest first, got %d", len(snapshots))
+	}
+
+	if err := service.DeleteSnapshot(context.Background(), userID, first.ID); err != nil {
+		t.Fatalf("DeleteSnapshot failed: %v", err)
+	}
+	if err := service.DeleteSnapshot(context.Background(), userID, first.ID); !errors.Is(err, repository.ErrStateSnapshotNotFound) {
+		t.Errorf("Expected ErrStateSnapshotNotFound deleting twice, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// tokenValidation is a cached successful provider token validation
+type tokenValidation struct {
+	CheckedAt time.Time              `json:"checked_at"`
+	Info      *providers.AccountInfo `json:"info"`
+}
+
+// tokenValidationCache caches successful provider token validations per account
+// so repeated health checks don't call the provider. A nil cache client disables caching.
+type tokenValidationCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func newTokenValidationCache(client *redis.Client, ttl time.Duration) *tokenValidationCache {
+	return &tokenValidationCache{client: client, ttl: ttl}
+}
+
+func tokenValidationKey(accountID string) string {
+	return fmt.Sprintf("provider:validation:account:%s", accountID)
+}
+
+// get returns the cached validation for an account, if any
+func (c *tokenValidationCache) get(ctx context.Context, accountID string) (*tokenValidation, bool) {
+	if c.client == nil || c.ttl <= 0 {
+		return nil, false
+	}
+
+	data, err := c.client.Get(ctx, tokenValidationKey(accountID)).Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	var validation tokenValidation
+	if err := json.Unmarshal(data, &validation); err != nil {
+		return nil, false
+	}
+	return &validation, true
+}
+
+// set caches a successful validation for an account
+func (c *tokenValidationCache) set(ctx context.Context, accountID string, validation *tokenValidation) error {
+	if c.client == nil || c.ttl <= 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(validation)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, tokenValidationKey(accountID), data, c.ttl).Err()
+}
+
+// invalidate drops the cached validation for an account
+func (c *tokenValidationCache) invalidate(ctx context.Context, accountID string) error {
+	if c.client == nil {
+		return nil
+	}
+	return c.client.Del(ctx, tokenValidationKey(accountID)).Err()
+}
+
+// invalidateOnUnauthorized drops the cached validation when err reports a rejected token
+func (c *tokenValidationCache) invalidateOnUnauthorized(ctx context.Context, accountID string, err error) {
+	if errors.Is(err, providers.ErrUnauthorized) {
+		// Best effort; a stale entry expires with its TTL
+		_ = c.invalidate(ctx, accountID)
+	}
+}
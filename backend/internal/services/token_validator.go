@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/logger"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+const (
+	// defaultTokenValidationInterval is how often stored provider tokens are re-validated
+	// when no interval is configured
+	defaultTokenValidationInterval = 6 * time.Hour
+	// tokenValidationPageSize is how many accounts are loaded at a time during a run
+	tokenValidationPageSize = 100
+)
+
+// TokenValidationRepository pages through every account and records whether its provider
+// still accepts its token
+type TokenValidationRepository interface {
+	FindAll(ctx context.Context, offset, limit int) ([]*models.Account, error)
+	GetDecryptedToken(ctx context.Context, accountID string) (string, error)
+	MarkTokenInvalid(ctx context.Context, accountID uuid.UUID, invalidAt time.Time) error
+	MarkTokenValid(ctx context.Context, accountID uuid.UUID) error
+}
+
+// TokenInvalidNotifier tells the owner of an account that its token must be replaced
+type TokenInvalidNotifier interface {
+	SendTokenInvalidEmail(to, providerName, accountLabel string) error
+}
+
+// TokenValidationResult counts what a single validation run found
+type TokenValidationResult struct {
+	Checked     int `json:"checked"`
+	Invalidated int `json:"invalidated"` // Accounts newly flagged as having an invalid token
+	Restored    int `json:"restored"`    // Flagged accounts whose token is accepted again
+	Failed      int `json:"failed"`      // Accounts that could not be checked
+}
+
+// TokenValidatorJob periodically re-validates the stored token of every account, so a
+// token revoked at the provider (say, after a password reset) is flagged and its owner
+// asked to reconnect, instead of every device request failing.
+type TokenValidatorJob struct {
+	accounts      TokenValidationRepository
+	users         repository.UserRepositoryInterface
+	deviceService *DeviceService
+	notifier      TokenInvalidNotifier
+	now           func() time.Time
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+	Interval      time.Duration
+}
+
+// NewTokenValidatorJob creates a job validating tokens with the provider clients of
+// deviceService every interval, notifying owners of newly invalid tokens through notifier
+func NewTokenValidatorJob(accounts TokenValidationRepository, users repository.UserRepositoryInterface, deviceService *DeviceService, notifier TokenInvalidNotifier, interval time.Duration) *TokenValidatorJob {
+	if interval <= 0 {
+		interval = defaultTokenValidationInterval
+	}
+
+	return &TokenValidatorJob{
+		accounts:      accounts,
+		users:         users,
+		deviceService: deviceService,
+		notifier:      notifier,
+		now:           time.Now,
+		Interval:      interval,
+	}
+}
+
+// Start runs the validation every Interval until ctx is cancelled or Stop is called
+func (j *TokenValidatorJob) Start(ctx context.Context) {
+	ctx, j.cancel = context.WithCancel(ctx)
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				result := j.Run(ctx)
+				logger.Info("Token validation completed",
+					"checked", result.Checked,
+					"invalidated", result.Invalidated,
+					"restored", result.Restored,
+					"failed", result.Failed,
+				)
+			}
+		}
+	}()
+}
+
+// Stop cancels the job and waits for a run in progress to finish
+func (j *TokenValidatorJob) Stop() {
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.wg.Wait()
+}
+
+// Run validates the token of every account once. Only a token the provider rejects is
+// flagged; an account that can't be checked (provider down, disabled provider) is logged
+// and left as is.
+func (j *TokenValidatorJob) Run(ctx context.Context) TokenValidationResult {
+	var result TokenValidationResult
+
+	for offset := 0; ctx.Err() == nil; offset += tokenValidationPageSize {
+		accounts, err := j.accounts.FindAll(ctx, offset, tokenValidationPageSize)
+		if err != nil {
+			logger.Error("Failed to list accounts for token validation", "error", err, "offset", offset)
+			return result
+		}
+
+		for _, account := range accounts {
+			if ctx.Err() != nil {
+				return result
+			}
+			j.validate(ctx, account, &result)
+		}
+
+		if len(accounts) < tokenValidationPageSize {
+			break
+		}
+	}
+
+	return result
+}
+
+// validate checks the token of account and updates its flag when the outcome changed
+func (j *TokenValidatorJob) validate(ctx context.Context, account *models.Account, result *TokenValidationResult) {
+	accountID := account.ID.String()
+
+	err := j.checkToken(ctx, account)
+	switch {
+	case errors.Is(err, providers.ErrUnauthorized):
+		result.Checked++
+		j.deviceService.validations.invalidateOnUnauthorized(ctx, accountID, err)
+		if account.TokenInvalidAt != nil {
+			return // Already flagged, and its owner notified
+		}
+		if err := j.accounts.MarkTokenInvalid(ctx, account.ID, j.now()); err != nil {
+			logger.Error("Failed to flag invalid token", "error", err, "account_id", accountID)
+			return
+		}
+		result.Invalidated++
+		j.notify(ctx, account)
+	case err != nil:
+		result.Failed++
+		logger.Warn("Failed to validate account token", "error", err, "account_id", accountID, "provider", account.Provider)
+	default:
+		result.Checked++
+		if account.TokenInvalidAt == nil {
+			return
+		}
+		if err := j.accounts.MarkTokenValid(ctx, account.ID); err != nil {
+			logger.Error("Failed to clear invalid token flag", "error", err, "account_id", accountID)
+			return
+		}
+		result.Restored++
+	}
+}
+
+// checkToken asks the provider of account whether its stored token is still accepted
+func (j *TokenValidatorJob) checkToken(ctx context.Context, account *models.Account) error {
+	token, err := j.accounts.GetDecryptedToken(ctx, account.ID.String())
+	if err != nil {
+		return err
+	}
+
+	client, err := j.deviceService.newClient(providers.Provider(account.Provider))
+	if err != nil {
+		return err
+	}
+
+	return j.deviceService.callProvider(ctx, account, "validate_token", "all", func(ctx context.Context) error {
+		_, err := providers.WithContext(ctx, client).ValidateToken(token)
+		return err
+	})
+}
+
+// notify emails the owner of account that its token was rejected. Failures are logged;
+// the account stays flagged either way.
+func (j *TokenValidatorJob) notify(ctx context.Context, account *models.Account) {
+	owner, err := j.users.GetByID(ctx, account.OwnerUserID)
+	if err != nil {
+		logger.Error("Failed to find owner of account with invalid token", "error", err, "account_id", account.ID)
+		return
+	}
+
+	if err := j.notifier.SendTokenInvalidEmail(owner.Email, providerName(account.Provider), account.Label); err != nil {
+		logger.Error("Failed to send invalid token email", "error", err, "account_id", account.ID)
+	}
+}
+
+// providerName returns the display name of a provider, or its ID when it is unknown
+func providerName(provider string) string {
+	for _, info := range providers.Registered() {
+		if string(info.ID) == provider {
+			return info.Name
+		}
+	}
+	return provider
+}
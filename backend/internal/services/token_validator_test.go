@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/providers"
+	"github.com/lightshare/backend/pkg/providers/mock"
+)
+
+// fakeTokenInvalidNotifier records the invalid token emails sent
+type fakeTokenInvalidNotifier struct {
+	sent []string
+}
+
+func (f *fakeTokenInvalidNotifier) SendTokenInvalidEmail(to, providerName, accountLabel string) error {
+	f.sent = append(f.sent, to+" "+providerName+" "+accountLabel)
+	return nil
+}
+
+func TestTokenValidatorJob_Run(t *testing.T) {
+	client := mock.NewMockClient()
+	client.ValidateTokenFn = func(token string) (*providers.AccountInfo, error) {
+		switch token {
+		case "revoked":
+			return nil, providers.ErrUnauthorized
+		case "unreachable":
+			return nil, errors.New("connection refused")
+		}
+		return &providers.AccountInfo{ProviderAccountID: mock.AccountID}, nil
+	}
+	service, valid := newTestDeviceService(t, client)
+	repo := service.accountRepo.(*MockAccountRepository)
+
+	owner := &models.User{ID: valid.OwnerUserID, Email: "owner@example.com"}
+	users := &mockUserRepository{users: []*models.User{owner}}
+
+	flaggedAt := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	create := func(label, token string, invalidAt *time.Time) *models.Account {
+		t.Helper()
+		account, err := repo.Create(context.Background(), &models.CreateAccountParams{
+			OwnerUserID:       owner.ID,
+			Provider:          string(providers.ProviderLIFX),
+			ProviderAccountID: label,
+			EncryptedToken:    []byte(token),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+		account.TokenInvalidAt = invalidAt
+		return account
+	}
+	revoked := create("revoked", "revoked", nil)
+	stillRevoked := create("still-revoked", "revoked", &flaggedAt)
+	restored := create("restored", "good", &flaggedAt)
+	unreachable := create("unreachable", "unreachable", nil)
+
+	notifier := &fakeTokenInvalidNotifier{}
+	job := NewTokenValidatorJob(repo, users, service, notifier, 0)
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	job.now = func() time.Time { return now }
+
+	if job.Interval != defaultTokenValidationInterval {
+		t.Errorf("Expected the default interval %v, got %v", defaultTokenValidationInterval, job.Interval)
+	}
+
+	result := job.Run(context.Background())
+
+	want := TokenValidationResult{Checked: 4, Invalidated: 1, Restored: 1, Failed: 1}
+	if result != want {
+		t.Errorf("Expected %+v, got %+v", want, result)
+	}
+	if got := client.CallCount("ValidateToken"); got != 5 {
+		t.Errorf("Expected every account to be validated, got %d calls", got)
+	}
+
+	tests := []struct {
+		name          string
+		account       *models.Account
+		wantInvalidAt *time.Time
+	}{
+		{"valid token stays unflagged", valid, nil},
+		{"revoked token is flagged", revoked, &now},
+		{"flagged revoked token keeps its flag", stillRevoked, &flaggedAt},
+		{"accepted token is unflagged", restored, nil},
+		{"unchecked token is left alone", unreachable, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.account.TokenInvalidAt
+			if (got == nil) != (tt.wantInvalidAt == nil) || (got != nil && !got.Equal(*tt.wantInvalidAt)) {
+				t.Errorf("Expected token_invalid_at %v, got %v", tt.wantInvalidAt, got)
+			}
+		})
+	}
+
+	// Only the newly revoked token notifies its owner
+	if len(notifier.sent) != 1 || notifier.sent[0] != "owner@example.com LIFX revoked" {
+		t.Errorf("Expected a single email about the revoked account, got %v", notifier.sent)
+	}
+
+	// Devices of a flagged account are not listed until it is reconnected
+	_, err := service.ListAccountDevices(context.Background(), owner.ID.String(), revoked.ID.String(), models.DeviceFilter{}, models.PaginationOptions{})
+	if !errors.Is(err, ErrAccountTokenInvalid) {
+		t.Errorf("Expected ErrAccountTokenInvalid, got %v", err)
+	}
+	if err := repo.UpdateToken(context.Background(), revoked.ID, owner.ID, []byte("good"), "", nil); err != nil {
+		t.Fatalf("Failed to reconnect account: %v", err)
+	}
+	if _, err := service.ListAccountDevices(context.Background(), owner.ID.String(), revoked.ID.String(), models.DeviceFilter{}, models.PaginationOptions{}); err != nil {
+		t.Errorf("Expected the reconnected account's devices to be listed, got %v", err)
+	}
+}
+
+func TestTokenValidatorJob_RunPaginates(t *testing.T) {
+	client := mock.NewMockClient()
+	service, _ := newTestDeviceService(t, client)
+	repo := service.accountRepo.(*MockAccountRepository)
+	for range tokenValidationPageSize {
+		if _, err := repo.Create(context.Background(), &models.CreateAccountParams{
+			OwnerUserID:       uuid.New(),
+			Provider:          string(providers.ProviderLIFX),
+			ProviderAccountID: uuid.NewString(),
+			EncryptedToken:    []byte("good"),
+		}); err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+	}
+
+	job := NewTokenValidatorJob(repo, &mockUserRepository{}, service, &fakeTokenInvalidNotifier{}, time.Hour)
+	result := job.Run(context.Background())
+
+	if result.Checked != tokenValidationPageSize+1 {
+		t.Errorf("Expected %d accounts checked across pages, got %d", tokenValidationPageSize+1, result.Checked)
+	}
+}
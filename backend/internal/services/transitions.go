@@ -0,0 +1,80 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/events"
+)
+
+// pendingTransition is a scheduled transition_complete event
+type pendingTransition struct {
+	timer *time.Timer
+}
+
+// transitionTracker publishes transition_complete events once an action's duration has
+// elapsed. A newer action on the same account and selector supersedes the pending event.
+type transitionTracker struct {
+	bus     *events.Bus
+	pending map[string]*pendingTransition
+	mu      sync.Mutex
+}
+
+func newTransitionTracker(bus *events.Bus) *transitionTracker {
+	return &transitionTracker{
+		bus:     bus,
+		pending: make(map[string]*pendingTransition),
+	}
+}
+
+// track records a successful action, cancelling any pending event for the same selector
+// and scheduling a new one if the action has a transition
+func (t *transitionTracker) track(userID, accountID, selector string, action *models.ActionRequest) {
+	key := accountID + "|" + selector
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if previous, ok := t.pending[key]; ok {
+		previous.timer.Stop()
+		delete(t.pending, key)
+	}
+
+	if !action.HasTransition() {
+		return
+	}
+
+	state := make(map[string]interface{}, len(action.Parameters))
+	for k, v := range action.Parameters {
+		if k != "duration" {
+			state[k] = v
+		}
+	}
+	event := events.Event{
+		Type:      events.TransitionComplete,
+		UserID:    userID,
+		AccountID: accountID,
+		Payload: &models.TransitionComplete{
+			Selector: selector,
+			Action:   action.Action,
+			State:    state,
+		},
+	}
+
+	entry := &pendingTransition{}
+	entry.timer = time.AfterFunc(time.Duration(action.GetDuration()*float64(time.Second)), func() {
+		t.mu.Lock()
+		current, ok := t.pending[key]
+		if !ok || current != entry {
+			// Superseded after the timer fired but before we took the lock
+			t.mu.Unlock()
+			return
+		}
+		delete(t.pending, key)
+		t.mu.Unlock()
+
+		t.bus.Publish(event)
+	})
+	t.pending[key] = entry
+}
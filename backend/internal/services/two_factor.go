@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pquerna/otp/totp"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/crypto"
+)
+
+var (
+	// ErrInvalidTOTPCode is returned when a two-factor code is wrong or was already used.
+	ErrInvalidTOTPCode = errors.New("invalid two-factor code")
+	// ErrTwoFactorAlreadyEnabled is returned when enrolling a user who already has 2FA.
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	// ErrTwoFactorNotEnabled is returned when disabling 2FA for a user without it.
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication not enabled")
+	// ErrNoPendingEnrollment is returned when confirming 2FA without a recent enrollment.
+	ErrNoPendingEnrollment = errors.New("no pending two-factor enrollment")
+	// ErrInvalidMFAToken is returned when an MFA pending token is invalid or expired.
+	ErrInvalidMFAToken = errors.New("invalid mfa pending token")
+	// ErrTwoFactorRateLimited is returned after too many two-factor code attempts.
+	ErrTwoFactorRateLimited = errors.New("too many two-factor attempts")
+)
+
+const (
+	// totpIssuer is shown next to the account in authenticator apps
+	totpIssuer = "LightShare"
+	// totpEnrollmentTTL is how long an unconfirmed TOTP secret is kept
+	totpEnrollmentTTL = 10 * time.Minute
+	// totpAttemptLimit is the maximum number of code attempts per user per window
+	totpAttemptLimit = 5
+	// totpAttemptWindow is the rate limit window for code attempts
+	totpAttemptWindow = 5 * time.Minute
+	// totpUsedCodeTTL covers the validation skew window so a code can't be replayed
+	totpUsedCodeTTL = 90 * time.Second
+)
+
+// MFARequiredError is returned by Login when the user has two-factor authentication
+// enabled; the pending token must be exchanged via CompleteLogin2FA
+type MFARequiredError struct {
+	ExpiresAt    time.Time `json:"expires_at"`
+	PendingToken string    `json:"mfa_pending_token"`
+}
+
+func (e *MFARequiredError) Error() string {
+	return "two-factor authentication required"
+}
+
+// TwoFactorEnrollment contains a new TOTP secret for the user to add to an authenticator app
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"` // otpauth:// URI for QR codes
+}
+
+func totpEnrollmentKey(userID uuid.UUID) string {
+	return fmt.Sprintf("2fa:enroll:user:%s", userID)
+}
+
+// Enroll2FA generates a TOTP secret for the user. It is only stored on the user
+// once a code from it is confirmed with Confirm2FA.
+func (s *AuthService) Enroll2FA(ctx context.Context, userID uuid.UUID) (*TwoFactorEnrollment, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer,
+		AccountName: user.Email,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+
+	encryptedSecret, _, err := s.secretKeys.EncryptToken(key.Secret())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt totp secret: %w", err)
+	}
+
+	if err := s.cache.Set(ctx, totpEnrollmentKey(userID), encryptedSecret, totpEnrollmentTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store pending enrollment: %w", err)
+	}
+
+	return &TwoFactorEnrollment{
+		Secret: key.Secret(),
+		URI:    key.URL(),
+	}, nil
+}
+
+// Confirm2FA verifies the first code from a pending enrollment and enables 2FA
+func (s *AuthService) Confirm2FA(ctx context.Context, userID uuid.UUID, code string) error {
+	encryptedSecret, err := s.cache.Get(ctx, totpEnrollmentKey(userID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrNoPendingEnrollment
+		}
+		return fmt.Errorf("failed to get pending enrollment: %w", err)
+	}
+
+	if err := s.validateTOTP(ctx, userID, encryptedSecret, code); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.SetTOTPSecret(ctx, userID, encryptedSecret); err != nil {
+		return fmt.Errorf("failed to store totp secret: %w", err)
+	}
+	s.invalidateProfile(ctx, userID)
+
+	// Best effort; the pending secret expires with its TTL
+	_ = s.cache.Del(ctx, totpEnrollmentKey(userID)).Err()
+
+	return nil
+}
+
+// Disable2FA turns off two-factor authentication after verifying a current code
+func (s *AuthService) Disable2FA(ctx context.Context, userID uuid.UUID, code string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !user.TOTPEnabled || user.TOTPSecret == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if err := s.validateTOTP(ctx, user.ID, *user.TOTPSecret, code); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.ClearTOTPSecret(ctx, user.ID); err != nil {
+		return err
+	}
+	s.invalidateProfile(ctx, user.ID)
+	return nil
+}
+
+// CompleteLogin2FA exchanges an MFA pending token and a valid code for a token pair
+func (s *AuthService) CompleteLogin2FA(ctx context.Context, pendingToken, code string, userAgent, ipAddress *string) (*LoginResponse, error) {
+	claims, err := s.jwtService.ValidateMFAPendingToken(pendingToken)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// 2FA may have been disabled since the password step
+	if !user.TOTPEnabled || user.TOTPSecret == nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	if err := s.validateTOTP(ctx, user.ID, *user.TOTPSecret, code); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.createSession(ctx, user, userAgent, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, models.EventLogin, user.ID, userAgent, ipAddress, map[string]interface{}{"method": "totp"})
+
+	return resp, nil
+}
+
+// validateTOTP checks a code against an encrypted secret, limiting attempts per user
+// and rejecting a code that was already accepted
+func (s *AuthService) validateTOTP(ctx context.Context, userID uuid.UUID, encryptedSecret []byte, code string) error {
+	key := fmt.Sprintf("ratelimit:2fa:user:%s", userID)
+	count, err := s.incrementWindow(ctx, key, totpAttemptWindow)
+	if err != nil {
+		return fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	if count > totpAttemptLimit {
+		return ErrTwoFactorRateLimited
+	}
+
+	secret, err := s.secretKeys.DecryptToken(encryptedSecret, "")
+	if err != nil {
+		return fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+
+	if !totp.Validate(code, secret) {
+		return ErrInvalidTOTPCode
+	}
+
+	fresh, err := s.cache.SetNX(ctx, fmt.Sprintf("2fa:used:user:%s:%s", userID, code), 1, totpUsedCodeTTL).Result()
+	if err != nil {
+		return fmt.Errorf("failed to record totp code: %w", err)
+	}
+	if !fresh {
+		return ErrInvalidTOTPCode
+	}
+
+	// A successful code resets the attempt budget
+	s.cache.Del(ctx, key)
+
+	return nil
+}
+
+// createSession starts a new session: it issues a token pair for the user and stores the
+// refresh token as the first of a new token family
+func (s *AuthService) createSession(ctx context.Context, user *models.User, userAgent, ipAddress *string) (*LoginResponse, error) {
+	sessionID := uuid.New()
+	tokenPair, err := s.jwtService.GenerateTokenPair(user.ID, user.Email, user.Role, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	}
+
+	// Store refresh token in database
+	refreshTokenHash := crypto.HashToken(tokenPair.RefreshToken)
+	_, err = s.refreshTokenRepo.Create(ctx, user.ID, uuid.New(), sessionID, refreshTokenHash, tokenPair.ExpiresAt.Add(29*24*time.Hour), userAgent, ipAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &LoginResponse{
+		User:         user,
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+		ExpiresAt:    tokenPair.ExpiresAt,
+		TokenType:    tokenPair.TokenType,
+	}, nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp/totp"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lightshare/backend/pkg/crypto"
+	"github.com/lightshare/backend/pkg/jwt"
+)
+
+// newTestTwoFactorService returns an AuthService with only the dependencies code validation needs,
+// plus an encrypted secret and its plaintext
+func newTestTwoFactorService(t *testing.T) (*AuthService, []byte, string) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	key := []byte("12345678901234567890123456789012")
+
+	service := &AuthService{
+		cache:      redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		secretKeys: newTestKeys(t),
+		jwtService: jwt.New(jwt.Config{Secret: "test-secret", AccessExpiration: time.Hour, RefreshExpiration: time.Hour}),
+	}
+
+	otpKey, err := totp.Generate(totp.GenerateOpts{Issuer: totpIssuer, AccountName: "user@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to generate secret: %v", err)
+	}
+	encrypted, err := crypto.EncryptToken(otpKey.Secret(), key)
+	if err != nil {
+		t.Fatalf("Failed to encrypt secret: %v", err)
+	}
+	return service, encrypted, otpKey.Secret()
+}
+
+func TestValidateTOTP_RejectsReplay(t *testing.T) {
+	service, encrypted, secret := newTestTwoFactorService(t)
+	userID := uuid.New()
+	ctx := context.Background()
+
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to generate code: %v", err)
+	}
+
+	if err := service.validateTOTP(ctx, userID, encrypted, code); err != nil {
+		t.Fatalf("Expected valid code to be accepted, got %v", err)
+	}
+	if err := service.validateTOTP(ctx, userID, encrypted, code); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Errorf("Expected replayed code to be rejected, got %v", err)
+	}
+}
+
+func TestValidateTOTP_LimitsAttempts(t *testing.T) {
+	service, encrypted, _ := newTestTwoFactorService(t)
+	userID := uuid.New()
+	ctx := context.Background()
+
+	for i := 0; i < totpAttemptLimit; i++ {
+		if err := service.validateTOTP(ctx, userID, encrypted, "abcdef"); !errors.Is(err, ErrInvalidTOTPCode) {
+			t.Fatalf("Attempt %d: expected ErrInvalidTOTPCode, got %v", i+1, err)
+		}
+	}
+
+	if err := service.validateTOTP(ctx, userID, encrypted, "abcdef"); !errors.Is(err, ErrTwoFactorRateLimited) {
+		t.Errorf("Expected ErrTwoFactorRateLimited, got %v", err)
+	}
+	if ttl := service.cache.TTL(ctx, "ratelimit:2fa:user:"+userID.String()).Val(); ttl != totpAttemptWindow {
+		t.Errorf("Expected the attempts to be counted for %v, got %v", totpAttemptWindow, ttl)
+	}
+}
+
+func TestCompleteLogin2FA_RejectsNonPendingToken(t *testing.T) {
+	service, _, _ := newTestTwoFactorService(t)
+
+	// A full access token must not be accepted in place of an MFA pending token
+	pair, err := service.jwtService.GenerateTokenPair(uuid.New(), "user@example.com", "user", uuid.New())
+	if err != nil {
+		t.Fatalf("Failed to generate tokens: %v", err)
+	}
+
+	if _, err := service.CompleteLogin2FA(context.Background(), pair.AccessToken, "123456", nil, nil); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Errorf("Expected ErrInvalidMFAToken, got %v", err)
+	}
+
+	// And an MFA pending token must not pass as an access token
+	pending, _, err := service.jwtService.GenerateMFAPendingToken(uuid.New(), "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("Failed to generate pending token: %v", err)
+	}
+	if _, err := service.jwtService.ValidateAccessToken(pending); !errors.Is(err, jwt.ErrInvalidTokenType) {
+		t.Errorf("Expected pending token to be rejected as an access token, got %v", err)
+	}
+}
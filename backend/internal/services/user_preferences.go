@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+// UserPreferencesService manages users' defaults for device actions
+type UserPreferencesService struct {
+	repo repository.UserPreferencesRepositoryInterface
+}
+
+// NewUserPreferencesService creates a new user preferences service
+func NewUserPreferencesService(repo repository.UserPreferencesRepositoryInterface) *UserPreferencesService {
+	return &UserPreferencesService{repo: repo}
+}
+
+// Get returns a user's preferences; a user who never saved any gets empty preferences
+func (s *UserPreferencesService) Get(ctx context.Context, userID uuid.UUID) (*models.UserPreferences, error) {
+	return s.repo.Get(ctx, userID)
+}
+
+// Update replaces every preference of a user
+func (s *UserPreferencesService) Update(ctx context.Context, userID uuid.UUID, req models.UpdatePreferencesRequest) (*models.UserPreferences, error) {
+	if err := req.Validate(); err != nil {
+		return nil, &apierror.BadRequestError{Message: err.Error(), Cause: err}
+	}
+
+	prefs := &models.UserPreferences{
+		DefaultBrightness:  req.DefaultBrightness,
+		DefaultKelvin:      req.DefaultKelvin,
+		FavoriteHue:        req.FavoriteHue,
+		FavoriteSaturation: req.FavoriteSaturation,
+		WakeKelvin:         req.WakeKelvin,
+		SleepKelvin:        req.SleepKelvin,
+	}
+	if err := s.repo.Upsert(ctx, userID, prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// backfillPreferences fills in the parameters an action sent with the default transition
+// leaves out from the user's preferences: the level of brightness actions, the kelvin of
+// color and temperature actions, and the hue and saturation of color actions given no
+// color. Parameters that were sent are kept, even when 0. Preferences that cannot be
+// loaded are skipped, leaving the action as sent.
+func (s *DeviceService) backfillPreferences(ctx context.Context, userID string, action *models.ActionRequest) {
+	if s.preferences == nil || action.GetDuration() != models.DefaultTransitionDuration {
+		return
+	}
+
+	missing := missingPreferenceParameters(action)
+	if len(missing) == 0 {
+		return
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return
+	}
+	prefs, err := s.preferences.Get(ctx, userUUID)
+	if err != nil {
+		logger.WithContext(ctx).Warn("Failed to load user preferences", "user_id", userID, "error", err)
+		return
+	}
+
+	if action.Parameters == nil {
+		action.Parameters = make(map[string]interface{})
+	}
+	for _, param := range missing {
+		switch {
+		case param == "level" && prefs.DefaultBrightness != nil:
+			action.Parameters["level"] = *prefs.DefaultBrightness
+		case param == "kelvin" && prefs.DefaultKelvin != nil:
+			action.Parameters["kelvin"] = float64(*prefs.DefaultKelvin)
+		case param == "color" && prefs.FavoriteHue != nil && prefs.FavoriteSaturation != nil:
+			action.Parameters["hue"] = *prefs.FavoriteHue
+			action.Parameters["saturation"] = *prefs.FavoriteSaturation
+		}
+	}
+}
+
+// missingPreferenceParameters returns the parameters of action that preferences can fill
+// in and that were not sent; "color" stands for hue and saturation together
+func missingPreferenceParameters(action *models.ActionRequest) []string {
+	has := func(param string) bool {
+		_, ok := action.Parameters[param]
+		return ok
+	}
+
+	var missing []string
+	switch action.Action {
+	case models.ActionBrightness:
+		if !has("level") {
+			missing = append(missing, "level")
+		}
+	case models.ActionTemperature:
+		if !has("kelvin") {
+			missing = append(missing, "kelvin")
+		}
+	case models.ActionColor:
+		if !has("kelvin") {
+			missing = append(missing, "kelvin")
+		}
+		if !has("hue") && !has("saturation") && !has("hex") {
+			missing = append(missing, "color")
+		}
+	}
+	return missing
+}
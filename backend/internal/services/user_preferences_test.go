@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// fakeUserPreferencesRepository is an in-memory UserPreferencesRepositoryInterface
+type fakeUserPreferencesRepository struct {
+	prefs map[uuid.UUID]models.UserPreferences
+	gets  int
+	mu    sync.Mutex
+}
+
+func newFakeUserPreferencesRepository() *fakeUserPreferencesRepository {
+	return &fakeUserPreferencesRepository{prefs: make(map[uuid.UUID]models.UserPreferences)}
+}
+
+func (r *fakeUserPreferencesRepository) Get(_ context.Context, userID uuid.UUID) (*models.UserPreferences, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.gets++
+	prefs := r.prefs[userID]
+	prefs.UserID = userID
+	return &prefs, nil
+}
+
+func (r *fakeUserPreferencesRepository) Upsert(_ context.Context, userID uuid.UUID, prefs *models.UserPreferences) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prefs.UserID = userID
+	r.prefs[userID] = *prefs
+	return nil
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+func intPtr(v int) *int { return &v }
+
+func TestUserPreferencesService_Update(t *testing.T) {
+	service := NewUserPreferencesService(newFakeUserPreferencesRepository())
+	userID := uuid.New()
+
+	prefs, err := service.Update(context.Background(), userID, models.UpdatePreferencesRequest{
+		DefaultBrightness: floatPtr(0.6),
+		DefaultKelvin:     intPtr(3000),
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if *prefs.DefaultBrightness != 0.6 || *prefs.DefaultKelvin != 3000 || prefs.FavoriteHue != nil {
+		t.Errorf("Unexpected preferences: %+v", prefs)
+	}
+
+	saved, err := service.Get(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if saved.DefaultKelvin == nil || *saved.DefaultKelvin != 3000 {
+		t.Errorf("Expected the saved kelvin 3000, got %v", saved.DefaultKelvin)
+	}
+
+	invalid := []models.UpdatePreferencesRequest{
+		{DefaultBrightness: floatPtr(1.5)},
+		{DefaultKelvin: intPtr(1000)},
+		{SleepKelvin: intPtr(10000)},
+		{FavoriteHue: floatPtr(400), FavoriteSaturation: floatPtr(1)},
+		{FavoriteHue: floatPtr(120)},
+	}
+	for _, req := range invalid {
+		var badRequestErr *apierror.BadRequestError
+		if _, err := service.Update(context.Background(), userID, req); !errors.As(err, &badRequestErr) {
+			t.Errorf("Expected a BadRequestError for %+v, got %v", req, err)
+		}
+	}
+}
+
+func TestBackfillPreferences(t *testing.T) {
+	repo := newFakeUserPreferencesRepository()
+	userID := uuid.New()
+	repo.prefs[userID] = models.UserPreferences{
+		DefaultBrightness:  floatPtr(0.6),
+		DefaultKelvin:      intPtr(3000),
+		FavoriteHue:        floatPtr(30),
+		FavoriteSaturation: floatPtr(0.5),
+	}
+	service := &DeviceService{preferences: NewUserPreferencesService(repo)}
+
+	tests := []struct {
+		name       string
+		action     string
+		parameters map[string]interface{}
+		want       map[string]interface{}
+	}{
+		{
+			name:   "brightness without level",
+			action: models.ActionBrightness,
+			want:   map[string]interface{}{"level": 0.6},
+		},
+		{
+			name:       "explicit zero level kept",
+			action:     models.ActionBrightness,
+			parameters: map[string]interface{}{"level": 0.0},
+			want:       map[string]interface{}{"level": 0.0},
+		},
+		{
+			name:       "temperature without kelvin",
+			action:     models.ActionTemperature,
+			parameters: map[string]interface{}{},
+			want:       map[string]interface{}{"kelvin": 3000.0},
+		},
+		{
+			name:       "color without kelvin",
+			action:     models.ActionColor,
+			parameters: map[string]interface{}{"hue": 240.0, "saturation": 1.0},
+			want:       map[string]interface{}{"hue": 240.0, "saturation": 1.0, "kelvin": 3000.0},
+		},
+		{
+			name:       "color without color",
+			action:     models.ActionColor,
+			parameters: map[string]interface{}{"kelvin": 4000.0},
+			want:       map[string]interface{}{"hue": 30.0, "saturation": 0.5, "kelvin": 4000.0},
+		},
+		{
+			name:       "custom transition not backfilled",
+			action:     models.ActionBrightness,
+			parameters: map[string]interface{}{"duration": 2.0},
+			want:       map[string]interface{}{"duration": 2.0},
+		},
+		{
+			name:       "power not backfilled",
+			action:     models.ActionPower,
+			parameters: map[string]interface{}{"state": "on"},
+			want:       map[string]interface{}{"state": "on"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action := &models.ActionRequest{Action: tt.action, Parameters: tt.parameters}
+			service.backfillPreferences(context.Background(), userID.String(), action)
+
+			if len(action.Parameters) != len(tt.want) {
+				t.Fatalf("Expected parameters %v, got %v", tt.want, action.Parameters)
+			}
+			for key, value := range tt.want {
+				if action.Parameters[key] != value {
+					t.Errorf("Expected %s %v, got %v", key, value, action.Parameters[key])
+				}
+			}
+		})
+	}
+
+	repo.gets = 0
+	service.backfillPreferences(context.Background(), userID.String(), &models.ActionRequest{Action: models.ActionToggle})
+	if repo.gets != 0 {
+		t.Errorf("Expected no preferences lookup for an action with nothing to fill in, got %d", repo.gets)
+	}
+}
+
+func TestExecuteAction_BackfillsBrightnessFromPreferences(t *testing.T) {
+	client := newFakeProviderClient(&providers.Device{ID: "d1", Label: "Bedroom"})
+	service, account := newTestDeviceService(t, client)
+	repo := newFakeUserPreferencesRepository()
+	repo.prefs[account.OwnerUserID] = models.UserPreferences{DefaultBrightness: floatPtr(0.6)}
+	service.preferences = NewUserPreferencesService(repo)
+
+	action := &models.ActionRequest{Action: models.ActionBrightness}
+	if err := service.ExecuteAction(context.Background(), account.OwnerUserID.String(), account.ID.String(), "id:d1", action); err != nil {
+		t.Fatalf("ExecuteAction failed: %v", err)
+	}
+	if client.callCount("SetBrightness") != 1 {
+		t.Errorf("Expected 1 SetBrightness call, got %d", client.callCount("SetBrightness"))
+	}
+
+	service.preferences = NewUserPreferencesService(newFakeUserPreferencesRepository())
+	err := service.ExecuteAction(context.Background(), account.OwnerUserID.String(), account.ID.String(), "id:d1", &models.ActionRequest{Action: models.ActionBrightness})
+	var badRequestErr *apierror.BadRequestError
+	if !errors.As(err, &badRequestErr) {
+		t.Errorf("Expected a BadRequestError without a default brightness, got %v", err)
+	}
+}
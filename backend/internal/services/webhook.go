@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/jwt"
+)
+
+// ErrInvalidWebhookRequest is returned when a webhook registration request is invalid
+var ErrInvalidWebhookRequest = errors.New("invalid webhook request")
+
+const (
+	// webhookSecretBytes is the amount of randomness in a generated signing secret
+	webhookSecretBytes = 32
+	// maxWebhookURLLength is the longest webhook URL accepted
+	maxWebhookURLLength = 2048
+)
+
+// WebhookService handles webhook registration
+type WebhookService struct {
+	webhookRepo repository.WebhookRepositoryInterface
+	lookupIP    func(ctx context.Context, host string) ([]net.IP, error)
+	allowIP     func(net.IP) bool // Addresses webhooks may point to; publicWebhookIP outside of tests
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(webhookRepo repository.WebhookRepositoryInterface) *WebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		lookupIP:    lookupWebhookIPs,
+		allowIP:     publicWebhookIP,
+	}
+}
+
+// CreateWebhookRequest represents a request to register a webhook. Event types default
+// to every supported event.
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+// CreateWebhookResponse contains the created webhook; the signing secret is only ever returned here
+type CreateWebhookResponse struct {
+	Webhook *models.Webhook `json:"webhook"`
+	Secret  string          `json:"secret"`
+}
+
+// Create registers a new webhook for the user
+func (s *WebhookService) Create(ctx context.Context, userID uuid.UUID, req CreateWebhookRequest) (*CreateWebhookResponse, error) {
+	req.URL = strings.TrimSpace(req.URL)
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	// The server must not be made to call its own network
+	parsed, _ := url.Parse(req.URL)
+	if err := checkWebhookHost(ctx, parsed.Hostname(), s.lookupIP, s.allowIP); err != nil {
+		return nil, err
+	}
+
+	if len(req.EventTypes) == 0 {
+		req.EventTypes = []string{models.WebhookEventActionCompleted}
+	}
+	for _, eventType := range req.EventTypes {
+		if !models.IsValidWebhookEventType(eventType) {
+			return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhookRequest, eventType)
+		}
+	}
+
+	secret, err := jwt.GenerateRandomToken(webhookSecretBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	secret = strings.TrimRight(secret, "=")
+
+	webhook, err := s.webhookRepo.Create(ctx, &models.CreateWebhookParams{
+		UserID:     userID,
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreateWebhookResponse{Webhook: webhook, Secret: secret}, nil
+}
+
+// List returns the user's webhooks
+func (s *WebhookService) List(ctx context.Context, userID uuid.UUID) ([]*models.Webhook, error) {
+	return s.webhookRepo.FindByUserID(ctx, userID)
+}
+
+// Delete removes one of the user's webhooks
+func (s *WebhookService) Delete(ctx context.Context, userID, webhookID uuid.UUID) error {
+	return s.webhookRepo.Delete(ctx, webhookID, userID)
+}
+
+// validateWebhookURL checks that a webhook URL is an absolute HTTP(S) URL
+func validateWebhookURL(rawURL string) error {
+	if rawURL == "" {
+		return fmt.Errorf("%w: url is required", ErrInvalidWebhookRequest)
+	}
+	if len(rawURL) > maxWebhookURLLength {
+		return fmt.Errorf("%w: url must be at most %d characters", ErrInvalidWebhookRequest, maxWebhookURLLength)
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhookRequest)
+	}
+	return nil
+}
+
+// WebhookSignature returns the X-LightShare-Signature header value for a payload: the
+// hex-encoded HMAC-SHA256 of the body keyed with the webhook secret
+func WebhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// errWebhookAddressForbidden is returned when dialing a webhook resolves to an internal address
+var errWebhookAddressForbidden = errors.New("webhook address is not publicly routable")
+
+// publicWebhookIP reports whether a webhook may be delivered to ip. Loopback, private,
+// link-local (including the cloud metadata service), unspecified and multicast addresses
+// are reserved for the server's own network and are never called.
+func publicWebhookIP(ip net.IP) bool {
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified())
+}
+
+// lookupWebhookIPs resolves the host of a webhook URL
+func lookupWebhookIPs(ctx context.Context, host string) ([]net.IP, error) {
+	return net.DefaultResolver.LookupIP(ctx, "ip", host)
+}
+
+// checkWebhookHost rejects a webhook host that is, or resolves to, an address allow
+// refuses. Every address must be allowed, since any of them may be dialed.
+func checkWebhookHost(ctx context.Context, host string, lookup func(ctx context.Context, host string) ([]net.IP, error), allow func(net.IP) bool) error {
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		if ips, err = lookup(ctx, host); err != nil || len(ips) == 0 {
+			return fmt.Errorf("%w: url host %q does not resolve", ErrInvalidWebhookRequest, host)
+		}
+	}
+
+	for _, ip := range ips {
+		if !allow(ip) {
+			return fmt.Errorf("%w: url must not point to a private or local address", ErrInvalidWebhookRequest)
+		}
+	}
+	return nil
+}
+
+// newWebhookClient returns the HTTP client delivering webhooks. The address allow refuses
+// is checked again when dialing, after resolution, so a host re-pointed at an internal
+// address after registration (DNS rebinding) is still not called. Redirects are not
+// followed: the redirect response counts as a failed delivery.
+func newWebhookClient(timeout time.Duration, allow func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allow(ip) {
+				return errWebhookAddressForbidden
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil // A proxy would dial on our behalf, past the address check
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/events"
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+// WebhookSignatureHeader carries the HMAC signature of a webhook payload
+const WebhookSignatureHeader = "X-LightShare-Signature"
+
+// WebhookDispatcherConfig holds webhook delivery configuration
+type WebhookDispatcherConfig struct {
+	Workers     int           // Events delivered concurrently (default 4)
+	QueueSize   int           // Events buffered while all workers are busy (default 100)
+	Timeout     time.Duration // Timeout of a single delivery attempt (default 10s)
+	MaxAttempts int           // Attempts per webhook before giving up (default 3)
+	Backoff     time.Duration // Delay before the first retry, doubled after each attempt (default 1s)
+}
+
+// WebhookDispatcher delivers bus events to the webhooks subscribed to them. Events are
+// queued by the bus subscriber and delivered by a pool of workers, so publishers never
+// block on webhook endpoints. Each attempt is logged as a webhook delivery.
+type WebhookDispatcher struct {
+	webhookRepo repository.WebhookRepositoryInterface
+	bus         *events.Bus
+	client      *http.Client
+	queue       chan events.Event
+	unsubscribe func()
+	config      WebhookDispatcherConfig
+	wg          sync.WaitGroup
+	mu          sync.RWMutex
+	stopped     bool
+}
+
+// NewWebhookDispatcher creates a webhook dispatcher for events published on bus
+func NewWebhookDispatcher(webhookRepo repository.WebhookRepositoryInterface, bus *events.Bus, config WebhookDispatcherConfig) *WebhookDispatcher {
+	if config.Workers <= 0 {
+		config.Workers = 4
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 100
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = time.Second
+	}
+
+	return &WebhookDispatcher{
+		webhookRepo: webhookRepo,
+		bus:         bus,
+		client:      newWebhookClient(config.Timeout, publicWebhookIP),
+		queue:       make(chan events.Event, config.QueueSize),
+		config:      config,
+	}
+}
+
+// Start subscribes to the bus and begins delivering events until ctx is cancelled or
+// Stop is called
+func (d *WebhookDispatcher) Start(ctx context.Context) {
+	for i := 0; i < d.config.Workers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event, ok := <-d.queue:
+					if !ok {
+						return
+					}
+					d.dispatch(ctx, event)
+				}
+			}
+		}()
+	}
+
+	d.unsubscribe = d.bus.Subscribe(d.enqueue)
+}
+
+// Stop unsubscribes from the bus and waits for queued events to be delivered
+func (d *WebhookDispatcher) Stop() {
+	if d.unsubscribe != nil {
+		d.unsubscribe()
+	}
+
+	d.mu.Lock()
+	if !d.stopped {
+		d.stopped = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	d.wg.Wait()
+}
+
+// enqueue queues a deliverable event without blocking the publisher
+func (d *WebhookDispatcher) enqueue(event events.Event) {
+	if !models.IsValidWebhookEventType(string(event.Type)) {
+		return
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.stopped {
+		return
+	}
+
+	select {
+	case d.queue <- event:
+	default:
+		logger.Warn("Webhook queue is full, dropping event", "event", event.Type, "account_id", event.AccountID)
+	}
+}
+
+// dispatch delivers an event to each of its user's webhooks subscribed to it
+func (d *WebhookDispatcher) dispatch(ctx context.Context, event events.Event) {
+	userID, err := uuid.Parse(event.UserID)
+	if err != nil {
+		return
+	}
+
+	body, err := webhookBody(event)
+	if err != nil {
+		logger.Error("Failed to encode webhook payload", "error", err, "event", event.Type)
+		return
+	}
+
+	webhooks, err := d.webhookRepo.FindActiveByEventType(ctx, userID, string(event.Type))
+	if err != nil {
+		logger.Error("Failed to find webhooks", "error", err, "event", event.Type)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		d.deliver(ctx, webhook, string(event.Type), body)
+	}
+}
+
+// deliver POSTs body to a webhook, retrying with exponential backoff until an attempt
+// succeeds or MaxAttempts is reached
+func (d *WebhookDispatcher) deliver(ctx context.Context, webhook *models.Webhook, eventType string, body []byte) {
+	backoff := d.config.Backoff
+	for attempt := 1; attempt <= d.config.MaxAttempts; attempt++ {
+		statusCode, err := d.post(ctx, webhook, eventType, body)
+
+		delivery := &models.WebhookDelivery{
+			WebhookID: webhook.ID,
+			EventType: eventType,
+			Attempt:   attempt,
+			Success:   err == nil,
+		}
+		if statusCode != 0 {
+			delivery.StatusCode = &statusCode
+		}
+		if err != nil {
+			message := err.Error()
+			delivery.Error = &message
+		}
+		if logErr := d.webhookRepo.CreateDelivery(ctx, delivery); logErr != nil {
+			logger.Error("Failed to log webhook delivery", "error", logErr, "webhook_id", webhook.ID)
+		}
+
+		if err == nil || attempt == d.config.MaxAttempts {
+			if err != nil {
+				logger.Warn("Webhook delivery failed", "error", err, "webhook_id", webhook.ID, "attempts", attempt)
+			}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends a single signed delivery attempt, returning the response status code, if any.
+// Any non-2xx response is a failed attempt.
+func (d *WebhookDispatcher) post(ctx context.Context, webhook *models.Webhook, eventType string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-LightShare-Event", eventType)
+	req.Header.Set(WebhookSignatureHeader, WebhookSignature(webhook.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// webhookBody encodes the JSON payload delivered for an event
+func webhookBody(event events.Event) ([]byte, error) {
+	completed, ok := event.Payload.(*models.ActionCompleted)
+	if !ok {
+		return nil, fmt.Errorf("unexpected payload %T for event %s", event.Payload, event.Type)
+	}
+
+	return json.Marshal(&models.WebhookPayload{
+		Event:     string(event.Type),
+		AccountID: event.AccountID,
+		Selector:  completed.Selector,
+		Action:    completed.Action,
+		Success:   completed.Success,
+		Error:     completed.Error,
+		Timestamp: event.Timestamp,
+	})
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lightshare/backend/internal/models"
+	"github.com/lightshare/backend/internal/repository"
+	"github.com/lightshare/backend/pkg/events"
+)
+
+// MockWebhookRepository is an in-memory webhook repository for testing
+type MockWebhookRepository struct {
+	webhooks   []*models.Webhook
+	deliveries []*models.WebhookDelivery
+	mu         sync.Mutex
+}
+
+func (m *MockWebhookRepository) Create(_ context.Context, params *models.CreateWebhookParams) (*models.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	webhook := &models.Webhook{
+		ID:         uuid.New(),
+		UserID:     params.UserID,
+		URL:        params.URL,
+		Secret:     params.Secret,
+		EventTypes: params.EventTypes,
+		Active:     true,
+		CreatedAt:  time.Now(),
+	}
+	m.webhooks = append(m.webhooks, webhook)
+	return webhook, nil
+}
+
+func (m *MockWebhookRepository) FindByUserID(_ context.Context, userID uuid.UUID) ([]*models.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	webhooks := make([]*models.Webhook, 0)
+	for _, webhook := range m.webhooks {
+		if webhook.UserID == userID {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (m *MockWebhookRepository) FindActiveByEventType(_ context.Context, userID uuid.UUID, eventType string) ([]*models.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	webhooks := make([]*models.Webhook, 0)
+	for _, webhook := range m.webhooks {
+		if webhook.UserID == userID && webhook.Active && webhook.HasEventType(eventType) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (m *MockWebhookRepository) Delete(_ context.Context, id, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, webhook := range m.webhooks {
+		if webhook.ID == id && webhook.UserID == userID {
+			m.webhooks = append(m.webhooks[:i], m.webhooks[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrWebhookNotFound
+}
+
+func (m *MockWebhookRepository) CreateDelivery(_ context.Context, delivery *models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delivery.ID = uuid.New()
+	delivery.CreatedAt = time.Now()
+	m.deliveries = append(m.deliveries, delivery)
+	return nil
+}
+
+// newTestWebhookService returns a WebhookService resolving example.com to a public address,
+// localhost to loopback and internal.example.com to a private address
+func newTestWebhookService(repo *MockWebhookRepository) *WebhookService {
+	service := NewWebhookService(repo)
+	service.lookupIP = func(_ context.Context, host string) ([]net.IP, error) {
+		switch host {
+		case "example.com":
+			return []net.IP{net.ParseIP("93.184.215.14")}, nil
+		case "localhost":
+			return []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}, nil
+		case "internal.example.com":
+			return []net.IP{net.ParseIP("93.184.215.14"), net.ParseIP("10.1.2.3")}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return service
+}
+
+// allowAnyWebhookIP lets webhooks reach the loopback httptest servers of the tests
+func allowAnyWebhookIP(net.IP) bool {
+	return true
+}
+
+// newLocalWebhookService returns a WebhookService accepting loopback URLs
+func newLocalWebhookService(repo *MockWebhookRepository) *WebhookService {
+	service := NewWebhookService(repo)
+	service.allowIP = allowAnyWebhookIP
+	return service
+}
+
+// newLocalWebhookDispatcher returns a dispatcher delivering to loopback URLs
+func newLocalWebhookDispatcher(repo *MockWebhookRepository, bus *events.Bus) *WebhookDispatcher {
+	dispatcher := NewWebhookDispatcher(repo, bus, WebhookDispatcherConfig{Backoff: time.Millisecond})
+	dispatcher.client = newWebhookClient(dispatcher.config.Timeout, allowAnyWebhookIP)
+	return dispatcher
+}
+
+func TestWebhookClient_RefusesInternalAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	// The host passed registration, but now resolves to loopback
+	_, err := newWebhookClient(time.Second, publicWebhookIP).Post(server.URL, "application/json", http.NoBody)
+	if !errors.Is(err, errWebhookAddressForbidden) {
+		t.Errorf("Expected errWebhookAddressForbidden, got %v", err)
+	}
+	if called {
+		t.Error("Expected the internal address not to be called")
+	}
+}
+
+func TestWebhookClient_DoesNotFollowRedirects(t *testing.T) {
+	redirected := false
+	target := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer server.Close()
+
+	resp, err := newWebhookClient(time.Second, allowAnyWebhookIP).Post(server.URL, "application/json", http.NoBody)
+	if err != nil {
+		t.Fatalf("Post failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTemporaryRedirect || redirected {
+		t.Errorf("Expected the redirect not to be followed, got status %d", resp.StatusCode)
+	}
+}
+
+func TestCreateWebhook(t *testing.T) {
+	service := newTestWebhookService(&MockWebhookRepository{})
+	userID := uuid.New()
+
+	resp, err := service.Create(context.Background(), userID, CreateWebhookRequest{URL: "https://example.com/hooks"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if resp.Secret == "" || resp.Webhook.Secret != resp.Secret {
+		t.Error("Expected a signing secret to be generated")
+	}
+	if len(resp.Webhook.EventTypes) != 1 || resp.Webhook.EventTypes[0] != models.WebhookEventActionCompleted {
+		t.Errorf("Expected default event types, got %v", resp.Webhook.EventTypes)
+	}
+
+	invalid := []CreateWebhookRequest{
+		{URL: ""},
+		{URL: "ftp://example.com/hooks"},
+		{URL: "/relative"},
+		{URL: "https://example.com/hooks", EventTypes: []string{"device.exploded"}},
+		{URL: "http://127.0.0.1:8080/hooks"},
+		{URL: "http://localhost/hooks"},
+		{URL: "http://10.0.0.5/hooks"},
+		{URL: "http://169.254.169.254/latest/meta-data/"},
+		{URL: "http://[::1]/hooks"},
+		{URL: "http://0.0.0.0/hooks"},
+		{URL: "http://224.0.0.1/hooks"},
+		{URL: "https://internal.example.com/hooks"},
+		{URL: "https://unresolvable.example.com/hooks"},
+	}
+	for _, req := range invalid {
+		if _, err := service.Create(context.Background(), userID, req); !errors.Is(err, ErrInvalidWebhookRequest) {
+			t.Errorf("Expected ErrInvalidWebhookRequest for %+v, got %v", req, err)
+		}
+	}
+}
+
+func TestWebhookDispatcher_DeliversSignedPayload(t *testing.T) {
+	type received struct {
+		signature string
+		body      []byte
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{signature: r.Header.Get(WebhookSignatureHeader), body: body}
+	}))
+	defer server.Close()
+
+	repo := &MockWebhookRepository{}
+	userID := uuid.New()
+	resp, err := newLocalWebhookService(repo).Create(context.Background(), userID, CreateWebhookRequest{URL: server.URL})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	bus := events.NewBus()
+	dispatcher := newLocalWebhookDispatcher(repo, bus)
+	dispatcher.Start(context.Background())
+
+	// Events of other users and event types are not delivered
+	bus.Publish(events.Event{Type: events.ActionCompleted, UserID: uuid.NewString(), Payload: &models.ActionCompleted{}})
+	bus.Publish(events.Event{Type: events.DeviceAdded, UserID: userID.String()})
+	bus.Publish(events.Event{
+		Type:      events.ActionCompleted,
+		UserID:    userID.String(),
+		AccountID: "account-1",
+		Payload:   &models.ActionCompleted{Selector: "all", Action: models.ActionPower, Success: true},
+	})
+
+	var req received
+	select {
+	case req = <-requests:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected webhook to be called")
+	}
+	dispatcher.Stop()
+
+	if req.signature != WebhookSignature(resp.Secret, req.body) {
+		t.Errorf("Expected valid signature, got %q", req.signature)
+	}
+
+	var payload models.WebhookPayload
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if payload.Event != "device.action.completed" || payload.AccountID != "account-1" ||
+		payload.Selector != "all" || payload.Action != models.ActionPower || !payload.Success {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+
+	if len(repo.deliveries) != 1 || !repo.deliveries[0].Success {
+		t.Errorf("Expected one successful delivery logged, got %d", len(repo.deliveries))
+	}
+}
+
+func TestWebhookDispatcher_RetriesFailedDeliveries(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	repo := &MockWebhookRepository{}
+	userID := uuid.New()
+	if _, err := newLocalWebhookService(repo).Create(context.Background(), userID, CreateWebhookRequest{URL: server.URL}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	bus := events.NewBus()
+	dispatcher := newLocalWebhookDispatcher(repo, bus)
+	dispatcher.Start(context.Background())
+	bus.Publish(events.Event{Type: events.ActionCompleted, UserID: userID.String(), Payload: &models.ActionCompleted{Action: models.ActionPower}})
+	dispatcher.Stop()
+
+	if len(repo.deliveries) != 3 {
+		t.Fatalf("Expected 3 delivery attempts logged, got %d", len(repo.deliveries))
+	}
+	for i, delivery := range repo.deliveries {
+		if delivery.Attempt != i+1 {
+			t.Errorf("Expected attempt %d, got %d", i+1, delivery.Attempt)
+		}
+		if delivery.Success != (i == 2) {
+			t.Errorf("Expected attempt %d success to be %v", i+1, i == 2)
+		}
+	}
+	if *repo.deliveries[0].StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status 500 logged, got %d", *repo.deliveries[0].StatusCode)
+	}
+}
+
+func TestExecuteAction_PublishesActionCompleted(t *testing.T) {
+	client := newFakeProviderClient()
+	service, account := newTestDeviceService(t, client)
+
+	var published []events.Event
+	service.events.Subscribe(func(event events.Event) {
+		if event.Type == events.ActionCompleted {
+			published = append(published, event)
+		}
+	})
+
+	action := &models.ActionRequest{Action: models.ActionPower, Parameters: map[string]interface{}{"state": "on"}}
+	if err := service.ExecuteAction(context.Background(), account.OwnerUserID.String(), account.ID.String(), "all", action); err != nil {
+		t.Fatalf("ExecuteAction failed: %v", err)
+	}
+
+	client.errs["SetPower"] = []error{errors.New("provider down")}
+	_ = service.ExecuteAction(context.Background(), account.OwnerUserID.String(), account.ID.String(), "all", action)
+
+	if len(published) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(published))
+	}
+	first := published[0].Payload.(*models.ActionCompleted)
+	second := published[1].Payload.(*models.ActionCompleted)
+	if !first.Success || second.Success || second.Error == "" {
+		t.Errorf("Expected success then failure, got %+v and %+v", first, second)
+	}
+}
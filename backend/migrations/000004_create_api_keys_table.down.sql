@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_api_keys_user_id;
+
+-- Drop api_keys table
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,7 @@
+-- Add password reset columns to users table
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS password_reset_token VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS password_reset_expires_at TIMESTAMP WITH TIME ZONE;
+
+-- Create index on password_reset_token for faster lookups
+CREATE INDEX IF NOT EXISTS idx_users_password_reset_token ON users(password_reset_token) WHERE password_reset_token IS NOT NULL;
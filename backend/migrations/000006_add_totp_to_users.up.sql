@@ -0,0 +1,4 @@
+-- Add TOTP two-factor authentication columns to users table
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS totp_secret BYTEA,
+    ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_audit_events_user_id_created_at;
+
+-- Drop audit_events table
+DROP TABLE IF EXISTS audit_events;
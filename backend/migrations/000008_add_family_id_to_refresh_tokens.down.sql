@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_refresh_tokens_family_id;
+
+-- Remove family_id from refresh_tokens
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS family_id;
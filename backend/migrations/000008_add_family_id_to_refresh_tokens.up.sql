@@ -0,0 +1,12 @@
+-- Add family_id to refresh_tokens
+-- Every token issued by rotation shares the family of the token it replaced,
+-- so reuse of a rotated-away token can revoke the whole chain
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS family_id UUID;
+
+-- Existing tokens each start their own family
+UPDATE refresh_tokens SET family_id = id WHERE family_id IS NULL;
+
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+
+-- Create index on family_id for family revocation
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
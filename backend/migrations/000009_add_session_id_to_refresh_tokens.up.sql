@@ -0,0 +1,12 @@
+-- Add session_id to refresh_tokens
+-- A session starts at login and keeps its ID across refresh token rotations;
+-- access tokens carry it in their session_id claim
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_id UUID;
+
+-- Existing tokens each stand for their own session
+UPDATE refresh_tokens SET session_id = family_id WHERE session_id IS NULL;
+
+ALTER TABLE refresh_tokens ALTER COLUMN session_id SET NOT NULL;
+
+-- Create index on session_id for session revocation
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens(session_id);
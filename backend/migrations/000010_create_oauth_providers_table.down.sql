@@ -0,0 +1,2 @@
+-- Drop oauth_providers table
+DROP TABLE IF EXISTS oauth_providers;
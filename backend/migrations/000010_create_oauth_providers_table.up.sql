@@ -0,0 +1,11 @@
+-- Create oauth_providers table
+-- Links a user to their identity at a social sign-in provider
+CREATE TABLE IF NOT EXISTS oauth_providers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    provider_user_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, provider_user_id),
+    UNIQUE (user_id, provider)
+);
@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_scenes_account_id_created_at;
+
+-- Drop scenes table
+DROP TABLE IF EXISTS scenes;
@@ -0,0 +1,13 @@
+-- Create scenes table
+-- A scene is a saved snapshot of the state of every device in an account
+CREATE TABLE IF NOT EXISTS scenes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    state_json JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create index for listing an account's scenes, newest first
+CREATE INDEX IF NOT EXISTS idx_scenes_account_id_created_at ON scenes(account_id, created_at DESC);
@@ -0,0 +1,7 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_webhook_deliveries_webhook_id_created_at;
+DROP INDEX IF EXISTS idx_webhooks_user_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,30 @@
+-- Create webhooks table
+-- A webhook receives signed POST requests for the event types it subscribes to
+CREATE TABLE IF NOT EXISTS webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create index for listing a user's webhooks
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks(user_id);
+
+-- Create webhook_deliveries table
+-- Each delivery attempt is logged, so a retried event has one row per attempt
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    attempt INTEGER NOT NULL,
+    status_code INTEGER,
+    success BOOLEAN NOT NULL,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create index for listing a webhook's deliveries, newest first
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id_created_at ON webhook_deliveries(webhook_id, created_at DESC);
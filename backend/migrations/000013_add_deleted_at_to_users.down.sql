@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_users_deleted_at;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS deleted_at;
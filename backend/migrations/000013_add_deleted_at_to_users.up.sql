@@ -0,0 +1,7 @@
+-- Add soft-delete column to users table
+-- A deleted user keeps their data for a grace period before it is purged
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Create index for finding users whose grace period has expired
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
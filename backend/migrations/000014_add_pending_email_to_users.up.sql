@@ -0,0 +1,9 @@
+-- Add pending email change columns to users table
+-- The new address only replaces email once its verification token is redeemed
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS pending_email VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS pending_email_token VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS pending_email_expires_at TIMESTAMP WITH TIME ZONE;
+
+-- Create index on pending_email_token for faster lookups
+CREATE INDEX IF NOT EXISTS idx_users_pending_email_token ON users(pending_email_token) WHERE pending_email_token IS NOT NULL;
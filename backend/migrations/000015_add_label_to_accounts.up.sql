@@ -0,0 +1,9 @@
+-- Add label column to accounts table
+-- Existing accounts are labelled with their provider account ID, as new ones are on creation
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS label VARCHAR(100);
+
+UPDATE accounts SET label = LEFT(provider_account_id, 100) WHERE label IS NULL;
+
+ALTER TABLE accounts
+    ALTER COLUMN label SET NOT NULL;
@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_device_state_events_device_created_at;
+
+-- Drop tables
+DROP TABLE IF EXISTS device_state_events;
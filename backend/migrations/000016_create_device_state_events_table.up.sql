@@ -0,0 +1,16 @@
+-- Create device_state_events table
+-- An append-only history of device state: actions sent to a device and state changes
+-- detected when an account's devices are refreshed
+CREATE TABLE IF NOT EXISTS device_state_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    device_id VARCHAR(255) NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    old_state_json JSONB NOT NULL DEFAULT 'null',
+    new_state_json JSONB NOT NULL DEFAULT 'null',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create index for a device's timeline over a time range
+CREATE INDEX IF NOT EXISTS idx_device_state_events_device_created_at ON device_state_events(account_id, device_id, created_at);
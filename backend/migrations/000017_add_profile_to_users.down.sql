@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS notification_preferences,
+    DROP COLUMN IF EXISTS display_name;
@@ -0,0 +1,5 @@
+-- Add profile columns to users table
+-- Notification preferences are a JSON object of client-defined settings
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS display_name VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS notification_preferences JSONB NOT NULL DEFAULT '{}';
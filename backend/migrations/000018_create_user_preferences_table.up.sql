@@ -0,0 +1,12 @@
+-- Create user_preferences table
+-- Defaults filled in for device actions that leave them out; unset preferences are NULL
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    default_brightness DOUBLE PRECISION,
+    default_kelvin INTEGER,
+    favorite_hue DOUBLE PRECISION,
+    favorite_saturation DOUBLE PRECISION,
+    wake_kelvin INTEGER,
+    sleep_kelvin INTEGER,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
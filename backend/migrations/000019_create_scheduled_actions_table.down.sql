@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_scheduled_actions_next_run_at;
+DROP INDEX IF EXISTS idx_scheduled_actions_user_id;
+
+-- Drop scheduled_actions table
+DROP TABLE IF EXISTS scheduled_actions;
@@ -0,0 +1,20 @@
+-- Create scheduled_actions table
+-- A scheduled action is a device action executed whenever its cron expression fires
+CREATE TABLE IF NOT EXISTS scheduled_actions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    selector VARCHAR(255) NOT NULL,
+    action_json JSONB NOT NULL,
+    cron_expression VARCHAR(100) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create index for listing a user's schedules
+CREATE INDEX IF NOT EXISTS idx_scheduled_actions_user_id ON scheduled_actions(user_id);
+
+-- Create index for finding the enabled schedules that are due
+CREATE INDEX IF NOT EXISTS idx_scheduled_actions_next_run_at ON scheduled_actions(next_run_at) WHERE enabled = TRUE;
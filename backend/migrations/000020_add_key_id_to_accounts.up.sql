@@ -0,0 +1,4 @@
+-- Add key_id column to accounts table
+-- Names the KMS key a provider token was encrypted with; NULL means the local key
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS key_id VARCHAR(2048);
@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_state_snapshots_user_id_created_at;
+
+-- Drop state_snapshots table
+DROP TABLE IF EXISTS state_snapshots;
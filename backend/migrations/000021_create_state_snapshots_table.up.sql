@@ -0,0 +1,15 @@
+-- Create state_snapshots table
+-- A state snapshot saves the state of the devices targeted by a selector, e.g. before a
+-- demo turns them all off, so that it can be restored afterwards
+CREATE TABLE IF NOT EXISTS state_snapshots (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    selector VARCHAR(255) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    state_json JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create index for listing a user's snapshots, newest first
+CREATE INDEX IF NOT EXISTS idx_state_snapshots_user_id_created_at ON state_snapshots(user_id, created_at DESC);
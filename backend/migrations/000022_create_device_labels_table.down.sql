@@ -0,0 +1,2 @@
+-- Drop device_labels table
+DROP TABLE IF EXISTS device_labels;
@@ -0,0 +1,11 @@
+-- Create device_labels table
+-- A device label is a user's nickname for a device, shown instead of the label the
+-- provider reports
+CREATE TABLE IF NOT EXISTS device_labels (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    device_id VARCHAR(255) NOT NULL,
+    label VARCHAR(100) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, provider, device_id)
+);
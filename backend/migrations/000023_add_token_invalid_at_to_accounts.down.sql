@@ -0,0 +1,2 @@
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS token_invalid_at;
@@ -0,0 +1,4 @@
+-- Add token_invalid_at column to accounts table
+-- Set when the provider rejected the stored token during re-validation; NULL while the token works
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS token_invalid_at TIMESTAMP WITH TIME ZONE;
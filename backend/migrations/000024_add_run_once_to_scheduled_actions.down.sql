@@ -0,0 +1,4 @@
+DELETE FROM scheduled_actions WHERE run_once = TRUE;
+
+ALTER TABLE scheduled_actions
+    DROP COLUMN IF EXISTS run_once;
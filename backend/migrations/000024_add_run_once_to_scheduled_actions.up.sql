@@ -0,0 +1,4 @@
+-- Add run_once column to scheduled_actions table
+-- A one-shot action runs once at next_run_at and is then removed; it has no cron expression
+ALTER TABLE scheduled_actions
+    ADD COLUMN IF NOT EXISTS run_once BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,105 @@
+// Package apierror provides structured API errors that clients can map back to request fields.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes why a single request field is invalid
+type FieldError struct {
+	Field   string `json:"field"`   // JSON name of the field
+	Code    string `json:"code"`    // Machine-readable reason, e.g. "required" or "too_short"
+	Message string `json:"message"` // Human-readable reason
+}
+
+// ValidationError collects every invalid field of a request
+type ValidationError struct {
+	Fields []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + ": " + field.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// validate checks `validate:` struct tags, naming fields after their JSON tag
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+// Validate checks the `validate:` struct tags of req, returning a ValidationError listing
+// every invalid field, or nil when req is valid
+func Validate(req interface{}) *ValidationError {
+	err := validate.Struct(req)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		// Not a struct; there are no tags to check
+		return nil
+	}
+
+	validationErr := &ValidationError{Fields: make([]FieldError, 0, len(fieldErrs))}
+	for _, fieldErr := range fieldErrs {
+		validationErr.Fields = append(validationErr.Fields, newFieldError(fieldErr))
+	}
+	return validationErr
+}
+
+// newFieldError maps a failed validator tag to a client-facing code and message
+func newFieldError(fieldErr validator.FieldError) FieldError {
+	field := FieldError{Field: fieldErr.Field()}
+
+	switch fieldErr.Tag() {
+	case "required":
+		field.Code, field.Message = "required", "is required"
+	case "email":
+		field.Code, field.Message = "invalid_email", "not a valid email"
+	case "min", "gte":
+		field.Code, field.Message = boundError(fieldErr, "too_short", "too_small", "minimum")
+	case "max", "lte":
+		field.Code, field.Message = boundError(fieldErr, "too_long", "too_large", "maximum")
+	case "oneof":
+		field.Code, field.Message = "invalid_value", "must be one of: "+strings.ReplaceAll(fieldErr.Param(), " ", ", ")
+	default:
+		field.Code, field.Message = "invalid", "is invalid"
+	}
+
+	return field
+}
+
+// boundError describes a failed min or max tag. Lengths of strings and collections use
+// lengthCode; numbers use valueCode.
+func boundError(fieldErr validator.FieldError, lengthCode, valueCode, bound string) (string, string) {
+	switch fieldErr.Kind() {
+	case reflect.String:
+		return lengthCode, fmt.Sprintf("%s %s characters", bound, fieldErr.Param())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return lengthCode, fmt.Sprintf("%s %s items", bound, fieldErr.Param())
+	default:
+		return valueCode, fmt.Sprintf("%s %s", bound, fieldErr.Param())
+	}
+}
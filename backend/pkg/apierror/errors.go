@@ -0,0 +1,87 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNotFound is matched via errors.Is by every error reporting a missing resource
+	ErrNotFound = errors.New("not found")
+	// ErrForbidden is matched via errors.Is by every error reporting a resource the caller may not access
+	ErrForbidden = errors.New("forbidden")
+	// ErrConflict is matched via errors.Is by every error reporting a request that conflicts
+	// with the current state of a resource
+	ErrConflict = errors.New("conflict")
+)
+
+// Error is an error of a given kind, such as ErrNotFound, with its own message. It lets
+// packages declare specific sentinels, e.g. "account not found", that still match the kind.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+// New returns an error of the given kind with message
+func New(kind error, message string) error {
+	return &Error{Kind: kind, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the kind of the error
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// BadRequestError reports a request the client must change before retrying
+type BadRequestError struct {
+	Cause   error
+	Message string
+}
+
+func (e *BadRequestError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error, if any
+func (e *BadRequestError) Unwrap() error {
+	return e.Cause
+}
+
+// RateLimitedError reports a request rejected by a rate limit. RetryAfter is how long to
+// wait before retrying, or 0 when unknown.
+type RateLimitedError struct {
+	Cause      error
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.Cause != nil {
+		return e.Cause.Error()
+	}
+	return "rate limit exceeded"
+}
+
+// Unwrap returns the underlying error, if any
+func (e *RateLimitedError) Unwrap() error {
+	return e.Cause
+}
+
+// ProviderError reports a failed call to a lighting provider's API
+type ProviderError struct {
+	Cause    error
+	Provider string
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s provider error: %v", e.Provider, e.Cause)
+}
+
+// Unwrap returns the error reported by the provider
+func (e *ProviderError) Unwrap() error {
+	return e.Cause
+}
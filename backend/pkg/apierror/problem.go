@@ -0,0 +1,84 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentTypeProblemJSON is the media type of ProblemDetails responses
+const ContentTypeProblemJSON = "application/problem+json"
+
+// Problem types identifying the errors clients may want to handle specifically. Errors
+// without a specific type use TypeAboutBlank, meaning the status code says it all.
+const (
+	TypeAboutBlank              = "about:blank"
+	TypeBadRequest              = "https://lightshare.com/errors/bad-request"
+	TypeValidation              = "https://lightshare.com/errors/validation"
+	TypeNotFound                = "https://lightshare.com/errors/not-found"
+	TypeForbidden               = "https://lightshare.com/errors/forbidden"
+	TypeConflict                = "https://lightshare.com/errors/conflict"
+	TypeAccountReadOnly         = "https://lightshare.com/errors/account-read-only"
+	TypeRateLimited             = "https://lightshare.com/errors/rate-limited"
+	TypeProviderRateLimited     = "https://lightshare.com/errors/provider-rate-limited"
+	TypeProviderUnavailable     = "https://lightshare.com/errors/provider-unavailable"
+	TypeProviderError           = "https://lightshare.com/errors/provider-error"
+	TypeCapabilityNotSupported  = "https://lightshare.com/errors/capability-not-supported"
+	TypeOperationNotImplemented = "https://lightshare.com/errors/operation-not-implemented"
+)
+
+// ProblemDetails is an RFC 7807 error response. Extensions are serialized as top-level
+// members alongside the standard ones.
+type ProblemDetails struct {
+	Extensions map[string]interface{} `json:"-"`
+	Type       string                 `json:"type"`
+	Title      string                 `json:"title"`
+	Detail     string                 `json:"detail,omitempty"`
+	Instance   string                 `json:"instance,omitempty"`
+	Status     int                    `json:"status"`
+}
+
+// NewProblem returns a problem of the given type and status. The title is the status text.
+func NewProblem(problemType string, status int, detail string) *ProblemDetails {
+	return &ProblemDetails{
+		Type:   problemType,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// With sets an extension member and returns the problem
+func (p *ProblemDetails) With(key string, value interface{}) *ProblemDetails {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// Error returns the detail, so that a problem can be returned from a handler as is
+func (p *ProblemDetails) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// MarshalJSON writes the extensions next to the standard members. Extensions never
+// override a standard member.
+func (p *ProblemDetails) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+	members["type"] = p.Type
+	members["title"] = p.Title
+	members["status"] = p.Status
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+	return json.Marshal(members)
+}
@@ -0,0 +1,177 @@
+// Package colorconv converts colors between the forms lighting APIs use: hue, saturation
+// and brightness, RGB, hex and color temperature. It has no dependencies, so both the
+// providers package and every provider adapter can share it.
+package colorconv
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidHexColor is returned when a hex color is not in #RGB or #RRGGBB form
+var ErrInvalidHexColor = errors.New("invalid hex color")
+
+// HSBToUnitRGB converts hue (degrees, wrapped into 0-360), saturation and brightness
+// (both clamped to 0.0-1.0) to RGB channels in 0.0-1.0
+func HSBToUnitRGB(hue, saturation, brightness float64) (r, g, b float64) {
+	hue = WrapHue(hue)
+	saturation = ClampUnit(saturation)
+	brightness = ClampUnit(brightness)
+
+	c := brightness * saturation
+	x := c * (1 - math.Abs(math.Mod(hue/60, 2)-1))
+	m := brightness - c
+
+	switch {
+	case hue < 60:
+		r, g, b = c, x, 0
+	case hue < 120:
+		r, g, b = x, c, 0
+	case hue < 180:
+		r, g, b = 0, c, x
+	case hue < 240:
+		r, g, b = 0, x, c
+	case hue < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return r + m, g + m, b + m
+}
+
+// UnitRGBToHSB converts RGB channels in 0.0-1.0 to hue (0-360), saturation (0.0-1.0) and
+// brightness (0.0-1.0). Grays, which have no hue, report a hue of 0.
+func UnitRGBToHSB(r, g, b float64) (hue, saturation, brightness float64) {
+	peak := math.Max(r, math.Max(g, b))
+	low := math.Min(r, math.Min(g, b))
+	delta := peak - low
+
+	switch {
+	case delta == 0:
+		hue = 0
+	case peak == r:
+		hue = 60 * math.Mod((g-b)/delta, 6)
+	case peak == g:
+		hue = 60 * ((b-r)/delta + 2)
+	default:
+		hue = 60 * ((r-g)/delta + 4)
+	}
+	if hue < 0 {
+		hue += 360
+	}
+
+	if peak > 0 {
+		saturation = delta / peak
+	}
+	return hue, saturation, peak
+}
+
+// RGBToHSB converts an 8-bit RGB color to hue (0-360), saturation (0.0-1.0) and
+// brightness (0.0-1.0)
+func RGBToHSB(r, g, b uint8) (hue, saturation, brightness float64) {
+	return UnitRGBToHSB(float64(r)/255, float64(g)/255, float64(b)/255)
+}
+
+// HSBToRGB converts hue (degrees, wrapped into 0-360), saturation and brightness
+// (both clamped to 0.0-1.0) to an 8-bit RGB color
+func HSBToRGB(hue, saturation, brightness float64) (r, g, b uint8) {
+	rf, gf, bf := HSBToUnitRGB(hue, saturation, brightness)
+	return toByte(rf), toByte(gf), toByte(bf)
+}
+
+// HueSaturationToChannels converts a hue and saturation at full brightness to the 0-255
+// RGB channels of lights that take their brightness separately
+func HueSaturationToChannels(hue, saturation float64) (r, g, b int) {
+	rb, gb, bb := HSBToRGB(hue, saturation, 1)
+	return int(rb), int(gb), int(bb)
+}
+
+// ChannelsToHueSaturation converts the 0-255 RGB channels a light reports to its hue
+// (0-360, to 0.01 degree) and saturation (0.0-1.0, to 0.001). Channels out of range are
+// clamped.
+func ChannelsToHueSaturation(r, g, b int) (hue, saturation float64) {
+	hue, saturation, _ = RGBToHSB(channelByte(r), channelByte(g), channelByte(b))
+	return math.Round(hue*100) / 100, math.Round(saturation*1000) / 1000
+}
+
+// HexToHSB parses a #RRGGBB or #RGB color, with or without the leading '#', and
+// converts it to hue, saturation and brightness
+func HexToHSB(hex string) (hue, saturation, brightness float64, err error) {
+	digits := strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	if len(digits) != 6 {
+		return 0, 0, 0, fmt.Errorf("%w: %q", ErrInvalidHexColor, hex)
+	}
+
+	value, parseErr := strconv.ParseUint(digits, 16, 32)
+	if parseErr != nil {
+		return 0, 0, 0, fmt.Errorf("%w: %q", ErrInvalidHexColor, hex)
+	}
+
+	hue, saturation, brightness = RGBToHSB(uint8(value>>16), uint8(value>>8), uint8(value))
+	return hue, saturation, brightness, nil
+}
+
+// HSBToHex converts hue, saturation and brightness to an uppercase #RRGGBB color
+func HSBToHex(hue, saturation, brightness float64) string {
+	r, g, b := HSBToRGB(hue, saturation, brightness)
+	return fmt.Sprintf("#%02X%02X%02X", r, g, b)
+}
+
+// KelvinToMireds converts a color temperature in kelvin to mireds (micro reciprocal
+// degrees). Non-positive temperatures convert to 0.
+func KelvinToMireds(kelvin int) int {
+	if kelvin <= 0 {
+		return 0
+	}
+	return int(math.Round(1_000_000 / float64(kelvin)))
+}
+
+// MiredsToKelvin converts mireds to a color temperature in kelvin. Non-positive
+// mireds convert to 0.
+func MiredsToKelvin(mireds int) int {
+	if mireds <= 0 {
+		return 0
+	}
+	return int(math.Round(1_000_000 / float64(mireds)))
+}
+
+// Interpolate returns the value a fraction t (clamped to 0.0-1.0) of the way from one
+// value to another
+func Interpolate(from, to, t float64) float64 {
+	return from + (to-from)*ClampUnit(t)
+}
+
+// InterpolateHue returns the hue a fraction t (clamped to 0.0-1.0) of the way from one hue
+// to another, going the short way around the color wheel
+func InterpolateHue(from, to, t float64) float64 {
+	delta := math.Mod(WrapHue(to)-WrapHue(from)+540, 360) - 180
+	return WrapHue(from + delta*ClampUnit(t))
+}
+
+// WrapHue wraps a hue in degrees into 0-360
+func WrapHue(hue float64) float64 {
+	hue = math.Mod(hue, 360)
+	if hue < 0 {
+		hue += 360
+	}
+	return hue
+}
+
+// ClampUnit clamps v to 0.0-1.0
+func ClampUnit(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+func toByte(v float64) uint8 {
+	return uint8(math.Round(ClampUnit(v) * 255))
+}
+
+func channelByte(v int) uint8 {
+	return uint8(max(0, min(255, v)))
+}
@@ -0,0 +1,202 @@
+package colorconv
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 0.01
+}
+
+func TestRGBToHSB(t *testing.T) {
+	tests := []struct {
+		name                        string
+		r, g, b                     uint8
+		hue, saturation, brightness float64
+	}{
+		{"black", 0, 0, 0, 0, 0, 0},
+		{"white", 255, 255, 255, 0, 0, 1},
+		{"gray", 128, 128, 128, 0, 0, 128.0 / 255},
+		{"red", 255, 0, 0, 0, 1, 1},
+		{"green", 0, 255, 0, 120, 1, 1},
+		{"blue", 0, 0, 255, 240, 1, 1},
+		{"yellow", 255, 255, 0, 60, 1, 1},
+		{"cyan", 0, 255, 255, 180, 1, 1},
+		{"magenta", 255, 0, 255, 300, 1, 1},
+		{"orange", 255, 128, 0, 30.12, 1, 1},
+		{"near red wraps below 360", 255, 0, 1, 359.76, 1, 1},
+		{"dark red", 128, 0, 0, 0, 1, 128.0 / 255},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hue, saturation, brightness := RGBToHSB(tt.r, tt.g, tt.b)
+			if !closeTo(hue, tt.hue) || !closeTo(saturation, tt.saturation) || !closeTo(brightness, tt.brightness) {
+				t.Errorf("Expected (%.2f, %.2f, %.2f), got (%.2f, %.2f, %.2f)",
+					tt.hue, tt.saturation, tt.brightness, hue, saturation, brightness)
+			}
+		})
+	}
+}
+
+func TestHSBToRGB(t *testing.T) {
+	tests := []struct {
+		name                        string
+		hue, saturation, brightness float64
+		r, g, b                     uint8
+	}{
+		{"black", 0, 0, 0, 0, 0, 0},
+		{"white", 0, 0, 1, 255, 255, 255},
+		{"red", 0, 1, 1, 255, 0, 0},
+		{"red at 360", 360, 1, 1, 255, 0, 0},
+		{"green", 120, 1, 1, 0, 255, 0},
+		{"blue", 240, 1, 1, 0, 0, 255},
+		{"negative hue wraps", -120, 1, 1, 0, 0, 255},
+		{"half brightness", 0, 1, 0.5, 128, 0, 0},
+		{"saturation clamped", 0, 2, 1, 255, 0, 0},
+		{"brightness clamped", 0, 0, -1, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, g, b := HSBToRGB(tt.hue, tt.saturation, tt.brightness)
+			if r != tt.r || g != tt.g || b != tt.b {
+				t.Errorf("Expected (%d, %d, %d), got (%d, %d, %d)", tt.r, tt.g, tt.b, r, g, b)
+			}
+		})
+	}
+}
+
+func TestHSBRoundTrip(t *testing.T) {
+	for _, rgb := range [][3]uint8{{0, 0, 0}, {255, 255, 255}, {255, 128, 0}, {12, 34, 56}, {1, 2, 3}, {254, 1, 127}} {
+		hue, saturation, brightness := RGBToHSB(rgb[0], rgb[1], rgb[2])
+		r, g, b := HSBToRGB(hue, saturation, brightness)
+		if r != rgb[0] || g != rgb[1] || b != rgb[2] {
+			t.Errorf("Expected %v to round trip, got (%d, %d, %d)", rgb, r, g, b)
+		}
+	}
+}
+
+func TestHexToHSB(t *testing.T) {
+	tests := []struct {
+		name                        string
+		hex                         string
+		hue, saturation, brightness float64
+		wantErr                     bool
+	}{
+		{"red with hash", "#FF0000", 0, 1, 1, false},
+		{"green without hash", "00FF00", 120, 1, 1, false},
+		{"blue lowercase", "#0000ff", 240, 1, 1, false},
+		{"white short form", "#FFF", 0, 0, 1, false},
+		{"black", "000000", 0, 0, 0, false},
+		{"orange", "FF8000", 30.12, 1, 1, false},
+		{"empty", "", 0, 0, 0, true},
+		{"too long", "#FF00000", 0, 0, 0, true},
+		{"not hex", "#GG0000", 0, 0, 0, true},
+		{"signed", "+F0000", 0, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hue, saturation, brightness, err := HexToHSB(tt.hex)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidHexColor) {
+					t.Errorf("Expected ErrInvalidHexColor, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("HexToHSB failed: %v", err)
+			}
+			if !closeTo(hue, tt.hue) || !closeTo(saturation, tt.saturation) || !closeTo(brightness, tt.brightness) {
+				t.Errorf("Expected (%.2f, %.2f, %.2f), got (%.2f, %.2f, %.2f)",
+					tt.hue, tt.saturation, tt.brightness, hue, saturation, brightness)
+			}
+		})
+	}
+}
+
+func TestHSBToHex(t *testing.T) {
+	tests := []struct {
+		hue, saturation, brightness float64
+		want                        string
+	}{
+		{0, 1, 1, "#FF0000"},
+		{120, 1, 1, "#00FF00"},
+		{240, 1, 1, "#0000FF"},
+		{0, 0, 1, "#FFFFFF"},
+		{0, 0, 0, "#000000"},
+		{30.12, 1, 1, "#FF8000"},
+	}
+
+	for _, tt := range tests {
+		if got := HSBToHex(tt.hue, tt.saturation, tt.brightness); got != tt.want {
+			t.Errorf("HSBToHex(%v, %v, %v): expected %s, got %s", tt.hue, tt.saturation, tt.brightness, tt.want, got)
+		}
+	}
+}
+
+func TestKelvinMiredsConversion(t *testing.T) {
+	tests := []struct {
+		kelvin, mireds int
+	}{
+		{2000, 500},
+		{2700, 370},
+		{6500, 154},
+		{1_000_000, 1},
+		{0, 0},
+		{-100, 0},
+	}
+
+	for _, tt := range tests {
+		if got := KelvinToMireds(tt.kelvin); got != tt.mireds {
+			t.Errorf("KelvinToMireds(%d): expected %d, got %d", tt.kelvin, tt.mireds, got)
+		}
+	}
+
+	for _, tt := range []struct{ mireds, kelvin int }{{500, 2000}, {153, 6536}, {370, 2703}, {0, 0}, {-1, 0}} {
+		if got := MiredsToKelvin(tt.mireds); got != tt.kelvin {
+			t.Errorf("MiredsToKelvin(%d): expected %d, got %d", tt.mireds, tt.kelvin, got)
+		}
+	}
+}
+
+func TestInterpolateHue(t *testing.T) {
+	tests := []struct {
+		from, to, t, want float64
+	}{
+		{0, 120, 0.5, 60},
+		{120, 240, 0.25, 150},
+		{350, 10, 0.5, 0},  // Across 0, not through 180
+		{10, 350, 0.25, 5}, // Backwards across 0
+		{0, 120, 1.5, 120}, // t is clamped
+		{0, 120, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := InterpolateHue(tt.from, tt.to, tt.t); !closeTo(got, tt.want) {
+			t.Errorf("InterpolateHue(%v, %v, %v): expected %v, got %v", tt.from, tt.to, tt.t, tt.want, got)
+		}
+	}
+
+	if got := Interpolate(0.2, 1, 0.5); !closeTo(got, 0.6) {
+		t.Errorf("Interpolate(0.2, 1, 0.5): expected 0.6, got %v", got)
+	}
+}
+
+func TestChannelsRoundTrip(t *testing.T) {
+	for _, hue := range []float64{0, 60, 120, 180, 240, 300} {
+		r, g, b := HueSaturationToChannels(hue, 1)
+		gotHue, gotSaturation := ChannelsToHueSaturation(r, g, b)
+		if gotHue != hue || gotSaturation != 1 {
+			t.Errorf("Expected hue %v at full saturation, got %v at %v", hue, gotHue, gotSaturation)
+		}
+	}
+
+	// Out of range channels are clamped
+	if hue, saturation := ChannelsToHueSaturation(300, -20, 0); hue != 0 || saturation != 1 {
+		t.Errorf("Expected red at full saturation, got %v at %v", hue, saturation)
+	}
+}
@@ -1,16 +1,44 @@
 package crypto
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
 	"os"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
 )
 
-// LoadEncryptionKey loads the encryption key from environment variable
-// For local development, expects ENCRYPTION_KEY to be a 64-character hex string (32 bytes)
-// In production, this would integrate with AWS KMS or similar
-func LoadEncryptionKey() ([]byte, error) {
+// LoadKeyProvider returns the key provider new provider tokens are encrypted with, selected
+// by ENCRYPTION_PROVIDER: "local" (the default) uses ENCRYPTION_KEY, and "kms" the AWS KMS
+// key KMS_KEY_ID, with credentials and region from the default AWS configuration
+func LoadKeyProvider() (KeyProvider, error) {
+	switch provider := os.Getenv("ENCRYPTION_PROVIDER"); provider {
+	case "", "local":
+		return LoadLocalKeyProvider()
+	case "kms":
+		keyID := os.Getenv("KMS_KEY_ID")
+		if keyID == "" {
+			return nil, fmt.Errorf("KMS_KEY_ID environment variable not set")
+		}
+
+		cfg, err := config.LoadDefaultConfig(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+		}
+
+		return NewAWSKMSProvider(kms.NewFromConfig(cfg), keyID), nil
+	default:
+		return nil, fmt.Errorf("unknown ENCRYPTION_PROVIDER %q: must be local or kms", provider)
+	}
+}
+
+// LoadLocalKeyProvider loads the local key from environment variable
+// Expects ENCRYPTION_KEY to be a 64-character hex string (32 bytes). The local key is
+// required with either ENCRYPTION_PROVIDER: it decrypts tokens stored without a key ID.
+func LoadLocalKeyProvider() (*LocalKeyProvider, error) {
 	keyHex := os.Getenv("ENCRYPTION_KEY")
 	if keyHex == "" {
 		return nil, fmt.Errorf("ENCRYPTION_KEY environment variable not set")
@@ -25,7 +53,7 @@ func LoadEncryptionKey() ([]byte, error) {
 		return nil, fmt.Errorf("encryption key must be 32 bytes (64 hex chars), got %d bytes", len(key))
 	}
 
-	return key, nil
+	return NewLocalKeyProvider(key)
 }
 
 // GenerateEncryptionKey generates a new random 32-byte encryption key
@@ -0,0 +1,142 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// kmsTimeout bounds each call to AWS KMS
+const kmsTimeout = 5 * time.Second
+
+// kmsSeparator separates the key ID from the ciphertext in tokens encrypted with KMS
+const kmsSeparator = '|'
+
+// ErrKeyMismatch is returned when a token is decrypted with a key ID other than the one
+// it was encrypted with
+var ErrKeyMismatch = errors.New("token was encrypted with a different key")
+
+// KeyProvider encrypts and decrypts provider tokens. The key ID returned with a ciphertext
+// names the key it was encrypted with and must be stored alongside it; an empty key ID
+// means the local key.
+type KeyProvider interface {
+	EncryptToken(plaintext string) (ciphertext []byte, keyID string, err error)
+	DecryptToken(ciphertext []byte, keyID string) (string, error)
+}
+
+// LocalKeyProvider encrypts tokens with AES-256-GCM under a 32-byte key held in memory.
+// Its tokens have no key ID, so rotating the key means re-encrypting every token.
+type LocalKeyProvider struct {
+	key []byte
+}
+
+// NewLocalKeyProvider creates a key provider for a 32-byte key
+func NewLocalKeyProvider(key []byte) (*LocalKeyProvider, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	return &LocalKeyProvider{key: key}, nil
+}
+
+// EncryptToken encrypts plaintext with the local key; the key ID is always empty
+func (p *LocalKeyProvider) EncryptToken(plaintext string) ([]byte, string, error) {
+	ciphertext, err := EncryptToken(plaintext, p.key)
+	return ciphertext, "", err
+}
+
+// DecryptToken decrypts a token encrypted with the local key, which has no key ID
+func (p *LocalKeyProvider) DecryptToken(ciphertext []byte, keyID string) (string, error) {
+	if keyID != "" {
+		return "", fmt.Errorf("%w: %s is not the local key", ErrKeyMismatch, keyID)
+	}
+	return DecryptToken(ciphertext, p.key)
+}
+
+// KMSAPI is the part of the AWS KMS client AWSKMSProvider uses
+type KMSAPI interface {
+	Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// AWSKMSProvider encrypts tokens with an AWS KMS key. Ciphertexts are stored as
+// "keyID|base64(ciphertext)", so a token can still be decrypted after the configured key
+// changes, as long as its own key is enabled.
+type AWSKMSProvider struct {
+	client KMSAPI
+	keyID  string
+}
+
+// NewAWSKMSProvider creates a key provider encrypting with the KMS key keyID, which may be
+// a key ID, key ARN or alias
+func NewAWSKMSProvider(client KMSAPI, keyID string) *AWSKMSProvider {
+	return &AWSKMSProvider{client: client, keyID: keyID}
+}
+
+// EncryptToken encrypts plaintext with the configured KMS key, returning the ARN of the
+// key as the key ID
+func (p *AWSKMSProvider) EncryptToken(plaintext string) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), kmsTimeout)
+	defer cancel()
+
+	out, err := p.client.Encrypt(ctx, &kms.EncryptInput{
+		KeyId:     aws.String(p.keyID),
+		Plaintext: []byte(plaintext),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encrypt with KMS: %w", err)
+	}
+
+	keyID := p.keyID
+	if out.KeyId != nil {
+		keyID = *out.KeyId
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(out.CiphertextBlob)
+	return []byte(keyID + string(kmsSeparator) + encoded), keyID, nil
+}
+
+// DecryptToken decrypts a token encrypted with the KMS key keyID
+func (p *AWSKMSProvider) DecryptToken(ciphertext []byte, keyID string) (string, error) {
+	storedKeyID, blob, err := parseKMSCiphertext(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if storedKeyID != keyID {
+		return "", fmt.Errorf("%w: expected %s, got %s", ErrKeyMismatch, keyID, storedKeyID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), kmsTimeout)
+	defer cancel()
+
+	out, err := p.client.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:          aws.String(storedKeyID),
+		CiphertextBlob: blob,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt with KMS: %w", err)
+	}
+
+	return string(out.Plaintext), nil
+}
+
+// parseKMSCiphertext splits a token encrypted with KMS into its key ID and ciphertext blob
+func parseKMSCiphertext(ciphertext []byte) (string, []byte, error) {
+	// Base64 never contains the separator, so the last one ends the key ID
+	i := bytes.LastIndexByte(ciphertext, kmsSeparator)
+	if i <= 0 {
+		return "", nil, fmt.Errorf("malformed KMS ciphertext")
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(string(ciphertext[i+1:]))
+	if err != nil {
+		return "", nil, fmt.Errorf("malformed KMS ciphertext: %w", err)
+	}
+
+	return string(ciphertext[:i]), blob, nil
+}
@@ -0,0 +1,203 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// fakeKMS "encrypts" by prefixing the plaintext with the key ARN
+type fakeKMS struct {
+	keyARNs map[string]string // key ID or alias -> key ARN
+}
+
+func (f *fakeKMS) Encrypt(_ context.Context, params *kms.EncryptInput, _ ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+	arn, ok := f.keyARNs[aws.ToString(params.KeyId)]
+	if !ok {
+		return nil, errors.New("NotFoundException: key does not exist")
+	}
+	return &kms.EncryptOutput{
+		KeyId:          aws.String(arn),
+		CiphertextBlob: append([]byte(arn+":"), params.Plaintext...),
+	}, nil
+}
+
+func (f *fakeKMS) Decrypt(_ context.Context, params *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	arn := aws.ToString(params.KeyId)
+	plaintext, ok := bytes.CutPrefix(params.CiphertextBlob, []byte(arn+":"))
+	if !ok {
+		return nil, errors.New("IncorrectKeyException: ciphertext was encrypted with another key")
+	}
+	return &kms.DecryptOutput{KeyId: aws.String(arn), Plaintext: plaintext}, nil
+}
+
+const (
+	testKeyARN        = "arn:aws:kms:eu-west-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	testRotatedKeyARN = "arn:aws:kms:eu-west-1:111122223333:key/0987dcba-09fe-87dc-65ba-ab0987654321"
+)
+
+func newFakeKMS() *fakeKMS {
+	return &fakeKMS{keyARNs: map[string]string{
+		"alias/lightshare": testKeyARN,
+		testKeyARN:         testKeyARN,
+		testRotatedKeyARN:  testRotatedKeyARN,
+	}}
+}
+
+func TestAWSKMSProvider_RoundTrip(t *testing.T) {
+	provider := NewAWSKMSProvider(newFakeKMS(), "alias/lightshare")
+
+	ciphertext, keyID, err := provider.EncryptToken("my-secret-token")
+	if err != nil {
+		t.Fatalf("EncryptToken failed: %v", err)
+	}
+	if keyID != testKeyARN {
+		t.Errorf("Expected the key ARN as key ID, got %q", keyID)
+	}
+	if !strings.HasPrefix(string(ciphertext), testKeyARN+"|") {
+		t.Errorf("Expected the ciphertext to start with the key ID, got %q", ciphertext)
+	}
+
+	plaintext, err := provider.DecryptToken(ciphertext, keyID)
+	if err != nil {
+		t.Fatalf("DecryptToken failed: %v", err)
+	}
+	if plaintext != "my-secret-token" {
+		t.Errorf("Expected the original token, got %q", plaintext)
+	}
+}
+
+func TestAWSKMSProvider_DecryptsAfterKeyRotation(t *testing.T) {
+	kmsClient := newFakeKMS()
+	ciphertext, keyID, err := NewAWSKMSProvider(kmsClient, testKeyARN).EncryptToken("my-secret-token")
+	if err != nil {
+		t.Fatalf("EncryptToken failed: %v", err)
+	}
+
+	// Tokens name their own key, so they outlive a change of the configured key
+	rotated := NewAWSKMSProvider(kmsClient, testRotatedKeyARN)
+	plaintext, err := rotated.DecryptToken(ciphertext, keyID)
+	if err != nil {
+		t.Fatalf("DecryptToken failed: %v", err)
+	}
+	if plaintext != "my-secret-token" {
+		t.Errorf("Expected the original token, got %q", plaintext)
+	}
+}
+
+func TestAWSKMSProvider_DecryptErrors(t *testing.T) {
+	provider := NewAWSKMSProvider(newFakeKMS(), testKeyARN)
+	ciphertext, keyID, err := provider.EncryptToken("my-secret-token")
+	if err != nil {
+		t.Fatalf("EncryptToken failed: %v", err)
+	}
+
+	testCases := []struct {
+		wantErr    error
+		name       string
+		keyID      string
+		ciphertext []byte
+	}{
+		{
+			name:       "key ID of another key",
+			ciphertext: ciphertext,
+			keyID:      testRotatedKeyARN,
+			wantErr:    ErrKeyMismatch,
+		},
+		{
+			name:       "no key ID in ciphertext",
+			ciphertext: []byte("bm90LWVuY3J5cHRlZA=="),
+			keyID:      keyID,
+		},
+		{
+			name:       "ciphertext not base64",
+			ciphertext: []byte(testKeyARN + "|not base64!"),
+			keyID:      keyID,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := provider.DecryptToken(tc.ciphertext, tc.keyID)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Errorf("Expected %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLocalKeyProvider(t *testing.T) {
+	provider, err := NewLocalKeyProvider([]byte("12345678901234567890123456789012"))
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider failed: %v", err)
+	}
+
+	ciphertext, keyID, err := provider.EncryptToken("my-secret-token")
+	if err != nil {
+		t.Fatalf("EncryptToken failed: %v", err)
+	}
+	if keyID != "" {
+		t.Errorf("Expected no key ID, got %q", keyID)
+	}
+
+	plaintext, err := provider.DecryptToken(ciphertext, "")
+	if err != nil || plaintext != "my-secret-token" {
+		t.Errorf("Expected the original token, got %q (%v)", plaintext, err)
+	}
+
+	if _, err := provider.DecryptToken(ciphertext, testKeyARN); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("Expected ErrKeyMismatch for a KMS key ID, got %v", err)
+	}
+
+	if _, err := NewLocalKeyProvider([]byte("short")); err == nil {
+		t.Error("Expected error for a short key, got nil")
+	}
+}
+
+func TestLoadKeyProvider(t *testing.T) {
+	testCases := []struct {
+		name     string
+		provider string
+		kmsKeyID string
+		wantErr  bool
+	}{
+		{name: "local by default"},
+		{name: "local", provider: "local"},
+		{name: "kms", provider: "kms", kmsKeyID: "alias/lightshare"},
+		{name: "kms without a key", provider: "kms", wantErr: true},
+		{name: "unknown provider", provider: "vault", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("ENCRYPTION_KEY", "3132333435363738393031323334353637383930313233343536373839303132")
+			t.Setenv("ENCRYPTION_PROVIDER", tc.provider)
+			t.Setenv("KMS_KEY_ID", tc.kmsKeyID)
+			t.Setenv("AWS_REGION", "eu-west-1")
+
+			provider, err := LoadKeyProvider()
+			if tc.wantErr {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadKeyProvider failed: %v", err)
+			}
+
+			_, isKMS := provider.(*AWSKMSProvider)
+			if isKMS != (tc.provider == "kms") {
+				t.Errorf("Expected a %q provider, got %T", tc.provider, provider)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+// Package ctxutil carries request-scoped values, such as the request ID, through contexts
+package ctxutil
+
+import "context"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the ID of the request being served
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromCtx returns the request ID carried by ctx, or "" if there is none
+func RequestIDFromCtx(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file" // file:// migration source
+	"github.com/jmoiron/sqlx"
+)
+
+// RunMigrations applies every pending migration in migrationsDir to db. A schema that is
+// already up to date is not an error.
+func RunMigrations(db *sqlx.DB, migrationsDir string) error {
+	m, err := NewMigrator(db, migrationsDir)
+	if err != nil {
+		return err
+	}
+	defer func() { _, _ = m.Close() }()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	return nil
+}
+
+// NewMigrator returns a migrator of db using the migrations in migrationsDir. It holds one
+// connection of db, released by its Close; db itself stays open.
+func NewMigrator(db *sqlx.DB, migrationsDir string) (*migrate.Migrate, error) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration connection: %w", err)
+	}
+
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to create migration driver: %w", err)
+	}
+
+	m, err := migrate.NewWithDatabaseInstance("file://"+migrationsDir, "postgres", driver)
+	if err != nil {
+		_ = driver.Close()
+		return nil, fmt.Errorf("failed to load migrations from %s: %w", migrationsDir, err)
+	}
+	return m, nil
+}
@@ -0,0 +1,113 @@
+//go:build integration
+
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+const migrationsDir = "../../migrations"
+
+// newMigrationDB starts an empty PostgreSQL container for the test
+func newMigrationDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	if os.Getenv("TEST_INTEGRATION") != "true" {
+		t.Skip("Set TEST_INTEGRATION=true to run the integration tests")
+	}
+
+	ctx := context.Background()
+	container, err := postgres.Run(ctx, "postgres:15-alpine",
+		postgres.WithDatabase("lightshare_migrate"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		postgres.BasicWaitStrategies(),
+	)
+	if err != nil {
+		t.Fatalf("Failed to start postgres container: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := testcontainers.TerminateContainer(container); err != nil {
+			t.Errorf("Failed to terminate postgres container: %v", err)
+		}
+	})
+
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to get postgres connection string: %v", err)
+	}
+	db, err := New(Config{URL: dsn, MaxOpenConns: 5, MaxIdleConns: 5})
+	if err != nil {
+		t.Fatalf("Failed to connect to postgres: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db.DB
+}
+
+// latestMigrationVersion returns the version of the newest migration file
+func latestMigrationVersion(t *testing.T) uint {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(migrationsDir, "*.up.sql"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Failed to list migrations: %v", err)
+	}
+
+	var latest uint
+	for _, file := range files {
+		prefix, _, _ := strings.Cut(filepath.Base(file), "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			t.Fatalf("Failed to parse version of %s: %v", file, err)
+		}
+		latest = max(latest, uint(version))
+	}
+	return latest
+}
+
+func TestRunMigrations(t *testing.T) {
+	db := newMigrationDB(t)
+	latest := latestMigrationVersion(t)
+
+	if err := RunMigrations(db, migrationsDir); err != nil {
+		t.Fatalf("RunMigrations failed: %v", err)
+	}
+	// An up to date schema is not an error
+	if err := RunMigrations(db, migrationsDir); err != nil {
+		t.Fatalf("RunMigrations on an up to date schema failed: %v", err)
+	}
+
+	m, err := NewMigrator(db, migrationsDir)
+	if err != nil {
+		t.Fatalf("NewMigrator failed: %v", err)
+	}
+	defer func() { _, _ = m.Close() }()
+
+	version, dirty, err := m.Version()
+	if err != nil || dirty || version != latest {
+		t.Fatalf("Expected clean version %d, got %d (dirty %t, error %v)", latest, version, dirty, err)
+	}
+
+	if err := m.Steps(-1); err != nil {
+		t.Fatalf("Failed to roll back one migration: %v", err)
+	}
+	// Migrations are numbered sequentially
+	if version, _, err = m.Version(); err != nil || version != latest-1 {
+		t.Errorf("Expected version %d, got %d (error %v)", latest-1, version, err)
+	}
+
+	// The database stays usable once the migrator is closed
+	if _, err := m.Close(); err != nil {
+		t.Errorf("Failed to close migrator: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Errorf("Expected the database to stay open, got %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"html/template"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/gomail.v2"
 )
@@ -172,8 +173,8 @@ func (s *Service) SendMagicLinkEmail(to, token string) error {
 	})
 }
 
-// SendPasswordResetEmail sends a password reset email
-func (s *Service) SendPasswordResetEmail(to, token string) error {
+// PasswordResetMessage builds the email carrying a password reset link
+func (s *Service) PasswordResetMessage(to, token string) (Message, error) {
 	resetURL := fmt.Sprintf("%s/reset-password?token=%s", s.config.BaseURL, token)
 
 	tmpl := getEmailTemplate(
@@ -184,18 +185,161 @@ func (s *Service) SendPasswordResetEmail(to, token string) error {
 	)
 
 	body, err := s.renderEmailTemplate("reset", tmpl, map[string]string{"URL": resetURL})
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      to,
+		Subject: "Reset your LightShare password",
+		Body:    body,
+		IsHTML:  true,
+	}, nil
+}
+
+// SendTokenInvalidEmail tells the owner of a connected account that its provider rejected
+// the stored token, so the account must be reconnected
+func (s *Service) SendTokenInvalidEmail(to, providerName, accountLabel string) error {
+	accountsURL := fmt.Sprintf("%s://accounts", s.config.MobileDeepLinkScheme)
+
+	// The provider name and label are template data, so they are escaped when rendered
+	tmpl := getEmailTemplate(
+		"Reconnect Your {{.Provider}} Account",
+		"Reconnect Account",
+		"{{.Provider}} no longer accepts the access token of your account <strong>{{.Label}}</strong>, so LightShare can't control its lights. This happens when the token is revoked or your {{.Provider}} password is reset. Reconnect the account with a new token to restore access:",
+		"Lights shared from this account stay unavailable until it is reconnected.",
+	)
+
+	body, err := s.renderEmailTemplate("token-invalid", tmpl, map[string]string{
+		"URL":      accountsURL,
+		"Provider": providerName,
+		"Label":    accountLabel,
+	})
 	if err != nil {
 		return err
 	}
 
 	return s.Send(Message{
 		To:      to,
-		Subject: "Reset your LightShare password",
+		Subject: fmt.Sprintf("Reconnect your %s account to LightShare", providerName),
 		Body:    body,
 		IsHTML:  true,
 	})
 }
 
+// EmailChangeVerificationMessage builds the email asking a user to verify their new address
+func (s *Service) EmailChangeVerificationMessage(to, token string) (Message, error) {
+	verificationURL := fmt.Sprintf("%s://change-email?token=%s", s.config.MobileDeepLinkScheme, token)
+
+	tmpl := getEmailTemplate(
+		"Confirm Your New Email",
+		"Confirm Email",
+		"You requested to change the email address of your LightShare account to this one. Please confirm it by clicking the button below:",
+		"This link will expire in 24 hours. If you didn't request this change, you can safely ignore this email.",
+	)
+
+	body, err := s.renderEmailTemplate("change-email", tmpl, map[string]string{"URL": verificationURL})
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      to,
+		Subject: "Confirm your new LightShare email",
+		Body:    body,
+		IsHTML:  true,
+	}, nil
+}
+
+// EmailChangeNoticeMessage builds the security notice sent to a user's current address when
+// a change to newEmail is requested
+func (s *Service) EmailChangeNoticeMessage(to, newEmail string) Message {
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Email change requested</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2563eb;">Email change requested</h1>
+        <p>A request was made to change the email address of your LightShare account to <strong>%s</strong>. The change takes effect once the new address is confirmed.</p>
+        <p style="color: #666; font-size: 14px;">
+            If you didn't request this change, reset your password right away.
+        </p>
+    </div>
+</body>
+</html>
+`, template.HTMLEscapeString(newEmail))
+
+	return Message{
+		To:      to,
+		Subject: "Your LightShare email is being changed",
+		Body:    body,
+		IsHTML:  true,
+	}
+}
+
+// AccountLockedMessage builds the security alert sent when an account is locked after
+// repeated failed logins
+func (s *Service) AccountLockedMessage(to string, lockedUntil time.Time) Message {
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Account locked</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2563eb;">Account locked</h1>
+        <p>There were several failed attempts to sign in to your LightShare account, so sign-in with a password is blocked until %s.</p>
+        <p style="color: #666; font-size: 14px;">
+            If this wasn't you, someone may be trying to guess your password. Consider resetting it once the lock expires.
+        </p>
+    </div>
+</body>
+</html>
+`, lockedUntil.UTC().Format("January 2, 2006 15:04 MST"))
+
+	return Message{
+		To:      to,
+		Subject: "Your LightShare account was locked",
+		Body:    body,
+		IsHTML:  true,
+	}
+}
+
+// TestEmailMessage builds a benign test message used to confirm email delivery works
+func (s *Service) TestEmailMessage(to string) Message {
+	body := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>LightShare test email</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2563eb;">This is a test</h1>
+        <p>You requested a test email from LightShare. If you are reading this, email delivery to your address works.</p>
+        <p style="color: #666; font-size: 14px;">
+            No action is required.
+        </p>
+    </div>
+</body>
+</html>
+`
+
+	return Message{
+		To:      to,
+		Subject: "LightShare test email",
+		Body:    body,
+		IsHTML:  true,
+	}
+}
+
 // ValidateEmail performs basic email validation
 func ValidateEmail(email string) bool {
 	email = strings.TrimSpace(strings.ToLower(email))
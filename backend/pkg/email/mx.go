@@ -0,0 +1,111 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MXResolver looks up mail exchanger records for a domain
+type MXResolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// mxCacheEntry is a cached MX lookup result
+type mxCacheEntry struct {
+	expiresAt time.Time
+	valid     bool
+}
+
+// DomainValidator rejects addresses whose domain has no mail exchanger.
+// Lookups fail open: DNS errors other than a definitive "not found" allow the address.
+type DomainValidator struct {
+	resolver MXResolver
+	cache    map[string]mxCacheEntry
+	timeout  time.Duration
+	cacheTTL time.Duration
+	mu       sync.Mutex
+}
+
+// NewDomainValidator creates a new MX-based domain validator
+func NewDomainValidator(resolver MXResolver, timeout, cacheTTL time.Duration) *DomainValidator {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	return &DomainValidator{
+		resolver: resolver,
+		cache:    make(map[string]mxCacheEntry),
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+	}
+}
+
+// HasMailExchanger reports whether the address's domain accepts mail.
+// A nil validator accepts every address.
+func (v *DomainValidator) HasMailExchanger(ctx context.Context, address string) bool {
+	if v == nil {
+		return true
+	}
+
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(address[at+1:])
+
+	if valid, ok := v.cached(domain); ok {
+		return valid
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	records, err := v.resolver.LookupMX(lookupCtx, domain)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+			// Fail open on timeouts and resolver failures
+			return true
+		}
+		records = nil
+	}
+
+	valid := hasUsableMX(records)
+	v.store(domain, valid)
+	return valid
+}
+
+// hasUsableMX reports whether any record points at a real host (RFC 7505 null MX is ".")
+func hasUsableMX(records []*net.MX) bool {
+	for _, record := range records {
+		if record.Host != "" && record.Host != "." {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *DomainValidator) cached(domain string) (valid, ok bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	entry, ok := v.cache[domain]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return false, false
+	}
+	return entry.valid, true
+}
+
+func (v *DomainValidator) store(domain string, valid bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.cache[domain] = mxCacheEntry{
+		valid:     valid,
+		expiresAt: time.Now().Add(v.cacheTTL),
+	}
+}
@@ -0,0 +1,80 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// stubResolver returns canned MX records per domain and counts lookups
+type stubResolver struct {
+	records map[string][]*net.MX
+	errs    map[string]error
+	lookups int
+}
+
+func (r *stubResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	r.lookups++
+	if err, ok := r.errs[name]; ok {
+		return nil, err
+	}
+	if records, ok := r.records[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func newStubResolver() *stubResolver {
+	return &stubResolver{
+		records: map[string][]*net.MX{
+			"example.com": {{Host: "mx1.example.com.", Pref: 10}},
+			"nullmx.com":  {{Host: ".", Pref: 0}},
+		},
+		errs: map[string]error{
+			"flaky.com": errors.New("i/o timeout"),
+		},
+	}
+}
+
+func TestDomainValidator_HasMailExchanger(t *testing.T) {
+	validator := NewDomainValidator(newStubResolver(), time.Second, time.Hour)
+
+	testCases := []struct {
+		address string
+		want    bool
+	}{
+		{address: "user@example.com", want: true},
+		{address: "user@EXAMPLE.com", want: true},
+		{address: "user@nomx.invalid", want: false},
+		{address: "user@nullmx.com", want: false},
+		{address: "user@flaky.com", want: true}, // fails open on resolver errors
+	}
+
+	for _, tc := range testCases {
+		if got := validator.HasMailExchanger(context.Background(), tc.address); got != tc.want {
+			t.Errorf("HasMailExchanger(%q) = %v, want %v", tc.address, got, tc.want)
+		}
+	}
+}
+
+func TestDomainValidator_CachesResults(t *testing.T) {
+	resolver := newStubResolver()
+	validator := NewDomainValidator(resolver, time.Second, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		validator.HasMailExchanger(context.Background(), "user@nomx.invalid")
+	}
+
+	if resolver.lookups != 1 {
+		t.Errorf("Expected 1 DNS lookup, got %d", resolver.lookups)
+	}
+}
+
+func TestDomainValidator_NilAcceptsEverything(t *testing.T) {
+	var validator *DomainValidator
+	if !validator.HasMailExchanger(context.Background(), "user@nomx.invalid") {
+		t.Error("Expected nil validator to accept the address")
+	}
+}
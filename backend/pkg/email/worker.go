@@ -0,0 +1,92 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+// ErrQueueFull is returned when the worker queue cannot accept more messages
+var ErrQueueFull = errors.New("email queue is full")
+
+// ErrWorkerStopped is returned when enqueueing a message after the worker was stopped
+var ErrWorkerStopped = errors.New("email worker is stopped")
+
+// Sender delivers a single email message
+type Sender interface {
+	Send(msg Message) error
+}
+
+// Worker delivers queued emails in the background so request handlers
+// don't block on SMTP round-trips
+type Worker struct {
+	sender  Sender
+	queue   chan Message
+	wg      sync.WaitGroup
+	mu      sync.RWMutex // Guards stopped, so that no message is sent on the closed queue
+	stopped bool
+}
+
+// NewWorker creates a new email worker with a bounded queue
+func NewWorker(sender Sender, queueSize int) *Worker {
+	if queueSize <= 0 {
+		queueSize = 100
+	}
+
+	return &Worker{
+		sender: sender,
+		queue:  make(chan Message, queueSize),
+	}
+}
+
+// Start begins processing queued messages until ctx is cancelled or Stop is called
+func (w *Worker) Start(ctx context.Context) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-w.queue:
+				if !ok {
+					return
+				}
+				if err := w.sender.Send(msg); err != nil {
+					logger.Error("Failed to deliver queued email", "error", err, "subject", msg.Subject)
+				}
+			}
+		}
+	}()
+}
+
+// Enqueue adds a message to the delivery queue without blocking
+func (w *Worker) Enqueue(msg Message) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.stopped {
+		return ErrWorkerStopped
+	}
+
+	select {
+	case w.queue <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Stop closes the queue and waits for pending messages to be delivered. Messages enqueued
+// afterwards are rejected with ErrWorkerStopped.
+func (w *Worker) Stop() {
+	w.mu.Lock()
+	if !w.stopped {
+		w.stopped = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+}
@@ -0,0 +1,70 @@
+package email
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// recordingSender captures delivered messages for assertions
+type recordingSender struct {
+	sent chan Message
+}
+
+func (r *recordingSender) Send(msg Message) error {
+	r.sent <- msg
+	return nil
+}
+
+func TestWorker_DeliversTestEmail(t *testing.T) {
+	sender := &recordingSender{sent: make(chan Message, 1)}
+	worker := NewWorker(sender, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	worker.Start(ctx)
+
+	service := New(&Config{FromEmail: "noreply@lightshare.com", FromName: "LightShare"})
+	if err := worker.Enqueue(service.TestEmailMessage("user@example.com")); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	select {
+	case msg := <-sender.sent:
+		if msg.To != "user@example.com" {
+			t.Errorf("Expected recipient 'user@example.com', got '%s'", msg.To)
+		}
+		if msg.Subject != "LightShare test email" {
+			t.Errorf("Unexpected subject '%s'", msg.Subject)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Worker did not deliver the queued message")
+	}
+
+	worker.Stop()
+}
+
+func TestWorker_QueueFull(t *testing.T) {
+	worker := NewWorker(&recordingSender{sent: make(chan Message, 1)}, 1)
+
+	// Worker is not started, so the second message cannot be queued
+	if err := worker.Enqueue(Message{To: "a@example.com"}); err != nil {
+		t.Fatalf("First enqueue failed: %v", err)
+	}
+	if err := worker.Enqueue(Message{To: "b@example.com"}); err != ErrQueueFull {
+		t.Fatalf("Expected ErrQueueFull, got %v", err)
+	}
+}
+
+func TestWorker_EnqueueAfterStop(t *testing.T) {
+	worker := NewWorker(&recordingSender{sent: make(chan Message, 1)}, 1)
+	worker.Start(context.Background())
+	worker.Stop()
+
+	if err := worker.Enqueue(Message{To: "a@example.com"}); err != ErrWorkerStopped {
+		t.Fatalf("Expected ErrWorkerStopped, got %v", err)
+	}
+
+	// Stopping again is harmless
+	worker.Stop()
+}
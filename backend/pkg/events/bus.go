@@ -0,0 +1,82 @@
+// Package events provides an in-process publish/subscribe bus for domain events.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type identifies the kind of event being published
+type Type string
+
+// Event types
+const (
+	DeviceAdded        Type = "device.added"
+	DeviceRemoved      Type = "device.removed"
+	TransitionComplete Type = "device.transition_complete"
+	ActionCompleted    Type = "device.action.completed"
+)
+
+// Event is a domain event delivered to bus subscribers
+type Event struct {
+	Timestamp time.Time   `json:"timestamp"`
+	Payload   interface{} `json:"payload,omitempty"`
+	Type      Type        `json:"event"`
+	UserID    string      `json:"user_id,omitempty"`
+	AccountID string      `json:"account_id,omitempty"`
+}
+
+// Handler receives published events. Handlers run synchronously on the
+// publisher's goroutine and must not block.
+type Handler func(Event)
+
+// Bus fans events out to all registered subscribers
+type Bus struct {
+	subscribers map[int]Handler
+	mu          sync.RWMutex
+	nextID      int
+}
+
+// NewBus creates a new event bus
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[int]Handler),
+	}
+}
+
+// Subscribe registers a handler and returns a function that removes it
+func (b *Bus) Subscribe(handler Handler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = handler
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+// Publish delivers an event to every subscriber. Publishing on a nil bus is a no-op.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.subscribers))
+	for _, handler := range b.subscribers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
@@ -0,0 +1,227 @@
+// Package jobs provides periodic background maintenance jobs.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lightshare/backend/pkg/logger"
+)
+
+const (
+	// magicLinkRetention is how long an expired magic link token is kept before it is cleared
+	magicLinkRetention = 24 * time.Hour
+	// maxStatusAge is how old a cached account status check may get before it is deleted
+	maxStatusAge = 24 * time.Hour
+	// scanBatchSize is how many keys are requested per Redis SCAN call
+	scanBatchSize = 100
+	// defaultDeletedAccountRetention is how long deleted accounts are kept when
+	// DeletedAccountRetention is not set
+	defaultDeletedAccountRetention = 30 * 24 * time.Hour
+)
+
+// rateLimitKeyPatterns match the rate limit counters that are expected to carry a TTL
+var rateLimitKeyPatterns = []string{"ratelimit:account:*", "ratelimit:user:*"}
+
+// statusKeyPattern matches cached account status checks
+const statusKeyPattern = "status:account:*"
+
+// RefreshTokenRepository deletes expired refresh tokens
+type RefreshTokenRepository interface {
+	DeleteExpired(ctx context.Context) (int, error)
+}
+
+// MagicLinkRepository clears expired magic link tokens
+type MagicLinkRepository interface {
+	ClearExpiredMagicLinks(ctx context.Context, expiredBefore time.Time) (int, error)
+}
+
+// DeletedAccountRepository permanently deletes accounts deleted long enough ago
+type DeletedAccountRepository interface {
+	HardDeleteExpiredAccounts(ctx context.Context, deletedBefore time.Time) (int, error)
+}
+
+// CleanupResult counts what a single cleanup run removed
+type CleanupResult struct {
+	RefreshTokens   int `json:"refresh_tokens"`
+	MagicLinks      int `json:"magic_links"`
+	DeletedAccounts int `json:"deleted_accounts"`
+	RateLimitKeys   int `json:"ratelimit_keys"`
+	StatusKeys      int `json:"status_keys"`
+}
+
+// TokenCleanupJob periodically purges expired tokens and accounts deleted past their grace
+// period from the database, and stale keys from Redis
+type TokenCleanupJob struct {
+	refreshTokens   RefreshTokenRepository
+	magicLinks      MagicLinkRepository
+	deletedAccounts DeletedAccountRepository
+	cache           *redis.Client
+	now             func() time.Time
+	cancel          context.CancelFunc
+	wg              sync.WaitGroup
+	CleanupInterval time.Duration
+	// DeletedAccountRetention is how long after its deletion an account is purged
+	DeletedAccountRetention time.Duration
+}
+
+// NewTokenCleanupJob creates a cleanup job that runs every cleanupInterval
+func NewTokenCleanupJob(refreshTokens RefreshTokenRepository, magicLinks MagicLinkRepository, deletedAccounts DeletedAccountRepository, cache *redis.Client, cleanupInterval time.Duration) *TokenCleanupJob {
+	if cleanupInterval <= 0 {
+		cleanupInterval = time.Hour
+	}
+
+	return &TokenCleanupJob{
+		refreshTokens:           refreshTokens,
+		magicLinks:              magicLinks,
+		deletedAccounts:         deletedAccounts,
+		cache:                   cache,
+		now:                     time.Now,
+		CleanupInterval:         cleanupInterval,
+		DeletedAccountRetention: defaultDeletedAccountRetention,
+	}
+}
+
+// Start runs the cleanup every CleanupInterval until ctx is cancelled or Stop is called
+func (j *TokenCleanupJob) Start(ctx context.Context) {
+	ctx, j.cancel = context.WithCancel(ctx)
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.CleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				result := j.Run(ctx)
+				logger.Info("Token cleanup completed",
+					"refresh_tokens", result.RefreshTokens,
+					"magic_links", result.MagicLinks,
+					"deleted_accounts", result.DeletedAccounts,
+					"ratelimit_keys", result.RateLimitKeys,
+					"status_keys", result.StatusKeys,
+				)
+			}
+		}
+	}()
+}
+
+// Stop cancels the job and waits for a run in progress to finish
+func (j *TokenCleanupJob) Stop() {
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.wg.Wait()
+}
+
+// Run performs a single cleanup. Each step is independent, so a failing step is logged
+// and the others still run.
+func (j *TokenCleanupJob) Run(ctx context.Context) CleanupResult {
+	var result CleanupResult
+	var err error
+
+	if result.RefreshTokens, err = j.refreshTokens.DeleteExpired(ctx); err != nil {
+		logger.Error("Failed to delete expired refresh tokens", "error", err)
+	}
+
+	if result.MagicLinks, err = j.magicLinks.ClearExpiredMagicLinks(ctx, j.now().Add(-magicLinkRetention)); err != nil {
+		logger.Error("Failed to clear expired magic links", "error", err)
+	}
+
+	if result.DeletedAccounts, err = j.deletedAccounts.HardDeleteExpiredAccounts(ctx, j.now().Add(-j.DeletedAccountRetention)); err != nil {
+		logger.Error("Failed to purge deleted accounts", "error", err)
+	}
+
+	if result.RateLimitKeys, err = j.deletePersistentRateLimitKeys(ctx); err != nil {
+		logger.Error("Failed to clean up rate limit keys", "error", err)
+	}
+
+	if result.StatusKeys, err = j.deleteStaleStatusKeys(ctx); err != nil {
+		logger.Error("Failed to clean up account status keys", "error", err)
+	}
+
+	return result
+}
+
+// deletePersistentRateLimitKeys deletes rate limit counters that have no TTL. A counter is
+// left without one if the process dies between its INCR and EXPIRE, and would otherwise
+// throttle its account or user forever.
+func (j *TokenCleanupJob) deletePersistentRateLimitKeys(ctx context.Context) (int, error) {
+	deleted := 0
+	for _, pattern := range rateLimitKeyPatterns {
+		err := j.scan(ctx, pattern, func(key string) error {
+			ttl, err := j.cache.TTL(ctx, key).Result()
+			if err != nil {
+				return fmt.Errorf("failed to get TTL of %s: %w", key, err)
+			}
+			// -1 means the key exists without an expiry
+			if ttl != -1 {
+				return nil
+			}
+			return j.deleteKey(ctx, key, &deleted)
+		})
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// deleteStaleStatusKeys deletes cached account status checks last refreshed more than
+// maxStatusAge ago
+func (j *TokenCleanupJob) deleteStaleStatusKeys(ctx context.Context) (int, error) {
+	cutoff := j.now().Add(-maxStatusAge)
+	deleted := 0
+	err := j.scan(ctx, statusKeyPattern, func(key string) error {
+		data, err := j.cache.Get(ctx, key).Bytes()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				return nil // Expired since the scan
+			}
+			return fmt.Errorf("failed to get %s: %w", key, err)
+		}
+
+		var status struct {
+			LastCheckedAt time.Time `json:"last_checked_at"`
+		}
+		if err := json.Unmarshal(data, &status); err != nil || !status.LastCheckedAt.Before(cutoff) {
+			return nil
+		}
+		return j.deleteKey(ctx, key, &deleted)
+	})
+	return deleted, err
+}
+
+// scan calls fn for every key matching pattern
+func (j *TokenCleanupJob) scan(ctx context.Context, pattern string, fn func(key string) error) error {
+	iter := j.cache.Scan(ctx, 0, pattern, scanBatchSize).Iterator()
+	for iter.Next(ctx) {
+		if err := fn(iter.Val()); err != nil {
+			return err
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to scan %s: %w", pattern, err)
+	}
+	return nil
+}
+
+func (j *TokenCleanupJob) deleteKey(ctx context.Context, key string, deleted *int) error {
+	removed, err := j.cache.Del(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	*deleted += int(removed)
+	return nil
+}
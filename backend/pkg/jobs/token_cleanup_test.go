@@ -0,0 +1,142 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type fakeRefreshTokens struct {
+	err     error
+	expired int
+	calls   int
+}
+
+func (f *fakeRefreshTokens) DeleteExpired(context.Context) (int, error) {
+	f.calls++
+	return f.expired, f.err
+}
+
+type fakeMagicLinks struct {
+	expiredBefore time.Time
+	cleared       int
+}
+
+func (f *fakeMagicLinks) ClearExpiredMagicLinks(_ context.Context, expiredBefore time.Time) (int, error) {
+	f.expiredBefore = expiredBefore
+	return f.cleared, nil
+}
+
+type fakeDeletedAccounts struct {
+	deletedBefore time.Time
+	purged        int
+}
+
+func (f *fakeDeletedAccounts) HardDeleteExpiredAccounts(_ context.Context, deletedBefore time.Time) (int, error) {
+	f.deletedBefore = deletedBefore
+	return f.purged, nil
+}
+
+func newTestJob(t *testing.T) (*TokenCleanupJob, *miniredis.Miniredis, *fakeRefreshTokens, *fakeMagicLinks) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	cache := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = cache.Close() })
+
+	tokens := &fakeRefreshTokens{expired: 3}
+	magicLinks := &fakeMagicLinks{cleared: 2}
+	return NewTokenCleanupJob(tokens, magicLinks, &fakeDeletedAccounts{purged: 1}, cache, time.Minute), mr, tokens, magicLinks
+}
+
+func statusJSON(checkedAt time.Time) string {
+	return fmt.Sprintf(`{"last_checked_at":%q,"status":"valid","provider":"lifx"}`, checkedAt.Format(time.RFC3339Nano))
+}
+
+func TestTokenCleanupJob_Run(t *testing.T) {
+	job, mr, _, magicLinks := newTestJob(t)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	job.now = func() time.Time { return now }
+
+	// Rate limit counters without a TTL are stale; ones with a TTL are live
+	mr.Set("ratelimit:account:acc-1:read", "5")
+	mr.Set("ratelimit:user:user-1", "7")
+	mr.Set("ratelimit:account:acc-2:write", "1")
+	mr.SetTTL("ratelimit:account:acc-2:write", time.Minute)
+	// Other rate limits are not touched
+	mr.Set("ratelimit:2fa:user:user-1", "1")
+
+	mr.Set("status:account:old", statusJSON(now.Add(-25*time.Hour)))
+	mr.Set("status:account:fresh", statusJSON(now.Add(-time.Hour)))
+	mr.Set("status:account:garbled", "not json")
+
+	result := job.Run(context.Background())
+
+	if result.RefreshTokens != 3 || result.MagicLinks != 2 || result.DeletedAccounts != 1 {
+		t.Errorf("Expected 3 refresh tokens, 2 magic links and 1 deleted account, got %+v", result)
+	}
+	if result.RateLimitKeys != 2 {
+		t.Errorf("Expected 2 rate limit keys deleted, got %d", result.RateLimitKeys)
+	}
+	if result.StatusKeys != 1 {
+		t.Errorf("Expected 1 status key deleted, got %d", result.StatusKeys)
+	}
+
+	for _, key := range []string{"ratelimit:account:acc-1:read", "ratelimit:user:user-1", "status:account:old"} {
+		if mr.Exists(key) {
+			t.Errorf("Expected %s to be deleted", key)
+		}
+	}
+	for _, key := range []string{"ratelimit:account:acc-2:write", "ratelimit:2fa:user:user-1", "status:account:fresh", "status:account:garbled"} {
+		if !mr.Exists(key) {
+			t.Errorf("Expected %s to be kept", key)
+		}
+	}
+
+	if want := now.Add(-24 * time.Hour); !magicLinks.expiredBefore.Equal(want) {
+		t.Errorf("Expected magic links expired before %v to be cleared, got %v", want, magicLinks.expiredBefore)
+	}
+	deletedAccounts := job.deletedAccounts.(*fakeDeletedAccounts)
+	if want := now.Add(-30 * 24 * time.Hour); !deletedAccounts.deletedBefore.Equal(want) {
+		t.Errorf("Expected accounts deleted before %v to be purged, got %v", want, deletedAccounts.deletedBefore)
+	}
+}
+
+func TestTokenCleanupJob_RunContinuesAfterFailure(t *testing.T) {
+	job, mr, tokens, _ := newTestJob(t)
+	tokens.err = errors.New("database unavailable")
+	mr.Set("ratelimit:user:user-1", "7")
+
+	result := job.Run(context.Background())
+
+	if result.RateLimitKeys != 1 {
+		t.Errorf("Expected Redis cleanup to run despite the database failure, got %+v", result)
+	}
+	if mr.Exists("ratelimit:user:user-1") {
+		t.Error("Expected stale rate limit key to be deleted")
+	}
+}
+
+func TestTokenCleanupJob_StartStop(t *testing.T) {
+	job, _, tokens, _ := newTestJob(t)
+	job.CleanupInterval = 10 * time.Millisecond
+
+	job.Start(context.Background())
+	time.Sleep(50 * time.Millisecond)
+	job.Stop()
+
+	calls := tokens.calls
+	if calls == 0 {
+		t.Fatal("Expected the job to run at least once")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if tokens.calls != calls {
+		t.Errorf("Expected no runs after Stop, got %d more", tokens.calls-calls)
+	}
+}
@@ -21,6 +21,12 @@ var (
 	ErrInvalidTokenType = errors.New("invalid token type")
 )
 
+// mfaPendingExpiration is how long a user has to complete a second factor after a password login
+const mfaPendingExpiration = 5 * time.Minute
+
+// impersonationExpiration is how long an admin's impersonation token stays valid
+const impersonationExpiration = 15 * time.Minute
+
 // Config holds JWT configuration
 type Config struct {
 	Secret            string
@@ -41,10 +47,15 @@ func New(cfg Config) *Service {
 // Claims represents JWT claims
 type Claims struct {
 	jwt.RegisteredClaims
-	Email  string    `json:"email"`
-	Role   string    `json:"role"`
-	Type   string    `json:"type"`
-	UserID uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Type      string    `json:"type"`
+	UserID    uuid.UUID `json:"user_id"`
+	SessionID uuid.UUID `json:"session_id,omitempty"` // The login session both tokens of a pair belong to
+	// ImpersonatedBy is the ID of the admin an impersonation token was issued to, and
+	// ImpersonationReason why; both are empty for the user's own tokens
+	ImpersonatedBy      string `json:"impersonated_by,omitempty"`
+	ImpersonationReason string `json:"reason,omitempty"`
 }
 
 // TokenPair represents an access and refresh token pair
@@ -55,18 +66,19 @@ type TokenPair struct {
 	TokenType    string    `json:"token_type"`
 }
 
-// GenerateTokenPair generates an access and refresh token pair
-func (s *Service) GenerateTokenPair(userID uuid.UUID, email, role string) (*TokenPair, error) {
+// GenerateTokenPair generates an access and refresh token pair for a session
+func (s *Service) GenerateTokenPair(userID uuid.UUID, email, role string, sessionID uuid.UUID) (*TokenPair, error) {
 	now := time.Now()
 	accessExpiresAt := now.Add(s.config.AccessExpiration)
 	refreshExpiresAt := now.Add(s.config.RefreshExpiration)
 
 	// Generate access token
 	accessClaims := Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
-		Type:   "access",
+		UserID:    userID,
+		SessionID: sessionID,
+		Email:     email,
+		Role:      role,
+		Type:      "access",
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(accessExpiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -84,16 +96,20 @@ func (s *Service) GenerateTokenPair(userID uuid.UUID, email, role string) (*Toke
 
 	// Generate refresh token
 	refreshClaims := Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
-		Type:   "refresh",
+		UserID:    userID,
+		SessionID: sessionID,
+		Email:     email,
+		Role:      role,
+		Type:      "refresh",
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(refreshExpiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "lightshare",
 			Subject:   userID.String(),
+			// A unique ID keeps tokens issued within the same second distinct,
+			// so a rotated token never hashes to the one it replaced
+			ID: uuid.NewString(),
 		},
 	}
 
@@ -164,6 +180,80 @@ func (s *Service) ValidateRefreshToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
+// GenerateMFAPendingToken generates a short-lived token proving the password step of a
+// login succeeded; it can only be exchanged for a token pair once the second factor is verified
+func (s *Service) GenerateMFAPendingToken(userID uuid.UUID, email, role string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(mfaPendingExpiration)
+
+	claims := Claims{
+		UserID: userID,
+		Email:  email,
+		Role:   role,
+		Type:   "mfa_pending",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "lightshare",
+			Subject:   userID.String(),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.config.Secret))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign mfa pending token: %w", err)
+	}
+
+	return token, expiresAt, nil
+}
+
+// ValidateMFAPendingToken validates an MFA pending token specifically
+func (s *Service) ValidateMFAPendingToken(tokenString string) (*Claims, error) {
+	claims, err := s.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Type != "mfa_pending" {
+		return nil, ErrInvalidTokenType
+	}
+
+	return claims, nil
+}
+
+// GenerateImpersonationToken generates a short-lived access token for a user, issued to an
+// admin who needs to see what the user sees. It belongs to no session and comes without a
+// refresh token, so it cannot be renewed.
+func (s *Service) GenerateImpersonationToken(userID uuid.UUID, email, role string, adminID uuid.UUID, reason string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(impersonationExpiration)
+
+	claims := Claims{
+		UserID:              userID,
+		Email:               email,
+		Role:                role,
+		Type:                "access",
+		ImpersonatedBy:      adminID.String(),
+		ImpersonationReason: reason,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "lightshare",
+			Subject:   userID.String(),
+			ID:        uuid.NewString(),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.config.Secret))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign impersonation token: %w", err)
+	}
+
+	return token, expiresAt, nil
+}
+
 // GenerateRandomToken generates a cryptographically secure random token
 // Useful for email verification tokens, magic link tokens, etc.
 func GenerateRandomToken(length int) (string, error) {
@@ -2,35 +2,107 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/lightshare/backend/pkg/ctxutil"
 )
 
 var defaultLogger *slog.Logger
 
-// Init initializes the logger with the specified level
+type loggerKey struct{}
+
+// Output types of an OutputConfig
+const (
+	OutputStdout = "stdout"
+	OutputFile   = "file"
+)
+
+// LogConfig configures the level and destinations of the logger
+type LogConfig struct {
+	Level   string // "debug", "info", "warn" or "error"; anything else means info
+	Outputs []OutputConfig
+}
+
+// OutputConfig configures a single log destination. Every output writes JSON.
+type OutputConfig struct {
+	Type       string // OutputStdout or OutputFile
+	Path       string // Log file path, for file outputs
+	MaxSizeMB  int    // Size at which the log file is rotated (lumberjack's default of 100 MB if 0)
+	MaxBackups int    // Rotated files kept (all if 0)
+	MaxAgeDays int    // Days rotated files are kept (forever if 0)
+}
+
+// Init initializes the logger with the specified level, writing to stdout
 func Init(level string) {
-	var logLevel slog.Level
-	switch level {
-	case "debug":
-		logLevel = slog.LevelDebug
-	case "info":
-		logLevel = slog.LevelInfo
-	case "warn":
-		logLevel = slog.LevelWarn
-	case "error":
-		logLevel = slog.LevelError
-	default:
-		logLevel = slog.LevelInfo
-	}
+	// A stdout-only configuration cannot fail
+	_ = InitWithConfig(LogConfig{
+		Level:   level,
+		Outputs: []OutputConfig{{Type: OutputStdout}},
+	})
+}
 
+// InitWithConfig initializes the logger to write to each of the configured outputs. File
+// outputs are rotated by size with lumberjack. Without outputs, it writes to stdout.
+func InitWithConfig(cfg LogConfig) error {
 	opts := &slog.HandlerOptions{
-		Level: logLevel,
+		Level: parseLevel(cfg.Level),
+	}
+
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []OutputConfig{{Type: OutputStdout}}
+	}
+
+	handlers := make([]slog.Handler, 0, len(outputs))
+	for _, output := range outputs {
+		var w io.Writer
+		switch output.Type {
+		case OutputStdout:
+			w = os.Stdout
+		case OutputFile:
+			if output.Path == "" {
+				return fmt.Errorf("file log output requires a path")
+			}
+			w = &lumberjack.Logger{
+				Filename:   output.Path,
+				MaxSize:    output.MaxSizeMB,
+				MaxBackups: output.MaxBackups,
+				MaxAge:     output.MaxAgeDays,
+			}
+		default:
+			return fmt.Errorf("unknown log output type %q: must be stdout or file", output.Type)
+		}
+		handlers = append(handlers, slog.NewJSONHandler(w, opts))
+	}
+
+	var handler slog.Handler = handlers[0]
+	if len(handlers) > 1 {
+		handler = NewMultiHandler(handlers...)
 	}
 
-	handler := slog.NewJSONHandler(os.Stdout, opts)
 	defaultLogger = slog.New(handler)
 	slog.SetDefault(defaultLogger)
+	return nil
+}
+
+// parseLevel maps a level name to its slog level, defaulting to info
+func parseLevel(level string) slog.Level {
+	switch level {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
 }
 
 // Get returns the default logger
@@ -41,6 +113,28 @@ func Get() *slog.Logger {
 	return defaultLogger
 }
 
+// SetDefault replaces the default logger, e.g. to capture log output in tests
+func SetDefault(l *slog.Logger) {
+	defaultLogger = l
+}
+
+// NewContext returns a copy of ctx carrying l, for WithContext to return
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// WithContext returns the logger carried by ctx. Without one, it returns the default
+// logger, with the request ID of ctx attached when there is one.
+func WithContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	if requestID := ctxutil.RequestIDFromCtx(ctx); requestID != "" {
+		return Get().With("request_id", requestID)
+	}
+	return Get()
+}
+
 // Debug logs at debug level
 func Debug(msg string, args ...any) {
 	Get().Debug(msg, args...)
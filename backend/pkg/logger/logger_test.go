@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitWithConfig_FileOutput(t *testing.T) {
+	t.Cleanup(func() { Init("info") })
+	path := filepath.Join(t.TempDir(), "lightshare.log")
+
+	err := InitWithConfig(LogConfig{
+		Level: "debug",
+		Outputs: []OutputConfig{
+			{Type: OutputStdout},
+			{Type: OutputFile, Path: path, MaxSizeMB: 1, MaxBackups: 2},
+		},
+	})
+	if err != nil {
+		t.Fatalf("InitWithConfig failed: %v", err)
+	}
+
+	Debug("Device state changed", "device_id", "d073d5000001")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimSpace(data), &entry); err != nil {
+		t.Fatalf("Expected a single JSON log entry, got %q: %v", data, err)
+	}
+	if entry["msg"] != "Device state changed" || entry["device_id"] != "d073d5000001" || entry["level"] != "DEBUG" {
+		t.Errorf("Unexpected log entry: %v", entry)
+	}
+}
+
+func TestInitWithConfig_InvalidOutputs(t *testing.T) {
+	t.Cleanup(func() { Init("info") })
+
+	testCases := map[string]OutputConfig{
+		"file without a path": {Type: OutputFile},
+		"unknown type":        {Type: "syslog"},
+	}
+	for name, output := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if err := InitWithConfig(LogConfig{Outputs: []OutputConfig{output}}); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestMultiHandler(t *testing.T) {
+	var debug, errorsOnly bytes.Buffer
+	handler := NewMultiHandler(
+		slog.NewJSONHandler(&debug, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		slog.NewJSONHandler(&errorsOnly, &slog.HandlerOptions{Level: slog.LevelError}),
+	)
+	l := slog.New(handler).With("request_id", "req-1")
+
+	if !handler.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("Expected debug to be enabled by the first handler")
+	}
+
+	l.Info("Listing devices")
+	l.Error("Provider unavailable")
+
+	if got := strings.Count(debug.String(), "\n"); got != 2 {
+		t.Errorf("Expected 2 entries in the debug output, got %d: %s", got, debug.String())
+	}
+	if got := errorsOnly.String(); strings.Count(got, "\n") != 1 || !strings.Contains(got, "Provider unavailable") {
+		t.Errorf("Expected only the error in the error output, got %s", got)
+	}
+	if !strings.Contains(errorsOnly.String(), `"request_id":"req-1"`) {
+		t.Errorf("Expected attributes to reach every handler, got %s", errorsOnly.String())
+	}
+}
@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// MultiHandler fans each record out to several handlers, e.g. stdout and a log file
+type MultiHandler struct {
+	handlers []slog.Handler
+}
+
+// NewMultiHandler creates a handler writing every record to each of handlers
+func NewMultiHandler(handlers ...slog.Handler) *MultiHandler {
+	return &MultiHandler{handlers: handlers}
+}
+
+// Enabled reports whether any of the handlers handles records at level
+func (m *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m.handlers {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes the record to every handler enabled for its level. A failing handler
+// does not stop the others; their errors are joined.
+func (m *MultiHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, h := range m.handlers {
+		if !h.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, record.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a MultiHandler whose handlers all carry attrs
+func (m *MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return &MultiHandler{handlers: handlers}
+}
+
+// WithGroup returns a MultiHandler whose handlers all nest attributes under name
+func (m *MultiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		handlers[i] = h.WithGroup(name)
+	}
+	return &MultiHandler{handlers: handlers}
+}
@@ -0,0 +1,161 @@
+// Package metrics defines the Prometheus metrics exported on /metrics
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the application's Prometheus collectors and the registry they are
+// registered with. The recording methods are safe to call on a nil *Metrics.
+type Metrics struct {
+	Registry             *prometheus.Registry
+	HTTPRequests         *prometheus.CounterVec
+	HTTPRequestDuration  *prometheus.HistogramVec
+	ProviderCalls        *prometheus.CounterVec
+	ProviderCallDuration *prometheus.HistogramVec
+	DeviceCacheHits      *prometheus.CounterVec
+	DeviceCacheMisses    *prometheus.CounterVec
+}
+
+// New creates the application metrics on a dedicated registry, alongside the Go
+// runtime (memory, goroutines) and process (CPU, file descriptors) collectors
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total number of HTTP requests by method, route and status code.",
+		}, []string{"method", "path", "status"}),
+		HTTPRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by method and route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "path"}),
+		ProviderCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "provider_api_calls_total",
+			Help: "Total number of provider API calls by provider, operation and outcome.",
+		}, []string{"provider", "operation", "status"}),
+		ProviderCallDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "provider_api_duration_seconds",
+			Help:    "Provider API call latency by provider and operation.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"provider", "operation"}),
+		DeviceCacheHits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "device_cache_hits_total",
+			Help: "Total number of device list cache hits by account.",
+		}, []string{"account_id"}),
+		DeviceCacheMisses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "device_cache_misses_total",
+			Help: "Total number of device list cache misses by account.",
+		}, []string{"account_id"}),
+	}
+
+	m.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.HTTPRequests,
+		m.HTTPRequestDuration,
+		m.ProviderCalls,
+		m.ProviderCallDuration,
+		m.DeviceCacheHits,
+		m.DeviceCacheMisses,
+	)
+
+	return m
+}
+
+// Handler returns an http.Handler serving the registry in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTPRequest records a served HTTP request
+func (m *Metrics) ObserveHTTPRequest(method, path, status string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.HTTPRequests.WithLabelValues(method, path, status).Inc()
+	m.HTTPRequestDuration.WithLabelValues(method, path).Observe(duration.Seconds())
+}
+
+// ObserveProviderCall records a provider API call; status is "success" or "error"
+func (m *Metrics) ObserveProviderCall(provider, operation string, err error, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	m.ProviderCalls.WithLabelValues(provider, operation, status).Inc()
+	m.ProviderCallDuration.WithLabelValues(provider, operation).Observe(duration.Seconds())
+}
+
+// CacheHit records a device list cache hit for an account
+func (m *Metrics) CacheHit(accountID string) {
+	if m == nil {
+		return
+	}
+	m.DeviceCacheHits.WithLabelValues(accountID).Inc()
+}
+
+// CacheMiss records a device list cache miss for an account
+func (m *Metrics) CacheMiss(accountID string) {
+	if m == nil {
+		return
+	}
+	m.DeviceCacheMisses.WithLabelValues(accountID).Inc()
+}
+
+// Summary totals the application counters since the process started
+type Summary struct {
+	HTTPRequests       float64 `json:"http_requests"`
+	HTTPServerErrors   float64 `json:"http_server_errors"` // Requests answered with a 5xx status
+	ProviderCalls      float64 `json:"provider_calls"`
+	ProviderCallErrors float64 `json:"provider_call_errors"`
+	DeviceCacheHits    float64 `json:"device_cache_hits"`
+	DeviceCacheMisses  float64 `json:"device_cache_misses"`
+}
+
+// Summary returns the application counters summed over all their labels
+func (m *Metrics) Summary() (Summary, error) {
+	var summary Summary
+	families, err := m.Registry.Gather()
+	if err != nil {
+		return summary, err
+	}
+
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			value := metric.GetCounter().GetValue()
+			labels := make(map[string]string, len(metric.GetLabel()))
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+
+			switch family.GetName() {
+			case "http_requests_total":
+				summary.HTTPRequests += value
+				if strings.HasPrefix(labels["status"], "5") {
+					summary.HTTPServerErrors += value
+				}
+			case "provider_api_calls_total":
+				summary.ProviderCalls += value
+				if labels["status"] == "error" {
+					summary.ProviderCallErrors += value
+				}
+			case "device_cache_hits_total":
+				summary.DeviceCacheHits += value
+			case "device_cache_misses_total":
+				summary.DeviceCacheMisses += value
+			}
+		}
+	}
+	return summary, nil
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// registered reports whether the registry exposes a metric family with the given name
+func registered(t *testing.T, m *Metrics, name string) bool {
+	t.Helper()
+
+	families, err := m.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNew_RegistersRuntimeCollectors(t *testing.T) {
+	m := New()
+
+	for _, name := range []string{"go_memstats_alloc_bytes", "process_cpu_seconds_total"} {
+		if !registered(t, m, name) {
+			t.Errorf("Expected %s to be registered", name)
+		}
+	}
+}
+
+func TestObserveHTTPRequest(t *testing.T) {
+	m := New()
+	m.ObserveHTTPRequest("GET", "/api/v1/devices", "200", 50*time.Millisecond)
+
+	if got := testutil.ToFloat64(m.HTTPRequests.WithLabelValues("GET", "/api/v1/devices", "200")); got != 1 {
+		t.Errorf("Expected http_requests_total 1, got %v", got)
+	}
+	if !registered(t, m, "http_requests_total") {
+		t.Error("Expected http_requests_total to be registered")
+	}
+	if !registered(t, m, "http_request_duration_seconds") {
+		t.Error("Expected http_request_duration_seconds to be registered")
+	}
+}
+
+func TestObserveProviderCall(t *testing.T) {
+	m := New()
+	m.ObserveProviderCall("lifx", "list_devices", nil, 100*time.Millisecond)
+	m.ObserveProviderCall("lifx", "list_devices", errors.New("timeout"), 100*time.Millisecond)
+
+	if got := testutil.ToFloat64(m.ProviderCalls.WithLabelValues("lifx", "list_devices", "success")); got != 1 {
+		t.Errorf("Expected 1 successful provider call, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.ProviderCalls.WithLabelValues("lifx", "list_devices", "error")); got != 1 {
+		t.Errorf("Expected 1 failed provider call, got %v", got)
+	}
+	if !registered(t, m, "provider_api_calls_total") {
+		t.Error("Expected provider_api_calls_total to be registered")
+	}
+	if !registered(t, m, "provider_api_duration_seconds") {
+		t.Error("Expected provider_api_duration_seconds to be registered")
+	}
+}
+
+func TestCacheHitsAndMisses(t *testing.T) {
+	m := New()
+	m.CacheHit("account-1")
+	m.CacheHit("account-1")
+	m.CacheMiss("account-1")
+
+	if got := testutil.ToFloat64(m.DeviceCacheHits.WithLabelValues("account-1")); got != 2 {
+		t.Errorf("Expected 2 cache hits, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.DeviceCacheMisses.WithLabelValues("account-1")); got != 1 {
+		t.Errorf("Expected 1 cache miss, got %v", got)
+	}
+	if !registered(t, m, "device_cache_hits_total") {
+		t.Error("Expected device_cache_hits_total to be registered")
+	}
+	if !registered(t, m, "device_cache_misses_total") {
+		t.Error("Expected device_cache_misses_total to be registered")
+	}
+}
+
+func TestNilMetrics_RecordingIsNoop(t *testing.T) {
+	var m *Metrics
+	m.ObserveHTTPRequest("GET", "/", "200", time.Millisecond)
+	m.ObserveProviderCall("lifx", "get_device", nil, time.Millisecond)
+	m.CacheHit("account-1")
+	m.CacheMiss("account-1")
+}
+
+func TestSummary(t *testing.T) {
+	m := New()
+	m.ObserveHTTPRequest("GET", "/api/v1/devices", "200", time.Millisecond)
+	m.ObserveHTTPRequest("GET", "/api/v1/devices", "502", time.Millisecond)
+	m.ObserveHTTPRequest("POST", "/api/v1/auth/login", "401", time.Millisecond)
+	m.ObserveProviderCall("lifx", "list_devices", nil, time.Millisecond)
+	m.ObserveProviderCall("hue", "list_devices", errors.New("timeout"), time.Millisecond)
+	m.CacheHit("account-1")
+	m.CacheHit("account-2")
+	m.CacheMiss("account-1")
+
+	summary, err := m.Summary()
+	if err != nil {
+		t.Fatalf("Summary failed: %v", err)
+	}
+	want := Summary{HTTPRequests: 3, HTTPServerErrors: 1, ProviderCalls: 2, ProviderCallErrors: 1, DeviceCacheHits: 2, DeviceCacheMisses: 1}
+	if summary != want {
+		t.Errorf("Expected %+v, got %+v", want, summary)
+	}
+}
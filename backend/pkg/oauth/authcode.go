@@ -0,0 +1,144 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Authorization endpoints of the providers accounts can be connected to with OAuth2
+var (
+	LIFXEndpoint = Endpoint{
+		AuthURL:  "https://cloud.lifx.com/oauth2/authorize",
+		TokenURL: "https://cloud.lifx.com/oauth2/token",
+	}
+	HueEndpoint = Endpoint{
+		AuthURL:  "https://api.meethue.com/v2/oauth2/authorize",
+		TokenURL: "https://api.meethue.com/v2/oauth2/token",
+	}
+)
+
+// CodeChallengeMethodS256 is the only PKCE code challenge method accepted
+const CodeChallengeMethodS256 = "S256"
+
+// ErrInvalidGrant is returned when a provider rejects an authorization code
+var ErrInvalidGrant = errors.New("invalid authorization code")
+
+// pkceValue matches a PKCE code verifier or S256 challenge (RFC 7636 section 4.1)
+var pkceValue = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// Endpoint holds a provider's OAuth2 authorization and token URLs
+type Endpoint struct {
+	AuthURL  string
+	TokenURL string
+}
+
+// AuthCodeConfig configures the OAuth2 authorization code flow with a provider
+type AuthCodeConfig struct {
+	httpClient   *http.Client
+	Endpoint     Endpoint
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+}
+
+// NewAuthCodeConfig creates an authorization code flow configuration for endpoint
+func NewAuthCodeConfig(endpoint Endpoint, clientID, clientSecret, redirectURI string) *AuthCodeConfig {
+	return &AuthCodeConfig{
+		httpClient:   &http.Client{Timeout: requestTimeout},
+		Endpoint:     endpoint,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURI:  redirectURI,
+	}
+}
+
+// AuthCodeURL returns the URL to send the user to for authorizing us, protected by state
+// and the S256 challenge of a PKCE code verifier
+func (c *AuthCodeConfig) AuthCodeURL(state, codeChallenge string) string {
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.ClientID},
+		"redirect_uri":          {c.RedirectURI},
+		"state":                 {state},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {CodeChallengeMethodS256},
+	}
+	return c.Endpoint.AuthURL + "?" + params.Encode()
+}
+
+// tokenResponse is the token endpoint's response (RFC 6749 section 5.1)
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	Error       string `json:"error"`
+}
+
+// Exchange redeems an authorization code for an access token, proving possession of the
+// code verifier whose challenge the authorization was requested with
+func (c *AuthCodeConfig) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.RedirectURI},
+		"client_id":     {c.ClientID},
+		"client_secret": {c.ClientSecret},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+
+	// Expired, reused or forged codes and wrong verifiers are all reported as invalid_grant
+	if token.Error == "invalid_grant" {
+		return "", ErrInvalidGrant
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return "", fmt.Errorf("unexpected token response: status %d, error %q", resp.StatusCode, token.Error)
+	}
+
+	return token.AccessToken, nil
+}
+
+// NewCodeVerifier returns a random PKCE code verifier
+func NewCodeVerifier() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CodeChallengeS256 returns the S256 challenge of a PKCE code verifier
+func CodeChallengeS256(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ValidCodeChallenge reports whether challenge is a well-formed PKCE code challenge
+func ValidCodeChallenge(challenge string) bool {
+	return pkceValue.MatchString(challenge)
+}
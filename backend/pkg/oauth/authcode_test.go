@@ -0,0 +1,127 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newTokenServer redeems the code "valid-code" for "access-token" when the code verifier
+// matches challenge, and answers invalid_grant otherwise
+func newTokenServer(t *testing.T, challenge string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_request"}`))
+			return
+		}
+		if r.PostForm.Get("code") != "valid-code" || CodeChallengeS256(r.PostForm.Get("code_verifier")) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"access-token","token_type":"Bearer"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCodeChallengeS256(t *testing.T) {
+	// Example from RFC 7636 appendix B
+	got := CodeChallengeS256("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("Expected challenge %s, got %s", want, got)
+	}
+}
+
+func TestNewCodeVerifier(t *testing.T) {
+	verifier, err := NewCodeVerifier()
+	if err != nil {
+		t.Fatalf("NewCodeVerifier failed: %v", err)
+	}
+	if !ValidCodeChallenge(verifier) {
+		t.Errorf("Expected a well-formed verifier, got %q", verifier)
+	}
+
+	other, _ := NewCodeVerifier()
+	if other == verifier {
+		t.Error("Expected verifiers to be random")
+	}
+}
+
+func TestValidCodeChallenge(t *testing.T) {
+	testCases := []struct {
+		challenge string
+		want      bool
+	}{
+		{challenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", want: true},
+		{challenge: strings.Repeat("a", 128), want: true},
+		{challenge: strings.Repeat("a", 42), want: false},
+		{challenge: strings.Repeat("a", 129), want: false},
+		{challenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw+cM=", want: false},
+		{challenge: "", want: false},
+	}
+
+	for _, tc := range testCases {
+		if got := ValidCodeChallenge(tc.challenge); got != tc.want {
+			t.Errorf("ValidCodeChallenge(%q) = %v, want %v", tc.challenge, got, tc.want)
+		}
+	}
+}
+
+func TestAuthCodeConfig_AuthCodeURL(t *testing.T) {
+	config := NewAuthCodeConfig(LIFXEndpoint, "client-id", "client-secret", "https://api.example.com/callback")
+
+	authURL, err := url.Parse(config.AuthCodeURL("state-1234567890ab", "challenge"))
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %v", err)
+	}
+	if got := authURL.Scheme + "://" + authURL.Host + authURL.Path; got != "https://cloud.lifx.com/oauth2/authorize" {
+		t.Errorf("Expected the LIFX authorization endpoint, got %s", got)
+	}
+
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             "client-id",
+		"redirect_uri":          "https://api.example.com/callback",
+		"state":                 "state-1234567890ab",
+		"code_challenge":        "challenge",
+		"code_challenge_method": "S256",
+	}
+	query := authURL.Query()
+	for key, value := range want {
+		if query.Get(key) != value {
+			t.Errorf("Expected %s=%s, got %q", key, value, query.Get(key))
+		}
+	}
+}
+
+func TestAuthCodeConfig_Exchange(t *testing.T) {
+	verifier, err := NewCodeVerifier()
+	if err != nil {
+		t.Fatalf("NewCodeVerifier failed: %v", err)
+	}
+	server := newTokenServer(t, CodeChallengeS256(verifier))
+	config := NewAuthCodeConfig(Endpoint{TokenURL: server.URL}, "client-id", "client-secret", "https://api.example.com/callback")
+
+	token, err := config.Exchange(context.Background(), "valid-code", verifier)
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+	if token != "access-token" {
+		t.Errorf("Expected access-token, got %q", token)
+	}
+
+	otherVerifier, _ := NewCodeVerifier()
+	if _, err := config.Exchange(context.Background(), "valid-code", otherVerifier); !errors.Is(err, ErrInvalidGrant) {
+		t.Errorf("Expected ErrInvalidGrant for a wrong verifier, got %v", err)
+	}
+	if _, err := config.Exchange(context.Background(), "forged-code", verifier); !errors.Is(err, ErrInvalidGrant) {
+		t.Errorf("Expected ErrInvalidGrant for an unknown code, got %v", err)
+	}
+}
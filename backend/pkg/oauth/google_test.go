@@ -0,0 +1,79 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTokenInfoServer serves info for the token "valid" and rejects everything else
+func newTokenInfoServer(t *testing.T, info map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("id_token") != "valid" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_token"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(info)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func validTokenInfo() map[string]string {
+	return map[string]string{
+		"iss":            "https://accounts.google.com",
+		"aud":            "client-id",
+		"sub":            "google-user-1",
+		"email":          "user@example.com",
+		"email_verified": "true",
+		"exp":            strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10),
+	}
+}
+
+func TestGoogleVerifier_Verify(t *testing.T) {
+	server := newTokenInfoServer(t, validTokenInfo())
+	verifier := NewGoogleVerifierWithURL("client-id", server.URL)
+
+	identity, err := verifier.Verify(context.Background(), "valid")
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if identity.Subject != "google-user-1" || identity.Email != "user@example.com" || !identity.EmailVerified {
+		t.Errorf("Unexpected identity: %+v", identity)
+	}
+}
+
+func TestGoogleVerifier_RejectsInvalidTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		mutate func(map[string]string)
+	}{
+		{name: "rejected by google", token: "forged", mutate: func(map[string]string) {}},
+		{name: "empty token", token: "", mutate: func(map[string]string) {}},
+		{name: "other audience", token: "valid", mutate: func(info map[string]string) { info["aud"] = "someone-else" }},
+		{name: "other issuer", token: "valid", mutate: func(info map[string]string) { info["iss"] = "evil.example.com" }},
+		{name: "expired", token: "valid", mutate: func(info map[string]string) {
+			info["exp"] = strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := validTokenInfo()
+			tt.mutate(info)
+			verifier := NewGoogleVerifierWithURL("client-id", newTokenInfoServer(t, info).URL)
+
+			if _, err := verifier.Verify(context.Background(), tt.token); !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("Expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package providers
+
+import (
+	"errors"
+
+	"github.com/lightshare/backend/pkg/colorconv"
+)
+
+// Color harmony modes accepted by HarmonyHues
+const (
+	HarmonyComplementary      = "complementary"
+	HarmonyTriadic            = "triadic"
+	HarmonyAnalogous          = "analogous"
+	HarmonySplitComplementary = "split_complementary"
+)
+
+const (
+	// AnalogousStepDegrees is the hue distance between neighboring analogous colors
+	AnalogousStepDegrees = 30
+	// splitComplementaryOffset is how far the split colors sit either side of the complement
+	splitComplementaryOffset = 30
+)
+
+// ErrInvalidHarmonyMode is returned for a color harmony mode HarmonyHues does not know
+var ErrInvalidHarmonyMode = errors.New("invalid color harmony mode")
+
+// Complementary returns hue and the hue opposite it on the color wheel
+func Complementary(hue float64) []float64 {
+	return []float64{colorconv.WrapHue(hue), colorconv.WrapHue(hue + 180)}
+}
+
+// Triadic returns hue and the two hues evenly spaced from it around the color wheel
+func Triadic(hue float64) []float64 {
+	return []float64{colorconv.WrapHue(hue), colorconv.WrapHue(hue + 120), colorconv.WrapHue(hue + 240)}
+}
+
+// Analogous returns count hues, stepDegrees apart, centered on hue. With an even count the
+// extra hue falls on the clockwise side.
+func Analogous(hue float64, count int, stepDegrees float64) []float64 {
+	if count <= 0 {
+		return []float64{}
+	}
+
+	hues := make([]float64, count)
+	first := hue - float64((count-1)/2)*stepDegrees
+	for i := range hues {
+		hues[i] = colorconv.WrapHue(first + float64(i)*stepDegrees)
+	}
+	return hues
+}
+
+// SplitComplementary returns hue and the two hues either side of its complement
+func SplitComplementary(hue float64) []float64 {
+	return []float64{
+		colorconv.WrapHue(hue),
+		colorconv.WrapHue(hue + 180 - splitComplementaryOffset),
+		colorconv.WrapHue(hue + 180 + splitComplementaryOffset),
+	}
+}
+
+// HarmonyHues returns count hues in the given harmony with hue. Analogous palettes spread
+// out by AnalogousStepDegrees per color; the fixed palettes of the other modes repeat to
+// fill count. A count of 0 or less returns the mode's own palette, 3 hues for analogous.
+func HarmonyHues(mode string, hue float64, count int) ([]float64, error) {
+	var palette []float64
+	switch mode {
+	case HarmonyComplementary:
+		palette = Complementary(hue)
+	case HarmonyTriadic:
+		palette = Triadic(hue)
+	case HarmonySplitComplementary:
+		palette = SplitComplementary(hue)
+	case HarmonyAnalogous:
+		if count <= 0 {
+			count = 3
+		}
+		return Analogous(hue, count, AnalogousStepDegrees), nil
+	default:
+		return nil, ErrInvalidHarmonyMode
+	}
+
+	if count <= 0 {
+		return palette, nil
+	}
+	hues := make([]float64, count)
+	for i := range hues {
+		hues[i] = palette[i%len(palette)]
+	}
+	return hues, nil
+}
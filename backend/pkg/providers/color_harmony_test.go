@@ -0,0 +1,86 @@
+package providers
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 0.01
+}
+
+func hueListsClose(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !closeTo(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHarmonyModes(t *testing.T) {
+	tests := []struct {
+		name string
+		got  []float64
+		want []float64
+	}{
+		{"complementary", Complementary(30), []float64{30, 210}},
+		{"complementary wraps", Complementary(270), []float64{270, 90}},
+		{"complementary of 360", Complementary(360), []float64{0, 180}},
+		{"triadic", Triadic(0), []float64{0, 120, 240}},
+		{"triadic wraps", Triadic(300), []float64{300, 60, 180}},
+		{"triadic negative hue", Triadic(-30), []float64{330, 90, 210}},
+		{"analogous odd count", Analogous(30, 3, 30), []float64{0, 30, 60}},
+		{"analogous even count", Analogous(30, 4, 15), []float64{15, 30, 45, 60}},
+		{"analogous wraps below 0", Analogous(10, 5, 20), []float64{330, 350, 10, 30, 50}},
+		{"analogous single", Analogous(200, 1, 30), []float64{200}},
+		{"analogous empty", Analogous(200, 0, 30), []float64{}},
+		{"split complementary", SplitComplementary(30), []float64{30, 180, 240}},
+		{"split complementary wraps", SplitComplementary(200), []float64{200, 350, 50}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !hueListsClose(tt.got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, tt.got)
+			}
+		})
+	}
+}
+
+func TestHarmonyHues(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  string
+		hue   float64
+		count int
+		want  []float64
+	}{
+		{"complementary own size", HarmonyComplementary, 30, 0, []float64{30, 210}},
+		{"complementary repeats", HarmonyComplementary, 30, 3, []float64{30, 210, 30}},
+		{"triadic truncated", HarmonyTriadic, 30, 2, []float64{30, 150}},
+		{"split complementary", HarmonySplitComplementary, 0, 0, []float64{0, 150, 210}},
+		{"analogous default count", HarmonyAnalogous, 30, 0, []float64{0, 30, 60}},
+		{"analogous count", HarmonyAnalogous, 30, 5, []float64{330, 0, 30, 60, 90}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hues, err := HarmonyHues(tt.mode, tt.hue, tt.count)
+			if err != nil {
+				t.Fatalf("HarmonyHues failed: %v", err)
+			}
+			if !hueListsClose(hues, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, hues)
+			}
+		})
+	}
+
+	if _, err := HarmonyHues("monochrome", 30, 3); !errors.Is(err, ErrInvalidHarmonyMode) {
+		t.Errorf("Expected ErrInvalidHarmonyMode, got %v", err)
+	}
+}
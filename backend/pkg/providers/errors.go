@@ -0,0 +1,238 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lightshare/backend/pkg/providers/govee"
+	"github.com/lightshare/backend/pkg/providers/homeassistant"
+	"github.com/lightshare/backend/pkg/providers/hue"
+	"github.com/lightshare/backend/pkg/providers/lifx"
+	"github.com/lightshare/backend/pkg/providers/nanoleaf"
+	"github.com/lightshare/backend/pkg/providers/wiz"
+)
+
+var (
+	// ErrUnauthorized is returned when a provider rejects the stored access token
+	ErrUnauthorized = errors.New("provider token unauthorized")
+	// ErrNotImplemented matches any NotImplementedError via errors.Is
+	ErrNotImplemented = errors.New("provider operation not implemented")
+	// ErrProviderDisabled is returned when creating a client of a provider that is not enabled
+	ErrProviderDisabled = errors.New("provider is disabled")
+	// ErrPartialSuccess matches any PartialSuccessError via errors.Is
+	ErrPartialSuccess = errors.New("action partially applied")
+	// ErrCapabilityNotSupported matches any CapabilityNotSupportedError via errors.Is
+	ErrCapabilityNotSupported = errors.New("capability not supported by provider")
+)
+
+// CapabilityNotSupportedError is returned for operations none of a provider's devices
+// support, such as infrared outside of LIFX
+type CapabilityNotSupportedError struct {
+	Provider   Provider
+	Capability string
+}
+
+func (e *CapabilityNotSupportedError) Error() string {
+	return fmt.Sprintf("%s devices do not support %s", e.Provider, e.Capability)
+}
+
+// Is reports whether target is ErrCapabilityNotSupported
+func (e *CapabilityNotSupportedError) Is(target error) bool {
+	return target == ErrCapabilityNotSupported
+}
+
+// NotImplementedError is returned when a provider does not (yet) support an operation
+type NotImplementedError struct {
+	Provider  Provider
+	Operation string
+}
+
+func (e *NotImplementedError) Error() string {
+	return fmt.Sprintf("%s provider does not implement %s", e.Provider, e.Operation)
+}
+
+// Is reports whether target is ErrNotImplemented
+func (e *NotImplementedError) Is(target error) bool {
+	return target == ErrNotImplemented
+}
+
+// NoEffects can be embedded by clients whose provider has no effects support;
+// its effect methods return a NotImplementedError
+type NoEffects struct {
+	Provider Provider
+}
+
+// Pulse is not supported by this provider
+func (n NoEffects) Pulse(_, _ string, _ *DeviceColor, _ int, _ float64) error {
+	return &NotImplementedError{Provider: n.Provider, Operation: "pulse effect"}
+}
+
+// Breathe is not supported by this provider
+func (n NoEffects) Breathe(_, _ string, _ *DeviceColor, _ int, _ float64) error {
+	return &NotImplementedError{Provider: n.Provider, Operation: "breathe effect"}
+}
+
+// Flame is not supported by this provider
+func (n NoEffects) Flame(_, _ string, _, _ float64) error {
+	return &NotImplementedError{Provider: n.Provider, Operation: "flame effect"}
+}
+
+// Move is not supported by this provider
+func (n NoEffects) Move(_, _, _ string, _, _ float64) error {
+	return &NotImplementedError{Provider: n.Provider, Operation: "move effect"}
+}
+
+// Waveform is not supported by this provider
+func (n NoEffects) Waveform(_, _ string, _ WaveformParams) error {
+	return &NotImplementedError{Provider: n.Provider, Operation: "waveform effect"}
+}
+
+// RateLimitError is returned when a provider throttles a request
+type RateLimitError struct {
+	Provider   Provider
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s rate limit exceeded: retry after %s", e.Provider, e.RetryAfter)
+}
+
+// StatusError is returned when a provider responds with an unexpected HTTP status code
+type StatusError struct {
+	Provider   Provider
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned unexpected status code: %d", e.Provider, e.StatusCode)
+}
+
+// PartialSuccessError is returned when an action was applied to some of the selected
+// devices but not others. Succeeded and Failed hold device IDs.
+type PartialSuccessError struct {
+	Provider  Provider
+	Succeeded []string
+	Failed    []string
+}
+
+func (e *PartialSuccessError) Error() string {
+	return fmt.Sprintf("%s applied the action to %d of %d devices", e.Provider, len(e.Succeeded), len(e.Succeeded)+len(e.Failed))
+}
+
+// Is reports whether target is ErrPartialSuccess
+func (e *PartialSuccessError) Is(target error) bool {
+	return target == ErrPartialSuccess
+}
+
+// convertLIFXError maps LIFX client errors to provider-agnostic error types
+func convertLIFXError(err error) error {
+	var rateLimitErr *lifx.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return &RateLimitError{Provider: ProviderLIFX, RetryAfter: rateLimitErr.RetryAfter}
+	}
+	var statusErr *lifx.StatusError
+	if errors.As(err, &statusErr) {
+		return &StatusError{Provider: ProviderLIFX, StatusCode: statusErr.StatusCode}
+	}
+	var partialErr *lifx.PartialSuccessError
+	if errors.As(err, &partialErr) {
+		converted := &PartialSuccessError{Provider: ProviderLIFX, Succeeded: []string{}, Failed: []string{}}
+		for _, result := range partialErr.Results {
+			if result.OK() {
+				converted.Succeeded = append(converted.Succeeded, result.ID)
+			} else {
+				converted.Failed = append(converted.Failed, result.ID)
+			}
+		}
+		return converted
+	}
+	if errors.Is(err, lifx.ErrUnauthorized) {
+		return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+	return err
+}
+
+// convertHueError maps Hue client errors to provider-agnostic error types
+func convertHueError(err error) error {
+	var rateLimitErr *hue.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return &RateLimitError{Provider: ProviderHue, RetryAfter: rateLimitErr.RetryAfter}
+	}
+	var statusErr *hue.StatusError
+	if errors.As(err, &statusErr) {
+		return &StatusError{Provider: ProviderHue, StatusCode: statusErr.StatusCode}
+	}
+	var capabilityErr *hue.CapabilityNotSupportedError
+	if errors.As(err, &capabilityErr) {
+		return &NotImplementedError{Provider: ProviderHue, Operation: capabilityErr.Capability}
+	}
+	if errors.Is(err, hue.ErrUnauthorized) {
+		return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+	return err
+}
+
+// convertNanoleafError maps Nanoleaf client errors to provider-agnostic error types
+func convertNanoleafError(err error) error {
+	var statusErr *nanoleaf.StatusError
+	if errors.As(err, &statusErr) {
+		return &StatusError{Provider: ProviderNanoleaf, StatusCode: statusErr.StatusCode}
+	}
+	var capabilityErr *nanoleaf.CapabilityNotSupportedError
+	if errors.As(err, &capabilityErr) {
+		return &NotImplementedError{Provider: ProviderNanoleaf, Operation: capabilityErr.Capability}
+	}
+	if errors.Is(err, nanoleaf.ErrUnauthorized) || errors.Is(err, nanoleaf.ErrInvalidToken) {
+		return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+	return err
+}
+
+// convertWiZError maps WiZ client errors to provider-agnostic error types
+func convertWiZError(err error) error {
+	var statusErr *wiz.StatusError
+	if errors.As(err, &statusErr) {
+		return &StatusError{Provider: ProviderWiZ, StatusCode: statusErr.StatusCode}
+	}
+	var capabilityErr *wiz.CapabilityNotSupportedError
+	if errors.As(err, &capabilityErr) {
+		return &NotImplementedError{Provider: ProviderWiZ, Operation: capabilityErr.Capability}
+	}
+	if errors.Is(err, wiz.ErrUnauthorized) {
+		return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+	return err
+}
+
+// convertGoveeError maps Govee client errors to provider-agnostic error types
+func convertGoveeError(err error) error {
+	var statusErr *govee.StatusError
+	if errors.As(err, &statusErr) {
+		return &StatusError{Provider: ProviderGovee, StatusCode: statusErr.StatusCode}
+	}
+	var capabilityErr *govee.CapabilityNotSupportedError
+	if errors.As(err, &capabilityErr) {
+		return &NotImplementedError{Provider: ProviderGovee, Operation: capabilityErr.Capability}
+	}
+	if errors.Is(err, govee.ErrUnauthorized) {
+		return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+	return err
+}
+
+// convertHomeAssistantError maps Home Assistant client errors to provider-agnostic error types
+func convertHomeAssistantError(err error) error {
+	var statusErr *homeassistant.StatusError
+	if errors.As(err, &statusErr) {
+		return &StatusError{Provider: ProviderHomeAssistant, StatusCode: statusErr.StatusCode}
+	}
+	var capabilityErr *homeassistant.CapabilityNotSupportedError
+	if errors.As(err, &capabilityErr) {
+		return &NotImplementedError{Provider: ProviderHomeAssistant, Operation: capabilityErr.Capability}
+	}
+	if errors.Is(err, homeassistant.ErrUnauthorized) || errors.Is(err, homeassistant.ErrInvalidToken) {
+		return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+	return err
+}
@@ -0,0 +1,489 @@
+// Package govee provides a client for the Govee developer API
+package govee
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lightshare/backend/pkg/colorconv"
+)
+
+const (
+	goveeAPIBaseURL = "https://developer-api.govee.com"
+	requestTimeout  = 10 * time.Second
+
+	// Default color temperature range of Govee lights, used when a device reports none
+	minKelvin = 2000
+	maxKelvin = 9000
+)
+
+// supportedCmdCapabilities maps Govee's supportCmds entries to capabilities. "turn" is
+// supported by every light, so it adds none.
+var supportedCmdCapabilities = map[string]string{
+	"brightness": "brightness",
+	"color":      "color",
+	"colorTem":   "temperature",
+}
+
+// AccountInfo contains information about a Govee account
+type AccountInfo struct {
+	// Additional metadata
+	Metadata map[string]interface{}
+	// ProviderAccountID identifies the account the API key belongs to
+	ProviderAccountID string
+	// Email of the account
+	Email string
+}
+
+// Client talks to the Govee developer API using an account's API key
+type Client struct {
+	ctx        context.Context
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewClient creates a new Govee client whose requests time out after timeout,
+// or after 10s when timeout is 0
+func NewClient(timeout time.Duration) *Client {
+	client := NewClientWithBaseURL(goveeAPIBaseURL)
+	if timeout > 0 {
+		client.httpClient.Timeout = timeout
+	}
+	return client
+}
+
+// NewClientWithBaseURL creates a new Govee client targeting a custom API base URL
+// This is primarily useful for pointing the client at a mock server in tests
+func NewClientWithBaseURL(baseURL string) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: requestTimeout,
+		},
+		ctx:     context.Background(),
+		baseURL: baseURL,
+	}
+}
+
+// WithContext returns a copy of the client whose requests carry ctx, so they are
+// cancelled with it
+func (c *Client) WithContext(ctx context.Context) *Client {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// Device represents a Govee light
+type Device struct {
+	Color        *DeviceColor
+	Metadata     map[string]interface{}
+	Raw          json.RawMessage // Original Govee device JSON for this light
+	ID           string
+	Label        string
+	Power        string
+	Capabilities []string
+	Brightness   float64
+	Connected    bool
+	Reachable    bool
+}
+
+// DeviceColor represents color information
+type DeviceColor struct {
+	Hue        float64 // 0-360
+	Saturation float64 // 0.0-1.0
+	Kelvin     int     // 2000-9000
+}
+
+// apiResponse is the envelope of every Govee API response
+type apiResponse struct {
+	Data    json.RawMessage `json:"data"`
+	Message string          `json:"message"`
+	Code    int             `json:"code"`
+}
+
+// userDevices is the account and device list returned by /v1/user/devices
+type userDevices struct {
+	Email   string            `json:"email"`
+	Devices []json.RawMessage `json:"devices"`
+}
+
+// goveeDevice is a light registered to the account
+type goveeDevice struct {
+	Device       string   `json:"device"` // MAC-like device ID, e.g. "AA:BB:CC:DD:EE:FF:11:22"
+	Model        string   `json:"model"`  // Product model, e.g. "H6159"
+	DeviceName   string   `json:"deviceName"`
+	SupportCmds  []string `json:"supportCmds"`
+	Controllable bool     `json:"controllable"`
+	Retrievable  bool     `json:"retrievable"`
+}
+
+// deviceState is the current state of a light, reported by Govee as a list of
+// single-property objects
+type deviceState struct {
+	Color      *rgb
+	PowerState string
+	Brightness int
+	ColorTem   int
+	Online     bool
+}
+
+// rgb is a color as Govee reports and accepts it
+type rgb struct {
+	R int `json:"r"`
+	G int `json:"g"`
+	B int `json:"b"`
+}
+
+// ValidateToken validates the API key by listing the account's devices, and returns
+// the account's email. Govee has no account ID, so the email identifies the account,
+// falling back to a fingerprint of the key when Govee reports none.
+func (c *Client) ValidateToken(token string) (*AccountInfo, error) {
+	user, err := c.getUserDevices(token)
+	if err != nil {
+		return nil, err
+	}
+
+	accountID := user.Email
+	if accountID == "" {
+		sum := sha256.Sum256([]byte(token))
+		accountID = "key-" + hex.EncodeToString(sum[:8])
+	}
+
+	return &AccountInfo{
+		ProviderAccountID: accountID,
+		Email:             user.Email,
+		Metadata: map[string]interface{}{
+			"lights_count": len(user.Devices),
+		},
+	}, nil
+}
+
+// GetAccountInfo retrieves information about the account
+// For Govee, this is the same as ValidateToken
+func (c *Client) GetAccountInfo(token string) (*AccountInfo, error) {
+	return c.ValidateToken(token)
+}
+
+// ListDevices returns every light of the account with its current state. Lights whose
+// state cannot be read are listed as unreachable.
+func (c *Client) ListDevices(token string) ([]*Device, error) {
+	devices, raws, err := c.listDevices(token)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Device, 0, len(devices))
+	for i, d := range devices {
+		device, err := c.getDevice(token, d, raws[i])
+		if err != nil {
+			if errors.Is(err, ErrUnauthorized) {
+				return nil, err
+			}
+			device = convertDevice(d, nil, raws[i])
+		}
+		result = append(result, device)
+	}
+	return result, nil
+}
+
+// GetDevice returns a specific light by its device ID
+func (c *Client) GetDevice(token, deviceID string) (*Device, error) {
+	devices, raws, err := c.listDevices(token)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, d := range devices {
+		if strings.EqualFold(d.Device, deviceID) {
+			return c.getDevice(token, d, raws[i])
+		}
+	}
+	return nil, fmt.Errorf("device not found: %s", deviceID)
+}
+
+// getDevice reads the state of a light and converts it to a Device. Lights that cannot
+// report their state are returned without one.
+func (c *Client) getDevice(token string, d goveeDevice, raw json.RawMessage) (*Device, error) {
+	if !d.Retrievable {
+		return convertDevice(d, nil, raw), nil
+	}
+
+	query := url.Values{"device": {d.Device}, "model": {d.Model}}
+	data, err := c.do(token, http.MethodGet, "/v1/devices/state?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := parseState(data)
+	if err != nil {
+		return nil, err
+	}
+	return convertDevice(d, state, raw), nil
+}
+
+// parseState decodes the property list of a /v1/devices/state response
+func parseState(data json.RawMessage) (*deviceState, error) {
+	var response struct {
+		Properties []map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	var state deviceState
+	for _, property := range response.Properties {
+		for name, value := range property {
+			var err error
+			switch name {
+			case "online":
+				// Some models report online as a string
+				var online interface{}
+				err = json.Unmarshal(value, &online)
+				state.Online = online == true || online == "true"
+			case "powerState":
+				err = json.Unmarshal(value, &state.PowerState)
+			case "brightness":
+				err = json.Unmarshal(value, &state.Brightness)
+			case "colorTem", "colorTemInKelvin":
+				err = json.Unmarshal(value, &state.ColorTem)
+			case "color":
+				err = json.Unmarshal(value, &state.Color)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode %s: %w", name, err)
+			}
+		}
+	}
+	return &state, nil
+}
+
+// convertDevice converts a light and its state to the Device type. A nil state marks the
+// light unreachable.
+func convertDevice(d goveeDevice, state *deviceState, raw json.RawMessage) *Device {
+	device := &Device{
+		ID:           d.Device,
+		Label:        d.DeviceName,
+		Power:        "off",
+		Capabilities: []string{},
+		Metadata: map[string]interface{}{
+			"model": d.Model,
+		},
+		Raw: raw,
+	}
+
+	for _, cmd := range d.SupportCmds {
+		if capability, ok := supportedCmdCapabilities[cmd]; ok {
+			device.Capabilities = append(device.Capabilities, capability)
+		}
+	}
+
+	if state == nil || !state.Online {
+		return device
+	}
+
+	device.Connected = true
+	device.Reachable = true
+	device.Brightness = float64(state.Brightness) / 100
+	if state.PowerState == "on" {
+		device.Power = "on"
+	}
+
+	switch {
+	case state.ColorTem > 0:
+		device.Color = &DeviceColor{Kelvin: state.ColorTem}
+	case state.Color != nil && (state.Color.R > 0 || state.Color.G > 0 || state.Color.B > 0):
+		hue, saturation := colorconv.ChannelsToHueSaturation(state.Color.R, state.Color.G, state.Color.B)
+		device.Color = &DeviceColor{Hue: hue, Saturation: saturation}
+	}
+
+	return device
+}
+
+// SetPower turns light(s) on or off
+// Govee switches power instantly, so duration is ignored
+func (c *Client) SetPower(token, selector string, state bool, _ float64) error {
+	value := "off"
+	if state {
+		value = "on"
+	}
+	return c.control(token, selector, "turn", value)
+}
+
+// SetBrightness adjusts brightness (0.0-1.0), sent to Govee as a percentage
+// Govee changes brightness instantly, so duration is ignored
+func (c *Client) SetBrightness(token, selector string, level, _ float64) error {
+	brightness := max(0, min(100, int(math.Round(level*100))))
+	return c.control(token, selector, "brightness", brightness)
+}
+
+// SetColor sets the hue and saturation, sent to Govee as RGB
+// Govee changes color instantly, so duration is ignored
+func (c *Client) SetColor(token, selector string, color *DeviceColor, _ float64) error {
+	r, g, b := colorconv.HueSaturationToChannels(color.Hue, color.Saturation)
+	return c.control(token, selector, "color", rgb{R: r, G: g, B: b})
+}
+
+// SetColorTemperature sets the white balance, clamped to the lights' 2000-9000K range
+// Govee changes color temperature instantly, so duration is ignored
+func (c *Client) SetColorTemperature(token, selector string, kelvin int, _ float64) error {
+	kelvin = max(minKelvin, min(maxKelvin, kelvin))
+	return c.control(token, selector, "colorTem", kelvin)
+}
+
+// Pulse is not supported by Govee
+func (c *Client) Pulse(_, _ string, _ *DeviceColor, _ int, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "pulse effect"}
+}
+
+// Breathe is not supported by Govee
+func (c *Client) Breathe(_, _ string, _ *DeviceColor, _ int, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "breathe effect"}
+}
+
+// Flame is not supported by Govee
+func (c *Client) Flame(_, _ string, _, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "flame effect"}
+}
+
+// Move is not supported by Govee
+func (c *Client) Move(_, _, _ string, _, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "move effect"}
+}
+
+// control sends a command to every light a selector targets, one request per light
+func (c *Client) control(token, selector, name string, value interface{}) error {
+	devices, err := c.resolveSelector(token, selector)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range devices {
+		body, err := json.Marshal(map[string]interface{}{
+			"device": d.Device,
+			"model":  d.Model,
+			"cmd":    map[string]interface{}{"name": name, "value": value},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		if _, err := c.do(token, http.MethodPut, "/v1/devices/control", body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveSelector maps a LightShare selector to the lights it targets. Govee commands
+// need the model of each light, so even "id:" selectors are looked up in the device list.
+// Govee has no rooms or homes, so only "all" and "id:" are supported.
+func (c *Client) resolveSelector(token, selector string) ([]goveeDevice, error) {
+	deviceID, byID := strings.CutPrefix(selector, "id:")
+	if !byID && selector != "all" {
+		return nil, fmt.Errorf("unsupported selector: %s", selector)
+	}
+
+	devices, _, err := c.listDevices(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []goveeDevice
+	for _, d := range devices {
+		if !d.Controllable {
+			continue
+		}
+		if !byID || strings.EqualFold(d.Device, deviceID) {
+			matched = append(matched, d)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("selector not found: %s", selector)
+	}
+	return matched, nil
+}
+
+// listDevices fetches the lights of the account, along with the raw JSON of each
+func (c *Client) listDevices(token string) ([]goveeDevice, []json.RawMessage, error) {
+	user, err := c.getUserDevices(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	devices := make([]goveeDevice, len(user.Devices))
+	for i, raw := range user.Devices {
+		if err := json.Unmarshal(raw, &devices[i]); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode device: %w", err)
+		}
+	}
+	return devices, user.Devices, nil
+}
+
+// getUserDevices fetches the account and device list the API key belongs to
+func (c *Client) getUserDevices(token string) (*userDevices, error) {
+	data, err := c.do(token, http.MethodGet, "/v1/user/devices", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var user userDevices
+	if err := json.Unmarshal(data, &user); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &user, nil
+}
+
+// do performs a Govee API request and returns the data of the response envelope
+func (c *Client) do(token, method, path string, body []byte) (json.RawMessage, error) {
+	var reqBody io.Reader = http.NoBody
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Govee-API-Key", token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Govee API: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			_ = closeErr
+		}
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ErrUnauthorized
+	case http.StatusOK:
+	default:
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	var envelope apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if envelope.Code != http.StatusOK {
+		return nil, &APIError{Code: envelope.Code, Message: envelope.Message}
+	}
+
+	return envelope.Data, nil
+}
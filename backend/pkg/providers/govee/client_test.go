@@ -0,0 +1,252 @@
+package govee
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testUserDevices is the account and device list served by the mock Govee API
+const testUserDevices = `{
+	"email": "user@example.com",
+	"devices": [
+		{"device": "AA:BB:CC:DD:EE:FF:00:01", "model": "H6159", "deviceName": "Strip", "controllable": true, "retrievable": true, "supportCmds": ["turn", "brightness", "color", "colorTem"]},
+		{"device": "AA:BB:CC:DD:EE:FF:00:02", "model": "H6008", "deviceName": "Bulb", "controllable": true, "retrievable": true, "supportCmds": ["turn", "brightness", "colorTem"]},
+		{"device": "AA:BB:CC:DD:EE:FF:00:03", "model": "H6052", "deviceName": "Lamp", "controllable": true, "retrievable": true, "supportCmds": ["turn", "brightness"]}
+	]
+}`
+
+// testStates are the states of the lights served by the mock Govee API; Lamp is unreachable
+var testStates = map[string]string{
+	"AA:BB:CC:DD:EE:FF:00:01": `{"properties": [{"online": true}, {"powerState": "on"}, {"brightness": 80}, {"color": {"r": 0, "g": 0, "b": 255}}]}`,
+	"AA:BB:CC:DD:EE:FF:00:02": `{"properties": [{"online": "true"}, {"powerState": "off"}, {"brightness": 40}, {"colorTem": 2700}]}`,
+}
+
+// controlRequest captures a command sent to the mock Govee API
+type controlRequest struct {
+	Cmd struct {
+		Value interface{} `json:"value"`
+		Name  string      `json:"name"`
+	} `json:"cmd"`
+	Device string `json:"device"`
+	Model  string `json:"model"`
+}
+
+// newTestServer returns a client for a mock Govee API accepting the API key "test-key"
+func newTestServer(t *testing.T) (*Client, *[]controlRequest) {
+	t.Helper()
+	var commands []controlRequest
+
+	respond := func(w http.ResponseWriter, data string) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"code": 200, "message": "Success", "data": ` + data + `}`))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Govee-API-Key") != "test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/user/devices":
+			respond(w, testUserDevices)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/devices/state":
+			state, ok := testStates[r.URL.Query().Get("device")]
+			if !ok {
+				_, _ = w.Write([]byte(`{"code": 400, "message": "devices not exist"}`))
+				return
+			}
+			respond(w, state)
+		case r.Method == http.MethodPut && r.URL.Path == "/v1/devices/control":
+			var command controlRequest
+			_ = json.NewDecoder(r.Body).Decode(&command)
+			commands = append(commands, command)
+			respond(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return NewClientWithBaseURL(server.URL), &commands
+}
+
+func TestValidateToken(t *testing.T) {
+	client, _ := newTestServer(t)
+
+	info, err := client.ValidateToken("test-key")
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	if info.Email != "user@example.com" || info.ProviderAccountID != "user@example.com" {
+		t.Errorf("Unexpected account info: %+v", info)
+	}
+	if info.Metadata["lights_count"] != 3 {
+		t.Errorf("Expected 3 lights, got %v", info.Metadata["lights_count"])
+	}
+
+	if _, err := client.ValidateToken("wrong-key"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestListDevices(t *testing.T) {
+	client, _ := newTestServer(t)
+
+	devices, err := client.ListDevices("test-key")
+	if err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	if len(devices) != 3 {
+		t.Fatalf("Expected 3 devices, got %d", len(devices))
+	}
+
+	testCases := []struct {
+		wantColor        *DeviceColor
+		name             string
+		wantPower        string
+		wantCapabilities []string
+		wantBrightness   float64
+		wantReachable    bool
+	}{
+		{
+			name:             "RGB strip",
+			wantPower:        "on",
+			wantBrightness:   0.8,
+			wantColor:        &DeviceColor{Hue: 240, Saturation: 1},
+			wantCapabilities: []string{"brightness", "color", "temperature"},
+			wantReachable:    true,
+		},
+		{
+			name:             "white bulb reporting online as a string",
+			wantPower:        "off",
+			wantBrightness:   0.4,
+			wantColor:        &DeviceColor{Kelvin: 2700},
+			wantCapabilities: []string{"brightness", "temperature"},
+			wantReachable:    true,
+		},
+		{
+			name:             "unreachable dimmable lamp",
+			wantPower:        "off",
+			wantCapabilities: []string{"brightness"},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			device := devices[i]
+			if device.Power != tc.wantPower || device.Brightness != tc.wantBrightness || device.Reachable != tc.wantReachable {
+				t.Errorf("Unexpected device state: %+v", device)
+			}
+			if (tc.wantColor == nil) != (device.Color == nil) || (tc.wantColor != nil && *tc.wantColor != *device.Color) {
+				t.Errorf("Expected color %+v, got %+v", tc.wantColor, device.Color)
+			}
+			if len(device.Capabilities) != len(tc.wantCapabilities) {
+				t.Fatalf("Expected capabilities %v, got %v", tc.wantCapabilities, device.Capabilities)
+			}
+			for j, capability := range tc.wantCapabilities {
+				if device.Capabilities[j] != capability {
+					t.Errorf("Expected capabilities %v, got %v", tc.wantCapabilities, device.Capabilities)
+				}
+			}
+		})
+	}
+}
+
+func TestControl(t *testing.T) {
+	testCases := []struct {
+		call        func(c *Client) error
+		wantValue   interface{}
+		name        string
+		wantCmd     string
+		wantDevices []string
+	}{
+		{
+			name:        "power on a single light",
+			call:        func(c *Client) error { return c.SetPower("test-key", "id:AA:BB:CC:DD:EE:FF:00:01", true, 1) },
+			wantDevices: []string{"AA:BB:CC:DD:EE:FF:00:01"},
+			wantCmd:     "turn",
+			wantValue:   "on",
+		},
+		{
+			name:        "brightness as a percentage",
+			call:        func(c *Client) error { return c.SetBrightness("test-key", "id:AA:BB:CC:DD:EE:FF:00:02", 0.42, 0) },
+			wantDevices: []string{"AA:BB:CC:DD:EE:FF:00:02"},
+			wantCmd:     "brightness",
+			wantValue:   42.0,
+		},
+		{
+			name: "color as RGB",
+			call: func(c *Client) error {
+				return c.SetColor("test-key", "id:AA:BB:CC:DD:EE:FF:00:01", &DeviceColor{Hue: 120, Saturation: 1}, 0)
+			},
+			wantDevices: []string{"AA:BB:CC:DD:EE:FF:00:01"},
+			wantCmd:     "color",
+			wantValue:   map[string]interface{}{"r": 0.0, "g": 255.0, "b": 0.0},
+		},
+		{
+			name:        "temperature clamped across all lights",
+			call:        func(c *Client) error { return c.SetColorTemperature("test-key", "all", 12000, 0) },
+			wantDevices: []string{"AA:BB:CC:DD:EE:FF:00:01", "AA:BB:CC:DD:EE:FF:00:02", "AA:BB:CC:DD:EE:FF:00:03"},
+			wantCmd:     "colorTem",
+			wantValue:   9000.0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, commands := newTestServer(t)
+
+			if err := tc.call(client); err != nil {
+				t.Fatalf("Call failed: %v", err)
+			}
+
+			if len(*commands) != len(tc.wantDevices) {
+				t.Fatalf("Expected %d commands, got %+v", len(tc.wantDevices), *commands)
+			}
+			for i, command := range *commands {
+				if command.Device != tc.wantDevices[i] || command.Model == "" {
+					t.Errorf("Expected command to %s with its model, got %+v", tc.wantDevices[i], command)
+				}
+				if command.Cmd.Name != tc.wantCmd {
+					t.Errorf("Expected command %s, got %s", tc.wantCmd, command.Cmd.Name)
+				}
+				got, _ := json.Marshal(command.Cmd.Value)
+				want, _ := json.Marshal(tc.wantValue)
+				if string(got) != string(want) {
+					t.Errorf("Expected value %s, got %s", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestControl_UnknownSelector(t *testing.T) {
+	client, _ := newTestServer(t)
+
+	if err := client.SetPower("test-key", "id:AA:BB:CC:DD:EE:FF:00:99", true, 0); err == nil {
+		t.Error("Expected error for an unknown device")
+	}
+	if err := client.SetPower("test-key", "group_id:1", true, 0); err == nil {
+		t.Error("Expected error for an unsupported selector")
+	}
+}
+
+func TestEffects_NotSupported(t *testing.T) {
+	client := NewClient(0)
+
+	testCases := map[string]error{
+		"pulse":   client.Pulse("test-key", "all", nil, 3, 1),
+		"breathe": client.Breathe("test-key", "all", nil, 3, 1),
+		"flame":   client.Flame("test-key", "all", 1, 0),
+		"move":    client.Move("test-key", "all", "forward", 1, 0),
+	}
+	for effect, err := range testCases {
+		if !errors.Is(err, ErrCapabilityNotSupported) {
+			t.Errorf("Expected %s to return ErrCapabilityNotSupported, got %v", effect, err)
+		}
+	}
+}
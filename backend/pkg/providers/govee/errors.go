@@ -0,0 +1,46 @@
+package govee
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnauthorized is returned when Govee rejects the API key
+	ErrUnauthorized = errors.New("invalid token: unauthorized")
+	// ErrCapabilityNotSupported matches any CapabilityNotSupportedError via errors.Is
+	ErrCapabilityNotSupported = errors.New("capability not supported by govee")
+)
+
+// CapabilityNotSupportedError is returned for operations Govee has no equivalent for
+type CapabilityNotSupportedError struct {
+	Capability string
+}
+
+func (e *CapabilityNotSupportedError) Error() string {
+	return fmt.Sprintf("govee does not support %s", e.Capability)
+}
+
+// Is reports whether target is ErrCapabilityNotSupported
+func (e *CapabilityNotSupportedError) Is(target error) bool {
+	return target == ErrCapabilityNotSupported
+}
+
+// StatusError is returned when Govee responds with an unexpected status code
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// APIError is returned when Govee reports a failed request in its response body
+type APIError struct {
+	Message string
+	Code    int
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("govee API error %d: %s", e.Code, e.Message)
+}
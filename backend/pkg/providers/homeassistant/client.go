@@ -0,0 +1,450 @@
+// Package homeassistant provides a client for the REST API of a Home Assistant instance,
+// controlling the lights it exposes as light entities
+package homeassistant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lightshare/backend/pkg/colorconv"
+)
+
+const (
+	requestTimeout = 10 * time.Second
+
+	// lightDomain prefixes the entity ID of every light entity
+	lightDomain = "light."
+)
+
+// colorModeCapabilities maps the color modes a light entity supports to capabilities.
+// Every mode but "onoff" implies brightness.
+var colorModeCapabilities = map[string][]string{
+	"brightness": {"brightness"},
+	"color_temp": {"brightness", "temperature"},
+	"hs":         {"brightness", "color"},
+	"xy":         {"brightness", "color"},
+	"rgb":        {"brightness", "color"},
+	"rgbw":       {"brightness", "color"},
+	"rgbww":      {"brightness", "color"},
+}
+
+// AccountInfo contains information about a Home Assistant instance
+type AccountInfo struct {
+	// Additional metadata
+	Metadata map[string]interface{}
+	// ProviderAccountID is the base URL of the instance
+	ProviderAccountID string
+	// Label is the Home Assistant version the instance runs
+	Label string
+}
+
+// Client talks to Home Assistant instances over their REST API. Instances are self-hosted,
+// so each token is a composite "<base_url>|<long_lived_access_token>" of the instance's
+// URL and an access token created in its user profile.
+type Client struct {
+	ctx        context.Context
+	httpClient *http.Client
+}
+
+// NewClient creates a new Home Assistant client whose requests time out after timeout, or
+// after 10s when timeout is 0
+func NewClient(timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = requestTimeout
+	}
+
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		ctx: context.Background(),
+	}
+}
+
+// WithContext returns a copy of the client whose requests carry ctx, so they are
+// cancelled with it
+func (c *Client) WithContext(ctx context.Context) *Client {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// Device represents a Home Assistant light entity
+type Device struct {
+	Color        *DeviceColor
+	Metadata     map[string]interface{}
+	Raw          json.RawMessage // Original Home Assistant state JSON for this entity
+	ID           string
+	Label        string
+	Power        string
+	Capabilities []string
+	Brightness   float64
+	Connected    bool
+	Reachable    bool
+}
+
+// DeviceColor represents color information
+type DeviceColor struct {
+	Hue        float64 // 0-360
+	Saturation float64 // 0.0-1.0
+	Kelvin     int
+}
+
+// endpoint is the instance API a token addresses
+type endpoint struct {
+	baseURL     string // Base URL of the instance, without a trailing slash
+	accessToken string
+}
+
+// entityState is the state of an entity returned by /api/states
+type entityState struct {
+	EntityID   string `json:"entity_id"`
+	State      string `json:"state"`
+	Attributes struct {
+		Brightness          *int     `json:"brightness"` // 0-255, null when off
+		ColorTemp           *int     `json:"color_temp"` // Mireds
+		RGBColor            []int    `json:"rgb_color"`
+		SupportedColorModes []string `json:"supported_color_modes"`
+		FriendlyName        string   `json:"friendly_name"`
+		ColorMode           string   `json:"color_mode"`
+	} `json:"attributes"`
+}
+
+// instanceConfig is the subset of /api/config the client uses
+type instanceConfig struct {
+	Version      string `json:"version"`
+	LocationName string `json:"location_name"`
+}
+
+// parseToken splits a "<base_url>|<token>" composite token into the instance API it addresses
+func parseToken(token string) (*endpoint, error) {
+	baseURL, accessToken, ok := strings.Cut(token, "|")
+	baseURL, accessToken = strings.TrimRight(strings.TrimSpace(baseURL), "/"), strings.TrimSpace(accessToken)
+	if !ok || baseURL == "" || accessToken == "" {
+		return nil, ErrInvalidToken
+	}
+
+	parsed, err := url.Parse(baseURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return &endpoint{baseURL: baseURL, accessToken: accessToken}, nil
+}
+
+// ValidateToken validates the token against GET /api/ and reads the instance version
+// The base URL is used as the account identifier since Home Assistant tokens are per instance
+func (c *Client) ValidateToken(token string) (*AccountInfo, error) {
+	ep, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.do(ep, http.MethodGet, "/api/", nil); err != nil {
+		return nil, err
+	}
+
+	data, err := c.do(ep, http.MethodGet, "/api/config", nil)
+	if err != nil {
+		return nil, err
+	}
+	var config instanceConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &AccountInfo{
+		ProviderAccountID: ep.baseURL,
+		Label:             config.Version,
+		Metadata: map[string]interface{}{
+			"base_url":      ep.baseURL,
+			"location_name": config.LocationName,
+			"version":       config.Version,
+		},
+	}, nil
+}
+
+// GetAccountInfo retrieves information about the instance
+// For Home Assistant, this is the same as ValidateToken
+func (c *Client) GetAccountInfo(token string) (*AccountInfo, error) {
+	return c.ValidateToken(token)
+}
+
+// ListDevices returns every light entity of the instance with its current state
+func (c *Client) ListDevices(token string) ([]*Device, error) {
+	ep, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.do(ep, http.MethodGet, "/api/states", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	devices := make([]*Device, 0, len(raws))
+	for _, raw := range raws {
+		var state entityState
+		if err := json.Unmarshal(raw, &state); err != nil {
+			return nil, fmt.Errorf("failed to decode entity state: %w", err)
+		}
+		if strings.HasPrefix(state.EntityID, lightDomain) {
+			devices = append(devices, convertEntity(&state, raw))
+		}
+	}
+	return devices, nil
+}
+
+// GetDevice returns a light entity by its entity ID, e.g. "light.kitchen"
+func (c *Client) GetDevice(token, deviceID string) (*Device, error) {
+	ep, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(deviceID, lightDomain) {
+		return nil, fmt.Errorf("device not found: %s", deviceID)
+	}
+
+	data, err := c.do(ep, http.MethodGet, "/api/states/"+url.PathEscape(deviceID), nil)
+	if err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("device not found: %s", deviceID)
+		}
+		return nil, err
+	}
+
+	var state entityState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return convertEntity(&state, data), nil
+}
+
+// convertEntity converts the state of a light entity to the Device type. Entities whose
+// integration lost the light report the "unavailable" state and are marked unreachable.
+func convertEntity(state *entityState, raw json.RawMessage) *Device {
+	attributes := state.Attributes
+	device := &Device{
+		ID:           state.EntityID,
+		Label:        attributes.FriendlyName,
+		Power:        "off",
+		Capabilities: colorModesCapabilities(attributes.SupportedColorModes),
+		Metadata: map[string]interface{}{
+			"color_mode":            attributes.ColorMode,
+			"supported_color_modes": attributes.SupportedColorModes,
+		},
+		Raw: raw,
+	}
+	if device.Label == "" {
+		device.Label = state.EntityID
+	}
+
+	if state.State == "unavailable" || state.State == "unknown" {
+		return device
+	}
+
+	device.Connected = true
+	device.Reachable = true
+	if state.State == "on" {
+		device.Power = "on"
+	}
+	if attributes.Brightness != nil {
+		device.Brightness = float64(*attributes.Brightness) / 255
+	}
+
+	hasRGB := len(attributes.RGBColor) == 3
+	hasColorTemp := attributes.ColorTemp != nil && *attributes.ColorTemp > 0
+	switch {
+	case hasColorTemp && (attributes.ColorMode == "color_temp" || !hasRGB):
+		device.Color = &DeviceColor{Kelvin: colorconv.MiredsToKelvin(*attributes.ColorTemp)}
+	case hasRGB:
+		hue, saturation := colorconv.ChannelsToHueSaturation(attributes.RGBColor[0], attributes.RGBColor[1], attributes.RGBColor[2])
+		device.Color = &DeviceColor{Hue: hue, Saturation: saturation}
+	}
+
+	return device
+}
+
+// colorModesCapabilities returns the capabilities of a light supporting modes
+func colorModesCapabilities(modes []string) []string {
+	capabilities := []string{}
+	seen := make(map[string]bool)
+	for _, mode := range modes {
+		for _, capability := range colorModeCapabilities[mode] {
+			if !seen[capability] {
+				seen[capability] = true
+				capabilities = append(capabilities, capability)
+			}
+		}
+	}
+	return capabilities
+}
+
+// SetPower turns light(s) on or off over duration
+func (c *Client) SetPower(token, selector string, state bool, duration float64) error {
+	service := "turn_off"
+	if state {
+		service = "turn_on"
+	}
+	return c.callService(token, selector, service, duration, nil)
+}
+
+// SetBrightness adjusts brightness (0.0-1.0) over duration, sent to Home Assistant as 0-255
+func (c *Client) SetBrightness(token, selector string, level, duration float64) error {
+	brightness := max(0, min(255, int(level*255)))
+	return c.callService(token, selector, "turn_on", duration, map[string]interface{}{
+		"brightness": brightness,
+	})
+}
+
+// SetColor sets the hue and saturation over duration, sent to Home Assistant as RGB
+func (c *Client) SetColor(token, selector string, color *DeviceColor, duration float64) error {
+	r, g, b := colorconv.HueSaturationToChannels(color.Hue, color.Saturation)
+	return c.callService(token, selector, "turn_on", duration, map[string]interface{}{
+		"rgb_color": []int{r, g, b},
+	})
+}
+
+// SetColorTemperature sets the white balance over duration, sent to Home Assistant in mireds.
+// Home Assistant clamps it to the range of each light.
+func (c *Client) SetColorTemperature(token, selector string, kelvin int, duration float64) error {
+	if kelvin <= 0 {
+		return fmt.Errorf("invalid color temperature: %d", kelvin)
+	}
+	return c.callService(token, selector, "turn_on", duration, map[string]interface{}{
+		"color_temp": colorconv.KelvinToMireds(kelvin),
+	})
+}
+
+// Pulse is not supported by Home Assistant
+func (c *Client) Pulse(_, _ string, _ *DeviceColor, _ int, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "pulse effect"}
+}
+
+// Breathe is not supported by Home Assistant
+func (c *Client) Breathe(_, _ string, _ *DeviceColor, _ int, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "breathe effect"}
+}
+
+// Flame is not supported by Home Assistant
+func (c *Client) Flame(_, _ string, _, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "flame effect"}
+}
+
+// Move is not supported by Home Assistant
+func (c *Client) Move(_, _, _ string, _, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "move effect"}
+}
+
+// callService calls a light service on the entities a selector targets. A positive
+// duration is passed as the transition, in seconds.
+func (c *Client) callService(token, selector, service string, duration float64, data map[string]interface{}) error {
+	ep, err := parseToken(token)
+	if err != nil {
+		return err
+	}
+
+	entityID, err := resolveSelector(selector)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{"entity_id": entityID}
+	for key, value := range data {
+		body[key] = value
+	}
+	if duration > 0 {
+		body["transition"] = duration
+	}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	_, err = c.do(ep, http.MethodPost, "/api/services/light/"+service, bodyBytes)
+	return err
+}
+
+// resolveSelector maps a LightShare selector to the entity_id of a service call: "all"
+// targets every light, and "id:" one or more comma-separated light entities. Areas are
+// not exposed by the REST API, so group and location selectors are not supported.
+func resolveSelector(selector string) (interface{}, error) {
+	if selector == "all" {
+		return "all", nil
+	}
+
+	ids, ok := strings.CutPrefix(selector, "id:")
+	if !ok {
+		return nil, fmt.Errorf("unsupported selector: %s", selector)
+	}
+
+	var entityIDs []string
+	for _, id := range strings.Split(ids, ",") {
+		id = strings.TrimPrefix(strings.TrimSpace(id), "id:")
+		if !strings.HasPrefix(id, lightDomain) {
+			return nil, fmt.Errorf("selector not found: %s", selector)
+		}
+		entityIDs = append(entityIDs, id)
+	}
+	if len(entityIDs) == 1 {
+		return entityIDs[0], nil
+	}
+	return entityIDs, nil
+}
+
+// do performs a Home Assistant API request and returns the response body
+func (c *Client) do(ep *endpoint, method, path string, body []byte) ([]byte, error) {
+	var reqBody io.Reader = http.NoBody
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, method, ep.baseURL+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+ep.accessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Home Assistant API: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			_ = closeErr
+		}
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ErrUnauthorized
+	case http.StatusOK, http.StatusCreated:
+	default:
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,231 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testStates are the entity states served by the mock Home Assistant API: a color light
+// that is on, a white-only light that is off, an unavailable light and a switch
+var testStates = map[string]string{
+	"light.kitchen":  `{"entity_id": "light.kitchen", "state": "on", "attributes": {"friendly_name": "Kitchen", "brightness": 204, "rgb_color": [0, 0, 255], "color_mode": "hs", "supported_color_modes": ["color_temp", "hs"]}}`,
+	"light.bedroom":  `{"entity_id": "light.bedroom", "state": "off", "attributes": {"friendly_name": "Bedroom", "brightness": null, "color_temp": 370, "supported_color_modes": ["color_temp"]}}`,
+	"light.porch":    `{"entity_id": "light.porch", "state": "unavailable", "attributes": {"friendly_name": "Porch", "supported_color_modes": ["onoff"]}}`,
+	"switch.kettle":  `{"entity_id": "switch.kettle", "state": "off", "attributes": {"friendly_name": "Kettle"}}`,
+	"light.nameless": `{"entity_id": "light.nameless", "state": "on", "attributes": {"brightness": 255, "supported_color_modes": ["brightness"]}}`,
+}
+
+// testStateOrder is the order /api/states lists the entities in
+var testStateOrder = []string{"light.kitchen", "light.bedroom", "switch.kettle", "light.porch", "light.nameless"}
+
+// serviceCall captures a light service call sent to the mock Home Assistant API
+type serviceCall struct {
+	Data    map[string]interface{}
+	Service string
+}
+
+// newTestServer returns a client and a token for a mock Home Assistant API accepting the
+// access token "test-token"
+func newTestServer(t *testing.T) (*Client, string, *[]serviceCall) {
+	t.Helper()
+	var calls []serviceCall
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/":
+			_, _ = w.Write([]byte(`{"message": "API running."}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/config":
+			_, _ = w.Write([]byte(`{"version": "2024.10.1", "location_name": "Home"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/states":
+			states := make([]string, len(testStateOrder))
+			for i, id := range testStateOrder {
+				states[i] = testStates[id]
+			}
+			_, _ = w.Write([]byte("[" + strings.Join(states, ",") + "]"))
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/states/"):
+			state, ok := testStates[strings.TrimPrefix(r.URL.Path, "/api/states/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message": "Entity not found."}`))
+				return
+			}
+			_, _ = w.Write([]byte(state))
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/services/light/"):
+			call := serviceCall{Service: strings.TrimPrefix(r.URL.Path, "/api/services/light/")}
+			_ = json.NewDecoder(r.Body).Decode(&call.Data)
+			calls = append(calls, call)
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return NewClient(0), server.URL + "/|test-token", &calls
+}
+
+func TestValidateToken(t *testing.T) {
+	client, token, _ := newTestServer(t)
+
+	info, err := client.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	if info.Label != "2024.10.1" {
+		t.Errorf("Expected the version as label, got %q", info.Label)
+	}
+	if info.Metadata["location_name"] != "Home" {
+		t.Errorf("Expected location_name Home, got %v", info.Metadata["location_name"])
+	}
+
+	ep, _ := parseToken(token)
+	if info.ProviderAccountID != ep.baseURL || strings.HasSuffix(ep.baseURL, "/") {
+		t.Errorf("Expected the base URL without trailing slash as account ID, got %q", info.ProviderAccountID)
+	}
+}
+
+func TestValidateToken_Errors(t *testing.T) {
+	client, token, _ := newTestServer(t)
+	ep, _ := parseToken(token)
+
+	if _, err := client.ValidateToken(ep.baseURL + "|wrong-token"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+
+	for _, invalid := range []string{"test-token", ep.baseURL + "|", "|test-token", "ftp://ha.local|test-token"} {
+		if _, err := client.ValidateToken(invalid); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Expected ErrInvalidToken for %q, got %v", invalid, err)
+		}
+	}
+}
+
+func TestListDevices(t *testing.T) {
+	client, token, _ := newTestServer(t)
+
+	devices, err := client.ListDevices(token)
+	if err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	if len(devices) != 4 {
+		t.Fatalf("Expected 4 lights, got %d", len(devices))
+	}
+
+	kitchen := devices[0]
+	if kitchen.ID != "light.kitchen" || kitchen.Label != "Kitchen" || kitchen.Power != "on" || !kitchen.Reachable {
+		t.Errorf("Unexpected kitchen light: %+v", kitchen)
+	}
+	if kitchen.Brightness != 0.8 {
+		t.Errorf("Expected brightness 0.8, got %v", kitchen.Brightness)
+	}
+	if kitchen.Color == nil || kitchen.Color.Hue != 240 || kitchen.Color.Saturation != 1 {
+		t.Errorf("Expected blue, got %+v", kitchen.Color)
+	}
+	if len(kitchen.Capabilities) != 3 {
+		t.Errorf("Expected brightness, temperature and color capabilities, got %v", kitchen.Capabilities)
+	}
+
+	bedroom := devices[1]
+	if bedroom.Power != "off" || bedroom.Brightness != 0 {
+		t.Errorf("Expected the bedroom light off, got %+v", bedroom)
+	}
+	if bedroom.Color == nil || bedroom.Color.Kelvin != 2703 {
+		t.Errorf("Expected 370 mireds as 2703K, got %+v", bedroom.Color)
+	}
+
+	porch := devices[2]
+	if porch.Reachable || porch.Connected || len(porch.Capabilities) != 0 {
+		t.Errorf("Expected the porch light unreachable without capabilities, got %+v", porch)
+	}
+
+	if devices[3].Label != "light.nameless" {
+		t.Errorf("Expected the entity ID as label without a friendly name, got %q", devices[3].Label)
+	}
+}
+
+func TestGetDevice(t *testing.T) {
+	client, token, _ := newTestServer(t)
+
+	device, err := client.GetDevice(token, "light.bedroom")
+	if err != nil {
+		t.Fatalf("GetDevice failed: %v", err)
+	}
+	if device.Label != "Bedroom" {
+		t.Errorf("Expected the bedroom light, got %+v", device)
+	}
+
+	for _, id := range []string{"light.attic", "switch.kettle"} {
+		if _, err := client.GetDevice(token, id); err == nil {
+			t.Errorf("Expected an error getting %s", id)
+		}
+	}
+}
+
+func TestControl(t *testing.T) {
+	client, token, calls := newTestServer(t)
+
+	if err := client.SetPower(token, "id:light.kitchen", false, 2); err != nil {
+		t.Fatalf("SetPower failed: %v", err)
+	}
+	if err := client.SetBrightness(token, "all", 0.5, 0); err != nil {
+		t.Fatalf("SetBrightness failed: %v", err)
+	}
+	if err := client.SetColor(token, "id:light.kitchen,id:light.bedroom", &DeviceColor{Hue: 120, Saturation: 1}, 0); err != nil {
+		t.Fatalf("SetColor failed: %v", err)
+	}
+	if err := client.SetColorTemperature(token, "id:light.bedroom", 2500, 0); err != nil {
+		t.Fatalf("SetColorTemperature failed: %v", err)
+	}
+
+	want := []struct {
+		data    string
+		service string
+	}{
+		{service: "turn_off", data: `{"entity_id":"light.kitchen","transition":2}`},
+		{service: "turn_on", data: `{"brightness":127,"entity_id":"all"}`},
+		{service: "turn_on", data: `{"entity_id":["light.kitchen","light.bedroom"],"rgb_color":[0,255,0]}`},
+		{service: "turn_on", data: `{"color_temp":400,"entity_id":"light.bedroom"}`},
+	}
+	if len(*calls) != len(want) {
+		t.Fatalf("Expected %d service calls, got %d", len(want), len(*calls))
+	}
+	for i, call := range *calls {
+		data, _ := json.Marshal(call.Data)
+		if call.Service != want[i].service || string(data) != want[i].data {
+			t.Errorf("Call %d: expected %s %s, got %s %s", i, want[i].service, want[i].data, call.Service, data)
+		}
+	}
+}
+
+func TestControl_UnsupportedSelector(t *testing.T) {
+	client, token, calls := newTestServer(t)
+
+	for _, selector := range []string{"group_id:kitchen", "location_id:home", "id:switch.kettle"} {
+		if err := client.SetPower(token, selector, true, 0); err == nil {
+			t.Errorf("Expected an error for selector %q", selector)
+		}
+	}
+	if len(*calls) != 0 {
+		t.Errorf("Expected no service calls, got %d", len(*calls))
+	}
+}
+
+func TestEffects_NotSupported(t *testing.T) {
+	client, token, _ := newTestServer(t)
+
+	if err := client.Pulse(token, "all", nil, 3, 1); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Expected ErrCapabilityNotSupported for pulse, got %v", err)
+	}
+	if err := client.Move(token, "all", "forward", 1, 1); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Expected ErrCapabilityNotSupported for move, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package homeassistant
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnauthorized is returned when Home Assistant rejects the access token
+	ErrUnauthorized = errors.New("invalid token: unauthorized")
+	// ErrInvalidToken is returned when a token is not of the form "<base_url>|<token>"
+	ErrInvalidToken = errors.New("invalid token: expected \"<base_url>|<long_lived_access_token>\"")
+	// ErrCapabilityNotSupported matches any CapabilityNotSupportedError via errors.Is
+	ErrCapabilityNotSupported = errors.New("capability not supported by home assistant")
+)
+
+// CapabilityNotSupportedError is returned for operations Home Assistant has no equivalent for
+type CapabilityNotSupportedError struct {
+	Capability string
+}
+
+func (e *CapabilityNotSupportedError) Error() string {
+	return fmt.Sprintf("home assistant does not support %s", e.Capability)
+}
+
+// Is reports whether target is ErrCapabilityNotSupported
+func (e *CapabilityNotSupportedError) Is(target error) bool {
+	return target == ErrCapabilityNotSupported
+}
+
+// StatusError is returned when Home Assistant responds with an unexpected status code
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
@@ -0,0 +1,526 @@
+// Package hue provides a client for interacting with the Philips Hue API v2
+package hue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lightshare/backend/pkg/colorconv"
+)
+
+const (
+	hueAPIBaseURL  = "https://api.meethue.com/route/clip/v2"
+	requestTimeout = 10 * time.Second
+)
+
+// AccountInfo contains information about a Hue bridge
+type AccountInfo struct {
+	// Additional metadata
+	Metadata map[string]interface{}
+	// ProviderAccountID is the bridge ID reported by the bridge
+	ProviderAccountID string
+	// Label or name for the bridge
+	Label string
+}
+
+// Client talks to a single Hue bridge using its bearer token
+type Client struct {
+	ctx        context.Context
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewClient creates a new Hue client whose requests time out after timeout,
+// or after 10s when timeout is 0
+func NewClient(timeout time.Duration) *Client {
+	client := NewClientWithBaseURL(hueAPIBaseURL)
+	if timeout > 0 {
+		client.httpClient.Timeout = timeout
+	}
+	return client
+}
+
+// NewClientWithBaseURL creates a new Hue client targeting a custom API base URL
+// This is primarily useful for pointing the client at a mock server in tests
+func NewClientWithBaseURL(baseURL string) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: requestTimeout,
+		},
+		ctx:     context.Background(),
+		baseURL: baseURL,
+	}
+}
+
+// WithContext returns a copy of the client whose requests carry ctx, so they are
+// cancelled with it
+func (c *Client) WithContext(ctx context.Context) *Client {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// Device represents a Hue light
+type Device struct {
+	Color        *DeviceColor
+	Group        *DeviceGroup
+	Location     *DeviceLocation
+	Metadata     map[string]interface{}
+	Raw          json.RawMessage // Original Hue JSON for this light
+	ID           string
+	Label        string
+	Power        string
+	Capabilities []string
+	Brightness   float64
+	Connected    bool
+	Reachable    bool
+}
+
+// DeviceColor represents color information
+type DeviceColor struct {
+	Hue        float64 // 0-360
+	Saturation float64 // 0.0-1.0
+	Kelvin     int     // 1500-9000
+}
+
+// DeviceGroup represents a Hue room
+type DeviceGroup struct {
+	ID   string
+	Name string
+}
+
+// DeviceLocation represents the bridge home
+type DeviceLocation struct {
+	ID   string
+	Name string
+}
+
+// resourceRef points at another Hue resource
+type resourceRef struct {
+	RID   string `json:"rid"`
+	RType string `json:"rtype"`
+}
+
+// light is the subset of the Hue v2 light resource the client uses
+// Optional services (dimming, color, color_temperature) are nil when the light lacks them
+type light struct {
+	Dimming *struct {
+		Brightness float64 `json:"brightness"`
+	} `json:"dimming"`
+	Color *struct {
+		XY struct {
+			X float64 `json:"x"`
+			Y float64 `json:"y"`
+		} `json:"xy"`
+	} `json:"color"`
+	ColorTemperature *struct {
+		Mirek *int `json:"mirek"`
+	} `json:"color_temperature"`
+	Owner    resourceRef `json:"owner"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Archetype string `json:"archetype"`
+	} `json:"metadata"`
+	ID string `json:"id"`
+	On struct {
+		On bool `json:"on"`
+	} `json:"on"`
+}
+
+// group is the subset of the Hue v2 room and bridge_home resources the client uses
+type group struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	ID       string        `json:"id"`
+	Children []resourceRef `json:"children"`
+	Services []resourceRef `json:"services"`
+}
+
+// groupedLight returns the grouped_light service controlling every light in the group
+func (g *group) groupedLight() (string, bool) {
+	for _, service := range g.Services {
+		if service.RType == "grouped_light" {
+			return service.RID, true
+		}
+	}
+	return "", false
+}
+
+// bridge is the subset of the Hue v2 bridge resource the client uses
+type bridge struct {
+	TimeZone struct {
+		TimeZone string `json:"time_zone"`
+	} `json:"time_zone"`
+	ID       string `json:"id"`
+	BridgeID string `json:"bridge_id"`
+}
+
+// connectivity is the subset of the Hue v2 zigbee_connectivity resource the client uses
+type connectivity struct {
+	Owner  resourceRef `json:"owner"`
+	Status string      `json:"status"`
+}
+
+// apiResponse is the envelope every Hue v2 response is wrapped in
+type apiResponse struct {
+	Errors []struct {
+		Description string `json:"description"`
+	} `json:"errors"`
+	Data []json.RawMessage `json:"data"`
+}
+
+// ValidateToken validates the bridge token by reading the bridge resource
+// The bridge ID is used as the account identifier since Hue tokens are per bridge
+func (c *Client) ValidateToken(token string) (*AccountInfo, error) {
+	var bridges []bridge
+	if _, err := c.getResources(token, "/resource/bridge", &bridges); err != nil {
+		return nil, err
+	}
+	if len(bridges) == 0 || bridges[0].BridgeID == "" {
+		return nil, fmt.Errorf("hue bridge did not report a bridge id")
+	}
+
+	var lights []light
+	if _, err := c.getResources(token, "/resource/light", &lights); err != nil {
+		return nil, err
+	}
+
+	return &AccountInfo{
+		ProviderAccountID: bridges[0].BridgeID,
+		Label:             "Hue Bridge",
+		Metadata: map[string]interface{}{
+			"bridge_id":    bridges[0].BridgeID,
+			"time_zone":    bridges[0].TimeZone.TimeZone,
+			"lights_count": len(lights),
+		},
+	}, nil
+}
+
+// GetAccountInfo retrieves information about the bridge
+// For Hue, this is the same as ValidateToken
+func (c *Client) GetAccountInfo(token string) (*AccountInfo, error) {
+	return c.ValidateToken(token)
+}
+
+// topology maps each Hue device to its room, the home and its reachability
+type topology struct {
+	rooms     map[string]*group // keyed by device ID
+	home      *group
+	reachable map[string]bool // keyed by device ID
+}
+
+// loadTopology fetches rooms, the bridge home and zigbee connectivity for device conversion
+func (c *Client) loadTopology(token string) (*topology, error) {
+	var rooms []group
+	if _, err := c.getResources(token, "/resource/room", &rooms); err != nil {
+		return nil, err
+	}
+
+	var homes []group
+	if _, err := c.getResources(token, "/resource/bridge_home", &homes); err != nil {
+		return nil, err
+	}
+
+	var connectivities []connectivity
+	if _, err := c.getResources(token, "/resource/zigbee_connectivity", &connectivities); err != nil {
+		return nil, err
+	}
+
+	topo := &topology{
+		rooms:     make(map[string]*group),
+		reachable: make(map[string]bool),
+	}
+	for i := range rooms {
+		for _, child := range rooms[i].Children {
+			if child.RType == "device" {
+				topo.rooms[child.RID] = &rooms[i]
+			}
+		}
+	}
+	if len(homes) > 0 {
+		topo.home = &homes[0]
+	}
+	for _, conn := range connectivities {
+		topo.reachable[conn.Owner.RID] = conn.Status == "connected"
+	}
+	return topo, nil
+}
+
+// ListDevices returns all lights on the bridge
+func (c *Client) ListDevices(token string) ([]*Device, error) {
+	var lights []light
+	raws, err := c.getResources(token, "/resource/light", &lights)
+	if err != nil {
+		return nil, err
+	}
+
+	topo, err := c.loadTopology(token)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]*Device, 0, len(lights))
+	for i := range lights {
+		devices = append(devices, convertLight(&lights[i], raws[i], topo))
+	}
+	return devices, nil
+}
+
+// GetDevice returns a specific light by ID
+func (c *Client) GetDevice(token, deviceID string) (*Device, error) {
+	var lights []light
+	raws, err := c.getResources(token, "/resource/light/"+deviceID, &lights)
+	if err != nil {
+		return nil, err
+	}
+	if len(lights) == 0 {
+		return nil, fmt.Errorf("device not found: %s", deviceID)
+	}
+
+	topo, err := c.loadTopology(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertLight(&lights[0], raws[0], topo), nil
+}
+
+// convertLight converts a Hue light to the Device type
+// Capabilities are inferred from the services the light exposes
+func convertLight(l *light, raw json.RawMessage, topo *topology) *Device {
+	reachable, known := topo.reachable[l.Owner.RID]
+	if !known {
+		// Lights without zigbee connectivity (e.g. bridge-attached) are assumed reachable
+		reachable = true
+	}
+
+	device := &Device{
+		ID:           l.ID,
+		Label:        l.Metadata.Name,
+		Power:        "off",
+		Connected:    reachable,
+		Reachable:    reachable,
+		Capabilities: []string{},
+		Metadata: map[string]interface{}{
+			"archetype": l.Metadata.Archetype,
+			"device_id": l.Owner.RID,
+		},
+		Raw: raw,
+	}
+	if l.On.On {
+		device.Power = "on"
+	}
+
+	if l.Dimming != nil {
+		device.Brightness = l.Dimming.Brightness / 100
+		device.Capabilities = append(device.Capabilities, "brightness")
+	}
+
+	if l.Color != nil || l.ColorTemperature != nil {
+		device.Color = &DeviceColor{}
+	}
+	if l.Color != nil {
+		device.Color.Hue, device.Color.Saturation = xyToHueSaturation(l.Color.XY.X, l.Color.XY.Y)
+		device.Capabilities = append(device.Capabilities, "color")
+	}
+	if l.ColorTemperature != nil {
+		if l.ColorTemperature.Mirek != nil {
+			device.Color.Kelvin = colorconv.MiredsToKelvin(*l.ColorTemperature.Mirek)
+		}
+		device.Capabilities = append(device.Capabilities, "temperature")
+	}
+
+	if room, ok := topo.rooms[l.Owner.RID]; ok {
+		device.Group = &DeviceGroup{ID: room.ID, Name: room.Metadata.Name}
+	}
+	if topo.home != nil {
+		device.Location = &DeviceLocation{ID: topo.home.ID, Name: "Home"}
+	}
+
+	return device
+}
+
+// SetPower turns light(s) on or off
+func (c *Client) SetPower(token, selector string, state bool, duration float64) error {
+	body := map[string]interface{}{
+		"on": map[string]interface{}{"on": state},
+	}
+	return c.setState(token, selector, body, duration)
+}
+
+// SetBrightness adjusts brightness (0.0-1.0)
+func (c *Client) SetBrightness(token, selector string, level, duration float64) error {
+	body := map[string]interface{}{
+		"dimming": map[string]interface{}{"brightness": math.Round(level * 100)},
+	}
+	return c.setState(token, selector, body, duration)
+}
+
+// SetColor sets the hue and saturation, converted to CIE xy
+func (c *Client) SetColor(token, selector string, color *DeviceColor, duration float64) error {
+	x, y := hueSaturationToXY(color.Hue, color.Saturation)
+	body := map[string]interface{}{
+		"color": map[string]interface{}{
+			"xy": map[string]interface{}{"x": x, "y": y},
+		},
+	}
+	return c.setState(token, selector, body, duration)
+}
+
+// SetColorTemperature sets the white balance, converted to mirek
+func (c *Client) SetColorTemperature(token, selector string, kelvin int, duration float64) error {
+	body := map[string]interface{}{
+		"color_temperature": map[string]interface{}{"mirek": kelvinToMirek(kelvin)},
+	}
+	return c.setState(token, selector, body, duration)
+}
+
+// Pulse is not supported; Hue has no direct equivalent
+func (c *Client) Pulse(_, _ string, _ *DeviceColor, _ int, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "pulse effect"}
+}
+
+// Breathe is not supported; Hue has no direct equivalent
+func (c *Client) Breathe(_, _ string, _ *DeviceColor, _ int, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "breathe effect"}
+}
+
+// Flame is not supported by Hue
+func (c *Client) Flame(_, _ string, _, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "flame effect"}
+}
+
+// Move is not supported by Hue
+func (c *Client) Move(_, _, _ string, _, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "move effect"}
+}
+
+// setState applies a state change to the resource a selector targets
+func (c *Client) setState(token, selector string, body map[string]interface{}, duration float64) error {
+	path, err := c.resolveSelector(token, selector)
+	if err != nil {
+		return err
+	}
+
+	if duration >= 0 {
+		body["dynamics"] = map[string]interface{}{"duration": int(math.Round(duration * 1000))}
+	}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	_, err = c.do(token, "PUT", path, bodyBytes, selector)
+	return err
+}
+
+// resolveSelector maps a LightShare selector to a Hue resource path
+// "id:" targets a light, "group_id:" a room and "location_id:"/"all" the bridge home
+func (c *Client) resolveSelector(token, selector string) (string, error) {
+	switch {
+	case strings.HasPrefix(selector, "id:"):
+		return "/resource/light/" + strings.TrimPrefix(selector, "id:"), nil
+	case strings.HasPrefix(selector, "group_id:"):
+		return c.groupedLightPath(token, "/resource/room/"+strings.TrimPrefix(selector, "group_id:"), selector)
+	case strings.HasPrefix(selector, "location_id:"):
+		return c.groupedLightPath(token, "/resource/bridge_home/"+strings.TrimPrefix(selector, "location_id:"), selector)
+	case selector == "all":
+		return c.groupedLightPath(token, "/resource/bridge_home", selector)
+	default:
+		return "", fmt.Errorf("unsupported selector: %s", selector)
+	}
+}
+
+// groupedLightPath returns the grouped_light path for a room or bridge home
+func (c *Client) groupedLightPath(token, groupPath, selector string) (string, error) {
+	var groups []group
+	if _, err := c.getResources(token, groupPath, &groups); err != nil {
+		return "", err
+	}
+	if len(groups) == 0 {
+		return "", fmt.Errorf("selector not found: %s", selector)
+	}
+
+	id, ok := groups[0].groupedLight()
+	if !ok {
+		return "", fmt.Errorf("selector has no grouped light: %s", selector)
+	}
+	return "/resource/grouped_light/" + id, nil
+}
+
+// getResources fetches a resource collection, decoding data into out
+// The original JSON of each resource is returned alongside
+func (c *Client) getResources(token, path string, out interface{}) ([]json.RawMessage, error) {
+	data, err := c.do(token, "GET", path, nil, path)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if err := json.Unmarshal(encoded, out); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return data, nil
+}
+
+// do performs a Hue API request and unwraps the response envelope
+func (c *Client) do(token, method, path string, body []byte, target string) ([]json.RawMessage, error) {
+	var reqBody io.Reader = http.NoBody
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Hue API: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			_ = closeErr
+		}
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ErrUnauthorized
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("selector not found: %s", target)
+	case http.StatusTooManyRequests:
+		return nil, newRateLimitError(resp)
+	case http.StatusOK, http.StatusMultiStatus:
+	default:
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	var envelope apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(envelope.Errors) > 0 && len(envelope.Data) == 0 {
+		return nil, fmt.Errorf("hue API error: %s", envelope.Errors[0].Description)
+	}
+
+	return envelope.Data, nil
+}
@@ -0,0 +1,252 @@
+package hue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testResources maps Hue resource paths to their response data
+var testResources = map[string]string{
+	"/resource/bridge": `[{"id": "b1", "bridge_id": "001788fffe6a1b2c", "time_zone": {"time_zone": "Europe/Paris"}}]`,
+	"/resource/light": `[
+		{"id": "light-color", "owner": {"rid": "dev-1", "rtype": "device"}, "metadata": {"name": "Desk", "archetype": "sultan_bulb"},
+		 "on": {"on": true}, "dimming": {"brightness": 50}, "color": {"xy": {"x": 0.1724, "y": 0.7468}},
+		 "color_temperature": {"mirek": null}},
+		{"id": "light-white", "owner": {"rid": "dev-2", "rtype": "device"}, "metadata": {"name": "Hallway", "archetype": "ceiling_round"},
+		 "on": {"on": false}, "dimming": {"brightness": 100}, "color_temperature": {"mirek": 250}}
+	]`,
+	"/resource/room":                `[{"id": "room-1", "metadata": {"name": "Office"}, "children": [{"rid": "dev-1", "rtype": "device"}], "services": [{"rid": "gl-room", "rtype": "grouped_light"}]}]`,
+	"/resource/room/room-1":         `[{"id": "room-1", "metadata": {"name": "Office"}, "children": [{"rid": "dev-1", "rtype": "device"}], "services": [{"rid": "gl-room", "rtype": "grouped_light"}]}]`,
+	"/resource/bridge_home":         `[{"id": "home-1", "services": [{"rid": "gl-home", "rtype": "grouped_light"}]}]`,
+	"/resource/zigbee_connectivity": `[{"owner": {"rid": "dev-1", "rtype": "device"}, "status": "connected"}, {"owner": {"rid": "dev-2", "rtype": "device"}, "status": "connectivity_issue"}]`,
+}
+
+// recordedRequest captures a state change sent to the mock bridge
+type recordedRequest struct {
+	Body   map[string]interface{}
+	Method string
+	Path   string
+}
+
+// newTestServer returns a mock Hue API that serves testResources and records PUTs
+func newTestServer(t *testing.T) (*httptest.Server, *[]recordedRequest) {
+	t.Helper()
+	var puts []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPut {
+			data, _ := io.ReadAll(r.Body)
+			var body map[string]interface{}
+			_ = json.Unmarshal(data, &body)
+			puts = append(puts, recordedRequest{Method: r.Method, Path: r.URL.Path, Body: body})
+			_, _ = w.Write([]byte(`{"errors": [], "data": [{"rid": "x", "rtype": "light"}]}`))
+			return
+		}
+
+		data, ok := testResources[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"errors": [], "data": ` + data + `}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &puts
+}
+
+func TestValidateToken_UsesBridgeID(t *testing.T) {
+	server, _ := newTestServer(t)
+	client := NewClientWithBaseURL(server.URL)
+
+	info, err := client.ValidateToken("test-token")
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	if info.ProviderAccountID != "001788fffe6a1b2c" {
+		t.Errorf("Expected bridge ID as account ID, got '%s'", info.ProviderAccountID)
+	}
+	if info.Metadata["lights_count"] != 2 {
+		t.Errorf("Expected 2 lights, got %v", info.Metadata["lights_count"])
+	}
+}
+
+func TestValidateToken_Unauthorized(t *testing.T) {
+	server, _ := newTestServer(t)
+	client := NewClientWithBaseURL(server.URL)
+
+	if _, err := client.ValidateToken("wrong-token"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("Expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestListDevices_InfersCapabilities(t *testing.T) {
+	server, _ := newTestServer(t)
+	client := NewClientWithBaseURL(server.URL)
+
+	devices, err := client.ListDevices("test-token")
+	if err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("Expected 2 devices, got %d", len(devices))
+	}
+
+	colorLight, whiteLight := devices[0], devices[1]
+
+	if got := colorLight.Capabilities; len(got) != 3 || got[1] != "color" {
+		t.Errorf("Expected brightness, color and temperature, got %v", got)
+	}
+	if colorLight.Power != "on" || colorLight.Brightness != 0.5 {
+		t.Errorf("Expected power on at 0.5 brightness, got %s at %f", colorLight.Power, colorLight.Brightness)
+	}
+	if math.Abs(colorLight.Color.Hue-120) > 5 {
+		t.Errorf("Expected green hue near 120, got %f", colorLight.Color.Hue)
+	}
+	if colorLight.Group == nil || colorLight.Group.ID != "room-1" || colorLight.Group.Name != "Office" {
+		t.Errorf("Expected light in room 'Office', got %+v", colorLight.Group)
+	}
+	if colorLight.Location == nil || colorLight.Location.ID != "home-1" {
+		t.Errorf("Expected light in home 'home-1', got %+v", colorLight.Location)
+	}
+	if !colorLight.Reachable {
+		t.Error("Expected connected light to be reachable")
+	}
+
+	if got := whiteLight.Capabilities; len(got) != 2 || got[0] != "brightness" || got[1] != "temperature" {
+		t.Errorf("Expected brightness and temperature only, got %v", got)
+	}
+	if whiteLight.Color.Kelvin != 4000 {
+		t.Errorf("Expected 4000K, got %d", whiteLight.Color.Kelvin)
+	}
+	if whiteLight.Reachable {
+		t.Error("Expected light with connectivity issue to be unreachable")
+	}
+}
+
+func TestSetState_ResolvesSelectors(t *testing.T) {
+	server, puts := newTestServer(t)
+	client := NewClientWithBaseURL(server.URL)
+
+	testCases := []struct {
+		call     func() error
+		name     string
+		wantPath string
+		wantKey  string
+	}{
+		{
+			name:     "light by id",
+			call:     func() error { return client.SetPower("test-token", "id:light-color", true, 1.5) },
+			wantPath: "/resource/light/light-color",
+			wantKey:  "on",
+		},
+		{
+			name:     "room by group id",
+			call:     func() error { return client.SetColorTemperature("test-token", "group_id:room-1", 2700, 0) },
+			wantPath: "/resource/grouped_light/gl-room",
+			wantKey:  "color_temperature",
+		},
+		{
+			name:     "all lights",
+			call:     func() error { return client.SetBrightness("test-token", "all", 0.25, 0) },
+			wantPath: "/resource/grouped_light/gl-home",
+			wantKey:  "dimming",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.call(); err != nil {
+				t.Fatalf("Call failed: %v", err)
+			}
+
+			last := (*puts)[len(*puts)-1]
+			if last.Path != tc.wantPath {
+				t.Errorf("Expected PUT to %s, got %s", tc.wantPath, last.Path)
+			}
+			if _, ok := last.Body[tc.wantKey]; !ok {
+				t.Errorf("Expected body to contain '%s', got %v", tc.wantKey, last.Body)
+			}
+		})
+	}
+
+	first := (*puts)[0]
+	if dynamics, _ := first.Body["dynamics"].(map[string]interface{}); dynamics["duration"] != float64(1500) {
+		t.Errorf("Expected 1500ms transition, got %v", first.Body["dynamics"])
+	}
+	mirek := (*puts)[1].Body["color_temperature"].(map[string]interface{})["mirek"]
+	if mirek != float64(370) {
+		t.Errorf("Expected 2700K as 370 mirek, got %v", mirek)
+	}
+}
+
+func TestEffects_NotSupported(t *testing.T) {
+	client := NewClient(0)
+
+	err := client.Pulse("test-token", "all", nil, 3, 1.0)
+	if !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Expected ErrCapabilityNotSupported from Pulse, got %v", err)
+	}
+	if err := client.Breathe("test-token", "all", nil, 3, 1.0); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Expected ErrCapabilityNotSupported from Breathe, got %v", err)
+	}
+}
+
+func TestColorConversion_RoundTrip(t *testing.T) {
+	for _, hue := range []float64{0, 60, 120, 240, 300} {
+		x, y := hueSaturationToXY(hue, 1)
+		gotHue, gotSaturation := xyToHueSaturation(x, y)
+
+		diff := math.Abs(gotHue - hue)
+		if diff > 180 {
+			diff = 360 - diff
+		}
+		if diff > 3 || gotSaturation < 0.95 {
+			t.Errorf("Hue %f round-tripped to hue %f saturation %f", hue, gotHue, gotSaturation)
+		}
+	}
+}
+
+func TestWithContext_CancelAbortsInFlightRequest(t *testing.T) {
+	arrived := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := NewClientWithBaseURL(server.URL).WithContext(ctx)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := client.ValidateToken("test-token")
+		errCh <- err
+	}()
+
+	<-arrived
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the request to be aborted when its context was cancelled")
+	}
+}
@@ -0,0 +1,79 @@
+package hue
+
+import (
+	"math"
+
+	"github.com/lightshare/backend/pkg/colorconv"
+)
+
+// Mirek bounds supported by Hue white ambiance lights (6500K-2000K)
+const (
+	minMirek = 153
+	maxMirek = 500
+)
+
+// kelvinToMirek converts a color temperature to mirek, clamped to the range Hue accepts
+func kelvinToMirek(kelvin int) int {
+	if kelvin <= 0 {
+		return maxMirek
+	}
+	return max(minMirek, min(maxMirek, colorconv.KelvinToMireds(kelvin)))
+}
+
+// hueSaturationToXY converts a hue (0-360) and saturation (0.0-1.0) at full value
+// to CIE 1931 xy coordinates using the wide-gamut D65 conversion Hue documents
+func hueSaturationToXY(hue, saturation float64) (float64, float64) {
+	r, g, b := colorconv.HSBToUnitRGB(hue, saturation, 1)
+	r, g, b = gammaExpand(r), gammaExpand(g), gammaExpand(b)
+
+	x := r*0.664511 + g*0.154324 + b*0.162028
+	y := r*0.283881 + g*0.668433 + b*0.047685
+	z := r*0.000088 + g*0.072310 + b*0.986039
+
+	sum := x + y + z
+	if sum == 0 {
+		return 0, 0
+	}
+	return roundXY(x / sum), roundXY(y / sum)
+}
+
+// xyToHueSaturation converts CIE 1931 xy coordinates back to hue (0-360) and saturation (0.0-1.0)
+func xyToHueSaturation(x, y float64) (float64, float64) {
+	if y == 0 {
+		return 0, 0
+	}
+
+	bigY := 1.0
+	bigX := (bigY / y) * x
+	bigZ := (bigY / y) * (1 - x - y)
+
+	r := bigX*1.656492 - bigY*0.354851 - bigZ*0.255038
+	g := -bigX*0.707196 + bigY*1.655397 + bigZ*0.036152
+	b := bigX*0.051713 - bigY*0.121364 + bigZ*1.011530
+
+	r, g, b = gammaCompress(math.Max(r, 0)), gammaCompress(math.Max(g, 0)), gammaCompress(math.Max(b, 0))
+	if peak := math.Max(r, math.Max(g, b)); peak > 1 {
+		r, g, b = r/peak, g/peak, b/peak
+	}
+
+	hue, saturation, _ := colorconv.UnitRGBToHSB(r, g, b)
+	return hue, saturation
+}
+
+func gammaExpand(c float64) float64 {
+	if c > 0.04045 {
+		return math.Pow((c+0.055)/1.055, 2.4)
+	}
+	return c / 12.92
+}
+
+func gammaCompress(c float64) float64 {
+	if c <= 0.0031308 {
+		return 12.92 * c
+	}
+	return 1.055*math.Pow(c, 1/2.4) - 0.055
+}
+
+func roundXY(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
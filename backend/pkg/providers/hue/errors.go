@@ -0,0 +1,59 @@
+package hue
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrUnauthorized is returned when Hue rejects the bridge token
+	ErrUnauthorized = errors.New("invalid token: unauthorized")
+	// ErrCapabilityNotSupported matches any CapabilityNotSupportedError via errors.Is
+	ErrCapabilityNotSupported = errors.New("capability not supported by hue")
+)
+
+// defaultRetryAfter is used when Hue throttles a request without saying when to retry
+const defaultRetryAfter = time.Second
+
+// CapabilityNotSupportedError is returned for operations Hue has no equivalent for
+type CapabilityNotSupportedError struct {
+	Capability string
+}
+
+func (e *CapabilityNotSupportedError) Error() string {
+	return fmt.Sprintf("hue does not support %s", e.Capability)
+}
+
+// Is reports whether target is ErrCapabilityNotSupported
+func (e *CapabilityNotSupportedError) Is(target error) bool {
+	return target == ErrCapabilityNotSupported
+}
+
+// RateLimitError is returned when Hue responds with 429 Too Many Requests
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited by Hue: retry after %s", e.RetryAfter)
+}
+
+// StatusError is returned when Hue responds with an unexpected status code
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// newRateLimitError builds a RateLimitError from a 429 response
+func newRateLimitError(resp *http.Response) *RateLimitError {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return &RateLimitError{RetryAfter: time.Duration(seconds) * time.Second}
+	}
+	return &RateLimitError{RetryAfter: defaultRetryAfter}
+}
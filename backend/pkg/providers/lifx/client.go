@@ -6,13 +6,32 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/lightshare/backend/pkg/ctxutil"
+	"github.com/lightshare/backend/pkg/logger"
+	"github.com/lightshare/backend/pkg/tracing"
 )
 
 const (
 	lifxAPIBaseURL = "https://api.lifx.com/v1"
 	requestTimeout = 10 * time.Second
+	// requestIDHeader correlates our requests with LIFX's, both ways
+	requestIDHeader = "X-Request-ID"
+)
+
+// Token scopes reported by ValidateToken in AccountInfo.Metadata["token_scopes"]
+const (
+	TokenScopesRead      = "read"       // lights:read only; state changes are forbidden
+	TokenScopesReadWrite = "read_write" // lights:read and lights:write
 )
 
 // AccountInfo contains information about a LIFX account
@@ -29,16 +48,78 @@ type AccountInfo struct {
 
 // Client implements the Client interface for LIFX
 type Client struct {
+	ctx        context.Context
 	httpClient *http.Client
+	baseURL    string
 }
 
-// NewClient creates a new LIFX client
-func NewClient() *Client {
+// NewClient creates a new LIFX client whose requests time out after timeout,
+// or after 10s when timeout is 0
+func NewClient(timeout time.Duration) *Client {
+	client := NewClientWithBaseURL(lifxAPIBaseURL)
+	if timeout > 0 {
+		client.httpClient.Timeout = timeout
+	}
+	return client
+}
+
+// NewClientWithBaseURL creates a new LIFX client targeting a custom API base URL
+// This is primarily useful for pointing the client at a mock server in tests
+func NewClientWithBaseURL(baseURL string) *Client {
 	return &Client{
 		httpClient: &http.Client{
 			Timeout: requestTimeout,
 		},
+		ctx:     context.Background(),
+		baseURL: baseURL,
+	}
+}
+
+// WithContext returns a copy of the client whose requests carry ctx, so they are
+// cancelled with it and traced as children of its span
+func (c *Client) WithContext(ctx context.Context) *Client {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// send performs a request inside an HTTP client span and propagates the trace
+// context to LIFX through the traceparent header, and the ID of the request being
+// served through X-Request-ID. The ID LIFX gives its own request is logged at debug level.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	ctx, span := tracing.StartSpan(req.Context(), "lifx "+req.Method,
+		attribute.String("http.request.method", req.Method),
+		attribute.String("url.full", req.URL.String()),
+	)
+	defer span.End()
+
+	req = req.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	requestID := ctxutil.RequestIDFromCtx(ctx)
+	if requestID != "" {
+		req.Header.Set(requestIDHeader, requestID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if upstreamID := resp.Header.Get(requestIDHeader); upstreamID != "" {
+		logger.Debug("LIFX API response",
+			"request_id", requestID,
+			"lifx_request_id", upstreamID,
+			"method", req.Method,
+			"status", resp.StatusCode,
+		)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, resp.Status)
 	}
+	return resp, nil
 }
 
 // LightsResponse represents the response from LIFX list lights endpoint
@@ -62,19 +143,20 @@ type LightsResponse []struct {
 	} `json:"color"`
 	Brightness float64 `json:"brightness"`
 	Connected  bool    `json:"connected"`
+	IsInfrared bool    `json:"is_infrared"` // LIFX+ lights, with an infrared channel
 }
 
 // ValidateToken validates the LIFX token by attempting to list lights
 // This confirms the token is valid and has the necessary permissions
 func (c *Client) ValidateToken(token string) (*AccountInfo, error) {
-	req, err := http.NewRequestWithContext(context.Background(), "GET", fmt.Sprintf("%s/lights/all", lifxAPIBaseURL), http.NoBody)
+	req, err := http.NewRequestWithContext(c.ctx, "GET", fmt.Sprintf("%s/lights/all", c.baseURL), http.NoBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.send(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call LIFX API: %w", err)
 	}
@@ -86,11 +168,15 @@ func (c *Client) ValidateToken(token string) (*AccountInfo, error) {
 	}()
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("invalid token: unauthorized")
+		return nil, ErrUnauthorized
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, newRateLimitError(resp)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	var lights LightsResponse
@@ -110,15 +196,57 @@ func (c *Client) ValidateToken(token string) (*AccountInfo, error) {
 		}
 	}
 
+	scopes, err := c.tokenScopes(token)
+	if err != nil {
+		return nil, err
+	}
+
 	return &AccountInfo{
 		ProviderAccountID: accountID,
 		Label:             accountLabel,
 		Metadata: map[string]interface{}{
 			"lights_count": len(lights),
+			"token_scopes": scopes,
 		},
 	}, nil
 }
 
+// tokenScopes reports whether token may change the lights, with a state change that
+// changes nothing. LIFX answers 403 Forbidden to tokens without the lights:write scope.
+// A token of an account without lights, answered 404, is assumed able to write.
+func (c *Client) tokenScopes(token string) (string, error) {
+	req, err := http.NewRequestWithContext(c.ctx, "PUT", fmt.Sprintf("%s/lights/all/state", c.baseURL), strings.NewReader(`{"duration":0}`))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.send(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call LIFX API: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			_ = closeErr
+		}
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusMultiStatus, http.StatusNotFound:
+		return TokenScopesReadWrite, nil
+	case http.StatusForbidden:
+		return TokenScopesRead, nil
+	case http.StatusUnauthorized:
+		return "", ErrUnauthorized
+	case http.StatusTooManyRequests:
+		return "", newRateLimitError(resp)
+	default:
+		return "", &StatusError{StatusCode: resp.StatusCode}
+	}
+}
+
 // GetAccountInfo retrieves account information for the LIFX account
 // For LIFX, this is similar to ValidateToken since LIFX doesn't have a dedicated account info endpoint
 func (c *Client) GetAccountInfo(token string) (*AccountInfo, error) {
@@ -133,6 +261,7 @@ type Device struct {
 	Group        *DeviceGroup
 	Location     *DeviceLocation
 	Metadata     map[string]interface{}
+	Raw          json.RawMessage // Original LIFX JSON for this light
 	ID           string
 	Label        string
 	Power        string
@@ -140,6 +269,8 @@ type Device struct {
 	Brightness   float64
 	Connected    bool
 	Reachable    bool
+	// SupportsInfrared is set for LIFX+ lights, whose infrared channel is set with SetInfrared
+	SupportsInfrared bool
 }
 
 // DeviceColor represents color information
@@ -163,14 +294,14 @@ type DeviceLocation struct {
 
 // ListDevices returns all lights for the LIFX account
 func (c *Client) ListDevices(token string) ([]*Device, error) {
-	req, err := http.NewRequestWithContext(context.Background(), "GET", fmt.Sprintf("%s/lights/all", lifxAPIBaseURL), http.NoBody)
+	req, err := http.NewRequestWithContext(c.ctx, "GET", fmt.Sprintf("%s/lights/all", c.baseURL), http.NoBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.send(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call LIFX API: %w", err)
 	}
@@ -181,16 +312,20 @@ func (c *Client) ListDevices(token string) ([]*Device, error) {
 	}()
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("invalid token: unauthorized")
+		return nil, ErrUnauthorized
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, newRateLimitError(resp)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
-	var lights LightsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&lights); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	lights, raws, err := decodeLights(resp.Body)
+	if err != nil {
+		return nil, err
 	}
 
 	// Convert LIFX response to unified Device format
@@ -211,6 +346,9 @@ func (c *Client) ListDevices(token string) ([]*Device, error) {
 
 		// All LIFX lights support color temperature and effects
 		capabilities = append(capabilities, "temperature", "effects")
+		if light.IsInfrared {
+			capabilities = append(capabilities, "infrared")
+		}
 
 		device := &Device{
 			ID:         light.ID,
@@ -222,9 +360,11 @@ func (c *Client) ListDevices(token string) ([]*Device, error) {
 				Saturation: light.Color.Saturation,
 				Kelvin:     light.Color.Kelvin,
 			},
-			Connected:    light.Connected,
-			Reachable:    light.Connected, // For LIFX, connected implies reachable
-			Capabilities: capabilities,
+			Connected:        light.Connected,
+			Reachable:        light.Connected, // For LIFX, connected implies reachable
+			Capabilities:     capabilities,
+			SupportsInfrared: light.IsInfrared,
+			Raw:              raws[i],
 		}
 
 		if light.Group.ID != "" {
@@ -250,14 +390,14 @@ func (c *Client) ListDevices(token string) ([]*Device, error) {
 // GetDevice returns a specific light by ID
 func (c *Client) GetDevice(token, deviceID string) (*Device, error) {
 	selector := fmt.Sprintf("id:%s", deviceID)
-	req, err := http.NewRequestWithContext(context.Background(), "GET", fmt.Sprintf("%s/lights/%s", lifxAPIBaseURL, selector), http.NoBody)
+	req, err := http.NewRequestWithContext(c.ctx, "GET", fmt.Sprintf("%s/lights/%s", c.baseURL, selector), http.NoBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.send(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call LIFX API: %w", err)
 	}
@@ -268,20 +408,24 @@ func (c *Client) GetDevice(token, deviceID string) (*Device, error) {
 	}()
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("invalid token: unauthorized")
+		return nil, ErrUnauthorized
 	}
 
 	if resp.StatusCode == http.StatusNotFound {
 		return nil, fmt.Errorf("device not found: %s", deviceID)
 	}
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, newRateLimitError(resp)
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &StatusError{StatusCode: resp.StatusCode}
 	}
 
-	var lights LightsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&lights); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	lights, raws, err := decodeLights(resp.Body)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(lights) == 0 {
@@ -290,16 +434,21 @@ func (c *Client) GetDevice(token, deviceID string) (*Device, error) {
 
 	light := lights[0]
 	capabilities := []string{"brightness", "color", "temperature", "effects"}
+	if light.IsInfrared {
+		capabilities = append(capabilities, "infrared")
+	}
 
 	device := &Device{
-		ID:           light.ID,
-		Label:        light.Label,
-		Power:        light.Power,
-		Brightness:   light.Brightness,
-		Color:        &DeviceColor{Hue: light.Color.Hue, Saturation: light.Color.Saturation, Kelvin: light.Color.Kelvin},
-		Connected:    light.Connected,
-		Reachable:    light.Connected,
-		Capabilities: capabilities,
+		ID:               light.ID,
+		Label:            light.Label,
+		Power:            light.Power,
+		Brightness:       light.Brightness,
+		Color:            &DeviceColor{Hue: light.Color.Hue, Saturation: light.Color.Saturation, Kelvin: light.Color.Kelvin},
+		Connected:        light.Connected,
+		Reachable:        light.Connected,
+		Capabilities:     capabilities,
+		SupportsInfrared: light.IsInfrared,
+		Raw:              raws[0],
 	}
 
 	if light.Group.ID != "" {
@@ -362,6 +511,50 @@ func (c *Client) SetColorTemperature(token, selector string, kelvin int, duratio
 	return c.setState(token, selector, body)
 }
 
+// SetInfrared sets the maximum level of the infrared channel of LIFX+ lights
+// level: 0.0-1.0
+func (c *Client) SetInfrared(token, selector string, level float64) error {
+	body := map[string]interface{}{
+		"infrared": level,
+	}
+
+	return c.setState(token, selector, body)
+}
+
+// TogglePower turns the selected lights off if any of them is on, and on otherwise
+func (c *Client) TogglePower(token, selector string, duration float64) error {
+	body := map[string]interface{}{
+		"duration": duration,
+	}
+
+	return c.postAction(token, selector, "toggle", body)
+}
+
+// MaxStates is the most states LIFX accepts in a single SetStates call
+const MaxStates = 50
+
+// LightState is one element of a LIFX set states request; unset fields are left unchanged
+type LightState struct {
+	Brightness *float64 `json:"brightness,omitempty"`
+	Selector   string   `json:"selector"`
+	Power      string   `json:"power,omitempty"`
+	Color      string   `json:"color,omitempty"`
+	Duration   float64  `json:"duration,omitempty"`
+}
+
+// SetStates applies several states, each to its own selector, in a single request
+func (c *Client) SetStates(token string, states []LightState) error {
+	if len(states) > MaxStates {
+		return fmt.Errorf("too many states: %d (maximum %d)", len(states), MaxStates)
+	}
+
+	body := map[string]interface{}{
+		"states": states,
+	}
+
+	return c.sendControl(token, "PUT", c.baseURL+"/lights/states", "states", body)
+}
+
 // Pulse creates a pulsing effect
 func (c *Client) Pulse(token, selector string, color *DeviceColor, cycles int, period float64) error {
 	body := map[string]interface{}{
@@ -392,15 +585,99 @@ func (c *Client) Breathe(token, selector string, color *DeviceColor, cycles int,
 	return c.postEffect(token, selector, "breathe", body)
 }
 
+// Flame runs a flickering flame effect on lights that support it (e.g. LIFX Tile)
+// period: time for one flicker cycle in seconds
+// duration: how long the effect runs in seconds (0 runs until the lights are next changed)
+func (c *Client) Flame(token, selector string, period, duration float64) error {
+	body := map[string]interface{}{
+		"period": period,
+	}
+	if duration > 0 {
+		body["duration"] = duration
+	}
+
+	return c.postEffect(token, selector, "flame", body)
+}
+
+// Move moves the colors along multizone lights (e.g. LIFX Z and Beam)
+// direction: "forward" or "reverse"
+// period: time for one full cycle in seconds
+// duration: how long the effect runs in seconds (0 runs until the lights are next changed)
+func (c *Client) Move(token, selector, direction string, period, duration float64) error {
+	body := map[string]interface{}{
+		"direction": direction,
+		"period":    period,
+	}
+	if duration > 0 {
+		body["duration"] = duration
+	}
+
+	return c.postEffect(token, selector, "move", body)
+}
+
+// Waveform types, in the order LIFX numbers them
+const (
+	WaveformSaw = iota
+	WaveformSine
+	WaveformHalfSine
+	WaveformTriangle
+	WaveformPulse
+)
+
+// WaveformParams configures a waveform effect
+type WaveformParams struct {
+	Color    *DeviceColor // Color the waveform cycles to; nil keeps the current color
+	Peak     *float64     // Where in each cycle the color peaks, 0-1; nil uses the LIFX default
+	Waveform int          // One of WaveformSaw, WaveformSine, WaveformHalfSine, WaveformTriangle or WaveformPulse
+	Period   float64      // Time for one cycle in seconds
+	Cycles   float64      // Number of cycles to run
+	Persist  bool         // Keep the waveform's color once the effect ends
+}
+
+// Waveform cycles the lights between their current color and params.Color along a
+// waveform
+func (c *Client) Waveform(token, selector string, params WaveformParams) error {
+	body := map[string]interface{}{
+		"waveform": params.Waveform,
+		"period":   params.Period,
+		"cycles":   params.Cycles,
+		"persist":  params.Persist,
+	}
+	if params.Color != nil {
+		body["color"] = fmt.Sprintf("hue:%f saturation:%f", params.Color.Hue, params.Color.Saturation)
+	}
+	if params.Peak != nil {
+		body["peak"] = *params.Peak
+	}
+
+	return c.postEffect(token, selector, "waveform", body)
+}
+
 // setState is a helper method to set state on lights
 func (c *Client) setState(token, selector string, body map[string]interface{}) error {
+	url := fmt.Sprintf("%s/lights/%s/state", c.baseURL, selector)
+	return c.sendControl(token, "PUT", url, selector, body)
+}
+
+// postEffect is a helper method to trigger effects
+func (c *Client) postEffect(token, selector, effect string, body map[string]interface{}) error {
+	return c.postAction(token, selector, "effects/"+effect, body)
+}
+
+// postAction is a helper method to POST to an action endpoint of the selected lights
+func (c *Client) postAction(token, selector, action string, body map[string]interface{}) error {
+	url := fmt.Sprintf("%s/lights/%s/%s", c.baseURL, selector, action)
+	return c.sendControl(token, "POST", url, selector, body)
+}
+
+// sendControl sends a JSON control request and maps the response status to an error
+func (c *Client) sendControl(token, method, url, selector string, body interface{}) error {
 	bodyBytes, err := json.Marshal(body)
 	if err != nil {
 		return fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/lights/%s/state", lifxAPIBaseURL, selector)
-	req, err := http.NewRequestWithContext(context.Background(), "PUT", url, bytes.NewReader(bodyBytes))
+	req, err := http.NewRequestWithContext(c.ctx, method, url, bytes.NewReader(bodyBytes))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -408,7 +685,7 @@ func (c *Client) setState(token, selector string, body map[string]interface{}) e
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.send(req)
 	if err != nil {
 		return fmt.Errorf("failed to call LIFX API: %w", err)
 	}
@@ -419,57 +696,73 @@ func (c *Client) setState(token, selector string, body map[string]interface{}) e
 	}()
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		return fmt.Errorf("invalid token: unauthorized")
+		return ErrUnauthorized
 	}
 
 	if resp.StatusCode == http.StatusNotFound {
 		return fmt.Errorf("selector not found: %s", selector)
 	}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMultiStatus {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return newRateLimitError(resp)
+	}
+
+	if resp.StatusCode == http.StatusMultiStatus {
+		return partialSuccess(resp.Body)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	return nil
 }
 
-// postEffect is a helper method to trigger effects
-func (c *Client) postEffect(token, selector, effect string, body map[string]interface{}) error {
-	bodyBytes, err := json.Marshal(body)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request body: %w", err)
+// partialSuccess reads the per-light results of a 207 Multi-Status response, returning a
+// PartialSuccessError when any light failed. Results without a status, like the nested
+// ones of a set states response, are skipped; an unreadable body is treated as success
+// since LIFX accepted the request.
+func partialSuccess(body io.Reader) error {
+	var response struct {
+		Results []LightResult `json:"results"`
 	}
-
-	url := fmt.Sprintf("%s/lights/%s/effects/%s", lifxAPIBaseURL, selector, effect)
-	req, err := http.NewRequestWithContext(context.Background(), "POST", url, bytes.NewReader(bodyBytes))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	if err := json.NewDecoder(body).Decode(&response); err != nil {
+		return nil
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-	req.Header.Set("Content-Type", "application/json")
+	results := make([]LightResult, 0, len(response.Results))
+	failed := false
+	for _, result := range response.Results {
+		if result.Status == "" {
+			continue
+		}
+		results = append(results, result)
+		failed = failed || !result.OK()
+	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to call LIFX API: %w", err)
+	if failed {
+		return &PartialSuccessError{Results: results}
 	}
-	defer func() {
-		if closeErr := resp.Body.Close(); closeErr != nil {
-			_ = closeErr
-		}
-	}()
+	return nil
+}
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return fmt.Errorf("invalid token: unauthorized")
+// decodeLights decodes a LIFX lights response, keeping each light's original JSON
+// alongside the typed representation so callers can expose provider-native fields
+func decodeLights(body io.Reader) (LightsResponse, []json.RawMessage, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	if resp.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("selector not found: %s", selector)
+	var lights LightsResponse
+	if err := json.Unmarshal(data, &lights); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMultiStatus {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return nil
+	return lights, raws, nil
 }
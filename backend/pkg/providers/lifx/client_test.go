@@ -0,0 +1,579 @@
+package lifx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/lightshare/backend/pkg/ctxutil"
+)
+
+const testLightsResponse = `[{
+	"id": "d073d5000001",
+	"uuid": "8fa5f072-af97-44ed-ae54-e70fd7bd9d20",
+	"label": "Kitchen",
+	"connected": true,
+	"power": "on",
+	"color": {"hue": 120, "saturation": 1, "kelvin": 3500},
+	"brightness": 0.5,
+	"group": {"id": "g1", "name": "Kitchen"},
+	"location": {"id": "l1", "name": "Home"},
+	"product": {"name": "LIFX A19", "identifier": "lifx_a19"}
+}]`
+
+// newTestServer returns a mock LIFX API server that records the last request
+func newTestServer(t *testing.T, status int, body string) (*httptest.Server, *http.Request) {
+	t.Helper()
+	var lastRequest http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRequest = *r
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &lastRequest
+}
+
+func TestListDevices_RetainsRawPayload(t *testing.T) {
+	server, _ := newTestServer(t, http.StatusOK, testLightsResponse)
+	client := NewClientWithBaseURL(server.URL)
+
+	devices, err := client.ListDevices("test-token")
+	if err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+
+	if len(devices) != 1 {
+		t.Fatalf("Expected 1 device, got %d", len(devices))
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(devices[0].Raw, &raw); err != nil {
+		t.Fatalf("Failed to decode raw payload: %v", err)
+	}
+
+	if _, ok := raw["product"]; !ok {
+		t.Error("Expected raw payload to retain the provider-native 'product' field")
+	}
+	if devices[0].Label != "Kitchen" {
+		t.Errorf("Expected label 'Kitchen', got '%s'", devices[0].Label)
+	}
+}
+
+func TestGetDevice_RetainsRawPayload(t *testing.T) {
+	server, lastRequest := newTestServer(t, http.StatusOK, testLightsResponse)
+	client := NewClientWithBaseURL(server.URL)
+
+	device, err := client.GetDevice("test-token", "d073d5000001")
+	if err != nil {
+		t.Fatalf("GetDevice failed: %v", err)
+	}
+
+	if lastRequest.URL.Path != "/lights/id:d073d5000001" {
+		t.Errorf("Unexpected request path: %s", lastRequest.URL.Path)
+	}
+	if len(device.Raw) == 0 {
+		t.Error("Expected raw payload to be retained")
+	}
+}
+
+func TestTogglePower_UsesToggleEndpoint(t *testing.T) {
+	server, lastRequest := newTestServer(t, http.StatusMultiStatus, `{"results":[]}`)
+	client := NewClientWithBaseURL(server.URL)
+
+	if err := client.TogglePower("test-token", "group_id:g1", 1.5); err != nil {
+		t.Fatalf("TogglePower failed: %v", err)
+	}
+
+	if lastRequest.Method != http.MethodPost || lastRequest.URL.Path != "/lights/group_id:g1/toggle" {
+		t.Errorf("Unexpected request: %s %s", lastRequest.Method, lastRequest.URL.Path)
+	}
+}
+
+func TestSetStates_SendsAllStatesInOneRequest(t *testing.T) {
+	var body struct {
+		States []map[string]interface{} `json:"states"`
+	}
+	var path, method string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusMultiStatus)
+	}))
+	defer server.Close()
+
+	brightness := 0.4
+	client := NewClientWithBaseURL(server.URL)
+	err := client.SetStates("test-token", []LightState{
+		{Selector: "id:d073d5000001", Power: "on", Duration: 1},
+		{Selector: "group_id:g1", Brightness: &brightness, Color: "kelvin:2700"},
+	})
+	if err != nil {
+		t.Fatalf("SetStates failed: %v", err)
+	}
+
+	if method != http.MethodPut || path != "/lights/states" {
+		t.Errorf("Unexpected request: %s %s", method, path)
+	}
+	if len(body.States) != 2 {
+		t.Fatalf("Expected 2 states, got %d", len(body.States))
+	}
+	if body.States[0]["power"] != "on" || body.States[0]["brightness"] != nil {
+		t.Errorf("Expected only power in the first state, got %v", body.States[0])
+	}
+	if body.States[1]["selector"] != "group_id:g1" || body.States[1]["color"] != "kelvin:2700" {
+		t.Errorf("Unexpected second state: %v", body.States[1])
+	}
+}
+
+const testEffectResponse = `{"results":[{"id":"d073d5000001","label":"Kitchen","status":"ok"}]}`
+
+func TestFlame_PostsFlameEffect(t *testing.T) {
+	var body map[string]interface{}
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(testEffectResponse))
+	}))
+	defer server.Close()
+
+	client := NewClientWithBaseURL(server.URL)
+	if err := client.Flame("test-token", "id:d073d5000001", 5, 0); err != nil {
+		t.Fatalf("Flame failed: %v", err)
+	}
+
+	if path != "/lights/id:d073d5000001/effects/flame" {
+		t.Errorf("Unexpected request path: %s", path)
+	}
+	if body["period"] != 5.0 {
+		t.Errorf("Expected period 5, got %v", body["period"])
+	}
+	if _, ok := body["duration"]; ok {
+		t.Errorf("Expected no duration for an unbounded effect, got %v", body["duration"])
+	}
+}
+
+func TestMove_PostsMoveEffect(t *testing.T) {
+	var body map[string]interface{}
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(testEffectResponse))
+	}))
+	defer server.Close()
+
+	client := NewClientWithBaseURL(server.URL)
+	if err := client.Move("test-token", "group_id:g1", "reverse", 2, 30); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+
+	if path != "/lights/group_id:g1/effects/move" {
+		t.Errorf("Unexpected request path: %s", path)
+	}
+	if body["direction"] != "reverse" || body["period"] != 2.0 || body["duration"] != 30.0 {
+		t.Errorf("Unexpected move body: %v", body)
+	}
+}
+
+func TestWaveform_PostsWaveformEffect(t *testing.T) {
+	var body map[string]interface{}
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(testEffectResponse))
+	}))
+	defer server.Close()
+
+	peak := 0.2
+	client := NewClientWithBaseURL(server.URL)
+	err := client.Waveform("test-token", "id:d073d5000001", WaveformParams{
+		Color:    &DeviceColor{Hue: 120, Saturation: 1},
+		Peak:     &peak,
+		Waveform: WaveformTriangle,
+		Period:   2,
+		Cycles:   5,
+		Persist:  true,
+	})
+	if err != nil {
+		t.Fatalf("Waveform failed: %v", err)
+	}
+
+	if path != "/lights/id:d073d5000001/effects/waveform" {
+		t.Errorf("Unexpected request path: %s", path)
+	}
+	want := map[string]interface{}{
+		"waveform": 3.0,
+		"color":    "hue:120.000000 saturation:1.000000",
+		"period":   2.0,
+		"cycles":   5.0,
+		"persist":  true,
+		"peak":     0.2,
+	}
+	if len(body) != len(want) {
+		t.Errorf("Expected %d fields, got %v", len(want), body)
+	}
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("Expected %s %v, got %v", key, value, body[key])
+		}
+	}
+}
+
+func TestWaveform_OmitsUnsetColorAndPeak(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(testEffectResponse))
+	}))
+	defer server.Close()
+
+	client := NewClientWithBaseURL(server.URL)
+	if err := client.Waveform("test-token", "all", WaveformParams{Waveform: WaveformSaw, Period: 1, Cycles: 3}); err != nil {
+		t.Fatalf("Waveform failed: %v", err)
+	}
+
+	if _, ok := body["color"]; ok {
+		t.Errorf("Expected no color, got %v", body["color"])
+	}
+	if _, ok := body["peak"]; ok {
+		t.Errorf("Expected no peak, got %v", body["peak"])
+	}
+	if body["waveform"] != 0.0 || body["persist"] != false {
+		t.Errorf("Unexpected waveform body: %v", body)
+	}
+}
+
+func TestMove_Unauthorized(t *testing.T) {
+	server, _ := newTestServer(t, http.StatusUnauthorized, `{"error":"Invalid token"}`)
+	client := NewClientWithBaseURL(server.URL)
+
+	if err := client.Move("bad-token", "all", "forward", 1, 0); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestSetPower_RateLimitedParsesRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewClientWithBaseURL(server.URL)
+	err := client.SetPower("test-token", "all", true, 0.5)
+
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("Expected RateLimitError, got %v", err)
+	}
+	if rateLimitErr.RetryAfter != 7*time.Second {
+		t.Errorf("Expected RetryAfter 7s, got %s", rateLimitErr.RetryAfter)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{name: "empty", value: "", want: 0, wantOk: false},
+		{name: "seconds", value: "30", want: 30 * time.Second, wantOk: true},
+		{name: "http date", value: "Mon, 01 Jan 2024 12:00:10 GMT", want: 10 * time.Second, wantOk: true},
+		{name: "past date", value: "Mon, 01 Jan 2024 11:59:00 GMT", want: 0, wantOk: true},
+		{name: "garbage", value: "soon", want: 0, wantOk: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tc.value, now)
+			if ok != tc.wantOk || got != tc.want {
+				t.Errorf("parseRetryAfter(%q) = %s, %v; want %s, %v", tc.value, got, ok, tc.want, tc.wantOk)
+			}
+		})
+	}
+}
+
+func TestWithContext_TracesRequestAndPropagatesTraceparent(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+
+	server, lastRequest := newTestServer(t, http.StatusOK, testLightsResponse)
+	client := NewClientWithBaseURL(server.URL).WithContext(ctx)
+
+	if _, err := client.ListDevices("test-token"); err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	parent.End()
+
+	if lastRequest.Header.Get("traceparent") == "" {
+		t.Error("Expected the traceparent header to be propagated to LIFX")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans (parent + HTTP), got %d", len(spans))
+	}
+	httpSpan := spans[0]
+	if httpSpan.Name != "lifx GET" {
+		t.Errorf("Expected span 'lifx GET', got '%s'", httpSpan.Name)
+	}
+	if httpSpan.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("Expected the HTTP span to be a child of the context's span")
+	}
+
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, attr := range httpSpan.Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+	if got := attrs["http.response.status_code"].AsInt64(); got != http.StatusOK {
+		t.Errorf("Expected status code attribute 200, got %d", got)
+	}
+	if got := attrs["url.full"].AsString(); got != server.URL+"/lights/all" {
+		t.Errorf("Expected url.full '%s/lights/all', got '%s'", server.URL, got)
+	}
+}
+
+func TestWithContext_CancelAbortsInFlightRequest(t *testing.T) {
+	arrived := make(chan struct{})
+	aborted := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		// The server only notices a dropped connection once the body has been read
+		_, _ = io.Copy(io.Discard, r.Body)
+		close(arrived)
+		select {
+		case <-r.Context().Done():
+			close(aborted)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := NewClientWithBaseURL(server.URL).WithContext(ctx)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- client.SetPower("test-token", "all", true, 0)
+	}()
+
+	<-arrived
+	cancel()
+
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Error("Expected the server to see the request aborted")
+	}
+}
+
+func TestSend_PropagatesRequestID(t *testing.T) {
+	var requestIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestIDs = append(requestIDs, r.Header.Get("X-Request-ID"))
+		w.Header().Set("X-Request-Id", "lifx-request-1")
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(testEffectResponse))
+	}))
+	defer server.Close()
+
+	ctx := ctxutil.WithRequestID(context.Background(), "request-123")
+	client := NewClientWithBaseURL(server.URL).WithContext(ctx)
+	if err := client.SetPower("test-token", "all", true, 0); err != nil {
+		t.Fatalf("SetPower failed: %v", err)
+	}
+	if err := client.Flame("test-token", "all", 5, 0); err != nil {
+		t.Fatalf("Flame failed: %v", err)
+	}
+	if len(requestIDs) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requestIDs))
+	}
+	for _, id := range requestIDs {
+		if id != "request-123" {
+			t.Errorf("Expected X-Request-ID request-123, got %q", id)
+		}
+	}
+
+	requestIDs = nil
+	if err := NewClientWithBaseURL(server.URL).SetPower("test-token", "all", true, 0); err != nil {
+		t.Fatalf("SetPower failed: %v", err)
+	}
+	if requestIDs[0] != "" {
+		t.Errorf("Expected no X-Request-ID without a request ID in context, got %q", requestIDs[0])
+	}
+}
+
+func TestValidateToken_ReportsTokenScopes(t *testing.T) {
+	tests := []struct {
+		name        string
+		writeStatus int
+		wantScopes  string
+	}{
+		{"write allowed", http.StatusOK, TokenScopesReadWrite},
+		{"write partially applied", http.StatusMultiStatus, TokenScopesReadWrite},
+		{"write forbidden", http.StatusForbidden, TokenScopesRead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var writeBody string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPut {
+					body, _ := io.ReadAll(r.Body)
+					writeBody = string(body)
+					w.WriteHeader(tt.writeStatus)
+					return
+				}
+				_, _ = w.Write([]byte(testLightsResponse))
+			}))
+			defer server.Close()
+
+			info, err := NewClientWithBaseURL(server.URL).ValidateToken("test-token")
+			if err != nil {
+				t.Fatalf("ValidateToken failed: %v", err)
+			}
+			if info.Metadata["token_scopes"] != tt.wantScopes {
+				t.Errorf("Expected token_scopes %s, got %v", tt.wantScopes, info.Metadata["token_scopes"])
+			}
+			if writeBody != `{"duration":0}` {
+				t.Errorf("Expected a no-op state change, got %s", writeBody)
+			}
+		})
+	}
+}
+
+func TestSetPower_MultiStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantResults []LightResult
+	}{
+		{
+			name: "every light reached",
+			body: `{"results":[{"id":"d073d5000001","label":"Kitchen","status":"ok"},{"id":"d073d5000002","label":"Hall","status":"ok"}]}`,
+		},
+		{
+			name: "some lights unreachable",
+			body: `{"results":[{"id":"d073d5000001","label":"Kitchen","status":"ok"},{"id":"d073d5000002","label":"Hall","status":"offline"},{"id":"d073d5000003","label":"Porch","status":"timed_out"}]}`,
+			wantResults: []LightResult{
+				{ID: "d073d5000001", Label: "Kitchen", Status: "ok"},
+				{ID: "d073d5000002", Label: "Hall", Status: "offline"},
+				{ID: "d073d5000003", Label: "Porch", Status: "timed_out"},
+			},
+		},
+		{
+			name: "no results",
+			body: `{"results":[]}`,
+		},
+		{
+			name: "unreadable body",
+			body: `not json`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, http.StatusMultiStatus, tt.body)
+			client := NewClientWithBaseURL(server.URL)
+
+			err := client.SetPower("test-token", "all", true, 0)
+			if tt.wantResults == nil {
+				if err != nil {
+					t.Errorf("Expected success, got %v", err)
+				}
+				return
+			}
+
+			var partialErr *PartialSuccessError
+			if !errors.As(err, &partialErr) {
+				t.Fatalf("Expected PartialSuccessError, got %v", err)
+			}
+			if len(partialErr.Results) != len(tt.wantResults) {
+				t.Fatalf("Expected %d results, got %+v", len(tt.wantResults), partialErr.Results)
+			}
+			for i, want := range tt.wantResults {
+				if partialErr.Results[i] != want {
+					t.Errorf("Expected result %+v, got %+v", want, partialErr.Results[i])
+				}
+			}
+		})
+	}
+}
+
+func TestListDevices_DetectsInfrared(t *testing.T) {
+	server, _ := newTestServer(t, http.StatusOK, `[
+		{"id": "d073d5000001", "label": "Porch", "connected": true, "is_infrared": true},
+		{"id": "d073d5000002", "label": "Kitchen", "connected": true}
+	]`)
+	client := NewClientWithBaseURL(server.URL)
+
+	devices, err := client.ListDevices("test-token")
+	if err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("Expected 2 devices, got %d", len(devices))
+	}
+
+	if !devices[0].SupportsInfrared || !slices.Contains(devices[0].Capabilities, "infrared") {
+		t.Errorf("Expected the LIFX+ light to support infrared, got %+v", devices[0])
+	}
+	if devices[1].SupportsInfrared || slices.Contains(devices[1].Capabilities, "infrared") {
+		t.Errorf("Expected the other light not to support infrared, got %+v", devices[1])
+	}
+}
+
+func TestSetInfrared_SetsState(t *testing.T) {
+	var body map[string]interface{}
+	var method, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(testEffectResponse))
+	}))
+	defer server.Close()
+
+	client := NewClientWithBaseURL(server.URL)
+	if err := client.SetInfrared("test-token", "id:d073d5000001", 0.75); err != nil {
+		t.Fatalf("SetInfrared failed: %v", err)
+	}
+
+	if method != http.MethodPut || path != "/lights/id:d073d5000001/state" {
+		t.Errorf("Unexpected request: %s %s", method, path)
+	}
+	if len(body) != 1 || body["infrared"] != 0.75 {
+		t.Errorf("Expected only an infrared level of 0.75, got %v", body)
+	}
+}
@@ -0,0 +1,107 @@
+package lifx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrUnauthorized is returned when LIFX rejects the access token
+	ErrUnauthorized = errors.New("invalid token: unauthorized")
+	// ErrLANDeviceNotFound is returned when a selector does not target a device the LAN client can reach
+	ErrLANDeviceNotFound = errors.New("device not found on the local network")
+)
+
+// defaultRetryAfter is used when LIFX throttles a request without saying when to retry
+const defaultRetryAfter = time.Second
+
+// RateLimitError is returned when LIFX responds with 429 Too Many Requests
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited by LIFX: retry after %s", e.RetryAfter)
+}
+
+// StatusError is returned when LIFX responds with an unexpected status code
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// LightResult is the outcome of a request for one of the lights it selected
+type LightResult struct {
+	ID     string `json:"id"`
+	Label  string `json:"label"`
+	Status string `json:"status"` // "ok", "offline" or "timed_out"
+}
+
+// OK reports whether the request was applied to the light
+func (r LightResult) OK() bool {
+	return r.Status == lightStatusOK
+}
+
+// lightStatusOK is the status of a light a request was applied to
+const lightStatusOK = "ok"
+
+// PartialSuccessError is returned when LIFX responds with 207 Multi-Status and some of the
+// selected lights could not be reached. Results holds the outcome for every light.
+type PartialSuccessError struct {
+	Results []LightResult
+}
+
+func (e *PartialSuccessError) Error() string {
+	failed := 0
+	for _, result := range e.Results {
+		if !result.OK() {
+			failed++
+		}
+	}
+	return fmt.Sprintf("%d of %d lights failed", failed, len(e.Results))
+}
+
+// newRateLimitError builds a RateLimitError from a 429 response
+// LIFX sends Retry-After (seconds or HTTP date); X-RateLimit-Reset (unix seconds) is used as a fallback
+func newRateLimitError(resp *http.Response) *RateLimitError {
+	now := time.Now()
+
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		if wait := time.Unix(reset, 0).Sub(now); wait > 0 {
+			return &RateLimitError{RetryAfter: wait}
+		}
+	}
+
+	return &RateLimitError{RetryAfter: defaultRetryAfter}
+}
+
+// parseRetryAfter parses a Retry-After header value as delay-seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := date.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,438 @@
+package lifx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LANPort is the UDP port LIFX devices serve the LAN protocol on
+const LANPort = 56700
+
+const (
+	lanHeaderSize = 36
+	// lanProtocol is the protocol number every LAN message carries
+	lanProtocol = 1024
+	// lanServiceUDP is the StateService service ID of the UDP transport
+	lanServiceUDP = 1
+	// lanDiscoveryTimeout is how long discovery waits for devices to answer
+	lanDiscoveryTimeout = 500 * time.Millisecond
+	// lanRequestTimeout is how long a single device is given to answer a request
+	lanRequestTimeout = 500 * time.Millisecond
+	// lanMaxMessageSize bounds the size of received messages
+	lanMaxMessageSize = 1024
+)
+
+// LAN protocol message types (https://lan.developer.lifx.com/docs/packet-contents)
+const (
+	lanGetService      = 2
+	lanStateService    = 3
+	lanGetWifiInfo     = 16
+	lanStateWifiInfo   = 17
+	lanAcknowledgement = 45
+	lanLightGet        = 101
+	lanLightSetColor   = 102
+	lanLightState      = 107
+	lanLightSetPower   = 117
+)
+
+// lanHeader is the decoded header of a LAN protocol message
+type lanHeader struct {
+	target   [8]byte
+	source   uint32
+	msgType  uint16
+	sequence uint8
+}
+
+// lanDevice is a device found on the local network
+type lanDevice struct {
+	addr   *net.UDPAddr
+	target [8]byte
+	owner  [sha256.Size]byte // Hash of the cloud token whose account lists the device
+}
+
+// hsbk is a color as the LAN protocol encodes it
+type hsbk struct {
+	hue, saturation, brightness, kelvin uint16
+}
+
+// LANClient controls LIFX devices directly over the local network with the LIFX LAN
+// protocol, skipping the round-trip through the cloud API. Only "id:" selectors of
+// devices it has discovered can be controlled; callers fall back to the cloud Client for
+// the others.
+type LANClient struct {
+	devices       map[string]*lanDevice // By serial number, as the cloud API reports device IDs
+	broadcastAddr string
+	timeout       time.Duration
+	mu            sync.RWMutex
+	sequence      atomic.Uint32
+	source        uint32
+}
+
+// NewLANClient creates a LAN client discovering devices by broadcasting on LANPort
+func NewLANClient() *LANClient {
+	return NewLANClientWithBroadcastAddr(fmt.Sprintf("255.255.255.255:%d", LANPort))
+}
+
+// NewLANClientWithBroadcastAddr creates a LAN client sending discovery to a custom address
+// This is primarily useful for pointing the client at a mock device in tests
+func NewLANClientWithBroadcastAddr(addr string) *LANClient {
+	return &LANClient{
+		devices:       make(map[string]*lanDevice),
+		broadcastAddr: addr,
+		timeout:       lanDiscoveryTimeout,
+		// Devices only answer to a non-zero source directly
+		source: rand.Uint32() | 1,
+	}
+}
+
+// ListDevices discovers the devices on the local network and queries their state.
+// Metadata holds each device's "lan_ip" and, when reported, its Wi-Fi "signal_strength"
+// in dBm.
+func (c *LANClient) ListDevices(_ string) ([]*Device, error) {
+	found, err := c.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	serials := make([]string, 0, len(found))
+	for serial := range found {
+		serials = append(serials, serial)
+	}
+	sort.Strings(serials)
+
+	devices := make([]*Device, 0, len(found))
+	for _, serial := range serials {
+		device := found[serial]
+		payload, err := c.request(device, lanLightGet, nil, lanLightState)
+		if err != nil || len(payload) < 44 {
+			continue // Gone since discovery
+		}
+
+		color := decodeHSBK(payload[0:8])
+		power := "off"
+		if binary.LittleEndian.Uint16(payload[10:12]) > 0 {
+			power = "on"
+		}
+
+		metadata := map[string]interface{}{"lan_ip": device.addr.IP.String()}
+		if wifi, err := c.request(device, lanGetWifiInfo, nil, lanStateWifiInfo); err == nil && len(wifi) >= 4 {
+			if signal := math.Float32frombits(binary.LittleEndian.Uint32(wifi[0:4])); signal > 0 {
+				metadata["signal_strength"] = int(math.Round(10 * math.Log10(float64(signal))))
+			}
+		}
+
+		devices = append(devices, &Device{
+			ID:    serial,
+			Label: string(bytes.TrimRight(payload[12:44], "\x00")),
+			Power: power,
+			Color: &DeviceColor{
+				Hue:        float64(color.hue) * 360 / math.MaxUint16,
+				Saturation: float64(color.saturation) / math.MaxUint16,
+				Kelvin:     int(color.kelvin),
+			},
+			Brightness: float64(color.brightness) / math.MaxUint16,
+			Metadata:   metadata,
+			Connected:  true,
+			Reachable:  true,
+		})
+	}
+	return devices, nil
+}
+
+// Claim records that the cloud account of token lists the devices with the given IDs.
+// A device is only controlled over the LAN for the account that claimed it, so users of
+// a shared deployment cannot reach each other's devices through the local network.
+func (c *LANClient) Claim(token string, ids []string) {
+	owner := sha256.Sum256([]byte(token))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		if device, ok := c.devices[strings.ToLower(id)]; ok {
+			device.owner = owner
+		}
+	}
+}
+
+// Resolves reports whether selector targets a discovered device claimed by token, which
+// can then be controlled over the LAN
+func (c *LANClient) Resolves(token, selector string) bool {
+	_, err := c.resolve(token, selector)
+	return err == nil
+}
+
+// SetPower turns a device on or off
+func (c *LANClient) SetPower(token, selector string, state bool, duration float64) error {
+	device, err := c.resolve(token, selector)
+	if err != nil {
+		return err
+	}
+
+	var level uint16
+	if state {
+		level = math.MaxUint16
+	}
+	payload := make([]byte, 6)
+	binary.LittleEndian.PutUint16(payload[0:2], level)
+	binary.LittleEndian.PutUint32(payload[2:6], durationMillis(duration))
+
+	return c.acknowledged(device, lanLightSetPower, payload)
+}
+
+// SetBrightness adjusts the brightness of a device, keeping its color
+func (c *LANClient) SetBrightness(token, selector string, level, duration float64) error {
+	return c.updateColor(token, selector, duration, func(color *hsbk) {
+		color.brightness = unitToUint16(level)
+	})
+}
+
+// SetColor sets the color of a device, keeping its brightness
+func (c *LANClient) SetColor(token, selector string, color *DeviceColor, duration float64) error {
+	return c.updateColor(token, selector, duration, func(current *hsbk) {
+		current.hue = uint16(math.Round(math.Mod(color.Hue, 360) / 360 * math.MaxUint16))
+		current.saturation = unitToUint16(color.Saturation)
+		if color.Kelvin > 0 {
+			current.kelvin = uint16(color.Kelvin)
+		}
+	})
+}
+
+// updateColor reads the color of a device, applies update and sets the result, as the
+// LAN protocol can only set all of hue, saturation, brightness and kelvin at once
+func (c *LANClient) updateColor(token, selector string, duration float64, update func(*hsbk)) error {
+	device, err := c.resolve(token, selector)
+	if err != nil {
+		return err
+	}
+
+	state, err := c.request(device, lanLightGet, nil, lanLightState)
+	if err != nil {
+		return err
+	}
+	if len(state) < 8 {
+		return fmt.Errorf("short light state from %s", device.addr)
+	}
+
+	color := decodeHSBK(state[0:8])
+	update(&color)
+
+	payload := make([]byte, 13)
+	encodeHSBK(payload[1:9], color)
+	binary.LittleEndian.PutUint32(payload[9:13], durationMillis(duration))
+
+	return c.acknowledged(device, lanLightSetColor, payload)
+}
+
+// discover broadcasts GetService and records the address of every device that answers
+func (c *LANClient) discover() (map[string]*lanDevice, error) {
+	broadcast, err := net.ResolveUDPAddr("udp4", c.broadcastAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve broadcast address: %w", err)
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open UDP socket: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	message := c.encode(lanGetService, [8]byte{}, nil, true, false)
+	if _, err := conn.WriteToUDP(message, broadcast); err != nil {
+		return nil, fmt.Errorf("failed to broadcast discovery: %w", err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]*lanDevice)
+	buf := make([]byte, lanMaxMessageSize)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			return nil, fmt.Errorf("failed to read discovery response: %w", err)
+		}
+
+		header, payload, err := decodeMessage(buf[:n])
+		if err != nil || header.msgType != lanStateService || len(payload) < 5 || payload[0] != lanServiceUDP {
+			continue
+		}
+		found[serialNumber(header.target)] = &lanDevice{
+			addr:   &net.UDPAddr{IP: from.IP, Port: int(binary.LittleEndian.Uint32(payload[1:5]))},
+			target: header.target,
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for serial, device := range found {
+		// Keep the claims of devices seen before
+		if previous, ok := c.devices[serial]; ok {
+			device.owner = previous.owner
+		}
+	}
+	c.devices = found
+
+	return found, nil
+}
+
+// resolve returns the discovered device an "id:" selector targets, if token claimed it
+func (c *LANClient) resolve(token, selector string) (*lanDevice, error) {
+	serial, ok := strings.CutPrefix(selector, "id:")
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrLANDeviceNotFound, selector)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	device, ok := c.devices[strings.ToLower(serial)]
+	if !ok || device.owner != sha256.Sum256([]byte(token)) {
+		return nil, fmt.Errorf("%w: %s", ErrLANDeviceNotFound, selector)
+	}
+	return device, nil
+}
+
+// request sends a message to a device and returns the payload of its response of type
+// responseType
+func (c *LANClient) request(device *lanDevice, msgType uint16, payload []byte, responseType uint16) ([]byte, error) {
+	return c.exchange(device, c.encode(msgType, device.target, payload, false, true), responseType)
+}
+
+// acknowledged sends a message to a device and waits for it to be acknowledged
+func (c *LANClient) acknowledged(device *lanDevice, msgType uint16, payload []byte) error {
+	_, err := c.exchange(device, c.encode(msgType, device.target, payload, true, false), lanAcknowledgement)
+	return err
+}
+
+// exchange sends message to a device and waits for the response of type responseType
+// matching its sequence number
+func (c *LANClient) exchange(device *lanDevice, message []byte, responseType uint16) ([]byte, error) {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open UDP socket: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.WriteToUDP(message, device.addr); err != nil {
+		return nil, fmt.Errorf("failed to send to %s: %w", device.addr, err)
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(lanRequestTimeout)); err != nil {
+		return nil, err
+	}
+
+	sequence := message[23]
+	buf := make([]byte, lanMaxMessageSize)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return nil, fmt.Errorf("no response from %s: %w", device.addr, err)
+		}
+
+		header, payload, err := decodeMessage(buf[:n])
+		if err != nil || header.msgType != responseType || header.sequence != sequence || header.source != c.source {
+			continue
+		}
+		return payload, nil
+	}
+}
+
+// encode builds a LAN protocol message. Tagged messages are addressed to every device.
+func (c *LANClient) encode(msgType uint16, target [8]byte, payload []byte, ackRequired, resRequired bool) []byte {
+	message := make([]byte, lanHeaderSize+len(payload))
+
+	// Frame header: size, protocol with the addressable and tagged flags, source
+	binary.LittleEndian.PutUint16(message[0:2], uint16(len(message)))
+	flags := uint16(lanProtocol) | 1<<12
+	if target == [8]byte{} {
+		flags |= 1 << 13
+	}
+	binary.LittleEndian.PutUint16(message[2:4], flags)
+	binary.LittleEndian.PutUint32(message[4:8], c.source)
+
+	// Frame address: target, response flags, sequence
+	copy(message[8:16], target[:])
+	if resRequired {
+		message[22] |= 1
+	}
+	if ackRequired {
+		message[22] |= 2
+	}
+	message[23] = uint8(c.sequence.Add(1))
+
+	// Protocol header: message type
+	binary.LittleEndian.PutUint16(message[32:34], msgType)
+
+	copy(message[lanHeaderSize:], payload)
+	return message
+}
+
+// decodeMessage splits a LAN protocol message into its header and payload
+func decodeMessage(data []byte) (lanHeader, []byte, error) {
+	if len(data) < lanHeaderSize {
+		return lanHeader{}, nil, fmt.Errorf("message too short: %d bytes", len(data))
+	}
+	size := int(binary.LittleEndian.Uint16(data[0:2]))
+	if size < lanHeaderSize || size > len(data) {
+		return lanHeader{}, nil, fmt.Errorf("invalid message size: %d", size)
+	}
+
+	var header lanHeader
+	header.source = binary.LittleEndian.Uint32(data[4:8])
+	copy(header.target[:], data[8:16])
+	header.sequence = data[23]
+	header.msgType = binary.LittleEndian.Uint16(data[32:34])
+
+	return header, data[lanHeaderSize:size], nil
+}
+
+func decodeHSBK(data []byte) hsbk {
+	return hsbk{
+		hue:        binary.LittleEndian.Uint16(data[0:2]),
+		saturation: binary.LittleEndian.Uint16(data[2:4]),
+		brightness: binary.LittleEndian.Uint16(data[4:6]),
+		kelvin:     binary.LittleEndian.Uint16(data[6:8]),
+	}
+}
+
+func encodeHSBK(data []byte, color hsbk) {
+	binary.LittleEndian.PutUint16(data[0:2], color.hue)
+	binary.LittleEndian.PutUint16(data[2:4], color.saturation)
+	binary.LittleEndian.PutUint16(data[4:6], color.brightness)
+	binary.LittleEndian.PutUint16(data[6:8], color.kelvin)
+}
+
+// serialNumber formats the MAC address in a target as the serial number the cloud API
+// uses as the device ID
+func serialNumber(target [8]byte) string {
+	return hex.EncodeToString(target[:6])
+}
+
+// unitToUint16 scales a 0.0-1.0 value to the protocol's 0-65535 range
+func unitToUint16(value float64) uint16 {
+	return uint16(math.Round(math.Max(0, math.Min(1, value)) * math.MaxUint16))
+}
+
+// durationMillis converts a transition duration in seconds to milliseconds
+func durationMillis(seconds float64) uint32 {
+	if seconds <= 0 {
+		return 0
+	}
+	return uint32(seconds * 1000)
+}
@@ -0,0 +1,223 @@
+package lifx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"testing"
+)
+
+// mockLANDevice answers LIFX LAN protocol requests on localhost:56700 like a single bulb
+type mockLANDevice struct {
+	conn   *net.UDPConn
+	target [8]byte
+	label  string
+	color  hsbk
+	mu     sync.Mutex
+	signal float32
+	power  uint16
+	// Durations in milliseconds of the last SetPower and SetColor
+	powerDuration, colorDuration uint32
+}
+
+func newMockLANDevice(t *testing.T) *mockLANDevice {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: LANPort})
+	if err != nil {
+		t.Skipf("LIFX LAN port unavailable: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	device := &mockLANDevice{
+		conn:   conn,
+		target: [8]byte{0xd0, 0x73, 0xd5, 0x00, 0x00, 0x01},
+		label:  "Kitchen",
+		color:  hsbk{hue: 21845, saturation: math.MaxUint16, brightness: 32768, kelvin: 3500},
+		power:  math.MaxUint16,
+		signal: 1e-7, // -70 dBm
+	}
+	go device.serve()
+	return device
+}
+
+func (d *mockLANDevice) serve() {
+	buf := make([]byte, lanMaxMessageSize)
+	for {
+		n, from, err := d.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		header, payload, err := decodeMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		d.mu.Lock()
+		var responseType uint16
+		var response []byte
+		switch header.msgType {
+		case lanGetService:
+			responseType, response = lanStateService, make([]byte, 5)
+			response[0] = lanServiceUDP
+			binary.LittleEndian.PutUint32(response[1:5], LANPort)
+		case lanLightGet:
+			responseType, response = lanLightState, make([]byte, 52)
+			encodeHSBK(response[0:8], d.color)
+			binary.LittleEndian.PutUint16(response[10:12], d.power)
+			copy(response[12:44], d.label)
+		case lanGetWifiInfo:
+			responseType, response = lanStateWifiInfo, make([]byte, 14)
+			binary.LittleEndian.PutUint32(response[0:4], math.Float32bits(d.signal))
+		case lanLightSetPower:
+			d.power = binary.LittleEndian.Uint16(payload[0:2])
+			d.powerDuration = binary.LittleEndian.Uint32(payload[2:6])
+			responseType = lanAcknowledgement
+		case lanLightSetColor:
+			d.color = decodeHSBK(payload[1:9])
+			d.colorDuration = binary.LittleEndian.Uint32(payload[9:13])
+			responseType = lanAcknowledgement
+		}
+		d.mu.Unlock()
+
+		if responseType != 0 {
+			_, _ = d.conn.WriteToUDP(d.encodeResponse(header, responseType, response), from)
+		}
+	}
+}
+
+// encodeResponse builds a response to request, echoing its source and sequence
+func (d *mockLANDevice) encodeResponse(request lanHeader, msgType uint16, payload []byte) []byte {
+	message := make([]byte, lanHeaderSize+len(payload))
+	binary.LittleEndian.PutUint16(message[0:2], uint16(len(message)))
+	binary.LittleEndian.PutUint16(message[2:4], lanProtocol|1<<12)
+	binary.LittleEndian.PutUint32(message[4:8], request.source)
+	copy(message[8:16], d.target[:])
+	message[23] = request.sequence
+	binary.LittleEndian.PutUint16(message[32:34], msgType)
+	copy(message[lanHeaderSize:], payload)
+	return message
+}
+
+func (d *mockLANDevice) state() (hsbk, uint16, uint32, uint32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.color, d.power, d.powerDuration, d.colorDuration
+}
+
+func newTestLANClient() *LANClient {
+	return NewLANClientWithBroadcastAddr(fmt.Sprintf("127.0.0.1:%d", LANPort))
+}
+
+func TestLANClient_ListDevices(t *testing.T) {
+	newMockLANDevice(t)
+	client := newTestLANClient()
+
+	devices, err := client.ListDevices("test-token")
+	if err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("Expected 1 device, got %d", len(devices))
+	}
+
+	device := devices[0]
+	if device.ID != "d073d5000001" || device.Label != "Kitchen" || device.Power != "on" {
+		t.Errorf("Unexpected device: %+v", device)
+	}
+	if math.Abs(device.Brightness-0.5) > 0.001 || math.Abs(device.Color.Hue-120) > 0.01 || device.Color.Kelvin != 3500 {
+		t.Errorf("Unexpected color: brightness %v, %+v", device.Brightness, device.Color)
+	}
+	if device.Metadata["lan_ip"] != "127.0.0.1" {
+		t.Errorf("Expected lan_ip 127.0.0.1, got %v", device.Metadata["lan_ip"])
+	}
+	if device.Metadata["signal_strength"] != -70 {
+		t.Errorf("Expected signal_strength -70, got %v", device.Metadata["signal_strength"])
+	}
+}
+
+func TestLANClient_ControlsClaimedDevice(t *testing.T) {
+	mock := newMockLANDevice(t)
+	client := newTestLANClient()
+
+	if _, err := client.ListDevices("test-token"); err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	client.Claim("test-token", []string{"d073d5000001"})
+
+	if err := client.SetPower("test-token", "id:d073d5000001", false, 1.5); err != nil {
+		t.Fatalf("SetPower failed: %v", err)
+	}
+	_, power, powerDuration, _ := mock.state()
+	if power != 0 || powerDuration != 1500 {
+		t.Errorf("Expected power 0 over 1500ms, got %d over %dms", power, powerDuration)
+	}
+
+	if err := client.SetColor("test-token", "id:d073d5000001", &DeviceColor{Hue: 240, Saturation: 0.5}, 0); err != nil {
+		t.Fatalf("SetColor failed: %v", err)
+	}
+	color, _, _, _ := mock.state()
+	if color.hue != 43690 || color.saturation != 32768 || color.brightness != 32768 || color.kelvin != 3500 {
+		t.Errorf("Expected the color to change and the brightness to be kept, got %+v", color)
+	}
+
+	if err := client.SetBrightness("test-token", "id:d073d5000001", 1, 0.25); err != nil {
+		t.Fatalf("SetBrightness failed: %v", err)
+	}
+	color, _, _, colorDuration := mock.state()
+	if color.brightness != math.MaxUint16 || color.hue != 43690 || colorDuration != 250 {
+		t.Errorf("Expected full brightness over 250ms with the color kept, got %+v over %dms", color, colorDuration)
+	}
+}
+
+func TestLANClient_ResolvesOnlyClaimedIDSelectors(t *testing.T) {
+	newMockLANDevice(t)
+	client := newTestLANClient()
+
+	if _, err := client.ListDevices("test-token"); err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	if client.Resolves("test-token", "id:d073d5000001") {
+		t.Error("Expected an unclaimed device not to resolve")
+	}
+
+	client.Claim("test-token", []string{"D073D5000001"})
+
+	testCases := []struct {
+		token    string
+		selector string
+		want     bool
+	}{
+		{token: "test-token", selector: "id:d073d5000001", want: true},
+		{token: "other-token", selector: "id:d073d5000001", want: false},
+		{token: "test-token", selector: "id:d073d5000002", want: false},
+		{token: "test-token", selector: "all", want: false},
+		{token: "test-token", selector: "group_id:g1", want: false},
+	}
+	for _, tc := range testCases {
+		if got := client.Resolves(tc.token, tc.selector); got != tc.want {
+			t.Errorf("Resolves(%q, %q) = %v, want %v", tc.token, tc.selector, got, tc.want)
+		}
+	}
+
+	if err := client.SetPower("other-token", "id:d073d5000001", true, 0); !errors.Is(err, ErrLANDeviceNotFound) {
+		t.Errorf("Expected ErrLANDeviceNotFound, got %v", err)
+	}
+}
+
+func TestDecodeMessage_RejectsMalformed(t *testing.T) {
+	client := newTestLANClient()
+	message := client.encode(lanLightGet, [8]byte{1}, nil, false, true)
+
+	if _, _, err := decodeMessage(message[:lanHeaderSize-1]); err == nil {
+		t.Error("Expected a truncated header to be rejected")
+	}
+
+	binary.LittleEndian.PutUint16(message[0:2], lanHeaderSize+10)
+	if _, _, err := decodeMessage(message); err == nil {
+		t.Error("Expected a size beyond the data to be rejected")
+	}
+}
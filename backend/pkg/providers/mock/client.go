@@ -0,0 +1,246 @@
+// Package mock provides a configurable providers.Client for tests
+package mock
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+// AccountID is the provider account ID reported by default for any token
+const AccountID = "mock-account"
+
+// MockClient is a providers.Client whose methods call the matching function field when
+// it is set, and otherwise return a default response: the configured devices, the
+// configured errors or success.
+type MockClient struct {
+	ValidateTokenFn       func(token string) (*providers.AccountInfo, error)
+	GetAccountInfoFn      func(token string) (*providers.AccountInfo, error)
+	ListDevicesFn         func(token string) ([]*providers.Device, error)
+	GetDeviceFn           func(token, deviceID string) (*providers.Device, error)
+	SetPowerFn            func(token, selector string, state bool, duration float64) error
+	SetBrightnessFn       func(token, selector string, level, duration float64) error
+	SetColorFn            func(token, selector string, color *providers.DeviceColor, duration float64) error
+	SetColorTemperatureFn func(token, selector string, kelvin int, duration float64) error
+	SetInfraredFn         func(token, selector string, level float64) error
+	TogglePowerFn         func(token, selector string, duration float64) error
+	SetStatesFn           func(token string, states []providers.DeviceState) error
+	PulseFn               func(token, selector string, color *providers.DeviceColor, cycles int, period float64) error
+	BreatheFn             func(token, selector string, color *providers.DeviceColor, cycles int, period float64) error
+	FlameFn               func(token, selector string, period, duration float64) error
+	MoveFn                func(token, selector, direction string, period, duration float64) error
+	WaveformFn            func(token, selector string, params providers.WaveformParams) error
+
+	// Calls counts the invocations of each method, by method name. Use CallCount while
+	// the client may still be called concurrently.
+	Calls map[string]int
+
+	devices            []*providers.Device
+	powerErr           error
+	listDevicesLatency time.Duration
+	mu                 sync.Mutex
+}
+
+// DeviceOption configures a MockClient created by NewMockClient
+type DeviceOption func(*MockClient)
+
+// Device adds device to the devices the client lists
+func Device(device *providers.Device) DeviceOption {
+	return func(m *MockClient) {
+		m.devices = append(m.devices, device)
+	}
+}
+
+// NewMockClient returns a client listing no devices, and whose calls all succeed, unless
+// configured otherwise by opts
+func NewMockClient(opts ...DeviceOption) *MockClient {
+	m := &MockClient{Calls: make(map[string]int)}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// WithDevices adds devices to the devices the client lists
+func (m *MockClient) WithDevices(devices ...*providers.Device) *MockClient {
+	m.devices = append(m.devices, devices...)
+	return m
+}
+
+// WithPowerError makes SetPower fail with err
+func (m *MockClient) WithPowerError(err error) *MockClient {
+	m.powerErr = err
+	return m
+}
+
+// WithListDevicesLatency delays every ListDevices call by d
+func (m *MockClient) WithListDevicesLatency(d time.Duration) *MockClient {
+	m.listDevicesLatency = d
+	return m
+}
+
+// CallCount returns how many times method was called
+func (m *MockClient) CallCount(method string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Calls[method]
+}
+
+// record counts a call to method
+func (m *MockClient) record(method string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Calls[method]++
+}
+
+// ValidateToken reports AccountID for any token by default
+func (m *MockClient) ValidateToken(token string) (*providers.AccountInfo, error) {
+	m.record("ValidateToken")
+	if m.ValidateTokenFn != nil {
+		return m.ValidateTokenFn(token)
+	}
+	return &providers.AccountInfo{ProviderAccountID: AccountID, Metadata: map[string]interface{}{}}, nil
+}
+
+// GetAccountInfo reports AccountID for any token by default
+func (m *MockClient) GetAccountInfo(token string) (*providers.AccountInfo, error) {
+	m.record("GetAccountInfo")
+	if m.GetAccountInfoFn != nil {
+		return m.GetAccountInfoFn(token)
+	}
+	return &providers.AccountInfo{ProviderAccountID: AccountID, Metadata: map[string]interface{}{}}, nil
+}
+
+// ListDevices returns the configured devices by default
+func (m *MockClient) ListDevices(token string) ([]*providers.Device, error) {
+	time.Sleep(m.listDevicesLatency)
+	m.record("ListDevices")
+	if m.ListDevicesFn != nil {
+		return m.ListDevicesFn(token)
+	}
+	return m.devices, nil
+}
+
+// GetDevice returns the configured device with ID deviceID by default
+func (m *MockClient) GetDevice(token, deviceID string) (*providers.Device, error) {
+	m.record("GetDevice")
+	if m.GetDeviceFn != nil {
+		return m.GetDeviceFn(token, deviceID)
+	}
+	for _, device := range m.devices {
+		if device.ID == deviceID {
+			return device, nil
+		}
+	}
+	return nil, apierror.New(apierror.ErrNotFound, "device not found")
+}
+
+// SetPower returns the error set by WithPowerError by default
+func (m *MockClient) SetPower(token, selector string, state bool, duration float64) error {
+	m.record("SetPower")
+	if m.SetPowerFn != nil {
+		return m.SetPowerFn(token, selector, state, duration)
+	}
+	return m.powerErr
+}
+
+// SetBrightness succeeds by default
+func (m *MockClient) SetBrightness(token, selector string, level, duration float64) error {
+	m.record("SetBrightness")
+	if m.SetBrightnessFn != nil {
+		return m.SetBrightnessFn(token, selector, level, duration)
+	}
+	return nil
+}
+
+// SetColor succeeds by default
+func (m *MockClient) SetColor(token, selector string, color *providers.DeviceColor, duration float64) error {
+	m.record("SetColor")
+	if m.SetColorFn != nil {
+		return m.SetColorFn(token, selector, color, duration)
+	}
+	return nil
+}
+
+// SetColorTemperature succeeds by default
+func (m *MockClient) SetColorTemperature(token, selector string, kelvin int, duration float64) error {
+	m.record("SetColorTemperature")
+	if m.SetColorTemperatureFn != nil {
+		return m.SetColorTemperatureFn(token, selector, kelvin, duration)
+	}
+	return nil
+}
+
+// SetInfrared succeeds by default
+func (m *MockClient) SetInfrared(token, selector string, level float64) error {
+	m.record("SetInfrared")
+	if m.SetInfraredFn != nil {
+		return m.SetInfraredFn(token, selector, level)
+	}
+	return nil
+}
+
+// TogglePower succeeds by default
+func (m *MockClient) TogglePower(token, selector string, duration float64) error {
+	m.record("TogglePower")
+	if m.TogglePowerFn != nil {
+		return m.TogglePowerFn(token, selector, duration)
+	}
+	return nil
+}
+
+// SetStates succeeds by default
+func (m *MockClient) SetStates(token string, states []providers.DeviceState) error {
+	m.record("SetStates")
+	if m.SetStatesFn != nil {
+		return m.SetStatesFn(token, states)
+	}
+	return nil
+}
+
+// Pulse succeeds by default
+func (m *MockClient) Pulse(token, selector string, color *providers.DeviceColor, cycles int, period float64) error {
+	m.record("Pulse")
+	if m.PulseFn != nil {
+		return m.PulseFn(token, selector, color, cycles, period)
+	}
+	return nil
+}
+
+// Breathe succeeds by default
+func (m *MockClient) Breathe(token, selector string, color *providers.DeviceColor, cycles int, period float64) error {
+	m.record("Breathe")
+	if m.BreatheFn != nil {
+		return m.BreatheFn(token, selector, color, cycles, period)
+	}
+	return nil
+}
+
+// Flame succeeds by default
+func (m *MockClient) Flame(token, selector string, period, duration float64) error {
+	m.record("Flame")
+	if m.FlameFn != nil {
+		return m.FlameFn(token, selector, period, duration)
+	}
+	return nil
+}
+
+// Move succeeds by default
+func (m *MockClient) Move(token, selector, direction string, period, duration float64) error {
+	m.record("Move")
+	if m.MoveFn != nil {
+		return m.MoveFn(token, selector, direction, period, duration)
+	}
+	return nil
+}
+
+// Waveform succeeds by default
+func (m *MockClient) Waveform(token, selector string, params providers.WaveformParams) error {
+	m.record("Waveform")
+	if m.WaveformFn != nil {
+		return m.WaveformFn(token, selector, params)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package mock
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lightshare/backend/pkg/apierror"
+	"github.com/lightshare/backend/pkg/providers"
+)
+
+func TestMockClient_Defaults(t *testing.T) {
+	client := NewMockClient(Device(&providers.Device{ID: "d1"})).WithDevices(&providers.Device{ID: "d2"})
+
+	devices, err := client.ListDevices("token")
+	if err != nil || len(devices) != 2 {
+		t.Fatalf("Expected the 2 configured devices, got %d devices and error %v", len(devices), err)
+	}
+	if device, err := client.GetDevice("token", "d2"); err != nil || device.ID != "d2" {
+		t.Errorf("Expected device d2, got %v and error %v", device, err)
+	}
+	if _, err := client.GetDevice("token", "missing"); !errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+	if info, err := client.ValidateToken("token"); err != nil || info.ProviderAccountID != AccountID {
+		t.Errorf("Expected account %s, got %v and error %v", AccountID, info, err)
+	}
+	if err := client.SetColorTemperature("token", "all", 3000, 0); err != nil {
+		t.Errorf("Expected SetColorTemperature to succeed, got %v", err)
+	}
+
+	if client.Calls["GetDevice"] != 2 || client.CallCount("ListDevices") != 1 {
+		t.Errorf("Expected calls to be counted by method, got %v", client.Calls)
+	}
+}
+
+func TestMockClient_Overrides(t *testing.T) {
+	powerErr := errors.New("bulb unreachable")
+	client := NewMockClient().WithPowerError(powerErr).WithListDevicesLatency(10 * time.Millisecond)
+
+	if err := client.SetPower("token", "all", true, 0); !errors.Is(err, powerErr) {
+		t.Errorf("Expected the configured power error, got %v", err)
+	}
+
+	start := time.Now()
+	if _, err := client.ListDevices("token"); err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("Expected ListDevices to take at least 10ms, took %v", elapsed)
+	}
+
+	client.SetPowerFn = func(_, selector string, _ bool, _ float64) error {
+		if selector != "id:d1" {
+			t.Errorf("Expected selector id:d1, got %q", selector)
+		}
+		return nil
+	}
+	if err := client.SetPower("token", "id:d1", true, 0); err != nil {
+		t.Errorf("Expected SetPowerFn to take precedence over the configured error, got %v", err)
+	}
+}
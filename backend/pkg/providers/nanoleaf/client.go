@@ -0,0 +1,453 @@
+// Package nanoleaf provides a client for the local REST API of Nanoleaf panel controllers
+package nanoleaf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultPort is the port controllers serve their API on
+	defaultPort    = "16021"
+	requestTimeout = 10 * time.Second
+
+	// Color temperature range supported by Nanoleaf panels
+	minKelvin = 1200
+	maxKelvin = 6500
+)
+
+// AccountInfo contains information about a Nanoleaf controller
+type AccountInfo struct {
+	// Additional metadata
+	Metadata map[string]interface{}
+	// ProviderAccountID is the serial number of the controller
+	ProviderAccountID string
+	// Label or name for the controller
+	Label string
+}
+
+// Client talks to Nanoleaf controllers on the local network. Controllers have no cloud
+// account, so each token is a composite "<ip>|<token>" of the controller's address (with
+// an optional port) and the auth token it issued.
+type Client struct {
+	ctx        context.Context
+	httpClient *http.Client
+}
+
+// NewClient creates a new Nanoleaf client whose requests time out after timeout, or
+// after 10s when timeout is 0
+func NewClient(timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = requestTimeout
+	}
+
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		ctx: context.Background(),
+	}
+}
+
+// WithContext returns a copy of the client whose requests carry ctx, so they are
+// cancelled with it
+func (c *Client) WithContext(ctx context.Context) *Client {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// Device represents a Nanoleaf controller and the panels it drives as a single light
+type Device struct {
+	Color        *DeviceColor
+	Group        *DeviceGroup
+	Location     *DeviceLocation
+	Metadata     map[string]interface{}
+	Raw          json.RawMessage // Original Nanoleaf JSON for this controller
+	ID           string
+	Label        string
+	Power        string
+	Capabilities []string
+	Brightness   float64
+	Connected    bool
+	Reachable    bool
+}
+
+// DeviceColor represents color information
+type DeviceColor struct {
+	Hue        float64 // 0-360
+	Saturation float64 // 0.0-1.0
+	Kelvin     int     // 1200-6500
+}
+
+// DeviceGroup represents the panel group of a controller
+type DeviceGroup struct {
+	ID   string
+	Name string
+}
+
+// DeviceLocation is never set; Nanoleaf controllers have no notion of a home
+type DeviceLocation struct {
+	ID   string
+	Name string
+}
+
+// stateValue is a single value of the controller state
+type stateValue struct {
+	Value int `json:"value"`
+}
+
+// controllerInfo is the subset of the controller info the client uses
+type controllerInfo struct {
+	Name            string `json:"name"`
+	SerialNo        string `json:"serialNo"`
+	Model           string `json:"model"`
+	FirmwareVersion string `json:"firmwareVersion"`
+	State           struct {
+		On struct {
+			Value bool `json:"value"`
+		} `json:"on"`
+		Brightness stateValue `json:"brightness"`
+		Hue        stateValue `json:"hue"`
+		Sat        stateValue `json:"sat"`
+		CT         stateValue `json:"ct"`
+		ColorMode  string     `json:"colorMode"`
+	} `json:"state"`
+	Effects struct {
+		Select      string   `json:"select"`
+		EffectsList []string `json:"effectsList"`
+	} `json:"effects"`
+	PanelLayout struct {
+		Layout struct {
+			NumPanels int `json:"numPanels"`
+		} `json:"layout"`
+	} `json:"panelLayout"`
+}
+
+// endpoint is the controller API a token addresses
+type endpoint struct {
+	host    string // Controller address, with port
+	baseURL string // Base URL of the API, including the auth token
+}
+
+// parseToken splits a "<ip>|<token>" composite token into the controller API it addresses
+func parseToken(token string) (*endpoint, error) {
+	address, authToken, ok := strings.Cut(token, "|")
+	address, authToken = strings.TrimSpace(address), strings.TrimSpace(authToken)
+	if !ok || address == "" || authToken == "" {
+		return nil, ErrInvalidToken
+	}
+
+	host := address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		host = net.JoinHostPort(strings.Trim(address, "[]"), defaultPort)
+	}
+
+	return &endpoint{
+		host:    host,
+		baseURL: fmt.Sprintf("http://%s/api/v1/%s", host, url.PathEscape(authToken)),
+	}, nil
+}
+
+// ValidateToken validates the token by reading the controller info
+// The serial number is used as the account identifier since Nanoleaf tokens are per controller
+func (c *Client) ValidateToken(token string) (*AccountInfo, error) {
+	ep, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	info, _, err := c.getInfo(ep)
+	if err != nil {
+		return nil, err
+	}
+	if info.SerialNo == "" {
+		return nil, fmt.Errorf("nanoleaf controller did not report a serial number")
+	}
+
+	return &AccountInfo{
+		ProviderAccountID: info.SerialNo,
+		Label:             info.Name,
+		Metadata: map[string]interface{}{
+			"local_endpoint":   "http://" + ep.host,
+			"model":            info.Model,
+			"firmware_version": info.FirmwareVersion,
+			"panels_count":     info.PanelLayout.Layout.NumPanels,
+		},
+	}, nil
+}
+
+// GetAccountInfo retrieves information about the controller
+// For Nanoleaf, this is the same as ValidateToken
+func (c *Client) GetAccountInfo(token string) (*AccountInfo, error) {
+	return c.ValidateToken(token)
+}
+
+// ListDevices returns the panel group of the controller as a single device
+func (c *Client) ListDevices(token string) ([]*Device, error) {
+	ep, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	info, raw, err := c.getInfo(ep)
+	if err != nil {
+		return nil, err
+	}
+	return []*Device{convertController(info, raw)}, nil
+}
+
+// GetDevice returns the controller's panel group, whose ID is the controller serial number
+func (c *Client) GetDevice(token, deviceID string) (*Device, error) {
+	ep, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	info, raw, err := c.getInfo(ep)
+	if err != nil {
+		return nil, err
+	}
+	if info.SerialNo != deviceID {
+		return nil, fmt.Errorf("device not found: %s", deviceID)
+	}
+	return convertController(info, raw), nil
+}
+
+// convertController converts a controller to the Device type
+func convertController(info *controllerInfo, raw json.RawMessage) *Device {
+	device := &Device{
+		ID:           info.SerialNo,
+		Label:        info.Name,
+		Power:        "off",
+		Brightness:   float64(info.State.Brightness.Value) / 100,
+		Connected:    true,
+		Reachable:    true,
+		Capabilities: []string{"color", "temperature", "brightness", "effects"},
+		Color: &DeviceColor{
+			Hue:        float64(info.State.Hue.Value),
+			Saturation: float64(info.State.Sat.Value) / 100,
+			Kelvin:     info.State.CT.Value,
+		},
+		Group: &DeviceGroup{ID: info.SerialNo, Name: info.Name},
+		Metadata: map[string]interface{}{
+			"model":          info.Model,
+			"panels_count":   info.PanelLayout.Layout.NumPanels,
+			"color_mode":     info.State.ColorMode,
+			"current_effect": info.Effects.Select,
+			"effects":        info.Effects.EffectsList,
+		},
+		Raw: raw,
+	}
+	if info.State.On.Value {
+		device.Power = "on"
+	}
+	return device
+}
+
+// SetPower turns the panels on or off
+// Nanoleaf switches power instantly, so duration is ignored
+func (c *Client) SetPower(token, selector string, state bool, _ float64) error {
+	body := map[string]interface{}{
+		"on": map[string]interface{}{"value": state},
+	}
+	return c.setState(token, selector, "/state", body)
+}
+
+// SetBrightness adjusts brightness (0.0-1.0) over duration, rounded to whole seconds
+func (c *Client) SetBrightness(token, selector string, level, duration float64) error {
+	body := map[string]interface{}{
+		"brightness": map[string]interface{}{
+			"value":    int(math.Round(level * 100)),
+			"duration": int(math.Round(math.Max(duration, 0))),
+		},
+	}
+	return c.setState(token, selector, "/state", body)
+}
+
+// SetColor sets the hue and saturation
+// Nanoleaf changes color instantly, so duration is ignored
+func (c *Client) SetColor(token, selector string, color *DeviceColor, _ float64) error {
+	body := map[string]interface{}{
+		"hue": map[string]interface{}{"value": int(math.Round(color.Hue))},
+		"sat": map[string]interface{}{"value": int(math.Round(color.Saturation * 100))},
+	}
+	return c.setState(token, selector, "/state", body)
+}
+
+// SetColorTemperature sets the white balance, clamped to the panels' 1200-6500K range
+// Nanoleaf changes color temperature instantly, so duration is ignored
+func (c *Client) SetColorTemperature(token, selector string, kelvin int, _ float64) error {
+	kelvin = max(minKelvin, min(maxKelvin, kelvin))
+	body := map[string]interface{}{
+		"ct": map[string]interface{}{"value": kelvin},
+	}
+	return c.setState(token, selector, "/state", body)
+}
+
+// Pulse flashes the panels with a custom "explode" effect
+func (c *Client) Pulse(token, selector string, color *DeviceColor, cycles int, period float64) error {
+	return c.writeEffect(token, selector, "explode", color, cycles, period)
+}
+
+// Breathe fades the panels in and out with a custom "fade" effect
+func (c *Client) Breathe(token, selector string, color *DeviceColor, cycles int, period float64) error {
+	return c.writeEffect(token, selector, "fade", color, cycles, period)
+}
+
+// Flame is not supported by Nanoleaf
+func (c *Client) Flame(_, _ string, _, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "flame effect"}
+}
+
+// Move is not supported by Nanoleaf
+func (c *Client) Move(_, _, _ string, _, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "move effect"}
+}
+
+// writeEffect displays a custom effect alternating between color (white when nil) and dark.
+// Nanoleaf cannot count cycles: a single cycle plays once, more loop until the panels are
+// next changed.
+func (c *Client) writeEffect(token, selector, animType string, color *DeviceColor, cycles int, period float64) error {
+	hue, saturation := 0, 0
+	if color != nil {
+		hue, saturation = int(math.Round(color.Hue)), int(math.Round(color.Saturation*100))
+	}
+
+	// Transition and delay times are in tenths of a second, each half of a cycle
+	halfPeriod := map[string]interface{}{
+		"minValue": int(math.Round(period * 5)),
+		"maxValue": int(math.Round(period * 5)),
+	}
+
+	body := map[string]interface{}{
+		"write": map[string]interface{}{
+			"command":   "display",
+			"animType":  animType,
+			"colorType": "HSB",
+			"palette": []map[string]interface{}{
+				{"hue": hue, "saturation": saturation, "brightness": 100},
+				{"hue": hue, "saturation": saturation, "brightness": 0},
+			},
+			"transTime": halfPeriod,
+			"delayTime": halfPeriod,
+			"loop":      cycles != 1,
+		},
+	}
+	return c.setState(token, selector, "/effects", body)
+}
+
+// setState sends a state or effects change to the controller a selector targets
+func (c *Client) setState(token, selector, path string, body map[string]interface{}) error {
+	ep, err := parseToken(token)
+	if err != nil {
+		return err
+	}
+
+	if err := c.resolveSelector(ep, selector); err != nil {
+		return err
+	}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	_, err = c.do("PUT", ep.baseURL+path, bodyBytes)
+	return err
+}
+
+// resolveSelector checks that a selector targets the controller
+// "all" always does; "id:" and "group_id:" must name the controller serial number
+func (c *Client) resolveSelector(ep *endpoint, selector string) error {
+	var serialNo string
+	switch {
+	case selector == "all":
+		return nil
+	case strings.HasPrefix(selector, "id:"):
+		serialNo = strings.TrimPrefix(selector, "id:")
+	case strings.HasPrefix(selector, "group_id:"):
+		serialNo = strings.TrimPrefix(selector, "group_id:")
+	default:
+		return fmt.Errorf("unsupported selector: %s", selector)
+	}
+
+	info, _, err := c.getInfo(ep)
+	if err != nil {
+		return err
+	}
+	if info.SerialNo != serialNo {
+		return fmt.Errorf("selector not found: %s", selector)
+	}
+	return nil
+}
+
+// getInfo fetches the controller info, returning its original JSON alongside
+func (c *Client) getInfo(ep *endpoint) (*controllerInfo, json.RawMessage, error) {
+	data, err := c.do("GET", ep.baseURL+"/", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var info controllerInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &info, data, nil
+}
+
+// do performs a Nanoleaf API request and returns the response body
+func (c *Client) do(method, requestURL string, body []byte) ([]byte, error) {
+	var reqBody io.Reader = http.NoBody
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, method, requestURL, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Strip the URL, which carries the auth token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to call Nanoleaf API: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			_ = closeErr
+		}
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ErrUnauthorized
+	case http.StatusOK, http.StatusNoContent:
+	default:
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,186 @@
+package nanoleaf
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testControllerInfo is the info served by the mock controller
+const testControllerInfo = `{
+	"name": "Living Room Shapes",
+	"serialNo": "S19124C8036",
+	"model": "NL42",
+	"firmwareVersion": "9.2.4",
+	"state": {
+		"on": {"value": true},
+		"brightness": {"value": 40, "max": 100, "min": 0},
+		"hue": {"value": 120, "max": 360, "min": 0},
+		"sat": {"value": 50, "max": 100, "min": 0},
+		"ct": {"value": 4000, "max": 6500, "min": 1200},
+		"colorMode": "hs"
+	},
+	"effects": {"select": "Northern Lights", "effectsList": ["Northern Lights", "Forest"]},
+	"panelLayout": {"layout": {"numPanels": 9}}
+}`
+
+// recordedRequest captures a change sent to the mock controller
+type recordedRequest struct {
+	Body map[string]interface{}
+	Path string
+}
+
+// newTestServer returns a mock controller accepting the auth token "test-token", and the
+// composite token addressing it
+func newTestServer(t *testing.T) (string, *[]recordedRequest) {
+	t.Helper()
+	var puts []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/test-token/":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(testControllerInfo))
+		case r.Method == http.MethodPut && (r.URL.Path == "/api/v1/test-token/state" || r.URL.Path == "/api/v1/test-token/effects"):
+			data, _ := io.ReadAll(r.Body)
+			var body map[string]interface{}
+			_ = json.Unmarshal(data, &body)
+			puts = append(puts, recordedRequest{Path: r.URL.Path, Body: body})
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server.Listener.Addr().String() + "|test-token", &puts
+}
+
+func TestParseToken(t *testing.T) {
+	testCases := []struct {
+		token   string
+		wantURL string
+	}{
+		{"192.168.1.20|abc", "http://192.168.1.20:16021/api/v1/abc"},
+		{"192.168.1.20:8080|abc", "http://192.168.1.20:8080/api/v1/abc"},
+		{"fe80::1|abc", "http://[fe80::1]:16021/api/v1/abc"},
+	}
+
+	for _, tc := range testCases {
+		ep, err := parseToken(tc.token)
+		if err != nil {
+			t.Fatalf("parseToken(%q) failed: %v", tc.token, err)
+		}
+		if ep.baseURL != tc.wantURL {
+			t.Errorf("Expected %s, got %s", tc.wantURL, ep.baseURL)
+		}
+	}
+
+	for _, token := range []string{"abc", "|abc", "192.168.1.20|"} {
+		if _, err := parseToken(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Expected ErrInvalidToken for %q, got %v", token, err)
+		}
+	}
+}
+
+func TestValidateToken(t *testing.T) {
+	token, _ := newTestServer(t)
+	client := NewClient(0)
+
+	info, err := client.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	if info.ProviderAccountID != "S19124C8036" || info.Label != "Living Room Shapes" {
+		t.Errorf("Unexpected account info: %+v", info)
+	}
+	if endpoint, _ := info.Metadata["local_endpoint"].(string); endpoint == "" {
+		t.Error("Expected local_endpoint metadata")
+	}
+}
+
+func TestValidateToken_Unauthorized(t *testing.T) {
+	token, _ := newTestServer(t)
+	ep, _ := parseToken(token)
+
+	_, err := NewClient(0).ValidateToken(ep.host + "|wrong-token")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestListDevices(t *testing.T) {
+	token, _ := newTestServer(t)
+
+	devices, err := NewClient(0).ListDevices(token)
+	if err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("Expected 1 device, got %d", len(devices))
+	}
+
+	device := devices[0]
+	if device.ID != "S19124C8036" || device.Power != "on" || device.Brightness != 0.4 {
+		t.Errorf("Unexpected device: %+v", device)
+	}
+	if device.Color == nil || device.Color.Hue != 120 || device.Color.Saturation != 0.5 {
+		t.Errorf("Expected hue 120 at 50%% saturation, got %+v", device.Color)
+	}
+	if len(device.Capabilities) != 4 {
+		t.Errorf("Expected 4 capabilities, got %v", device.Capabilities)
+	}
+}
+
+func TestSetState(t *testing.T) {
+	token, puts := newTestServer(t)
+	client := NewClient(0)
+
+	if err := client.SetBrightness(token, "id:S19124C8036", 0.75, 2); err != nil {
+		t.Fatalf("SetBrightness failed: %v", err)
+	}
+	if err := client.SetColorTemperature(token, "all", 9000, 0); err != nil {
+		t.Fatalf("SetColorTemperature failed: %v", err)
+	}
+
+	if len(*puts) != 2 {
+		t.Fatalf("Expected 2 state changes, got %d", len(*puts))
+	}
+	brightness := (*puts)[0].Body["brightness"].(map[string]interface{})
+	if brightness["value"] != 75.0 || brightness["duration"] != 2.0 {
+		t.Errorf("Expected brightness 75 over 2s, got %v", brightness)
+	}
+	ct := (*puts)[1].Body["ct"].(map[string]interface{})
+	if ct["value"] != 6500.0 {
+		t.Errorf("Expected color temperature clamped to 6500, got %v", ct["value"])
+	}
+
+	if err := client.SetPower(token, "id:other-controller", true, 0); err == nil {
+		t.Error("Expected error for a selector naming another controller")
+	}
+}
+
+func TestPulse_WritesEffect(t *testing.T) {
+	token, puts := newTestServer(t)
+
+	if err := NewClient(0).Pulse(token, "all", &DeviceColor{Hue: 240, Saturation: 1}, 3, 2); err != nil {
+		t.Fatalf("Pulse failed: %v", err)
+	}
+
+	if len(*puts) != 1 || (*puts)[0].Path != "/api/v1/test-token/effects" {
+		t.Fatalf("Expected one effects write, got %+v", *puts)
+	}
+	write := (*puts)[0].Body["write"].(map[string]interface{})
+	if write["animType"] != "explode" || write["loop"] != true {
+		t.Errorf("Expected looping explode effect, got %v", write)
+	}
+}
+
+func TestFlame_NotSupported(t *testing.T) {
+	err := NewClient(0).Flame("192.168.1.20|abc", "all", 1, 0)
+	if !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Expected ErrCapabilityNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package nanoleaf
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnauthorized is returned when the controller rejects the auth token
+	ErrUnauthorized = errors.New("invalid token: unauthorized")
+	// ErrInvalidToken is returned when a token is not of the form "<ip>|<token>"
+	ErrInvalidToken = errors.New("invalid token: expected \"<ip>|<token>\"")
+	// ErrCapabilityNotSupported matches any CapabilityNotSupportedError via errors.Is
+	ErrCapabilityNotSupported = errors.New("capability not supported by nanoleaf")
+)
+
+// CapabilityNotSupportedError is returned for operations Nanoleaf has no equivalent for
+type CapabilityNotSupportedError struct {
+	Capability string
+}
+
+func (e *CapabilityNotSupportedError) Error() string {
+	return fmt.Sprintf("nanoleaf does not support %s", e.Capability)
+}
+
+// Is reports whether target is ErrCapabilityNotSupported
+func (e *CapabilityNotSupportedError) Is(target error) bool {
+	return target == ErrCapabilityNotSupported
+}
+
+// StatusError is returned when the controller responds with an unexpected status code
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
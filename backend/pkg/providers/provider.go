@@ -3,9 +3,20 @@
 package providers
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/lightshare/backend/pkg/providers/govee"
+	"github.com/lightshare/backend/pkg/providers/homeassistant"
+	"github.com/lightshare/backend/pkg/providers/hue"
 	"github.com/lightshare/backend/pkg/providers/lifx"
+	"github.com/lightshare/backend/pkg/providers/nanoleaf"
+	"github.com/lightshare/backend/pkg/providers/wiz"
 )
 
 // Provider represents the type of smart lighting provider
@@ -17,11 +28,58 @@ const (
 	ProviderLIFX Provider = "lifx"
 	// ProviderHue represents the Philips Hue smart lighting provider
 	ProviderHue Provider = "hue"
+	// ProviderNanoleaf represents Nanoleaf panels, controlled over their local API
+	ProviderNanoleaf Provider = "nanoleaf"
+	// ProviderWiZ represents WiZ (Signify) lights, controlled through the WiZ cloud
+	ProviderWiZ Provider = "wiz"
+	// ProviderGovee represents Govee lights, controlled through the Govee developer API
+	ProviderGovee Provider = "govee"
+	// ProviderHomeAssistant represents the lights of a Home Assistant instance, controlled
+	// over its REST API
+	ProviderHomeAssistant Provider = "homeassistant"
 )
 
+// Token scopes some providers report in AccountInfo.Metadata under MetadataTokenScopes
+const (
+	MetadataTokenScopes = "token_scopes"
+	// TokenScopesRead is reported for tokens that can list but not control devices
+	TokenScopesRead = lifx.TokenScopesRead
+	// TokenScopesReadWrite is reported for tokens that can list and control devices
+	TokenScopesReadWrite = lifx.TokenScopesReadWrite
+)
+
+// Info describes a registered provider
+type Info struct {
+	ID          Provider `json:"id"`
+	Name        string   `json:"name"`
+	Implemented bool     `json:"implemented"` // False for providers that are planned but not yet supported
+}
+
+// registry lists every provider known to LightShare, in display order
+var registry = []Info{
+	{ID: ProviderLIFX, Name: "LIFX", Implemented: true},
+	{ID: ProviderHue, Name: "Philips Hue", Implemented: true},
+	{ID: ProviderNanoleaf, Name: "Nanoleaf", Implemented: true},
+	{ID: ProviderWiZ, Name: "WiZ", Implemented: true},
+	{ID: ProviderGovee, Name: "Govee", Implemented: true},
+	{ID: ProviderHomeAssistant, Name: "Home Assistant", Implemented: true},
+}
+
+// Registered returns all registered providers
+func Registered() []Info {
+	infos := make([]Info, len(registry))
+	copy(infos, registry)
+	return infos
+}
+
 // IsValid checks if the provider type is valid
 func (p Provider) IsValid() bool {
-	return p == ProviderLIFX || p == ProviderHue
+	for _, info := range registry {
+		if info.ID == p {
+			return true
+		}
+	}
+	return false
 }
 
 // String returns the string representation of the provider
@@ -47,6 +105,7 @@ type Device struct {
 	Group        *DeviceGroup
 	Location     *DeviceLocation
 	Metadata     map[string]interface{}
+	Raw          json.RawMessage // Provider-native payload, sanitized of credentials
 	ID           string
 	Label        string
 	Power        string
@@ -54,6 +113,8 @@ type Device struct {
 	Brightness   float64
 	Connected    bool
 	Reachable    bool
+	// SupportsInfrared is set for lights with an infrared channel (LIFX+)
+	SupportsInfrared bool
 }
 
 // DeviceColor represents color information for a device
@@ -75,6 +136,20 @@ type DeviceLocation struct {
 	Name string
 }
 
+// ContextClient is implemented by clients whose requests can be bound to a context,
+// for cancellation and trace propagation
+type ContextClient interface {
+	WithContext(ctx context.Context) Client
+}
+
+// WithContext binds client's requests to ctx when the client supports it
+func WithContext(ctx context.Context, client Client) Client {
+	if contextClient, ok := client.(ContextClient); ok {
+		return contextClient.WithContext(ctx)
+	}
+	return client
+}
+
 // Client defines the interface that all provider clients must implement
 type Client interface {
 	// ValidateToken validates the token by making a test API call
@@ -112,7 +187,24 @@ type Client interface {
 	// duration: transition time in seconds
 	SetColorTemperature(token, selector string, kelvin int, duration float64) error
 
-	// --- Effects (LIFX-specific, will return error for Hue) ---
+	// SetInfrared sets the maximum level of the infrared channel of night vision lights
+	// level: 0.0-1.0
+	// Providers without infrared lights return a CapabilityNotSupportedError
+	SetInfrared(token, selector string, level float64) error
+
+	// TogglePower turns device(s) on if they are off and off if they are on
+	// Providers without a native toggle use TogglePowerState, which follows the state
+	// of the first selected device
+	// duration: transition time in seconds
+	TogglePower(token, selector string, duration float64) error
+
+	// SetStates applies several states, each to its own selector, in as few round-trips
+	// as the provider allows
+	// Providers without a batch endpoint use SetStatesSequentially
+	// Failures of individual states are reported as a *StatesError
+	SetStates(token string, states []DeviceState) error
+
+	// --- Effects (LIFX-specific, return a NotImplementedError elsewhere; see NoEffects) ---
 
 	// Pulse creates a pulsing effect
 	// cycles: number of times to pulse
@@ -123,17 +215,50 @@ type Client interface {
 	// cycles: number of times to breathe
 	// period: time for one cycle in seconds
 	Breathe(token, selector string, color *DeviceColor, cycles int, period float64) error
+
+	// Flame creates a flickering flame effect
+	// period: time for one flicker cycle in seconds
+	// duration: how long the effect runs in seconds (0 runs until the lights are next changed)
+	Flame(token, selector string, period, duration float64) error
+
+	// Move moves colors along multizone devices
+	// direction: "forward" or "reverse"
+	// period: time for one full cycle in seconds
+	// duration: how long the effect runs in seconds (0 runs until the lights are next changed)
+	Move(token, selector, direction string, period, duration float64) error
+
+	// Waveform cycles the lights between their current color and params.Color along a
+	// waveform
+	Waveform(token, selector string, params WaveformParams) error
+}
+
+// WaveformParams configures a waveform effect
+type WaveformParams struct {
+	Color   *DeviceColor // Color the waveform cycles to; nil keeps the current color
+	Peak    *float64     // Where in each cycle the color peaks, 0-1; nil uses the provider default
+	Type    int          // Waveform shape: 0 saw, 1 sine, 2 half sine, 3 triangle, 4 pulse
+	Period  float64      // Time for one cycle in seconds
+	Cycles  float64      // Number of cycles to run
+	Persist bool         // Keep the waveform's color once the effect ends
 }
 
 // lifxClientAdapter adapts the LIFX client to the Client interface
 type lifxClientAdapter struct {
 	client *lifx.Client
+	// lan, when set, controls the devices it can reach over the local network instead of
+	// going through the cloud
+	lan *lifx.LANClient
+}
+
+// WithContext returns an adapter whose LIFX requests carry ctx
+func (a *lifxClientAdapter) WithContext(ctx context.Context) Client {
+	return &lifxClientAdapter{client: a.client.WithContext(ctx), lan: a.lan}
 }
 
 func (a *lifxClientAdapter) ValidateToken(token string) (*AccountInfo, error) {
 	info, err := a.client.ValidateToken(token)
 	if err != nil {
-		return nil, err
+		return nil, convertLIFXError(err)
 	}
 	return &AccountInfo{
 		ProviderAccountID: info.ProviderAccountID,
@@ -146,7 +271,7 @@ func (a *lifxClientAdapter) ValidateToken(token string) (*AccountInfo, error) {
 func (a *lifxClientAdapter) GetAccountInfo(token string) (*AccountInfo, error) {
 	info, err := a.client.GetAccountInfo(token)
 	if err != nil {
-		return nil, err
+		return nil, convertLIFXError(err)
 	}
 	return &AccountInfo{
 		ProviderAccountID: info.ProviderAccountID,
@@ -160,7 +285,11 @@ func (a *lifxClientAdapter) GetAccountInfo(token string) (*AccountInfo, error) {
 func (a *lifxClientAdapter) ListDevices(token string) ([]*Device, error) {
 	lifxDevices, err := a.client.ListDevices(token)
 	if err != nil {
-		return nil, err
+		return nil, convertLIFXError(err)
+	}
+
+	if a.lan != nil {
+		a.mergeLANDevices(token, lifxDevices)
 	}
 
 	devices := make([]*Device, len(lifxDevices))
@@ -170,23 +299,73 @@ func (a *lifxClientAdapter) ListDevices(token string) ([]*Device, error) {
 	return devices, nil
 }
 
+// mergeLANDevices discovers the devices on the local network, adds their LAN metadata to
+// the matching cloud devices and claims them for token, so they are then controlled over
+// the LAN. Devices the cloud account does not list are ignored.
+func (a *lifxClientAdapter) mergeLANDevices(token string, cloudDevices []*lifx.Device) {
+	lanDevices, err := a.lan.ListDevices(token)
+	if err != nil {
+		return // Discovery failures leave the devices on the cloud
+	}
+
+	byID := make(map[string]*lifx.Device, len(lanDevices))
+	for _, d := range lanDevices {
+		byID[d.ID] = d
+	}
+
+	var claimed []string
+	for _, d := range cloudDevices {
+		lanDevice, ok := byID[strings.ToLower(d.ID)]
+		if !ok {
+			continue
+		}
+		if d.Metadata == nil {
+			d.Metadata = make(map[string]interface{})
+		}
+		for key, value := range lanDevice.Metadata {
+			d.Metadata[key] = value
+		}
+		claimed = append(claimed, d.ID)
+	}
+	a.lan.Claim(token, claimed)
+}
+
+// viaLAN runs call over the LAN when selector targets a device reachable there, reporting
+// whether it succeeded; on false the caller falls back to the cloud
+func (a *lifxClientAdapter) viaLAN(token, selector string, call func(lan *lifx.LANClient) error) bool {
+	if a.lan == nil || !a.lan.Resolves(token, selector) {
+		return false
+	}
+	return call(a.lan) == nil
+}
+
 // GetDevice returns a specific device by ID
 func (a *lifxClientAdapter) GetDevice(token, deviceID string) (*Device, error) {
 	lifxDevice, err := a.client.GetDevice(token, deviceID)
 	if err != nil {
-		return nil, err
+		return nil, convertLIFXError(err)
 	}
 	return convertLIFXDevice(lifxDevice), nil
 }
 
 // SetPower turns device(s) on or off
 func (a *lifxClientAdapter) SetPower(token, selector string, state bool, duration float64) error {
-	return a.client.SetPower(token, selector, state, duration)
+	if a.viaLAN(token, selector, func(lan *lifx.LANClient) error {
+		return lan.SetPower(token, selector, state, duration)
+	}) {
+		return nil
+	}
+	return convertLIFXError(a.client.SetPower(token, selector, state, duration))
 }
 
 // SetBrightness adjusts device brightness
 func (a *lifxClientAdapter) SetBrightness(token, selector string, level, duration float64) error {
-	return a.client.SetBrightness(token, selector, level, duration)
+	if a.viaLAN(token, selector, func(lan *lifx.LANClient) error {
+		return lan.SetBrightness(token, selector, level, duration)
+	}) {
+		return nil
+	}
+	return convertLIFXError(a.client.SetBrightness(token, selector, level, duration))
 }
 
 // SetColor sets device color
@@ -196,12 +375,37 @@ func (a *lifxClientAdapter) SetColor(token, selector string, color *DeviceColor,
 		Saturation: color.Saturation,
 		Kelvin:     color.Kelvin,
 	}
-	return a.client.SetColor(token, selector, lifxColor, duration)
+	if a.viaLAN(token, selector, func(lan *lifx.LANClient) error {
+		return lan.SetColor(token, selector, lifxColor, duration)
+	}) {
+		return nil
+	}
+	return convertLIFXError(a.client.SetColor(token, selector, lifxColor, duration))
 }
 
 // SetColorTemperature sets white balance
 func (a *lifxClientAdapter) SetColorTemperature(token, selector string, kelvin int, duration float64) error {
-	return a.client.SetColorTemperature(token, selector, kelvin, duration)
+	return convertLIFXError(a.client.SetColorTemperature(token, selector, kelvin, duration))
+}
+
+// SetInfrared sets the infrared level of LIFX+ lights
+func (a *lifxClientAdapter) SetInfrared(token, selector string, level float64) error {
+	return convertLIFXError(a.client.SetInfrared(token, selector, level))
+}
+
+// TogglePower toggles device(s) with the native LIFX toggle, which turns the selection
+// off if any of its devices is on
+func (a *lifxClientAdapter) TogglePower(token, selector string, duration float64) error {
+	return convertLIFXError(a.client.TogglePower(token, selector, duration))
+}
+
+// SetStates applies the states with a single LIFX set states request
+func (a *lifxClientAdapter) SetStates(token string, states []DeviceState) error {
+	lifxStates := make([]lifx.LightState, len(states))
+	for i, state := range states {
+		lifxStates[i] = convertLIFXState(state)
+	}
+	return convertLIFXError(a.client.SetStates(token, lifxStates))
 }
 
 // Pulse creates a pulsing effect
@@ -214,7 +418,7 @@ func (a *lifxClientAdapter) Pulse(token, selector string, color *DeviceColor, cy
 			Kelvin:     color.Kelvin,
 		}
 	}
-	return a.client.Pulse(token, selector, lifxColor, cycles, period)
+	return convertLIFXError(a.client.Pulse(token, selector, lifxColor, cycles, period))
 }
 
 // Breathe creates a breathing effect
@@ -227,11 +431,230 @@ func (a *lifxClientAdapter) Breathe(token, selector string, color *DeviceColor,
 			Kelvin:     color.Kelvin,
 		}
 	}
-	return a.client.Breathe(token, selector, lifxColor, cycles, period)
+	return convertLIFXError(a.client.Breathe(token, selector, lifxColor, cycles, period))
+}
+
+// Flame creates a flickering flame effect
+func (a *lifxClientAdapter) Flame(token, selector string, period, duration float64) error {
+	return convertLIFXError(a.client.Flame(token, selector, period, duration))
+}
+
+// Move moves colors along multizone devices
+func (a *lifxClientAdapter) Move(token, selector, direction string, period, duration float64) error {
+	return convertLIFXError(a.client.Move(token, selector, direction, period, duration))
+}
+
+// Waveform cycles the lights along a waveform
+func (a *lifxClientAdapter) Waveform(token, selector string, params WaveformParams) error {
+	lifxParams := lifx.WaveformParams{
+		Peak:     params.Peak,
+		Waveform: params.Type,
+		Period:   params.Period,
+		Cycles:   params.Cycles,
+		Persist:  params.Persist,
+	}
+	if params.Color != nil {
+		lifxParams.Color = &lifx.DeviceColor{
+			Hue:        params.Color.Hue,
+			Saturation: params.Color.Saturation,
+			Kelvin:     params.Color.Kelvin,
+		}
+	}
+	return convertLIFXError(a.client.Waveform(token, selector, lifxParams))
+}
+
+// convertLIFXState converts a generic device state to a LIFX set states element
+func convertLIFXState(state DeviceState) lifx.LightState {
+	lifxState := lifx.LightState{
+		Selector:   state.Selector,
+		Brightness: state.Brightness,
+		Duration:   state.Duration,
+	}
+
+	if state.Power != nil {
+		lifxState.Power = "off"
+		if *state.Power {
+			lifxState.Power = "on"
+		}
+	}
+
+	switch {
+	case state.Color != nil:
+		lifxState.Color = fmt.Sprintf("hue:%f saturation:%f", state.Color.Hue, state.Color.Saturation)
+		if state.Color.Kelvin > 0 {
+			lifxState.Color += fmt.Sprintf(" kelvin:%d", state.Color.Kelvin)
+		}
+	case state.Kelvin > 0:
+		lifxState.Color = fmt.Sprintf("kelvin:%d", state.Kelvin)
+	}
+
+	return lifxState
 }
 
 // convertLIFXDevice converts a LIFX device to the generic Device type
 func convertLIFXDevice(d *lifx.Device) *Device {
+	device := &Device{
+		ID:               d.ID,
+		Label:            d.Label,
+		Power:            d.Power,
+		Brightness:       d.Brightness,
+		Connected:        d.Connected,
+		Reachable:        d.Reachable,
+		Capabilities:     d.Capabilities,
+		Metadata:         d.Metadata,
+		Raw:              sanitizeRawPayload(d.Raw),
+		SupportsInfrared: d.SupportsInfrared,
+	}
+
+	if d.Color != nil {
+		device.Color = &DeviceColor{
+			Hue:        d.Color.Hue,
+			Saturation: d.Color.Saturation,
+			Kelvin:     d.Color.Kelvin,
+		}
+	}
+
+	if d.Group != nil {
+		device.Group = &DeviceGroup{
+			ID:   d.Group.ID,
+			Name: d.Group.Name,
+		}
+	}
+
+	if d.Location != nil {
+		device.Location = &DeviceLocation{
+			ID:   d.Location.ID,
+			Name: d.Location.Name,
+		}
+	}
+
+	return device
+}
+
+// hueClientAdapter adapts the Hue client to the Client interface
+type hueClientAdapter struct {
+	client *hue.Client
+}
+
+// WithContext returns an adapter whose Hue requests carry ctx
+func (a *hueClientAdapter) WithContext(ctx context.Context) Client {
+	return &hueClientAdapter{client: a.client.WithContext(ctx)}
+}
+
+func (a *hueClientAdapter) ValidateToken(token string) (*AccountInfo, error) {
+	info, err := a.client.ValidateToken(token)
+	if err != nil {
+		return nil, convertHueError(err)
+	}
+	return &AccountInfo{
+		ProviderAccountID: info.ProviderAccountID,
+		Label:             info.Label,
+		Metadata:          info.Metadata,
+	}, nil
+}
+
+func (a *hueClientAdapter) GetAccountInfo(token string) (*AccountInfo, error) {
+	info, err := a.client.GetAccountInfo(token)
+	if err != nil {
+		return nil, convertHueError(err)
+	}
+	return &AccountInfo{
+		ProviderAccountID: info.ProviderAccountID,
+		Label:             info.Label,
+		Metadata:          info.Metadata,
+	}, nil
+}
+
+// ListDevices returns all lights on the bridge
+func (a *hueClientAdapter) ListDevices(token string) ([]*Device, error) {
+	hueDevices, err := a.client.ListDevices(token)
+	if err != nil {
+		return nil, convertHueError(err)
+	}
+
+	devices := make([]*Device, len(hueDevices))
+	for i, d := range hueDevices {
+		devices[i] = convertHueDevice(d)
+	}
+	return devices, nil
+}
+
+// GetDevice returns a specific light by ID
+func (a *hueClientAdapter) GetDevice(token, deviceID string) (*Device, error) {
+	hueDevice, err := a.client.GetDevice(token, deviceID)
+	if err != nil {
+		return nil, convertHueError(err)
+	}
+	return convertHueDevice(hueDevice), nil
+}
+
+// SetPower turns light(s) on or off
+func (a *hueClientAdapter) SetPower(token, selector string, state bool, duration float64) error {
+	return convertHueError(a.client.SetPower(token, selector, state, duration))
+}
+
+// SetBrightness adjusts light brightness
+func (a *hueClientAdapter) SetBrightness(token, selector string, level, duration float64) error {
+	return convertHueError(a.client.SetBrightness(token, selector, level, duration))
+}
+
+// SetColor sets light color
+func (a *hueClientAdapter) SetColor(token, selector string, color *DeviceColor, duration float64) error {
+	hueColor := &hue.DeviceColor{
+		Hue:        color.Hue,
+		Saturation: color.Saturation,
+		Kelvin:     color.Kelvin,
+	}
+	return convertHueError(a.client.SetColor(token, selector, hueColor, duration))
+}
+
+// SetColorTemperature sets white balance
+func (a *hueClientAdapter) SetColorTemperature(token, selector string, kelvin int, duration float64) error {
+	return convertHueError(a.client.SetColorTemperature(token, selector, kelvin, duration))
+}
+
+// SetInfrared is not supported: Hue has no infrared lights
+func (a *hueClientAdapter) SetInfrared(_, _ string, _ float64) error {
+	return &CapabilityNotSupportedError{Provider: ProviderHue, Capability: "infrared"}
+}
+
+// TogglePower toggles light(s) based on the current state of the first selected light
+func (a *hueClientAdapter) TogglePower(token, selector string, duration float64) error {
+	return TogglePowerState(a, token, selector, duration)
+}
+
+// SetStates applies the states one light or group at a time, as Hue has no batch endpoint
+func (a *hueClientAdapter) SetStates(token string, states []DeviceState) error {
+	return SetStatesSequentially(a, token, states)
+}
+
+// Pulse is not supported by Hue
+func (a *hueClientAdapter) Pulse(token, selector string, _ *DeviceColor, cycles int, period float64) error {
+	return convertHueError(a.client.Pulse(token, selector, nil, cycles, period))
+}
+
+// Breathe is not supported by Hue
+func (a *hueClientAdapter) Breathe(token, selector string, _ *DeviceColor, cycles int, period float64) error {
+	return convertHueError(a.client.Breathe(token, selector, nil, cycles, period))
+}
+
+// Flame is not supported by Hue
+func (a *hueClientAdapter) Flame(token, selector string, period, duration float64) error {
+	return convertHueError(a.client.Flame(token, selector, period, duration))
+}
+
+// Move is not supported by Hue
+func (a *hueClientAdapter) Move(token, selector, direction string, period, duration float64) error {
+	return convertHueError(a.client.Move(token, selector, direction, period, duration))
+}
+
+// Waveform is not supported by Hue
+func (a *hueClientAdapter) Waveform(_, _ string, _ WaveformParams) error {
+	return convertHueError(&hue.CapabilityNotSupportedError{Capability: "waveform effect"})
+}
+
+// convertHueDevice converts a Hue device to the generic Device type
+func convertHueDevice(d *hue.Device) *Device {
 	device := &Device{
 		ID:           d.ID,
 		Label:        d.Label,
@@ -241,6 +664,7 @@ func convertLIFXDevice(d *lifx.Device) *Device {
 		Reachable:    d.Reachable,
 		Capabilities: d.Capabilities,
 		Metadata:     d.Metadata,
+		Raw:          sanitizeRawPayload(d.Raw),
 	}
 
 	if d.Color != nil {
@@ -268,13 +692,735 @@ func convertLIFXDevice(d *lifx.Device) *Device {
 	return device
 }
 
-// NewClient creates a new provider client based on the provider type
-func NewClient(provider Provider) (Client, error) {
-	switch provider {
-	case ProviderLIFX:
-		return &lifxClientAdapter{client: lifx.NewClient()}, nil
+// nanoleafClientAdapter adapts the Nanoleaf client to the Client interface
+type nanoleafClientAdapter struct {
+	client *nanoleaf.Client
+}
+
+// WithContext returns an adapter whose Nanoleaf requests carry ctx
+func (a *nanoleafClientAdapter) WithContext(ctx context.Context) Client {
+	return &nanoleafClientAdapter{client: a.client.WithContext(ctx)}
+}
+
+func (a *nanoleafClientAdapter) ValidateToken(token string) (*AccountInfo, error) {
+	info, err := a.client.ValidateToken(token)
+	if err != nil {
+		return nil, convertNanoleafError(err)
+	}
+	return &AccountInfo{
+		ProviderAccountID: info.ProviderAccountID,
+		Label:             info.Label,
+		Metadata:          info.Metadata,
+	}, nil
+}
+
+func (a *nanoleafClientAdapter) GetAccountInfo(token string) (*AccountInfo, error) {
+	info, err := a.client.GetAccountInfo(token)
+	if err != nil {
+		return nil, convertNanoleafError(err)
+	}
+	return &AccountInfo{
+		ProviderAccountID: info.ProviderAccountID,
+		Label:             info.Label,
+		Metadata:          info.Metadata,
+	}, nil
+}
+
+// ListDevices returns the panel group of the controller
+func (a *nanoleafClientAdapter) ListDevices(token string) ([]*Device, error) {
+	nanoleafDevices, err := a.client.ListDevices(token)
+	if err != nil {
+		return nil, convertNanoleafError(err)
+	}
+
+	devices := make([]*Device, len(nanoleafDevices))
+	for i, d := range nanoleafDevices {
+		devices[i] = convertNanoleafDevice(d)
+	}
+	return devices, nil
+}
+
+// GetDevice returns the panel group of the controller by ID
+func (a *nanoleafClientAdapter) GetDevice(token, deviceID string) (*Device, error) {
+	nanoleafDevice, err := a.client.GetDevice(token, deviceID)
+	if err != nil {
+		return nil, convertNanoleafError(err)
+	}
+	return convertNanoleafDevice(nanoleafDevice), nil
+}
+
+// SetPower turns the panels on or off
+func (a *nanoleafClientAdapter) SetPower(token, selector string, state bool, duration float64) error {
+	return convertNanoleafError(a.client.SetPower(token, selector, state, duration))
+}
+
+// SetBrightness adjusts panel brightness
+func (a *nanoleafClientAdapter) SetBrightness(token, selector string, level, duration float64) error {
+	return convertNanoleafError(a.client.SetBrightness(token, selector, level, duration))
+}
+
+// SetColor sets panel color
+func (a *nanoleafClientAdapter) SetColor(token, selector string, color *DeviceColor, duration float64) error {
+	nanoleafColor := &nanoleaf.DeviceColor{
+		Hue:        color.Hue,
+		Saturation: color.Saturation,
+		Kelvin:     color.Kelvin,
+	}
+	return convertNanoleafError(a.client.SetColor(token, selector, nanoleafColor, duration))
+}
+
+// SetColorTemperature sets white balance
+func (a *nanoleafClientAdapter) SetColorTemperature(token, selector string, kelvin int, duration float64) error {
+	return convertNanoleafError(a.client.SetColorTemperature(token, selector, kelvin, duration))
+}
+
+// SetInfrared is not supported: Nanoleaf has no infrared lights
+func (a *nanoleafClientAdapter) SetInfrared(_, _ string, _ float64) error {
+	return &CapabilityNotSupportedError{Provider: ProviderNanoleaf, Capability: "infrared"}
+}
+
+// TogglePower toggles the panels based on their current state
+func (a *nanoleafClientAdapter) TogglePower(token, selector string, duration float64) error {
+	return TogglePowerState(a, token, selector, duration)
+}
+
+// SetStates applies the states one at a time, as Nanoleaf has no batch endpoint
+func (a *nanoleafClientAdapter) SetStates(token string, states []DeviceState) error {
+	return SetStatesSequentially(a, token, states)
+}
+
+// Pulse flashes the panels with a custom effect
+func (a *nanoleafClientAdapter) Pulse(token, selector string, color *DeviceColor, cycles int, period float64) error {
+	return convertNanoleafError(a.client.Pulse(token, selector, convertNanoleafColor(color), cycles, period))
+}
+
+// Breathe fades the panels in and out with a custom effect
+func (a *nanoleafClientAdapter) Breathe(token, selector string, color *DeviceColor, cycles int, period float64) error {
+	return convertNanoleafError(a.client.Breathe(token, selector, convertNanoleafColor(color), cycles, period))
+}
+
+// Flame is not supported by Nanoleaf
+func (a *nanoleafClientAdapter) Flame(token, selector string, period, duration float64) error {
+	return convertNanoleafError(a.client.Flame(token, selector, period, duration))
+}
+
+// Move is not supported by Nanoleaf
+func (a *nanoleafClientAdapter) Move(token, selector, direction string, period, duration float64) error {
+	return convertNanoleafError(a.client.Move(token, selector, direction, period, duration))
+}
+
+// Waveform is not supported by Nanoleaf
+func (a *nanoleafClientAdapter) Waveform(_, _ string, _ WaveformParams) error {
+	return convertNanoleafError(&nanoleaf.CapabilityNotSupportedError{Capability: "waveform effect"})
+}
+
+// convertNanoleafColor converts an optional generic color to a Nanoleaf color
+func convertNanoleafColor(color *DeviceColor) *nanoleaf.DeviceColor {
+	if color == nil {
+		return nil
+	}
+	return &nanoleaf.DeviceColor{
+		Hue:        color.Hue,
+		Saturation: color.Saturation,
+		Kelvin:     color.Kelvin,
+	}
+}
+
+// convertNanoleafDevice converts a Nanoleaf device to the generic Device type
+func convertNanoleafDevice(d *nanoleaf.Device) *Device {
+	device := &Device{
+		ID:           d.ID,
+		Label:        d.Label,
+		Power:        d.Power,
+		Brightness:   d.Brightness,
+		Connected:    d.Connected,
+		Reachable:    d.Reachable,
+		Capabilities: d.Capabilities,
+		Metadata:     d.Metadata,
+		Raw:          sanitizeRawPayload(d.Raw),
+	}
+
+	if d.Color != nil {
+		device.Color = &DeviceColor{
+			Hue:        d.Color.Hue,
+			Saturation: d.Color.Saturation,
+			Kelvin:     d.Color.Kelvin,
+		}
+	}
+
+	if d.Group != nil {
+		device.Group = &DeviceGroup{
+			ID:   d.Group.ID,
+			Name: d.Group.Name,
+		}
+	}
+
+	if d.Location != nil {
+		device.Location = &DeviceLocation{
+			ID:   d.Location.ID,
+			Name: d.Location.Name,
+		}
+	}
+
+	return device
+}
+
+// wizClientAdapter adapts the WiZ client to the Client interface
+type wizClientAdapter struct {
+	client *wiz.Client
+}
+
+// WithContext returns an adapter whose WiZ requests carry ctx
+func (a *wizClientAdapter) WithContext(ctx context.Context) Client {
+	return &wizClientAdapter{client: a.client.WithContext(ctx)}
+}
+
+func (a *wizClientAdapter) ValidateToken(token string) (*AccountInfo, error) {
+	info, err := a.client.ValidateToken(token)
+	if err != nil {
+		return nil, convertWiZError(err)
+	}
+	return &AccountInfo{
+		ProviderAccountID: info.ProviderAccountID,
+		Label:             info.Label,
+		Metadata:          info.Metadata,
+	}, nil
+}
+
+func (a *wizClientAdapter) GetAccountInfo(token string) (*AccountInfo, error) {
+	info, err := a.client.GetAccountInfo(token)
+	if err != nil {
+		return nil, convertWiZError(err)
+	}
+	return &AccountInfo{
+		ProviderAccountID: info.ProviderAccountID,
+		Label:             info.Label,
+		Metadata:          info.Metadata,
+	}, nil
+}
+
+// ListDevices returns all lights in the home
+func (a *wizClientAdapter) ListDevices(token string) ([]*Device, error) {
+	wizDevices, err := a.client.ListDevices(token)
+	if err != nil {
+		return nil, convertWiZError(err)
+	}
+
+	devices := make([]*Device, len(wizDevices))
+	for i, d := range wizDevices {
+		devices[i] = convertWiZDevice(d)
+	}
+	return devices, nil
+}
+
+// GetDevice returns a specific light by MAC address
+func (a *wizClientAdapter) GetDevice(token, deviceID string) (*Device, error) {
+	wizDevice, err := a.client.GetDevice(token, deviceID)
+	if err != nil {
+		return nil, convertWiZError(err)
+	}
+	return convertWiZDevice(wizDevice), nil
+}
+
+// SetPower turns light(s) on or off
+func (a *wizClientAdapter) SetPower(token, selector string, state bool, duration float64) error {
+	return convertWiZError(a.client.SetPower(token, selector, state, duration))
+}
+
+// SetBrightness adjusts light brightness
+func (a *wizClientAdapter) SetBrightness(token, selector string, level, duration float64) error {
+	return convertWiZError(a.client.SetBrightness(token, selector, level, duration))
+}
+
+// SetColor sets light color
+func (a *wizClientAdapter) SetColor(token, selector string, color *DeviceColor, duration float64) error {
+	wizColor := &wiz.DeviceColor{
+		Hue:        color.Hue,
+		Saturation: color.Saturation,
+		Kelvin:     color.Kelvin,
+	}
+	return convertWiZError(a.client.SetColor(token, selector, wizColor, duration))
+}
+
+// SetColorTemperature sets white balance
+func (a *wizClientAdapter) SetColorTemperature(token, selector string, kelvin int, duration float64) error {
+	return convertWiZError(a.client.SetColorTemperature(token, selector, kelvin, duration))
+}
+
+// SetInfrared is not supported: WiZ has no infrared lights
+func (a *wizClientAdapter) SetInfrared(_, _ string, _ float64) error {
+	return &CapabilityNotSupportedError{Provider: ProviderWiZ, Capability: "infrared"}
+}
+
+// TogglePower toggles light(s) based on the current state of the first selected light
+func (a *wizClientAdapter) TogglePower(token, selector string, duration float64) error {
+	return TogglePowerState(a, token, selector, duration)
+}
+
+// SetStates applies the states one light or room at a time, as WiZ has no batch endpoint
+func (a *wizClientAdapter) SetStates(token string, states []DeviceState) error {
+	return SetStatesSequentially(a, token, states)
+}
+
+// Pulse is not supported by WiZ
+func (a *wizClientAdapter) Pulse(token, selector string, _ *DeviceColor, cycles int, period float64) error {
+	return convertWiZError(a.client.Pulse(token, selector, nil, cycles, period))
+}
+
+// Breathe is not supported by WiZ
+func (a *wizClientAdapter) Breathe(token, selector string, _ *DeviceColor, cycles int, period float64) error {
+	return convertWiZError(a.client.Breathe(token, selector, nil, cycles, period))
+}
+
+// Flame plays the WiZ fireplace scene
+func (a *wizClientAdapter) Flame(token, selector string, period, duration float64) error {
+	return convertWiZError(a.client.Flame(token, selector, period, duration))
+}
+
+// Move is not supported by WiZ
+func (a *wizClientAdapter) Move(token, selector, direction string, period, duration float64) error {
+	return convertWiZError(a.client.Move(token, selector, direction, period, duration))
+}
+
+// Waveform is not supported by WiZ
+func (a *wizClientAdapter) Waveform(_, _ string, _ WaveformParams) error {
+	return convertWiZError(&wiz.CapabilityNotSupportedError{Capability: "waveform effect"})
+}
+
+// convertWiZDevice converts a WiZ device to the generic Device type
+func convertWiZDevice(d *wiz.Device) *Device {
+	device := &Device{
+		ID:           d.ID,
+		Label:        d.Label,
+		Power:        d.Power,
+		Brightness:   d.Brightness,
+		Connected:    d.Connected,
+		Reachable:    d.Reachable,
+		Capabilities: d.Capabilities,
+		Metadata:     d.Metadata,
+		Raw:          sanitizeRawPayload(d.Raw),
+	}
+
+	if d.Color != nil {
+		device.Color = &DeviceColor{
+			Hue:        d.Color.Hue,
+			Saturation: d.Color.Saturation,
+			Kelvin:     d.Color.Kelvin,
+		}
+	}
+
+	if d.Group != nil {
+		device.Group = &DeviceGroup{
+			ID:   d.Group.ID,
+			Name: d.Group.Name,
+		}
+	}
+
+	if d.Location != nil {
+		device.Location = &DeviceLocation{
+			ID:   d.Location.ID,
+			Name: d.Location.Name,
+		}
+	}
+
+	return device
+}
+
+// goveeClientAdapter adapts the Govee client to the Client interface
+type goveeClientAdapter struct {
+	client *govee.Client
+}
+
+// WithContext returns an adapter whose Govee requests carry ctx
+func (a *goveeClientAdapter) WithContext(ctx context.Context) Client {
+	return &goveeClientAdapter{client: a.client.WithContext(ctx)}
+}
+
+func (a *goveeClientAdapter) ValidateToken(token string) (*AccountInfo, error) {
+	info, err := a.client.ValidateToken(token)
+	if err != nil {
+		return nil, convertGoveeError(err)
+	}
+	return &AccountInfo{
+		ProviderAccountID: info.ProviderAccountID,
+		Email:             info.Email,
+		Label:             info.Email,
+		Metadata:          info.Metadata,
+	}, nil
+}
+
+func (a *goveeClientAdapter) GetAccountInfo(token string) (*AccountInfo, error) {
+	info, err := a.client.GetAccountInfo(token)
+	if err != nil {
+		return nil, convertGoveeError(err)
+	}
+	return &AccountInfo{
+		ProviderAccountID: info.ProviderAccountID,
+		Email:             info.Email,
+		Label:             info.Email,
+		Metadata:          info.Metadata,
+	}, nil
+}
+
+// ListDevices returns all lights of the account
+func (a *goveeClientAdapter) ListDevices(token string) ([]*Device, error) {
+	goveeDevices, err := a.client.ListDevices(token)
+	if err != nil {
+		return nil, convertGoveeError(err)
+	}
+
+	devices := make([]*Device, len(goveeDevices))
+	for i, d := range goveeDevices {
+		devices[i] = convertGoveeDevice(d)
+	}
+	return devices, nil
+}
+
+// GetDevice returns a specific light by device ID
+func (a *goveeClientAdapter) GetDevice(token, deviceID string) (*Device, error) {
+	goveeDevice, err := a.client.GetDevice(token, deviceID)
+	if err != nil {
+		return nil, convertGoveeError(err)
+	}
+	return convertGoveeDevice(goveeDevice), nil
+}
+
+// SetPower turns light(s) on or off
+func (a *goveeClientAdapter) SetPower(token, selector string, state bool, duration float64) error {
+	return convertGoveeError(a.client.SetPower(token, selector, state, duration))
+}
+
+// SetBrightness adjusts light brightness
+func (a *goveeClientAdapter) SetBrightness(token, selector string, level, duration float64) error {
+	return convertGoveeError(a.client.SetBrightness(token, selector, level, duration))
+}
+
+// SetColor sets light color
+func (a *goveeClientAdapter) SetColor(token, selector string, color *DeviceColor, duration float64) error {
+	goveeColor := &govee.DeviceColor{
+		Hue:        color.Hue,
+		Saturation: color.Saturation,
+		Kelvin:     color.Kelvin,
+	}
+	return convertGoveeError(a.client.SetColor(token, selector, goveeColor, duration))
+}
+
+// SetColorTemperature sets white balance
+func (a *goveeClientAdapter) SetColorTemperature(token, selector string, kelvin int, duration float64) error {
+	return convertGoveeError(a.client.SetColorTemperature(token, selector, kelvin, duration))
+}
+
+// SetInfrared is not supported: Govee has no infrared lights
+func (a *goveeClientAdapter) SetInfrared(_, _ string, _ float64) error {
+	return &CapabilityNotSupportedError{Provider: ProviderGovee, Capability: "infrared"}
+}
+
+// TogglePower toggles light(s) based on the current state of the first selected light
+func (a *goveeClientAdapter) TogglePower(token, selector string, duration float64) error {
+	return TogglePowerState(a, token, selector, duration)
+}
+
+// SetStates applies the states one light at a time, as Govee has no batch endpoint
+func (a *goveeClientAdapter) SetStates(token string, states []DeviceState) error {
+	return SetStatesSequentially(a, token, states)
+}
+
+// Pulse is not supported by Govee
+func (a *goveeClientAdapter) Pulse(token, selector string, _ *DeviceColor, cycles int, period float64) error {
+	return convertGoveeError(a.client.Pulse(token, selector, nil, cycles, period))
+}
+
+// Breathe is not supported by Govee
+func (a *goveeClientAdapter) Breathe(token, selector string, _ *DeviceColor, cycles int, period float64) error {
+	return convertGoveeError(a.client.Breathe(token, selector, nil, cycles, period))
+}
+
+// Flame is not supported by Govee
+func (a *goveeClientAdapter) Flame(token, selector string, period, duration float64) error {
+	return convertGoveeError(a.client.Flame(token, selector, period, duration))
+}
+
+// Move is not supported by Govee
+func (a *goveeClientAdapter) Move(token, selector, direction string, period, duration float64) error {
+	return convertGoveeError(a.client.Move(token, selector, direction, period, duration))
+}
+
+// Waveform is not supported by Govee
+func (a *goveeClientAdapter) Waveform(_, _ string, _ WaveformParams) error {
+	return convertGoveeError(&govee.CapabilityNotSupportedError{Capability: "waveform effect"})
+}
+
+// convertGoveeDevice converts a Govee device to the generic Device type
+// Govee has no rooms or homes, so the device has no group or location
+func convertGoveeDevice(d *govee.Device) *Device {
+	device := &Device{
+		ID:           d.ID,
+		Label:        d.Label,
+		Power:        d.Power,
+		Brightness:   d.Brightness,
+		Connected:    d.Connected,
+		Reachable:    d.Reachable,
+		Capabilities: d.Capabilities,
+		Metadata:     d.Metadata,
+		Raw:          sanitizeRawPayload(d.Raw),
+	}
+
+	if d.Color != nil {
+		device.Color = &DeviceColor{
+			Hue:        d.Color.Hue,
+			Saturation: d.Color.Saturation,
+			Kelvin:     d.Color.Kelvin,
+		}
+	}
+
+	return device
+}
+
+// homeAssistantClientAdapter adapts the Home Assistant client to the Client interface
+type homeAssistantClientAdapter struct {
+	client *homeassistant.Client
+}
+
+// WithContext returns an adapter whose Home Assistant requests carry ctx
+func (a *homeAssistantClientAdapter) WithContext(ctx context.Context) Client {
+	return &homeAssistantClientAdapter{client: a.client.WithContext(ctx)}
+}
+
+func (a *homeAssistantClientAdapter) ValidateToken(token string) (*AccountInfo, error) {
+	info, err := a.client.ValidateToken(token)
+	if err != nil {
+		return nil, convertHomeAssistantError(err)
+	}
+	return &AccountInfo{
+		ProviderAccountID: info.ProviderAccountID,
+		Label:             info.Label,
+		Metadata:          info.Metadata,
+	}, nil
+}
+
+func (a *homeAssistantClientAdapter) GetAccountInfo(token string) (*AccountInfo, error) {
+	info, err := a.client.GetAccountInfo(token)
+	if err != nil {
+		return nil, convertHomeAssistantError(err)
+	}
+	return &AccountInfo{
+		ProviderAccountID: info.ProviderAccountID,
+		Label:             info.Label,
+		Metadata:          info.Metadata,
+	}, nil
+}
+
+// ListDevices returns all light entities of the instance
+func (a *homeAssistantClientAdapter) ListDevices(token string) ([]*Device, error) {
+	haDevices, err := a.client.ListDevices(token)
+	if err != nil {
+		return nil, convertHomeAssistantError(err)
+	}
+
+	devices := make([]*Device, len(haDevices))
+	for i, d := range haDevices {
+		devices[i] = convertHomeAssistantDevice(d)
+	}
+	return devices, nil
+}
+
+// GetDevice returns a specific light by entity ID
+func (a *homeAssistantClientAdapter) GetDevice(token, deviceID string) (*Device, error) {
+	haDevice, err := a.client.GetDevice(token, deviceID)
+	if err != nil {
+		return nil, convertHomeAssistantError(err)
+	}
+	return convertHomeAssistantDevice(haDevice), nil
+}
+
+// SetPower turns light(s) on or off
+func (a *homeAssistantClientAdapter) SetPower(token, selector string, state bool, duration float64) error {
+	return convertHomeAssistantError(a.client.SetPower(token, selector, state, duration))
+}
+
+// SetBrightness adjusts light brightness
+func (a *homeAssistantClientAdapter) SetBrightness(token, selector string, level, duration float64) error {
+	return convertHomeAssistantError(a.client.SetBrightness(token, selector, level, duration))
+}
+
+// SetColor sets light color
+func (a *homeAssistantClientAdapter) SetColor(token, selector string, color *DeviceColor, duration float64) error {
+	haColor := &homeassistant.DeviceColor{
+		Hue:        color.Hue,
+		Saturation: color.Saturation,
+		Kelvin:     color.Kelvin,
+	}
+	return convertHomeAssistantError(a.client.SetColor(token, selector, haColor, duration))
+}
+
+// SetColorTemperature sets white balance
+func (a *homeAssistantClientAdapter) SetColorTemperature(token, selector string, kelvin int, duration float64) error {
+	return convertHomeAssistantError(a.client.SetColorTemperature(token, selector, kelvin, duration))
+}
+
+// SetInfrared is not supported: Home Assistant has no infrared lights
+func (a *homeAssistantClientAdapter) SetInfrared(_, _ string, _ float64) error {
+	return &CapabilityNotSupportedError{Provider: ProviderHomeAssistant, Capability: "infrared"}
+}
+
+// TogglePower toggles light(s) based on the current state of the first selected light
+func (a *homeAssistantClientAdapter) TogglePower(token, selector string, duration float64) error {
+	return TogglePowerState(a, token, selector, duration)
+}
+
+// SetStates applies the states one light at a time, as the REST API has no batch endpoint
+func (a *homeAssistantClientAdapter) SetStates(token string, states []DeviceState) error {
+	return SetStatesSequentially(a, token, states)
+}
+
+// Pulse is not supported by Home Assistant
+func (a *homeAssistantClientAdapter) Pulse(token, selector string, _ *DeviceColor, cycles int, period float64) error {
+	return convertHomeAssistantError(a.client.Pulse(token, selector, nil, cycles, period))
+}
+
+// Breathe is not supported by Home Assistant
+func (a *homeAssistantClientAdapter) Breathe(token, selector string, _ *DeviceColor, cycles int, period float64) error {
+	return convertHomeAssistantError(a.client.Breathe(token, selector, nil, cycles, period))
+}
+
+// Flame is not supported by Home Assistant
+func (a *homeAssistantClientAdapter) Flame(token, selector string, period, duration float64) error {
+	return convertHomeAssistantError(a.client.Flame(token, selector, period, duration))
+}
+
+// Move is not supported by Home Assistant
+func (a *homeAssistantClientAdapter) Move(token, selector, direction string, period, duration float64) error {
+	return convertHomeAssistantError(a.client.Move(token, selector, direction, period, duration))
+}
+
+// Waveform is not supported by Home Assistant
+func (a *homeAssistantClientAdapter) Waveform(_, _ string, _ WaveformParams) error {
+	return convertHomeAssistantError(&homeassistant.CapabilityNotSupportedError{Capability: "waveform effect"})
+}
+
+// convertHomeAssistantDevice converts a Home Assistant light entity to the generic Device type
+// Areas are not exposed by the REST API, so the device has no group or location
+func convertHomeAssistantDevice(d *homeassistant.Device) *Device {
+	device := &Device{
+		ID:           d.ID,
+		Label:        d.Label,
+		Power:        d.Power,
+		Brightness:   d.Brightness,
+		Connected:    d.Connected,
+		Reachable:    d.Reachable,
+		Capabilities: d.Capabilities,
+		Metadata:     d.Metadata,
+		Raw:          sanitizeRawPayload(d.Raw),
+	}
+
+	if d.Color != nil {
+		device.Color = &DeviceColor{
+			Hue:        d.Color.Hue,
+			Saturation: d.Color.Saturation,
+			Kelvin:     d.Color.Kelvin,
+		}
+	}
+
+	return device
+}
+
+// sensitiveRawKeys lists payload fields that must never be passed through to clients
+var sensitiveRawKeys = []string{"token", "access_token", "refresh_token", "secret", "password", "api_key"}
+
+// sanitizeRawPayload strips credential-like fields from a provider-native payload
+// Returns nil if the payload is empty or not a JSON object
+func sanitizeRawPayload(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil
+	}
+
+	for key := range fields {
+		for _, sensitive := range sensitiveRawKeys {
+			if strings.EqualFold(key, sensitive) {
+				delete(fields, key)
+				break
+			}
+		}
+	}
+
+	sanitized, err := json.Marshal(fields)
+	if err != nil {
+		return nil
+	}
+	return sanitized
+}
+
+// ProviderOption configures a client created by NewClient
+type ProviderOption func(*clientOptions)
+
+// clientOptions holds the settings applied by ProviderOptions
+type clientOptions struct {
+	enabled []string
+	timeout time.Duration
+	useLAN  bool
+}
+
+// lanClient is shared by every LIFX client in LAN mode, so devices discovered while
+// listing one account's devices can be controlled by later clients
+var lanClient = sync.OnceValue(lifx.NewLANClient)
+
+// WithTimeout sets the HTTP timeout of the client's API requests. A timeout of 0 keeps
+// the provider's default.
+func WithTimeout(d time.Duration) ProviderOption {
+	return func(o *clientOptions) {
+		o.timeout = d
+	}
+}
+
+// WithLAN makes LIFX clients control the devices they find on the local network over the
+// LIFX LAN protocol, falling back to the cloud API for the others. Other providers ignore it.
+func WithLAN(useLAN bool) ProviderOption {
+	return func(o *clientOptions) {
+		o.useLAN = useLAN
+	}
+}
+
+// WithEnabledProviders restricts NewClient to the listed providers; the others fail with
+// ErrProviderDisabled. A nil list enables every provider.
+func WithEnabledProviders(enabled []string) ProviderOption {
+	return func(o *clientOptions) {
+		o.enabled = enabled
+	}
+}
+
+// NewClient creates a new provider client based on the provider type
+func NewClient(provider Provider, opts ...ProviderOption) (Client, error) {
+	var options clientOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if provider.IsValid() && options.enabled != nil && !slices.Contains(options.enabled, string(provider)) {
+		return nil, fmt.Errorf("%w: %s", ErrProviderDisabled, provider)
+	}
+
+	switch provider {
+	case ProviderLIFX:
+		adapter := &lifxClientAdapter{client: lifx.NewClient(options.timeout)}
+		if options.useLAN {
+			adapter.lan = lanClient()
+		}
+		return adapter, nil
 	case ProviderHue:
-		return nil, fmt.Errorf("hue provider not yet implemented")
+		return &hueClientAdapter{client: hue.NewClient(options.timeout)}, nil
+	case ProviderNanoleaf:
+		return &nanoleafClientAdapter{client: nanoleaf.NewClient(options.timeout)}, nil
+	case ProviderWiZ:
+		return &wizClientAdapter{client: wiz.NewClient(options.timeout)}, nil
+	case ProviderGovee:
+		return &goveeClientAdapter{client: govee.NewClient(options.timeout)}, nil
+	case ProviderHomeAssistant:
+		return &homeAssistantClientAdapter{client: homeassistant.NewClient(options.timeout)}, nil
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", provider)
 	}
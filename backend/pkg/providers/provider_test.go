@@ -0,0 +1,196 @@
+package providers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lightshare/backend/pkg/providers/lifx"
+)
+
+func TestSanitizeRawPayload(t *testing.T) {
+	raw := json.RawMessage(`{"id":"d073d5","label":"Kitchen","access_token":"secret","Token":"secret"}`)
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(sanitizeRawPayload(raw), &fields); err != nil {
+		t.Fatalf("Failed to decode sanitized payload: %v", err)
+	}
+
+	if _, ok := fields["access_token"]; ok {
+		t.Error("Expected access_token to be removed")
+	}
+	if _, ok := fields["Token"]; ok {
+		t.Error("Expected Token to be removed")
+	}
+	if fields["label"] != "Kitchen" {
+		t.Errorf("Expected label to be kept, got %v", fields["label"])
+	}
+}
+
+func TestSanitizeRawPayload_NotObject(t *testing.T) {
+	if got := sanitizeRawPayload(json.RawMessage(`[1,2,3]`)); got != nil {
+		t.Errorf("Expected nil for non-object payload, got %s", got)
+	}
+	if got := sanitizeRawPayload(nil); got != nil {
+		t.Errorf("Expected nil for empty payload, got %s", got)
+	}
+}
+
+func TestNewClient_HueEffectsNotImplemented(t *testing.T) {
+	client, err := NewClient(ProviderHue)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	err = client.Pulse("token", "all", nil, 3, 1.0)
+	if !errors.Is(err, ErrNotImplemented) {
+		t.Fatalf("Expected ErrNotImplemented, got %v", err)
+	}
+
+	var notImplErr *NotImplementedError
+	if !errors.As(err, &notImplErr) || notImplErr.Provider != ProviderHue || notImplErr.Operation != "pulse effect" {
+		t.Errorf("Expected NotImplementedError for hue pulse, got %v", err)
+	}
+}
+
+func TestNewClient_HueFlameAndMoveNotImplemented(t *testing.T) {
+	client, err := NewClient(ProviderHue)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if err := client.Flame("token", "all", 5, 0); !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("Expected Flame to return ErrNotImplemented, got %v", err)
+	}
+	if err := client.Move("token", "all", "forward", 1, 0); !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("Expected Move to return ErrNotImplemented, got %v", err)
+	}
+}
+
+func TestNewClient_WaveformNotImplementedOutsideLIFX(t *testing.T) {
+	for _, provider := range []Provider{ProviderHue, ProviderNanoleaf, ProviderWiZ, ProviderGovee, ProviderHomeAssistant} {
+		client, err := NewClient(provider)
+		if err != nil {
+			t.Fatalf("NewClient(%s) failed: %v", provider, err)
+		}
+
+		if err := client.Waveform("token", "all", WaveformParams{Period: 1, Cycles: 3}); !errors.Is(err, ErrNotImplemented) {
+			t.Errorf("Expected %s Waveform to return ErrNotImplemented, got %v", provider, err)
+		}
+	}
+}
+
+func TestNewClient_InfraredNotSupportedOutsideLIFX(t *testing.T) {
+	for _, provider := range []Provider{ProviderHue, ProviderNanoleaf, ProviderWiZ, ProviderGovee, ProviderHomeAssistant} {
+		client, err := NewClient(provider)
+		if err != nil {
+			t.Fatalf("NewClient(%s) failed: %v", provider, err)
+		}
+
+		if err := client.SetInfrared("token", "all", 0.5); !errors.Is(err, ErrCapabilityNotSupported) {
+			t.Errorf("Expected %s SetInfrared to return ErrCapabilityNotSupported, got %v", provider, err)
+		}
+	}
+}
+
+func TestNoEffects_ReturnsNotImplemented(t *testing.T) {
+	effects := NoEffects{Provider: ProviderHue}
+
+	if err := effects.Pulse("token", "all", nil, 3, 1.0); !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("Expected Pulse to return ErrNotImplemented, got %v", err)
+	}
+	if err := effects.Breathe("token", "all", nil, 3, 1.0); !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("Expected Breathe to return ErrNotImplemented, got %v", err)
+	}
+}
+
+func TestNewClient_WithLAN(t *testing.T) {
+	client, err := NewClient(ProviderLIFX, WithLAN(true))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if adapter, ok := client.(*lifxClientAdapter); !ok || adapter.lan == nil {
+		t.Error("Expected a LIFX client in LAN mode")
+	}
+
+	client, err = NewClient(ProviderLIFX)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if adapter, ok := client.(*lifxClientAdapter); !ok || adapter.lan != nil {
+		t.Error("Expected a cloud-only LIFX client by default")
+	}
+}
+
+func TestNewClient_WithEnabledProviders(t *testing.T) {
+	enabled := WithEnabledProviders([]string{"lifx"})
+
+	if _, err := NewClient(ProviderLIFX, enabled); err != nil {
+		t.Errorf("Expected an enabled provider to be created, got %v", err)
+	}
+	if _, err := NewClient(ProviderHue, enabled); !errors.Is(err, ErrProviderDisabled) {
+		t.Errorf("Expected ErrProviderDisabled, got %v", err)
+	}
+	if _, err := NewClient(ProviderHue, WithEnabledProviders(nil)); err != nil {
+		t.Errorf("Expected a nil list to enable every provider, got %v", err)
+	}
+}
+
+func TestLIFXAdapter_FallsBackToCloudWithoutLANDevice(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`[{"id":"d073d5000001","label":"Kitchen","power":"on","connected":true}]`))
+			return
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`{"results":[{"id":"d073d5000001","status":"ok"}]}`))
+	}))
+	defer server.Close()
+
+	// Nothing answers discovery on this address, so no device is reachable over the LAN
+	adapter := &lifxClientAdapter{
+		client: lifx.NewClientWithBaseURL(server.URL),
+		lan:    lifx.NewLANClientWithBroadcastAddr("127.0.0.1:9"),
+	}
+
+	devices, err := adapter.ListDevices("test-token")
+	if err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	if len(devices) != 1 || devices[0].Metadata["lan_ip"] != nil {
+		t.Errorf("Expected the cloud device without LAN metadata, got %+v", devices)
+	}
+
+	if err := adapter.SetPower("test-token", "id:d073d5000001", false, 0); err != nil {
+		t.Fatalf("SetPower failed: %v", err)
+	}
+	if len(paths) != 2 || paths[1] != "PUT /lights/id:d073d5000001/state" {
+		t.Errorf("Expected SetPower to go through the cloud, got %v", paths)
+	}
+}
+
+func TestLIFXAdapter_PartialSuccess(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`{"results":[{"id":"d073d5000001","status":"ok"},{"id":"d073d5000002","status":"offline"}]}`))
+	}))
+	defer server.Close()
+
+	adapter := &lifxClientAdapter{client: lifx.NewClientWithBaseURL(server.URL)}
+
+	err := adapter.SetBrightness("test-token", "group_id:g1", 0.5, 0)
+	var partialErr *PartialSuccessError
+	if !errors.As(err, &partialErr) || !errors.Is(err, ErrPartialSuccess) {
+		t.Fatalf("Expected PartialSuccessError, got %v", err)
+	}
+	if len(partialErr.Succeeded) != 1 || partialErr.Succeeded[0] != "d073d5000001" {
+		t.Errorf("Expected d073d5000001 to succeed, got %v", partialErr.Succeeded)
+	}
+	if len(partialErr.Failed) != 1 || partialErr.Failed[0] != "d073d5000002" {
+		t.Errorf("Expected d073d5000002 to fail, got %v", partialErr.Failed)
+	}
+}
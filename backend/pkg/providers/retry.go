@@ -0,0 +1,200 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// retryMaxAttempts is the total number of attempts made for a single call
+	retryMaxAttempts = 3
+	// retryBaseDelay is the delay before the first retry; it doubles with each attempt
+	retryBaseDelay = 100 * time.Millisecond
+	// retryMaxDelay caps the backoff delay, and is the longest Retry-After we wait out inline
+	retryMaxDelay = 2 * time.Second
+	// retryJitter is the fraction by which each backoff delay is randomly varied
+	retryJitter = 0.25
+)
+
+// RetryClient wraps a Client and retries device calls that failed transiently:
+// network timeouts, 5xx responses and 429 responses with a short Retry-After.
+// Token validation is passed through without retries.
+type RetryClient struct {
+	ctx    context.Context
+	client Client
+	sleep  func(time.Duration)
+}
+
+// NewRetryClient wraps client with exponential backoff retries
+func NewRetryClient(client Client) *RetryClient {
+	return &RetryClient{
+		ctx:    context.Background(),
+		client: client,
+		sleep:  time.Sleep,
+	}
+}
+
+// WithContext binds the wrapped client's requests to ctx, and stops retrying once ctx is done
+func (r *RetryClient) WithContext(ctx context.Context) Client {
+	return &RetryClient{ctx: ctx, client: WithContext(ctx, r.client), sleep: r.sleep}
+}
+
+// ValidateToken validates the token without retrying
+func (r *RetryClient) ValidateToken(token string) (*AccountInfo, error) {
+	return r.client.ValidateToken(token)
+}
+
+// GetAccountInfo retrieves account information without retrying
+func (r *RetryClient) GetAccountInfo(token string) (*AccountInfo, error) {
+	return r.client.GetAccountInfo(token)
+}
+
+// ListDevices returns all devices, retrying transient failures
+func (r *RetryClient) ListDevices(token string) ([]*Device, error) {
+	var devices []*Device
+	err := r.retry(func() (err error) {
+		devices, err = r.client.ListDevices(token)
+		return err
+	})
+	return devices, err
+}
+
+// GetDevice returns a specific device, retrying transient failures
+func (r *RetryClient) GetDevice(token, deviceID string) (*Device, error) {
+	var device *Device
+	err := r.retry(func() (err error) {
+		device, err = r.client.GetDevice(token, deviceID)
+		return err
+	})
+	return device, err
+}
+
+// SetPower turns device(s) on or off, retrying transient failures
+func (r *RetryClient) SetPower(token, selector string, state bool, duration float64) error {
+	return r.retry(func() error {
+		return r.client.SetPower(token, selector, state, duration)
+	})
+}
+
+// SetBrightness sets brightness, retrying transient failures
+func (r *RetryClient) SetBrightness(token, selector string, level, duration float64) error {
+	return r.retry(func() error {
+		return r.client.SetBrightness(token, selector, level, duration)
+	})
+}
+
+// SetColor sets the color, retrying transient failures
+func (r *RetryClient) SetColor(token, selector string, color *DeviceColor, duration float64) error {
+	return r.retry(func() error {
+		return r.client.SetColor(token, selector, color, duration)
+	})
+}
+
+// SetColorTemperature sets the white balance, retrying transient failures
+func (r *RetryClient) SetColorTemperature(token, selector string, kelvin int, duration float64) error {
+	return r.retry(func() error {
+		return r.client.SetColorTemperature(token, selector, kelvin, duration)
+	})
+}
+
+// SetInfrared sets the infrared level, retrying transient failures
+func (r *RetryClient) SetInfrared(token, selector string, level float64) error {
+	return r.retry(func() error {
+		return r.client.SetInfrared(token, selector, level)
+	})
+}
+
+// TogglePower toggles power without retrying, since a retried toggle that had already
+// been applied would undo itself
+func (r *RetryClient) TogglePower(token, selector string, duration float64) error {
+	return r.client.TogglePower(token, selector, duration)
+}
+
+// SetStates applies several states, retrying transient failures of the whole batch
+func (r *RetryClient) SetStates(token string, states []DeviceState) error {
+	return r.retry(func() error {
+		return r.client.SetStates(token, states)
+	})
+}
+
+// Pulse runs a pulse effect, retrying transient failures
+func (r *RetryClient) Pulse(token, selector string, color *DeviceColor, cycles int, period float64) error {
+	return r.retry(func() error {
+		return r.client.Pulse(token, selector, color, cycles, period)
+	})
+}
+
+// Breathe runs a breathe effect, retrying transient failures
+func (r *RetryClient) Breathe(token, selector string, color *DeviceColor, cycles int, period float64) error {
+	return r.retry(func() error {
+		return r.client.Breathe(token, selector, color, cycles, period)
+	})
+}
+
+// Flame runs a flame effect, retrying transient failures
+func (r *RetryClient) Flame(token, selector string, period, duration float64) error {
+	return r.retry(func() error {
+		return r.client.Flame(token, selector, period, duration)
+	})
+}
+
+// Move runs a move effect, retrying transient failures
+func (r *RetryClient) Move(token, selector, direction string, period, duration float64) error {
+	return r.retry(func() error {
+		return r.client.Move(token, selector, direction, period, duration)
+	})
+}
+
+// Waveform runs a waveform effect, retrying transient failures
+func (r *RetryClient) Waveform(token, selector string, params WaveformParams) error {
+	return r.retry(func() error {
+		return r.client.Waveform(token, selector, params)
+	})
+}
+
+// retry runs call up to retryMaxAttempts times, backing off between transient failures
+func (r *RetryClient) retry(call func() error) error {
+	delay := retryBaseDelay
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = call()
+		if err == nil || attempt == retryMaxAttempts || r.ctx.Err() != nil {
+			return err
+		}
+
+		wait, ok := retryDelay(err, delay)
+		if !ok {
+			return err
+		}
+		r.sleep(wait)
+
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// retryDelay reports whether err is transient and how long to wait before retrying it.
+// A provider's Retry-After is honored as-is; waits longer than retryMaxDelay are not
+// retried inline so callers can defer the action instead.
+func retryDelay(err error, backoff time.Duration) (time.Duration, bool) {
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr.RetryAfter, rateLimitErr.RetryAfter <= retryMaxDelay
+	}
+
+	var statusErr *StatusError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &statusErr) && statusErr.StatusCode >= http.StatusInternalServerError:
+	case errors.As(err, &netErr) && netErr.Timeout():
+	default:
+		return 0, false
+	}
+
+	jitter := 1 + retryJitter*(2*rand.Float64()-1)
+	return time.Duration(float64(backoff) * jitter), true
+}
@@ -0,0 +1,132 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyClient returns queued errors from SetPower; other methods are left unimplemented
+type flakyClient struct {
+	Client
+	errs  []error
+	calls int
+}
+
+func (f *flakyClient) SetPower(_, _ string, _ bool, _ float64) error {
+	f.calls++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+// newTestRetryClient wraps client, recording sleeps instead of waiting
+func newTestRetryClient(client Client) (*RetryClient, *[]time.Duration) {
+	var sleeps []time.Duration
+	retry := NewRetryClient(client)
+	retry.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	return retry, &sleeps
+}
+
+func TestRetryClient_RetriesServerErrors(t *testing.T) {
+	client := &flakyClient{errs: []error{
+		&StatusError{Provider: ProviderLIFX, StatusCode: 503},
+		&StatusError{Provider: ProviderLIFX, StatusCode: 502},
+	}}
+	retry, sleeps := newTestRetryClient(client)
+
+	if err := retry.SetPower("token", "all", true, 0); err != nil {
+		t.Fatalf("Expected success after retries, got %v", err)
+	}
+	if client.calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", client.calls)
+	}
+	if len(*sleeps) != 2 {
+		t.Fatalf("Expected 2 backoff sleeps, got %d", len(*sleeps))
+	}
+	if d := (*sleeps)[0]; d < 75*time.Millisecond || d > 125*time.Millisecond {
+		t.Errorf("Expected first backoff within 100ms ±25%%, got %s", d)
+	}
+	if d := (*sleeps)[1]; d < 150*time.Millisecond || d > 250*time.Millisecond {
+		t.Errorf("Expected second backoff within 200ms ±25%%, got %s", d)
+	}
+}
+
+func TestRetryClient_GivesUpAfterMaxAttempts(t *testing.T) {
+	client := &flakyClient{errs: []error{
+		&StatusError{Provider: ProviderHue, StatusCode: 500},
+		&StatusError{Provider: ProviderHue, StatusCode: 500},
+		&StatusError{Provider: ProviderHue, StatusCode: 500},
+		&StatusError{Provider: ProviderHue, StatusCode: 500},
+	}}
+	retry, _ := newTestRetryClient(client)
+
+	var statusErr *StatusError
+	if err := retry.SetPower("token", "all", true, 0); !errors.As(err, &statusErr) {
+		t.Fatalf("Expected StatusError, got %v", err)
+	}
+	if client.calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", client.calls)
+	}
+}
+
+func TestRetryClient_HonorsRetryAfter(t *testing.T) {
+	client := &flakyClient{errs: []error{
+		&RateLimitError{Provider: ProviderLIFX, RetryAfter: time.Second},
+	}}
+	retry, sleeps := newTestRetryClient(client)
+
+	if err := retry.SetPower("token", "all", true, 0); err != nil {
+		t.Fatalf("Expected success after retry, got %v", err)
+	}
+	if len(*sleeps) != 1 || (*sleeps)[0] != time.Second {
+		t.Errorf("Expected a single 1s sleep, got %v", *sleeps)
+	}
+}
+
+func TestRetryClient_DoesNotRetryPermanentErrors(t *testing.T) {
+	tests := []struct {
+		err  error
+		name string
+	}{
+		{name: "unauthorized", err: ErrUnauthorized},
+		{name: "client error", err: &StatusError{Provider: ProviderLIFX, StatusCode: 400}},
+		{name: "long retry-after", err: &RateLimitError{Provider: ProviderLIFX, RetryAfter: time.Minute}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &flakyClient{errs: []error{tt.err}}
+			retry, sleeps := newTestRetryClient(client)
+
+			if err := retry.SetPower("token", "all", true, 0); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+			if client.calls != 1 || len(*sleeps) != 0 {
+				t.Errorf("Expected a single call without sleeping, got %d calls and %v", client.calls, *sleeps)
+			}
+		})
+	}
+}
+
+func TestRetryClient_StopsRetryingWhenContextDone(t *testing.T) {
+	client := &flakyClient{errs: []error{
+		&StatusError{Provider: ProviderLIFX, StatusCode: 503},
+		&StatusError{Provider: ProviderLIFX, StatusCode: 503},
+	}}
+	retry, sleeps := newTestRetryClient(client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := retry.WithContext(ctx).SetPower("token", "all", true, 0); err == nil {
+		t.Fatal("Expected the first failure to be returned")
+	}
+	if client.calls != 1 || len(*sleeps) != 0 {
+		t.Errorf("Expected 1 call and no backoff once the context is done, got %d calls and %d sleeps", client.calls, len(*sleeps))
+	}
+}
@@ -0,0 +1,86 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DeviceState is a state to apply to the devices matched by Selector; unset fields are
+// left unchanged
+type DeviceState struct {
+	Power      *bool
+	Brightness *float64     // 0.0-1.0
+	Color      *DeviceColor // Hue and saturation; Kelvin is used when set
+	Selector   string
+	Kelvin     int     // White balance, applied when Color is not set (0 leaves it unchanged)
+	Duration   float64 // Transition time in seconds
+}
+
+// StatesError reports the states of a SetStates call that failed. Errors is indexed like
+// the states passed in, with nil for each state that was applied.
+type StatesError struct {
+	Errors []error
+}
+
+func (e *StatesError) Error() string {
+	failed := e.Unwrap()
+	if len(failed) == 0 {
+		return "no states failed"
+	}
+	return fmt.Sprintf("%d of %d states failed: %v", len(failed), len(e.Errors), failed[0])
+}
+
+// Unwrap returns the errors of the failed states
+func (e *StatesError) Unwrap() []error {
+	failed := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return failed
+}
+
+// SetStatesSequentially applies states one property call at a time, for providers without
+// a batch endpoint. Every state is attempted; failures are reported as a *StatesError.
+func SetStatesSequentially(client Client, token string, states []DeviceState) error {
+	errs := make([]error, len(states))
+	failed := false
+	for i, state := range states {
+		if err := setStateSequentially(client, token, state); err != nil {
+			errs[i] = err
+			failed = true
+		}
+	}
+
+	if failed {
+		return &StatesError{Errors: errs}
+	}
+	return nil
+}
+
+// setStateSequentially applies each property of a state, stopping at the first failure
+func setStateSequentially(client Client, token string, state DeviceState) error {
+	if state.Power != nil {
+		if err := client.SetPower(token, state.Selector, *state.Power, state.Duration); err != nil {
+			return err
+		}
+	}
+	if state.Brightness != nil {
+		if err := client.SetBrightness(token, state.Selector, *state.Brightness, state.Duration); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case state.Color != nil:
+		return client.SetColor(token, state.Selector, state.Color, state.Duration)
+	case state.Kelvin > 0:
+		return client.SetColorTemperature(token, state.Selector, state.Kelvin, state.Duration)
+	}
+
+	if state.Power == nil && state.Brightness == nil {
+		return errors.New("state sets no properties")
+	}
+	return nil
+}
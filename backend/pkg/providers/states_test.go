@@ -0,0 +1,49 @@
+package providers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetStatesSequentially_ReportsFailedStates(t *testing.T) {
+	client := newStateClient()
+	on, off := true, false
+
+	err := SetStatesSequentially(client, "token", []DeviceState{
+		{Selector: "id:bulb-1", Power: &off},
+		{Selector: "id:bulb-2"},
+		{Selector: "id:bulb-3", Power: &on},
+	})
+
+	var statesErr *StatesError
+	if !errors.As(err, &statesErr) {
+		t.Fatalf("Expected StatesError, got %v", err)
+	}
+	if len(statesErr.Errors) != 3 || statesErr.Errors[0] != nil || statesErr.Errors[1] == nil || statesErr.Errors[2] != nil {
+		t.Errorf("Expected only the empty state to fail, got %v", statesErr.Errors)
+	}
+	if len(client.powered) != 2 {
+		t.Errorf("Expected the other states to be applied, got %v", client.powered)
+	}
+}
+
+func TestConvertLIFXState(t *testing.T) {
+	on := true
+	state := convertLIFXState(DeviceState{
+		Selector: "id:d073d5",
+		Power:    &on,
+		Color:    &DeviceColor{Hue: 120, Saturation: 1},
+		Duration: 2,
+	})
+
+	if state.Power != "on" || state.Color != "hue:120.000000 saturation:1.000000" || state.Duration != 2 {
+		t.Errorf("Unexpected LIFX state: %+v", state)
+	}
+	if state.Brightness != nil {
+		t.Errorf("Expected brightness to be left unchanged, got %v", *state.Brightness)
+	}
+
+	if white := convertLIFXState(DeviceState{Selector: "all", Kelvin: 2700}); white.Color != "kelvin:2700" {
+		t.Errorf("Expected a kelvin color, got %q", white.Color)
+	}
+}
@@ -0,0 +1,57 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TogglePowerState toggles power for providers without a native toggle: it reads the
+// current power of the selected device, then sets the opposite state. Selectors that
+// target several devices are toggled based on the state of the first matching device;
+// a majority vote across the selection is a possible future improvement.
+func TogglePowerState(client Client, token, selector string, duration float64) error {
+	device, err := firstSelectedDevice(client, token, selector)
+	if err != nil {
+		return err
+	}
+
+	return client.SetPower(token, selector, device.Power != "on", duration)
+}
+
+// firstSelectedDevice returns the first device a selector targets
+func firstSelectedDevice(client Client, token, selector string) (*Device, error) {
+	if deviceID, ok := strings.CutPrefix(selector, "id:"); ok {
+		return client.GetDevice(token, deviceID)
+	}
+
+	devices, err := client.ListDevices(token)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, device := range devices {
+		matches, err := selectorMatches(selector, device)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			return device, nil
+		}
+	}
+
+	return nil, fmt.Errorf("selector not found: %s", selector)
+}
+
+// selectorMatches reports whether a selector targets a device
+func selectorMatches(selector string, device *Device) (bool, error) {
+	switch {
+	case selector == "all":
+		return true, nil
+	case strings.HasPrefix(selector, "group_id:"):
+		return device.Group != nil && device.Group.ID == strings.TrimPrefix(selector, "group_id:"), nil
+	case strings.HasPrefix(selector, "location_id:"):
+		return device.Location != nil && device.Location.ID == strings.TrimPrefix(selector, "location_id:"), nil
+	default:
+		return false, fmt.Errorf("unsupported selector for toggle: %s", selector)
+	}
+}
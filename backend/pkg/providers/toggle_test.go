@@ -0,0 +1,82 @@
+package providers
+
+import (
+	"testing"
+)
+
+// stateClient serves a fixed device list and records the power states it is asked to set;
+// other methods are left unimplemented
+type stateClient struct {
+	Client
+	devices []*Device
+	powered []bool
+}
+
+func (s *stateClient) ListDevices(_ string) ([]*Device, error) {
+	return s.devices, nil
+}
+
+func (s *stateClient) GetDevice(_, deviceID string) (*Device, error) {
+	for _, device := range s.devices {
+		if device.ID == deviceID {
+			return device, nil
+		}
+	}
+	return nil, &StatusError{Provider: ProviderHue, StatusCode: 404}
+}
+
+func (s *stateClient) SetPower(_, _ string, state bool, _ float64) error {
+	s.powered = append(s.powered, state)
+	return nil
+}
+
+func newStateClient() *stateClient {
+	kitchen := &DeviceGroup{ID: "grp-kitchen", Name: "Kitchen"}
+	return &stateClient{devices: []*Device{
+		{ID: "bulb-1", Power: "on", Group: &DeviceGroup{ID: "grp-bedroom", Name: "Bedroom"}},
+		{ID: "bulb-2", Power: "off", Group: kitchen},
+		{ID: "bulb-3", Power: "on", Group: kitchen},
+	}}
+}
+
+func TestTogglePowerState_InvertsDevicePower(t *testing.T) {
+	client := newStateClient()
+
+	if err := TogglePowerState(client, "token", "id:bulb-1", 0.5); err != nil {
+		t.Fatalf("TogglePowerState failed: %v", err)
+	}
+	if err := TogglePowerState(client, "token", "id:bulb-2", 0.5); err != nil {
+		t.Fatalf("TogglePowerState failed: %v", err)
+	}
+
+	if len(client.powered) != 2 || client.powered[0] || !client.powered[1] {
+		t.Errorf("Expected bulb-1 off and bulb-2 on, got %v", client.powered)
+	}
+}
+
+func TestTogglePowerState_GroupFollowsFirstDevice(t *testing.T) {
+	client := newStateClient()
+
+	if err := TogglePowerState(client, "token", "group_id:grp-kitchen", 0.5); err != nil {
+		t.Fatalf("TogglePowerState failed: %v", err)
+	}
+
+	// bulb-2 is the first kitchen device and is off, so the group is turned on
+	if len(client.powered) != 1 || !client.powered[0] {
+		t.Errorf("Expected the kitchen to be turned on, got %v", client.powered)
+	}
+}
+
+func TestTogglePowerState_UnknownSelector(t *testing.T) {
+	client := newStateClient()
+
+	if err := TogglePowerState(client, "token", "group_id:grp-garage", 0.5); err == nil {
+		t.Error("Expected an error for a selector matching no device")
+	}
+	if err := TogglePowerState(client, "token", "label:Kitchen", 0.5); err == nil {
+		t.Error("Expected an error for an unsupported selector")
+	}
+	if len(client.powered) != 0 {
+		t.Errorf("Expected no power change, got %v", client.powered)
+	}
+}
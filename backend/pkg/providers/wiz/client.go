@@ -0,0 +1,499 @@
+// Package wiz provides a client for the WiZ (Signify) cloud API
+package wiz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lightshare/backend/pkg/colorconv"
+)
+
+const (
+	wizAPIBaseURL  = "https://api.eu.wiz.com"
+	requestTimeout = 10 * time.Second
+
+	// Color temperature range supported by WiZ lights
+	minKelvin = 2200
+	maxKelvin = 6500
+	// minDimming is the lowest brightness percentage WiZ lights accept
+	minDimming = 10
+	// fireplaceSceneID is the built-in scene used for the flame effect
+	fireplaceSceneID = 5
+)
+
+// sceneNames maps the IDs of WiZ's built-in scenes to effect names
+var sceneNames = map[int]string{
+	1: "ocean", 2: "romance", 3: "sunset", 4: "party", 5: "fireplace", 6: "cozy",
+	7: "forest", 8: "pastel_colors", 9: "wake_up", 10: "bedtime", 11: "warm_white",
+	12: "daylight", 13: "cool_white", 14: "night_light", 15: "focus", 16: "relax",
+	17: "true_colors", 18: "tv_time", 19: "plant_growth", 20: "spring", 21: "summer",
+	22: "fall", 23: "deep_dive", 24: "jungle", 25: "mojito", 26: "club", 27: "christmas",
+	28: "halloween", 29: "candlelight", 30: "golden_white", 31: "pulse", 32: "steampunk",
+}
+
+// AccountInfo contains information about a WiZ home
+type AccountInfo struct {
+	// Additional metadata
+	Metadata map[string]interface{}
+	// ProviderAccountID is the ID of the home the token belongs to
+	ProviderAccountID string
+	// Label or name for the home
+	Label string
+}
+
+// LoginResult is the home and access token a WiZ login returns
+type LoginResult struct {
+	HomeID      string
+	AccessToken string
+}
+
+// Client talks to the WiZ cloud API using the access token of a home
+type Client struct {
+	ctx        context.Context
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewClient creates a new WiZ client whose requests time out after timeout,
+// or after 10s when timeout is 0
+func NewClient(timeout time.Duration) *Client {
+	client := NewClientWithBaseURL(wizAPIBaseURL)
+	if timeout > 0 {
+		client.httpClient.Timeout = timeout
+	}
+	return client
+}
+
+// NewClientWithBaseURL creates a new WiZ client targeting a custom API base URL
+// This is primarily useful for pointing the client at a mock server in tests
+func NewClientWithBaseURL(baseURL string) *Client {
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: requestTimeout,
+		},
+		ctx:     context.Background(),
+		baseURL: baseURL,
+	}
+}
+
+// WithContext returns a copy of the client whose requests carry ctx, so they are
+// cancelled with it
+func (c *Client) WithContext(ctx context.Context) *Client {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// Device represents a WiZ light
+type Device struct {
+	Color        *DeviceColor
+	Group        *DeviceGroup
+	Location     *DeviceLocation
+	Metadata     map[string]interface{}
+	Raw          json.RawMessage // Original WiZ pilot JSON for this light
+	ID           string
+	Label        string
+	Power        string
+	Capabilities []string
+	Brightness   float64
+	Connected    bool
+	Reachable    bool
+}
+
+// DeviceColor represents color information
+type DeviceColor struct {
+	Hue        float64 // 0-360
+	Saturation float64 // 0.0-1.0
+	Kelvin     int     // 2200-6500
+}
+
+// DeviceGroup represents a WiZ room
+type DeviceGroup struct {
+	ID   string
+	Name string
+}
+
+// DeviceLocation represents the WiZ home
+type DeviceLocation struct {
+	ID   string
+	Name string
+}
+
+// apiResponse is the envelope of every WiZ API response
+type apiResponse struct {
+	Data      json.RawMessage `json:"data"`
+	Message   string          `json:"message"`
+	ErrorCode int             `json:"errorCode"`
+	Success   bool            `json:"success"`
+}
+
+// homeConfig is the subset of the home configuration the client uses
+type homeConfig struct {
+	HomeName string       `json:"homeName"`
+	Rooms    []room       `json:"rooms"`
+	Devices  []homeDevice `json:"devices"`
+	HomeID   int64        `json:"homeId"`
+}
+
+// room is a room of the home
+type room struct {
+	Name string `json:"name"`
+	ID   int64  `json:"id"`
+}
+
+// homeDevice is a light registered in the home
+type homeDevice struct {
+	MAC        string `json:"mac"`
+	Name       string `json:"name"`
+	ModuleName string `json:"moduleName"` // Hardware module, e.g. "ESP01_SHRGB1C_31"
+	RoomID     int64  `json:"roomId"`
+}
+
+// pilot is the current state of a light
+type pilot struct {
+	MAC     string `json:"mac"`
+	SceneID int    `json:"sceneId"`
+	R       int    `json:"r"`
+	G       int    `json:"g"`
+	B       int    `json:"b"`
+	Temp    int    `json:"temp"`
+	Dimming int    `json:"dimming"`
+	State   bool   `json:"state"`
+}
+
+// Login exchanges the email and password of a WiZ account for the ID and access token of
+// its home
+func (c *Client) Login(email, password string) (*LoginResult, error) {
+	body, err := json.Marshal(map[string]string{"email": email, "password": password})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	data, err := c.do("", http.MethodPost, "/user-registration/login", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		AccessToken string `json:"accessToken"`
+		HomeID      int64  `json:"homeId"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if result.AccessToken == "" {
+		return nil, ErrUnauthorized
+	}
+
+	return &LoginResult{HomeID: strconv.FormatInt(result.HomeID, 10), AccessToken: result.AccessToken}, nil
+}
+
+// ValidateToken validates the token by reading the home configuration
+// The home ID is used as the account identifier since WiZ tokens are per home
+func (c *Client) ValidateToken(token string) (*AccountInfo, error) {
+	config, err := c.getConfig(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountInfo{
+		ProviderAccountID: strconv.FormatInt(config.HomeID, 10),
+		Label:             config.HomeName,
+		Metadata: map[string]interface{}{
+			"rooms_count":  len(config.Rooms),
+			"lights_count": len(config.Devices),
+		},
+	}, nil
+}
+
+// GetAccountInfo retrieves information about the home
+// For WiZ, this is the same as ValidateToken
+func (c *Client) GetAccountInfo(token string) (*AccountInfo, error) {
+	return c.ValidateToken(token)
+}
+
+// ListDevices returns every light in the home with its current state. Lights whose state
+// cannot be read are listed as unreachable.
+func (c *Client) ListDevices(token string) ([]*Device, error) {
+	config, err := c.getConfig(token)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]*Device, 0, len(config.Devices))
+	for _, d := range config.Devices {
+		device, err := c.getDevice(token, config, d)
+		if err != nil {
+			if errors.Is(err, ErrUnauthorized) {
+				return nil, err
+			}
+			device = convertDevice(config, d, nil, nil)
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// GetDevice returns a specific light by its MAC address
+func (c *Client) GetDevice(token, deviceID string) (*Device, error) {
+	config, err := c.getConfig(token)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range config.Devices {
+		if strings.EqualFold(d.MAC, deviceID) {
+			return c.getDevice(token, config, d)
+		}
+	}
+	return nil, fmt.Errorf("device not found: %s", deviceID)
+}
+
+// getDevice reads the pilot of a light and converts it to a Device
+func (c *Client) getDevice(token string, config *homeConfig, d homeDevice) (*Device, error) {
+	data, err := c.do(token, http.MethodGet, "/get-pilot?mac="+url.QueryEscape(d.MAC), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var state pilot
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return convertDevice(config, d, &state, data), nil
+}
+
+// convertDevice converts a light and its pilot to the Device type. A nil pilot marks the
+// light unreachable. Capabilities are inferred from the hardware module: RGB modules
+// support color and temperature, tunable white ("TW") modules temperature only.
+func convertDevice(config *homeConfig, d homeDevice, state *pilot, raw json.RawMessage) *Device {
+	device := &Device{
+		ID:           d.MAC,
+		Label:        d.Name,
+		Power:        "off",
+		Capabilities: []string{"brightness"},
+		Location:     &DeviceLocation{ID: strconv.FormatInt(config.HomeID, 10), Name: config.HomeName},
+		Metadata: map[string]interface{}{
+			"module_name": d.ModuleName,
+		},
+		Raw: raw,
+	}
+
+	module := strings.ToUpper(d.ModuleName)
+	switch {
+	case strings.Contains(module, "RGB"):
+		device.Capabilities = append(device.Capabilities, "color", "temperature", "effects")
+	case strings.Contains(module, "TW"):
+		device.Capabilities = append(device.Capabilities, "temperature", "effects")
+	}
+
+	for _, r := range config.Rooms {
+		if r.ID == d.RoomID {
+			device.Group = &DeviceGroup{ID: strconv.FormatInt(r.ID, 10), Name: r.Name}
+			break
+		}
+	}
+
+	if state == nil {
+		return device
+	}
+
+	device.Connected = true
+	device.Reachable = true
+	device.Brightness = float64(state.Dimming) / 100
+	if state.State {
+		device.Power = "on"
+	}
+
+	switch {
+	case state.Temp > 0:
+		device.Color = &DeviceColor{Kelvin: state.Temp}
+	case state.R > 0 || state.G > 0 || state.B > 0:
+		hue, saturation := colorconv.ChannelsToHueSaturation(state.R, state.G, state.B)
+		device.Color = &DeviceColor{Hue: hue, Saturation: saturation}
+	}
+
+	if name, ok := sceneNames[state.SceneID]; ok {
+		device.Metadata["current_effect"] = name
+	}
+
+	return device
+}
+
+// SetPower turns light(s) on or off
+// WiZ switches power instantly, so duration is ignored
+func (c *Client) SetPower(token, selector string, state bool, _ float64) error {
+	return c.setPilot(token, selector, map[string]interface{}{"state": state})
+}
+
+// SetBrightness adjusts brightness (0.0-1.0), raised to the 10% minimum WiZ lights accept
+// WiZ changes brightness instantly, so duration is ignored
+func (c *Client) SetBrightness(token, selector string, level, _ float64) error {
+	dimming := max(minDimming, min(100, int(math.Round(level*100))))
+	return c.setPilot(token, selector, map[string]interface{}{"dimming": dimming})
+}
+
+// SetColor sets the hue and saturation, sent to WiZ as RGB
+// WiZ changes color instantly, so duration is ignored
+func (c *Client) SetColor(token, selector string, color *DeviceColor, _ float64) error {
+	r, g, b := colorconv.HueSaturationToChannels(color.Hue, color.Saturation)
+	return c.setPilot(token, selector, map[string]interface{}{"r": r, "g": g, "b": b})
+}
+
+// SetColorTemperature sets the white balance, clamped to the lights' 2200-6500K range
+// WiZ changes color temperature instantly, so duration is ignored
+func (c *Client) SetColorTemperature(token, selector string, kelvin int, _ float64) error {
+	kelvin = max(minKelvin, min(maxKelvin, kelvin))
+	return c.setPilot(token, selector, map[string]interface{}{"temp": kelvin})
+}
+
+// Pulse is not supported by WiZ
+func (c *Client) Pulse(_, _ string, _ *DeviceColor, _ int, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "pulse effect"}
+}
+
+// Breathe is not supported by WiZ
+func (c *Client) Breathe(_, _ string, _ *DeviceColor, _ int, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "breathe effect"}
+}
+
+// Flame plays WiZ's built-in fireplace scene, which runs until the lights are next changed
+// WiZ scenes have no cycle period or duration, so both are ignored
+func (c *Client) Flame(token, selector string, _, _ float64) error {
+	return c.setPilot(token, selector, map[string]interface{}{"sceneId": fireplaceSceneID})
+}
+
+// Move is not supported by WiZ
+func (c *Client) Move(_, _, _ string, _, _ float64) error {
+	return &CapabilityNotSupportedError{Capability: "move effect"}
+}
+
+// setPilot applies params to every light a selector targets, one request per light
+func (c *Client) setPilot(token, selector string, params map[string]interface{}) error {
+	macs, err := c.resolveSelector(token, selector)
+	if err != nil {
+		return err
+	}
+
+	for _, mac := range macs {
+		body, err := json.Marshal(map[string]interface{}{"mac": mac, "params": params})
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		if _, err := c.do(token, http.MethodPost, "/set-pilot", body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveSelector maps a LightShare selector to the MAC addresses of the lights it targets
+// "id:" takes a MAC address, "group_id:" a room ID and "location_id:" the home ID
+func (c *Client) resolveSelector(token, selector string) ([]string, error) {
+	if mac, ok := strings.CutPrefix(selector, "id:"); ok {
+		return []string{mac}, nil
+	}
+
+	config, err := c.getConfig(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var match func(d homeDevice) bool
+	switch {
+	case selector == "all":
+		match = func(homeDevice) bool { return true }
+	case strings.HasPrefix(selector, "group_id:"):
+		roomID := strings.TrimPrefix(selector, "group_id:")
+		match = func(d homeDevice) bool { return strconv.FormatInt(d.RoomID, 10) == roomID }
+	case strings.HasPrefix(selector, "location_id:"):
+		homeID := strings.TrimPrefix(selector, "location_id:")
+		match = func(homeDevice) bool { return strconv.FormatInt(config.HomeID, 10) == homeID }
+	default:
+		return nil, fmt.Errorf("unsupported selector: %s", selector)
+	}
+
+	var macs []string
+	for _, d := range config.Devices {
+		if match(d) {
+			macs = append(macs, d.MAC)
+		}
+	}
+	if len(macs) == 0 {
+		return nil, fmt.Errorf("selector not found: %s", selector)
+	}
+	return macs, nil
+}
+
+// getConfig fetches the configuration of the home the token belongs to
+func (c *Client) getConfig(token string) (*homeConfig, error) {
+	data, err := c.do(token, http.MethodGet, "/get-wizc-config", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var config homeConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &config, nil
+}
+
+// do performs a WiZ API request and returns the data of the response envelope. Requests
+// without a token are sent unauthenticated.
+func (c *Client) do(token, method, path string, body []byte) (json.RawMessage, error) {
+	var reqBody io.Reader = http.NoBody
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call WiZ API: %w", err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			_ = closeErr
+		}
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ErrUnauthorized
+	case http.StatusOK:
+	default:
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	var envelope apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !envelope.Success {
+		return nil, &APIError{Code: envelope.ErrorCode, Message: envelope.Message}
+	}
+
+	return envelope.Data, nil
+}
@@ -0,0 +1,281 @@
+package wiz
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testHomeConfig is the home configuration served by the mock WiZ API
+const testHomeConfig = `{
+	"homeId": 41872,
+	"homeName": "Apartment",
+	"rooms": [{"id": 7, "name": "Bedroom"}, {"id": 8, "name": "Office"}],
+	"devices": [
+		{"mac": "a8bb50e1c2d3", "name": "Bedside", "roomId": 7, "moduleName": "ESP01_SHRGB1C_31"},
+		{"mac": "a8bb50e1c2d4", "name": "Ceiling", "roomId": 7, "moduleName": "ESP05_SHTW_21"},
+		{"mac": "a8bb50e1c2d5", "name": "Desk", "roomId": 8, "moduleName": "ESP06_SHDW9_01"}
+	]
+}`
+
+// testPilots are the states of the lights served by the mock WiZ API; Desk is unreachable
+var testPilots = map[string]string{
+	"a8bb50e1c2d3": `{"mac": "a8bb50e1c2d3", "state": true, "sceneId": 0, "r": 0, "g": 0, "b": 255, "dimming": 80}`,
+	"a8bb50e1c2d4": `{"mac": "a8bb50e1c2d4", "state": false, "sceneId": 5, "temp": 2700, "dimming": 40}`,
+}
+
+// setPilotRequest captures a change sent to the mock WiZ API
+type setPilotRequest struct {
+	Params map[string]interface{} `json:"params"`
+	MAC    string                 `json:"mac"`
+}
+
+// newTestServer returns a client for a mock WiZ API accepting the access token "test-token"
+func newTestServer(t *testing.T) (*Client, *[]setPilotRequest) {
+	t.Helper()
+	var changes []setPilotRequest
+
+	respond := func(w http.ResponseWriter, data string) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"success": true, "data": ` + data + `}`))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/user-registration/login" {
+			var creds map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&creds)
+			if creds["email"] != "user@example.com" || creds["password"] != "hunter2" {
+				_, _ = w.Write([]byte(`{"success": false, "errorCode": 4001, "message": "invalid credentials"}`))
+				return
+			}
+			respond(w, `{"homeId": 41872, "accessToken": "test-token"}`)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/get-wizc-config":
+			respond(w, testHomeConfig)
+		case r.Method == http.MethodGet && r.URL.Path == "/get-pilot":
+			pilot, ok := testPilots[r.URL.Query().Get("mac")]
+			if !ok {
+				_, _ = w.Write([]byte(`{"success": false, "errorCode": 3004, "message": "device offline"}`))
+				return
+			}
+			respond(w, pilot)
+		case r.Method == http.MethodPost && r.URL.Path == "/set-pilot":
+			data, _ := io.ReadAll(r.Body)
+			var change setPilotRequest
+			_ = json.Unmarshal(data, &change)
+			changes = append(changes, change)
+			respond(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return NewClientWithBaseURL(server.URL), &changes
+}
+
+func TestLogin(t *testing.T) {
+	client, _ := newTestServer(t)
+
+	result, err := client.Login("user@example.com", "hunter2")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if result.HomeID != "41872" || result.AccessToken != "test-token" {
+		t.Errorf("Unexpected login result: %+v", result)
+	}
+
+	_, err = client.Login("user@example.com", "wrong")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 4001 {
+		t.Errorf("Expected APIError 4001, got %v", err)
+	}
+}
+
+func TestValidateToken(t *testing.T) {
+	client, _ := newTestServer(t)
+
+	info, err := client.ValidateToken("test-token")
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	if info.ProviderAccountID != "41872" || info.Label != "Apartment" {
+		t.Errorf("Unexpected account info: %+v", info)
+	}
+	if info.Metadata["lights_count"] != 3 {
+		t.Errorf("Expected 3 lights, got %v", info.Metadata["lights_count"])
+	}
+
+	if _, err := client.ValidateToken("wrong-token"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestListDevices(t *testing.T) {
+	client, _ := newTestServer(t)
+
+	devices, err := client.ListDevices("test-token")
+	if err != nil {
+		t.Fatalf("ListDevices failed: %v", err)
+	}
+	if len(devices) != 3 {
+		t.Fatalf("Expected 3 devices, got %d", len(devices))
+	}
+
+	testCases := []struct {
+		wantColor        *DeviceColor
+		wantEffect       interface{}
+		name             string
+		wantPower        string
+		wantCapabilities int
+		wantBrightness   float64
+		wantReachable    bool
+	}{
+		{
+			name:             "RGB light",
+			wantPower:        "on",
+			wantBrightness:   0.8,
+			wantColor:        &DeviceColor{Hue: 240, Saturation: 1},
+			wantCapabilities: 4,
+			wantReachable:    true,
+		},
+		{
+			name:             "tunable white light playing a scene",
+			wantPower:        "off",
+			wantBrightness:   0.4,
+			wantColor:        &DeviceColor{Kelvin: 2700},
+			wantEffect:       "fireplace",
+			wantCapabilities: 3,
+			wantReachable:    true,
+		},
+		{
+			name:             "unreachable dimmable light",
+			wantPower:        "off",
+			wantCapabilities: 1,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			device := devices[i]
+			if device.Power != tc.wantPower || device.Brightness != tc.wantBrightness || device.Reachable != tc.wantReachable {
+				t.Errorf("Unexpected device state: %+v", device)
+			}
+			if (tc.wantColor == nil) != (device.Color == nil) || (tc.wantColor != nil && *tc.wantColor != *device.Color) {
+				t.Errorf("Expected color %+v, got %+v", tc.wantColor, device.Color)
+			}
+			if device.Metadata["current_effect"] != tc.wantEffect {
+				t.Errorf("Expected effect %v, got %v", tc.wantEffect, device.Metadata["current_effect"])
+			}
+			if len(device.Capabilities) != tc.wantCapabilities {
+				t.Errorf("Expected %d capabilities, got %v", tc.wantCapabilities, device.Capabilities)
+			}
+			if device.Group == nil || device.Location == nil || device.Location.ID != "41872" {
+				t.Errorf("Expected room and home, got %+v and %+v", device.Group, device.Location)
+			}
+		})
+	}
+}
+
+func TestSetPilot(t *testing.T) {
+	testCases := []struct {
+		call       func(c *Client) error
+		wantParams map[string]interface{}
+		name       string
+		wantMACs   []string
+	}{
+		{
+			name:       "power on a single light",
+			call:       func(c *Client) error { return c.SetPower("test-token", "id:a8bb50e1c2d3", true, 1) },
+			wantMACs:   []string{"a8bb50e1c2d3"},
+			wantParams: map[string]interface{}{"state": true},
+		},
+		{
+			name:       "brightness of a room, raised to the minimum",
+			call:       func(c *Client) error { return c.SetBrightness("test-token", "group_id:7", 0.02, 0) },
+			wantMACs:   []string{"a8bb50e1c2d3", "a8bb50e1c2d4"},
+			wantParams: map[string]interface{}{"dimming": 10.0},
+		},
+		{
+			name: "color as RGB",
+			call: func(c *Client) error {
+				return c.SetColor("test-token", "id:a8bb50e1c2d3", &DeviceColor{Hue: 120, Saturation: 1}, 0)
+			},
+			wantMACs:   []string{"a8bb50e1c2d3"},
+			wantParams: map[string]interface{}{"r": 0.0, "g": 255.0, "b": 0.0},
+		},
+		{
+			name:       "temperature clamped across the home",
+			call:       func(c *Client) error { return c.SetColorTemperature("test-token", "all", 9000, 0) },
+			wantMACs:   []string{"a8bb50e1c2d3", "a8bb50e1c2d4", "a8bb50e1c2d5"},
+			wantParams: map[string]interface{}{"temp": 6500.0},
+		},
+		{
+			name:       "flame plays the fireplace scene",
+			call:       func(c *Client) error { return c.Flame("test-token", "location_id:41872", 1, 0) },
+			wantMACs:   []string{"a8bb50e1c2d3", "a8bb50e1c2d4", "a8bb50e1c2d5"},
+			wantParams: map[string]interface{}{"sceneId": 5.0},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, changes := newTestServer(t)
+
+			if err := tc.call(client); err != nil {
+				t.Fatalf("Call failed: %v", err)
+			}
+
+			if len(*changes) != len(tc.wantMACs) {
+				t.Fatalf("Expected %d pilot changes, got %+v", len(tc.wantMACs), *changes)
+			}
+			for i, change := range *changes {
+				if change.MAC != tc.wantMACs[i] {
+					t.Errorf("Expected change to %s, got %s", tc.wantMACs[i], change.MAC)
+				}
+				for key, want := range tc.wantParams {
+					if change.Params[key] != want {
+						t.Errorf("Expected %s=%v, got %v", key, want, change.Params[key])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSetPilot_UnknownSelector(t *testing.T) {
+	client, _ := newTestServer(t)
+
+	if err := client.SetPower("test-token", "group_id:99", true, 0); err == nil {
+		t.Error("Expected error for a room without lights")
+	}
+	if err := client.SetPower("test-token", "label:Desk", true, 0); err == nil {
+		t.Error("Expected error for an unsupported selector")
+	}
+}
+
+func TestEffects_NotSupported(t *testing.T) {
+	client := NewClient(0)
+
+	testCases := map[string]error{
+		"pulse":   client.Pulse("test-token", "all", nil, 3, 1),
+		"breathe": client.Breathe("test-token", "all", nil, 3, 1),
+		"move":    client.Move("test-token", "all", "forward", 1, 0),
+	}
+	for effect, err := range testCases {
+		if !errors.Is(err, ErrCapabilityNotSupported) {
+			t.Errorf("Expected %s to return ErrCapabilityNotSupported, got %v", effect, err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Package ratelimit provides a Redis-backed sliding-window rate limiter.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// defaultWindow is the sliding window over which the per-minute limit applies
+	defaultWindow = time.Minute
+	// burstWindow is the span over which the burst limit applies
+	burstWindow = time.Second
+)
+
+// Limit describes how many requests are allowed
+type Limit struct {
+	PerMinute int // Requests allowed in any sliding 60-second window
+	Burst     int // Requests allowed in any one-second span (0 disables the burst check)
+}
+
+// Result is the outcome of a rate-limit check
+type Result struct {
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Allowed    bool
+}
+
+// allowScript atomically trims the window, checks both limits and records the request.
+// Scores are Unix milliseconds. Returns {allowed, count, retry_after_ms}.
+var allowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local burst = tonumber(ARGV[4])
+local burst_window = tonumber(ARGV[5])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+if count >= limit then
+	local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+	return {0, count, tonumber(oldest[2]) + window - now}
+end
+
+if burst > 0 then
+	local recent = redis.call('ZRANGEBYSCORE', key, '(' .. (now - burst_window), '+inf', 'WITHSCORES')
+	if #recent / 2 >= burst then
+		return {0, count, tonumber(recent[2]) + burst_window - now}
+	end
+end
+
+redis.call('ZADD', key, now, ARGV[6])
+redis.call('PEXPIRE', key, window)
+return {1, count + 1, 0}
+`)
+
+// LoadScripts loads the limiter's Lua script into Redis so the first Allow doesn't
+// pay for the script upload. Allow falls back to EVAL if the script cache is flushed.
+func LoadScripts(ctx context.Context, client *redis.Client) error {
+	if err := allowScript.Load(ctx, client).Err(); err != nil {
+		return fmt.Errorf("failed to load rate limit script: %w", err)
+	}
+	return nil
+}
+
+// Limiter enforces sliding-window limits using Redis sorted sets
+type Limiter struct {
+	client *redis.Client
+	now    func() time.Time
+	window time.Duration
+}
+
+// New creates a new sliding-window limiter
+func New(client *redis.Client) *Limiter {
+	return &Limiter{
+		client: client,
+		now:    time.Now,
+		window: defaultWindow,
+	}
+}
+
+// Allow records a request against key if it fits within limit
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	now := l.now().UnixMilli()
+
+	values, err := allowScript.Run(ctx, l.client, []string{key},
+		now,
+		l.window.Milliseconds(),
+		limit.PerMinute,
+		limit.Burst,
+		burstWindow.Milliseconds(),
+		uuid.NewString(),
+	).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+
+	return &Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit.PerMinute,
+		Remaining:  remaining(limit.PerMinute, values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
+
+// Peek reports the current budget for key without recording a request
+func (l *Limiter) Peek(ctx context.Context, key string, limit Limit) (*Result, error) {
+	windowStart := l.now().Add(-l.window).UnixMilli()
+
+	count, err := l.client.ZCount(ctx, key, fmt.Sprintf("(%d", windowStart), "+inf").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate limit: %w", err)
+	}
+
+	return &Result{
+		Allowed:   count < int64(limit.PerMinute),
+		Limit:     limit.PerMinute,
+		Remaining: remaining(limit.PerMinute, count),
+	}, nil
+}
+
+func remaining(limit int, count int64) int {
+	if left := int64(limit) - count; left > 0 {
+		return int(left)
+	}
+	return 0
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestLimiter returns a limiter backed by miniredis with a controllable clock
+func newTestLimiter(t *testing.T) (*Limiter, *time.Time) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := New(client)
+	limiter.now = func() time.Time { return now }
+
+	return limiter, &now
+}
+
+func TestLimiter_SlidingWindow(t *testing.T) {
+	limiter, now := newTestLimiter(t)
+	ctx := context.Background()
+	limit := Limit{PerMinute: 3}
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(ctx, "key", limit)
+		if err != nil {
+			t.Fatalf("Allow failed: %v", err)
+		}
+		if !result.Allowed {
+			t.Fatalf("Request %d should be allowed", i+1)
+		}
+		*now = now.Add(10 * time.Second)
+	}
+
+	result, err := limiter.Allow(ctx, "key", limit)
+	if err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	if result.Allowed {
+		t.Fatal("Fourth request within the window should be rejected")
+	}
+	if result.RetryAfter != 30*time.Second {
+		t.Errorf("Expected RetryAfter 30s, got %s", result.RetryAfter)
+	}
+
+	// Once the first request slides out of the window, one slot frees up
+	*now = now.Add(31 * time.Second)
+	result, err = limiter.Allow(ctx, "key", limit)
+	if err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	if !result.Allowed || result.Remaining != 0 {
+		t.Errorf("Expected request allowed with 0 remaining, got %+v", result)
+	}
+}
+
+func TestLimiter_Burst(t *testing.T) {
+	limiter, now := newTestLimiter(t)
+	ctx := context.Background()
+	limit := Limit{PerMinute: 100, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		if result, _ := limiter.Allow(ctx, "key", limit); !result.Allowed {
+			t.Fatalf("Request %d should be allowed", i+1)
+		}
+	}
+
+	result, err := limiter.Allow(ctx, "key", limit)
+	if err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	if result.Allowed {
+		t.Fatal("Third request within one second should exceed the burst")
+	}
+
+	*now = now.Add(time.Second)
+	if result, _ := limiter.Allow(ctx, "key", limit); !result.Allowed {
+		t.Error("Request after the burst window should be allowed")
+	}
+}
+
+func TestLimiter_PeekDoesNotConsume(t *testing.T) {
+	limiter, _ := newTestLimiter(t)
+	ctx := context.Background()
+	limit := Limit{PerMinute: 5}
+
+	if _, err := limiter.Allow(ctx, "key", limit); err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Peek(ctx, "key", limit)
+		if err != nil {
+			t.Fatalf("Peek failed: %v", err)
+		}
+		if result.Remaining != 4 {
+			t.Errorf("Expected 4 remaining, got %d", result.Remaining)
+		}
+	}
+}
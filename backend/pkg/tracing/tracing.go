@@ -0,0 +1,56 @@
+// Package tracing configures OpenTelemetry tracing and provides span helpers
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName identifies the instrumentation library in exported spans
+const TracerName = "github.com/lightshare/backend"
+
+// Init creates a tracer provider exporting spans over OTLP/HTTP and installs it, along
+// with the W3C trace context propagator, as the global provider. The exporter reads its
+// endpoint from OTEL_EXPORTER_OTLP_ENDPOINT. Callers must Shutdown the provider on exit
+// to flush buffered spans.
+func Init(ctx context.Context, serviceName, serviceVersion string) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(serviceVersion),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return provider, nil
+}
+
+// StartSpan starts a child of the span in ctx using that span's tracer provider.
+// Without a span in ctx the returned span is a no-op, so untraced code paths stay free.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(TracerName)
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
@@ -2,7 +2,8 @@ import 'account.dart';
 
 enum Provider {
   lifx('lifx', 'LIFX'),
-  hue('hue', 'Philips Hue');
+  hue('hue', 'Philips Hue'),
+  nanoleaf('nanoleaf', 'Nanoleaf');
 
   const Provider(this.value, this.displayName);
 
@@ -47,6 +47,13 @@ class _TokenEntryScreenState extends ConsumerState<TokenEntryScreen> {
 3. Create a new app
 4. Copy the generated token
 5. Paste it below''';
+      case models.Provider.nanoleaf:
+        return '''To get your Nanoleaf token:
+1. Find your controller's IP address in your router
+2. Hold the controller's power button for 5-7 seconds
+3. Within 30 seconds, send POST http://<ip>:16021/api/v1/new
+4. Copy the returned auth_token
+5. Paste it below as <ip>|<auth_token>''';
     }
   }
 